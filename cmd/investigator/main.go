@@ -1,19 +1,39 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jth/claude/GoInspectorGadget/pkg/archive"
 	"github.com/jth/claude/GoInspectorGadget/pkg/casefile"
 	"github.com/jth/claude/GoInspectorGadget/pkg/casemanagement"
+	"github.com/jth/claude/GoInspectorGadget/pkg/chat"
 	"github.com/jth/claude/GoInspectorGadget/pkg/correspondence"
 	"github.com/jth/claude/GoInspectorGadget/pkg/document"
+	"github.com/jth/claude/GoInspectorGadget/pkg/email"
+	"github.com/jth/claude/GoInspectorGadget/pkg/entity"
 	"github.com/jth/claude/GoInspectorGadget/pkg/evidence"
+	"github.com/jth/claude/GoInspectorGadget/pkg/hashicorp"
+	"github.com/jth/claude/GoInspectorGadget/pkg/hashset"
 	"github.com/jth/claude/GoInspectorGadget/pkg/interview"
+	"github.com/jth/claude/GoInspectorGadget/pkg/metadata"
+	"github.com/jth/claude/GoInspectorGadget/pkg/ocr"
+	"github.com/jth/claude/GoInspectorGadget/pkg/pii"
 )
 
 // Simple in-memory repositories for demonstration
@@ -22,6 +42,15 @@ type inMemoryRepo struct {
 	casefiles      map[string]*casefile.Case
 	documents      map[string]*document.Document
 	evidence       map[string]*evidence.Evidence
+	biological     map[string]*evidence.BiologicalEvidence
+	digital        map[string]*evidence.DigitalEvidence
+	secrets        map[string]*evidence.EvidenceSecret
+	secretAccess   map[string][]evidence.SecretAccess
+	decryptions    map[string]evidence.DecryptionRecord
+	labRequests    map[string]*evidence.LabRequest
+	emails         map[string]*evidence.EmailMessage
+	chatMessages   []*evidence.ChatMessage
+	redactionLog   []*document.RedactionLogEntry
 	interviews     map[string]*interview.Interview
 	transcripts    map[string]*interview.Transcript
 	correspondence map[string]*correspondence.Correspondence
@@ -37,13 +66,24 @@ type InvestigatorApp struct {
 	// Services
 	caseService           *casemanagement.CaseService
 	casefileService       *casefile.CaseService
-	documentService       document.DocumentProcessor
+	documentProcessors    *document.Registry
+	redactionService      *document.RedactionService
+	versionService        *document.VersionService
 	evidenceService       *evidence.EvidenceService
+	biologicalMonitor     *evidence.BiologicalMonitor
+	secretManager         *evidence.SecretManager
+	labService            *evidence.LabService
+	emailService          *evidence.EmailService
+	chatService           *evidence.ChatService
 	interviewService      *interview.InterviewService
 	correspondenceService *correspondence.CorrespondenceService
+	hashIndex             *hashset.Index
+	ocrEngine             *ocr.Tesseract
+	entityExtractor       *entity.Extractor
 
 	// Repositories
-	repo *inMemoryRepo
+	repo     *inMemoryRepo
+	importMu sync.Mutex // Held while checking for and saving imported documents
 }
 
 func NewInvestigatorApp(workingDir string) *InvestigatorApp {
@@ -52,6 +92,13 @@ func NewInvestigatorApp(workingDir string) *InvestigatorApp {
 		casefiles:      make(map[string]*casefile.Case),
 		documents:      make(map[string]*document.Document),
 		evidence:       make(map[string]*evidence.Evidence),
+		biological:     make(map[string]*evidence.BiologicalEvidence),
+		digital:        make(map[string]*evidence.DigitalEvidence),
+		secrets:        make(map[string]*evidence.EvidenceSecret),
+		secretAccess:   make(map[string][]evidence.SecretAccess),
+		decryptions:    make(map[string]evidence.DecryptionRecord),
+		labRequests:    make(map[string]*evidence.LabRequest),
+		emails:         make(map[string]*evidence.EmailMessage),
 		interviews:     make(map[string]*interview.Interview),
 		transcripts:    make(map[string]*interview.Transcript),
 		correspondence: make(map[string]*correspondence.Correspondence),
@@ -81,17 +128,48 @@ func (app *InvestigatorApp) initializeServices() {
 	// Initialize document service
 	tempDir := filepath.Join(app.workingDir, "temp")
 	os.MkdirAll(tempDir, 0755)
-	// Create a new PDF processor for document handling
-	pdfProcessor := &document.PDFProcessor{
-		PdfToTextPath: "",
-		UseOCR:        false,
-		TempDir:       tempDir,
+	// Choose a processor for each document from its content, reading
+	// scanned pages and images with tesseract when it is installed
+	if engine, err := ocr.NewTesseract(tempDir); err == nil {
+		app.ocrEngine = engine
+	}
+	app.documentProcessors = document.NewDefaultRegistry(tempDir, app.ocrEngine)
+	// Use the retrained document classifier if there is one
+	if _, err := os.Stat(app.classifierPath()); err == nil {
+		if c, err := document.LoadClassifier(app.classifierPath()); err == nil {
+			app.documentProcessors.SetClassifier(c)
+		} else {
+			fmt.Printf("Warning: using the baseline document classifier: %v\n", err)
+		}
 	}
-	app.documentService = pdfProcessor
+	app.entityExtractor = entity.NewExtractor()
+	app.redactionService = document.NewRedactionService(&inMemoryDocumentRepo{repo: app.repo})
+	app.versionService = document.NewVersionService(&inMemoryDocumentRepo{repo: app.repo})
 
 	// Initialize evidence repository implementation
 	evidenceRepo := &inMemoryEvidenceRepo{evidence: app.repo.evidence}
 	app.evidenceService = evidence.NewEvidenceService(evidenceRepo)
+	app.evidenceService.SetCaseChecker(&caseDispositionChecker{caseService: app.caseService})
+	app.evidenceService.SetDigitalRepository(&inMemoryDigitalRepo{digital: app.repo.digital})
+
+	// Classify digital evidence against hash sets once any have been imported
+	if _, err := os.Stat(filepath.Join(app.workingDir, "hashsets", "sets.json")); err == nil {
+		app.hashSets()
+	}
+
+	biologicalRepo := &inMemoryBiologicalRepo{biological: app.repo.biological}
+	app.biologicalMonitor = evidence.NewBiologicalMonitor(app.evidenceService, biologicalRepo,
+		&caseNoteWriter{caseService: app.caseService})
+
+	labRepo := &inMemoryLabRepo{requests: app.repo.labRequests}
+	app.labService = evidence.NewLabService(app.evidenceService, labRepo, biologicalRepo,
+		&labReportImporter{app: app})
+
+	emailRepo := &inMemoryEmailRepo{emails: app.repo.emails}
+	app.emailService = evidence.NewEmailService(app.evidenceService, emailRepo,
+		&caseEventWriter{caseService: app.caseService}, &casePersonMatcher{caseService: app.caseService})
+
+	app.chatService = evidence.NewChatService(app.evidenceService, &inMemoryChatRepo{repo: app.repo})
 
 	// Initialize interview repository implementations
 	interviewRepo := &inMemoryInterviewRepo{interviews: app.repo.interviews}
@@ -132,15 +210,271 @@ func main() {
 	// Document import flags
 	docPath := docImportCmd.String("path", "", "Path to document file")
 	docCase := docImportCmd.String("case", "", "Case ID to associate document with")
+	docLang := docImportCmd.String("lang", "", "OCR languages for scanned pages and images, e.g. eng, spa or eng+spa (default: eng and spa where installed)")
+	docSupersedes := docImportCmd.String("supersedes", "", "ID of the earlier version the document revises")
+	docDir := docImportCmd.String("dir", "", "Directory to import recursively instead of a single file")
+	docInclude := docImportCmd.String("include", "", "Comma-separated glob patterns of the files to import, e.g. \"*.pdf,*.docx\"")
+	docExclude := docImportCmd.String("exclude", "", "Comma-separated glob patterns of the files and directories to leave out")
+	docWorkers := docImportCmd.Int("workers", 4, "Files imported at once")
+	docLog := docImportCmd.String("log", "", "Import log, from which an interrupted import resumes (default: under imports in the working directory)")
+
+	// Document redaction flags
+	docRedactCmd := flag.NewFlagSet("doc redact", flag.ExitOnError)
+	redactID := docRedactCmd.String("id", "", "Document ID")
+	redactStart := docRedactCmd.Int("start", -1, "Start of the redacted text, in bytes of the document content")
+	redactEnd := docRedactCmd.Int("end", -1, "End of the redacted text, in bytes of the document content")
+	redactText := docRedactCmd.String("text", "", "Redact every occurrence of this text instead of a range")
+	redactReason := docRedactCmd.String("reason", "", "Reason for the redaction, e.g. \"Witness identity\"")
+	redactTemporary := docRedactCmd.Bool("temporary", false, "Redaction may be lifted for court-authorized viewers")
+
+	docExportCmd := flag.NewFlagSet("doc export", flag.ExitOnError)
+	exportID := docExportCmd.String("id", "", "Document ID")
+	exportFor := docExportCmd.String("for", "", "Person the redacted copy is for (default: current user)")
+	exportCourtOrder := docExportCmd.String("court-order", "", "Court order authorizing the viewer to see temporary redactions")
+	exportReviewedBy := docExportCmd.String("reviewed-by", "", "Person who checked the court order (required with --court-order)")
+	exportOutput := docExportCmd.String("output", "", "Directory for the redacted copy (default: redacted in the working directory)")
+
+	docRedactionsCmd := flag.NewFlagSet("doc redactions", flag.ExitOnError)
+	redactionsID := docRedactionsCmd.String("id", "", "Document ID")
+
+	docPIICmd := flag.NewFlagSet("doc pii", flag.ExitOnError)
+	piiID := docPIICmd.String("id", "", "Document ID to scan for personal information")
+
+	docEntitiesCmd := flag.NewFlagSet("doc entities", flag.ExitOnError)
+	entitiesID := docEntitiesCmd.String("id", "", "Document ID")
+	entitiesKind := docEntitiesCmd.String("kind", "", "Comma-separated kinds to list (PERSON, PHONE, EMAIL, ADDRESS, VEHICLE, PLATE, WEAPON, DATE, MONEY)")
+
+	docPersonCmd := flag.NewFlagSet("doc person", flag.ExitOnError)
+	personDocID := docPersonCmd.String("id", "", "Document ID")
+	personEntity := docPersonCmd.String("entity", "", "ID of the PERSON entity, e.g. E3")
+	personDetails := docPersonCmd.String("details", "", "Comma-separated IDs of the person's phone, email, address and other entities")
+	personRole := docPersonCmd.String("role", "Witness", "Role in the case (Victim, Suspect, Witness)")
+
+	docEventCmd := flag.NewFlagSet("doc event", flag.ExitOnError)
+	eventDocID := docEventCmd.String("id", "", "Document ID")
+	eventEntity := docEventCmd.String("entity", "", "ID of the DATE entity, e.g. E1")
+	eventWith := docEventCmd.String("with", "", "Comma-separated IDs of the people and address involved")
+	eventDesc := docEventCmd.String("desc", "", "Event description (default: the sentence mentioning the date)")
+
+	docSupersedeCmd := flag.NewFlagSet("doc supersede", flag.ExitOnError)
+	supersedeID := docSupersedeCmd.String("id", "", "Document ID of the revision")
+	supersedePrevious := docSupersedeCmd.String("previous", "", "Document ID of the earlier version")
+
+	docVersionsCmd := flag.NewFlagSet("doc versions", flag.ExitOnError)
+	versionsID := docVersionsCmd.String("id", "", "Document ID")
+
+	docRelabelCmd := flag.NewFlagSet("doc relabel", flag.ExitOnError)
+	relabelID := docRelabelCmd.String("id", "", "Document ID")
+	relabelType := docRelabelCmd.String("type", "", "Correct document type, e.g. \"Witness Statement\"")
+
+	docClassifierEvaluateCmd := flag.NewFlagSet("doc classifier evaluate", flag.ExitOnError)
+	evaluateFolds := docClassifierEvaluateCmd.Int("folds", 5, "Cross-validation folds")
+	evaluateOutput := docClassifierEvaluateCmd.String("output", "", "File for the report (default: stdout)")
+
+	docReviewCmd := flag.NewFlagSet("doc review", flag.ExitOnError)
+	reviewID := docReviewCmd.String("id", "", "Document ID")
+	reviewProposals := docReviewCmd.String("proposal", "", "Comma-separated proposal IDs, e.g. P1,P3")
+	reviewAccept := docReviewCmd.Bool("accept", false, "Accept the proposals, redacting the text")
+	reviewReject := docReviewCmd.Bool("reject", false, "Reject the proposals")
 
 	// Evidence subcommands
 	evidenceAddCmd := flag.NewFlagSet("evidence add", flag.ExitOnError)
 	evidenceListCmd := flag.NewFlagSet("evidence list", flag.ExitOnError)
+	listHideKnown := evidenceListCmd.Bool("hide-known", false, "Hide files found in known-good hash sets")
 
 	// Evidence add flags
 	evidenceDesc := evidenceAddCmd.String("desc", "", "Evidence description")
 	evidenceType := evidenceAddCmd.String("type", "PHYSICAL", "Evidence type (PHYSICAL, DIGITAL, etc.)")
 	evidenceCase := evidenceAddCmd.String("case", "", "Case ID to associate evidence with")
+	evidenceBioType := evidenceAddCmd.String("bio-type", "", "Biological sample type (BLOOD, DNA, TISSUE, etc.) for BIOLOGICAL evidence")
+	evidenceConditions := evidenceAddCmd.String("conditions", "", "Required storage conditions (FROZEN, REFRIGERATED, DRY)")
+	evidenceExpires := evidenceAddCmd.String("expires", "", "Expiration date (YYYY-MM-DD) for BIOLOGICAL evidence")
+	evidenceLocation := evidenceAddCmd.String("location", "Evidence Locker", "Storage location")
+	evidenceFile := evidenceAddCmd.String("file", "", "File for DIGITAL evidence; embedded photo/video metadata is extracted")
+	evidenceExpand := evidenceAddCmd.Bool("expand", false, "Expand ZIP, TAR, GZIP and BZIP2 files into child evidence items")
+
+	// Evidence acquire flags
+	evidenceAcquireCmd := flag.NewFlagSet("evidence acquire", flag.ExitOnError)
+	acquirePath := evidenceAcquireCmd.String("path", "", "Directory to acquire file by file, or raw image (.dd/.img) to hash as a whole")
+	acquireDesc := evidenceAcquireCmd.String("desc", "", "Evidence description")
+	acquireCase := evidenceAcquireCmd.String("case", "", "Case ID to associate evidence with")
+	acquireManifest := evidenceAcquireCmd.String("manifest", "", "Path for the DFXML manifest (default: manifests/<source>-<timestamp>.xml)")
+
+	// Evidence verify flags
+	evidenceVerifyCmd := flag.NewFlagSet("evidence verify", flag.ExitOnError)
+	verifyManifest := evidenceVerifyCmd.String("manifest", "", "DFXML manifest to verify against")
+	verifyPath := evidenceVerifyCmd.String("path", "", "Source path to verify (default: path recorded in manifest)")
+	verifyManifestHash := evidenceVerifyCmd.String("manifest-sha256", "", "SHA-256 of the manifest printed at acquisition, if the evidence item is not on record")
+
+	// Evidence dispose flags
+	evidenceDisposeCmd := flag.NewFlagSet("evidence dispose", flag.ExitOnError)
+	disposeID := evidenceDisposeCmd.String("id", "", "Evidence ID to dispose of")
+	disposeAction := evidenceDisposeCmd.String("action", "", "Disposition action (RELEASE, DESTROY, RETURN, TRANSFER)")
+	disposeAuthorizedBy := evidenceDisposeCmd.String("authorized-by", "", "ID of the authorizing supervisor")
+	disposeWitness := evidenceDisposeCmd.String("witness", "", "ID of the witness (required for destruction, weapons and narcotics)")
+	disposeRecipient := evidenceDisposeCmd.String("recipient", "", "Person or agency receiving the evidence")
+	disposeMethod := evidenceDisposeCmd.String("method", "", "Destruction method")
+	disposeCourtOrder := evidenceDisposeCmd.String("court-order", "", "Court order reference")
+	disposeReason := evidenceDisposeCmd.String("reason", "", "Reason for disposition")
+
+	// Evidence label flags
+	evidenceLabelCmd := flag.NewFlagSet("evidence label", flag.ExitOnError)
+	labelID := evidenceLabelCmd.String("id", "", "Evidence ID to print a label for")
+	labelFormat := evidenceLabelCmd.String("format", "PNG", "Label format (PNG, SVG, ZPL)")
+	labelOutput := evidenceLabelCmd.String("output", "", "Output file (default: labels/<evidence-id>.<format>)")
+
+	// Evidence scan flags
+	evidenceScanCmd := flag.NewFlagSet("evidence scan", flag.ExitOnError)
+	scanCode := evidenceScanCmd.String("code", "", "Decoded barcode or QR code from the label")
+	scanTo := evidenceScanCmd.String("to", "", "Person receiving the evidence (transfers custody when set)")
+	scanLocation := evidenceScanCmd.String("location", "", "New storage location (transfers custody when set)")
+	scanReason := evidenceScanCmd.String("reason", "", "Reason for the transfer")
+
+	// Evidence audit flags
+	evidenceAuditCmd := flag.NewFlagSet("evidence audit", flag.ExitOnError)
+	auditLocation := evidenceAuditCmd.String("location", "", "Storage location being audited")
+	auditFile := evidenceAuditCmd.String("file", "", "File with one scanned code per line")
+	auditCodes := evidenceAuditCmd.String("codes", "", "Comma-separated scanned codes")
+	auditCorrect := evidenceAuditCmd.Bool("correct", false, "Update the storage location of misplaced items")
+
+	// Evidence monitor flags
+	evidenceMonitorCmd := flag.NewFlagSet("evidence monitor", flag.ExitOnError)
+	monitorDays := evidenceMonitorCmd.Int("days", 30, "Warn about biological evidence expiring within this many days")
+	monitorTempLog := evidenceMonitorCmd.String("templog", "", "Temperature log CSV from a storage unit")
+	monitorUnit := evidenceMonitorCmd.String("unit", "", "Storage unit name when the log has no unit column")
+	monitorNotify := evidenceMonitorCmd.Bool("notify", false, "Write warnings into the owning cases")
+
+	evidenceMetadataCmd := flag.NewFlagSet("evidence metadata", flag.ExitOnError)
+	metadataFile := evidenceMetadataCmd.String("file", "", "Photo or video to read embedded metadata from")
+
+	evidenceSecretAddCmd := flag.NewFlagSet("evidence secret add", flag.ExitOnError)
+	secretAddID := evidenceSecretAddCmd.String("id", "", "Evidence ID the secret unlocks")
+	secretAddKind := evidenceSecretAddCmd.String("kind", "PASSWORD", "Secret kind (PASSWORD, DECRYPTION_KEY, RECOVERY_KEY)")
+	secretAddValueFile := evidenceSecretAddCmd.String("value-file", "", "File holding the password or key, - for standard input (default: prompt)")
+	secretAddLabel := evidenceSecretAddCmd.String("label", "", "Short label for the secret")
+	secretAddSource := evidenceSecretAddCmd.String("source", "", "Where the secret was obtained")
+	secretAddUser := evidenceSecretAddCmd.String("user", "", "User storing the secret")
+
+	evidenceSecretRevealCmd := flag.NewFlagSet("evidence secret reveal", flag.ExitOnError)
+	secretRevealID := evidenceSecretRevealCmd.String("secret", "", "Secret ID")
+	secretRevealUser := evidenceSecretRevealCmd.String("user", "", "User requesting the secret")
+	secretRevealReason := evidenceSecretRevealCmd.String("reason", "", "Reason for access")
+
+	evidenceSecretLogCmd := flag.NewFlagSet("evidence secret log", flag.ExitOnError)
+	secretLogID := evidenceSecretLogCmd.String("id", "", "Evidence ID")
+
+	evidenceDecryptCmd := flag.NewFlagSet("evidence decrypt", flag.ExitOnError)
+	decryptID := evidenceDecryptCmd.String("id", "", "Evidence ID")
+	decryptSecret := evidenceDecryptCmd.String("secret", "", "Secret ID that was tried")
+	decryptUser := evidenceDecryptCmd.String("user", "", "User performing the decryption")
+	decryptMethod := evidenceDecryptCmd.String("method", "", "Tool or procedure used")
+	decryptFailed := evidenceDecryptCmd.Bool("failed", false, "Record a failed attempt")
+
+	evidenceExpandCmd := flag.NewFlagSet("evidence expand", flag.ExitOnError)
+	expandID := evidenceExpandCmd.String("id", "", "Evidence ID of the archive")
+	expandFile := evidenceExpandCmd.String("file", "", "Archive file (must match the recorded hash)")
+	expandMaxDepth := evidenceExpandCmd.Int("max-depth", archive.DefaultLimits().MaxDepth, "Levels of nested archives to expand")
+	expandMaxSize := evidenceExpandCmd.Int64("max-size", archive.DefaultLimits().MaxTotalSize>>20, "Total MB to extract")
+	expandOperator := evidenceExpandCmd.String("operator", "", "Person performing the expansion")
+
+	evidenceCustodyCmd := flag.NewFlagSet("evidence custody", flag.ExitOnError)
+	custodyIDs := evidenceCustodyCmd.String("id", "", "Comma-separated evidence IDs")
+	custodyCase := evidenceCustodyCmd.String("case", "", "Report on every item in a case")
+	custodyFormat := evidenceCustodyCmd.String("format", "TEXT", "Report format (TEXT, HTML, PDF)")
+	custodyOutput := evidenceCustodyCmd.String("output", "", "Output file (default: stdout for TEXT, reports/ otherwise)")
+	custodyPreparedBy := evidenceCustodyCmd.String("prepared-by", "", "Person certifying the report")
+
+	evidenceLabSubmitCmd := flag.NewFlagSet("evidence lab submit", flag.ExitOnError)
+	labSubmitID := evidenceLabSubmitCmd.String("id", "", "Evidence ID to submit")
+	labSubmitLab := evidenceLabSubmitCmd.String("lab", "", "Forensic lab")
+	labSubmitExams := evidenceLabSubmitCmd.String("exams", "", "Comma-separated examinations requested")
+	labSubmitOfficer := evidenceLabSubmitCmd.String("officer", "", "Submitting officer")
+	labSubmitPriority := evidenceLabSubmitCmd.String("priority", "ROUTINE", "Priority (ROUTINE, HIGH, RUSH)")
+	labSubmitDue := evidenceLabSubmitCmd.String("due", "", "Due date (YYYY-MM-DD)")
+	labSubmitNotes := evidenceLabSubmitCmd.String("notes", "", "Notes for the lab")
+
+	evidenceLabShipCmd := flag.NewFlagSet("evidence lab ship", flag.ExitOnError)
+	labShipRequest := evidenceLabShipCmd.String("request", "", "Lab request ID")
+	labShipBy := evidenceLabShipCmd.String("by", "", "Person shipping the evidence")
+	labShipCarrier := evidenceLabShipCmd.String("carrier", "", "Carrier or courier")
+	labShipTracking := evidenceLabShipCmd.String("tracking", "", "Tracking number")
+
+	evidenceLabStatusCmd := flag.NewFlagSet("evidence lab status", flag.ExitOnError)
+	labStatusRequest := evidenceLabStatusCmd.String("request", "", "Lab request ID")
+	labStatusValue := evidenceLabStatusCmd.String("status", "", "New status (PROCESSING, ANALYZED, CANCELLED)")
+	labStatusBy := evidenceLabStatusCmd.String("by", "", "Person recording the update")
+	labStatusNote := evidenceLabStatusCmd.String("note", "", "Note, e.g. the lab's case number")
+
+	evidenceLabResultsCmd := flag.NewFlagSet("evidence lab results", flag.ExitOnError)
+	labResultsRequest := evidenceLabResultsCmd.String("request", "", "Lab request ID")
+	labResultsFile := evidenceLabResultsCmd.String("file", "", "Lab result report")
+	labResultsSummary := evidenceLabResultsCmd.String("summary", "", "Summary of the results (taken from the report if omitted)")
+	labResultsBy := evidenceLabResultsCmd.String("by", "", "Person importing the results")
+
+	evidenceLabReturnCmd := flag.NewFlagSet("evidence lab return", flag.ExitOnError)
+	labReturnRequest := evidenceLabReturnCmd.String("request", "", "Lab request ID")
+	labReturnBy := evidenceLabReturnCmd.String("by", "", "Person receiving the evidence")
+	labReturnLocation := evidenceLabReturnCmd.String("location", "", "Storage location")
+	labReturnNotes := evidenceLabReturnCmd.String("notes", "", "Condition of packaging, seals, etc.")
+
+	evidenceLabListCmd := flag.NewFlagSet("evidence lab list", flag.ExitOnError)
+	labListID := evidenceLabListCmd.String("id", "", "Evidence ID")
+	labListOverdue := evidenceLabListCmd.Bool("overdue", false, "List open requests past their due date")
+
+	evidenceEmailImportCmd := flag.NewFlagSet("evidence email import", flag.ExitOnError)
+	emailImportPath := evidenceEmailImportCmd.String("path", "", "EML file, mbox file or Maildir directory")
+	emailImportID := evidenceEmailImportCmd.String("id", "", "Existing evidence item holding the email")
+	emailImportCase := evidenceEmailImportCmd.String("case", "", "Case ID for a new evidence item")
+	emailImportDesc := evidenceEmailImportCmd.String("desc", "", "Description for a new evidence item")
+	emailImportNoTimeline := evidenceEmailImportCmd.Bool("no-timeline", false, "Do not add messages to the case timeline")
+
+	evidenceEmailListCmd := flag.NewFlagSet("evidence email list", flag.ExitOnError)
+	emailListID := evidenceEmailListCmd.String("id", "", "Evidence ID")
+
+	evidenceEmailShowCmd := flag.NewFlagSet("evidence email show", flag.ExitOnError)
+	emailShowMessage := evidenceEmailShowCmd.String("message", "", "Message ID")
+	emailShowHeaders := evidenceEmailShowCmd.Bool("headers", false, "Print all header fields")
+	emailShowHTML := evidenceEmailShowCmd.Bool("html", false, "Print the HTML body instead of the text body")
+
+	evidenceChatImportCmd := flag.NewFlagSet("evidence chat import", flag.ExitOnError)
+	chatImportPath := evidenceChatImportCmd.String("path", "", "WhatsApp .txt, Telegram result.json, CSV file or unpacked export directory")
+	chatImportID := evidenceChatImportCmd.String("id", "", "Existing evidence item holding the export")
+	chatImportCase := evidenceChatImportCmd.String("case", "", "Case ID for a new evidence item")
+	chatImportDesc := evidenceChatImportCmd.String("desc", "", "Description for a new evidence item")
+	chatImportTZ := evidenceChatImportCmd.String("tz", "", "Time zone of the export's timestamps, e.g. America/Caracas (default: local)")
+	chatImportOrder := evidenceChatImportCmd.String("date-order", "AUTO", "Order of numeric dates: DMY, MDY or AUTO")
+
+	evidenceChatSearchCmd := flag.NewFlagSet("evidence chat search", flag.ExitOnError)
+	chatSearchCase := evidenceChatSearchCmd.String("case", "", "Case ID")
+	chatSearchQuery := evidenceChatSearchCmd.String("query", "", "Words to find; end a word with * to match its prefix")
+
+	evidenceChatViewCmd := flag.NewFlagSet("evidence chat view", flag.ExitOnError)
+	chatViewCase := evidenceChatViewCmd.String("case", "", "Case ID")
+	chatViewID := evidenceChatViewCmd.String("id", "", "Only the chats of this evidence item")
+	chatViewFormat := evidenceChatViewCmd.String("format", "TEXT", "Output format: TEXT or HTML")
+	chatViewOutput := evidenceChatViewCmd.String("output", "", "Output file (default: print TEXT, save HTML under reports/)")
+
+	evidenceHashsetImportCmd := flag.NewFlagSet("evidence hashset import", flag.ExitOnError)
+	hashsetImportFile := evidenceHashsetImportCmd.String("file", "", "NSRL RDS-style CSV or list of hashes")
+	hashsetImportName := evidenceHashsetImportCmd.String("name", "", "Name of the hash set")
+	hashsetImportKind := evidenceHashsetImportCmd.String("kind", "good", "Kind of set (good, bad)")
+
+	evidenceHashsetListCmd := flag.NewFlagSet("evidence hashset list", flag.ExitOnError)
+
+	evidenceHashsetLookupCmd := flag.NewFlagSet("evidence hashset lookup", flag.ExitOnError)
+	hashsetLookupHash := evidenceHashsetLookupCmd.String("hash", "", "MD5, SHA-1 or SHA-256 hash")
+	hashsetLookupFile := evidenceHashsetLookupCmd.String("file", "", "File to hash and look up")
+
+	evidenceDeriveCmd := flag.NewFlagSet("evidence derive", flag.ExitOnError)
+	deriveParent := evidenceDeriveCmd.String("parent", "", "Evidence ID the new item was derived from")
+	deriveRelation := evidenceDeriveCmd.String("relation", "EXTRACTED_FROM", "Relation (EXTRACTED_FROM, COPY_OF, ANALYZED_INTO, SUBSAMPLE_OF)")
+	deriveDesc := evidenceDeriveCmd.String("desc", "", "Description of the derived item")
+	deriveType := evidenceDeriveCmd.String("type", "", "Evidence type (defaults to the parent's type)")
+	deriveFile := evidenceDeriveCmd.String("file", "", "File of the derived item (hashed and identified)")
+	deriveTool := evidenceDeriveCmd.String("tool", "", "Tool used, e.g. \"Cellebrite UFED\"")
+	deriveToolVersion := evidenceDeriveCmd.String("tool-version", "", "Tool version")
+	deriveOperator := evidenceDeriveCmd.String("operator", "", "Person who performed the derivation")
+	deriveNotes := evidenceDeriveCmd.String("notes", "", "Notes")
 
 	// Interview subcommands
 	interviewAddCmd := flag.NewFlagSet("interview add", flag.ExitOnError)
@@ -154,6 +488,18 @@ func main() {
 	// Interview transcribe flags
 	interviewID := interviewTranscribeCmd.String("id", "", "Interview ID to transcribe")
 
+	interviewTranscriptCmd := flag.NewFlagSet("interview transcript", flag.ExitOnError)
+	transcriptID := interviewTranscriptCmd.String("id", "", "Interview ID")
+
+	interviewPIICmd := flag.NewFlagSet("interview pii", flag.ExitOnError)
+	interviewPIIID := interviewPIICmd.String("id", "", "Interview ID whose transcript to scan for personal information")
+
+	interviewReviewCmd := flag.NewFlagSet("interview review", flag.ExitOnError)
+	interviewReviewID := interviewReviewCmd.String("id", "", "Interview ID")
+	interviewReviewProposals := interviewReviewCmd.String("proposal", "", "Comma-separated proposal IDs, e.g. P1,P3")
+	interviewReviewAccept := interviewReviewCmd.Bool("accept", false, "Accept the proposals, redacting the text")
+	interviewReviewReject := interviewReviewCmd.Bool("reject", false, "Reject the proposals")
+
 	// Correspondence subcommands
 	corrCreateCmd := flag.NewFlagSet("correspondence create", flag.ExitOnError)
 	corrListCmd := flag.NewFlagSet("correspondence list", flag.ExitOnError)
@@ -167,6 +513,9 @@ func main() {
 	corrRecipient := corrCreateCmd.String("recipient", "", "Recipient name")
 	corrCase := corrCreateCmd.String("case", "", "Case ID")
 	corrTemplate := corrCreateCmd.String("template", "", "Template ID to use")
+	corrEvidence := corrCreateCmd.String("evidence", "", "Evidence ID whose details and chain of custody fill in the template")
+	var corrVars templateVars
+	corrCreateCmd.Var(&corrVars, "set", "Template variable as NAME=VALUE (repeatable)")
 
 	// Correspondence send flags
 	corrID := corrSendCmd.String("id", "", "Correspondence ID to send")
@@ -228,7 +577,79 @@ func main() {
 		switch os.Args[2] {
 		case "import":
 			docImportCmd.Parse(os.Args[3:])
-			app.handleDocImport(*docPath, *docCase)
+			if *docDir != "" {
+				app.handleDocImportDir(*docDir, *docCase, *docLang, *docInclude, *docExclude, *docWorkers, *docLog)
+			} else {
+				app.handleDocImport(*docPath, *docCase, *docLang, *docSupersedes)
+			}
+
+		case "supersede":
+			docSupersedeCmd.Parse(os.Args[3:])
+			app.handleDocSupersede(*supersedeID, *supersedePrevious)
+
+		case "versions":
+			docVersionsCmd.Parse(os.Args[3:])
+			id := *versionsID
+			if id == "" {
+				id = docVersionsCmd.Arg(0)
+			}
+			app.handleDocVersions(id)
+
+		case "processors":
+			app.handleDocProcessors()
+
+		case "redact":
+			docRedactCmd.Parse(os.Args[3:])
+			app.handleDocRedact(*redactID, *redactStart, *redactEnd, *redactText, *redactReason, *redactTemporary)
+
+		case "export":
+			docExportCmd.Parse(os.Args[3:])
+			app.handleDocExport(*exportID, *exportFor, *exportCourtOrder, *exportReviewedBy, *exportOutput)
+
+		case "redactions":
+			docRedactionsCmd.Parse(os.Args[3:])
+			app.handleDocRedactions(*redactionsID)
+
+		case "pii":
+			docPIICmd.Parse(os.Args[3:])
+			app.handleDocPII(*piiID)
+
+		case "entities":
+			docEntitiesCmd.Parse(os.Args[3:])
+			app.handleDocEntities(*entitiesID, *entitiesKind)
+
+		case "person":
+			docPersonCmd.Parse(os.Args[3:])
+			app.handleDocPerson(*personDocID, *personEntity, *personDetails, *personRole)
+
+		case "event":
+			docEventCmd.Parse(os.Args[3:])
+			app.handleDocEvent(*eventDocID, *eventEntity, *eventWith, *eventDesc)
+
+		case "relabel":
+			docRelabelCmd.Parse(os.Args[3:])
+			app.handleDocRelabel(*relabelID, *relabelType)
+
+		case "classifier":
+			if len(os.Args) < 4 {
+				fmt.Println("Missing doc classifier subcommand")
+				os.Exit(1)
+			}
+
+			switch os.Args[3] {
+			case "train":
+				app.handleClassifierTrain()
+			case "evaluate":
+				docClassifierEvaluateCmd.Parse(os.Args[4:])
+				app.handleClassifierEvaluate(*evaluateFolds, *evaluateOutput)
+			default:
+				fmt.Printf("Unknown doc classifier subcommand: %s\n", os.Args[3])
+				os.Exit(1)
+			}
+
+		case "review":
+			docReviewCmd.Parse(os.Args[3:])
+			app.handleDocReview(*reviewID, *reviewProposals, *reviewAccept, *reviewReject)
 
 		default:
 			fmt.Printf("Unknown document subcommand: %s\n", os.Args[2])
@@ -244,15 +665,206 @@ func main() {
 		switch os.Args[2] {
 		case "add":
 			evidenceAddCmd.Parse(os.Args[3:])
-			app.handleEvidenceAdd(*evidenceDesc, *evidenceType, *evidenceCase)
+			app.handleEvidenceAdd(*evidenceDesc, *evidenceType, *evidenceCase, *evidenceLocation,
+				*evidenceBioType, *evidenceConditions, *evidenceExpires, *evidenceFile, *evidenceExpand)
 
 		case "list":
 			evidenceListCmd.Parse(os.Args[3:])
 			if evidenceListCmd.NArg() > 0 {
-				app.handleEvidenceList(evidenceListCmd.Arg(0))
+				app.handleEvidenceList(evidenceListCmd.Arg(0), *listHideKnown)
 			} else {
-				app.handleEvidenceList("")
+				app.handleEvidenceList("", *listHideKnown)
+			}
+
+		case "acquire":
+			evidenceAcquireCmd.Parse(os.Args[3:])
+			app.handleEvidenceAcquire(*acquirePath, *acquireDesc, *acquireCase, *acquireManifest)
+
+		case "verify":
+			evidenceVerifyCmd.Parse(os.Args[3:])
+			app.handleEvidenceVerify(*verifyManifest, *verifyPath, *verifyManifestHash)
+
+		case "dispose":
+			evidenceDisposeCmd.Parse(os.Args[3:])
+			app.handleEvidenceDispose(*disposeID, *disposeAction, evidence.DispositionRequest{
+				PerformedBy:  "Current User", // Would come from auth system
+				AuthorizedBy: *disposeAuthorizedBy,
+				WitnessedBy:  *disposeWitness,
+				Recipient:    *disposeRecipient,
+				Method:       *disposeMethod,
+				CourtOrder:   *disposeCourtOrder,
+				Reason:       *disposeReason,
+			})
+
+		case "label":
+			evidenceLabelCmd.Parse(os.Args[3:])
+			app.handleEvidenceLabel(*labelID, *labelFormat, *labelOutput)
+
+		case "scan":
+			evidenceScanCmd.Parse(os.Args[3:])
+			app.handleEvidenceScan(*scanCode, *scanTo, *scanLocation, *scanReason)
+
+		case "audit":
+			evidenceAuditCmd.Parse(os.Args[3:])
+			app.handleEvidenceAudit(*auditLocation, *auditFile, *auditCodes, *auditCorrect)
+
+		case "monitor":
+			evidenceMonitorCmd.Parse(os.Args[3:])
+			app.handleEvidenceMonitor(*monitorDays, *monitorTempLog, *monitorUnit, *monitorNotify)
+
+		case "metadata":
+			evidenceMetadataCmd.Parse(os.Args[3:])
+			app.handleEvidenceMetadata(*metadataFile)
+
+		case "secret":
+			if len(os.Args) < 4 {
+				fmt.Println("Missing evidence secret subcommand")
+				os.Exit(1)
+			}
+
+			switch os.Args[3] {
+			case "add":
+				evidenceSecretAddCmd.Parse(os.Args[4:])
+				app.handleSecretAdd(*secretAddID, *secretAddKind, *secretAddValueFile, *secretAddLabel,
+					*secretAddSource, *secretAddUser)
+			case "reveal":
+				evidenceSecretRevealCmd.Parse(os.Args[4:])
+				app.handleSecretReveal(*secretRevealID, *secretRevealUser, *secretRevealReason)
+			case "log":
+				evidenceSecretLogCmd.Parse(os.Args[4:])
+				app.handleSecretLog(*secretLogID)
+			case "genkey":
+				app.handleSecretGenKey()
+			default:
+				fmt.Printf("Unknown evidence secret subcommand: %s\n", os.Args[3])
+				os.Exit(1)
+			}
+
+		case "expand":
+			evidenceExpandCmd.Parse(os.Args[3:])
+			limits := archive.DefaultLimits()
+			limits.MaxDepth = *expandMaxDepth
+			limits.MaxTotalSize = *expandMaxSize << 20
+			app.handleEvidenceExpand(*expandID, *expandFile, *expandOperator, limits)
+
+		case "custody":
+			evidenceCustodyCmd.Parse(os.Args[3:])
+			app.handleEvidenceCustody(*custodyIDs, *custodyCase, *custodyFormat, *custodyOutput, *custodyPreparedBy)
+
+		case "lab":
+			if len(os.Args) < 4 {
+				fmt.Println("Missing evidence lab subcommand")
+				os.Exit(1)
+			}
+
+			switch os.Args[3] {
+			case "submit":
+				evidenceLabSubmitCmd.Parse(os.Args[4:])
+				app.handleLabSubmit(*labSubmitID, *labSubmitLab, *labSubmitExams, *labSubmitOfficer,
+					*labSubmitPriority, *labSubmitDue, *labSubmitNotes)
+			case "ship":
+				evidenceLabShipCmd.Parse(os.Args[4:])
+				app.handleLabShip(*labShipRequest, *labShipBy, *labShipCarrier, *labShipTracking)
+			case "status":
+				evidenceLabStatusCmd.Parse(os.Args[4:])
+				app.handleLabStatus(*labStatusRequest, *labStatusValue, *labStatusBy, *labStatusNote)
+			case "results":
+				evidenceLabResultsCmd.Parse(os.Args[4:])
+				app.handleLabResults(*labResultsRequest, *labResultsFile, *labResultsSummary, *labResultsBy)
+			case "return":
+				evidenceLabReturnCmd.Parse(os.Args[4:])
+				app.handleLabReturn(*labReturnRequest, *labReturnBy, *labReturnLocation, *labReturnNotes)
+			case "list":
+				evidenceLabListCmd.Parse(os.Args[4:])
+				app.handleLabList(*labListID, *labListOverdue)
+			default:
+				fmt.Printf("Unknown evidence lab subcommand: %s\n", os.Args[3])
+				os.Exit(1)
+			}
+
+		case "email":
+			if len(os.Args) < 4 {
+				fmt.Println("Missing evidence email subcommand")
+				os.Exit(1)
+			}
+
+			switch os.Args[3] {
+			case "import":
+				evidenceEmailImportCmd.Parse(os.Args[4:])
+				app.handleEmailImport(*emailImportPath, *emailImportID, *emailImportCase, *emailImportDesc, !*emailImportNoTimeline)
+			case "list":
+				evidenceEmailListCmd.Parse(os.Args[4:])
+				app.handleEmailList(*emailListID)
+			case "show":
+				evidenceEmailShowCmd.Parse(os.Args[4:])
+				app.handleEmailShow(*emailShowMessage, *emailShowHeaders, *emailShowHTML)
+			default:
+				fmt.Printf("Unknown evidence email subcommand: %s\n", os.Args[3])
+				os.Exit(1)
+			}
+
+		case "chat":
+			if len(os.Args) < 4 {
+				fmt.Println("Missing evidence chat subcommand")
+				os.Exit(1)
+			}
+
+			switch os.Args[3] {
+			case "import":
+				evidenceChatImportCmd.Parse(os.Args[4:])
+				app.handleChatImport(*chatImportPath, *chatImportID, *chatImportCase, *chatImportDesc, *chatImportTZ, *chatImportOrder)
+			case "search":
+				evidenceChatSearchCmd.Parse(os.Args[4:])
+				app.handleChatSearch(*chatSearchCase, *chatSearchQuery)
+			case "view":
+				evidenceChatViewCmd.Parse(os.Args[4:])
+				app.handleChatView(*chatViewCase, *chatViewID, *chatViewFormat, *chatViewOutput)
+			default:
+				fmt.Printf("Unknown evidence chat subcommand: %s\n", os.Args[3])
+				os.Exit(1)
+			}
+
+		case "hashset":
+			if len(os.Args) < 4 {
+				fmt.Println("Missing evidence hashset subcommand")
+				os.Exit(1)
+			}
+
+			switch os.Args[3] {
+			case "import":
+				evidenceHashsetImportCmd.Parse(os.Args[4:])
+				app.handleHashsetImport(*hashsetImportFile, *hashsetImportName, *hashsetImportKind)
+			case "list":
+				evidenceHashsetListCmd.Parse(os.Args[4:])
+				app.handleHashsetList()
+			case "lookup":
+				evidenceHashsetLookupCmd.Parse(os.Args[4:])
+				app.handleHashsetLookup(*hashsetLookupHash, *hashsetLookupFile)
+			default:
+				fmt.Printf("Unknown evidence hashset subcommand: %s\n", os.Args[3])
+				os.Exit(1)
+			}
+
+		case "derive":
+			evidenceDeriveCmd.Parse(os.Args[3:])
+			app.handleEvidenceDerive(*deriveDesc, *deriveType, *deriveFile, evidence.DerivationRequest{
+				ParentID:    *deriveParent,
+				Tool:        *deriveTool,
+				ToolVersion: *deriveToolVersion,
+				Operator:    *deriveOperator,
+				Notes:       *deriveNotes,
+			}, *deriveRelation)
+
+		case "lineage":
+			if len(os.Args) < 4 {
+				fmt.Println("Error: Evidence ID is required")
+				os.Exit(1)
 			}
+			app.handleEvidenceLineage(os.Args[3])
+
+		case "decrypt":
+			evidenceDecryptCmd.Parse(os.Args[3:])
+			app.handleEvidenceDecrypt(*decryptID, *decryptSecret, *decryptUser, *decryptMethod, !*decryptFailed)
 
 		default:
 			fmt.Printf("Unknown evidence subcommand: %s\n", os.Args[2])
@@ -274,6 +886,18 @@ func main() {
 			interviewTranscribeCmd.Parse(os.Args[3:])
 			app.handleInterviewTranscribe(*interviewID)
 
+		case "transcript":
+			interviewTranscriptCmd.Parse(os.Args[3:])
+			app.handleInterviewTranscript(*transcriptID)
+
+		case "pii":
+			interviewPIICmd.Parse(os.Args[3:])
+			app.handleInterviewPII(*interviewPIIID)
+
+		case "review":
+			interviewReviewCmd.Parse(os.Args[3:])
+			app.handleInterviewReview(*interviewReviewID, *interviewReviewProposals, *interviewReviewAccept, *interviewReviewReject)
+
 		default:
 			fmt.Printf("Unknown interview subcommand: %s\n", os.Args[2])
 			os.Exit(1)
@@ -288,7 +912,8 @@ func main() {
 		switch os.Args[2] {
 		case "create":
 			corrCreateCmd.Parse(os.Args[3:])
-			app.handleCorrespondenceCreate(*corrType, *corrSubject, *corrBody, *corrRecipient, *corrCase, *corrTemplate)
+			app.handleCorrespondenceCreate(*corrType, *corrSubject, *corrBody, *corrRecipient, *corrCase, *corrTemplate,
+				*corrEvidence, corrVars)
 
 		case "list":
 			corrListCmd.Parse(os.Args[3:])
@@ -327,11 +952,64 @@ func printUsage() {
 	fmt.Println("  investigator case create --title \"Title\" --desc \"Description\" --type \"Homicide\"")
 	fmt.Println("  investigator case open <case-id>")
 	fmt.Println("  investigator case list")
-	fmt.Println("  investigator doc import --path \"path/to/file.pdf\" --case <case-id>")
+	fmt.Println("  investigator doc import --path \"path/to/file.pdf\" --case <case-id> [--lang eng+spa] [--supersedes <doc-id>]")
+	fmt.Println("  investigator doc import --dir <directory> --case <case-id> [--include \"*.pdf,*.docx\"] [--exclude \"*.tmp\"] [--workers 4] [--log import.jsonl]")
+	fmt.Println("  investigator doc supersede --id <doc-id> --previous <doc-id>")
+	fmt.Println("  investigator doc versions <doc-id>")
+	fmt.Println("  investigator doc processors")
+	fmt.Println("  investigator doc redact --id <doc-id> --start 120 --end 134 | --text \"Jane Doe\" --reason \"Witness identity\" [--temporary]")
+	fmt.Println("  investigator doc export --id <doc-id> [--for \"ADA Smith\"] [--court-order \"Order 2024-117\" --reviewed-by SGT-42] [--output dir]")
+	fmt.Println("  investigator doc redactions --id <doc-id>")
+	fmt.Println("  investigator doc pii --id <doc-id>")
+	fmt.Println("  investigator doc review --id <doc-id> --proposal P1,P2 --accept | --reject")
+	fmt.Println("  investigator doc relabel --id <doc-id> --type \"Witness Statement\"")
+	fmt.Println("  investigator doc classifier train")
+	fmt.Println("  investigator doc classifier evaluate [--folds 5] [--output report.txt]")
+	fmt.Println("  investigator doc entities --id <doc-id> [--kind PERSON,DATE]")
+	fmt.Println("  investigator doc person --id <doc-id> --entity E3 [--details E5,E6] [--role Witness]")
+	fmt.Println("  investigator doc event --id <doc-id> --entity E1 [--with E3,E8] [--desc \"Robbery at the bank\"]")
 	fmt.Println("  investigator evidence add --desc \"Description\" --type \"PHYSICAL\" --case <case-id>")
-	fmt.Println("  investigator evidence list [case-id]")
+	fmt.Println("  investigator evidence add --desc \"Blood sample\" --type \"BIOLOGICAL\" --bio-type BLOOD --conditions REFRIGERATED --expires 2025-06-01 --location \"Refrigerator 1\"")
+	fmt.Println("  investigator evidence add --desc \"Scene photo\" --type DIGITAL --file IMG_0042.jpg --case <case-id>")
+	fmt.Println("  investigator evidence add --desc \"Seized archive\" --type DIGITAL --file export.zip --expand --case <case-id>")
+	fmt.Println("  investigator evidence list [--hide-known] [case-id]")
+	fmt.Println("  investigator evidence metadata --file IMG_0042.jpg")
+	fmt.Println("  investigator evidence acquire --path \"path/to/dir-or-image\" --desc \"Description\" --case <case-id> [--manifest out.xml]")
+	fmt.Println("  investigator evidence verify --manifest manifest.xml [--path \"path/to/source\"] [--manifest-sha256 <hash>]")
+	fmt.Println("  investigator evidence label --id <evidence-id> --format PNG|SVG|ZPL [--output file]")
+	fmt.Println("  investigator evidence scan --code <scanned-code> [--to \"Person\" --location \"Locker 4\" --reason \"Reason\"]")
+	fmt.Println("  investigator evidence audit --location \"Shelf A3\" --file scanned.txt [--correct]")
+	fmt.Println("  investigator evidence monitor [--days 30] [--templog log.csv --unit \"Freezer 2\"] [--notify]")
+	fmt.Println("  investigator evidence derive --parent <evidence-id> --relation EXTRACTED_FROM --desc \"Description\" --tool \"Tool\" --operator <id> [--file path]")
+	fmt.Println("  investigator evidence lineage <evidence-id>")
+	fmt.Println("  investigator evidence expand --id <evidence-id> --file archive.zip [--max-depth 5 --max-size 16384 --operator <id>]")
+	fmt.Println("  investigator evidence custody --id <evidence-id>[,<evidence-id>...] | --case <case-id> [--format TEXT|HTML|PDF --output file --prepared-by <id>]")
+	fmt.Println("  investigator evidence lab submit --id <evidence-id> --lab \"State Crime Lab\" --exams \"DNA profiling\" --officer <id> [--priority RUSH --due 2025-06-01]")
+	fmt.Println("  investigator evidence lab ship --request <lab-id> --by <id> [--carrier \"Courier\" --tracking <number>]")
+	fmt.Println("  investigator evidence lab status --request <lab-id> --status PROCESSING|ANALYZED|CANCELLED --by <id>")
+	fmt.Println("  investigator evidence lab results --request <lab-id> --file report.pdf --by <id> [--summary \"Summary\"]")
+	fmt.Println("  investigator evidence lab return --request <lab-id> --by <id> --location \"Locker 4\"")
+	fmt.Println("  investigator evidence lab list [--id <evidence-id>] [--overdue]")
+	fmt.Println("  investigator evidence email import --path mailbox.mbox|message.eml|Maildir [--id <evidence-id> | --case <case-id> --desc \"...\"] [--no-timeline]")
+	fmt.Println("  investigator evidence email list --id <evidence-id>")
+	fmt.Println("  investigator evidence email show --message <message-id> [--headers] [--html]")
+	fmt.Println("  investigator evidence chat import --path chat.txt|result.json|chat.csv|export-dir [--id <evidence-id> | --case <case-id> --desc \"...\"] [--tz America/Caracas] [--date-order DMY|MDY|AUTO]")
+	fmt.Println("  investigator evidence chat search --case <case-id> --query \"...\"")
+	fmt.Println("  investigator evidence chat view --case <case-id> [--id <evidence-id>] [--format TEXT|HTML] [--output file]")
+	fmt.Println("  investigator evidence hashset import --file NSRLFile.txt --name \"NSRL RDS\" --kind good|bad")
+	fmt.Println("  investigator evidence hashset list")
+	fmt.Println("  investigator evidence hashset lookup --hash <md5|sha1|sha256> | --file <path>")
+	fmt.Println("  investigator evidence secret genkey")
+	fmt.Println("  investigator evidence secret add --id <evidence-id> --kind PASSWORD --value <secret> --user <id> [--label \"Label\" --source \"Source\"]")
+	fmt.Println("  investigator evidence secret reveal --secret <secret-id> --user <id> --reason \"Reason\"")
+	fmt.Println("  investigator evidence secret log --id <evidence-id>")
+	fmt.Println("  investigator evidence decrypt --id <evidence-id> --secret <secret-id> --user <id> --method \"Tool\" [--failed]")
+	fmt.Println("  investigator evidence dispose --id <evidence-id> --action DESTROY --authorized-by <id> --witness <id> --method \"Incineration\"")
 	fmt.Println("  investigator interview add --title \"Interview\" --type \"WITNESS\" --case <case-id>")
 	fmt.Println("  investigator interview transcribe --id <interview-id>")
+	fmt.Println("  investigator interview transcript --id <interview-id>")
+	fmt.Println("  investigator interview pii --id <interview-id>")
+	fmt.Println("  investigator interview review --id <interview-id> --proposal P1,P2 --accept | --reject")
 	fmt.Println("  investigator correspondence create --type \"EMAIL\" --subject \"Subject\" --recipient \"Name\" --case <case-id>")
 	fmt.Println("  investigator correspondence create --template <template-id> --recipient \"Name\" --case <case-id>")
 	fmt.Println("  investigator correspondence list [case-id]")
@@ -400,51 +1078,243 @@ func (app *InvestigatorApp) handleCaseList() {
 	}
 }
 
-func (app *InvestigatorApp) handleDocImport(path, caseID string) {
+func (app *InvestigatorApp) handleDocImport(path, caseID, lang, supersedes string) {
 	if path == "" {
 		fmt.Println("Error: Document path is required")
 		os.Exit(1)
 	}
+	caseID = app.importCase(caseID)
 
-	if caseID == "" {
-		if app.currentCaseID == "" {
-			fmt.Println("Error: No case specified and no case is currently open")
+	if supersedes != "" {
+		if _, ok := app.repo.documents[supersedes]; !ok {
+			fmt.Printf("Error: Document not found: %s\n", supersedes)
 			os.Exit(1)
 		}
-		caseID = app.currentCaseID
 	}
+	app.setOCRLanguages(lang)
 
-	// Ensure the case exists
-	_, err := app.caseService.GetCase(caseID)
+	// Process the document
+	doc, duplicate, err := app.importDocument(path, caseID)
 	if err != nil {
-		fmt.Printf("Error: Case not found: %v\n", err)
+		fmt.Printf("Error importing document: %v\n", err)
 		os.Exit(1)
 	}
+	if duplicate {
+		fmt.Printf("Already imported as %s (%s), identical SHA-256 %s\n", doc.ID, doc.Title, doc.SHA256)
+		return
+	}
 
-	// Process the document
-	doc, err := document.ImportDocument(path, filepath.Join(app.workingDir, "documents"), app.documentService)
+	fmt.Printf("Document imported successfully. ID: %s, Type: %s",
+		doc.ID, document.GetDocumentTypeString(doc.Type))
+	if scores := doc.TopTypeScores(2); len(scores) > 0 {
+		fmt.Printf(" (%.0f%%", scores[0].Confidence*100)
+		if doc.Type == document.TypeUnknown {
+			fmt.Printf(" %s", document.GetDocumentTypeString(scores[0].Type))
+		}
+		if len(scores) > 1 && scores[1].Confidence >= 0.01 {
+			fmt.Printf(", next %s %.0f%%", document.GetDocumentTypeString(scores[1].Type), scores[1].Confidence*100)
+		}
+		fmt.Print(")")
+	}
+	fmt.Println()
+	if mismatch := doc.Metadata.CustomFields["ExtensionMismatch"]; mismatch != "" {
+		fmt.Printf("Warning: %s - possible concealment\n", mismatch)
+	}
+	if m := doc.Metadata; m.Author != "" {
+		fmt.Printf("Author: %s", m.Author)
+		if m.LastModifiedBy != "" {
+			fmt.Printf(", last modified by %s", m.LastModifiedBy)
+		}
+		if m.Revision > 0 {
+			fmt.Printf(", revision %d", m.Revision)
+		}
+		fmt.Println()
+	}
+	if len(doc.Pages) > 0 {
+		fmt.Printf("Pages: %d, text extracted with %s\n", len(doc.Pages), doc.Metadata.CustomFields["Extraction"])
+	}
+	if doc.MachineRead {
+		fmt.Printf("Machine-read by OCR: %s of %d pages, %d words, mean confidence %s%%\n",
+			doc.Metadata.CustomFields["OCR pages"], len(doc.Pages), len(doc.OCRWords), doc.Metadata.CustomFields["OCR confidence"])
+	}
+	if len(doc.Annotations) > 0 || len(doc.Attachments) > 0 {
+		fmt.Printf("Comments and tracked changes: %d, embedded files: %d\n", len(doc.Annotations), len(doc.Attachments))
+	}
+	fmt.Printf("Content preview: %s\n", preview(doc.Content, 150))
+
+	if len(doc.Entities) > 0 {
+		counts := make(map[entity.Kind]int)
+		for _, e := range doc.Entities {
+			counts[e.Kind]++
+		}
+		var found []string
+		for _, kind := range entity.Kinds {
+			if counts[kind] > 0 {
+				found = append(found, fmt.Sprintf("%s %d", kind, counts[kind]))
+			}
+		}
+		fmt.Printf("Entities found: %s (list with: investigator doc entities --id %s)\n", strings.Join(found, ", "), doc.ID)
+	}
+
+	if supersedes != "" {
+		if err := app.versionService.Supersede(supersedes, doc.ID); err != nil {
+			fmt.Printf("Error recording version: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Version %d, supersedes %s\n", doc.Version, supersedes)
+		return
+	}
+	matches, err := app.versionService.Duplicates(doc.ID)
 	if err != nil {
-		fmt.Printf("Error importing document: %v\n", err)
+		fmt.Printf("Error checking for duplicates: %v\n", err)
 		os.Exit(1)
 	}
+	for _, m := range matches {
+		fmt.Printf("Near-duplicate of %s (%s), %.0f%% similar\n", m.Document.ID, m.Document.Title, m.Similarity*100)
+	}
+	if len(matches) > 0 {
+		fmt.Printf("If it revises an earlier document: investigator doc supersede --id %s --previous %s\n", doc.ID, matches[0].Document.ID)
+	}
+}
 
-	// Set case ID
-	doc.CaseID = caseID
+func (app *InvestigatorApp) handleDocImportDir(dir, caseID, lang, include, exclude string, workers int, logPath string) {
+	caseID = app.importCase(caseID)
+	app.setOCRLanguages(lang)
 
-	// In a real implementation, save to repository
-	app.repo.documents[doc.ID] = doc
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	files, unreadable, err := document.BatchFiles(root, document.BatchOptions{Include: splitList(include), Exclude: splitList(exclude)})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
-	fmt.Printf("Document imported successfully. ID: %s, Type: %s\n",
-		doc.ID, document.GetDocumentTypeString(doc.Type))
-	fmt.Printf("Content preview: %s\n", preview(doc.Content, 150))
-}
+	// Each directory imported into a case has its own log
+	if logPath == "" {
+		sum := sha256.Sum256([]byte(root))
+		logPath = filepath.Join(app.workingDir, "imports",
+			fmt.Sprintf("%s-%s-%s.jsonl", caseID, filepath.Base(root), hex.EncodeToString(sum[:4])))
+	}
+	if abs, err := filepath.Abs(logPath); err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil {
+			files = slices.DeleteFunc(files, func(f string) bool { return f == filepath.ToSlash(rel) })
+		}
+	}
+	importLog, err := document.OpenBatchLog(logPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer importLog.Close()
+	// Documents are kept in memory, so those of an earlier run are imported again
+	importLog.SetDocumentCheck(func(id string) bool {
+		app.importMu.Lock()
+		defer app.importMu.Unlock()
+		_, ok := app.repo.documents[id]
+		return ok
+	})
+
+	resumed := 0
+	for _, f := range files {
+		if importLog.Done(root, f) {
+			resumed++
+		}
+	}
+	fmt.Printf("Importing %d files from %s with %d workers, log %s\n", len(files), root, max(workers, 1), logPath)
+	if resumed > 0 {
+		fmt.Printf("Resuming: %d files were imported by an earlier run\n", resumed)
+	}
+	for _, e := range unreadable {
+		if err := importLog.Write(e); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  FAILED     %s: %s\n", e.Path, e.Error)
+	}
 
-func (app *InvestigatorApp) handleEvidenceAdd(description, evidenceType, caseID string) {
-	if description == "" {
-		fmt.Println("Error: Evidence description is required")
+	// Stop starting files on Ctrl-C; the files in progress are finished
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	done := resumed
+	err = document.ImportBatch(ctx, root, files, workers, importLog,
+		func(root, rel string) document.BatchEntry {
+			doc, duplicate, err := app.importDocument(filepath.Join(root, filepath.FromSlash(rel)), caseID)
+			if err != nil {
+				return document.BatchEntry{Status: document.BatchFailed, Error: err.Error()}
+			}
+			e := document.BatchEntry{DocumentID: doc.ID, SHA256: doc.SHA256, Type: document.GetDocumentTypeString(doc.Type)}
+			if duplicate {
+				e.Status = document.BatchDuplicate
+				return e
+			}
+			e.Status = document.BatchImported
+			app.importMu.Lock()
+			matches, err := app.versionService.Duplicates(doc.ID)
+			app.importMu.Unlock()
+			if err == nil && len(matches) > 0 {
+				e.NearDuplicateOf = matches[0].Document.ID
+			}
+			return e
+		},
+		func(e document.BatchEntry) {
+			done++
+			detail := e.DocumentID + " " + e.Type
+			switch {
+			case e.Status == document.BatchFailed:
+				detail = e.Error
+			case e.Status == document.BatchDuplicate:
+				detail = "same file as " + e.DocumentID
+			case e.NearDuplicateOf != "":
+				detail += ", near-duplicate of " + e.NearDuplicateOf
+			}
+			fmt.Printf("  [%d/%d] %-9s  %s  %s\n", done, len(files), e.Status, e.Path, detail)
+		})
+
+	s := importLog.Summary(files)
+	fmt.Printf("\nFiles: %d, imported: %d, duplicates: %d, failed: %d", s.Files, s.Imported, s.Duplicates, s.Failed+len(unreadable))
+	if s.NearDuplicates > 0 {
+		fmt.Printf(", near-duplicates: %d", s.NearDuplicates)
+	}
+	if s.Pending > 0 {
+		fmt.Printf(", not yet imported: %d", s.Pending)
+	}
+	fmt.Println()
+	if len(s.ByType) > 0 {
+		types := make([]string, 0, len(s.ByType))
+		for t := range s.ByType {
+			types = append(types, t)
+		}
+		sort.Slice(types, func(i, j int) bool {
+			if s.ByType[types[i]] != s.ByType[types[j]] {
+				return s.ByType[types[i]] > s.ByType[types[j]]
+			}
+			return types[i] < types[j]
+		})
+		fmt.Println("Imported documents by type:")
+		for _, t := range types {
+			fmt.Printf("  %-24s %d\n", t, s.ByType[t])
+		}
+	}
+	if s.Failed+len(unreadable) > 0 {
+		fmt.Printf("Failed files are listed in the log and tried again when the import is resumed\n")
+	}
+
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Import interrupted: run the same command again to resume")
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Printf("Error importing directory: %v\n", err)
 		os.Exit(1)
 	}
+}
 
+// importCase returns the case to import documents into, the open case if
+// none is given
+func (app *InvestigatorApp) importCase(caseID string) string {
 	if caseID == "" {
 		if app.currentCaseID == "" {
 			fmt.Println("Error: No case specified and no case is currently open")
@@ -454,69 +1324,2047 @@ func (app *InvestigatorApp) handleEvidenceAdd(description, evidenceType, caseID
 	}
 
 	// Ensure the case exists
-	_, err := app.caseService.GetCase(caseID)
-	if err != nil {
+	if _, err := app.caseService.GetCase(caseID); err != nil {
 		fmt.Printf("Error: Case not found: %v\n", err)
 		os.Exit(1)
 	}
+	return caseID
+}
 
-	// Create evidence
-	e := &evidence.Evidence{
-		Description:    description,
-		CaseID:         caseID,
-		Type:           evidence.EvidenceType(strings.ToUpper(evidenceType)),
-		Status:         evidence.StatusCollected,
-		CollectedBy:    "Current User", // Would come from auth system
-		CollectionDate: time.Now(),
-		Location: evidence.Location{
-			Description: "Not specified",
-		},
-		StorageLocation: "Evidence Locker",
+// setOCRLanguages chooses the languages scanned pages and images are read in
+func (app *InvestigatorApp) setOCRLanguages(lang string) {
+	if lang == "" {
+		return
 	}
+	if app.ocrEngine == nil {
+		fmt.Println("Warning: tesseract is not installed, scanned pages and images will not be read")
+		return
+	}
+	app.ocrEngine.Languages = strings.FieldsFunc(lang, func(r rune) bool { return r == '+' || r == ',' })
+	if _, err := app.ocrEngine.LanguageList(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-	err = app.evidenceService.CreateEvidence(e)
+// importDocument imports a file into a case and extracts its entities. A
+// file already imported into the case is not imported again; the earlier
+// document is returned, with true. Safe to call from several goroutines.
+func (app *InvestigatorApp) importDocument(path, caseID string) (*document.Document, bool, error) {
+	// The same file is imported into a case only once
+	hash, err := document.HashFile(path)
 	if err != nil {
-		fmt.Printf("Error adding evidence: %v\n", err)
+		return nil, false, err
+	}
+	app.importMu.Lock()
+	existing, err := app.versionService.ExactDuplicate(caseID, hash)
+	app.importMu.Unlock()
+	if err != nil || existing != nil {
+		return existing, existing != nil, err
+	}
+
+	docDir := filepath.Join(app.workingDir, "documents")
+	if err := os.MkdirAll(docDir, 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create document directory: %w", err)
+	}
+	doc, err := document.ImportDocument(path, docDir, app.documentProcessors)
+	if err != nil {
+		return nil, false, err
+	}
+	doc.CaseID = caseID
+	doc.ExtractEntities(app.entityExtractor)
+
+	// Another worker may have imported the same file meanwhile
+	app.importMu.Lock()
+	defer app.importMu.Unlock()
+	if existing, err := app.versionService.ExactDuplicate(caseID, doc.SHA256); err != nil || existing != nil {
+		return existing, existing != nil, err
+	}
+
+	// In a real implementation, save to repository
+	app.repo.documents[doc.ID] = doc
+	return doc, false, nil
+}
+
+func (app *InvestigatorApp) handleDocSupersede(id, previous string) {
+	if id == "" || previous == "" {
+		fmt.Println("Error: Document ID and earlier version ID are required")
 		os.Exit(1)
 	}
+	if err := app.versionService.Supersede(previous, id); err != nil {
+		fmt.Printf("Error recording version: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s supersedes %s\n", id, previous)
+	app.handleDocVersions(id)
+}
 
-	fmt.Printf("Evidence added successfully. ID: %s\n", e.ID)
+func (app *InvestigatorApp) handleDocVersions(id string) {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	chain, err := app.versionService.Versions(id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Versions of %s:\n", id)
+	for i, doc := range chain {
+		marker := " "
+		if doc.ID == id {
+			marker = "*"
+		}
+		status := "current"
+		if doc.SupersededBy != "" {
+			status = "superseded by " + doc.SupersededBy
+		}
+		similarity := ""
+		if i > 0 {
+			similarity = fmt.Sprintf(", %.0f%% similar to v%d", document.Similarity(chain[i-1], doc)*100, i)
+		}
+		fmt.Printf("%s v%d  %s  %s  imported %s  SHA-256 %.12s  %s%s\n", marker, i+1, doc.ID, doc.Title,
+			doc.CreatedAt.Format("2006-01-02 15:04"), doc.SHA256, status, similarity)
+	}
+
+	matches, err := app.versionService.Duplicates(id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	inChain := make(map[string]bool)
+	for _, doc := range chain {
+		inChain[doc.ID] = true
+	}
+	printed := false
+	for _, m := range matches {
+		if inChain[m.Document.ID] {
+			continue
+		}
+		if !printed {
+			fmt.Println("Duplicates outside the version chain:")
+			printed = true
+		}
+		fmt.Printf("  %s  %s  %s, %.0f%% similar\n", m.Document.ID, m.Document.Title, m.Kind, m.Similarity*100)
+	}
 }
 
-func (app *InvestigatorApp) handleEvidenceList(caseID string) {
-	if caseID == "" {
-		if app.currentCaseID == "" {
-			fmt.Println("Error: No case specified and no case is currently open")
-			os.Exit(1)
+func (app *InvestigatorApp) handleDocProcessors() {
+	fmt.Println("Document processors, in the order they are tried:")
+	for _, p := range app.documentProcessors.Processors() {
+		status := "available"
+		if !p.Available() {
+			status = "unavailable, missing " + strings.Join(p.Missing, ", ")
+		} else if len(p.MissingOptional) > 0 {
+			status = "available, without " + strings.Join(p.MissingOptional, ", ")
+		}
+		handles := strings.Join(append(append([]string{}, p.FileTypes...), p.MIMETypes...), ", ")
+		if p.Fallback {
+			handles = "anything else"
+		}
+		fmt.Printf("  %-6s %s\n", p.Name, p.Description)
+		fmt.Printf("  %-6s Handles: %s\n", "", handles)
+		fmt.Printf("  %-6s Status: %s\n", "", status)
+	}
+	if app.ocrEngine != nil {
+		if languages, err := app.ocrEngine.InstalledLanguages(); err == nil {
+			fmt.Printf("OCR languages installed: %s\n", strings.Join(languages, ", "))
 		}
-		caseID = app.currentCaseID
 	}
+}
 
-	// Filter evidence by case ID
-	var items []*evidence.Evidence
-	for _, e := range app.repo.evidence {
-		if e.CaseID == caseID {
-			items = append(items, e)
+func (app *InvestigatorApp) handleDocRedact(id string, start, end int, text, reason string, temporary bool) {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	redaction := document.Redaction{
+		StartPos:    start,
+		EndPos:      end,
+		Reason:      reason,
+		RedactedBy:  "Current User", // Would come from auth system
+		IsTemporary: temporary,
+	}
+
+	var added []document.Redaction
+	if text != "" {
+		var err error
+		if added, err = app.redactionService.RedactText(id, text, redaction); err != nil {
+			fmt.Printf("Error redacting document: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		r, err := app.redactionService.Redact(id, redaction)
+		if err != nil {
+			fmt.Printf("Error redacting document: %v\n", err)
+			os.Exit(1)
 		}
+		added = append(added, *r)
+	}
+
+	kind := "Permanent"
+	if temporary {
+		kind = "Temporary"
+	}
+	fmt.Printf("%s redactions added to %s: %d\n", kind, id, len(added))
+	for _, r := range added {
+		fmt.Printf("  %d-%d\n", r.StartPos, r.EndPos)
+	}
+}
+
+func (app *InvestigatorApp) handleDocExport(id, viewerName, courtOrder, reviewedBy, outputDir string) {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	if viewerName == "" {
+		viewerName = "Current User" // Would come from auth system
+	}
+	if outputDir == "" {
+		outputDir = filepath.Join(app.workingDir, "redacted")
+	}
+
+	export, err := app.redactionService.Export(id, document.Viewer{Name: viewerName, CourtOrder: courtOrder, ReviewedBy: reviewedBy}, outputDir)
+	if err != nil {
+		fmt.Printf("Error exporting document: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Redacted copy of %s for %s: %d redactions applied", export.DocumentID, viewerName, export.Applied)
+	if export.Lifted > 0 {
+		fmt.Printf(", %d temporary redactions lifted under %s, checked by %s", export.Lifted, courtOrder, reviewedBy)
+	}
+	fmt.Println()
+	fmt.Printf("  Text: %s\n        SHA-256 %s\n", export.TextPath, export.TextSHA256)
+	if export.PDFPath != "" {
+		fmt.Printf("  PDF:  %s\n        SHA-256 %s\n", export.PDFPath, export.PDFSHA256)
+	}
+}
+
+func (app *InvestigatorApp) handleDocRedactions(id string) {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	doc, ok := app.repo.documents[id]
+	if !ok {
+		fmt.Printf("Error: Document not found: %s\n", id)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Redactions of %s (%d):\n", id, len(doc.Redactions))
+	for _, r := range doc.Redactions {
+		kind := "permanent"
+		if r.IsTemporary {
+			kind = "temporary"
+		}
+		page := ""
+		if p := doc.PageAt(r.StartPos); p > 0 {
+			page = fmt.Sprintf(" page %d", p)
+		}
+		fmt.Printf("  %d-%d%s  %s  %s by %s on %s\n", r.StartPos, r.EndPos, page, kind, r.Reason, r.RedactedBy, r.RedactedAt.Format("2006-01-02 15:04"))
+	}
+
+	entries, err := app.redactionService.Log(id)
+	if err != nil {
+		fmt.Printf("Error reading redaction log: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\nRedaction log:")
+	for _, e := range entries {
+		fmt.Printf("  %s  %-8s %s", e.Timestamp.Format("2006-01-02 15:04:05"), e.Action, e.User)
+		if e.Authorization != "" {
+			fmt.Printf(" (court order %s, checked by %s)", e.Authorization, e.ReviewedBy)
+		}
+		if e.Action != document.RedactionExported {
+			fmt.Printf("  %d-%d", e.StartPos, e.EndPos)
+		}
+		if e.Reason != "" {
+			fmt.Printf("  %s", e.Reason)
+		}
+		fmt.Printf("  %s\n", e.Detail)
+	}
+
+	if len(doc.Proposals) > 0 {
+		fmt.Println("\nProposed redactions:")
+		printProposals(doc.Content, doc.Proposals, doc.PageAt)
+	}
+}
+
+func (app *InvestigatorApp) handleDocPII(id string) {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	doc, ok := app.repo.documents[id]
+	if !ok {
+		fmt.Printf("Error: Document not found: %s\n", id)
+		os.Exit(1)
+	}
+
+	added, err := app.redactionService.ProposeRedactions(id, pii.NewDetector(app.protectedNames(doc.CaseID)))
+	if err != nil {
+		fmt.Printf("Error scanning document: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Personal information found in %s: %d new proposals\n", id, len(added))
+	printProposals(doc.Content, added, doc.PageAt)
+	if len(added) > 0 {
+		fmt.Printf("\nReview with: investigator doc review --id %s --proposal <ids> --accept | --reject\n", id)
+	}
+}
+
+func (app *InvestigatorApp) handleDocReview(id, proposals string, accept, reject bool) {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	ids := reviewArgs(proposals, accept, reject)
+	for _, proposalID := range ids {
+		p, err := app.redactionService.ReviewProposal(id, proposalID, accept, "Current User") // Would come from auth system
+		if err != nil {
+			fmt.Printf("Error reviewing proposal %s: %v\n", proposalID, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Proposal %s %s: %d-%d %s\n", p.ID, strings.ToLower(string(p.Status)), p.StartPos, p.EndPos, p.Reason)
+	}
+}
+
+func (app *InvestigatorApp) handleDocRelabel(id, typeName string) {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	doc, ok := app.repo.documents[id]
+	if !ok {
+		fmt.Printf("Error: Document not found: %s\n", id)
+		os.Exit(1)
+	}
+	docType, err := document.ParseDocumentType(typeName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		var names []string
+		for _, t := range document.DocumentTypes() {
+			names = append(names, document.GetDocumentTypeString(t))
+		}
+		fmt.Printf("Document types: %s\n", strings.Join(names, ", "))
+		os.Exit(1)
+	}
+
+	previous := doc.Type
+	doc.Type = docType
+	doc.TypeRelabeledBy = "Current User" // Would come from auth system
+	doc.ModifiedAt = time.Now()
+	fmt.Printf("%s relabeled from %s to %s\n", id, document.GetDocumentTypeString(previous), document.GetDocumentTypeString(docType))
+	fmt.Println("Retrain the classifier with: investigator doc classifier train")
+}
+
+func (app *InvestigatorApp) handleClassifierTrain() {
+	docs := app.documentList()
+	relabeled := len(document.RelabeledSamples(docs))
+	c := document.TrainClassifier(docs)
+
+	path := app.classifierPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Printf("Error creating model directory: %v\n", err)
+		os.Exit(1)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Error saving classifier: %v\n", err)
+		os.Exit(1)
+	}
+	if err := c.Save(f); err != nil {
+		f.Close()
+		fmt.Printf("Error saving classifier: %v\n", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Printf("Error saving classifier: %v\n", err)
+		os.Exit(1)
+	}
+	app.documentProcessors.SetClassifier(c)
+
+	// Reclassify the documents not relabeled by hand
+	changed := 0
+	for _, doc := range docs {
+		previous := doc.Type
+		c.Classify(doc)
+		if doc.Type != previous {
+			changed++
+		}
+	}
+	fmt.Printf("Classifier trained on %d baseline samples and %d relabeled documents, saved to %s\n",
+		len(document.BaselineSamples()), relabeled, path)
+	fmt.Printf("Documents reclassified: %d of %d changed type\n", changed, len(docs))
+}
+
+func (app *InvestigatorApp) handleClassifierEvaluate(folds int, output string) {
+	docs := app.documentList()
+	var b strings.Builder
+	document.EvaluateClassifier(docs, folds).WriteText(&b)
+	if len(document.RelabeledSamples(docs)) > 0 {
+		b.WriteString("\nBaseline classifier on documents relabeled by users\n")
+		document.EvaluateBaseline(docs).WriteText(&b)
+	}
+
+	if output == "" {
+		fmt.Print(b.String())
+		return
+	}
+	if err := os.WriteFile(output, []byte(b.String()), 0644); err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Evaluation report written to %s\n", output)
+}
+
+// classifierPath is where the retrained document classifier is kept
+func (app *InvestigatorApp) classifierPath() string {
+	return filepath.Join(app.workingDir, "models", "document-classifier.json")
+}
+
+// documentList returns the imported documents, oldest first
+func (app *InvestigatorApp) documentList() []*document.Document {
+	docs := make([]*document.Document, 0, len(app.repo.documents))
+	for _, doc := range app.repo.documents {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].CreatedAt.Before(docs[j].CreatedAt) })
+	return docs
+}
+
+func (app *InvestigatorApp) handleDocEntities(id, kinds string) {
+	doc := app.entityDocument(id)
+	wanted := make(map[entity.Kind]bool)
+	for _, kind := range strings.Split(kinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			wanted[entity.Kind(strings.ToUpper(kind))] = true
+		}
+	}
+
+	fmt.Printf("Entities in %s:\n", id)
+	for _, e := range doc.Entities {
+		if len(wanted) > 0 && !wanted[e.Kind] {
+			continue
+		}
+		page := ""
+		if n := doc.PageAt(e.Start); n > 0 {
+			page = fmt.Sprintf(" page %d", n)
+		}
+		fmt.Printf("  %-4s %-8s %3.0f%%  %d-%d%s  %q", e.ID, e.Kind, e.Confidence*100, e.Start, e.End, page, e.Text)
+		if e.Value != e.Text {
+			fmt.Printf("  %s", e.Value)
+		}
+		if e.Kind == entity.KindPerson {
+			if p, err := app.caseService.FindPerson(doc.CaseID, "", e.Value); err == nil && p != nil {
+				fmt.Printf("  (in case as %s)", p.ID)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+func (app *InvestigatorApp) handleDocPerson(id, entityID, details, role string) {
+	doc := app.entityDocument(id)
+	if entityID == "" {
+		fmt.Println("Error: Entity ID is required")
+		os.Exit(1)
+	}
+	name, err := doc.FindEntities(entityID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	extra, err := doc.FindEntities(splitList(details)...)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if role != "" {
+		role = strings.ToUpper(role[:1]) + strings.ToLower(role[1:])
+	}
+	p, err := app.caseService.AddPersonFromEntities(doc.CaseID, role, doc.ID, name[0], extra)
+	if err != nil {
+		fmt.Printf("Error adding person: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s %s added to case %s. ID: %s\n", p.Role, p.FullName, doc.CaseID, p.ID)
+}
+
+func (app *InvestigatorApp) handleDocEvent(id, entityID, with, description string) {
+	doc := app.entityDocument(id)
+	if entityID == "" {
+		fmt.Println("Error: Entity ID is required")
+		os.Exit(1)
+	}
+	date, err := doc.FindEntities(entityID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	related, err := doc.FindEntities(splitList(with)...)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if description == "" {
+		description = entity.Sentence(doc.Content, date[0].Start, date[0].End)
+	}
+
+	e, err := app.caseService.AddEventFromEntities(doc.CaseID, doc.ID, description, "Current User", date[0], related) // Would come from auth system
+	if err != nil {
+		fmt.Printf("Error adding event: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Event added to the timeline of case %s. ID: %s\n", doc.CaseID, e.ID)
+	fmt.Printf("  %s  %s\n", e.Timestamp.Format("2006-01-02 15:04"), e.Description)
+}
+
+// entityDocument returns a document with its entities extracted
+func (app *InvestigatorApp) entityDocument(id string) *document.Document {
+	if id == "" {
+		fmt.Println("Error: Document ID is required")
+		os.Exit(1)
+	}
+	doc, ok := app.repo.documents[id]
+	if !ok {
+		fmt.Printf("Error: Document not found: %s\n", id)
+		os.Exit(1)
+	}
+	if doc.Entities == nil {
+		doc.ExtractEntities(app.entityExtractor)
+	}
+	return doc
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// protectedNames returns the names of the protected persons of a case
+func (app *InvestigatorApp) protectedNames(caseID string) []string {
+	if caseID == "" {
+		return nil
+	}
+	c, err := app.caseService.GetCase(caseID)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, people := range [][]casemanagement.Person{c.Victims, c.Suspects, c.Witnesses} {
+		for _, p := range people {
+			if p.IsProtected && p.FullName != "" {
+				names = append(names, p.FullName)
+			}
+		}
+	}
+	return names
+}
+
+// reviewArgs checks the flags of a review command and returns the proposal
+// IDs
+func reviewArgs(proposals string, accept, reject bool) []string {
+	if accept == reject {
+		fmt.Println("Error: Either --accept or --reject is required")
+		os.Exit(1)
+	}
+	ids := splitList(proposals)
+	if len(ids) == 0 {
+		fmt.Println("Error: Proposal IDs are required")
+		os.Exit(1)
+	}
+	return ids
+}
+
+// printProposals lists proposed redactions with the text they cover
+func printProposals(content string, proposals []document.RedactionProposal, pageAt func(int) int) {
+	for _, p := range proposals {
+		page := ""
+		if n := pageAt(p.StartPos); n > 0 {
+			page = fmt.Sprintf(" page %d", n)
+		}
+		fmt.Printf("  %-4s %-15s %3.0f%%  %d-%d%s  %q  %s", p.ID, p.Kind, p.Confidence*100, p.StartPos, p.EndPos, page, content[p.StartPos:p.EndPos], p.Reason)
+		if p.Status != document.ProposalPending {
+			fmt.Printf("  %s by %s", p.Status, p.ReviewedBy)
+		}
+		fmt.Println()
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceAdd(description, evidenceType, caseID, location, bioType, conditions, expires, filePath string, expand bool) {
+	if description == "" {
+		fmt.Println("Error: Evidence description is required")
+		os.Exit(1)
+	}
+
+	if caseID == "" {
+		if app.currentCaseID == "" {
+			fmt.Println("Error: No case specified and no case is currently open")
+			os.Exit(1)
+		}
+		caseID = app.currentCaseID
+	}
+
+	// Ensure the case exists
+	_, err := app.caseService.GetCase(caseID)
+	if err != nil {
+		fmt.Printf("Error: Case not found: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create evidence
+	e := &evidence.Evidence{
+		Description:    description,
+		CaseID:         caseID,
+		Type:           evidence.EvidenceType(strings.ToUpper(evidenceType)),
+		Status:         evidence.StatusCollected,
+		CollectedBy:    "Current User", // Would come from auth system
+		CollectionDate: time.Now(),
+		Location: evidence.Location{
+			Description: "Not specified",
+		},
+		StorageLocation: location,
+	}
+
+	if e.Type == evidence.TypeBiological {
+		b := &evidence.BiologicalEvidence{
+			Evidence:          *e,
+			BiologicalType:    strings.ToUpper(bioType),
+			StorageConditions: conditions,
+		}
+		if expires != "" {
+			b.ExpirationDate, err = time.Parse("2006-01-02", expires)
+			if err != nil {
+				fmt.Printf("Error: Invalid expiration date: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		err = app.biologicalMonitor.CreateBiologicalEvidence(b)
+		e = &b.Evidence
+	} else if filePath != "" {
+		d := &evidence.DigitalEvidence{Evidence: *e, FilePath: filePath}
+		if err = app.evidenceService.CreateDigitalEvidence(d); err == nil {
+			e = &d.Evidence
+			if expand {
+				defer app.expandArchive(d, "", archive.DefaultLimits())
+			}
+			defer app.reportEmbeddedMetadata(d)
+		}
+	} else {
+		err = app.evidenceService.CreateEvidence(e)
+	}
+	if err != nil {
+		fmt.Printf("Error adding evidence: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Evidence added successfully. ID: %s\n", e.ID)
+}
+
+// reportEmbeddedMetadata prints the type and metadata read from a digital
+// evidence file and places captured photos and videos on the case timeline
+func (app *InvestigatorApp) reportEmbeddedMetadata(d *evidence.DigitalEvidence) {
+	fmt.Printf("File type: %s (%s)\n", d.FileType, d.MIMEType)
+	if d.TypeMismatch {
+		fmt.Printf("Warning: file extension does not match its content - possible concealment\n")
+	}
+
+	if d.Metadata["MetadataFormat"] == "" {
+		if msg := d.Metadata["MetadataError"]; msg != "" {
+			fmt.Printf("Warning: could not read embedded metadata: %s\n", msg)
+		}
+		return
+	}
+
+	fmt.Printf("Embedded %s metadata:\n", d.Metadata["MetadataFormat"])
+	for _, key := range []string{"Make", "Model", "Software", "CaptureTime", "CaptureTimeZone", "Orientation", "GPS"} {
+		if value := d.Metadata[key]; value != "" {
+			fmt.Printf("  %-12s %s\n", key+":", value)
+		}
+	}
+
+	if _, ok := d.CaptureTime(); ok {
+		if err := app.evidenceService.PlaceOnTimeline(d, &caseEventWriter{caseService: app.caseService}); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			return
+		}
+		fmt.Println("Capture time added to the case timeline")
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceMetadata(filePath string) {
+	if filePath == "" {
+		fmt.Println("Error: A file path is required")
+		os.Exit(1)
+	}
+
+	m, err := metadata.ExtractFile(filePath)
+	if err != nil {
+		fmt.Printf("Error reading metadata: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nMetadata for %s (%s):\n", filePath, m.Format)
+	fmt.Println("-------------------------------------------------")
+	if m.Make != "" || m.Model != "" {
+		fmt.Printf("Camera:       %s %s\n", m.Make, m.Model)
+	}
+	if m.Software != "" {
+		fmt.Printf("Software:     %s\n", m.Software)
+	}
+	if !m.CaptureTime.IsZero() {
+		if m.CaptureTimeZoneless {
+			fmt.Printf("Captured:     %s (no time zone recorded; read as local time)\n", m.CaptureTime.Format(time.RFC3339))
+		} else {
+			fmt.Printf("Captured:     %s\n", m.CaptureTime.Format(time.RFC3339))
+		}
+	}
+	if m.Orientation != 0 {
+		fmt.Printf("Orientation:  %d\n", m.Orientation)
+	}
+	if m.GPS != nil {
+		fmt.Printf("GPS:          %s\n", m.GPS)
+		if m.GPS.HasAltitude {
+			fmt.Printf("Altitude:     %.1f m\n", m.GPS.Altitude)
+		}
+	}
+
+	names := make([]string, 0, len(m.Fields))
+	for name := range m.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("\nAll fields:")
+	for _, name := range names {
+		fmt.Printf("  %s: %s\n", name, m.Fields[name])
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceList(caseID string, hideKnown bool) {
+	if caseID == "" {
+		if app.currentCaseID == "" {
+			fmt.Println("Error: No case specified and no case is currently open")
+			os.Exit(1)
+		}
+		caseID = app.currentCaseID
+	}
+
+	// Filter evidence by case ID
+	var items []*evidence.Evidence
+	hidden := 0
+	for _, e := range app.repo.evidence {
+		if e.CaseID != caseID {
+			continue
+		}
+		if hideKnown && e.IsKnownGood() {
+			hidden++
+			continue
+		}
+		items = append(items, e)
+	}
+
+	if len(items) == 0 {
+		fmt.Printf("No evidence found for case: %s\n", caseID)
+		if hidden > 0 {
+			fmt.Printf("%d known-good files hidden\n", hidden)
+		}
+		return
+	}
+
+	fmt.Printf("\nEvidence for Case %s:\n", caseID)
+	fmt.Println("-------------------------------------------------")
+	fmt.Println("ID\t\tType\t\tStatus\tDescription")
+	fmt.Println("-------------------------------------------------")
+
+	for _, e := range items {
+		description := e.Description
+		if e.IsKnownBad() {
+			description += fmt.Sprintf(" [KNOWN BAD: %s]", strings.Join(e.KnownFileSets, ", "))
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n",
+			e.ID,
+			e.Type,
+			e.Status,
+			description)
+	}
+	if hidden > 0 {
+		fmt.Printf("\n%d known-good files hidden\n", hidden)
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceAcquire(path, description, caseID, manifestPath string) {
+	if path == "" {
+		fmt.Println("Error: Source path is required")
+		os.Exit(1)
+	}
+
+	if caseID == "" {
+		if app.currentCaseID == "" {
+			fmt.Println("Error: No case specified and no case is currently open")
+			os.Exit(1)
+		}
+		caseID = app.currentCaseID
+	}
+
+	// Ensure the case exists
+	_, err := app.caseService.GetCase(caseID)
+	if err != nil {
+		fmt.Printf("Error: Case not found: %v\n", err)
+		os.Exit(1)
+	}
+
+	if description == "" {
+		description = fmt.Sprintf("Acquisition of %s", filepath.Base(path))
+	}
+
+	parent := &evidence.DigitalEvidence{
+		Evidence: evidence.Evidence{
+			Description:    description,
+			CaseID:         caseID,
+			CollectedBy:    "Current User", // Would come from auth system
+			CollectionDate: time.Now(),
+			Location: evidence.Location{
+				Description: "Not specified",
+			},
+			StorageLocation: "Evidence Locker",
+		},
+	}
+
+	if manifestPath == "" {
+		manifestPath = filepath.Join(app.workingDir, "manifests",
+			fmt.Sprintf("%s-%s.xml", filepath.Base(path), time.Now().Format("20060102-150405")))
+	}
+
+	acq, err := app.evidenceService.AcquireSource(parent, path, manifestPath)
+	if err != nil {
+		fmt.Printf("Error acquiring evidence: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Evidence acquired successfully. ID: %s\n", parent.ID)
+	if acq.Type == evidence.AcquisitionRawImage {
+		fmt.Printf("Source: %s (%s), %d bytes in %d byte runs\n", path, acq.Type, parent.FileSize, len(acq.Files[0].ByteRuns))
+		fmt.Println("The files inside the image are not itemized; mount it read-only and acquire the mounted directory for a file listing")
+	} else {
+		fmt.Printf("Source: %s (%s), %d files, %d bytes\n", path, acq.Type, len(acq.Files), parent.FileSize)
+	}
+	fmt.Printf("Manifest: %s (SHA-256 %s)\n", manifestPath, parent.FileHash)
+	for _, e := range acq.Errors {
+		fmt.Printf("Warning: %s\n", e)
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceExpand(id, filePath, operator string, limits archive.Limits) {
+	if filePath == "" {
+		fmt.Println("Error: Archive file is required")
+		os.Exit(1)
+	}
+	d := app.digitalEvidence(id)
+	d.FilePath = filePath
+	app.expandArchive(d, operator, limits)
+}
+
+// expandArchive expands an archive into child evidence items and prints
+// what was extracted
+func (app *InvestigatorApp) expandArchive(d *evidence.DigitalEvidence, operator string, limits archive.Limits) {
+	opts := evidence.ExpansionOptions{
+		DestDir:  filepath.Join(app.workingDir, "extracted"),
+		Operator: operator,
+		Limits:   limits,
+	}
+	report, err := app.evidenceService.ExpandArchive(d, opts)
+	if report == nil {
+		fmt.Printf("Error expanding archive: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Expanded %s archive %s: %d files\n", report.Format, report.ContainerID, len(report.Files))
+	for _, f := range report.Files {
+		fmt.Printf("  %s%s  %s (%d bytes)\n", strings.Repeat("  ", f.Depth-1), f.EvidenceID, f.ArchivePath, f.Size)
+	}
+	for _, skipped := range report.Skipped {
+		fmt.Printf("Skipped: %s\n", skipped)
+	}
+	for _, e := range report.Errors {
+		fmt.Printf("Warning: %s\n", e)
+	}
+	if err != nil {
+		// Limit errors are already listed with the report
+		if !errors.Is(err, archive.ErrLimitExceeded) {
+			fmt.Printf("Error expanding archive: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceVerify(manifestPath, path, manifestHash string) {
+	if manifestPath == "" {
+		fmt.Println("Error: Manifest path is required")
+		os.Exit(1)
+	}
+
+	report, err := app.evidenceService.VerifyAcquisition(manifestPath, path, manifestHash)
+	if err != nil {
+		fmt.Printf("Error verifying evidence: %v\n", err)
+		os.Exit(1)
+	}
+	if report.ManifestModified() {
+		fmt.Printf("Manifest %s has been modified: SHA-256 %s, recorded %s\n",
+			manifestPath, report.ManifestSHA256, report.ExpectedManifestSHA256)
+		fmt.Println("The file hashes it lists cannot be trusted")
+		os.Exit(1)
+	}
+
+	mismatches := report.Mismatches()
+	fmt.Printf("Verified %d files from %s against %s\n", len(report.Results), report.SourcePath, manifestPath)
+	if len(mismatches) == 0 {
+		fmt.Println("All files match the manifest")
+		return
+	}
+
+	fmt.Println("-------------------------------------------------")
+	fmt.Println("Status\t\tPath\tDetail")
+	fmt.Println("-------------------------------------------------")
+	for _, m := range mismatches {
+		fmt.Printf("%s\t%s\t%s\n", m.Status, m.RelativePath, m.Detail)
+	}
+	os.Exit(1)
+}
+
+func (app *InvestigatorApp) handleEvidenceAudit(location, codeFile, codeList string, correct bool) {
+	if location == "" {
+		fmt.Println("Error: Storage location is required")
+		os.Exit(1)
+	}
+
+	var codes []string
+	if codeFile != "" {
+		f, err := os.Open(codeFile)
+		if err != nil {
+			fmt.Printf("Error opening code file: %v\n", err)
+			os.Exit(1)
+		}
+		codes, err = evidence.ReadAuditCodes(f)
+		f.Close()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, code := range strings.Split(codeList, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	report, err := app.evidenceService.AuditLocation(evidence.AuditRequest{
+		Location:         location,
+		ScannedCodes:     codes,
+		AuditedBy:        "Current User", // Would come from auth system
+		CorrectMisplaced: correct,
+	})
+	if err != nil {
+		fmt.Printf("Error auditing location: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nAudit %s of %s (%d codes scanned):\n", report.ID, report.Location, len(codes))
+	fmt.Println("-------------------------------------------------")
+	fmt.Println("Finding\t\tEvidence\tDescription\tDetail")
+	fmt.Println("-------------------------------------------------")
+	for _, item := range report.Items {
+		ref := item.EvidenceNumber
+		if ref == "" {
+			ref = item.EvidenceID
+		}
+		if ref == "" {
+			ref = item.Code
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", item.Finding, ref, item.Description, item.Detail)
+	}
+
+	fmt.Printf("\nPresent: %d, Missing: %d, Misplaced: %d, Unexpected: %d\n",
+		len(report.ByFinding(evidence.AuditPresent)),
+		len(report.ByFinding(evidence.AuditMissing)),
+		len(report.ByFinding(evidence.AuditMisplaced)),
+		len(report.ByFinding(evidence.AuditUnexpected)))
+}
+
+func (app *InvestigatorApp) handleEvidenceDerive(description, evidenceType, filePath string, req evidence.DerivationRequest, relation string) {
+	if description == "" {
+		fmt.Println("Error: Evidence description is required")
+		os.Exit(1)
+	}
+
+	var err error
+	req.Relation, err = evidence.ParseRelationType(relation)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	parent, err := app.evidenceService.GetEvidence(req.ParentID)
+	if err != nil {
+		fmt.Printf("Error: Parent evidence not found: %v\n", err)
+		os.Exit(1)
+	}
+
+	child := evidence.Evidence{
+		Description:     description,
+		Type:            parent.Type,
+		StorageLocation: parent.StorageLocation,
+		Location:        parent.Location,
+	}
+	if evidenceType != "" {
+		child.Type = evidence.EvidenceType(strings.ToUpper(evidenceType))
+	}
+
+	if filePath != "" {
+		d := &evidence.DigitalEvidence{Evidence: child, FilePath: filePath}
+		err = app.evidenceService.DeriveDigitalEvidence(d, req)
+		child = d.Evidence
+	} else {
+		err = app.evidenceService.DeriveEvidence(&child, req)
+	}
+	if err != nil {
+		fmt.Printf("Error deriving evidence: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Derived evidence added. ID: %s (%s %s)\n", child.ID,
+		strings.ToLower(strings.ReplaceAll(string(req.Relation), "_", " ")), parent.ID)
+	if child.FileHash != "" {
+		fmt.Printf("Hash: %s\n", child.FileHash)
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceLineage(id string) {
+	tree, err := app.evidenceService.Lineage(id)
+	if err != nil {
+		fmt.Printf("Error building lineage: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nLineage of %s:\n", id)
+	fmt.Println("-------------------------------------------------")
+	fmt.Print(evidence.FormatLineage(tree, id))
+}
+
+// secrets returns the secret manager, opening the encrypted vault on first use
+func (app *InvestigatorApp) secrets() *evidence.SecretManager {
+	if app.secretManager != nil {
+		return app.secretManager
+	}
+
+	key, err := hashicorp.MasterKeyFromEnvironment()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Generate a key with 'investigator evidence secret genkey' and export it as " + hashicorp.MasterKeyEnv)
+		os.Exit(1)
+	}
+	credentials, err := hashicorp.FromEnvironment()
+	if err != nil {
+		fmt.Printf("Error opening credential store: %v\n", err)
+		os.Exit(1)
+	}
+	vault, err := hashicorp.NewSecretStore(credentials, "evidence-secrets", key)
+	if err != nil {
+		fmt.Printf("Error opening secret vault: %v\n", err)
+		os.Exit(1)
+	}
+
+	secretRepo := &inMemorySecretRepo{
+		secrets:     app.repo.secrets,
+		access:      app.repo.secretAccess,
+		decryptions: app.repo.decryptions,
+	}
+	app.secretManager = evidence.NewSecretManager(app.evidenceService, vault, secretRepo)
+	return app.secretManager
+}
+
+// digitalEvidence loads a digital evidence item with its stored details
+func (app *InvestigatorApp) digitalEvidence(id string) *evidence.DigitalEvidence {
+	if id == "" {
+		fmt.Println("Error: Evidence ID is required")
+		os.Exit(1)
+	}
+	d, err := app.evidenceService.GetDigitalEvidence(id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return d
+}
+
+func (app *InvestigatorApp) handleSecretAdd(id, kind, valueFile, label, source, user string) {
+	d := app.digitalEvidence(id)
+
+	value, err := readSecretValue(valueFile)
+	if err != nil {
+		fmt.Printf("Error reading secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	secret, err := app.secrets().AddSecret(d, evidence.SecretKind(strings.ToUpper(kind)), value, label, source, user)
+	if err != nil {
+		fmt.Printf("Error storing secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Secret stored encrypted. Secret ID: %s (evidence %s)\n", secret.ID, d.ID)
+}
+
+// readSecretValue reads a password or key from a file, from standard input
+// ("-"), or from a prompt that does not echo it. Secrets are never taken on
+// the command line, where other users and the shell history would see them.
+func readSecretValue(path string) (string, error) {
+	if path != "" && path != "-" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		// Key files are kept as they are, apart from a final line break
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	info, err := os.Stdin.Stat()
+	terminal := err == nil && info.Mode()&os.ModeCharDevice != 0
+	if path == "" && terminal {
+		fmt.Print("Password or key: ")
+		if err := stty("-echo"); err == nil {
+			defer func() {
+				stty("echo")
+				fmt.Println()
+			}()
+		}
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// stty changes the settings of the terminal on standard input
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+func (app *InvestigatorApp) handleSecretReveal(secretID, user, reason string) {
+	value, err := app.secrets().RevealSecret(secretID, user, reason)
+	if err != nil {
+		fmt.Printf("Error revealing secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Access logged for %s\n", user)
+	fmt.Println(value)
+}
+
+func (app *InvestigatorApp) handleSecretLog(id string) {
+	d := app.digitalEvidence(id)
+	manager := app.secrets()
+
+	secrets, err := manager.SecretsFor(d.ID)
+	if err != nil {
+		fmt.Printf("Error listing secrets: %v\n", err)
+		os.Exit(1)
+	}
+	log, err := manager.AccessLog(d.ID)
+	if err != nil {
+		fmt.Printf("Error reading access log: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nSecrets for %s:\n", d.ID)
+	fmt.Println("-------------------------------------------------")
+	for _, s := range secrets {
+		fmt.Printf("%s\t%s\t%s\t(added by %s)\n", s.ID, s.Kind, s.Label, s.CreatedBy)
+	}
+	if record, err := manager.Decryption(d.ID); err == nil {
+		fmt.Printf("\nDecrypted with %s by %s on %s (%s)\n", record.SecretID, record.DecryptedBy,
+			record.Timestamp.Format("2006-01-02 15:04"), record.Method)
+	}
+
+	fmt.Println("\nAccess log:")
+	fmt.Println("-------------------------------------------------")
+	for _, a := range log {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n", a.Timestamp.Format("2006-01-02 15:04:05"),
+			a.User, a.Action, a.SecretID, a.Reason)
+	}
+}
+
+func (app *InvestigatorApp) handleSecretGenKey() {
+	key, err := hashicorp.GenerateMasterKey()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("export %s=%s\n", hashicorp.MasterKeyEnv, key)
+}
+
+func (app *InvestigatorApp) handleEvidenceDecrypt(id, secretID, user, method string, succeeded bool) {
+	d := app.digitalEvidence(id)
+
+	if err := app.secrets().RecordDecryptionAttempt(d, secretID, user, method, succeeded); err != nil {
+		fmt.Printf("Error recording decryption: %v\n", err)
+		os.Exit(1)
+	}
+
+	if succeeded {
+		fmt.Printf("Evidence %s recorded as decrypted with secret %s\n", d.ID, secretID)
+	} else {
+		fmt.Printf("Failed decryption attempt with secret %s recorded\n", secretID)
+	}
+}
+
+func (app *InvestigatorApp) handleLabSubmit(id, lab, exams, officer, priority, due, notes string) {
+	if id == "" {
+		fmt.Println("Error: Evidence ID is required")
+		os.Exit(1)
+	}
+
+	p, err := evidence.ParseLabPriority(priority)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sub := evidence.LabSubmission{
+		Lab:         lab,
+		SubmittedBy: officer,
+		Priority:    p,
+		Notes:       notes,
+	}
+	for _, exam := range strings.Split(exams, ",") {
+		if exam = strings.TrimSpace(exam); exam != "" {
+			sub.Exams = append(sub.Exams, exam)
+		}
+	}
+	if due != "" {
+		sub.DueDate, err = time.Parse("2006-01-02", due)
+		if err != nil {
+			fmt.Printf("Error: Invalid due date (expected YYYY-MM-DD): %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	request, err := app.labService.SubmitToLab(id, sub)
+	if err != nil {
+		fmt.Printf("Error submitting to lab: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Lab request created. ID: %s (%s, %s)\n", request.ID, request.Lab, request.Priority)
+}
+
+func (app *InvestigatorApp) handleLabShip(requestID, by, carrier, tracking string) {
+	if err := app.labService.ShipToLab(requestID, by, carrier, tracking); err != nil {
+		fmt.Printf("Error recording shipment: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Shipment of lab request %s recorded in the chain of custody\n", requestID)
+}
+
+func (app *InvestigatorApp) handleLabStatus(requestID, status, by, note string) {
+	s := evidence.LabStatus(strings.ToUpper(status))
+
+	var err error
+	if s == evidence.LabCancelled {
+		err = app.labService.CancelLabRequest(requestID, by, note)
+	} else {
+		err = app.labService.UpdateLabStatus(requestID, s, by, note)
+	}
+	if err != nil {
+		fmt.Printf("Error updating lab request: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Lab request %s is now %s\n", requestID, s)
+}
+
+func (app *InvestigatorApp) handleLabResults(requestID, filePath, summary, by string) {
+	if filePath == "" {
+		fmt.Println("Error: Report file is required")
+		os.Exit(1)
+	}
+
+	report, err := app.labService.ImportResults(requestID, filePath, summary, by)
+	if err != nil {
+		fmt.Printf("Error importing lab results: %v\n", err)
+		os.Exit(1)
+	}
+
+	request, _ := app.labService.GetLabRequest(requestID)
+	fmt.Printf("Lab report imported as document %s\n", report.DocumentID)
+	if request != nil && request.ResultsSummary != "" {
+		fmt.Printf("Results: %s\n", request.ResultsSummary)
+	}
+}
+
+func (app *InvestigatorApp) handleLabReturn(requestID, by, location, notes string) {
+	if err := app.labService.ReturnFromLab(requestID, by, location, notes); err != nil {
+		fmt.Printf("Error recording return: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Return of lab request %s to %s recorded in the chain of custody\n", requestID, location)
+}
+
+func (app *InvestigatorApp) handleLabList(id string, overdue bool) {
+	var requests []*evidence.LabRequest
+	var err error
+	switch {
+	case overdue:
+		requests, err = app.labService.Overdue(time.Now())
+	case id != "":
+		requests, err = app.labService.RequestsFor(id)
+	default:
+		fmt.Println("Error: Evidence ID or --overdue is required")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error listing lab requests: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(requests) == 0 {
+		fmt.Println("No lab requests found")
+		return
+	}
+
+	fmt.Println("\nLab Requests:")
+	fmt.Println("-------------------------------------------------")
+	for _, r := range requests {
+		due := "-"
+		if !r.DueDate.IsZero() {
+			due = r.DueDate.Format("2006-01-02")
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\tdue %s\t%s\n", r.ID, r.EvidenceID, r.Lab, r.Status, r.Priority,
+			due, strings.Join(r.Exams, ", "))
+		if r.ResultsSummary != "" {
+			fmt.Printf("\tResults: %s\n", r.ResultsSummary)
+		}
+	}
+}
+
+func (app *InvestigatorApp) handleEmailImport(path, id, caseID, description string, timeline bool) {
+	if path == "" {
+		fmt.Println("Error: Email path is required")
+		os.Exit(1)
+	}
+	format, err := email.DetectFormat(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var source *evidence.DigitalEvidence
+	if id != "" {
+		source = app.digitalEvidence(id)
+		source.FilePath = path
+	} else {
+		source = app.newEmailSource(path, format, caseID, description)
+	}
+
+	report, err := app.emailService.ImportEmail(source, evidence.EmailImportOptions{
+		DestDir:  filepath.Join(app.workingDir, "extracted"),
+		Timeline: timeline,
+	})
+	if report == nil {
+		fmt.Printf("Error processing email: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Processed %s email in %s: %d messages, %d attachments\n",
+		report.Format, report.EvidenceID, len(report.Messages), report.Attachments)
+	if report.TimelineEvents > 0 {
+		fmt.Printf("%d messages added to the case timeline\n", report.TimelineEvents)
+	}
+	if len(report.Participants) > 0 {
+		fmt.Println("\nParticipants:")
+		for _, p := range report.Participants {
+			person := ""
+			if p.PersonID != "" {
+				person = "  person " + p.PersonID
+			}
+			fmt.Printf("  %-40s %4d messages%s\n", p.Address.String(), p.Messages, person)
+		}
+	}
+	for _, e := range report.Errors {
+		fmt.Printf("Warning: %s\n", e)
+	}
+	if err != nil {
+		fmt.Printf("Error processing email: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newEmailSource records an email file or Maildir as a new evidence item
+func (app *InvestigatorApp) newEmailSource(path string, format email.Format, caseID, description string) *evidence.DigitalEvidence {
+	if description == "" {
+		description = fmt.Sprintf("Email (%s) %s", format, filepath.Base(path))
+	}
+	// A Maildir is a directory, so it is recorded without a file hash
+	dirType := ""
+	if format == email.FormatMaildir {
+		dirType = string(format)
+	}
+	return app.newSourceEvidence(path, caseID, description, dirType)
+}
+
+// newSourceEvidence records a file, or a directory of the given type, as a
+// new digital evidence item in a case
+func (app *InvestigatorApp) newSourceEvidence(path, caseID, description, dirType string) *evidence.DigitalEvidence {
+	if caseID == "" {
+		if app.currentCaseID == "" {
+			fmt.Println("Error: No case specified and no case is currently open")
+			os.Exit(1)
+		}
+		caseID = app.currentCaseID
+	}
+	if _, err := app.caseService.GetCase(caseID); err != nil {
+		fmt.Printf("Error: Case not found: %v\n", err)
+		os.Exit(1)
+	}
+	source := &evidence.DigitalEvidence{
+		Evidence: evidence.Evidence{
+			Description:    description,
+			CaseID:         caseID,
+			Type:           evidence.TypeDigital,
+			CollectedBy:    "Current User", // Would come from auth system
+			CollectionDate: time.Now(),
+			Location: evidence.Location{
+				Description: "Not specified",
+			},
+			StorageLocation: "Evidence Locker",
+		},
+		FilePath: path,
+	}
+
+	var err error
+	if dirType != "" {
+		source.FileType = dirType
+		if err = app.evidenceService.CreateEvidence(&source.Evidence); err == nil {
+			err = app.evidenceService.UpdateDigitalEvidence(source)
+		}
+	} else {
+		err = app.evidenceService.CreateDigitalEvidence(source)
+	}
+	if err != nil {
+		fmt.Printf("Error adding evidence: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Evidence added successfully. ID: %s\n", source.ID)
+	return source
+}
+
+func (app *InvestigatorApp) handleEmailList(id string) {
+	if id == "" {
+		fmt.Println("Error: Evidence ID is required")
+		os.Exit(1)
+	}
+	messages, err := app.emailService.Messages(id)
+	if err != nil {
+		fmt.Printf("Error listing messages: %v\n", err)
+		os.Exit(1)
+	}
+	if len(messages) == 0 {
+		fmt.Printf("No messages found for evidence: %s\n", id)
+		return
+	}
+
+	fmt.Printf("\nMessages in %s:\n", id)
+	fmt.Println("-------------------------------------------------")
+	fmt.Println("ID\t\tDate\t\t\tFrom\tSubject")
+	fmt.Println("-------------------------------------------------")
+	for _, m := range messages {
+		date := "-"
+		if !m.Date.IsZero() {
+			date = m.Date.Format("2006-01-02 15:04 MST")
+		}
+		from := "-"
+		if len(m.From) > 0 {
+			from = m.From[0].Address
+		}
+		subject := m.Subject
+		if n := len(m.Attachments); n > 0 {
+			subject += fmt.Sprintf(" [%d attachments]", n)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", m.ID, date, from, subject)
+	}
+}
+
+func (app *InvestigatorApp) handleEmailShow(id string, allHeaders, html bool) {
+	if id == "" {
+		fmt.Println("Error: Message ID is required")
+		os.Exit(1)
+	}
+	m, err := app.emailService.GetEmail(id)
+	if err != nil {
+		fmt.Printf("Error: Message not found: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nMessage %s (%s in %s)\n", m.ID, m.Source, m.EvidenceID)
+	fmt.Println("-------------------------------------------------")
+	if allHeaders {
+		for _, h := range m.Headers {
+			fmt.Printf("%s: %s\n", h.Name, h.Value)
+		}
+	} else {
+		for _, field := range []struct {
+			name      string
+			addresses []email.Address
+		}{{"From", m.From}, {"To", m.To}, {"Cc", m.Cc}, {"Bcc", m.Bcc}, {"Reply-To", m.ReplyTo}} {
+			if len(field.addresses) == 0 {
+				continue
+			}
+			var list []string
+			for _, a := range field.addresses {
+				list = append(list, a.String())
+			}
+			fmt.Printf("%s: %s\n", field.name, strings.Join(list, ", "))
+		}
+		if !m.Date.IsZero() {
+			fmt.Printf("Date: %s\n", m.Date.Format(time.RFC1123Z))
+		}
+		fmt.Printf("Subject: %s\n", m.Subject)
+		if m.MessageID != "" {
+			fmt.Printf("Message-ID: %s\n", m.MessageID)
+		}
+	}
+
+	if len(m.Received) > 0 {
+		fmt.Println("\nReceived chain (most recent first):")
+		for i, hop := range m.Received {
+			date := ""
+			if !hop.Date.IsZero() {
+				date = hop.Date.Format(time.RFC3339)
+			}
+			line := fmt.Sprintf("  %d. %s", i+1, date)
+			for _, clause := range []struct{ name, value string }{{"from", hop.From}, {"by", hop.By}, {"with", hop.With}} {
+				if clause.value != "" {
+					line += fmt.Sprintf("  %s %s", clause.name, clause.value)
+				}
+			}
+			fmt.Println(line)
+		}
+	}
+
+	body := m.TextBody
+	if html || body == "" {
+		body = m.HTMLBody
+	}
+	fmt.Printf("\n%s\n", strings.TrimRight(body, "\n"))
+
+	if len(m.Attachments) > 0 {
+		fmt.Println("\nAttachments:")
+		for _, a := range m.Attachments {
+			fmt.Printf("  %s  %s (%s, %d bytes)  SHA-256 %s\n", a.EvidenceID, a.Filename, a.ContentType, a.Size, a.SHA256)
+		}
+	}
+	if len(m.PersonIDs) > 0 {
+		fmt.Printf("\nCase persons: %s\n", strings.Join(m.PersonIDs, ", "))
+	}
+	for _, w := range m.Warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+}
+
+func (app *InvestigatorApp) handleChatImport(path, id, caseID, description, tz, dateOrder string) {
+	if path == "" {
+		fmt.Println("Error: Chat export path is required")
+		os.Exit(1)
+	}
+	format, _, err := chat.Detect(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	order, err := chat.ParseDateOrder(dateOrder)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	location := time.Local
+	if tz != "" {
+		if location, err = time.LoadLocation(tz); err != nil {
+			fmt.Printf("Error: Invalid time zone: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var source *evidence.DigitalEvidence
+	if id != "" {
+		source = app.digitalEvidence(id)
+		source.FilePath = path
+	} else {
+		if description == "" {
+			description = fmt.Sprintf("Chat export (%s) %s", format, filepath.Base(path))
+		}
+		// An unpacked export is a directory, so it is recorded without a file hash
+		dirType := ""
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirType = "CHAT_EXPORT"
+		}
+		source = app.newSourceEvidence(path, caseID, description, dirType)
+	}
+
+	report, err := app.chatService.ImportChat(source, evidence.ChatImportOptions{
+		Location:  location,
+		DateOrder: order,
+	})
+	if report == nil {
+		fmt.Printf("Error processing chat export: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Processed %s chat export in %s: %d messages in %d conversations\n",
+		report.Platform, report.EvidenceID, report.Messages, len(report.Conversations))
+	fmt.Printf("Media linked as evidence: %d, missing from export: %d\n", report.Media, report.MissingMedia)
+	fmt.Println("\nConversations:")
+	for _, c := range report.Conversations {
+		period := "-"
+		if c.Messages > 0 {
+			period = fmt.Sprintf("%s to %s", c.First.Format("2006-01-02 15:04"), c.Last.Format("2006-01-02 15:04 MST"))
+		}
+		fmt.Printf("  %s: %d messages, %s\n", c.Title, c.Messages, period)
+		if len(c.Participants) > 0 {
+			fmt.Printf("    Participants: %s\n", strings.Join(c.Participants, ", "))
+		}
+		if c.AssumedZone != "" {
+			fmt.Printf("    Times without a zone in the export read as %s\n", c.AssumedZone)
+		}
+	}
+	for _, w := range report.Warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	if err != nil {
+		fmt.Printf("Error processing chat export: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (app *InvestigatorApp) handleChatSearch(caseID, query string) {
+	if caseID == "" {
+		caseID = app.currentCaseID
+	}
+	if caseID == "" || query == "" {
+		fmt.Println("Error: Case ID and query are required")
+		os.Exit(1)
+	}
+	messages, err := app.chatService.SearchChats(caseID, query)
+	if err != nil {
+		fmt.Printf("Error searching chats: %v\n", err)
+		os.Exit(1)
+	}
+	if len(messages) == 0 {
+		fmt.Printf("No chat messages match: %s\n", query)
+		return
+	}
+
+	fmt.Printf("\n%d chat messages match %q:\n", len(messages), query)
+	fmt.Println("-------------------------------------------------")
+	for _, m := range messages {
+		sender := m.Sender
+		if sender == "" {
+			sender = "-"
+		}
+		fmt.Printf("%s  %s  [%s] %s\n", m.Timestamp.Format("2006-01-02 15:04:05 MST"), m.EvidenceID, m.Conversation, sender)
+		fmt.Printf("    %s\n", strings.ReplaceAll(m.Text, "\n", "\n    "))
+	}
+}
+
+func (app *InvestigatorApp) handleChatView(caseID, id, format, output string) {
+	if caseID == "" && id == "" {
+		caseID = app.currentCaseID
+	}
+	if caseID == "" && id == "" {
+		fmt.Println("Error: Case ID or evidence ID is required")
+		os.Exit(1)
+	}
+	transcript, err := app.chatService.Transcript(caseID, id)
+	if err != nil {
+		fmt.Printf("Error building chat transcript: %v\n", err)
+		os.Exit(1)
+	}
+
+	format = strings.ToUpper(format)
+	if output == "" && format == string(evidence.TranscriptText) {
+		if err := transcript.Render(os.Stdout, evidence.TranscriptText); err != nil {
+			fmt.Printf("Error rendering chat transcript: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if output == "" {
+		reportDir := filepath.Join(app.workingDir, "reports")
+		os.MkdirAll(reportDir, 0755)
+		name := transcript.CaseID
+		if id != "" {
+			name = id
+		}
+		output = filepath.Join(reportDir, fmt.Sprintf("chat-%s.%s", name, strings.ToLower(format)))
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		fmt.Printf("Error creating transcript file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if err := transcript.Render(f, evidence.TranscriptFormat(format)); err != nil {
+		fmt.Printf("Error rendering chat transcript: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Chat transcript of %d conversations written to: %s\n", len(transcript.Threads), output)
+}
+
+// hashSets opens the hash set index and enables known-file classification
+func (app *InvestigatorApp) hashSets() *hashset.Index {
+	if app.hashIndex != nil {
+		return app.hashIndex
+	}
+	index, err := hashset.Open(filepath.Join(app.workingDir, "hashsets"))
+	if err != nil {
+		fmt.Printf("Error opening hash sets: %v\n", err)
+		os.Exit(1)
+	}
+	app.hashIndex = index
+	app.evidenceService.SetKnownFileIndex(index, &knownBadAlerter{caseService: app.caseService})
+	return app.hashIndex
+}
+
+func (app *InvestigatorApp) handleHashsetImport(filePath, name, kind string) {
+	if filePath == "" {
+		fmt.Println("Error: Hash set file is required")
+		os.Exit(1)
+	}
+	status, err := hashset.ParseStatus(kind)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	set, err := app.hashSets().Import(filePath, name, status)
+	if err != nil {
+		fmt.Printf("Error importing hash set: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Hash set %q imported as %s\n", set.Name, set.Kind)
+	for _, alg := range []hashset.Algorithm{hashset.MD5, hashset.SHA1, hashset.SHA256} {
+		if n := set.Hashes[alg]; n > 0 {
+			fmt.Printf("  %s: %d hashes\n", alg, n)
+		}
+	}
+	if set.Rejected > 0 {
+		fmt.Printf("Warning: %d lines had no recognisable hash\n", set.Rejected)
+	}
+}
+
+func (app *InvestigatorApp) handleHashsetList() {
+	sets := app.hashSets().Sets()
+	if len(sets) == 0 {
+		fmt.Println("No hash sets imported")
+		return
+	}
+
+	fmt.Println("\nHash Sets:")
+	fmt.Println("-------------------------------------------------")
+	for _, set := range sets {
+		total := 0
+		for _, n := range set.Hashes {
+			total += n
+		}
+		state := ""
+		if !set.Complete {
+			state = " (incomplete, ignored)"
+		}
+		fmt.Printf("%s\t%s\t%d hashes\t%s\t%s%s\n", set.Name, set.Kind, total,
+			set.ImportedAt.Format("2006-01-02"), set.Source, state)
+	}
+}
+
+func (app *InvestigatorApp) handleHashsetLookup(hash, filePath string) {
+	index := app.hashSets()
+
+	var match hashset.Match
+	var err error
+	var md5, sha1, sha256 string
+	switch {
+	case filePath != "":
+		match, err = app.evidenceService.CheckKnownFile(filePath)
+	case len(hash) == 32:
+		md5 = hash
+	case len(hash) == 40:
+		sha1 = hash
+	case len(hash) == 64:
+		sha256 = hash
+	default:
+		fmt.Println("Error: An MD5, SHA-1 or SHA-256 hash or a file is required")
+		os.Exit(1)
+	}
+
+	if filePath == "" {
+		match, err = index.Lookup(md5, sha1, sha256)
+	}
+	if err != nil {
+		fmt.Printf("Error looking up hash: %v\n", err)
+		os.Exit(1)
+	}
+	if match.Status == hashset.StatusUnknown {
+		fmt.Println("UNKNOWN: not found in any hash set")
+		return
+	}
+	fmt.Printf("%s: %s %s found in %s\n", match.Status, match.Algorithm, match.Hash, strings.Join(match.Sets, ", "))
+}
+
+func (app *InvestigatorApp) handleEvidenceMonitor(days int, tempLog, unit string, notify bool) {
+	report, err := app.biologicalMonitor.Check(time.Duration(days) * 24 * time.Hour)
+	if err != nil {
+		fmt.Printf("Error checking biological evidence: %v\n", err)
+		os.Exit(1)
+	}
+
+	if tempLog != "" {
+		f, err := os.Open(tempLog)
+		if err != nil {
+			fmt.Printf("Error opening temperature log: %v\n", err)
+			os.Exit(1)
+		}
+		logReport, err := app.biologicalMonitor.IngestTemperatureLog(f, unit)
+		f.Close()
+		if err != nil {
+			fmt.Printf("Error reading temperature log: %v\n", err)
+			os.Exit(1)
+		}
+		report.Excursions = logReport.Excursions
+		report.Warnings = append(report.Warnings, logReport.Warnings...)
+	}
+
+	for _, exc := range report.Excursions {
+		fmt.Printf("Excursion: %s from %s to %s (%.1f°C to %.1f°C)\n", exc.Unit,
+			exc.Start.Format("2006-01-02 15:04"), exc.End.Format("2006-01-02 15:04"), exc.MinTempC, exc.MaxTempC)
+	}
+
+	if len(report.Warnings) == 0 {
+		fmt.Println("No biological evidence warnings")
+		return
+	}
+
+	fmt.Println("\nBiological Evidence Warnings:")
+	fmt.Println("-------------------------------------------------")
+	fmt.Println("Kind\t\tEvidence\tCase\tMessage")
+	fmt.Println("-------------------------------------------------")
+	for _, w := range report.Warnings {
+		fmt.Printf("%s\t%s\t%s\t%s\n", w.Kind, w.EvidenceID, w.CaseID, w.Message)
+	}
+
+	if notify {
+		if err := app.biologicalMonitor.WriteWarningsToCases(report); err != nil {
+			fmt.Printf("Error writing warnings to cases: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("\nWarnings written to the owning cases")
+	}
+}
+
+func (app *InvestigatorApp) handleEvidenceDispose(id, action string, req evidence.DispositionRequest) {
+	if id == "" {
+		fmt.Println("Error: Evidence ID is required")
+		os.Exit(1)
+	}
+	req.EvidenceID = id
+
+	var record *evidence.DispositionRecord
+	var err error
+	switch strings.ToUpper(action) {
+	case "RELEASE":
+		record, err = app.evidenceService.ReleaseEvidence(req)
+	case "DESTROY":
+		record, err = app.evidenceService.DestroyEvidence(req)
+	case "RETURN", "RETURN_TO_OWNER":
+		record, err = app.evidenceService.ReturnToOwner(req)
+	case "TRANSFER":
+		record, err = app.evidenceService.TransferToAgency(req)
+	default:
+		fmt.Println("Error: Action must be one of RELEASE, DESTROY, RETURN or TRANSFER")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error disposing of evidence: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Evidence %s disposed of successfully. Disposition record: %s\n", id, record.ID)
+	fmt.Printf("Action: %s, Authorized by: %s", record.Action, record.AuthorizedBy)
+	if record.WitnessedBy != "" {
+		fmt.Printf(", Witnessed by: %s", record.WitnessedBy)
+	}
+	fmt.Println()
+}
+
+func (app *InvestigatorApp) handleEvidenceLabel(id, format, output string) {
+	if id == "" {
+		fmt.Println("Error: Evidence ID is required")
+		os.Exit(1)
+	}
+
+	labelFormat, err := evidence.ParseLabelFormat(format)
+	if err != nil {
+		fmt.Printf("Error: %v (use PNG, SVG or ZPL)\n", err)
+		os.Exit(1)
+	}
+
+	e, err := app.evidenceService.GetEvidence(id)
+	if err != nil {
+		fmt.Printf("Error: Evidence not found: %v\n", err)
+		os.Exit(1)
+	}
+
+	caseNumber := ""
+	if c, err := app.caseService.GetCase(e.CaseID); err == nil {
+		caseNumber = c.CaseNumber
+	}
+
+	if output == "" {
+		labelDir := filepath.Join(app.workingDir, "labels")
+		os.MkdirAll(labelDir, 0755)
+		output = filepath.Join(labelDir, fmt.Sprintf("%s.%s", e.ID, strings.ToLower(format)))
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		fmt.Printf("Error creating label file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	label := evidence.NewLabel(e, caseNumber)
+	if err := label.Render(f, labelFormat); err != nil {
+		fmt.Printf("Error rendering label: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Label written to: %s\n", output)
+}
+
+func (app *InvestigatorApp) handleEvidenceCustody(ids, caseID, format, output, preparedBy string) {
+	var idList []string
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			idList = append(idList, id)
+		}
+	}
+	if len(idList) == 0 && caseID == "" {
+		caseID = app.currentCaseID
+	}
+
+	// Use the official case number when the owning case is known
+	caseNumber := ""
+	reportCase := caseID
+	if reportCase == "" && len(idList) > 0 {
+		if e, err := app.evidenceService.GetEvidence(idList[0]); err == nil {
+			reportCase = e.CaseID
+		}
+	}
+	if c, err := app.caseService.GetCase(reportCase); err == nil {
+		caseNumber = c.CaseNumber
+	}
+
+	report, err := app.evidenceService.CustodyReport(caseID, idList, caseNumber, preparedBy)
+	if err != nil {
+		fmt.Printf("Error building custody report: %v\n", err)
+		os.Exit(1)
+	}
+
+	format = strings.ToUpper(format)
+	if output == "" && format == string(evidence.CustodyText) {
+		if err := report.Render(os.Stdout, evidence.CustodyText); err != nil {
+			fmt.Printf("Error rendering custody report: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if output == "" {
+		reportDir := filepath.Join(app.workingDir, "reports")
+		os.MkdirAll(reportDir, 0755)
+		name := reportCase
+		if len(idList) == 1 {
+			name = idList[0]
+		}
+		output = filepath.Join(reportDir, fmt.Sprintf("custody-%s.%s", name, strings.ToLower(format)))
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		fmt.Printf("Error creating report file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if err := report.Render(f, evidence.CustodyReportFormat(format)); err != nil {
+		fmt.Printf("Error rendering custody report: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Custody report for %d item(s) written to: %s\n", len(report.Items), output)
+}
+
+func (app *InvestigatorApp) handleEvidenceScan(code, toPerson, toLocation, reason string) {
+	if code == "" {
+		fmt.Println("Error: Scanned code is required")
+		os.Exit(1)
+	}
+
+	e, err := app.evidenceService.LookupByLabelCode(code)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	if len(items) == 0 {
-		fmt.Printf("No evidence found for case: %s\n", caseID)
+	fmt.Printf("Evidence %s (%s): %s\n", e.ID, e.EvidenceNumber, e.Description)
+	fmt.Printf("Case: %s, Status: %s, Location: %s\n", e.CaseID, e.Status, e.StorageLocation)
+
+	if toPerson == "" && toLocation == "" {
 		return
 	}
 
-	fmt.Printf("\nEvidence for Case %s:\n", caseID)
-	fmt.Println("-------------------------------------------------")
-	fmt.Println("ID\t\tType\t\tStatus\tDescription")
-	fmt.Println("-------------------------------------------------")
+	if toLocation == "" {
+		toLocation = e.StorageLocation
+	}
+	fromPerson := ""
+	if n := len(e.ChainOfCustody); n > 0 {
+		fromPerson = e.ChainOfCustody[n-1].ToPerson
+	}
 
-	for _, e := range items {
-		fmt.Printf("%s\t%s\t%s\t%s\n",
-			e.ID,
-			e.Type,
-			e.Status,
-			e.Description)
+	err = app.evidenceService.TransferCustody(e.ID, fromPerson, toPerson, e.StorageLocation, toLocation, reason,
+		"Recorded by barcode scan")
+	if err != nil {
+		fmt.Printf("Error transferring custody: %v\n", err)
+		os.Exit(1)
 	}
+
+	fmt.Printf("Custody transferred to %s at %s\n", toPerson, toLocation)
 }
 
 func (app *InvestigatorApp) handleInterviewAdd(title, interviewType, caseID string) {
@@ -596,8 +3444,72 @@ func (app *InvestigatorApp) handleInterviewTranscribe(interviewID string) {
 	fmt.Printf("Transcript preview: %s\n", preview(transcript.Content, 150))
 }
 
+func (app *InvestigatorApp) handleInterviewTranscript(interviewID string) {
+	if interviewID == "" {
+		fmt.Println("Error: Interview ID is required")
+		os.Exit(1)
+	}
+	text, err := app.interviewService.RedactedTranscript(interviewID)
+	if err != nil {
+		fmt.Printf("Error reading transcript: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(text)
+
+	segments, err := app.interviewService.RedactedSegments(interviewID)
+	if err != nil {
+		fmt.Printf("Error reading transcript: %v\n", err)
+		os.Exit(1)
+	}
+	if len(segments) > 0 {
+		fmt.Println("\nSegments:")
+	}
+	for _, s := range segments {
+		fmt.Printf("  [%s] %s: %s\n", s.StartTime.Round(time.Second), s.SpeakerRole, s.Text)
+	}
+}
+
+func (app *InvestigatorApp) handleInterviewPII(interviewID string) {
+	if interviewID == "" {
+		fmt.Println("Error: Interview ID is required")
+		os.Exit(1)
+	}
+	i, err := app.interviewService.GetInterview(interviewID)
+	if err != nil {
+		fmt.Printf("Error: Interview not found: %v\n", err)
+		os.Exit(1)
+	}
+
+	added, err := app.interviewService.ProposeTranscriptRedactions(interviewID, pii.NewDetector(app.protectedNames(i.CaseID)))
+	if err != nil {
+		fmt.Printf("Error scanning transcript: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Personal information found in the transcript of %s: %d new proposals\n", interviewID, len(added))
+	printProposals(app.repo.transcripts[i.TranscriptID].Content, added, func(int) int { return 0 })
+	if len(added) > 0 {
+		fmt.Printf("\nReview with: investigator interview review --id %s --proposal <ids> --accept | --reject\n", interviewID)
+	}
+}
+
+func (app *InvestigatorApp) handleInterviewReview(interviewID, proposals string, accept, reject bool) {
+	if interviewID == "" {
+		fmt.Println("Error: Interview ID is required")
+		os.Exit(1)
+	}
+	ids := reviewArgs(proposals, accept, reject)
+	for _, proposalID := range ids {
+		p, err := app.interviewService.ReviewTranscriptProposal(interviewID, proposalID, accept, "Current User") // Would come from auth system
+		if err != nil {
+			fmt.Printf("Error reviewing proposal %s: %v\n", proposalID, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Proposal %s %s: %d-%d %s\n", p.ID, strings.ToLower(string(p.Status)), p.StartPos, p.EndPos, p.Reason)
+	}
+}
+
 // New correspondence handlers
-func (app *InvestigatorApp) handleCorrespondenceCreate(corrType, subject, body, recipient, caseID, templateID string) {
+func (app *InvestigatorApp) handleCorrespondenceCreate(corrType, subject, body, recipient, caseID, templateID, evidenceID string, vars templateVars) {
 	if caseID == "" {
 		if app.currentCaseID == "" {
 			fmt.Println("Error: No case specified and no case is currently open")
@@ -607,7 +3519,7 @@ func (app *InvestigatorApp) handleCorrespondenceCreate(corrType, subject, body,
 	}
 
 	// Ensure the case exists
-	_, err := app.caseService.GetCase(caseID)
+	caseRecord, err := app.caseService.GetCase(caseID)
 	if err != nil {
 		fmt.Printf("Error: Case not found: %v\n", err)
 		os.Exit(1)
@@ -630,12 +3542,28 @@ func (app *InvestigatorApp) handleCorrespondenceCreate(corrType, subject, body,
 
 	// If using a template
 	if templateID != "" {
+		data := map[string]any{"CaseNumber": caseRecord.CaseNumber, "CaseTitle": caseRecord.Title}
+		if evidenceID != "" {
+			e, err := app.evidenceService.GetEvidence(evidenceID)
+			if err != nil {
+				fmt.Printf("Error: Evidence not found: %v\n", err)
+				os.Exit(1)
+			}
+			for k, v := range custodyTemplateData(e) {
+				data[k] = v
+			}
+		}
+		for k, v := range vars {
+			data[k] = v
+		}
+
 		// Create from template
 		c, err = app.correspondenceService.CreateFromTemplate(
 			templateID,
 			caseID,
 			sender,
 			[]correspondence.Person{recipientPerson},
+			data,
 		)
 		if err != nil {
 			fmt.Printf("Error creating correspondence from template: %v\n", err)
@@ -678,6 +3606,67 @@ func (app *InvestigatorApp) handleCorrespondenceCreate(corrType, subject, body,
 	fmt.Printf("Subject: %s\n", c.Subject)
 }
 
+// templateVars collects repeated NAME=VALUE template variable flags
+type templateVars map[string]string
+
+func (v *templateVars) String() string {
+	return fmt.Sprint(map[string]string(*v))
+}
+
+func (v *templateVars) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected NAME=VALUE, got %q", s)
+	}
+	if *v == nil {
+		*v = make(templateVars)
+	}
+	(*v)[strings.TrimSpace(name)] = value
+	return nil
+}
+
+// custodyTemplateData returns the template variables describing an
+// evidence item, with one transfer per entry of its chain of custody
+func custodyTemplateData(e *evidence.Evidence) map[string]any {
+	data := map[string]any{
+		"EvidenceNumber":      e.EvidenceNumber,
+		"EvidenceDescription": e.Description,
+		"RecoveredBy":         e.CollectedBy,
+		"RecoveryLocation":    e.Location.Description,
+		"RecoveryNotes":       e.Notes,
+	}
+	if !e.CollectionDate.IsZero() {
+		data["RecoveryDateTime"] = e.CollectionDate.Format("2006-01-02 15:04")
+	}
+
+	var transfers []correspondence.CustodyTransfer
+	for i, event := range e.ChainOfCustody {
+		purpose := event.Action
+		if event.Reason != "" {
+			purpose += ": " + event.Reason
+		}
+		transfers = append(transfers, correspondence.CustodyTransfer{
+			Number:             i + 1,
+			From:               event.FromPerson,
+			FromTitle:          event.FromLocation,
+			To:                 event.ToPerson,
+			ToTitle:            event.ToLocation,
+			DateTime:           event.Timestamp.Format("2006-01-02 15:04"),
+			Purpose:            purpose,
+			VerificationMethod: event.VerificationMethod,
+			Notes:              event.Notes,
+		})
+	}
+	data["Transfers"] = transfers
+
+	if d := e.Disposition; d != nil {
+		data["FinalDisposition"] = strings.TrimSpace(fmt.Sprintf("%s %s", d.Action, d.Recipient))
+		data["AuthorizedBy"] = d.AuthorizedBy
+		data["DispositionDate"] = d.Timestamp.Format("2006-01-02")
+	}
+	return data
+}
+
 func (app *InvestigatorApp) handleCorrespondenceList(caseID string) {
 	if caseID == "" {
 		if app.currentCaseID == "" {
@@ -876,6 +3865,25 @@ func (r *inMemoryEvidenceRepo) FindByCase(caseID string) ([]*evidence.Evidence,
 	return result, nil
 }
 
+func (r *inMemoryEvidenceRepo) FindByEvidenceNumber(evidenceNumber string) (*evidence.Evidence, error) {
+	for _, e := range r.evidence {
+		if e.EvidenceNumber == evidenceNumber {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("evidence not found with number: %s", evidenceNumber)
+}
+
+func (r *inMemoryEvidenceRepo) FindByStorageLocation(location string) ([]*evidence.Evidence, error) {
+	var result []*evidence.Evidence
+	for _, e := range r.evidence {
+		if strings.EqualFold(strings.TrimSpace(e.StorageLocation), strings.TrimSpace(location)) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
 func (r *inMemoryEvidenceRepo) Search(query string) ([]*evidence.Evidence, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -890,6 +3898,46 @@ func (r *inMemoryEvidenceRepo) Delete(id string) error {
 	return nil
 }
 
+type inMemoryDigitalRepo struct {
+	digital map[string]*evidence.DigitalEvidence
+}
+
+func (r *inMemoryDigitalRepo) SaveDigital(d *evidence.DigitalEvidence) error {
+	r.digital[d.ID] = d
+	return nil
+}
+
+func (r *inMemoryDigitalRepo) FindDigital(id string) (*evidence.DigitalEvidence, error) {
+	if d, ok := r.digital[id]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("digital evidence not found: %s", id)
+}
+
+type inMemoryBiologicalRepo struct {
+	biological map[string]*evidence.BiologicalEvidence
+}
+
+func (r *inMemoryBiologicalRepo) SaveBiological(b *evidence.BiologicalEvidence) error {
+	r.biological[b.ID] = b
+	return nil
+}
+
+func (r *inMemoryBiologicalRepo) FindBiological(id string) (*evidence.BiologicalEvidence, error) {
+	if b, ok := r.biological[id]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("biological evidence not found: %s", id)
+}
+
+func (r *inMemoryBiologicalRepo) ListBiological() ([]*evidence.BiologicalEvidence, error) {
+	result := make([]*evidence.BiologicalEvidence, 0, len(r.biological))
+	for _, b := range r.biological {
+		result = append(result, b)
+	}
+	return result, nil
+}
+
 type inMemoryInterviewRepo struct {
 	interviews map[string]*interview.Interview
 }
@@ -965,6 +4013,303 @@ func (r *inMemoryTranscriptRepo) Delete(id string) error {
 	return nil
 }
 
+// caseDispositionChecker allows evidence disposition only for closed or
+// prosecuted cases
+type caseDispositionChecker struct {
+	caseService *casemanagement.CaseService
+}
+
+func (c *caseDispositionChecker) CanDisposeEvidence(caseID string) error {
+	cs, err := c.caseService.GetCase(caseID)
+	if err != nil {
+		return err
+	}
+
+	switch cs.Status {
+	case casemanagement.StatusClosed, casemanagement.StatusProsecuted:
+		return nil
+	default:
+		return fmt.Errorf("case status is %s", cs.Status)
+	}
+}
+
+// caseNoteWriter adds monitor warnings to cases as investigator notes
+type caseNoteWriter struct {
+	caseService *casemanagement.CaseService
+}
+
+func (w *caseNoteWriter) AddCaseNote(caseID, title, content string) error {
+	return w.caseService.AddNote(caseID, casemanagement.Note{
+		Title:     title,
+		Content:   content,
+		CreatedBy: "Evidence Monitor",
+		Tags:      []string{"biological-evidence", "warning"},
+	})
+}
+
+// knownBadAlerter reports files matching known-bad hash sets on the console
+// and in the owning case
+type knownBadAlerter struct {
+	caseService *casemanagement.CaseService
+}
+
+func (a *knownBadAlerter) KnownBadFile(e *evidence.Evidence) {
+	sets := strings.Join(e.KnownFileSets, ", ")
+	fmt.Printf("ALERT: %s (%s) matches known-bad hash set %s\n", e.ID, e.Description, sets)
+
+	err := a.caseService.AddNote(e.CaseID, casemanagement.Note{
+		Title:     fmt.Sprintf("Known-bad file: %s", e.ID),
+		Content:   fmt.Sprintf("%s (%s) matches known-bad hash set %s.\nSHA-256: %s", e.ID, e.Description, sets, e.FileHash),
+		CreatedBy: "Hash Set Filter",
+		Tags:      []string{"known-bad", "alert"},
+	})
+	if err != nil {
+		fmt.Printf("Warning: could not add alert to case %s: %v\n", e.CaseID, err)
+	}
+}
+
+type inMemorySecretRepo struct {
+	secrets     map[string]*evidence.EvidenceSecret
+	access      map[string][]evidence.SecretAccess
+	decryptions map[string]evidence.DecryptionRecord
+}
+
+func (r *inMemorySecretRepo) SaveSecret(secret *evidence.EvidenceSecret) error {
+	r.secrets[secret.ID] = secret
+	return nil
+}
+
+func (r *inMemorySecretRepo) FindSecret(id string) (*evidence.EvidenceSecret, error) {
+	if s, ok := r.secrets[id]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("secret not found: %s", id)
+}
+
+func (r *inMemorySecretRepo) FindSecretsByEvidence(evidenceID string) ([]*evidence.EvidenceSecret, error) {
+	var result []*evidence.EvidenceSecret
+	for _, s := range r.secrets {
+		if s.EvidenceID == evidenceID {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (r *inMemorySecretRepo) LogSecretAccess(access evidence.SecretAccess) error {
+	r.access[access.EvidenceID] = append(r.access[access.EvidenceID], access)
+	return nil
+}
+
+func (r *inMemorySecretRepo) SecretAccessLog(evidenceID string) ([]evidence.SecretAccess, error) {
+	return r.access[evidenceID], nil
+}
+
+func (r *inMemorySecretRepo) SaveDecryption(record evidence.DecryptionRecord) error {
+	r.decryptions[record.EvidenceID] = record
+	return nil
+}
+
+func (r *inMemorySecretRepo) FindDecryption(evidenceID string) (*evidence.DecryptionRecord, error) {
+	if d, ok := r.decryptions[evidenceID]; ok {
+		return &d, nil
+	}
+	return nil, fmt.Errorf("no decryption recorded for evidence: %s", evidenceID)
+}
+
+type inMemoryLabRepo struct {
+	requests map[string]*evidence.LabRequest
+}
+
+func (r *inMemoryLabRepo) SaveLabRequest(request *evidence.LabRequest) error {
+	r.requests[request.ID] = request
+	return nil
+}
+
+func (r *inMemoryLabRepo) FindLabRequest(id string) (*evidence.LabRequest, error) {
+	if request, ok := r.requests[id]; ok {
+		return request, nil
+	}
+	return nil, fmt.Errorf("lab request not found: %s", id)
+}
+
+func (r *inMemoryLabRepo) FindLabRequestsByEvidence(evidenceID string) ([]*evidence.LabRequest, error) {
+	var result []*evidence.LabRequest
+	for _, request := range r.requests {
+		if request.EvidenceID == evidenceID {
+			result = append(result, request)
+		}
+	}
+	return result, nil
+}
+
+func (r *inMemoryLabRepo) ListLabRequests() ([]*evidence.LabRequest, error) {
+	result := make([]*evidence.LabRequest, 0, len(r.requests))
+	for _, request := range r.requests {
+		result = append(result, request)
+	}
+	return result, nil
+}
+
+func (r *inMemoryLabRepo) UpdateLabRequest(request *evidence.LabRequest) error {
+	r.requests[request.ID] = request
+	return nil
+}
+
+type inMemoryEmailRepo struct {
+	emails map[string]*evidence.EmailMessage
+}
+
+func (r *inMemoryEmailRepo) SaveEmail(m *evidence.EmailMessage) error {
+	r.emails[m.ID] = m
+	return nil
+}
+
+func (r *inMemoryEmailRepo) FindEmail(id string) (*evidence.EmailMessage, error) {
+	if m, ok := r.emails[id]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("message not found: %s", id)
+}
+
+func (r *inMemoryEmailRepo) FindEmailsByEvidence(evidenceID string) ([]*evidence.EmailMessage, error) {
+	var result []*evidence.EmailMessage
+	for _, m := range r.emails {
+		if m.EvidenceID == evidenceID {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// inMemoryDocumentRepo stores documents and their redaction log
+type inMemoryDocumentRepo struct {
+	repo *inMemoryRepo
+}
+
+func (r *inMemoryDocumentRepo) Find(id string) (*document.Document, error) {
+	if doc, ok := r.repo.documents[id]; ok {
+		return doc, nil
+	}
+	return nil, fmt.Errorf("document not found: %s", id)
+}
+
+func (r *inMemoryDocumentRepo) FindByCase(caseID string) ([]*document.Document, error) {
+	var result []*document.Document
+	for _, doc := range r.repo.documents {
+		if doc.CaseID == caseID {
+			result = append(result, doc)
+		}
+	}
+	return result, nil
+}
+
+func (r *inMemoryDocumentRepo) Update(doc *document.Document) error {
+	if _, ok := r.repo.documents[doc.ID]; !ok {
+		return fmt.Errorf("document not found: %s", doc.ID)
+	}
+	r.repo.documents[doc.ID] = doc
+	return nil
+}
+
+func (r *inMemoryDocumentRepo) SaveRedactionLog(entry *document.RedactionLogEntry) error {
+	r.repo.redactionLog = append(r.repo.redactionLog, entry)
+	return nil
+}
+
+func (r *inMemoryDocumentRepo) FindRedactionLog(documentID string) ([]*document.RedactionLogEntry, error) {
+	var result []*document.RedactionLogEntry
+	for _, e := range r.repo.redactionLog {
+		if e.DocumentID == documentID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// inMemoryChatRepo keeps chat messages in import order
+type inMemoryChatRepo struct {
+	repo *inMemoryRepo
+}
+
+func (r *inMemoryChatRepo) SaveChatMessage(m *evidence.ChatMessage) error {
+	r.repo.chatMessages = append(r.repo.chatMessages, m)
+	return nil
+}
+
+func (r *inMemoryChatRepo) FindChatMessagesByEvidence(evidenceID string) ([]*evidence.ChatMessage, error) {
+	var result []*evidence.ChatMessage
+	for _, m := range r.repo.chatMessages {
+		if m.EvidenceID == evidenceID {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+func (r *inMemoryChatRepo) FindChatMessagesByCase(caseID string) ([]*evidence.ChatMessage, error) {
+	var result []*evidence.ChatMessage
+	for _, m := range r.repo.chatMessages {
+		if m.CaseID == caseID {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// casePersonMatcher matches email participants to the persons of a case
+type casePersonMatcher struct {
+	caseService *casemanagement.CaseService
+}
+
+func (m *casePersonMatcher) MatchPerson(caseID, address, name string) (string, error) {
+	p, err := m.caseService.FindPerson(caseID, address, name)
+	if err != nil || p == nil {
+		return "", err
+	}
+	return p.ID, nil
+}
+
+// labReportImporter stores lab result reports as forensic report documents
+type labReportImporter struct {
+	app *InvestigatorApp
+}
+
+func (i *labReportImporter) ImportLabReport(caseID, evidenceID, filePath string) (*evidence.LabReport, error) {
+	docDir := filepath.Join(i.app.workingDir, "documents")
+	if err := os.MkdirAll(docDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create document directory: %w", err)
+	}
+
+	doc, err := document.ImportDocument(filePath, docDir, i.app.documentProcessors)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.CaseID = caseID
+	doc.Type = document.TypeForensicReport
+	doc.Tags = append(doc.Tags, "lab-report")
+	doc.Metadata.CustomFields["EvidenceID"] = evidenceID
+	i.app.repo.documents[doc.ID] = doc
+
+	return &evidence.LabReport{DocumentID: doc.ID, Content: doc.Content}, nil
+}
+
+// caseEventWriter places evidence capture times on case timelines
+type caseEventWriter struct {
+	caseService *casemanagement.CaseService
+}
+
+func (w *caseEventWriter) AddCaseEvent(caseID string, timestamp time.Time, description, location string, evidenceIDs []string) error {
+	return w.caseService.AddEvent(caseID, casemanagement.Event{
+		Timestamp:   timestamp,
+		Description: description,
+		Location:    location,
+		EvidenceIDs: evidenceIDs,
+		CreatedBy:   "Evidence Intake",
+	})
+}
+
 // Dummy speech recognizer for demonstration
 type dummySpeechRecognizer struct{}
 
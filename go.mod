@@ -4,7 +4,10 @@ go 1.23.0
 
 toolchain go1.24.2
 
-require github.com/hashicorp/terraform-exec v0.23.0
+require (
+	github.com/hashicorp/terraform-exec v0.23.0
+	golang.org/x/image v0.24.0
+)
 
 require (
 	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
@@ -0,0 +1,441 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format identifies an archive or compression container
+type Format string
+
+const (
+	FormatNone   Format = ""
+	FormatZIP    Format = "ZIP"
+	FormatTAR    Format = "TAR"
+	FormatTARGZ  Format = "TAR.GZ"
+	FormatTARBZ2 Format = "TAR.BZ2"
+	FormatGZIP   Format = "GZIP"
+	FormatBZIP2  Format = "BZIP2"
+	Format7Z     Format = "7Z"
+	FormatRAR    Format = "RAR"
+	FormatXZ     Format = "XZ"
+)
+
+// ErrUnsupportedFormat is returned for containers that are recognized but
+// cannot be expanded
+var ErrUnsupportedFormat = errors.New("unsupported archive format")
+
+// ErrLimitExceeded is returned when an archive exceeds the expansion limits,
+// as happens with decompression bombs
+var ErrLimitExceeded = errors.New("archive expansion limit exceeded")
+
+// Limits bound the resources used when expanding archives
+type Limits struct {
+	MaxDepth         int   // Levels of nested archives to expand
+	MaxFileSize      int64 // Largest single extracted file
+	MaxTotalSize     int64 // Total bytes extracted across all levels
+	MaxEntries       int   // Total files extracted across all levels
+	MaxRatio         int64 // Largest uncompressed/compressed ratio for a ZIP entry
+	MinRatioFileSize int64 // Entries smaller than this are exempt from the ratio check
+}
+
+// DefaultLimits returns limits suitable for typical seized media
+func DefaultLimits() Limits {
+	return Limits{
+		MaxDepth:         5,
+		MaxFileSize:      4 << 30,
+		MaxTotalSize:     16 << 30,
+		MaxEntries:       100000,
+		MaxRatio:         200,
+		MinRatioFileSize: 1 << 20,
+	}
+}
+
+// Budget tracks what has been extracted so limits apply across nested levels
+type Budget struct {
+	Limits    Limits
+	Extracted int64
+	Entries   int
+}
+
+// NewBudget creates a budget for one expansion
+func NewBudget(limits Limits) *Budget {
+	return &Budget{Limits: limits}
+}
+
+// Entry is a file extracted from an archive
+type Entry struct {
+	Path          string // Path inside the archive, as recorded by the archive
+	ExtractedPath string // Where the file was written
+	Size          int64
+	ModTime       time.Time
+	Mode          fs.FileMode
+}
+
+// Listing is the result of expanding one archive
+type Listing struct {
+	Format  Format
+	Entries []Entry
+	Skipped []string // Links, devices and other entries that were not extracted
+}
+
+// magic numbers of supported and recognized containers
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte{0x1F, 0x8B}
+	bzip2Magic    = []byte("BZh")
+	sevenZipMagic = []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}
+	rarMagic      = []byte("Rar!\x1A\x07")
+	xzMagic       = []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// tarMagicOffset is the offset of the "ustar" magic in a tar header
+const tarMagicOffset = 257
+
+// DetectFormat identifies the container format of a file from its content
+func DetectFormat(filePath string) (Format, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return FormatNone, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return FormatNone, fmt.Errorf("failed to read file: %w", err)
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic), bytes.HasPrefix(header, zipEmptyMagic):
+		return FormatZIP, nil
+	case bytes.HasPrefix(header, sevenZipMagic):
+		return Format7Z, nil
+	case bytes.HasPrefix(header, rarMagic):
+		return FormatRAR, nil
+	case bytes.HasPrefix(header, xzMagic):
+		return FormatXZ, nil
+	case isTarHeader(header):
+		return FormatTAR, nil
+	case bytes.HasPrefix(header, gzipMagic):
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return FormatNone, err
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return FormatNone, nil // Damaged stream; not expandable
+		}
+		defer gz.Close()
+		if wrapsTar(gz) {
+			return FormatTARGZ, nil
+		}
+		return FormatGZIP, nil
+	case bytes.HasPrefix(header, bzip2Magic):
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return FormatNone, err
+		}
+		if wrapsTar(bzip2.NewReader(f)) {
+			return FormatTARBZ2, nil
+		}
+		return FormatBZIP2, nil
+	}
+	return FormatNone, nil
+}
+
+// Supported reports whether a format can be expanded
+func Supported(format Format) bool {
+	return CheckSupported(format) == nil
+}
+
+// Extract expands one level of an archive into destDir. Nested archives are
+// written out as files; callers expand them with another call sharing the
+// same budget. Entry names are confined to destDir.
+func Extract(filePath, destDir string, budget *Budget) (*Listing, error) {
+	format, err := DetectFormat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatNone {
+		return nil, fmt.Errorf("%s is not an archive", filePath)
+	}
+	if err := CheckSupported(format); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+
+	x := &extractor{dest: destDir, budget: budget, listing: &Listing{Format: format}}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	switch format {
+	case FormatZIP:
+		err = x.extractZIP(f)
+	case FormatTAR:
+		err = x.extractTAR(f)
+	case FormatTARGZ, FormatGZIP:
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(bufio.NewReader(f)); err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		if format == FormatTARGZ {
+			err = x.extractTAR(gz)
+		} else {
+			err = x.extractStream(gz, streamName(filePath, gz.Name, ".gz"), gz.ModTime)
+		}
+	case FormatTARBZ2, FormatBZIP2:
+		r := bzip2.NewReader(bufio.NewReader(f))
+		if format == FormatTARBZ2 {
+			err = x.extractTAR(r)
+		} else {
+			err = x.extractStream(r, streamName(filePath, "", ".bz2"), time.Time{})
+		}
+	case FormatXZ:
+		err = x.extractXZ(filePath)
+	case Format7Z, FormatRAR:
+		err = x.extractSevenZip(filePath)
+	}
+	return x.listing, err
+}
+
+// extractor writes the entries of one archive
+type extractor struct {
+	dest    string
+	budget  *Budget
+	listing *Listing
+}
+
+// extractZIP extracts the files of a ZIP archive
+func (x *extractor) extractZIP(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			continue
+		case !mode.IsRegular():
+			x.listing.Skipped = append(x.listing.Skipped, fmt.Sprintf("%s: %s entry", zf.Name, mode.Type()))
+			continue
+		}
+
+		// Reject entries that claim an extreme compression ratio before
+		// decompressing anything
+		limits := x.budget.Limits
+		if limits.MaxRatio > 0 && zf.UncompressedSize64 > uint64(limits.MinRatioFileSize) &&
+			zf.UncompressedSize64 > uint64(limits.MaxRatio)*max(zf.CompressedSize64, 1) {
+			return fmt.Errorf("%w: %s expands from %d to %d bytes", ErrLimitExceeded, zf.Name,
+				zf.CompressedSize64, zf.UncompressedSize64)
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			x.listing.Skipped = append(x.listing.Skipped, fmt.Sprintf("%s: %v", zf.Name, err))
+			continue
+		}
+		err = x.writeEntry(rc, zf.Name, zf.Modified, mode)
+		rc.Close()
+		if err != nil {
+			if errors.Is(err, ErrLimitExceeded) {
+				return err
+			}
+			x.listing.Skipped = append(x.listing.Skipped, fmt.Sprintf("%s: %v", zf.Name, err))
+		}
+	}
+	return nil
+}
+
+// extractTAR extracts the regular files of a tar stream
+func (x *extractor) extractTAR(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		switch {
+		case hdr.Typeflag == tar.TypeDir:
+			continue
+		case !hdr.FileInfo().Mode().IsRegular():
+			x.listing.Skipped = append(x.listing.Skipped, fmt.Sprintf("%s: %s", hdr.Name, tarTypeName(hdr)))
+			continue
+		}
+
+		if err := x.writeEntry(tr, hdr.Name, hdr.ModTime, hdr.FileInfo().Mode()); err != nil {
+			if errors.Is(err, ErrLimitExceeded) {
+				return err
+			}
+			x.listing.Skipped = append(x.listing.Skipped, fmt.Sprintf("%s: %v", hdr.Name, err))
+		}
+	}
+}
+
+// extractStream extracts the single file held in a compressed stream
+func (x *extractor) extractStream(r io.Reader, name string, modTime time.Time) error {
+	return x.writeEntry(r, name, modTime, 0644)
+}
+
+// writeEntry writes one file within the budget
+func (x *extractor) writeEntry(r io.Reader, name string, modTime time.Time, mode fs.FileMode) error {
+	limits := x.budget.Limits
+	if limits.MaxEntries > 0 && x.budget.Entries >= limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d files", ErrLimitExceeded, limits.MaxEntries)
+	}
+
+	// The file may use whatever remains of the per-file and total limits;
+	// reading one byte more than allowed proves the limit was exceeded
+	allowed := int64(-1)
+	if limits.MaxFileSize > 0 {
+		allowed = limits.MaxFileSize
+	}
+	if limits.MaxTotalSize > 0 {
+		remaining := limits.MaxTotalSize - x.budget.Extracted
+		if allowed < 0 || remaining < allowed {
+			allowed = remaining
+		}
+	}
+
+	target, err := x.targetPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	src := r
+	if allowed >= 0 {
+		src = io.LimitReader(r, allowed+1)
+	}
+	n, err := io.Copy(out, src)
+	closeErr := out.Close()
+	if err == nil && allowed >= 0 && n > allowed {
+		err = fmt.Errorf("%w: %s is larger than the %d bytes remaining", ErrLimitExceeded, name, allowed)
+	}
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return err
+	}
+
+	if !modTime.IsZero() {
+		os.Chtimes(target, modTime, modTime)
+	}
+
+	x.budget.Extracted += n
+	x.budget.Entries++
+	x.listing.Entries = append(x.listing.Entries, Entry{
+		Path:          name,
+		ExtractedPath: target,
+		Size:          n,
+		ModTime:       modTime,
+		Mode:          mode,
+	})
+	return nil
+}
+
+// targetPath maps an entry name to a new file under the destination,
+// removing any ".." or absolute components and avoiding name clashes
+func (x *extractor) targetPath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if i := strings.Index(name, ":"); i == 1 {
+		name = name[2:] // Drive letter
+	}
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if clean == "" || clean == "." {
+		clean = "unnamed"
+	}
+
+	target := filepath.Join(x.dest, filepath.FromSlash(clean))
+	if rel, err := filepath.Rel(x.dest, target); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("entry %q escapes the extraction directory", name)
+	}
+
+	// Archives may hold the same name more than once
+	candidate := target
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		}
+		ext := filepath.Ext(target)
+		candidate = fmt.Sprintf("%s~%d%s", strings.TrimSuffix(target, ext), i, ext)
+	}
+}
+
+// isTarHeader reports whether a block starts a tar archive
+func isTarHeader(header []byte) bool {
+	return len(header) >= tarMagicOffset+5 && string(header[tarMagicOffset:tarMagicOffset+5]) == "ustar"
+}
+
+// wrapsTar reports whether a decompressed stream holds a tar archive
+func wrapsTar(r io.Reader) bool {
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(r, header)
+	return isTarHeader(header[:n])
+}
+
+// streamName names the file held in a single-file compressed stream
+func streamName(archivePath, recorded, suffix string) string {
+	if recorded != "" {
+		return path.Base(strings.ReplaceAll(recorded, "\\", "/"))
+	}
+	base := filepath.Base(archivePath)
+	if strings.HasSuffix(strings.ToLower(base), suffix) {
+		return base[:len(base)-len(suffix)]
+	}
+	return base + ".out"
+}
+
+// tarTypeName describes a tar entry that is not extracted
+func tarTypeName(hdr *tar.Header) string {
+	switch hdr.Typeflag {
+	case tar.TypeSymlink:
+		return "symbolic link to " + hdr.Linkname
+	case tar.TypeLink:
+		return "hard link to " + hdr.Linkname
+	case tar.TypeChar, tar.TypeBlock:
+		return "device"
+	case tar.TypeFifo:
+		return "named pipe"
+	}
+	return fmt.Sprintf("entry type %q", hdr.Typeflag)
+}
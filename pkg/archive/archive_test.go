@@ -0,0 +1,221 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSevenZip installs a script that answers "7z l -slt" with listing and
+// "7z x -so ... -- archive name" with the file of that name under dir
+func fakeSevenZip(t *testing.T, dir, listing string) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "listing"), []byte(listing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+if [ "$1" = l ]; then cat "` + filepath.Join(bin, "listing") + `"; exit 0; fi
+for last; do :; done
+if [ -f "` + dir + `/$last" ]; then cat "` + dir + `/$last"; exit 0; fi
+echo "ERROR: Data Error : $last" >&2
+exit 2
+`
+	tool := filepath.Join(bin, "7z")
+	if err := os.WriteFile(tool, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	withTools(t, map[string]string{"7z": tool})
+}
+
+// withTools makes only the given tools visible to the package
+func withTools(t *testing.T, tools map[string]string) {
+	t.Helper()
+	saved := lookPath
+	lookPath = func(name string) (string, error) {
+		if tool, ok := tools[name]; ok {
+			return tool, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	t.Cleanup(func() { lookPath = saved })
+}
+
+// writeFile writes a file under dir and returns its path
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckSupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		tools   map[string]string
+		format  Format
+		wantErr string
+	}{
+		{"zip needs no tool", nil, FormatZIP, ""},
+		{"7z without 7-Zip", nil, Format7Z, "install 7-Zip"},
+		{"rar without 7-Zip", map[string]string{"xz": "/bin/xz"}, FormatRAR, "install 7-Zip"},
+		{"xz without tools", nil, FormatXZ, "install xz or 7-Zip"},
+		{"7z with 7zz", map[string]string{"7zz": "/bin/7zz"}, Format7Z, ""},
+		{"xz with xz", map[string]string{"xz": "/bin/xz"}, FormatXZ, ""},
+		{"xz with 7-Zip", map[string]string{"7za": "/bin/7za"}, FormatXZ, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTools(t, tt.tools)
+			err := CheckSupported(tt.format)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("err = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnsupportedFormat) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want ErrUnsupportedFormat naming %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractUnsupportedWithoutTool(t *testing.T) {
+	withTools(t, nil)
+	path := writeFile(t, t.TempDir(), "seized.7z", append([]byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, make([]byte, 32)...))
+	_, err := Extract(path, t.TempDir(), NewBudget(DefaultLimits()))
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("err = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+const sevenZipListing = `
+7-Zip [64] 16.02 : Copyright (c) 1999-2016 Igor Pavlov : 2016-05-21
+
+Listing archive: seized.7z
+
+--
+Path = seized.7z
+Type = 7z
+
+----------
+Path = docs
+Folder = +
+Attributes = D_ drwxr-xr-x
+
+Path = docs/report.txt
+Size = 12
+Modified = 2024-03-01 10:15:30.1234567
+Attributes = A_ -rw-r--r--
+
+Path = docs/link
+Size = 10
+Modified = 2024-03-01 10:15:30
+Attributes = A_ lrwxrwxrwx
+
+Path = notes *.txt
+Size = 5
+Modified = 2024-03-02 08:00:00
+Attributes = A_ -rw-r--r--
+
+Path = damaged.bin
+Size = 5
+Modified = 2024-03-02 08:00:00
+Attributes = A_ -rw-r--r--
+`
+
+func TestExtractSevenZip(t *testing.T) {
+	files := t.TempDir()
+	os.MkdirAll(filepath.Join(files, "docs"), 0755)
+	writeFile(t, files, "docs/report.txt", []byte("hello report"))
+	writeFile(t, files, "notes *.txt", []byte("notes"))
+	fakeSevenZip(t, files, sevenZipListing)
+
+	tests := []struct {
+		name        string
+		limits      Limits
+		wantEntries []string
+		wantSkipped int
+		wantErr     error
+	}{
+		{"within limits", DefaultLimits(), []string{"docs/report.txt", "notes *.txt"}, 2, nil},
+		{"total size exceeded", Limits{MaxTotalSize: 8}, nil, 0, ErrLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), "seized.7z", append([]byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, make([]byte, 32)...))
+			listing, err := Extract(path, t.TempDir(), NewBudget(tt.limits))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if listing.Format != Format7Z {
+				t.Errorf("format = %s", listing.Format)
+			}
+			var got []string
+			for _, e := range listing.Entries {
+				got = append(got, e.Path)
+				data, _ := os.ReadFile(e.ExtractedPath)
+				if int64(len(data)) != e.Size {
+					t.Errorf("%s: wrote %d bytes, listed %d", e.Path, len(data), e.Size)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantEntries, ",") {
+				t.Errorf("entries = %v, want %v", got, tt.wantEntries)
+			}
+			if len(listing.Skipped) != tt.wantSkipped {
+				t.Errorf("skipped = %v, want %d", listing.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestExtractXZ(t *testing.T) {
+	xz, err := exec.LookPath("xz")
+	if err != nil {
+		t.Skip("xz is not installed")
+	}
+	withTools(t, map[string]string{"xz": xz})
+
+	dir := t.TempDir()
+	plain := writeFile(t, dir, "mail.mbox", []byte("From: a@example.com\n"))
+	if out, err := exec.Command(xz, "-z", plain).CombinedOutput(); err != nil {
+		t.Fatalf("xz: %v: %s", err, out)
+	}
+	corrupt := writeFile(t, dir, "broken.xz", []byte{0xFD, '7', 'z', 'X', 'Z', 0x00, 1, 2, 3, 4})
+
+	tests := []struct {
+		name     string
+		path     string
+		wantName string
+		wantErr  bool
+	}{
+		{"single file", plain + ".xz", "mail.mbox", false},
+		{"damaged stream", corrupt, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, err := Extract(tt.path, t.TempDir(), NewBudget(DefaultLimits()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(listing.Entries) != 0 {
+					t.Errorf("damaged stream produced %v", listing.Entries)
+				}
+				return
+			}
+			if listing.Format != FormatXZ || len(listing.Entries) != 1 || listing.Entries[0].Path != tt.wantName {
+				t.Errorf("listing = %+v", listing)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sevenZipTools are the names under which 7-Zip is installed
+var sevenZipTools = []string{"7z", "7zz", "7za"}
+
+// lookPath finds external tools; replaced in tests
+var lookPath = exec.LookPath
+
+// sevenZipTool returns the path of the installed 7-Zip program, or "" when
+// there is none
+func sevenZipTool() string {
+	for _, name := range sevenZipTools {
+		if tool, err := lookPath(name); err == nil {
+			return tool
+		}
+	}
+	return ""
+}
+
+// CheckSupported returns nil when a format can be expanded, and otherwise an
+// ErrUnsupportedFormat error naming the missing tool. 7z and RAR archives
+// are expanded with 7-Zip, and XZ streams with xz or 7-Zip.
+func CheckSupported(format Format) error {
+	switch format {
+	case FormatZIP, FormatTAR, FormatTARGZ, FormatTARBZ2, FormatGZIP, FormatBZIP2:
+		return nil
+	case Format7Z, FormatRAR:
+		if sevenZipTool() == "" {
+			return fmt.Errorf("%w: %s (install 7-Zip to expand it)", ErrUnsupportedFormat, format)
+		}
+		return nil
+	case FormatXZ:
+		if _, err := lookPath("xz"); err != nil && sevenZipTool() == "" {
+			return fmt.Errorf("%w: %s (install xz or 7-Zip to expand it)", ErrUnsupportedFormat, format)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// commandReader streams the output of an external tool. Reading to the end
+// returns the tool's failure, if any, so that a damaged archive is not taken
+// for a short file.
+type commandReader struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+	done   bool
+	err    error // Returned once the output is exhausted
+}
+
+// startCommand runs a tool and returns a reader for its output
+func startCommand(cmd *exec.Cmd) (*commandReader, error) {
+	r := &commandReader{cmd: cmd}
+	cmd.Stderr = &r.stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	r.stdout = stdout
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", cmd.Path, err)
+	}
+	return r, nil
+}
+
+func (r *commandReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, r.err
+	}
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		r.done = true
+		r.err = io.EOF
+		if waitErr := r.cmd.Wait(); waitErr != nil {
+			r.err = r.failure(waitErr)
+		}
+		return n, r.err
+	}
+	return n, err
+}
+
+// Close stops the tool if its output was not read to the end
+func (r *commandReader) Close() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	r.cmd.Process.Kill()
+	r.cmd.Wait()
+	return nil
+}
+
+// failure describes why the tool failed
+func (r *commandReader) failure(err error) error {
+	if msg := strings.TrimSpace(r.stderr.String()); msg != "" {
+		return fmt.Errorf("%s failed: %s", r.cmd.Path, lastLine(msg))
+	}
+	return fmt.Errorf("%s failed: %w", r.cmd.Path, err)
+}
+
+// extractXZ decompresses an XZ stream, which holds either a tar archive or a
+// single file
+func (x *extractor) extractXZ(filePath string) error {
+	var cmd *exec.Cmd
+	if tool, err := lookPath("xz"); err == nil {
+		cmd = exec.Command(tool, "-dc", "--", filePath)
+	} else {
+		cmd = exec.Command(sevenZipTool(), "x", "-so", "--", filePath)
+	}
+	rc, err := startCommand(cmd)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	br := bufio.NewReader(rc)
+	header, _ := br.Peek(512)
+	if isTarHeader(header) {
+		return x.extractTAR(br)
+	}
+	return x.extractStream(br, streamName(filePath, "", ".xz"), time.Time{})
+}
+
+// sevenZipEntry is a file listed by 7-Zip
+type sevenZipEntry struct {
+	path    string
+	modTime time.Time
+	dir     bool
+	mode    fs.FileMode
+}
+
+// extractSevenZip extracts a 7z or RAR archive with 7-Zip. The archive is
+// listed first and each file is then streamed through writeEntry, so that
+// the budget applies as it does to the formats read natively.
+func (x *extractor) extractSevenZip(filePath string) error {
+	tool := sevenZipTool()
+	out, err := exec.Command(tool, "l", "-slt", "--", filePath).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("failed to list archive: %s", lastLine(strings.TrimSpace(string(exitErr.Stderr))))
+		}
+		return fmt.Errorf("failed to list archive: %w", err)
+	}
+
+	for _, entry := range parseSevenZipListing(string(out)) {
+		switch {
+		case entry.dir:
+			continue
+		case !entry.mode.IsRegular():
+			x.listing.Skipped = append(x.listing.Skipped, fmt.Sprintf("%s: %s entry", entry.path, entry.mode.Type()))
+			continue
+		}
+
+		// -spd matches the name literally rather than as a wildcard
+		rc, err := startCommand(exec.Command(tool, "x", "-so", "-spd", "--", filePath, entry.path))
+		if err != nil {
+			return err
+		}
+		err = x.writeEntry(rc, entry.path, entry.modTime, entry.mode)
+		rc.Close()
+		if err != nil {
+			if errors.Is(err, ErrLimitExceeded) {
+				return err
+			}
+			x.listing.Skipped = append(x.listing.Skipped, fmt.Sprintf("%s: %v", entry.path, err))
+		}
+	}
+	return nil
+}
+
+// parseSevenZipListing reads the entries of "7z l -slt" output, which
+// follow a line of dashes as blocks of "Key = Value" lines
+func parseSevenZipListing(out string) []sevenZipEntry {
+	var entries []sevenZipEntry
+	var current *sevenZipEntry
+	started := false
+	for _, line := range strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n") {
+		if !started {
+			started = strings.HasPrefix(line, "----------")
+			continue
+		}
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Path":
+			entries = append(entries, sevenZipEntry{path: value, mode: 0644})
+			current = &entries[len(entries)-1]
+		case "Modified":
+			if current != nil {
+				if len(value) > 19 {
+					value = value[:19] // Drop fractional seconds
+				}
+				current.modTime, _ = time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+			}
+		case "Folder":
+			if current != nil && value == "+" {
+				current.dir = true
+			}
+		case "Attributes":
+			if current != nil {
+				current.dir = current.dir || strings.HasPrefix(value, "D")
+				current.mode = attributeMode(value)
+			}
+		}
+	}
+	return entries
+}
+
+// attributeMode reads the Unix file type 7-Zip prints after the Windows
+// attributes, such as "A_ -rw-r--r--" or "A_ lrwxrwxrwx"
+func attributeMode(attributes string) fs.FileMode {
+	fields := strings.Fields(attributes)
+	if len(fields) < 2 || len(fields[1]) != 10 {
+		return 0644
+	}
+	switch fields[1][0] {
+	case 'l':
+		return fs.ModeSymlink | 0777
+	case 'c':
+		return fs.ModeDevice | fs.ModeCharDevice
+	case 'b':
+		return fs.ModeDevice
+	case 'p':
+		return fs.ModeNamedPipe
+	case 's':
+		return fs.ModeSocket
+	}
+	return 0644
+}
+
+// lastLine returns the last line of a tool's error output
+func lastLine(s string) string {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return strings.TrimSpace(s[i+1:])
+	}
+	return s
+}
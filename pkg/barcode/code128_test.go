@@ -0,0 +1,57 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+// widths returns the run lengths of the modules of a symbol as digits
+func widths(modules []bool) string {
+	var b strings.Builder
+	for i := 0; i < len(modules); {
+		n := 1
+		for i+n < len(modules) && modules[i+n] == modules[i] {
+			n++
+		}
+		b.WriteByte(byte('0' + n))
+		i += n
+	}
+	return b.String()
+}
+
+func TestCode128Modules(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string // Patterns of the symbol values, start code to stop code
+	}{
+		// Start B, "A" (33), checksum (104+33)%103 = 34, stop
+		{"A", []string{"211214", "111323", "131123", "2331112"}},
+		// Start C, "12" (12), "34" (34), checksum (105+12+68)%103 = 82, stop
+		{"1234", []string{"211232", "112232", "131123", "121241", "2331112"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			modules, err := Code128(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := widths(modules), strings.Join(tt.want, ""); got != want {
+				t.Errorf("widths = %s, want %s", got, want)
+			}
+			if want := 11*(len(tt.want)-1) + 13; len(modules) != want {
+				t.Errorf("%d modules, want %d", len(modules), want)
+			}
+			if !modules[len(modules)-1] || !modules[len(modules)-2] || modules[len(modules)-3] {
+				t.Error("symbol does not end with the two-module terminating bar")
+			}
+		})
+	}
+}
+
+func TestCode128RejectsUnsupportedText(t *testing.T) {
+	for _, text := range []string{"", "tab\there", "café"} {
+		if _, err := Code128(text); err == nil {
+			t.Errorf("Code128(%q) succeeded", text)
+		}
+	}
+}
@@ -0,0 +1,545 @@
+package barcode
+
+import (
+	"fmt"
+)
+
+// ECLevel is the QR error correction level
+type ECLevel int
+
+const (
+	ECLevelL ECLevel = iota // Recovers ~7% of damaged codewords
+	ECLevelM                // Recovers ~15%
+	ECLevelQ                // Recovers ~25%
+	ECLevelH                // Recovers ~30%
+)
+
+// qrMaxVersion is the largest QR version supported. Version 10 holds up to
+// 271 bytes at level L, which is ample for label payloads.
+const qrMaxVersion = 10
+
+// qrBlockGroup describes a group of Reed-Solomon blocks with the same size
+type qrBlockGroup struct {
+	count    int // Number of blocks in the group
+	dataSize int // Data codewords per block
+}
+
+// qrECInfo describes the error correction layout for a version and level
+type qrECInfo struct {
+	ecPerBlock int
+	groups     []qrBlockGroup
+}
+
+// qrECTable is indexed by [version-1][ECLevel]
+var qrECTable = [qrMaxVersion][4]qrECInfo{
+	{{7, []qrBlockGroup{{1, 19}}}, {10, []qrBlockGroup{{1, 16}}}, {13, []qrBlockGroup{{1, 13}}}, {17, []qrBlockGroup{{1, 9}}}},
+	{{10, []qrBlockGroup{{1, 34}}}, {16, []qrBlockGroup{{1, 28}}}, {22, []qrBlockGroup{{1, 22}}}, {28, []qrBlockGroup{{1, 16}}}},
+	{{15, []qrBlockGroup{{1, 55}}}, {26, []qrBlockGroup{{1, 44}}}, {18, []qrBlockGroup{{2, 17}}}, {22, []qrBlockGroup{{2, 13}}}},
+	{{20, []qrBlockGroup{{1, 80}}}, {18, []qrBlockGroup{{2, 32}}}, {26, []qrBlockGroup{{2, 24}}}, {16, []qrBlockGroup{{4, 9}}}},
+	{{26, []qrBlockGroup{{1, 108}}}, {24, []qrBlockGroup{{2, 43}}}, {18, []qrBlockGroup{{2, 15}, {2, 16}}}, {22, []qrBlockGroup{{2, 11}, {2, 12}}}},
+	{{18, []qrBlockGroup{{2, 68}}}, {16, []qrBlockGroup{{4, 27}}}, {24, []qrBlockGroup{{4, 19}}}, {28, []qrBlockGroup{{4, 15}}}},
+	{{20, []qrBlockGroup{{2, 78}}}, {18, []qrBlockGroup{{4, 31}}}, {18, []qrBlockGroup{{2, 14}, {4, 15}}}, {26, []qrBlockGroup{{4, 13}, {1, 14}}}},
+	{{24, []qrBlockGroup{{2, 97}}}, {22, []qrBlockGroup{{2, 38}, {2, 39}}}, {22, []qrBlockGroup{{4, 18}, {2, 19}}}, {26, []qrBlockGroup{{4, 14}, {2, 15}}}},
+	{{30, []qrBlockGroup{{2, 116}}}, {22, []qrBlockGroup{{3, 36}, {2, 37}}}, {20, []qrBlockGroup{{4, 16}, {4, 17}}}, {24, []qrBlockGroup{{4, 12}, {4, 13}}}},
+	{{18, []qrBlockGroup{{2, 68}, {2, 69}}}, {26, []qrBlockGroup{{4, 43}, {1, 44}}}, {24, []qrBlockGroup{{6, 19}, {2, 20}}}, {28, []qrBlockGroup{{6, 15}, {2, 16}}}},
+}
+
+// qrAlignment lists alignment pattern centre coordinates per version
+var qrAlignment = [qrMaxVersion][]int{
+	{}, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34}, {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+// qrFormatBits are the two format-information bits for each level
+var qrFormatBits = [4]int{1, 0, 3, 2}
+
+// QRCode is an encoded QR symbol. Modules[y][x] is true for dark modules.
+// Quiet zones are not included.
+type QRCode struct {
+	Version int
+	Level   ECLevel
+	Size    int
+	Modules [][]bool
+}
+
+// EncodeQR encodes data in byte mode using the smallest version that fits
+func EncodeQR(data []byte, level ECLevel) (*QRCode, error) {
+	if level < ECLevelL || level > ECLevelH {
+		return nil, fmt.Errorf("invalid error correction level: %d", level)
+	}
+
+	for version := 1; version <= qrMaxVersion; version++ {
+		if len(data) <= qrByteCapacity(version, level) {
+			return encodeQRVersion(data, version, level), nil
+		}
+	}
+
+	return nil, fmt.Errorf("data too long for a QR code: %d bytes (maximum %d)",
+		len(data), qrByteCapacity(qrMaxVersion, level))
+}
+
+// qrDataCodewords returns the number of data codewords for a version and level
+func qrDataCodewords(version int, level ECLevel) int {
+	total := 0
+	for _, g := range qrECTable[version-1][level].groups {
+		total += g.count * g.dataSize
+	}
+	return total
+}
+
+// qrCountBits returns the width of the byte-mode character count field
+func qrCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// qrByteCapacity returns how many bytes fit in a version and level
+func qrByteCapacity(version int, level ECLevel) int {
+	return (qrDataCodewords(version, level)*8 - 4 - qrCountBits(version)) / 8
+}
+
+// encodeQRVersion builds the symbol for a fixed version
+func encodeQRVersion(data []byte, version int, level ECLevel) *QRCode {
+	codewords := qrInterleave(qrDataStream(data, version, level), version, level)
+
+	size := version*4 + 17
+	q := &qrMatrix{
+		size:     size,
+		modules:  newGrid(size),
+		function: newGrid(size),
+	}
+	q.drawFunctionPatterns(version)
+	q.drawCodewords(codewords)
+
+	// Pick the mask with the lowest penalty
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(level, mask)
+		penalty := q.penalty()
+		if bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask) // XOR again to undo
+	}
+	q.applyMask(bestMask)
+	q.drawFormatBits(level, bestMask)
+
+	return &QRCode{
+		Version: version,
+		Level:   level,
+		Size:    size,
+		Modules: q.modules,
+	}
+}
+
+// qrDataStream builds the padded data codeword sequence
+func qrDataStream(data []byte, version int, level ECLevel) []byte {
+	capacity := qrDataCodewords(version, level)
+	var bits bitBuffer
+
+	bits.append(0x4, 4) // Byte mode indicator
+	bits.append(len(data), qrCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	// Terminator and padding to a byte boundary
+	terminator := capacity*8 - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	if rem := len(bits) % 8; rem != 0 {
+		bits.append(0, 8-rem)
+	}
+
+	result := bits.bytes()
+	for pad := byte(0xEC); len(result) < capacity; pad ^= 0xEC ^ 0x11 {
+		result = append(result, pad)
+	}
+	return result
+}
+
+// qrInterleave splits data into blocks, appends error correction and
+// interleaves the result
+func qrInterleave(data []byte, version int, level ECLevel) []byte {
+	info := qrECTable[version-1][level]
+	generator := rsGenerator(info.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	maxData := 0
+	for _, g := range info.groups {
+		for i := 0; i < g.count; i++ {
+			block := data[offset : offset+g.dataSize]
+			offset += g.dataSize
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, generator))
+			if g.dataSize > maxData {
+				maxData = g.dataSize
+			}
+		}
+	}
+
+	var result []byte
+	for i := 0; i < maxData; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < info.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// qrMatrix holds a symbol under construction
+type qrMatrix struct {
+	size     int
+	modules  [][]bool
+	function [][]bool // Modules reserved for function patterns
+}
+
+func newGrid(size int) [][]bool {
+	grid := make([][]bool, size)
+	for i := range grid {
+		grid[i] = make([]bool, size)
+	}
+	return grid
+}
+
+// setFunction sets a function module and marks it reserved
+func (q *qrMatrix) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFunctionPatterns draws finder, timing and alignment patterns and
+// reserves the format and version areas
+func (q *qrMatrix) drawFunctionPatterns(version int) {
+	// Timing patterns
+	for i := 0; i < q.size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with separators
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+
+	// Alignment patterns, skipping those that overlap finders
+	positions := qrAlignment[version-1]
+	n := len(positions)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue
+			}
+			q.drawAlignment(positions[i], positions[j])
+		}
+	}
+
+	// Reserve format areas; the real bits are drawn after masking
+	q.drawFormatBits(ECLevelL, 0)
+	q.drawVersionBits(version)
+}
+
+// drawFinder draws a finder pattern and its separator centred at (cx, cy)
+func (q *qrMatrix) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= q.size || y < 0 || y >= q.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			q.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centred at (cx, cy)
+func (q *qrMatrix) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the format information
+func (q *qrMatrix) drawFormatBits(level ECLevel, mask int) {
+	data := qrFormatBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	// First copy, around the top-left finder
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(bits, i))
+	}
+	q.setFunction(8, 7, bit(bits, 6))
+	q.setFunction(8, 8, bit(bits, 7))
+	q.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	// Second copy, split between the other two finders
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(bits, i))
+	}
+	q.setFunction(8, q.size-8, true) // Always dark
+}
+
+// drawVersionBits draws the version information for versions 7 and up
+func (q *qrMatrix) drawVersionBits(version int) {
+	if version < 7 {
+		return
+	}
+
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		a := q.size - 11 + i%3
+		b := i / 3
+		q.setFunction(a, b, bit(bits, i))
+		q.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords places the data and error correction bits in the zigzag
+// order defined by the standard
+func (q *qrMatrix) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < q.size; vert++ {
+			y := vert
+			if upward {
+				y = q.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if q.function[y][x] {
+					continue
+				}
+				if i < len(codewords)*8 {
+					q.modules[y][x] = bit(int(codewords[i>>3]), 7-(i&7))
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask XORs a mask pattern over all non-function modules
+func (q *qrMatrix) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol using the four rules from the standard
+func (q *qrMatrix) penalty() int {
+	score := 0
+	get := func(x, y int, vertical bool) bool {
+		if vertical {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < q.size; y++ {
+			// Rule 1: runs of five or more same-colored modules
+			run := 1
+			for x := 1; x < q.size; x++ {
+				if get(x, y, vertical) == get(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+
+			// Rule 3: finder-like 1:1:3:1:1 patterns next to four light
+			// modules; modules outside the symbol count as light
+			line := func(x int) bool {
+				return x >= 0 && x < q.size && get(x, y, vertical)
+			}
+			for x := 0; x+7 <= q.size; x++ {
+				if matchFinderCore(line, x) && (lightRun(line, x-4, x) || lightRun(line, x+7, x+11)) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	// Rule 2: 2x2 blocks of the same color
+	dark := 0
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < q.size && y+1 < q.size {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// Rule 4: balance of dark and light modules
+	total := q.size * q.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	score += k * 10
+
+	return score
+}
+
+// matchFinderCore checks for dark-light-dark×3-light-dark starting at x
+func matchFinderCore(line func(int) bool, x int) bool {
+	core := [7]bool{true, false, true, true, true, false, true}
+	for i, want := range core {
+		if line(x+i) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// lightRun reports whether every module in [from, to) is light
+func lightRun(line func(int) bool, from, to int) bool {
+	for x := from; x < to; x++ {
+		if line(x) {
+			return false
+		}
+	}
+	return true
+}
+
+// bitBuffer accumulates bits most significant first
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 != 0)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, (len(b)+7)/8)
+	for i, v := range b {
+		if v {
+			result[i>>3] |= 0x80 >> (i & 7)
+		}
+	}
+	return result
+}
+
+// rsGenerator returns the Reed-Solomon generator polynomial of the given
+// degree over GF(256), highest-order coefficient omitted
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder computes the error correction codewords for a data block
+func rsRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(generator[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies two elements of GF(256) modulo x^8+x^4+x^3+x^2+1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func bit(value, i int) bool {
+	return (value>>i)&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
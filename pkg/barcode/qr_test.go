@@ -0,0 +1,352 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The decoder below reads a symbol back following ISO/IEC 18004 with its
+// own tables, so that the test does not share the encoder's layout code.
+
+// testBlocks is the block structure of the versions and levels under test:
+// error correction codewords per block and data codewords of each block
+var testBlocks = map[[2]int]struct {
+	ec   int
+	data []int
+}{
+	{1, int(ECLevelL)}:  {7, []int{19}},
+	{1, int(ECLevelM)}:  {10, []int{16}},
+	{2, int(ECLevelM)}:  {16, []int{28}},
+	{5, int(ECLevelQ)}:  {18, []int{15, 15, 16, 16}},
+	{7, int(ECLevelH)}:  {26, []int{13, 13, 13, 13, 14}},
+	{10, int(ECLevelM)}: {26, []int{43, 43, 43, 43, 44}},
+	{10, int(ECLevelL)}: {18, []int{68, 68, 69, 69}},
+}
+
+// testAlignment lists the alignment pattern centres of the versions under test
+var testAlignment = map[int][]int{1: {}, 2: {6, 18}, 5: {6, 30}, 7: {6, 22, 38}, 10: {6, 28, 50}}
+
+// testLevels maps the two format-information level bits to levels
+var testLevels = map[int]ECLevel{1: ECLevelL, 0: ECLevelM, 3: ECLevelQ, 2: ECLevelH}
+
+// bchCode appends the BCH remainder of value to it
+func bchCode(value, valueBits, generator, generatorBits int) int {
+	code := value << (generatorBits - 1)
+	for i := valueBits + generatorBits - 2; i >= generatorBits-1; i-- {
+		if code&(1<<i) != 0 {
+			code ^= generator << (i - generatorBits + 1)
+		}
+	}
+	return value<<(generatorBits-1) | code
+}
+
+// decodeQR reads the data bytes back from a symbol
+func decodeQR(q *QRCode) ([]byte, error) {
+	size := len(q.Modules)
+	version := (size - 17) / 4
+	if size != q.Size || size != version*4+17 {
+		return nil, fmt.Errorf("invalid symbol size %d", size)
+	}
+	dark := func(x, y int) bool { return q.Modules[y][x] }
+
+	// Finder patterns
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := max(abs(dx-3), abs(dy-3))
+				if dark(corner[0]+dx, corner[1]+dy) != (ring != 2) {
+					return nil, fmt.Errorf("bad finder pattern at %v", corner)
+				}
+			}
+		}
+	}
+	if !dark(8, size-8) {
+		return nil, fmt.Errorf("dark module missing")
+	}
+
+	// Both copies of the format information
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= boolBit(dark(8, i)) << i
+	}
+	first |= boolBit(dark(8, 7))<<6 | boolBit(dark(8, 8))<<7 | boolBit(dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		first |= boolBit(dark(14-i, 8)) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= boolBit(dark(size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= boolBit(dark(8, size-15+i)) << i
+	}
+	if first != second {
+		return nil, fmt.Errorf("format copies differ: %015b, %015b", first, second)
+	}
+	format := first ^ 0x5412
+	if bchCode(format>>10, 5, 0x537, 11) != format {
+		return nil, fmt.Errorf("invalid format information %015b", format)
+	}
+	level, mask := testLevels[format>>13], format>>10&7
+	if level != q.Level {
+		return nil, fmt.Errorf("format level %d, symbol level %d", level, q.Level)
+	}
+
+	// Version information, from version 7
+	if version >= 7 {
+		var a, b int
+		for i := 0; i < 18; i++ {
+			a |= boolBit(dark(size-11+i%3, i/3)) << i
+			b |= boolBit(dark(i/3, size-11+i%3)) << i
+		}
+		if a != b || a != bchCode(version, 6, 0x1F25, 13) {
+			return nil, fmt.Errorf("invalid version information %018b, %018b", a, b)
+		}
+	}
+
+	// Function modules
+	reserved := make([][]bool, size)
+	for y := range reserved {
+		reserved[y] = make([]bool, size)
+	}
+	fill := func(x0, y0, w, h int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				reserved[y][x] = true
+			}
+		}
+	}
+	fill(0, 0, 9, 9)
+	fill(size-8, 0, 8, 9)
+	fill(0, size-8, 9, 8)
+	fill(6, 0, 1, size)
+	fill(0, 6, size, 1)
+	positions := testAlignment[version]
+	for i, cy := range positions {
+		for j, cx := range positions {
+			last := len(positions) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			fill(cx-2, cy-2, 5, 5)
+		}
+	}
+	if version >= 7 {
+		fill(size-11, 0, 3, 6)
+		fill(0, size-11, 6, 3)
+	}
+
+	// Codewords, read in the two-module-wide zigzag and unmasked
+	var raw bytes.Buffer
+	var cur, n int
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if reserved[y][x] {
+					continue
+				}
+				cur = cur<<1 | boolBit(dark(x, y) != testMask(mask, x, y))
+				if n++; n%8 == 0 {
+					raw.WriteByte(byte(cur))
+					cur = 0
+				}
+			}
+		}
+	}
+
+	// De-interleave the blocks and check their error correction
+	blocks, ok := testBlocks[[2]int{version, int(level)}]
+	if !ok {
+		return nil, fmt.Errorf("no block table for version %d level %d", version, level)
+	}
+	codewords := raw.Bytes()
+	split := make([][]byte, len(blocks.data))
+	pos := 0
+	for i := 0; i < blocks.data[len(blocks.data)-1]; i++ {
+		for b, size := range blocks.data {
+			if i < size {
+				split[b] = append(split[b], codewords[pos])
+				pos++
+			}
+		}
+	}
+	for i := 0; i < blocks.ec; i++ {
+		for b := range split {
+			split[b] = append(split[b], codewords[pos])
+			pos++
+		}
+	}
+	var data []byte
+	for b, block := range split {
+		for i := 0; i < blocks.ec; i++ {
+			if s := testSyndrome(block, i); s != 0 {
+				return nil, fmt.Errorf("block %d: syndrome %d is %d", b, i, s)
+			}
+		}
+		data = append(data, block[:blocks.data[b]]...)
+	}
+
+	// Byte-mode segment
+	reader := &testBitReader{data: data}
+	if m := reader.read(4); m != 0x4 {
+		return nil, fmt.Errorf("mode %04b, want byte mode", m)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	count := reader.read(countBits)
+	result := make([]byte, count)
+	for i := range result {
+		result[i] = byte(reader.read(8))
+	}
+	if reader.pos > len(data)*8 {
+		return nil, fmt.Errorf("count %d overruns the data codewords", count)
+	}
+	return result, nil
+}
+
+// testMask reports whether a mask pattern inverts the module at x, y
+func testMask(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// testSyndrome evaluates a Reed-Solomon block at the i-th power of the
+// generator root; it is zero for a valid block
+func testSyndrome(block []byte, i int) byte {
+	root := byte(1)
+	for k := 0; k < i; k++ {
+		root = testMul(root, 2)
+	}
+	var s byte
+	for _, c := range block {
+		s = testMul(s, root) ^ c
+	}
+	return s
+}
+
+// testMul multiplies in GF(256) modulo x^8 + x^4 + x^3 + x^2 + 1
+func testMul(a, b byte) byte {
+	var p byte
+	for ; b != 0; b >>= 1 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a&0x80 != 0
+		a <<= 1
+		if carry {
+			a ^= 0x1D
+		}
+	}
+	return p
+}
+
+// testBitReader reads big-endian bit fields
+type testBitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *testBitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit := 0
+		if r.pos/8 < len(r.data) && r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0 {
+			bit = 1
+		}
+		v = v<<1 | bit
+		r.pos++
+	}
+	return v
+}
+
+func boolBit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEncodeQR(t *testing.T) {
+	allBytes := make([]byte, 200)
+	for i := range allBytes {
+		allBytes[i] = byte(i * 7)
+	}
+	tests := []struct {
+		name        string
+		data        []byte
+		level       ECLevel
+		wantVersion int
+	}{
+		{"one byte", []byte("A"), ECLevelL, 1},
+		{"full version 1", []byte("EVD1|C-12|E-34"), ECLevelM, 1},
+		{"one byte over version 1", []byte("EVD1|C-12|E-345"), ECLevelM, 2},
+		{"two block groups", []byte(strings.Repeat("0123456789", 6)), ECLevelQ, 5},
+		{"version information", []byte(strings.Repeat("EVD1|", 12) + "DATA"), ECLevelH, 7},
+		{"binary data with a 16-bit count", allBytes, ECLevelM, 10},
+		{"largest symbol", bytes.Repeat([]byte{0xFF}, 271), ECLevelL, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := EncodeQR(tt.data, tt.level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", q.Version, tt.wantVersion)
+			}
+			got, err := decodeQR(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("decoded %q, want %q", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestEncodeQRRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		level ECLevel
+	}{
+		{"too long", make([]byte, 272), ECLevelL},
+		{"too long at level H", make([]byte, 120), ECLevelH},
+		{"invalid level", []byte("A"), ECLevel(4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncodeQR(tt.data, tt.level); err == nil {
+				t.Error("EncodeQR succeeded")
+			}
+		})
+	}
+}
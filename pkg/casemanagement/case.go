@@ -2,6 +2,7 @@ package casemanagement
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -199,6 +200,34 @@ func (s *CaseService) AddPerson(caseID string, p Person) error {
 	return s.repo.Update(c)
 }
 
+// FindPerson returns the victim, suspect or witness of a case with an email
+// address, or failing that with a full name; nil if there is none
+func (s *CaseService) FindPerson(caseID, email, name string) (*Person, error) {
+	c, err := s.repo.Find(caseID)
+	if err != nil {
+		return nil, err
+	}
+
+	people := append(append(append([]Person{}, c.Victims...), c.Suspects...), c.Witnesses...)
+	if email != "" {
+		for i := range people {
+			for _, address := range people[i].EmailAddresses {
+				if strings.EqualFold(address, email) {
+					return &people[i], nil
+				}
+			}
+		}
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		for i := range people {
+			if strings.EqualFold(people[i].FullName, name) {
+				return &people[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
 // AddEvent adds an event to a case timeline
 func (s *CaseService) AddEvent(caseID string, event Event) error {
 	c, err := s.repo.Find(caseID)
@@ -0,0 +1,106 @@
+package casemanagement
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/entity"
+)
+
+// AddPersonFromEntities adds the person named by an entity extracted from a
+// document to a case. Phone numbers, email addresses and an address among
+// the details are recorded as such; other details go in the notes.
+func (s *CaseService) AddPersonFromEntities(caseID, role, documentID string, name entity.Entity, details []entity.Entity) (*Person, error) {
+	if name.Kind != entity.KindPerson {
+		return nil, fmt.Errorf("entity %s is a %s, not a person", name.ID, strings.ToLower(string(name.Kind)))
+	}
+	existing, err := s.FindPerson(caseID, "", name.Value)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%s is already in the case as %s", name.Value, existing.ID)
+	}
+
+	p := Person{
+		ID:       generateID(),
+		FullName: name.Value,
+		Role:     role,
+	}
+	if documentID != "" {
+		p.DocumentIDs = []string{documentID}
+	}
+	var notes []string
+	for _, d := range details {
+		switch {
+		case d.Kind == entity.KindPhone:
+			p.PhoneNumbers = append(p.PhoneNumbers, d.Text)
+		case d.Kind == entity.KindEmail:
+			p.EmailAddresses = append(p.EmailAddresses, d.Value)
+		case d.Kind == entity.KindAddress && p.Address == "":
+			p.Address = d.Value
+		default:
+			notes = append(notes, fmt.Sprintf("%s: %s", strings.ToLower(string(d.Kind)), d.Text))
+		}
+	}
+	if len(notes) > 0 {
+		p.Notes = "From " + documentID + ": " + strings.Join(notes, "; ")
+	}
+
+	if err := s.AddPerson(caseID, p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// AddEventFromEntities adds a timeline event at the date of an entity
+// extracted from a document. Related people already in the case become
+// participants and the first related address the location.
+func (s *CaseService) AddEventFromEntities(caseID, documentID, description, createdBy string, date entity.Entity, related []entity.Entity) (*Event, error) {
+	if date.Kind != entity.KindDate {
+		return nil, fmt.Errorf("entity %s is a %s, not a date", date.ID, strings.ToLower(string(date.Kind)))
+	}
+	if strings.TrimSpace(description) == "" {
+		return nil, fmt.Errorf("an event description is required")
+	}
+
+	e := Event{
+		ID:          generateID(),
+		Timestamp:   date.Time,
+		Description: description,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+	if documentID != "" {
+		e.DocumentIDs = []string{documentID}
+	}
+	var unknown []string
+	for _, r := range related {
+		switch r.Kind {
+		case entity.KindPerson:
+			p, err := s.FindPerson(caseID, "", r.Value)
+			if err != nil {
+				return nil, err
+			}
+			if p == nil {
+				unknown = append(unknown, r.Value)
+			} else {
+				e.Participants = append(e.Participants, p.ID)
+			}
+		case entity.KindAddress:
+			if e.Location == "" {
+				e.Location = r.Value
+			}
+		}
+	}
+	// People not in the case are named in the description instead
+	if len(unknown) > 0 {
+		e.Description += " (also involved: " + strings.Join(unknown, ", ") + ")"
+	}
+
+	if err := s.AddEvent(caseID, e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
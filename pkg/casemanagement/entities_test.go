@@ -0,0 +1,142 @@
+package casemanagement
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/entity"
+)
+
+// memRepo keeps cases in memory, sharing them as the CLI does
+type memRepo struct {
+	CaseRepository
+	cases map[string]*Case
+}
+
+func (r *memRepo) Find(id string) (*Case, error) {
+	c, ok := r.cases[id]
+	if !ok {
+		return nil, fmt.Errorf("case not found: %s", id)
+	}
+	return c, nil
+}
+
+func (r *memRepo) Update(c *Case) error {
+	r.cases[c.ID] = c
+	return nil
+}
+
+// newCaseService creates a service holding case C1 with one suspect
+func newCaseService() *CaseService {
+	return NewCaseService(&memRepo{cases: map[string]*Case{
+		"C1": {ID: "C1", Suspects: []Person{{ID: "P1", FullName: "John Smith", Role: "Suspect"}}},
+	}})
+}
+
+func TestAddPersonFromEntities(t *testing.T) {
+	name := entity.Entity{ID: "E1", Kind: entity.KindPerson, Text: "María  Pérez", Value: "María Pérez"}
+	details := []entity.Entity{
+		{ID: "E2", Kind: entity.KindPhone, Text: "(555) 123-4567", Value: "5551234567"},
+		{ID: "E3", Kind: entity.KindEmail, Text: "MPerez@Example.com", Value: "mperez@example.com"},
+		{ID: "E4", Kind: entity.KindAddress, Text: "12 Main St", Value: "12 Main St"},
+		{ID: "E5", Kind: entity.KindAddress, Text: "99 Oak Ave", Value: "99 Oak Ave"},
+		{ID: "E6", Kind: entity.KindVehicle, Text: "Toyota Corolla", Value: "Toyota Corolla"},
+	}
+
+	tests := []struct {
+		name    string
+		role    string
+		person  entity.Entity
+		details []entity.Entity
+		wantErr string
+		check   func(t *testing.T, p *Person)
+	}{
+		{
+			name: "person with details", role: "Witness", person: name, details: details,
+			check: func(t *testing.T, p *Person) {
+				if p.FullName != "María Pérez" || p.Address != "12 Main St" || len(p.DocumentIDs) != 1 {
+					t.Errorf("person = %+v", p)
+				}
+				if strings.Join(p.PhoneNumbers, ",") != "(555) 123-4567" || strings.Join(p.EmailAddresses, ",") != "mperez@example.com" {
+					t.Errorf("contacts = %v %v", p.PhoneNumbers, p.EmailAddresses)
+				}
+				if p.Notes != "From D1: address: 99 Oak Ave; vehicle: Toyota Corolla" {
+					t.Errorf("notes = %q", p.Notes)
+				}
+			},
+		},
+		{name: "not a person", role: "Witness", person: details[0], wantErr: "not a person"},
+		{name: "already in the case", role: "Victim", person: entity.Entity{ID: "E9", Kind: entity.KindPerson, Value: "john smith"}, wantErr: "already in the case as P1"},
+		{name: "invalid role", role: "Bystander", person: name, wantErr: "invalid person role"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCaseService()
+			p, err := s.AddPersonFromEntities("C1", tt.role, "D1", tt.person, tt.details)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, p)
+			found, _ := s.FindPerson("C1", "", p.FullName)
+			if found == nil || found.ID != p.ID {
+				t.Errorf("person not added to the case: %+v", found)
+			}
+		})
+	}
+}
+
+func TestAddEventFromEntities(t *testing.T) {
+	when := time.Date(2024, 3, 5, 21, 30, 0, 0, time.Local)
+	date := entity.Entity{ID: "E1", Kind: entity.KindDate, Text: "March 5, 2024 at 9:30 pm", Time: when}
+	related := []entity.Entity{
+		{ID: "E2", Kind: entity.KindPerson, Value: "John Smith"},
+		{ID: "E3", Kind: entity.KindPerson, Value: "Laura Gómez"},
+		{ID: "E4", Kind: entity.KindAddress, Value: "1234 Main Street"},
+		{ID: "E5", Kind: entity.KindWeapon, Value: "pistol"},
+	}
+
+	tests := []struct {
+		name            string
+		date            entity.Entity
+		description     string
+		wantErr         string
+		wantDescription string
+	}{
+		{"event with participants", date, "Robbery", "", "Robbery (also involved: Laura Gómez)"},
+		{"not a date", related[0], "Robbery", "not a date", ""},
+		{"no description", date, " ", "description is required", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCaseService()
+			e, err := s.AddEventFromEntities("C1", "D1", tt.description, "det.gomez", tt.date, related)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Description != tt.wantDescription || !e.Timestamp.Equal(when) || e.Location != "1234 Main Street" {
+				t.Errorf("event = %+v", e)
+			}
+			if strings.Join(e.Participants, ",") != "P1" || strings.Join(e.DocumentIDs, ",") != "D1" {
+				t.Errorf("participants %v, documents %v", e.Participants, e.DocumentIDs)
+			}
+			c, _ := s.GetCase("C1")
+			if len(c.Timeline) != 1 || c.Timeline[0].ID != e.ID {
+				t.Errorf("timeline = %+v", c.Timeline)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format identifies the application that exported a chat
+type Format string
+
+const (
+	FormatWhatsApp Format = "WHATSAPP"
+	FormatTelegram Format = "TELEGRAM"
+	FormatCSV      Format = "CSV"
+)
+
+// ErrNotChatExport is returned for files that are not a supported chat export
+var ErrNotChatExport = errors.New("not a supported chat export")
+
+// Options control how an export is read
+type Options struct {
+	Location  *time.Location // Zone of timestamps recorded without one; defaults to the local zone
+	DateOrder DateOrder
+}
+
+// Message is a message in a normalized form shared by all export formats
+type Message struct {
+	Sender       string
+	Timestamp    time.Time
+	Text         string
+	Media        []string // Media files, relative to the conversation's directory
+	MediaOmitted bool     // Media was sent but left out of the export
+	System       bool     // Notices such as encryption or group membership changes
+	Position     int      // Line, record or message number in the export
+}
+
+// Conversation is a chat read from an export
+type Conversation struct {
+	Format       Format
+	Title        string
+	Dir          string   // Directory media paths are relative to
+	Participants []string // In order of their first message
+	Messages     []Message
+	Warnings     []string // Lines or records that could not be read
+	AssumedZone  string   // Zone timestamps recorded without one were read in; empty if every timestamp had its own
+}
+
+// Detect identifies the export format of a file, or of the export file in an
+// unpacked export directory, and returns the file to read
+func Detect(path string) (Format, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get export info: %w", err)
+	}
+	if info.IsDir() {
+		file, err := findExportFile(path)
+		if err != nil {
+			return "", "", err
+		}
+		path = file
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatTelegram, path, nil
+	case ".csv":
+		return FormatCSV, path, nil
+	case ".txt":
+		if ok, err := isWhatsApp(path); err != nil {
+			return "", "", err
+		} else if ok {
+			return FormatWhatsApp, path, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrNotChatExport, path)
+}
+
+// ParseFile reads the conversations in an export file or unpacked export
+// directory. Telegram exports of a whole account hold several conversations.
+func ParseFile(path string, opts Options) ([]*Conversation, error) {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	format, file, err := Detect(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversations []*Conversation
+	switch format {
+	case FormatWhatsApp:
+		var c *Conversation
+		c, err = parseWhatsApp(file, opts)
+		conversations = []*Conversation{c}
+	case FormatTelegram:
+		conversations, err = parseTelegram(file, opts)
+	case FormatCSV:
+		conversations, err = parseCSV(file, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range conversations {
+		c.Format = format
+		c.Dir = filepath.Dir(file)
+		c.Participants = participants(c.Messages)
+	}
+	return conversations, nil
+}
+
+// findExportFile finds the chat file in an unpacked export
+func findExportFile(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read export directory: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	// Telegram's result.json and WhatsApp's iOS _chat.txt first, then any
+	// WhatsApp text export or CSV file
+	for _, name := range []string{"result.json", "_chat.txt"} {
+		for _, n := range names {
+			if n == name {
+				return filepath.Join(dir, n), nil
+			}
+		}
+	}
+	for _, n := range names {
+		lower := strings.ToLower(n)
+		if strings.HasSuffix(lower, ".txt") && strings.Contains(lower, "whatsapp") || strings.HasSuffix(lower, ".csv") {
+			return filepath.Join(dir, n), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no chat file in %s", ErrNotChatExport, dir)
+}
+
+// participants lists the senders of a conversation in order of appearance
+func participants(messages []Message) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range messages {
+		if m.Sender != "" && !m.System && !seen[m.Sender] {
+			seen[m.Sender] = true
+			names = append(names, m.Sender)
+		}
+	}
+	return names
+}
@@ -0,0 +1,115 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testZone is the zone fixtures without their own are read in
+var testZone = time.FixedZone("VET", -4*60*60)
+
+// writeExport writes a fixture file under dir and returns its path
+func writeExport(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// describe summarizes a message on one line for comparison
+func describe(m Message) string {
+	kind := "from " + m.Sender
+	if m.System {
+		kind = "system"
+	}
+	s := fmt.Sprintf("%s %s: %q", m.Timestamp.Format("2006-01-02 15:04:05 MST"), kind, m.Text)
+	if len(m.Media) > 0 {
+		s += " media " + strings.Join(m.Media, ",")
+	}
+	if m.MediaOmitted {
+		s += " omitted"
+	}
+	return s
+}
+
+// checkMessages compares the messages of a conversation with their summaries
+func checkMessages(t *testing.T, c *Conversation, want []string) {
+	t.Helper()
+	if len(c.Messages) != len(want) {
+		for _, m := range c.Messages {
+			t.Log(describe(m))
+		}
+		t.Fatalf("%d messages, want %d", len(c.Messages), len(want))
+	}
+	for i, m := range c.Messages {
+		if got := describe(m); got != want[i] {
+			t.Errorf("message %d:\n got %s\nwant %s", i+1, got, want[i])
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	whatsApp := writeExport(t, dir, "android/Chat de WhatsApp con Pedro.txt", "31/12/23, 21:15 - Pedro: Hola\n")
+	ios := writeExport(t, dir, "ios/_chat.txt", "[31/12/23, 21:15:07] Pedro: Hola\n")
+	writeExport(t, dir, "ios/notes.txt", "not a chat\n")
+	telegram := writeExport(t, dir, "telegram/result.json", `{"name": "Pedro", "messages": []}`)
+	writeExport(t, dir, "telegram/photos/photo_1.jpg", "jpeg")
+	notes := writeExport(t, dir, "notes.txt", "Shopping list\nmilk\n")
+	writeExport(t, dir, "empty/readme.md", "nothing here")
+
+	tests := []struct {
+		path     string
+		want     Format
+		wantFile string
+	}{
+		{whatsApp, FormatWhatsApp, whatsApp},
+		{filepath.Join(dir, "android"), FormatWhatsApp, whatsApp},
+		{filepath.Join(dir, "ios"), FormatWhatsApp, ios},
+		{filepath.Join(dir, "telegram"), FormatTelegram, telegram},
+		{notes, "", ""},
+		{filepath.Join(dir, "empty"), "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			format, file, err := Detect(tt.path)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNotChatExport) {
+					t.Errorf("err = %v, want ErrNotChatExport", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.want || file != tt.wantFile {
+				t.Errorf("got %s %s, want %s %s", format, file, tt.want, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestParseFileDefaultsToLocalZone(t *testing.T) {
+	path := writeExport(t, t.TempDir(), "WhatsApp Chat with Pedro.txt", "12/31/23, 21:15 - Pedro: Hi\n")
+	conversations, err := ParseFile(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := conversations[0]
+	if c.AssumedZone != time.Local.String() {
+		t.Errorf("assumed zone %q, want %q", c.AssumedZone, time.Local.String())
+	}
+	if got := c.Messages[0].Timestamp; got.Location() != time.Local || got.Hour() != 21 {
+		t.Errorf("timestamp %s, want 21:15 local", got)
+	}
+}
@@ -0,0 +1,159 @@
+package chat
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// csvColumns maps the header names of generic chat CSV files, as written by
+// forensic and backup tools in English and Spanish, to message fields
+var csvColumns = map[string]string{
+	"timestamp": "timestamp", "datetime": "timestamp", "date time": "timestamp", "sent": "timestamp",
+	"fecha y hora": "timestamp", "fecha hora": "timestamp", "marca de tiempo": "timestamp",
+	"date": "date", "fecha": "date",
+	"time": "time", "hora": "time",
+	"sender": "sender", "from": "sender", "author": "sender", "name": "sender",
+	"remitente": "sender", "autor": "sender", "de": "sender", "emisor": "sender",
+	"text": "text", "message": "text", "body": "text", "content": "text",
+	"mensaje": "text", "texto": "text", "contenido": "text", "cuerpo": "text",
+	"media": "media", "attachment": "media", "attachments": "media", "file": "media",
+	"adjunto": "media", "adjuntos": "media", "archivo": "media", "multimedia": "media",
+	"chat": "chat", "conversation": "chat", "thread": "chat",
+	"conversación": "chat", "conversacion": "chat", "grupo": "chat",
+}
+
+// parseCSV reads a CSV file with one message per row. Rows are grouped into
+// conversations by a chat column when the file has one.
+func parseCSV(path string, opts Options) ([]*Conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotChatExport, path, err)
+	}
+	// Spanish-locale spreadsheets save with semicolons
+	if len(header) == 1 && strings.Contains(header[0], ";") {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		r = csv.NewReader(f)
+		r.Comma = ';'
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		if header, err = r.Read(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrNotChatExport, path, err)
+		}
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(cleanText(name)))
+		if field, ok := csvColumns[key]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	_, hasTimestamp := columns["timestamp"]
+	_, hasDate := columns["date"]
+	if _, ok := columns["text"]; !ok || !hasTimestamp && !hasDate {
+		return nil, fmt.Errorf("%w: %s needs a text column and a timestamp or date column", ErrNotChatExport, path)
+	}
+
+	type row struct {
+		record []string
+		line   int
+	}
+	var rows []row
+	var dates []string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, row{record, line})
+		if hasDate {
+			dates = append(dates, field(record, columns, "date"))
+		} else {
+			date, _ := splitDateTime(field(record, columns, "timestamp"))
+			dates = append(dates, date)
+		}
+	}
+	order := detectDateOrder(dates, opts.DateOrder)
+
+	var conversations []*Conversation
+	byTitle := make(map[string]*Conversation)
+	for _, row := range rows {
+		title := field(row.record, columns, "chat")
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		c, ok := byTitle[title]
+		if !ok {
+			c = &Conversation{Title: title}
+			byTitle[title] = c
+			conversations = append(conversations, c)
+		}
+
+		var timestamp time.Time
+		zoned := false
+		if hasTimestamp {
+			timestamp, zoned, err = parseTimestamp(field(row.record, columns, "timestamp"), order, opts.Location)
+		} else {
+			timestamp, err = parseDateTime(field(row.record, columns, "date"), field(row.record, columns, "time"), order, opts.Location)
+		}
+		if err != nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("line %d: %v", row.line, err))
+			continue
+		}
+		if !zoned {
+			c.AssumedZone = opts.Location.String()
+		}
+
+		m := Message{
+			Sender:    strings.TrimSpace(field(row.record, columns, "sender")),
+			Timestamp: timestamp,
+			Text:      field(row.record, columns, "text"),
+			Position:  row.line,
+		}
+		for _, media := range strings.FieldsFunc(field(row.record, columns, "media"), func(r rune) bool { return r == '|' || r == ';' }) {
+			if media = strings.TrimSpace(media); media != "" {
+				m.Media = append(m.Media, media)
+			}
+		}
+		m.System = m.Sender == ""
+		c.Messages = append(c.Messages, m)
+	}
+	if len(conversations) == 0 {
+		return nil, fmt.Errorf("%w: no messages in %s", ErrNotChatExport, path)
+	}
+	return conversations, nil
+}
+
+// field returns a named column of a record, or "" when the file has no such
+// column or the row is short
+func field(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return cleanText(record[i])
+}
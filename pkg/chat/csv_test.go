@@ -0,0 +1,93 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantZone string
+		want     map[string][]string // Messages of each conversation
+	}{
+		{"spanish with semicolons and separate date and time",
+			"\ufeffFecha;Hora;Remitente;Mensaje;Adjunto\n" +
+				"31/12/2023;9:15 p. m.;Pedro;Hola;\n" +
+				"01/01/2024;12:05 a. m.;Ana;\"Mira esto; ya\";IMG_0001.jpg|IMG_0002.jpg\n" +
+				"01/01/2024;1:00 p. m.;;Ana salió del grupo;\n",
+			"VET", map[string][]string{"chat": {
+				`2023-12-31 21:15:00 VET from Pedro: "Hola"`,
+				`2024-01-01 00:05:00 VET from Ana: "Mira esto; ya" media IMG_0001.jpg,IMG_0002.jpg`,
+				`2024-01-01 13:00:00 VET system: "Ana salió del grupo"`,
+			}}},
+		{"english with zoned timestamps and a chat column",
+			"Timestamp,Sender,Message,Chat\n" +
+				"2024-01-13T12:00:00Z,Bob,Hi,Docks\n" +
+				"1705147260,Carol,Unix time,Docks\n" +
+				"2024-01-13T08:02:00-04:00,Bob,\"Line one\nline two\",Other\n",
+			"", map[string][]string{
+				"Docks": {
+					`2024-01-13 08:00:00 VET from Bob: "Hi"`,
+					`2024-01-13 08:01:00 VET from Carol: "Unix time"`,
+				},
+				"Other": {`2024-01-13 08:02:00 VET from Bob: "Line one\nline two"`},
+			}},
+		{"month-first timestamps without a zone",
+			"Date Time,From,Text\n" +
+				"12/31/2023 21:15,Pedro,Hi\n" +
+				"2024-01-01 08:00:00,Ana,ISO\n",
+			"VET", map[string][]string{"chat": {
+				`2023-12-31 21:15:00 VET from Pedro: "Hi"`,
+				`2024-01-01 08:00:00 VET from Ana: "ISO"`,
+			}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeExport(t, t.TempDir(), "chat.csv", tt.content)
+			conversations, err := ParseFile(path, Options{Location: testZone})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conversations) != len(tt.want) {
+				t.Fatalf("%d conversations, want %d", len(conversations), len(tt.want))
+			}
+			for _, c := range conversations {
+				want, ok := tt.want[c.Title]
+				if !ok {
+					t.Fatalf("unexpected conversation %q", c.Title)
+				}
+				if c.Format != FormatCSV || c.AssumedZone != tt.wantZone || len(c.Warnings) > 0 {
+					t.Errorf("%s: format %s, assumed zone %q, warnings %q", c.Title, c.Format, c.AssumedZone, c.Warnings)
+				}
+				checkMessages(t, c, want)
+			}
+		})
+	}
+}
+
+func TestParseCSVRejects(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"no-text.csv": "Timestamp,Sender\n2024-01-01T10:00:00Z,Ana\n",
+		"no-time.csv": "Sender,Message\nAna,Hola\n",
+		"empty.csv":   "",
+		"header.csv":  "Fecha,Mensaje\n",
+	} {
+		path := writeExport(t, dir, name, content)
+		if _, err := ParseFile(path, Options{Location: testZone}); !errors.Is(err, ErrNotChatExport) {
+			t.Errorf("%s: err = %v, want ErrNotChatExport", name, err)
+		}
+	}
+
+	// Rows with dates that cannot be read are reported, not imported
+	path := writeExport(t, dir, "bad-date.csv", "Fecha,Mensaje\n31/02/2024,Hola\n01/03/2024,Adiós\n")
+	conversations, err := ParseFile(path, Options{Location: testZone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := conversations[0]; len(c.Messages) != 1 || len(c.Warnings) != 1 {
+		t.Errorf("%d messages, warnings %q", len(c.Messages), c.Warnings)
+	}
+}
@@ -0,0 +1,235 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder says how numeric dates such as 03/04/24 are read
+type DateOrder string
+
+const (
+	DateOrderAuto DateOrder = ""    // Decided from the dates in the export, day first if ambiguous
+	DayFirst      DateOrder = "DMY" // 31/12/24, used in Spanish-language locales
+	MonthFirst    DateOrder = "MDY" // 12/31/24
+)
+
+// ParseDateOrder parses a date order such as "DMY" or "MDY"
+func ParseDateOrder(value string) (DateOrder, error) {
+	switch o := DateOrder(strings.ToUpper(strings.TrimSpace(value))); o {
+	case DateOrderAuto, DayFirst, MonthFirst:
+		return o, nil
+	case "AUTO":
+		return DateOrderAuto, nil
+	}
+	return "", fmt.Errorf("unknown date order %q (expected DMY, MDY or AUTO)", value)
+}
+
+var (
+	numericDate = regexp.MustCompile(`^(\d{1,2})[/.\-](\d{1,2})[/.\-](\d{2,4})$`)
+	clockTime   = regexp.MustCompile(`(?i)^(\d{1,2}):(\d{2})(?::(\d{2}))?(?:\s*([ap])\.?\s?m\.?)?$`)
+	namedDate   = regexp.MustCompile(`(?i)^(\d{1,2})(?:\s+de)?\s+([a-záéíóúñ]+)\.?(?:\s+de)?,?\s+(\d{4})$`)
+	namedDateEN = regexp.MustCompile(`(?i)^([a-z]+)\.?\s+(\d{1,2}),?\s+(\d{4})$`)
+)
+
+// months maps Spanish and English month names and abbreviations
+var months = map[string]time.Month{
+	"enero": 1, "ene": 1, "january": 1, "jan": 1,
+	"febrero": 2, "feb": 2, "february": 2,
+	"marzo": 3, "mar": 3, "march": 3,
+	"abril": 4, "abr": 4, "april": 4, "apr": 4,
+	"mayo": 5, "may": 5,
+	"junio": 6, "jun": 6, "june": 6,
+	"julio": 7, "jul": 7, "july": 7,
+	"agosto": 8, "ago": 8, "august": 8, "aug": 8,
+	"septiembre": 9, "setiembre": 9, "sep": 9, "sept": 9, "set": 9, "september": 9,
+	"octubre": 10, "oct": 10, "october": 10,
+	"noviembre": 11, "nov": 11, "november": 11,
+	"diciembre": 12, "dic": 12, "december": 12, "dec": 12,
+}
+
+// cleanText removes the direction marks and unusual spaces that messaging
+// apps put around dates and names
+func cleanText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\u200e', '\u200f', '\u202a', '\u202c', '\ufeff':
+			return -1
+		case '\u00a0', '\u202f', '\u2009':
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// detectDateOrder decides the order of numeric dates from values where the
+// day or month is unambiguous
+func detectDateOrder(dates []string, order DateOrder) DateOrder {
+	if order != DateOrderAuto {
+		return order
+	}
+	dayFirst, monthFirst := 0, 0
+	for _, d := range dates {
+		m := numericDate.FindStringSubmatch(strings.TrimSpace(d))
+		if m == nil {
+			continue
+		}
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[2])
+		switch {
+		case a > 12 && b <= 12:
+			dayFirst++
+		case b > 12 && a <= 12:
+			monthFirst++
+		}
+	}
+	if monthFirst > dayFirst {
+		return MonthFirst
+	}
+	return DayFirst
+}
+
+// parseDateTime parses a date and an optional time of day in loc
+func parseDateTime(date, clock string, order DateOrder, loc *time.Location) (time.Time, error) {
+	date = strings.TrimSpace(cleanText(date))
+	year, month, day, err := parseDate(date, order)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var hour, minute, second int
+	if clock = strings.TrimSpace(cleanText(clock)); clock != "" {
+		if hour, minute, second, err = parseClock(clock); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Date(year, month, day, hour, minute, second, 0, loc), nil
+}
+
+// parseDate parses numeric dates and dates with Spanish or English month
+// names, rejecting days the month does not have
+func parseDate(date string, order DateOrder) (int, time.Month, int, error) {
+	year, month, day, err := matchDate(date, order)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC); day < 1 || t.Month() != month || t.Day() != day {
+		return 0, 0, 0, fmt.Errorf("invalid date %q", date)
+	}
+	return year, month, day, nil
+}
+
+// matchDate reads the year, month and day of a date without checking them
+func matchDate(date string, order DateOrder) (int, time.Month, int, error) {
+	if m := numericDate.FindStringSubmatch(date); m != nil {
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[2])
+		year, _ := strconv.Atoi(m[3])
+		if year < 100 {
+			year += 2000
+		}
+		day, month := a, b
+		if order == MonthFirst {
+			day, month = b, a
+		}
+		if month < 1 || month > 12 {
+			return 0, 0, 0, fmt.Errorf("invalid date %q", date)
+		}
+		return year, time.Month(month), day, nil
+	}
+
+	if m := namedDate.FindStringSubmatch(date); m != nil {
+		if month, ok := months[strings.ToLower(m[2])]; ok {
+			day, _ := strconv.Atoi(m[1])
+			year, _ := strconv.Atoi(m[3])
+			return year, month, day, nil
+		}
+	}
+	if m := namedDateEN.FindStringSubmatch(date); m != nil {
+		if month, ok := months[strings.ToLower(m[1])]; ok {
+			day, _ := strconv.Atoi(m[2])
+			year, _ := strconv.Atoi(m[3])
+			return year, month, day, nil
+		}
+	}
+	return 0, 0, 0, fmt.Errorf("unrecognised date %q", date)
+}
+
+// parseClock parses 24-hour times and 12-hour times with AM/PM, including
+// the Spanish "a. m." and "p. m."
+func parseClock(clock string) (int, int, int, error) {
+	m := clockTime.FindStringSubmatch(clock)
+	if m == nil {
+		return 0, 0, 0, fmt.Errorf("unrecognised time %q", clock)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	second, _ := strconv.Atoi(m[3])
+	if m[4] != "" && (hour < 1 || hour > 12) {
+		return 0, 0, 0, fmt.Errorf("invalid time %q", clock)
+	}
+	switch strings.ToLower(m[4]) {
+	case "a":
+		if hour == 12 {
+			hour = 0
+		}
+	case "p":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return 0, 0, 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return hour, minute, second, nil
+}
+
+// isoLayouts are machine-written timestamp layouts without a zone
+var isoLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTimestamp parses a timestamp from a CSV export. Values with a zone or
+// Unix times are exact and reported as zoned; others are read in loc.
+func parseTimestamp(value string, order DateOrder, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(cleanText(value))
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.In(loc), true, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		switch {
+		case len(value) == 13:
+			return time.UnixMilli(n).In(loc), true, nil
+		case len(value) == 10:
+			return time.Unix(n, 0).In(loc), true, nil
+		}
+	}
+	for _, layout := range isoLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, nil
+		}
+	}
+
+	// A date followed by a time: "31/12/2023 21:15", "1 de enero de 2024, 9:15 p. m."
+	date, clock := splitDateTime(value)
+	t, err := parseDateTime(date, clock, order, loc)
+	return t, false, err
+}
+
+// timeStart finds the time of day that follows a date
+var timeStart = regexp.MustCompile(`,?\s+(?:a las\s+|at\s+)?(\d{1,2}:\d{2}.*)$`)
+
+// splitDateTime splits a value at the start of its time of day
+func splitDateTime(value string) (string, string) {
+	if loc := timeStart.FindStringSubmatchIndex(value); loc != nil {
+		return value[:loc[0]], value[loc[2]:loc[3]]
+	}
+	return value, ""
+}
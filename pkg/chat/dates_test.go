@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		date    string
+		order   DateOrder
+		want    string
+		wantErr bool
+	}{
+		{"31/12/2023", DayFirst, "2023-12-31", false},
+		{"12/31/23", MonthFirst, "2023-12-31", false},
+		{"29/02/2024", DayFirst, "2024-02-29", false},
+		{"29/02/2023", DayFirst, "", true},
+		{"31/04/2024", DayFirst, "", true},
+		{"30/02/24", DayFirst, "", true},
+		{"00/01/2024", DayFirst, "", true},
+		{"01/13/2024", DayFirst, "", true},
+		{"1 de enero de 2024", DayFirst, "2024-01-01", false},
+		{"31 de junio de 2024", DayFirst, "", true},
+		{"0 de marzo de 2024", DayFirst, "", true},
+		{"February 29, 2024", MonthFirst, "2024-02-29", false},
+		{"February 30, 2024", MonthFirst, "", true},
+		{"Sept 99 2024", MonthFirst, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			year, month, day, err := parseDate(tt.date, tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02"); !tt.wantErr && got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		clock   string
+		want    string
+		wantErr bool
+	}{
+		{"21:15", "21:15:00", false},
+		{"9:15:30", "09:15:30", false},
+		{"9:15 p. m.", "21:15:00", false},
+		{"12:05 AM", "00:05:00", false},
+		{"12:05 pm", "12:05:00", false},
+		{"13:00 am", "", true},
+		{"13:00 p. m.", "", true},
+		{"0:30 am", "", true},
+		{"24:00", "", true},
+		{"10:60", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clock, func(t *testing.T) {
+			hour, minute, second, err := parseClock(tt.clock)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := time.Date(2024, 1, 1, hour, minute, second, 0, time.UTC).Format("15:04:05"); !tt.wantErr && got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestampRejectsInvalidDates(t *testing.T) {
+	for _, value := range []string{"31/02/2024 10:00", "30/02/2024, 9:15 p. m.", "1 de enero de 2024, 13:00 p. m."} {
+		if ts, _, err := parseTimestamp(value, DayFirst, time.UTC); err == nil {
+			t.Errorf("%q parsed as %s", value, ts)
+		}
+	}
+}
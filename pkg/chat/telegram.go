@@ -0,0 +1,168 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramExport is the result.json of Telegram Desktop's export, either of
+// a single chat or of a whole account
+type telegramExport struct {
+	telegramChat
+	Chats *struct {
+		List []telegramChat `json:"list"`
+	} `json:"chats"`
+}
+
+type telegramChat struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Messages []telegramMessage `json:"messages"`
+}
+
+type telegramMessage struct {
+	ID           int             `json:"id"`
+	Type         string          `json:"type"`
+	Date         string          `json:"date"`
+	DateUnixtime string          `json:"date_unixtime"`
+	From         *string         `json:"from"`
+	Actor        string          `json:"actor"`
+	Action       string          `json:"action"`
+	Text         json.RawMessage `json:"text"`
+	Photo        string          `json:"photo"`
+	File         string          `json:"file"`
+	Thumbnail    string          `json:"thumbnail"`
+}
+
+// telegramMissing starts the placeholder Telegram writes for media left out
+// of the export
+const telegramMissing = "(File not included."
+
+// parseTelegram reads a Telegram JSON export
+func parseTelegram(path string, opts Options) ([]*Conversation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	var export telegramExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotChatExport, path, err)
+	}
+
+	chats := []telegramChat{export.telegramChat}
+	if export.Chats != nil {
+		chats = export.Chats.List
+	}
+
+	var conversations []*Conversation
+	for _, chat := range chats {
+		if chat.Messages == nil {
+			continue
+		}
+		c := &Conversation{Title: chat.Name}
+		if c.Title == "" {
+			c.Title = chat.Type
+		}
+		for _, tm := range chat.Messages {
+			m, err := telegramToMessage(tm, opts.Location)
+			if err != nil {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("message %d: %v", tm.ID, err))
+				continue
+			}
+			if tm.DateUnixtime == "" {
+				c.AssumedZone = opts.Location.String()
+			}
+			c.Messages = append(c.Messages, m)
+		}
+		conversations = append(conversations, c)
+	}
+	if len(conversations) == 0 {
+		return nil, fmt.Errorf("%w: no chats in %s", ErrNotChatExport, path)
+	}
+	return conversations, nil
+}
+
+// telegramToMessage converts an exported message. Unix times are exact; the
+// local date is read in loc for older exports without them.
+func telegramToMessage(tm telegramMessage, loc *time.Location) (Message, error) {
+	m := Message{Position: tm.ID}
+
+	if tm.DateUnixtime != "" {
+		seconds, err := strconv.ParseInt(tm.DateUnixtime, 10, 64)
+		if err != nil {
+			return m, fmt.Errorf("invalid date_unixtime %q", tm.DateUnixtime)
+		}
+		m.Timestamp = time.Unix(seconds, 0).In(loc)
+	} else {
+		t, err := time.ParseInLocation("2006-01-02T15:04:05", tm.Date, loc)
+		if err != nil {
+			return m, fmt.Errorf("invalid date %q", tm.Date)
+		}
+		m.Timestamp = t
+	}
+
+	text, err := telegramText(tm.Text)
+	if err != nil {
+		return m, err
+	}
+	m.Text = text
+
+	if tm.Type == "service" {
+		m.System = true
+		m.Sender = tm.Actor
+		if m.Text == "" && tm.Action != "" {
+			m.Text = strings.ReplaceAll(tm.Action, "_", " ")
+		}
+		return m, nil
+	}
+	if tm.From != nil {
+		m.Sender = *tm.From
+	}
+
+	for _, media := range []string{tm.Photo, tm.File} {
+		switch {
+		case media == "":
+		case strings.HasPrefix(media, telegramMissing):
+			m.MediaOmitted = true
+		default:
+			m.Media = append(m.Media, media)
+		}
+	}
+	return m, nil
+}
+
+// telegramText flattens a message's text, which is a string or, when it has
+// formatting or links, a list of strings and {"type", "text"} objects
+func telegramText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("unrecognised text: %w", err)
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if err := json.Unmarshal(part, &s); err == nil {
+			b.WriteString(s)
+			continue
+		}
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err != nil {
+			return "", fmt.Errorf("unrecognised text: %w", err)
+		}
+		b.WriteString(entity.Text)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// telegramChatExport is a single-chat export with service messages, rich
+// text, media and a message from an older export without a Unix time
+const telegramChatExport = `{
+ "name": "Pedro Pérez",
+ "type": "personal_chat",
+ "id": 4242,
+ "messages": [
+  {"id": 1, "type": "service", "date": "2024-01-01T20:00:00", "date_unixtime": "1704153600",
+   "actor": "Ana", "actor_id": "user1", "action": "phone_call", "text": ""},
+  {"id": 2, "type": "message", "date": "2024-01-01T20:01:00", "date_unixtime": "1704153660",
+   "from": "Ana", "from_id": "user1",
+   "text": ["Mira ", {"type": "link", "text": "https://example.com/mapa"}, " y llega ", {"type": "bold", "text": "temprano"}, "."]},
+  {"id": 3, "type": "message", "date": "2024-01-01T20:02:00", "date_unixtime": "1704153720",
+   "from": "Pedro Pérez", "from_id": "user2", "photo": "photos/photo_1@01-01-2024_20-02-00.jpg",
+   "width": 1280, "height": 960, "text": "La entrada"},
+  {"id": 4, "type": "message", "date": "2024-01-01T20:03:00", "date_unixtime": "1704153780",
+   "from": "Pedro Pérez", "from_id": "user2", "media_type": "voice_message",
+   "file": "(File not included. Change data exporting settings to download.)", "text": ""},
+  {"id": 5, "type": "message", "date": "2024-01-01T20:04:00",
+   "from": null, "from_id": "user3", "text": "Deleted account"},
+  {"id": 6, "type": "message", "date": "not a date", "from": "Ana", "text": "Lost"}
+ ]
+}`
+
+// telegramAccountExport is a whole-account export with two chats, all with
+// Unix times
+const telegramAccountExport = `{
+ "about": "Here is the data you requested.",
+ "chats": {"about": "", "list": [
+  {"name": "Pedro", "type": "personal_chat", "messages": [
+   {"id": 10, "type": "message", "date": "2024-02-01T09:00:00", "date_unixtime": "1706792400",
+    "from": "Pedro", "text": "Hola"}
+  ]},
+  {"name": "", "type": "saved_messages", "messages": [
+   {"id": 11, "type": "message", "date": "2024-02-01T09:05:00", "date_unixtime": "1706792700",
+    "from": "Ana", "file": "files/recibo.pdf", "text": [{"type": "italic", "text": "recibo"}]}
+  ]}
+ ]}
+}`
+
+func TestParseTelegram(t *testing.T) {
+	dir := t.TempDir()
+	writeExport(t, dir, "result.json", telegramChatExport)
+	conversations, err := ParseFile(dir, Options{Location: testZone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("%d conversations, want 1", len(conversations))
+	}
+	c := conversations[0]
+	if c.Format != FormatTelegram || c.Title != "Pedro Pérez" || c.Dir != dir {
+		t.Errorf("format %s, title %q, dir %s", c.Format, c.Title, c.Dir)
+	}
+	// Message 5 has no Unix time, so its local date was read in the zone given
+	if c.AssumedZone != "VET" {
+		t.Errorf("assumed zone %q, want VET", c.AssumedZone)
+	}
+	if len(c.Warnings) != 1 || c.Warnings[0] != `message 6: invalid date "not a date"` {
+		t.Errorf("warnings %q", c.Warnings)
+	}
+	checkMessages(t, c, []string{
+		`2024-01-01 20:00:00 VET system: "phone call"`,
+		`2024-01-01 20:01:00 VET from Ana: "Mira https://example.com/mapa y llega temprano."`,
+		`2024-01-01 20:02:00 VET from Pedro Pérez: "La entrada" media photos/photo_1@01-01-2024_20-02-00.jpg`,
+		`2024-01-01 20:03:00 VET from Pedro Pérez: "" omitted`,
+		`2024-01-01 20:04:00 VET from : "Deleted account"`,
+	})
+	if c.Messages[0].Sender != "Ana" || c.Messages[4].Position != 5 {
+		t.Errorf("service actor %q, position %d", c.Messages[0].Sender, c.Messages[4].Position)
+	}
+}
+
+func TestParseTelegramAccount(t *testing.T) {
+	path := writeExport(t, t.TempDir(), "result.json", telegramAccountExport)
+	conversations, err := ParseFile(path, Options{Location: testZone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversations) != 2 {
+		t.Fatalf("%d conversations, want 2", len(conversations))
+	}
+	if conversations[0].Title != "Pedro" || conversations[1].Title != "saved_messages" {
+		t.Errorf("titles %q, %q", conversations[0].Title, conversations[1].Title)
+	}
+	for _, c := range conversations {
+		if c.AssumedZone != "" {
+			t.Errorf("%s: assumed zone %q, want none for Unix times", c.Title, c.AssumedZone)
+		}
+	}
+	checkMessages(t, conversations[1], []string{
+		`2024-02-01 09:05:00 VET from Ana: "recibo" media files/recibo.pdf`,
+	})
+}
+
+func TestParseTelegramRejects(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"not-json.json": "name: Pedro",
+		"no-chats.json": `{"about": "Here is the data you requested."}`,
+		"bad-text.json": `{"name": "Pedro", "messages": [{"id": 1, "type": "message", "date_unixtime": "1704153600", "text": [42]}]}`,
+	} {
+		path := writeExport(t, dir, name, content)
+		conversations, err := ParseFile(path, Options{Location: testZone})
+		if name == "bad-text.json" {
+			// A message that cannot be read is a warning, not a failed import
+			if err != nil || len(conversations[0].Warnings) != 1 {
+				t.Errorf("%s: err = %v, conversations %v", name, err, conversations)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: parsed as %s", filepath.Base(path), conversations[0].Title)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// whatsAppLine matches the start of a message in Android ("31/12/23, 21:15 -
+// Ana: Hola") and iOS ("[31/12/23, 21:15:07] Ana: Hola") text exports, in
+// 24-hour or 12-hour form
+var whatsAppLine = regexp.MustCompile(`(?i)^\[?(\d{1,2}[/.\-]\d{1,2}[/.\-]\d{2,4}),?\s+` +
+	`(\d{1,2}:\d{2}(?::\d{2})?(?:\s*[ap]\.?\s?m\.?)?)\]?\s*(?:-\s+)?(.*)$`)
+
+var (
+	// "IMG-20240101-WA0001.jpg (file attached)", "(archivo adjunto)"
+	whatsAppAttached = regexp.MustCompile(`(?i)^(.+?\.[a-z0-9]{2,5}) \((?:file attached|archivo adjunto)\)`)
+	// "<attached: 00000012-PHOTO-2024-01-01-10-00-00.jpg>", "<adjunto: ...>"
+	whatsAppAttachedIOS = regexp.MustCompile(`(?i)<(?:attached|adjunto): ([^>]+)>`)
+	// "<Media omitted>", "<Multimedia omitido>", "image omitted", "imagen omitida"
+	whatsAppOmitted = regexp.MustCompile(`(?i)^<?(?:media omitted|multimedia omitido|archivo omitido|` +
+		`(?:image|video|audio|sticker|gif|document) omitted|(?:imagen|video|audio|sticker|gif|documento) omitid[oa])>?$`)
+)
+
+// whatsAppTitle matches the file names of Android exports
+var whatsAppTitle = regexp.MustCompile(`(?i)^(?:whatsapp chat with|chat de whatsapp con)\s+(.+)\.txt$`)
+
+// isWhatsApp reports whether a text file starts like a WhatsApp export
+func isWhatsApp(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for i := 0; i < 5 && scanner.Scan(); i++ {
+		if whatsAppLine.MatchString(cleanText(scanner.Text())) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// whatsAppEntry is a message before its timestamp is parsed
+type whatsAppEntry struct {
+	date, clock string
+	text        []string
+	line        int
+}
+
+// parseWhatsApp reads a WhatsApp text export. Dates are read once the whole
+// file has been seen so that the day and month order can be detected.
+func parseWhatsApp(path string, opts Options) (*Conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer f.Close()
+
+	var entries []*whatsAppEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := cleanText(scanner.Text())
+		if m := whatsAppLine.FindStringSubmatch(text); m != nil {
+			entries = append(entries, &whatsAppEntry{date: m[1], clock: m[2], text: []string{m[3]}, line: line})
+		} else if len(entries) > 0 {
+			last := entries[len(entries)-1]
+			last.text = append(last.text, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no messages in %s", ErrNotChatExport, path)
+	}
+
+	dates := make([]string, len(entries))
+	for i, e := range entries {
+		dates[i] = e.date
+	}
+	order := detectDateOrder(dates, opts.DateOrder)
+
+	bodies := make([]string, len(entries))
+	for i, e := range entries {
+		bodies[i] = strings.Join(e.text, "\n")
+	}
+	senders := whatsAppSenders(bodies)
+
+	c := &Conversation{Title: whatsAppChatTitle(path), AssumedZone: opts.Location.String()}
+	for i, e := range entries {
+		timestamp, err := parseDateTime(e.date, e.clock, order, opts.Location)
+		if err != nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("line %d: %v", e.line, err))
+			continue
+		}
+
+		m := Message{Timestamp: timestamp, Position: e.line}
+		body := bodies[i]
+		if sender, text, ok := whatsAppSplit(body); ok && senders[sender] {
+			m.Sender = sender
+			body = text
+		} else {
+			m.System = true
+		}
+		m.Text, m.Media, m.MediaOmitted = whatsAppMedia(body)
+		c.Messages = append(c.Messages, m)
+	}
+	return c, nil
+}
+
+// whatsAppSplit splits a message body at the first ": " of its first line
+// into a candidate sender and the text
+func whatsAppSplit(body string) (string, string, bool) {
+	sender, text, ok := strings.Cut(body, ": ")
+	if !ok || strings.Contains(sender, "\n") {
+		return "", "", false
+	}
+	return strings.TrimSpace(sender), text, true
+}
+
+// whatsAppSenders finds the real senders among the candidates of an export.
+// System lines can hold ": " too, as in `Ana cambió el asunto a "Plan:
+// viernes"`, so candidates with quotes are dropped, as are those made of
+// another candidate's name followed by a word in lowercase, which is an
+// action by that participant rather than a name.
+func whatsAppSenders(bodies []string) map[string]bool {
+	candidates := make(map[string]bool)
+	for _, body := range bodies {
+		if sender, _, ok := whatsAppSplit(body); ok && sender != "" && !strings.ContainsAny(sender, "\"“”«»„") {
+			candidates[sender] = true
+		}
+	}
+
+	senders := make(map[string]bool)
+	for c := range candidates {
+		action := false
+		for name := range candidates {
+			if rest, ok := strings.CutPrefix(c, name+" "); ok {
+				if r, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(r) {
+					action = true
+					break
+				}
+			}
+		}
+		if !action {
+			senders[c] = true
+		}
+	}
+	return senders
+}
+
+// whatsAppMedia separates media references from the text of a message
+func whatsAppMedia(body string) (string, []string, bool) {
+	first, rest, _ := strings.Cut(body, "\n")
+	trimmed := strings.TrimSpace(first)
+
+	if whatsAppOmitted.MatchString(trimmed) {
+		return rest, nil, true
+	}
+	if m := whatsAppAttached.FindStringSubmatch(trimmed); m != nil {
+		return rest, []string{m[1]}, false
+	}
+	if matches := whatsAppAttachedIOS.FindAllStringSubmatch(body, -1); matches != nil {
+		var media []string
+		for _, m := range matches {
+			media = append(media, strings.TrimSpace(m[1]))
+		}
+		text := strings.TrimSpace(whatsAppAttachedIOS.ReplaceAllString(body, ""))
+		return text, media, false
+	}
+	return body, nil, false
+}
+
+// whatsAppChatTitle names a conversation after its export file or, for iOS
+// exports whose file is always _chat.txt, after its directory
+func whatsAppChatTitle(path string) string {
+	name := filepath.Base(path)
+	if m := whatsAppTitle.FindStringSubmatch(name); m != nil {
+		return m[1]
+	}
+	if name == "_chat.txt" {
+		return filepath.Base(filepath.Dir(path))
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
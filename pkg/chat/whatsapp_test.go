@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// androidSpanish is an Android export in Spanish with 12-hour times. Newer
+// exports put a narrow no-break space before "p. m.".
+const androidSpanish = "31/12/23, 9:15\u202fp.\u00a0m. - Los mensajes y las llamadas están cifrados de extremo a extremo. Nadie fuera de este chat, ni siquiera WhatsApp, puede leerlos ni escucharlos.\n" +
+	"31/12/23, 9:16 p. m. - Pedro Pérez: Hola, ¿a qué hora?\n" +
+	"01/01/24, 12:05 a. m. - Ana: IMG-20240101-WA0001.jpg (archivo adjunto)\n" +
+	"mira esto\n" +
+	"01/01/24, 12:06 a. m. - Ana: <Multimedia omitido>\n" +
+	"01/01/24, 1:30 p. m. - Ana cambió el asunto a \"Plan: viernes\"\n" +
+	"01/01/24, 1:31 p. m. - Pedro Pérez: Nos vemos: a las 8\n" +
+	"segunda línea\n" +
+	"01/01/24, 1:32 p. m. - Ana añadió a Luis\n"
+
+// androidEnglish is an Android export in English with 24-hour times
+const androidEnglish = "1/13/24, 08:00 - Messages and calls are end-to-end encrypted.\n" +
+	"1/13/24, 08:01 - Bob: VID-20240113-WA0002.mp4 (file attached)\n" +
+	"1/13/24, 08:02 - Carol Ann: <Media omitted>\n" +
+	"1/13/24, 08:03 - Bob changed the group description to: meet at the docks\n" +
+	"1/13/24, 08:04 - Carol Ann: ok\n"
+
+// iosEnglish is an iOS export in English with 12-hour times and seconds
+const iosEnglish = "[12/31/23, 9:15:07 PM] Ana Smith: ‎Messages and calls are end-to-end encrypted.\n" +
+	"[12/31/23, 9:15:30 PM] Bob: Hi there\n" +
+	"[12/31/23, 9:16:00 PM] Bob: ‎<attached: 00000012-PHOTO-2023-12-31-21-16-00.jpg>\n" +
+	"[1/1/24, 12:01:30 AM] Ana Smith: ‎image omitted\n" +
+	"[1/1/24, 12:02:00 AM] ‎Ana Smith changed the subject to “Plan: Friday”\n" +
+	"[1/1/24, 12:03:00 AM] Ana Smith: Time: 10 PM\n"
+
+// iosSpanish is an iOS export in Spanish with 24-hour times
+const iosSpanish = "[31/12/23, 21:15:07] Pedro: ‎<adjunto: 00000013-AUDIO-2023-12-31-21-15-07.opus>\n" +
+	"[31/12/23, 21:16:00] Pedro: ‎imagen omitida\n" +
+	"[31/12/23, 21:17:00] Marta: Vale\n"
+
+func TestParseWhatsApp(t *testing.T) {
+	tests := []struct {
+		name             string
+		file             string
+		content          string
+		wantTitle        string
+		wantParticipants []string
+		want             []string
+	}{
+		{"android spanish 12-hour", "Chat de WhatsApp con Pedro Pérez.txt", androidSpanish, "Pedro Pérez",
+			[]string{"Pedro Pérez", "Ana"}, []string{
+				`2023-12-31 21:15:00 VET system: "Los mensajes y las llamadas están cifrados de extremo a extremo. Nadie fuera de este chat, ni siquiera WhatsApp, puede leerlos ni escucharlos."`,
+				`2023-12-31 21:16:00 VET from Pedro Pérez: "Hola, ¿a qué hora?"`,
+				`2024-01-01 00:05:00 VET from Ana: "mira esto" media IMG-20240101-WA0001.jpg`,
+				`2024-01-01 00:06:00 VET from Ana: "" omitted`,
+				`2024-01-01 13:30:00 VET system: "Ana cambió el asunto a \"Plan: viernes\""`,
+				`2024-01-01 13:31:00 VET from Pedro Pérez: "Nos vemos: a las 8\nsegunda línea"`,
+				`2024-01-01 13:32:00 VET system: "Ana añadió a Luis"`,
+			}},
+		{"android english 24-hour", "WhatsApp Chat with Dock Group.txt", androidEnglish, "Dock Group",
+			[]string{"Bob", "Carol Ann"}, []string{
+				`2024-01-13 08:00:00 VET system: "Messages and calls are end-to-end encrypted."`,
+				`2024-01-13 08:01:00 VET from Bob: "" media VID-20240113-WA0002.mp4`,
+				`2024-01-13 08:02:00 VET from Carol Ann: "" omitted`,
+				`2024-01-13 08:03:00 VET system: "Bob changed the group description to: meet at the docks"`,
+				`2024-01-13 08:04:00 VET from Carol Ann: "ok"`,
+			}},
+		{"ios english 12-hour", "Ana Smith/_chat.txt", iosEnglish, "Ana Smith",
+			[]string{"Ana Smith", "Bob"}, []string{
+				`2023-12-31 21:15:07 VET from Ana Smith: "Messages and calls are end-to-end encrypted."`,
+				`2023-12-31 21:15:30 VET from Bob: "Hi there"`,
+				`2023-12-31 21:16:00 VET from Bob: "" media 00000012-PHOTO-2023-12-31-21-16-00.jpg`,
+				`2024-01-01 00:01:30 VET from Ana Smith: "" omitted`,
+				`2024-01-01 00:02:00 VET system: "Ana Smith changed the subject to “Plan: Friday”"`,
+				`2024-01-01 00:03:00 VET from Ana Smith: "Time: 10 PM"`,
+			}},
+		{"ios spanish 24-hour", "Marta/_chat.txt", iosSpanish, "Marta",
+			[]string{"Pedro", "Marta"}, []string{
+				`2023-12-31 21:15:07 VET from Pedro: "" media 00000013-AUDIO-2023-12-31-21-15-07.opus`,
+				`2023-12-31 21:16:00 VET from Pedro: "" omitted`,
+				`2023-12-31 21:17:00 VET from Marta: "Vale"`,
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeExport(t, dir, tt.file, tt.content)
+			conversations, err := ParseFile(filepath.Dir(path), Options{Location: testZone})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conversations) != 1 {
+				t.Fatalf("%d conversations, want 1", len(conversations))
+			}
+			c := conversations[0]
+			if c.Format != FormatWhatsApp || c.Title != tt.wantTitle || c.Dir != filepath.Dir(path) {
+				t.Errorf("format %s, title %q, dir %s", c.Format, c.Title, c.Dir)
+			}
+			if c.AssumedZone != "VET" {
+				t.Errorf("assumed zone %q, want VET", c.AssumedZone)
+			}
+			if len(c.Warnings) > 0 {
+				t.Errorf("warnings: %v", c.Warnings)
+			}
+			if strings.Join(c.Participants, "|") != strings.Join(tt.wantParticipants, "|") {
+				t.Errorf("participants %q, want %q", c.Participants, tt.wantParticipants)
+			}
+			checkMessages(t, c, tt.want)
+		})
+	}
+}
+
+func TestParseWhatsAppWarnings(t *testing.T) {
+	path := writeExport(t, t.TempDir(), "WhatsApp Chat with Pedro.txt",
+		"31/12/23, 21:15 - Pedro: Hola\n30/02/24, 10:00 - Pedro: Fecha imposible\n")
+	conversations, err := ParseFile(path, Options{Location: testZone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := conversations[0]
+	if len(c.Messages) != 1 || len(c.Warnings) != 1 {
+		t.Errorf("%d messages, warnings %q", len(c.Messages), c.Warnings)
+	}
+}
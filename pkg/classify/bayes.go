@@ -0,0 +1,192 @@
+// Package classify assigns labels to text with a multinomial naive Bayes
+// classifier over word unigrams and bigrams, and evaluates classifiers by
+// cross-validation.
+package classify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxTextLength limits the text of a document read for features
+const maxTextLength = 64 << 10
+
+// Sample is a text with its known label
+type Sample struct {
+	Label string
+	Text  string
+}
+
+// Score is the probability of a label for a text
+type Score struct {
+	Label       string
+	Probability float64
+}
+
+// classStats holds the training counts of one label
+type classStats struct {
+	Documents int            `json:"documents"`
+	Features  int            `json:"features"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// Model is a naive Bayes classifier. Features are counted once per
+// document, which suits texts of very different lengths.
+type Model struct {
+	Alpha      float64                `json:"alpha"` // Additive smoothing
+	Classes    map[string]*classStats `json:"classes"`
+	Vocabulary map[string]bool        `json:"-"`
+}
+
+// New creates an empty model
+func New() *Model {
+	return &Model{Alpha: 0.5, Classes: make(map[string]*classStats), Vocabulary: make(map[string]bool)}
+}
+
+// Train builds a model from labeled samples
+func Train(samples []Sample) *Model {
+	m := New()
+	for _, s := range samples {
+		m.Add(s.Label, s.Text)
+	}
+	return m
+}
+
+// Add trains the model on one labeled text
+func (m *Model) Add(label, text string) {
+	c, ok := m.Classes[label]
+	if !ok {
+		c = &classStats{Counts: make(map[string]int)}
+		m.Classes[label] = c
+	}
+	c.Documents++
+	for f := range Features(text) {
+		c.Counts[f]++
+		c.Features++
+		m.Vocabulary[f] = true
+	}
+}
+
+// Labels returns the labels the model knows, sorted
+func (m *Model) Labels() []string {
+	labels := make([]string, 0, len(m.Classes))
+	for label := range m.Classes {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Predict returns the probability of each label for a text, most likely
+// first, and the number of features of the text the model knows. With no
+// known features there are no scores.
+func (m *Model) Predict(text string) ([]Score, int) {
+	features := Features(text)
+	known := 0
+	for f := range features {
+		if m.Vocabulary[f] {
+			known++
+		}
+	}
+	if known == 0 || len(m.Classes) == 0 {
+		return nil, 0
+	}
+
+	total := 0
+	for _, c := range m.Classes {
+		total += c.Documents
+	}
+	vocabulary := float64(len(m.Vocabulary))
+	scores := make([]Score, 0, len(m.Classes))
+	best := math.Inf(-1)
+	for _, label := range m.Labels() {
+		c := m.Classes[label]
+		logp := math.Log(float64(c.Documents) / float64(total))
+		denominator := math.Log(float64(c.Features) + m.Alpha*vocabulary)
+		for f := range features {
+			if m.Vocabulary[f] {
+				logp += math.Log(float64(c.Counts[f])+m.Alpha) - denominator
+			}
+		}
+		scores = append(scores, Score{Label: label, Probability: logp})
+		best = math.Max(best, logp)
+	}
+
+	// Normalize the log scores into probabilities
+	sum := 0.0
+	for i := range scores {
+		scores[i].Probability = math.Exp(scores[i].Probability - best)
+		sum += scores[i].Probability
+	}
+	for i := range scores {
+		scores[i].Probability /= sum
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Probability > scores[j].Probability })
+	return scores, known
+}
+
+// Save writes the model as JSON
+func (m *Model) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("failed to save model: %w", err)
+	}
+	return nil
+}
+
+// Load reads a model saved as JSON
+func Load(r io.Reader) (*Model, error) {
+	m := New()
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("failed to load model: %w", err)
+	}
+	if len(m.Classes) == 0 {
+		return nil, fmt.Errorf("failed to load model: no classes")
+	}
+	for _, c := range m.Classes {
+		for f := range c.Counts {
+			m.Vocabulary[f] = true
+		}
+	}
+	return m, nil
+}
+
+// Features returns the set of lowercase words and word pairs of a text
+func Features(text string) map[string]bool {
+	if len(text) > maxTextLength {
+		text = text[:maxTextLength]
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	features := make(map[string]bool, 2*len(words))
+	for i, w := range words {
+		// Numbers and common words say little about the kind of document
+		if strings.IndexFunc(w, unicode.IsLetter) < 0 || stopWords[w] {
+			continue
+		}
+		features[w] = true
+		if i > 0 {
+			features[words[i-1]+" "+w] = true
+		}
+	}
+	return features
+}
+
+// stopWords are common English and Spanish words that say nothing about
+// the kind of a text
+var stopWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(`a an the and or of to in on at by for with from as is was were be been are it its
+		this that these those he she they his her their i you we my our me him them
+		el la los las un una unos unas y o de del en por para con que se su sus al lo le les es fue era son como mi me yo`) {
+		words[w] = true
+	}
+	return words
+}()
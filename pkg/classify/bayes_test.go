@@ -0,0 +1,105 @@
+package classify
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// toySamples are short texts of two labels
+var toySamples = []Sample{
+	{"report", "Officer responded to the scene and arrested the suspect"},
+	{"report", "Officer filed the incident report after the arrest"},
+	{"report", "Patrol officer responded to a burglary call"},
+	{"medical", "Patient admitted with a fracture of the left arm"},
+	{"medical", "Patient treated in the emergency room for burns"},
+	{"medical", "The physician examined the patient and ordered x-rays"},
+}
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"The Suspect fled", []string{"fled", "suspect", "suspect fled", "the suspect"}},
+		{"robo de vehículo", []string{"de vehículo", "robo", "vehículo"}},
+		{"Case 2024 report", []string{"2024 report", "case", "report"}},
+		{"of the and", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for f := range Features(tt.text) {
+			got = append(got, f)
+		}
+		sort.Strings(got)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Features(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPredict(t *testing.T) {
+	m := Train(toySamples)
+	tests := []struct {
+		name      string
+		text      string
+		wantLabel string
+		wantKnown int
+	}{
+		{"report", "The officer responded and arrested a man", "report", 5},
+		{"medical", "Patient with burns treated by the physician", "medical", 5},
+		{"nothing known", "Quarterly sales figures", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores, known := m.Predict(tt.text)
+			if known != tt.wantKnown {
+				t.Errorf("known = %d, want %d", known, tt.wantKnown)
+			}
+			if tt.wantLabel == "" {
+				if scores != nil {
+					t.Errorf("scores = %v, want none", scores)
+				}
+				return
+			}
+			if len(scores) != 2 || scores[0].Label != tt.wantLabel || scores[0].Probability <= scores[1].Probability {
+				t.Fatalf("scores = %v", scores)
+			}
+			if sum := scores[0].Probability + scores[1].Probability; sum < 0.999 || sum > 1.001 {
+				t.Errorf("probabilities sum to %v", sum)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	m := Train(toySamples)
+	var b bytes.Buffer
+	if err := m.Save(&b); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "The officer responded to the patient"
+	want, _ := m.Predict(text)
+	got, _ := loaded.Predict(text)
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("loaded model predicts %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not JSON", "model"},
+		{"no classes", `{"alpha": 0.5, "classes": {}}`},
+	}
+	for _, tt := range tests {
+		if _, err := Load(strings.NewReader(tt.data)); err == nil {
+			t.Errorf("%s: loaded", tt.name)
+		}
+	}
+}
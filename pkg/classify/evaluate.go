@@ -0,0 +1,192 @@
+package classify
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// noLabel is reported for texts the model cannot label
+const noLabel = "(none)"
+
+// ClassReport measures the predictions for one label
+type ClassReport struct {
+	Label     string
+	Support   int // Samples with the label
+	Predicted int // Samples predicted to have the label
+	Correct   int
+	Precision float64
+	Recall    float64
+	F1        float64
+}
+
+// Report measures a classifier against samples with known labels
+type Report struct {
+	Samples   int
+	Folds     int // Cross-validation folds, 0 for a held-out test
+	Correct   int
+	Accuracy  float64
+	MacroF1   float64 // Mean F1 over the labels of the samples
+	Classes   []ClassReport
+	Confusion map[string]map[string]int // Counts by true then predicted label
+}
+
+// Evaluate tests a trained model on held-out samples
+func Evaluate(m *Model, samples []Sample) Report {
+	r := Report{Confusion: make(map[string]map[string]int)}
+	for _, s := range samples {
+		r.add(s.Label, predictLabel(m, s.Text))
+	}
+	r.finish()
+	return r
+}
+
+// CrossValidate trains and tests a model on each of k folds of the
+// samples, each label spread evenly over the folds
+func CrossValidate(samples []Sample, folds int) Report {
+	if folds < 2 {
+		folds = 2
+	}
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Label < sorted[j].Label })
+
+	r := Report{Folds: folds, Confusion: make(map[string]map[string]int)}
+	for k := 0; k < folds; k++ {
+		var train, test []Sample
+		for i, s := range sorted {
+			if i%folds == k {
+				test = append(test, s)
+			} else {
+				train = append(train, s)
+			}
+		}
+		m := Train(train)
+		for _, s := range test {
+			r.add(s.Label, predictLabel(m, s.Text))
+		}
+	}
+	r.finish()
+	return r
+}
+
+// predictLabel returns the most likely label of a text
+func predictLabel(m *Model, text string) string {
+	scores, _ := m.Predict(text)
+	if len(scores) == 0 {
+		return noLabel
+	}
+	return scores[0].Label
+}
+
+// add counts one prediction
+func (r *Report) add(label, predicted string) {
+	r.Samples++
+	if label == predicted {
+		r.Correct++
+	}
+	if r.Confusion[label] == nil {
+		r.Confusion[label] = make(map[string]int)
+	}
+	r.Confusion[label][predicted]++
+}
+
+// finish computes the rates from the counts
+func (r *Report) finish() {
+	if r.Samples > 0 {
+		r.Accuracy = float64(r.Correct) / float64(r.Samples)
+	}
+	predicted := make(map[string]int)
+	for _, row := range r.Confusion {
+		for p, n := range row {
+			predicted[p] += n
+		}
+	}
+	for label, row := range r.Confusion {
+		c := ClassReport{Label: label, Predicted: predicted[label], Correct: row[label]}
+		for _, n := range row {
+			c.Support += n
+		}
+		if c.Predicted > 0 {
+			c.Precision = float64(c.Correct) / float64(c.Predicted)
+		}
+		c.Recall = float64(c.Correct) / float64(c.Support)
+		if c.Precision+c.Recall > 0 {
+			c.F1 = 2 * c.Precision * c.Recall / (c.Precision + c.Recall)
+		}
+		r.Classes = append(r.Classes, c)
+		r.MacroF1 += c.F1
+	}
+	if len(r.Classes) > 0 {
+		r.MacroF1 /= float64(len(r.Classes))
+	}
+	sort.Slice(r.Classes, func(i, j int) bool { return r.Classes[i].Label < r.Classes[j].Label })
+}
+
+// WriteText writes the report as a table per label followed by the most
+// frequent confusions
+func (r Report) WriteText(w io.Writer) error {
+	method := "held-out test"
+	if r.Folds > 0 {
+		method = fmt.Sprintf("%d-fold cross-validation", r.Folds)
+	}
+	fmt.Fprintf(w, "Evaluation by %s on %d samples\n", method, r.Samples)
+	fmt.Fprintf(w, "Accuracy: %.1f%% (%d/%d), macro F1: %.3f\n\n", r.Accuracy*100, r.Correct, r.Samples, r.MacroF1)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Label\tSamples\tPrecision\tRecall\tF1")
+	for _, c := range r.Classes {
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\t%.3f\n", c.Label, c.Support, c.Precision, c.Recall, c.F1)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	type confusion struct {
+		label, predicted string
+		n                int
+	}
+	var confusions []confusion
+	for label, row := range r.Confusion {
+		for predicted, n := range row {
+			if predicted != label {
+				confusions = append(confusions, confusion{label, predicted, n})
+			}
+		}
+	}
+	if len(confusions) == 0 {
+		return nil
+	}
+	sort.Slice(confusions, func(i, j int) bool {
+		if confusions[i].n != confusions[j].n {
+			return confusions[i].n > confusions[j].n
+		}
+		return confusions[i].label+confusions[i].predicted < confusions[j].label+confusions[j].predicted
+	})
+	fmt.Fprintln(w, "\nMost frequent confusions:")
+	for i, c := range confusions {
+		if i == 10 {
+			break
+		}
+		fmt.Fprintf(w, "  %-*s taken for %s: %d\n", longest(r.Classes), c.label, c.predicted, c.n)
+	}
+	return nil
+}
+
+// longest returns the length of the longest label
+func longest(classes []ClassReport) int {
+	n := 0
+	for _, c := range classes {
+		n = max(n, len(c.Label))
+	}
+	return n
+}
+
+// String returns the report as text
+func (r Report) String() string {
+	var b strings.Builder
+	r.WriteText(&b)
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package classify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	m := Train(toySamples)
+	r := Evaluate(m, []Sample{
+		{"report", "Officer arrested the suspect"},
+		{"report", "Patient admitted to the emergency room"}, // Mislabeled on purpose
+		{"medical", "Physician examined the fracture"},
+		{"medical", "Quarterly sales figures"},
+	})
+
+	if r.Samples != 4 || r.Correct != 2 || r.Accuracy != 0.5 || r.Folds != 0 {
+		t.Errorf("report = %+v", r)
+	}
+	tests := []struct {
+		label                       string
+		support, predicted, correct int
+	}{
+		{"medical", 2, 2, 1},
+		{"report", 2, 1, 1},
+	}
+	if len(r.Classes) != len(tests) {
+		t.Fatalf("classes = %+v", r.Classes)
+	}
+	for i, tt := range tests {
+		c := r.Classes[i]
+		if c.Label != tt.label || c.Support != tt.support || c.Predicted != tt.predicted || c.Correct != tt.correct {
+			t.Errorf("class %+v, want %+v", c, tt)
+		}
+	}
+	if r.Confusion["medical"][noLabel] != 1 || r.Confusion["report"]["medical"] != 1 {
+		t.Errorf("confusion = %v", r.Confusion)
+	}
+
+	text := r.String()
+	for _, want := range []string{"held-out test on 4 samples", "Accuracy: 50.0% (2/4)", "report  taken for medical: 1", "medical taken for (none): 1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report text lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestCrossValidate(t *testing.T) {
+	tests := []struct {
+		folds     int
+		wantFolds int
+	}{
+		{3, 3},
+		{1, 2},
+	}
+	for _, tt := range tests {
+		r := CrossValidate(toySamples, tt.folds)
+		if r.Folds != tt.wantFolds || r.Samples != len(toySamples) {
+			t.Errorf("folds %d: report = %+v", tt.folds, r)
+		}
+		if !strings.Contains(r.String(), "-fold cross-validation") {
+			t.Errorf("report text = %q", r.String())
+		}
+	}
+}
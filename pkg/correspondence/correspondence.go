@@ -2,6 +2,8 @@ package correspondence
 
 import (
 	"fmt"
+	"strings"
+	"text/template"
 	"time"
 )
 
@@ -110,6 +112,21 @@ type Template struct {
 	UpdatedAt    time.Time
 }
 
+// CustodyTransfer is one entry of the Transfers list used by the chain of
+// custody template
+type CustodyTransfer struct {
+	Number             int
+	From               string
+	FromTitle          string
+	To                 string
+	ToTitle            string
+	DateTime           string
+	Purpose            string
+	Condition          string
+	VerificationMethod string
+	Notes              string
+}
+
 // CorrespondenceRepository defines the interface for correspondence storage
 type CorrespondenceRepository interface {
 	Save(c *Correspondence) error
@@ -166,24 +183,36 @@ func (s *CorrespondenceService) GetCorrespondence(id string) (*Correspondence, e
 	return s.correspondenceRepo.Find(id)
 }
 
-// CreateFromTemplate creates a correspondence from a template
+// CreateFromTemplate creates a correspondence from a template, filling in
+// its variables from data. The sender and first recipient supply the
+// officer and recipient variables that data does not set.
 func (s *CorrespondenceService) CreateFromTemplate(
 	templateID string,
 	caseID string,
 	sender Person,
 	recipients []Person,
+	data map[string]any,
 ) (*Correspondence, error) {
-	template, err := s.templateRepo.Find(templateID)
+	tmpl, err := s.templateRepo.Find(templateID)
 	if err != nil {
 		return nil, fmt.Errorf("template not found: %w", err)
 	}
 
+	vars := personVars(sender, recipients)
+	for k, v := range data {
+		vars[k] = v
+	}
+	subject, body, err := RenderTemplate(tmpl, vars)
+	if err != nil {
+		return nil, err
+	}
+
 	corr := &Correspondence{
 		ID:                 generateID("CORR"),
 		CaseID:             caseID,
-		CorrespondenceType: template.Type,
-		Subject:            template.Subject,
-		Body:               template.Body,
+		CorrespondenceType: tmpl.Type,
+		Subject:            subject,
+		Body:               body,
 		Sender:             sender,
 		Recipients:         recipients,
 		Direction:          "OUTGOING",
@@ -196,6 +225,63 @@ func (s *CorrespondenceService) CreateFromTemplate(
 	return corr, s.correspondenceRepo.Save(corr)
 }
 
+// RenderTemplate fills in the subject and body of a template. Variables
+// the template declares but data does not set are left blank.
+func RenderTemplate(t *Template, data map[string]any) (subject, body string, err error) {
+	vars := make(map[string]any, len(t.TemplateVars)+len(data))
+	for _, name := range t.TemplateVars {
+		vars[name] = ""
+	}
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	if subject, err = execute(t.ID+" subject", t.Subject, vars); err != nil {
+		return "", "", err
+	}
+	if body, err = execute(t.ID+" body", t.Body, vars); err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// execute runs one template text against the variables
+func execute(name, text string, vars map[string]any) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %w", name, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("failed to fill in template %s: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// personVars returns the template variables describing the sender and the
+// first recipient
+func personVars(sender Person, recipients []Person) map[string]any {
+	vars := map[string]any{
+		"OfficerName":    sender.Name,
+		"OfficerTitle":   sender.Title,
+		"BadgeNumber":    sender.BadgeNumber,
+		"DepartmentName": sender.Department,
+		"ContactPhone":   sender.Phone,
+		"ContactEmail":   sender.Email,
+	}
+	if len(recipients) > 0 {
+		r := recipients[0]
+		vars["RecipientName"] = r.Name
+		vars["RecipientTitle"] = r.Title
+		vars["RecipientOrganization"] = r.Organization
+		vars["RecipientAddress"] = r.Address
+		if names := strings.Fields(r.Name); len(names) > 0 {
+			vars["RecipientLastName"] = names[len(names)-1]
+		}
+	}
+	return vars
+}
+
 // SendCorrespondence marks a correspondence as sent
 func (s *CorrespondenceService) SendCorrespondence(id string, sentAt time.Time) error {
 	corr, err := s.correspondenceRepo.Find(id)
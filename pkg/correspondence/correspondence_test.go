@@ -0,0 +1,84 @@
+package correspondence
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// memCorrespondenceRepo is an in-memory CorrespondenceRepository for tests
+type memCorrespondenceRepo struct {
+	CorrespondenceRepository
+	items map[string]*Correspondence
+}
+
+func (r *memCorrespondenceRepo) Save(c *Correspondence) error {
+	r.items[c.ID] = c
+	return nil
+}
+
+// memTemplateRepo is an in-memory TemplateRepository for tests
+type memTemplateRepo struct {
+	TemplateRepository
+}
+
+func (memTemplateRepo) Find(id string) (*Template, error) {
+	if t := GetTemplateByID(id); t != nil {
+		return t, nil
+	}
+	return nil, fmt.Errorf("template not found: %s", id)
+}
+
+func TestCreateFromTemplateRendersCustodyTransfers(t *testing.T) {
+	tests := []struct {
+		name      string
+		transfers []CustodyTransfer
+		want      []string
+		notWant   []string
+	}{
+		{"no transfers", nil, []string{"CHAIN OF CUSTODY:", "FINAL DISPOSITION"}, []string{"1. FROM"}},
+		{"three transfers", []CustodyTransfer{
+			{Number: 1, From: "OFC-1", To: "Evidence Room", Purpose: "COLLECTED"},
+			{Number: 2, From: "Evidence Room", To: "State Lab", Purpose: "SHIPPED_TO_LAB", VerificationMethod: "Seal intact"},
+			{Number: 3, From: "State Lab", To: "DET-42", Purpose: "RETURNED_FROM_LAB"},
+		}, []string{"1. FROM: OFC-1", "2. FROM: Evidence Room", "VERIFICATION: Seal intact", "3. FROM: State Lab\n   TO: DET-42"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCorrespondenceService(&memCorrespondenceRepo{items: make(map[string]*Correspondence)}, memTemplateRepo{})
+			c, err := s.CreateFromTemplate("TMPL-EVIDENCE-CUSTODY-1", "CASE-1",
+				Person{Name: "Det. Jane Roe", Department: "Metro PD"}, []Person{{Name: "Clerk of Court"}},
+				map[string]any{"CaseNumber": "2024-001", "EvidenceNumber": "E-7", "Transfers": tt.transfers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Subject != "Chain of Custody: Evidence #E-7 - Case 2024-001" {
+				t.Errorf("subject = %q", c.Subject)
+			}
+			for _, want := range append(tt.want, "Metro PD") {
+				if !strings.Contains(c.Body, want) {
+					t.Errorf("body does not contain %q:\n%s", want, c.Body)
+				}
+			}
+			for _, notWant := range append(tt.notWant, "{{", "<no value>") {
+				if strings.Contains(c.Body, notWant) {
+					t.Errorf("body contains %q:\n%s", notWant, c.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	for _, tmpl := range GetDefaultTemplates() {
+		t.Run(tmpl.ID, func(t *testing.T) {
+			subject, body, err := RenderTemplate(tmpl, map[string]any{"CaseNumber": "2024-001"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(subject+body, "<no value>") {
+				t.Errorf("template uses variables it does not declare:\n%s", body)
+			}
+		})
+	}
+}
@@ -327,27 +327,17 @@ RECOVERY DATE/TIME: {{.RecoveryDateTime}}
 RECOVERY NOTES: {{.RecoveryNotes}}
 
 CHAIN OF CUSTODY:
-
-1. FROM: {{.RecoveredBy}}, Badge #{{.RecoveredByBadge}}
-   TO: {{.FirstCustodian}}, {{.FirstCustodianTitle}}
-   DATE/TIME: {{.FirstTransferDateTime}}
-   PURPOSE: {{.FirstTransferPurpose}}
-   CONDITION: {{.FirstTransferCondition}}
-   NOTES: {{.FirstTransferNotes}}
-   SIGNATURE (FROM): ___________________________
-   SIGNATURE (TO): ___________________________
-
-{{if .SecondCustodian}}
-2. FROM: {{.FirstCustodian}}, {{.FirstCustodianTitle}}
-   TO: {{.SecondCustodian}}, {{.SecondCustodianTitle}}
-   DATE/TIME: {{.SecondTransferDateTime}}
-   PURPOSE: {{.SecondTransferPurpose}}
-   CONDITION: {{.SecondTransferCondition}}
-   NOTES: {{.SecondTransferNotes}}
+{{if .Transfers}}{{range .Transfers}}
+{{.Number}}. FROM: {{.From}}{{if .FromTitle}}, {{.FromTitle}}{{end}}
+   TO: {{.To}}{{if .ToTitle}}, {{.ToTitle}}{{end}}
+   DATE/TIME: {{.DateTime}}
+   PURPOSE: {{.Purpose}}
+   CONDITION: {{.Condition}}
+   VERIFICATION: {{.VerificationMethod}}
+   NOTES: {{.Notes}}
    SIGNATURE (FROM): ___________________________
    SIGNATURE (TO): ___________________________
-{{end}}
-
+{{end}}{{end}}
 FINAL DISPOSITION: {{.FinalDisposition}}
 AUTHORIZED BY: {{.AuthorizedBy}}, {{.AuthorizedByTitle}}
 DATE: {{.DispositionDate}}
@@ -355,11 +345,8 @@ DATE: {{.DispositionDate}}
 			TemplateVars: []string{
 				"DepartmentName", "CaseNumber", "EvidenceNumber", "EvidenceDescription",
 				"RecoveredBy", "RecoveredByBadge", "RecoveryLocation", "RecoveryDateTime",
-				"RecoveryNotes", "FirstCustodian", "FirstCustodianTitle", "FirstTransferDateTime",
-				"FirstTransferPurpose", "FirstTransferCondition", "FirstTransferNotes",
-				"SecondCustodian", "SecondCustodianTitle", "SecondTransferDateTime",
-				"SecondTransferPurpose", "SecondTransferCondition", "SecondTransferNotes",
-				"FinalDisposition", "AuthorizedBy", "AuthorizedByTitle", "DispositionDate",
+				"RecoveryNotes", "Transfers", "FinalDisposition", "AuthorizedBy", "AuthorizedByTitle",
+				"DispositionDate",
 			},
 			Department: "Evidence Unit",
 			IsApproved: true,
@@ -0,0 +1,332 @@
+package document
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchStatus is the outcome of importing one file of a batch
+type BatchStatus string
+
+const (
+	BatchImported  BatchStatus = "IMPORTED"
+	BatchDuplicate BatchStatus = "DUPLICATE" // Same file as a document already in the case
+	BatchFailed    BatchStatus = "FAILED"
+)
+
+// BatchOptions selects the files of a directory to import
+type BatchOptions struct {
+	Include []string // Glob patterns of the files to import, all files if empty
+	Exclude []string // Glob patterns of the files and directories to leave out
+}
+
+// BatchEntry records the import of one file of a batch
+type BatchEntry struct {
+	Path            string      `json:"path"` // Relative to the batch directory, slash separated
+	Status          BatchStatus `json:"status"`
+	DocumentID      string      `json:"document_id,omitempty"` // The imported document, or the one it duplicates
+	Type            string      `json:"type,omitempty"`
+	SHA256          string      `json:"sha256,omitempty"`
+	NearDuplicateOf string      `json:"near_duplicate_of,omitempty"`
+	Error           string      `json:"error,omitempty"`
+	Time            time.Time   `json:"time"`
+}
+
+// BatchSummary counts the outcomes of the files of a batch
+type BatchSummary struct {
+	Files          int // Files selected in the directory
+	Imported       int
+	Duplicates     int
+	NearDuplicates int // Imported files with most of their text in another document
+	Failed         int
+	Pending        int            // Files not yet imported, after an interruption
+	ByType         map[string]int // Imported documents by document type
+}
+
+// BatchLog is the import log of a batch, one JSON entry per line. Entries
+// are written as each file is done, so that an interrupted batch can be
+// resumed. It is safe for concurrent use.
+type BatchLog struct {
+	mu       sync.Mutex
+	file     *os.File
+	entries  map[string]BatchEntry // Latest entry of each file
+	verified map[string]bool       // Files whose entry Done has checked
+	exists   func(documentID string) bool
+}
+
+// OpenBatchLog opens an import log, reading the entries of earlier runs
+func OpenBatchLog(logPath string) (*BatchLog, error) {
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create import log directory: %w", err)
+	}
+	f, err := os.OpenFile(logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import log: %w", err)
+	}
+
+	l := &BatchLog{file: f, entries: make(map[string]BatchEntry), verified: make(map[string]bool)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e BatchEntry
+		// A line cut short by an interruption is ignored
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil && e.Path != "" {
+			l.entries[e.Path] = e
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read import log: %w", err)
+	}
+	return l, nil
+}
+
+// SetDocumentCheck sets how Done confirms that the document a file was
+// imported as, or duplicates, is still on record. Without it the document
+// IDs in the log are trusted.
+func (l *BatchLog) SetDocumentCheck(exists func(documentID string) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.exists = exists
+}
+
+// Done reports whether a file under root was imported, or found to be a
+// duplicate, by an earlier run. Failed files are tried again, as are files
+// whose content no longer matches the logged SHA-256 and files whose
+// document is no longer on record. The answer for each file is kept.
+func (l *BatchLog) Done(root, relPath string) bool {
+	l.mu.Lock()
+	e, ok := l.entries[relPath]
+	done, checked := l.verified[relPath]
+	exists := l.exists
+	l.mu.Unlock()
+	if !ok || e.Status == BatchFailed {
+		return false
+	}
+	if checked {
+		return done
+	}
+
+	done = true
+	if e.SHA256 != "" {
+		sum, err := HashFile(filepath.Join(root, filepath.FromSlash(relPath)))
+		done = err == nil && strings.EqualFold(sum, e.SHA256)
+	}
+	if done && exists != nil && e.DocumentID != "" {
+		done = exists(e.DocumentID)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// A new entry written meanwhile is judged by itself
+	if current := l.entries[relPath]; current == e {
+		l.verified[relPath] = done
+	}
+	return done
+}
+
+// Write appends an entry to the log
+func (l *BatchLog) Write(e BatchEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode import log entry: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write import log: %w", err)
+	}
+	l.entries[e.Path] = e
+	l.verified[e.Path] = e.Status != BatchFailed
+	return nil
+}
+
+// Entries returns the latest entry of each file, by path
+func (l *BatchLog) Entries() []BatchEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make([]BatchEntry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Close closes the log file
+func (l *BatchLog) Close() error {
+	return l.file.Close()
+}
+
+// Summary counts the outcomes of the selected files, over this run and the
+// earlier runs of the batch
+func (l *BatchLog) Summary(files []string) BatchSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := BatchSummary{Files: len(files), ByType: make(map[string]int)}
+	for _, f := range files {
+		e, ok := l.entries[f]
+		switch {
+		case !ok:
+			s.Pending++
+		case e.Status == BatchImported:
+			s.Imported++
+			s.ByType[e.Type]++
+			if e.NearDuplicateOf != "" {
+				s.NearDuplicates++
+			}
+		case e.Status == BatchDuplicate:
+			s.Duplicates++
+		default:
+			s.Failed++
+		}
+	}
+	return s
+}
+
+// BatchFiles returns the regular files under a directory selected by the
+// options, as sorted slash-separated paths relative to it. Directories that
+// cannot be read are returned as failed entries.
+func BatchFiles(root string, opts BatchOptions) ([]string, []BatchEntry, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read import directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("not a directory: %s", root)
+	}
+
+	var files []string
+	var failed []BatchEntry
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		rel, relErr := filepath.Rel(root, p)
+		if relErr != nil {
+			return fmt.Errorf("failed to resolve relative path: %w", relErr)
+		}
+		rel = filepath.ToSlash(rel)
+		if err != nil {
+			failed = append(failed, BatchEntry{Path: rel, Status: BatchFailed, Error: err.Error(), Time: time.Now()})
+			return nil
+		}
+		if rel == "." {
+			return nil
+		}
+		if matchAny(opts.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks and special files are not followed, as in acquisitions
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(opts.Include) == 0 || matchAny(opts.Include, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(files)
+	return files, failed, nil
+}
+
+// matchAny reports whether a relative path matches one of the patterns,
+// ignoring case. Patterns with a slash are matched against the whole path,
+// others against the file or directory name.
+func matchAny(patterns []string, rel string) bool {
+	rel = strings.ToLower(rel)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(filepath.ToSlash(pattern))
+		name := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			name = rel
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportBatch imports the files of a batch with a bounded number of
+// workers, skipping the files the log records as done and logging each
+// result. importFile is given the path of a file relative to root. When the
+// context is cancelled no more files are started, and the files already
+// started are finished and logged. progress, if set, is called after each
+// file, one call at a time.
+func ImportBatch(ctx context.Context, root string, files []string, workers int, log *BatchLog,
+	importFile func(root, relPath string) BatchEntry, progress func(e BatchEntry)) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range jobs {
+				e := importOne(importFile, root, rel)
+				if e.Time.IsZero() {
+					e.Time = time.Now()
+				}
+				err := log.Write(e)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if progress != nil {
+					progress(e)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, rel := range files {
+		if log.Done(root, rel) {
+			continue
+		}
+		select {
+		case jobs <- rel:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// importOne imports one file of a batch, recording a file that crashes its
+// processor as failed rather than stopping the batch
+func importOne(importFile func(root, relPath string) BatchEntry, root, rel string) (e BatchEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = BatchEntry{Status: BatchFailed, Error: fmt.Sprintf("processing failed: %v", r)}
+		}
+		e.Path = rel
+	}()
+	return importFile(root, rel)
+}
@@ -0,0 +1,183 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeBatchDir writes files named by their content under a new directory
+func writeBatchDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// fakeImporter imports files as documents numbered in order, recording
+// which files it was given
+type fakeImporter struct {
+	mu       sync.Mutex
+	imported []string
+	next     atomic.Int64
+}
+
+func (f *fakeImporter) importFile(root, rel string) BatchEntry {
+	sum, err := HashFile(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return BatchEntry{Status: BatchFailed, Error: err.Error()}
+	}
+	f.mu.Lock()
+	f.imported = append(f.imported, rel)
+	f.mu.Unlock()
+	return BatchEntry{Status: BatchImported, DocumentID: fmt.Sprintf("DOC-%d", f.next.Add(1)), SHA256: sum}
+}
+
+func TestImportBatchResume(t *testing.T) {
+	tests := []struct {
+		name        string
+		earlier     BatchEntry
+		change      bool // The file changes after the earlier run
+		onRecord    bool // The earlier document is still on record
+		wantImport  bool
+		checkRecord bool
+	}{
+		{"unchanged and on record", BatchEntry{Status: BatchImported, DocumentID: "DOC-OLD"}, false, true, false, true},
+		{"duplicate on record", BatchEntry{Status: BatchDuplicate, DocumentID: "DOC-OLD"}, false, true, false, true},
+		{"file changed", BatchEntry{Status: BatchImported, DocumentID: "DOC-OLD"}, true, true, true, true},
+		{"document not on record", BatchEntry{Status: BatchImported, DocumentID: "DOC-OLD"}, false, false, true, true},
+		{"failed", BatchEntry{Status: BatchFailed, Error: "unreadable"}, false, true, true, true},
+		{"no document check", BatchEntry{Status: BatchImported, DocumentID: "DOC-OLD"}, false, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeBatchDir(t, map[string]string{"a/report.txt": "original report"})
+			logPath := filepath.Join(t.TempDir(), "import.jsonl")
+
+			// The earlier run logged the file as it was then
+			log, err := OpenBatchLog(logPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.earlier.Path = "a/report.txt"
+			if tt.earlier.Status != BatchFailed {
+				tt.earlier.SHA256, _ = HashFile(filepath.Join(root, "a", "report.txt"))
+			}
+			if err := log.Write(tt.earlier); err != nil {
+				t.Fatal(err)
+			}
+			log.Close()
+			if tt.change {
+				os.WriteFile(filepath.Join(root, "a", "report.txt"), []byte("edited report"), 0644)
+			}
+
+			log, err = OpenBatchLog(logPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer log.Close()
+			if tt.checkRecord {
+				log.SetDocumentCheck(func(id string) bool { return tt.onRecord && id == "DOC-OLD" })
+			}
+			importer := &fakeImporter{}
+			if err := ImportBatch(context.Background(), root, []string{"a/report.txt"}, 2, log, importer.importFile, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(importer.imported) == 1; got != tt.wantImport {
+				t.Errorf("imported %v, want import %v", importer.imported, tt.wantImport)
+			}
+			if s := log.Summary([]string{"a/report.txt"}); s.Pending != 0 || s.Failed != 0 {
+				t.Errorf("summary %+v", s)
+			}
+		})
+	}
+}
+
+func TestImportBatchConcurrent(t *testing.T) {
+	files := make(map[string]string)
+	var names []string
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("dir%d/file%03d.txt", i%7, i)
+		files[name] = fmt.Sprintf("content %d", i)
+		names = append(names, name)
+	}
+	root := writeBatchDir(t, files)
+	log, err := OpenBatchLog(filepath.Join(t.TempDir(), "import.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	log.SetDocumentCheck(func(string) bool { return true })
+
+	selected, failed, err := BatchFiles(root, BatchOptions{})
+	if err != nil || len(failed) > 0 || len(selected) != len(names) {
+		t.Fatalf("selected %d files, failed %v, err %v", len(selected), failed, err)
+	}
+
+	importer := &fakeImporter{}
+	progress := 0
+	importFile := func(root, rel string) BatchEntry {
+		if rel == "dir3/file010.txt" {
+			panic("corrupt file")
+		}
+		return importer.importFile(root, rel)
+	}
+	err = ImportBatch(context.Background(), root, selected, 8, log, importFile, func(BatchEntry) { progress++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress != len(names) {
+		t.Errorf("progress called %d times, want %d", progress, len(names))
+	}
+	s := log.Summary(selected)
+	if s.Imported != len(names)-1 || s.Failed != 1 || s.Pending != 0 {
+		t.Errorf("summary %+v", s)
+	}
+
+	// A second run imports only the file that failed, which fails again
+	importer.imported = nil
+	if err := ImportBatch(context.Background(), root, selected, 8, log, importFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(importer.imported) != 0 || log.Summary(selected).Failed != 1 {
+		t.Errorf("second run imported %v", importer.imported)
+	}
+}
+
+func TestImportBatchCancelled(t *testing.T) {
+	root := writeBatchDir(t, map[string]string{"1.txt": "one", "2.txt": "two", "3.txt": "three", "4.txt": "four"})
+	log, err := OpenBatchLog(filepath.Join(t.TempDir(), "import.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	importer := &fakeImporter{}
+	importFile := func(root, rel string) BatchEntry {
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		return importer.importFile(root, rel)
+	}
+	files := []string{"1.txt", "2.txt", "3.txt", "4.txt"}
+	if err := ImportBatch(ctx, root, files, 1, log, importFile, nil); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	s := log.Summary(files)
+	if s.Imported == 0 || s.Pending == 0 || s.Imported+s.Pending != len(files) {
+		t.Errorf("summary %+v", s)
+	}
+}
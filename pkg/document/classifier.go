@@ -0,0 +1,209 @@
+package document
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/classify"
+)
+
+// minTypeConfidence is the least probability at which a document is given
+// a type; less certain documents stay unknown
+const minTypeConfidence = 0.5
+
+// minTypeFeatures is the least number of known words and word pairs a
+// document needs to be classified
+const minTypeFeatures = 5
+
+//go:embed classifier_corpus.txt
+var baselineCorpus string
+
+// baseline is the classifier trained on the shipped corpus, built once
+var baseline struct {
+	once       sync.Once
+	classifier *Classifier
+}
+
+// TypeScore is the classifier's confidence that a document is of a type
+type TypeScore struct {
+	Type       DocumentType
+	Confidence float64 // 0 to 1
+}
+
+// Classifier assigns document types from their content with a naive Bayes
+// model
+type Classifier struct {
+	model *classify.Model
+}
+
+// BaselineClassifier returns the classifier trained on the shipped corpus,
+// which covers every document type in English and Spanish
+func BaselineClassifier() *Classifier {
+	baseline.once.Do(func() {
+		baseline.classifier = &Classifier{model: classify.Train(BaselineSamples())}
+	})
+	return baseline.classifier
+}
+
+// TrainClassifier trains a classifier on the shipped corpus and on the
+// documents whose type was corrected by a user
+func TrainClassifier(docs []*Document) *Classifier {
+	return &Classifier{model: classify.Train(append(BaselineSamples(), RelabeledSamples(docs)...))}
+}
+
+// LoadClassifier reads a classifier saved with Save
+func LoadClassifier(path string) (*Classifier, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open classifier model: %w", err)
+	}
+	defer f.Close()
+	model, err := classify.Load(f)
+	if err != nil {
+		return nil, err
+	}
+	return &Classifier{model: model}, nil
+}
+
+// Save writes the classifier's model
+func (c *Classifier) Save(w io.Writer) error {
+	return c.model.Save(w)
+}
+
+// Scores returns the confidence in each document type for a text, most
+// likely first, and whether the text says enough to be classified
+func (c *Classifier) Scores(text string) ([]TypeScore, bool) {
+	predicted, known := c.model.Predict(text)
+	var scores []TypeScore
+	for _, p := range predicted {
+		if t, err := ParseDocumentType(p.Label); err == nil {
+			scores = append(scores, TypeScore{Type: t, Confidence: p.Probability})
+		}
+	}
+	return scores, known >= minTypeFeatures
+}
+
+// Classify sets the type of a document and its confidence in each type.
+// Types corrected by a user are kept.
+func (c *Classifier) Classify(doc *Document) {
+	scores, enough := c.Scores(doc.Content)
+	doc.TypeScores = make(map[DocumentType]float64, len(scores))
+	for _, s := range scores {
+		doc.TypeScores[s.Type] = s.Confidence
+	}
+	if doc.TypeRelabeledBy != "" {
+		return
+	}
+	doc.Type = TypeUnknown
+	if enough && len(scores) > 0 && scores[0].Confidence >= minTypeConfidence {
+		doc.Type = scores[0].Type
+	}
+}
+
+// EvaluateClassifier measures the classifier against the shipped corpus and
+// the documents relabeled by users, by cross-validation over both
+func EvaluateClassifier(docs []*Document, folds int) classify.Report {
+	return classify.CrossValidate(append(BaselineSamples(), RelabeledSamples(docs)...), folds)
+}
+
+// EvaluateBaseline tests the shipped classifier on the documents relabeled
+// by users, which it was not trained on
+func EvaluateBaseline(docs []*Document) classify.Report {
+	return classify.Evaluate(BaselineClassifier().model, RelabeledSamples(docs))
+}
+
+// TopTypeScores returns the highest type scores of a document
+func (d *Document) TopTypeScores(n int) []TypeScore {
+	var scores []TypeScore
+	for t, confidence := range d.TypeScores {
+		scores = append(scores, TypeScore{Type: t, Confidence: confidence})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Confidence != scores[j].Confidence {
+			return scores[i].Confidence > scores[j].Confidence
+		}
+		return scores[i].Type < scores[j].Type
+	})
+	if len(scores) > n {
+		scores = scores[:n]
+	}
+	return scores
+}
+
+// BaselineSamples returns the shipped training corpus, labeled with
+// document type names
+func BaselineSamples() []classify.Sample {
+	var samples []classify.Sample
+	label := ""
+	var text []string
+	flush := func() {
+		if label != "" && len(text) > 0 {
+			samples = append(samples, classify.Sample{Label: label, Text: strings.Join(text, "\n")})
+		}
+		text = nil
+	}
+	for _, line := range strings.Split(baselineCorpus, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "## "):
+			flush()
+			label = strings.TrimSpace(line[3:])
+		case strings.HasPrefix(line, "#"):
+		case line == "":
+			flush()
+		default:
+			text = append(text, line)
+		}
+	}
+	flush()
+	return samples
+}
+
+// RelabeledSamples returns the documents whose type was corrected by a user
+// as training samples
+func RelabeledSamples(docs []*Document) []classify.Sample {
+	var samples []classify.Sample
+	for _, doc := range docs {
+		if doc.TypeRelabeledBy != "" && doc.Type != TypeUnknown && strings.TrimSpace(doc.Content) != "" {
+			samples = append(samples, classify.Sample{Label: GetDocumentTypeString(doc.Type), Text: doc.Content})
+		}
+	}
+	return samples
+}
+
+// ParseDocumentType returns the document type with a name such as "Witness
+// Statement" or "witness-statement", ignoring case and punctuation
+func ParseDocumentType(name string) (DocumentType, error) {
+	key := typeKey(name)
+	for t := TypeUnknown; t <= TypeTranscriptRecord; t++ {
+		if typeKey(GetDocumentTypeString(t)) == key {
+			return t, nil
+		}
+	}
+	return TypeUnknown, fmt.Errorf("unknown document type: %s", name)
+}
+
+// DocumentTypes returns every document type other than unknown
+func DocumentTypes() []DocumentType {
+	var types []DocumentType
+	for t := TypePoliceReport; t <= TypeTranscriptRecord; t++ {
+		types = append(types, t)
+	}
+	return types
+}
+
+// typeKey reduces a type name to its lowercase letters
+func typeKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
@@ -0,0 +1,127 @@
+package document
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBaselineClassifier(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want DocumentType
+	}{
+		{
+			"English police report",
+			"INCIDENT REPORT. Reporting officer Ofc. Lopez responded to a call of a burglary. Upon arrival the suspect fled on foot. Dispatch was notified and the case referred to the detective division.",
+			TypePoliceReport,
+		},
+		{
+			"Spanish police report",
+			"ACTA POLICIAL. El funcionario actuante, en labores de patrullaje, fue comisionado por la central para atender un hurto. Se practicó la aprehensión del ciudadano en flagrancia.",
+			TypePoliceReport,
+		},
+		{
+			"English witness statement",
+			"WITNESS STATEMENT. I, the undersigned, state of my own free will that I saw a man break the window of a car. This statement is true to the best of my recollection. Signed by the witness.",
+			TypeWitnessStatement,
+		},
+		{"too little to go on", "Page 2 of 7", TypeUnknown},
+	}
+	c := BaselineClassifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Content: tt.text}
+			c.Classify(doc)
+			if doc.Type != tt.want {
+				t.Errorf("type = %s, want %s; scores %v", GetDocumentTypeString(doc.Type), GetDocumentTypeString(tt.want), doc.TopTypeScores(3))
+			}
+		})
+	}
+}
+
+func TestClassifyKeepsRelabeledType(t *testing.T) {
+	doc := &Document{
+		Content:         "WITNESS STATEMENT. I saw a man break the window of a car. This statement is true. Signed by the witness.",
+		Type:            TypeNote,
+		TypeRelabeledBy: "det.gomez",
+	}
+	BaselineClassifier().Classify(doc)
+	if doc.Type != TypeNote {
+		t.Errorf("type = %s, want the relabeled type kept", GetDocumentTypeString(doc.Type))
+	}
+	if top := doc.TopTypeScores(1); len(top) != 1 || top[0].Type != TypeWitnessStatement {
+		t.Errorf("scores = %v", top)
+	}
+}
+
+func TestTrainClassifierOnRelabels(t *testing.T) {
+	// A kind of document the corpus does not cover, relabeled by a user
+	tip := "Anonymous crime stoppers tip line caller reported hotline tip reward number assigned"
+	var docs []*Document
+	for i := 0; i < 4; i++ {
+		docs = append(docs, &Document{Content: tip, Type: TypeNote, TypeRelabeledBy: "det.gomez"})
+	}
+	docs = append(docs, &Document{Content: tip, Type: TypeNote}) // Not relabeled, so not used
+
+	if n := len(RelabeledSamples(docs)); n != 4 {
+		t.Fatalf("relabeled samples = %d, want 4", n)
+	}
+	c := TrainClassifier(docs)
+	doc := &Document{Content: tip}
+	c.Classify(doc)
+	if doc.Type != TypeNote {
+		t.Errorf("type = %s, want Investigator Note; scores %v", GetDocumentTypeString(doc.Type), doc.TopTypeScores(3))
+	}
+
+	var b bytes.Buffer
+	if err := c.Save(&b); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadClassifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = &Document{Content: tip}
+	loaded.Classify(doc)
+	if doc.Type != TypeNote {
+		t.Errorf("loaded classifier type = %s", GetDocumentTypeString(doc.Type))
+	}
+}
+
+func TestEvaluateBaselineCorpus(t *testing.T) {
+	r := EvaluateClassifier(nil, 5)
+	if r.Samples != len(BaselineSamples()) || len(r.Classes) != len(DocumentTypes()) {
+		t.Errorf("report covers %d samples and %d types", r.Samples, len(r.Classes))
+	}
+	// A guard against changes that break the corpus or the model
+	if r.Accuracy < 0.6 {
+		t.Errorf("cross-validated accuracy %.2f is below 0.6:\n%s", r.Accuracy, r)
+	}
+}
+
+func TestParseDocumentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    DocumentType
+		wantErr bool
+	}{
+		{"Witness Statement", TypeWitnessStatement, false},
+		{"witness-statement", TypeWitnessStatement, false},
+		{"EVIDENCE_RECORD", TypeEvidence, false},
+		{"Unknown Document", TypeUnknown, false},
+		{"shopping list", TypeUnknown, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDocumentType(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseDocumentType(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
@@ -2,13 +2,22 @@ package document
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/entity"
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+	"github.com/jth/claude/GoInspectorGadget/pkg/ocr"
+	"github.com/jth/claude/GoInspectorGadget/pkg/pdf"
 )
 
 // DocumentType defines the type of document
@@ -36,31 +45,48 @@ const (
 
 // Document represents any document in the investigation system
 type Document struct {
-	ID             string
-	Title          string
-	Type           DocumentType
-	FilePath       string
-	ContentType    string
-	FileSize       int64
-	CreatedAt      time.Time
-	ModifiedAt     time.Time
-	Content        string      // Plain text content (if available)
-	Metadata       Metadata    // Document metadata
-	CaseID         string      // ID of the case this document belongs to
-	Tags           []string    // User-defined tags
-	Redactions     []Redaction // Any redacted portions
-	Annotations    []Annotation
-	IsConfidential bool
+	ID              string
+	Title           string
+	Type            DocumentType
+	TypeScores      map[DocumentType]float64 // Classifier confidence in each type
+	TypeRelabeledBy string                   // User who corrected the type; such documents retrain the classifier
+	FilePath        string                   // Stored copy, named by its SHA-256 hash
+	SourcePath      string                   // Path the document was imported from
+	SHA256          string
+	ContentType     string
+	FileSize        int64
+	CreatedAt       time.Time
+	ModifiedAt      time.Time
+	Content         string              // Plain text content (if available)
+	Metadata        Metadata            // Document metadata
+	CaseID          string              // ID of the case this document belongs to
+	Tags            []string            // User-defined tags
+	Redactions      []Redaction         // Any redacted portions
+	Proposals       []RedactionProposal // Redactions proposed by PII detection
+	Annotations     []Annotation
+	Attachments     []Attachment    // Images and other files embedded in the document
+	Pages           []Page          // Text of each page, for documents with pages
+	MachineRead     bool            // Some or all of the content was read by OCR
+	OCRWords        []OCRWord       // Words read by OCR, with their confidence
+	Entities        []entity.Entity // People, phones, dates and other entities in the content
+	Fingerprint     []uint64        // MinHash of the content, for finding near-duplicates
+	Version         int             // Place in its version chain, 0 for a document with no other versions
+	Supersedes      string          // ID of the earlier version this document revises
+	SupersededBy    string          // ID of the revision that replaces this document
+	IsConfidential  bool
 }
 
 // Metadata contains document metadata
 type Metadata struct {
-	Author       string
-	CreationDate time.Time
-	Subject      string
-	Keywords     []string
-	Source       string
-	CustomFields map[string]string
+	Author         string
+	CreationDate   time.Time
+	LastModifiedBy string
+	ModifiedDate   time.Time
+	Revision       int // Number of times the document was saved, if recorded
+	Subject        string
+	Keywords       []string
+	Source         string
+	CustomFields   map[string]string
 }
 
 // Redaction represents a redacted portion of a document
@@ -73,16 +99,73 @@ type Redaction struct {
 	IsTemporary bool
 }
 
+// AnnotationKind identifies annotations read from the document file itself
+type AnnotationKind string
+
+const (
+	AnnotationComment   AnnotationKind = "COMMENT"   // Reviewer comment
+	AnnotationInsertion AnnotationKind = "INSERTION" // Tracked insertion
+	AnnotationDeletion  AnnotationKind = "DELETION"  // Tracked deletion, whose text is not in the content
+)
+
 // Annotation represents a note or comment on a document
 type Annotation struct {
 	ID        string
 	UserID    string
+	Kind      AnnotationKind // Empty for notes added by investigators
+	Author    string         // Author recorded in the document file
 	Text      string
 	CreatedAt time.Time
 	Position  int // Position in the document where annotation is attached
 	IsPrivate bool
 }
 
+// Attachment is a file embedded in a document, such as an image
+type Attachment struct {
+	Name        string
+	ContentType string
+	Size        int64
+	SHA256      string
+	Path        string // Where the attachment was saved on import
+	Data        []byte // Content, until the attachment is saved
+}
+
+// pageSeparator separates the text of pages in the content, as pdftotext does
+const pageSeparator = "\f"
+
+// Page is the text of one page of a document, kept so that findings can
+// cite the page they were found on
+type Page struct {
+	Number      int
+	Offset      int // Position of the page's text in the content
+	Text        string
+	MachineRead bool // The text was read by OCR
+}
+
+// setPages sets the content of a document from the text of its pages
+func (d *Document) setPages(pages []string) {
+	d.Pages = make([]Page, len(pages))
+	offset := 0
+	for i, text := range pages {
+		d.Pages[i] = Page{Number: i + 1, Offset: offset, Text: text}
+		offset += len(text) + len(pageSeparator)
+	}
+	d.Content = strings.Join(pages, pageSeparator)
+}
+
+// PageAt returns the number of the page holding a position in the content,
+// or 0 if the document has no pages
+func (d *Document) PageAt(position int) int {
+	page := 0
+	for _, p := range d.Pages {
+		if p.Offset > position {
+			break
+		}
+		page = p.Number
+	}
+	return page
+}
+
 // DocumentProcessor defines the interface for processing different document types
 type DocumentProcessor interface {
 	Process(filePath string) (*Document, error)
@@ -99,33 +182,59 @@ type DocumentRepository interface {
 	Update(doc *Document) error
 }
 
-// ImportDocument imports a document into the system
-func ImportDocument(filePath string, targetDir string, processor DocumentProcessor) (*Document, error) {
+// ImportDocument imports a document into the system, processing it with
+// the registered processor for its content
+func ImportDocument(filePath string, targetDir string, processors *Registry) (*Document, error) {
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("file does not exist: %s", filePath)
 	}
 
+	// Identify the true file type from its content
+	fileType, err := filetype.IdentifyFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify file type: %w", err)
+	}
+
 	// Process document to extract metadata and content
+	processor, err := processors.ProcessorFor(filePath, fileType)
+	if err != nil {
+		return nil, err
+	}
 	doc, err := processor.Process(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process document: %w", err)
 	}
+	recordFileType(doc, fileType)
+	doc.Metadata.CustomFields["Processor"] = processor.Info().Name
+	processors.Classifier().Classify(doc)
+	doc.Fingerprint = Fingerprint(doc.Content)
 
 	// Generate a unique ID for the document if not set
 	if doc.ID == "" {
 		doc.ID = generateID()
 	}
 
-	// Copy file to target directory
+	// Store the file under its hash, so that documents with the same name
+	// never overwrite each other and identical files are stored once
+	doc.SourcePath = filePath
 	if targetDir != "" {
-		destPath := filepath.Join(targetDir, filepath.Base(filePath))
-		if err := copyFile(filePath, destPath); err != nil {
+		destPath, hash, err := storeFile(filePath, filepath.Join(targetDir, storeDir))
+		if err != nil {
 			return nil, fmt.Errorf("failed to copy file: %w", err)
 		}
 		doc.FilePath = destPath
+		doc.SHA256 = hash
+		if err := saveAttachments(doc, filepath.Join(targetDir, doc.ID+"-attachments")); err != nil {
+			return nil, err
+		}
 	} else {
+		hash, err := HashFile(filePath)
+		if err != nil {
+			return nil, err
+		}
 		doc.FilePath = filePath
+		doc.SHA256 = hash
 	}
 
 	// Set timestamps
@@ -136,31 +245,128 @@ func ImportDocument(filePath string, targetDir string, processor DocumentProcess
 	return doc, nil
 }
 
-// copyFile copies a file from src to dst
-func copyFile(src, dst string) error {
-	// Open source file
+// recordFileType stores the content-based type of an imported file and
+// flags an extension that does not match the content
+func recordFileType(doc *Document, fileType *filetype.Result) {
+	doc.ContentType = fileType.MIME
+	if doc.Metadata.CustomFields == nil {
+		doc.Metadata.CustomFields = make(map[string]string)
+	}
+	doc.Metadata.CustomFields["DetectedType"] = fileType.Name
+
+	if fileType.ExtensionMismatch {
+		doc.Metadata.CustomFields["ExtensionMismatch"] = fmt.Sprintf(
+			"extension .%s does not match content (%s)", fileType.Extension, fileType.Description)
+		doc.Tags = append(doc.Tags, filetype.MismatchTag)
+	}
+}
+
+// saveAttachments writes the embedded files of a document to dir
+func saveAttachments(doc *Document, dir string) error {
+	if len(doc.Attachments) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+	for i := range doc.Attachments {
+		a := &doc.Attachments[i]
+		path := filepath.Join(dir, fmt.Sprintf("%03d-%s", i+1, filepath.Base(a.Name)))
+		if err := os.WriteFile(path, a.Data, 0644); err != nil {
+			return fmt.Errorf("failed to save attachment %s: %w", a.Name, err)
+		}
+		a.Path = path
+		a.Data = nil
+	}
+	return nil
+}
+
+// storeDir is the directory of a document directory holding the stored
+// files, under subdirectories named by the first two digits of their hash
+const storeDir = "store"
+
+// storeFile copies a file into the content-addressed store and returns the
+// stored path and the file's SHA-256 hash. A file already stored is kept.
+func storeFile(src, dir string) (string, string, error) {
 	sourceFile, err := os.Open(src)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 	defer sourceFile.Close()
 
-	// Create destination file
-	destFile, err := os.Create(dst)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".import-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	// Hash the content while copying it
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), sourceFile); err != nil {
+		tmp.Close()
+		return "", "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	destPath := filepath.Join(dir, sum[:2], sum+strings.ToLower(filepath.Ext(src)))
+	if _, err := os.Stat(destPath); err == nil {
+		return destPath, sum, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return "", "", err
+	}
+	return destPath, sum, nil
+}
+
+// HashFile returns the SHA-256 hash of a file
+func HashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to open file: %w", err)
 	}
-	defer destFile.Close()
+	defer file.Close()
 
-	// Copy content
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
+// lastID holds the last timestamp handed out by newID
+var lastID int64
+
 // generateID generates a unique ID for a document
 func generateID() string {
+	return newID("DOC")
+}
+
+// newID generates a unique ID with a prefix. IDs are strictly increasing so
+// that documents imported in parallel never collide.
+func newID(prefix string) string {
 	// Simple implementation - would use UUID in production
-	return fmt.Sprintf("DOC-%d", time.Now().UnixNano())
+	for {
+		last := atomic.LoadInt64(&lastID)
+		next := time.Now().UnixNano()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastID, last, next) {
+			return fmt.Sprintf("%s-%d", prefix, next)
+		}
+	}
 }
 
 // GetDocumentTypeString returns a string representation of a document type
@@ -208,16 +414,33 @@ func GetDocumentTypeString(docType DocumentType) string {
 // PDFProcessor implements DocumentProcessor for PDF files
 type PDFProcessor struct {
 	// Configuration
-	PdfToTextPath string // Path to pdftotext executable
-	UseOCR        bool   // Whether to use OCR for image-based PDFs
-	TempDir       string // Directory for temporary files
+	PdfToTextPath string         // Path to pdftotext executable
+	PdfToPpmPath  string         // Path to pdftoppm executable, used to render pages for OCR
+	UseOCR        bool           // Whether to use OCR for image-based PDFs
+	OCR           *ocr.Tesseract // OCR engine for pages without text
+	TempDir       string         // Directory for temporary files
+}
+
+// Info declares the content handled by the PDF processor
+func (p *PDFProcessor) Info() ProcessorInfo {
+	return ProcessorInfo{
+		Name:          "pdf",
+		Description:   "PDF documents, page by page, with pdftotext and pdfinfo from poppler-utils or the built-in parser; scanned pages are read by OCR with tesseract",
+		FileTypes:     []string{"PDF"},
+		MIMETypes:     []string{"application/pdf"},
+		OptionalTools: []string{"pdftotext", "pdfinfo", "pdftoppm", "tesseract"},
+	}
 }
 
 // Process processes a PDF file and returns a Document
 func (p *PDFProcessor) Process(filePath string) (*Document, error) {
-	// Check if file exists and is PDF
-	if !strings.HasSuffix(strings.ToLower(filePath), ".pdf") {
-		return nil, fmt.Errorf("not a PDF file: %s", filePath)
+	// Check the content is PDF, whatever the file is called
+	fileType, err := filetype.IdentifyFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify file type: %w", err)
+	}
+	if fileType.Name != "PDF" {
+		return nil, fmt.Errorf("not a PDF file: %s (content is %s)", filePath, fileType.Description)
 	}
 
 	// Get file info
@@ -227,7 +450,7 @@ func (p *PDFProcessor) Process(filePath string) (*Document, error) {
 	}
 
 	// Extract text
-	text, err := p.ExtractText(filePath)
+	pages, extraction, err := p.ExtractPages(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract text: %w", err)
 	}
@@ -238,6 +461,23 @@ func (p *PDFProcessor) Process(filePath string) (*Document, error) {
 		// Don't fail completely on metadata extraction failure
 		fmt.Printf("Warning: Failed to extract metadata from %s: %v\n", filePath, err)
 	}
+	if metadata.CustomFields == nil {
+		metadata.CustomFields = make(map[string]string)
+	}
+
+	// Read pages without text, such as scanned pages, with OCR
+	read := make(map[int]ocr.Page)
+	if p.UseOCR && p.OCR != nil {
+		read = p.ocrPages(filePath, pages)
+		for i, page := range read {
+			pages[i] = page.Text
+		}
+		if len(read) > 0 {
+			languages, _ := p.OCR.LanguageList()
+			extraction += ", OCR with tesseract (" + languages + ")"
+		}
+	}
+	metadata.CustomFields["Extraction"] = extraction
 
 	// Create document
 	doc := &Document{
@@ -246,26 +486,54 @@ func (p *PDFProcessor) Process(filePath string) (*Document, error) {
 		FilePath:    filePath,
 		ContentType: "application/pdf",
 		FileSize:    fileInfo.Size(),
-		Content:     text,
 		Metadata:    metadata,
 		CreatedAt:   time.Now(),
 		ModifiedAt:  time.Now(),
 	}
+	doc.setPages(pages)
+	doc.addOCR(read)
 
 	// Try to infer document type from content
-	doc.Type = inferDocumentType(doc)
 
 	return doc, nil
 }
 
-// ExtractText extracts text from a PDF file
+// ExtractPages extracts the text of each page of a PDF file, with pdftotext
+// when it is installed and otherwise with the built-in parser, and reports
+// which was used
+func (p *PDFProcessor) ExtractPages(filePath string) ([]string, string, error) {
+	if p.PdfToTextPath != "" {
+		text, err := p.ExtractText(filePath)
+		if err == nil {
+			return strings.Split(strings.TrimSuffix(text, pageSeparator), pageSeparator), "pdftotext", nil
+		}
+		fmt.Printf("Warning: %v; using the built-in PDF parser\n", err)
+	}
+
+	f, err := pdf.Open(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	pages, err := f.PageText()
+	if err != nil {
+		return nil, "", err
+	}
+	return pages, "built-in PDF parser", nil
+}
+
+// ExtractText extracts text from a PDF file with pdftotext
 func (p *PDFProcessor) ExtractText(filePath string) (string, error) {
 	if p.PdfToTextPath == "" {
 		return "", fmt.Errorf("pdftotext not found, please install poppler-utils")
 	}
 
 	// Create temporary file for output
-	outputFile := filepath.Join(p.TempDir, fmt.Sprintf("%d.txt", time.Now().UnixNano()))
+	output, err := os.CreateTemp(p.TempDir, "pdftotext-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	output.Close()
+	outputFile := output.Name()
 	defer os.Remove(outputFile)
 
 	// Run pdftotext command
@@ -292,10 +560,10 @@ func (p *PDFProcessor) ExtractMetadata(filePath string) (Metadata, error) {
 		CustomFields: make(map[string]string),
 	}
 
-	// Use pdfinfo to extract metadata
+	// Use pdfinfo to extract metadata, or the built-in parser without it
 	pdfinfoPath, err := exec.LookPath("pdfinfo")
 	if err != nil {
-		return metadata, fmt.Errorf("pdfinfo not found, please install poppler-utils")
+		return readPDFMetadata(filePath)
 	}
 
 	cmd := exec.Command(pdfinfoPath, filePath)
@@ -344,88 +612,40 @@ func (p *PDFProcessor) ExtractMetadata(filePath string) (Metadata, error) {
 	return metadata, nil
 }
 
-// inferDocumentType attempts to determine the document type based on content
-func inferDocumentType(doc *Document) DocumentType {
-	text := strings.ToLower(doc.Content)
-
-	// Police report indicators
-	if containsAny(text, []string{
-		"police report",
-		"incident report",
-		"offense report",
-		"case report",
-		"officer narrative",
-		"reporting officer",
-	}) {
-		return TypePoliceReport
-	}
-
-	// Witness statement indicators
-	if containsAny(text, []string{
-		"witness statement",
-		"statement of witness",
-		"i, the undersigned",
-		"do hereby state",
-		"to the best of my recollection",
-		"i witnessed",
-		"i observed",
-	}) {
-		return TypeWitnessStatement
-	}
-
-	// Forensic report indicators
-	if containsAny(text, []string{
-		"forensic report",
-		"laboratory report",
-		"examination results",
-		"dna analysis",
-		"ballistics report",
-		"toxicology report",
-		"fingerprint analysis",
-	}) {
-		return TypeForensicReport
-	}
-
-	// Court filing indicators
-	if containsAny(text, []string{
-		"court of",
-		"state vs",
-		"plaintiff",
-		"defendant",
-		"motion to",
-		"hereby ordered",
-		"judge",
-		"docket",
-		"hearing",
-		"trial",
-	}) {
-		return TypeCourtFiling
-	}
-
-	// Medical report indicators
-	if containsAny(text, []string{
-		"medical report",
-		"patient name",
-		"diagnosis",
-		"treatment",
-		"physician",
-		"hospital",
-		"medical record",
-		"symptoms",
-	}) {
-		return TypeMedicalReport
-	}
-
-	// Default to unknown
-	return TypeUnknown
-}
-
-// containsAny checks if the text contains any of the keywords
-func containsAny(text string, keywords []string) bool {
-	for _, keyword := range keywords {
-		if strings.Contains(text, keyword) {
-			return true
+// readPDFMetadata reads the document information and XMP metadata of a PDF
+// file with the built-in parser, naming fields as pdfinfo does
+func readPDFMetadata(filePath string) (Metadata, error) {
+	metadata := Metadata{CustomFields: make(map[string]string)}
+	f, err := pdf.Open(filePath)
+	if err != nil {
+		return metadata, err
+	}
+	info := f.Info()
+
+	metadata.Subject = info.Title
+	metadata.Author = info.Author
+	metadata.Keywords = splitKeywords(info.Keywords)
+	metadata.CreationDate = info.CreationDate
+	metadata.ModifiedDate = info.ModDate
+	for key, value := range info.XMP {
+		metadata.CustomFields[key] = value
+	}
+	for key, value := range map[string]string{
+		"Subject":     info.Subject,
+		"Creator":     info.Creator,
+		"Producer":    info.Producer,
+		"Pages":       strconv.Itoa(f.NumPages()),
+		"PDF version": f.Version,
+	} {
+		if value != "" {
+			metadata.CustomFields[key] = value
 		}
 	}
-	return false
+	if !info.ModDate.IsZero() {
+		metadata.CustomFields["ModDate"] = info.ModDate.Format(time.RFC3339)
+	}
+	if f.Encrypted() {
+		metadata.CustomFields["Encrypted"] = "yes"
+	}
+	return metadata, nil
 }
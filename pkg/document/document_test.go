@@ -0,0 +1,84 @@
+package document
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// twoPagePDF has no cross-reference table, which the built-in parser
+// recovers by scanning for objects
+const twoPagePDF = `%PDF-1.4
+1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj
+2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >> endobj
+3 0 obj << /Type /Page /Parent 2 0 R /Contents 6 0 R >> endobj
+4 0 obj << /Type /Page /Parent 2 0 R /Contents 7 0 R >> endobj
+5 0 obj << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> endobj
+6 0 obj << /Length 44 >>
+stream
+BT /F1 12 Tf 72 700 Td (Primera pagina) Tj ET
+endstream
+endobj
+7 0 obj << /Length 43 >>
+stream
+BT /F1 12 Tf 72 700 Td (Segunda pagina) Tj ET
+endstream
+endobj
+8 0 obj << /Author (Ana Garcia) /Title (Informe) /CreationDate (D:20240301091500Z) >> endobj
+trailer << /Root 1 0 R /Info 8 0 R >>
+%%EOF
+`
+
+func TestPDFProcessorBuiltIn(t *testing.T) {
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "report.bin")
+	if err := os.WriteFile(pdfPath, []byte(twoPagePDF), 0644); err != nil {
+		t.Fatal(err)
+	}
+	textPath := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(textPath, []byte("not a PDF at all\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantPages  []string
+		wantAuthor string
+		wantErr    string
+	}{
+		{"pages and properties", pdfPath, []string{"Primera pagina", "Segunda pagina"}, "Ana Garcia", ""},
+		{"content that is not PDF", textPath, nil, "", "not a PDF file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := (&PDFProcessor{}).Process(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pages []string
+			for _, p := range doc.Pages {
+				pages = append(pages, p.Text)
+			}
+			if strings.Join(pages, "|") != strings.Join(tt.wantPages, "|") {
+				t.Errorf("pages = %q", pages)
+			}
+			if doc.PageAt(strings.Index(doc.Content, "Segunda")) != 2 {
+				t.Errorf("second page text not on page 2 of %q", doc.Content)
+			}
+			if doc.Metadata.Author != tt.wantAuthor || doc.Metadata.CreationDate.IsZero() {
+				t.Errorf("metadata = %+v", doc.Metadata)
+			}
+			if doc.Metadata.CustomFields["Extraction"] != "built-in PDF parser" {
+				t.Errorf("extraction = %q", doc.Metadata.CustomFields["Extraction"])
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	nsWordML       = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	relOfficeDocID = "/officeDocument" // Suffix of the main document relationship type
+)
+
+// DOCXProcessor implements DocumentProcessor for Word 2007+ documents
+type DOCXProcessor struct{}
+
+// Info declares the content handled by the Word processor
+func (p *DOCXProcessor) Info() ProcessorInfo {
+	return ProcessorInfo{
+		Name:        "docx",
+		Description: "Word 2007+ documents: text, properties, comments, tracked changes and images",
+		FileTypes:   []string{"DOCX"},
+	}
+}
+
+// Process extracts the text, properties, embedded images, comments and
+// tracked changes of a Word document
+func (p *DOCXProcessor) Process(filePath string) (*Document, error) {
+	fileType, info, err := identifyAs(filePath, "DOCX")
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	defer zr.Close()
+
+	mainPart := docxMainPart(&zr.Reader)
+	body, err := readZipPart(&zr.Reader, mainPart)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("document has no %s", mainPart)
+	}
+
+	text := &textBuilder{}
+	commentStarts, err := walkDOCXBody(body, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", mainPart, err)
+	}
+	comments, err := readZipPart(&zr.Reader, path.Join(path.Dir(mainPart), "comments.xml"))
+	if err != nil {
+		return nil, err
+	}
+	if comments != nil {
+		if err := readDOCXComments(comments, commentStarts, text); err != nil {
+			return nil, fmt.Errorf("failed to read comments: %w", err)
+		}
+	}
+	sort.SliceStable(text.annotations, func(i, j int) bool { return text.annotations[i].Position < text.annotations[j].Position })
+
+	metadata, err := docxMetadata(&zr.Reader)
+	if err != nil {
+		return nil, err
+	}
+	doc := newProcessedDocument(filePath, fileType, info, text, metadata)
+	if doc.Attachments, err = zipAttachments(&zr.Reader, path.Dir(mainPart)+"/media/"); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// docxMainPart finds the main document part from the package relationships,
+// which is word/document.xml in files saved by Word
+func docxMainPart(zr *zip.Reader) string {
+	data, err := readZipPart(zr, "_rels/.rels")
+	if err == nil && data != nil {
+		var rels struct {
+			Relationships []struct {
+				Type   string `xml:"Type,attr"`
+				Target string `xml:"Target,attr"`
+			} `xml:"Relationship"`
+		}
+		if xml.Unmarshal(data, &rels) == nil {
+			for _, r := range rels.Relationships {
+				if strings.HasSuffix(r.Type, relOfficeDocID) {
+					return strings.TrimPrefix(r.Target, "/")
+				}
+			}
+		}
+	}
+	return "word/document.xml"
+}
+
+// docxChange is a tracked insertion or deletion being read
+type docxChange struct {
+	kind     AnnotationKind
+	author   string
+	date     time.Time
+	position int
+	text     strings.Builder
+}
+
+// walkDOCXBody writes the text of a document part to text, recording
+// tracked changes, and returns where each comment's range starts
+func walkDOCXBody(data []byte, text *textBuilder) (map[string]int, error) {
+	commentStarts := make(map[string]int)
+	var changes []*docxChange
+	var cells []int // Cells started in each open table row
+	inText, inDeleted, skip := false, false, 0
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != nsWordML {
+				continue
+			}
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "pPr", "rPr", "sectPr", "tblPr":
+				skip = 1 // Properties, including tab stop definitions
+			case "t":
+				inText = true
+			case "delText":
+				inDeleted = true
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.WriteString("\n")
+			case "noBreakHyphen":
+				text.WriteString("-")
+			case "tr":
+				cells = append(cells, 0)
+			case "tc":
+				if n := len(cells); n > 0 {
+					if cells[n-1] > 0 {
+						text.separate("\t")
+					}
+					cells[n-1]++
+				}
+			case "ins", "moveTo":
+				changes = append(changes, newDOCXChange(AnnotationInsertion, t, text.offset()))
+			case "del", "moveFrom":
+				changes = append(changes, newDOCXChange(AnnotationDeletion, t, text.offset()))
+			case "commentRangeStart":
+				commentStarts[xmlAttr(t, "id")] = text.offset()
+			case "commentReference":
+				if _, ok := commentStarts[xmlAttr(t, "id")]; !ok {
+					commentStarts[xmlAttr(t, "id")] = text.offset()
+				}
+			}
+
+		case xml.EndElement:
+			if t.Name.Space != nsWordML {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "delText":
+				inDeleted = false
+			case "p":
+				if len(cells) > 0 {
+					text.separate(" ")
+				} else {
+					text.endParagraph()
+				}
+			case "tr":
+				if n := len(cells); n > 0 {
+					cells = cells[:n-1]
+				}
+				text.separate("\n")
+			case "ins", "moveTo", "del", "moveFrom":
+				if n := len(changes); n > 0 {
+					c := changes[n-1]
+					changes = changes[:n-1]
+					text.annotate(c.kind, c.author, c.date, c.text.String(), c.position)
+				}
+			}
+
+		case xml.CharData:
+			if skip > 0 {
+				continue
+			}
+			var change *docxChange
+			if n := len(changes); n > 0 {
+				change = changes[n-1]
+			}
+			switch {
+			case inText:
+				text.WriteString(string(t))
+				if change != nil && change.kind == AnnotationInsertion {
+					change.text.Write(t)
+				}
+			case inDeleted && change != nil:
+				change.text.Write(t)
+			}
+		}
+	}
+	return commentStarts, nil
+}
+
+// newDOCXChange starts reading a w:ins or w:del element
+func newDOCXChange(kind AnnotationKind, e xml.StartElement, position int) *docxChange {
+	return &docxChange{
+		kind:     kind,
+		author:   xmlAttr(e, "author"),
+		date:     parseDocumentDate(xmlAttr(e, "date")),
+		position: position,
+	}
+}
+
+// readDOCXComments adds the comments of a document as annotations at the
+// start of the text they refer to
+func readDOCXComments(data []byte, starts map[string]int, text *textBuilder) error {
+	var comments struct {
+		Comments []struct {
+			ID         string `xml:"id,attr"`
+			Author     string `xml:"author,attr"`
+			Date       string `xml:"date,attr"`
+			Paragraphs []struct {
+				Inner []byte `xml:",innerxml"`
+			} `xml:"p"`
+		} `xml:"comment"`
+	}
+	if err := xml.Unmarshal(data, &comments); err != nil {
+		return err
+	}
+
+	for _, c := range comments.Comments {
+		var body []string
+		for _, p := range c.Paragraphs {
+			body = append(body, xmlText(p.Inner, "t"))
+		}
+		position, ok := starts[c.ID]
+		if !ok {
+			position = text.offset()
+		}
+		text.annotate(AnnotationComment, c.Author, parseDocumentDate(c.Date), strings.Join(body, "\n"), position)
+	}
+	return nil
+}
+
+// docxMetadata reads the core and application properties of a Word document
+func docxMetadata(zr *zip.Reader) (Metadata, error) {
+	metadata := Metadata{CustomFields: make(map[string]string)}
+
+	core, err := readZipPart(zr, "docProps/core.xml")
+	if err != nil {
+		return metadata, err
+	}
+	if core != nil {
+		fields, err := xmlLeafFields(core)
+		if err != nil {
+			return metadata, fmt.Errorf("failed to read core properties: %w", err)
+		}
+		for name, value := range fields {
+			switch name {
+			case "creator":
+				metadata.Author = value
+			case "lastModifiedBy":
+				metadata.LastModifiedBy = value
+			case "created":
+				metadata.CreationDate = parseDocumentDate(value)
+			case "modified":
+				metadata.ModifiedDate = parseDocumentDate(value)
+			case "revision":
+				metadata.Revision = atoi(value)
+			case "subject":
+				metadata.Subject = value
+			case "keywords":
+				metadata.Keywords = splitKeywords(value)
+			case "title":
+				metadata.CustomFields["Title"] = value
+			case "description":
+				metadata.CustomFields["Comments"] = value
+			case "lastPrinted":
+				metadata.CustomFields["LastPrinted"] = value
+			case "category":
+				metadata.CustomFields["Category"] = value
+			}
+		}
+		if metadata.Subject == "" {
+			metadata.Subject = metadata.CustomFields["Title"]
+		}
+	}
+
+	app, err := readZipPart(zr, "docProps/app.xml")
+	if err != nil {
+		return metadata, err
+	}
+	if app != nil {
+		fields, err := xmlLeafFields(app)
+		if err != nil {
+			return metadata, fmt.Errorf("failed to read application properties: %w", err)
+		}
+		for _, name := range []string{"Application", "AppVersion", "Company", "Manager", "Template", "TotalTime", "Pages", "Words"} {
+			if value := fields[name]; value != "" {
+				metadata.CustomFields[name] = value
+			}
+		}
+	}
+	return metadata, nil
+}
+
+// xmlAttr returns an attribute of an element by its local name
+func xmlAttr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// xmlText concatenates the text of the elements with a local name in an XML
+// fragment. Fragments lose their namespace declarations, so only the local
+// name is compared.
+func xmlText(fragment []byte, local string) string {
+	var b strings.Builder
+	d := xml.NewDecoder(bytes.NewReader(fragment))
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == local {
+				depth++
+			}
+		case xml.EndElement:
+			if t.Name.Local == local && depth > 0 {
+				depth--
+			}
+		case xml.CharData:
+			if depth > 0 {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String()
+}
+
+// xmlLeafFields returns the text of the elements without children in a
+// properties document, keyed by local name. Repeated fields are joined.
+func xmlLeafFields(data []byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var name string
+	var value strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name = t.Name.Local
+			value.Reset()
+		case xml.CharData:
+			value.Write(t)
+		case xml.EndElement:
+			if name == t.Name.Local {
+				if v := strings.TrimSpace(value.String()); v != "" {
+					if fields[name] != "" {
+						v = fields[name] + ", " + v
+					}
+					fields[name] = v
+				}
+			}
+			name = ""
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/entity"
+)
+
+// ExtractEntities finds the people, phone numbers, dates and other entities
+// mentioned in a document and records them on it
+func (d *Document) ExtractEntities(x *entity.Extractor) []entity.Entity {
+	d.Entities = x.Extract(d.Content)
+	return d.Entities
+}
+
+// FindEntities returns the extracted entities of a document with the given
+// IDs, in the order given
+func (d *Document) FindEntities(ids ...string) ([]entity.Entity, error) {
+	var found []entity.Entity
+	for _, id := range ids {
+		i := 0
+		for i < len(d.Entities) && !strings.EqualFold(d.Entities[i].ID, id) {
+			i++
+		}
+		if i == len(d.Entities) {
+			return nil, fmt.Errorf("entity not found in %s: %s", d.ID, id)
+		}
+		found = append(found, d.Entities[i])
+	}
+	return found, nil
+}
@@ -0,0 +1,247 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+	"github.com/jth/claude/GoInspectorGadget/pkg/ocr"
+	"github.com/jth/claude/GoInspectorGadget/pkg/pdf"
+)
+
+// minPageText is the least text a PDF page holds unless it is a scanned
+// image; pages with less are read by OCR
+const minPageText = 10
+
+// rasterDPI is the resolution PDF pages are rendered at for OCR
+const rasterDPI = 300
+
+// OCRWord is a word read by OCR, with the engine's confidence in it
+type OCRWord struct {
+	Text       string
+	Confidence float64 // 0 to 100
+	Page       int     // Page number, 0 for documents without pages
+	Offset     int     // Position of the word in the content
+}
+
+// imageFileTypes are the image formats read by OCR, as named by pkg/filetype
+var imageFileTypes = []string{"JPEG", "PNG", "TIFF", "BMP", "GIF"}
+
+// ImageProcessor implements DocumentProcessor for scanned pages and
+// photographs, reading their text with OCR
+type ImageProcessor struct {
+	OCR *ocr.Tesseract
+}
+
+// Info declares the content handled by the image processor
+func (p *ImageProcessor) Info() ProcessorInfo {
+	return ProcessorInfo{
+		Name:        "image",
+		Description: "Scanned pages and photographs (JPEG, PNG, TIFF, BMP, GIF), read by OCR with tesseract",
+		FileTypes:   imageFileTypes,
+		Tools:       []string{"tesseract"},
+	}
+}
+
+// Process reads the text of an image with OCR, one page per image in
+// multi-page TIFF files
+func (p *ImageProcessor) Process(filePath string) (*Document, error) {
+	fileType, err := filetype.IdentifyFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify file type: %w", err)
+	}
+	if !containsString(imageFileTypes, fileType.Name) {
+		return nil, fmt.Errorf("not a supported image: %s (content is %s)", filePath, fileType.Description)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if p.OCR == nil {
+		return nil, fmt.Errorf("tesseract not found, please install tesseract-ocr")
+	}
+
+	pages, err := p.OCR.Recognize(filePath, 0)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(pages))
+	read := make(map[int]ocr.Page)
+	for i, page := range pages {
+		texts[i] = page.Text
+		read[i] = page
+	}
+
+	doc := &Document{
+		Title:       filepath.Base(filePath),
+		Type:        TypeUnknown,
+		FilePath:    filePath,
+		ContentType: fileType.MIME,
+		FileSize:    info.Size(),
+		Metadata:    Metadata{CustomFields: make(map[string]string)},
+		CreatedAt:   time.Now(),
+		ModifiedAt:  time.Now(),
+	}
+	doc.setPages(texts)
+	doc.addOCR(read)
+	languages, _ := p.OCR.LanguageList()
+	doc.Metadata.CustomFields["Extraction"] = "OCR with tesseract (" + languages + ")"
+	return doc, nil
+}
+
+// addOCR marks the pages read by OCR, keyed by index, and records their
+// words at their positions in the content
+func (d *Document) addOCR(read map[int]ocr.Page) {
+	total, count := 0.0, 0
+	for i := range d.Pages {
+		page, ok := read[i]
+		if !ok {
+			continue
+		}
+		d.MachineRead = true
+		d.Pages[i].MachineRead = true
+		for _, w := range page.Words {
+			d.OCRWords = append(d.OCRWords, OCRWord{
+				Text:       w.Text,
+				Confidence: w.Confidence,
+				Page:       d.Pages[i].Number,
+				Offset:     d.Pages[i].Offset + w.Offset,
+			})
+			total += w.Confidence
+			count++
+		}
+	}
+	if d.MachineRead {
+		if d.Metadata.CustomFields == nil {
+			d.Metadata.CustomFields = make(map[string]string)
+		}
+		d.Metadata.CustomFields["OCR pages"] = strconv.Itoa(len(read))
+		if count > 0 {
+			d.Metadata.CustomFields["OCR confidence"] = strconv.FormatFloat(total/float64(count), 'f', 1, 64)
+		}
+	}
+}
+
+// ocrPages reads the pages of a PDF file that hold little or no text with
+// OCR, returning what was read keyed by page index. Pages that cannot be
+// read keep their text.
+func (p *PDFProcessor) ocrPages(filePath string, texts []string) map[int]ocr.Page {
+	read := make(map[int]ocr.Page)
+	var f *pdf.File
+	for i, text := range texts {
+		if len(strings.TrimSpace(text)) >= minPageText {
+			continue
+		}
+		var page ocr.Page
+		var err error
+		if p.PdfToPpmPath != "" {
+			page, err = p.ocrRenderedPage(filePath, i+1)
+		} else {
+			if f == nil {
+				if f, err = pdf.Open(filePath); err != nil {
+					fmt.Printf("Warning: OCR skipped: %v\n", err)
+					return read
+				}
+			}
+			page, err = p.ocrPageImages(f, i+1)
+		}
+		if err != nil {
+			fmt.Printf("Warning: OCR of page %d failed: %v\n", i+1, err)
+			continue
+		}
+		if strings.TrimSpace(page.Text) != "" {
+			read[i] = page
+		}
+	}
+	return read
+}
+
+// ocrRenderedPage renders a page with pdftoppm and reads it with OCR
+func (p *PDFProcessor) ocrRenderedPage(filePath string, number int) (ocr.Page, error) {
+	dir, err := os.MkdirTemp(p.TempDir, "ocr-page-")
+	if err != nil {
+		return ocr.Page{}, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := strconv.Itoa(number)
+	outBase := filepath.Join(dir, "page")
+	cmd := exec.Command(p.PdfToPpmPath, "-r", strconv.Itoa(rasterDPI), "-f", n, "-l", n, "-png", "-singlefile", filePath, outBase)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return ocr.Page{}, fmt.Errorf("pdftoppm failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	pages, err := p.OCR.Recognize(outBase+".png", rasterDPI)
+	if err != nil {
+		return ocr.Page{}, err
+	}
+	return joinOCRPages(pages), nil
+}
+
+// ocrPageImages reads the images drawn on a page with OCR, for when
+// pdftoppm is not installed to render the page
+func (p *PDFProcessor) ocrPageImages(f *pdf.File, number int) (ocr.Page, error) {
+	images, err := f.PageImages(number)
+	if err != nil {
+		return ocr.Page{}, err
+	}
+	if len(images) == 0 {
+		return ocr.Page{}, fmt.Errorf("no images in a supported format")
+	}
+	dir, err := os.MkdirTemp(p.TempDir, "ocr-page-")
+	if err != nil {
+		return ocr.Page{}, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var pages []ocr.Page
+	for i, img := range images {
+		path := filepath.Join(dir, fmt.Sprintf("image%d.%s", i+1, img.Format))
+		if err := os.WriteFile(path, img.Data, 0644); err != nil {
+			return ocr.Page{}, fmt.Errorf("failed to write page image: %w", err)
+		}
+		read, err := p.OCR.Recognize(path, img.DPI)
+		if err != nil {
+			return ocr.Page{}, err
+		}
+		pages = append(pages, read...)
+	}
+	return joinOCRPages(pages), nil
+}
+
+// joinOCRPages combines the text read from several images of one page,
+// separating them with a blank line
+func joinOCRPages(pages []ocr.Page) ocr.Page {
+	var joined ocr.Page
+	for _, page := range pages {
+		if strings.TrimSpace(page.Text) == "" {
+			continue
+		}
+		if joined.Text != "" {
+			joined.Text += "\n\n"
+		}
+		for _, w := range page.Words {
+			w.Offset += len(joined.Text)
+			joined.Words = append(joined.Words, w)
+		}
+		joined.Text += page.Text
+	}
+	return joined
+}
+
+// containsString reports whether a list holds a string
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package document
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/ocr"
+)
+
+// fakeOCR installs a tesseract script that lists eng and spa and reads
+// every image as the given words, recording the arguments of each run
+func fakeOCR(t *testing.T, words ...string) (*ocr.Tesseract, string) {
+	t.Helper()
+	dir := t.TempDir()
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n"
+	for i, w := range words {
+		tsv += fmt.Sprintf("5\t1\t1\t1\t1\t%d\t0\t0\t10\t10\t%d\t%s\n", i+1, 90-10*i, w)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "out.tsv"), []byte(tsv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = --list-langs ]; then printf 'eng\\nspa\\n'; exit 0; fi\n" +
+		"echo \"$@\" >> " + args + "\n" +
+		"cat " + filepath.Join(dir, "out.tsv") + " > \"$2.tsv\"\n"
+	path := filepath.Join(dir, "tesseract")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &ocr.Tesseract{Path: path, TempDir: dir}, args
+}
+
+func TestImageProcessor(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "scan.jpg")
+	if err := os.WriteFile(image, []byte(pngData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	text := filepath.Join(dir, "notes.png")
+	if err := os.WriteFile(text, []byte("plain text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine, _ := fakeOCR(t, "Matrícula", "1234ABC")
+
+	tests := []struct {
+		name    string
+		path    string
+		engine  *ocr.Tesseract
+		wantErr string
+	}{
+		{"image read by OCR", image, engine, ""},
+		{"content that is not an image", text, engine, "not a supported image"},
+		{"tesseract not installed", image, nil, "tesseract not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := (&ImageProcessor{OCR: tt.engine}).Process(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Content != "Matrícula 1234ABC" || !doc.MachineRead || len(doc.Pages) != 1 || !doc.Pages[0].MachineRead {
+				t.Errorf("document = %q, machine read %v, pages %+v", doc.Content, doc.MachineRead, doc.Pages)
+			}
+			if len(doc.OCRWords) != 2 || doc.OCRWords[1].Offset != strings.Index(doc.Content, "1234ABC") || doc.OCRWords[1].Confidence != 80 {
+				t.Errorf("words = %+v", doc.OCRWords)
+			}
+			fields := doc.Metadata.CustomFields
+			if fields["OCR confidence"] != "85.0" || fields["Extraction"] != "OCR with tesseract (eng+spa)" {
+				t.Errorf("fields = %v", fields)
+			}
+		})
+	}
+}
+
+// scannedPDF has a page of text followed by a page holding only a JPEG
+// image 2550 pixels wide drawn across the 612 point page, that is at 300 DPI
+const scannedPDF = `%PDF-1.4
+1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj
+2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj
+3 0 obj << /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 6 0 R >> endobj
+4 0 obj << /Type /Page /Parent 2 0 R /Resources << /XObject << /Im1 8 0 R >> >> /Contents 7 0 R >> endobj
+5 0 obj << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> endobj
+6 0 obj << /Length 51 >>
+stream
+BT /F1 12 Tf 72 700 Td (Declaracion del testigo) Tj ET
+endstream
+endobj
+7 0 obj << /Length 30 >>
+stream
+q 612 0 0 792 0 0 cm /Im1 Do Q
+endstream
+endobj
+8 0 obj << /Type /XObject /Subtype /Image /Width 2550 /Height 3300 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode /Length 4 >>
+stream
+JPEG
+endstream
+endobj
+trailer << /Root 1 0 R >>
+%%EOF
+`
+
+func TestPDFProcessorOCR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statement.pdf")
+	if err := os.WriteFile(path, []byte(scannedPDF), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		useOCR        bool
+		wantPages     []string
+		wantMachine   []bool
+		wantRuns      int
+		wantExtracted string
+	}{
+		{"scanned page read", true, []string{"Declaracion del testigo", "Firmado"}, []bool{false, true}, 1, "built-in PDF parser, OCR with tesseract (eng+spa)"},
+		{"OCR turned off", false, []string{"Declaracion del testigo", ""}, []bool{false, false}, 0, "built-in PDF parser"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, args := fakeOCR(t, "Firmado")
+			p := &PDFProcessor{UseOCR: tt.useOCR, OCR: engine, TempDir: t.TempDir()}
+			doc, err := p.Process(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(doc.Pages) != len(tt.wantPages) {
+				t.Fatalf("pages = %+v", doc.Pages)
+			}
+			for i, page := range doc.Pages {
+				if page.Text != tt.wantPages[i] || page.MachineRead != tt.wantMachine[i] {
+					t.Errorf("page %d = %+v", i+1, page)
+				}
+			}
+			if doc.Metadata.CustomFields["Extraction"] != tt.wantExtracted {
+				t.Errorf("extraction = %q", doc.Metadata.CustomFields["Extraction"])
+			}
+			data, _ := os.ReadFile(args)
+			runs := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(data) == 0 {
+				runs = nil
+			}
+			if len(runs) != tt.wantRuns {
+				t.Fatalf("tesseract runs = %q", runs)
+			}
+			if tt.wantRuns > 0 && !strings.Contains(runs[0], "--dpi 300") {
+				t.Errorf("run = %q, want the image resolution", runs[0])
+			}
+			if tt.useOCR && (len(doc.OCRWords) != 1 || doc.OCRWords[0].Page != 2 || doc.OCRWords[0].Offset != strings.Index(doc.Content, "Firmado")) {
+				t.Errorf("words = %+v", doc.OCRWords)
+			}
+		})
+	}
+}
+
+func TestJoinOCRPages(t *testing.T) {
+	page := func(text string) ocr.Page {
+		return ocr.Page{Text: text, Words: []ocr.Word{{Text: text, Offset: 0}}}
+	}
+	tests := []struct {
+		name    string
+		pages   []ocr.Page
+		want    string
+		offsets []int
+	}{
+		{"one image", []ocr.Page{page("uno")}, "uno", []int{0}},
+		{"several images", []ocr.Page{page("uno"), page("dos")}, "uno\n\ndos", []int{0, 5}},
+		{"blank images skipped", []ocr.Page{{Text: " "}, page("uno"), {}, page("dos")}, "uno\n\ndos", []int{0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joined := joinOCRPages(tt.pages)
+			if joined.Text != tt.want || len(joined.Words) != len(tt.offsets) {
+				t.Fatalf("joined = %+v", joined)
+			}
+			for i, w := range joined.Words {
+				if w.Offset != tt.offsets[i] {
+					t.Errorf("word %d offset = %d, want %d", i, w.Offset, tt.offsets[i])
+				}
+			}
+		})
+	}
+}
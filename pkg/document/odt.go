@@ -0,0 +1,319 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// XML namespaces of OpenDocument files
+const (
+	nsODFText   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	nsODFTable  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	nsODFOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	nsODFMeta   = "urn:oasis:names:tc:opendocument:xmlns:meta:1.0"
+	nsDC        = "http://purl.org/dc/elements/1.1/"
+)
+
+// ODTProcessor implements DocumentProcessor for OpenDocument text files
+type ODTProcessor struct{}
+
+// Info declares the content handled by the OpenDocument processor
+func (p *ODTProcessor) Info() ProcessorInfo {
+	return ProcessorInfo{
+		Name:        "odt",
+		Description: "OpenDocument text: text, properties, comments, tracked changes and images",
+		FileTypes:   []string{"ODT"},
+	}
+}
+
+// Process extracts the text, properties, embedded images, comments and
+// tracked changes of an OpenDocument text file
+func (p *ODTProcessor) Process(filePath string) (*Document, error) {
+	fileType, info, err := identifyAs(filePath, "ODT")
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	defer zr.Close()
+
+	content, err := readZipPart(&zr.Reader, "content.xml")
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, fmt.Errorf("document has no content.xml")
+	}
+
+	text := &textBuilder{}
+	if err := walkODTContent(content, text); err != nil {
+		return nil, fmt.Errorf("failed to read content.xml: %w", err)
+	}
+	sort.SliceStable(text.annotations, func(i, j int) bool { return text.annotations[i].Position < text.annotations[j].Position })
+
+	metadata := Metadata{CustomFields: make(map[string]string)}
+	meta, err := readZipPart(&zr.Reader, "meta.xml")
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil {
+		if err := readODTMetadata(meta, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to read meta.xml: %w", err)
+		}
+	}
+
+	doc := newProcessedDocument(filePath, fileType, info, text, metadata)
+	if doc.Attachments, err = zipAttachments(&zr.Reader, "Pictures/"); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// odtChange is a tracked change listed at the start of the text
+type odtChange struct {
+	kind    AnnotationKind
+	author  string
+	date    time.Time
+	deleted strings.Builder // Text removed by a deletion
+}
+
+// odtComment is an office:annotation being read
+type odtComment struct {
+	author   string
+	date     time.Time
+	position int
+	text     strings.Builder
+}
+
+// walkODTContent writes the text of content.xml to text. Tracked changes
+// are listed before the text and anchored in it by change marks.
+func walkODTContent(data []byte, text *textBuilder) error {
+	changes := make(map[string]*odtChange)
+	insertStarts := make(map[string]int)
+	var change *odtChange   // Change whose details are being read
+	var comment *odtComment // Comment being read
+	var cells []int         // Cells started in each open table row
+	var field string        // Dublin Core element being read
+	paragraphs, notes := 0, 0
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Space + " " + t.Name.Local {
+			case nsODFText + " changed-region":
+				change = &odtChange{}
+				changes[xmlAttr(t, "id")] = change
+			case nsODFText + " insertion":
+				if change != nil {
+					change.kind = AnnotationInsertion
+				}
+			case nsODFText + " deletion":
+				if change != nil {
+					change.kind = AnnotationDeletion
+				}
+			case nsODFOffice + " annotation":
+				comment = &odtComment{position: text.offset()}
+			case nsDC + " creator", nsDC + " date":
+				field = t.Name.Local
+			case nsODFText + " p", nsODFText + " h":
+				paragraphs++
+			case nsODFText + " note-body":
+				notes++
+			case nsODFText + " s":
+				count := atoi(xmlAttr(t, "c"))
+				if count < 1 {
+					count = 1
+				}
+				writeODT(text, change, comment, strings.Repeat(" ", count))
+			case nsODFText + " tab":
+				writeODT(text, change, comment, "\t")
+			case nsODFText + " line-break":
+				writeODT(text, change, comment, "\n")
+			case nsODFTable + " table-row":
+				cells = append(cells, 0)
+			case nsODFTable + " table-cell":
+				if n := len(cells); n > 0 && change == nil && comment == nil {
+					if cells[n-1] > 0 {
+						text.separate("\t")
+					}
+					cells[n-1]++
+				}
+			case nsODFText + " change-start":
+				insertStarts[xmlAttr(t, "change-id")] = text.offset()
+			case nsODFText + " change-end":
+				id := xmlAttr(t, "change-id")
+				if c, ok := changes[id]; ok {
+					if start, ok := insertStarts[id]; ok && c.kind == AnnotationInsertion {
+						text.annotate(c.kind, c.author, c.date, text.since(start), start)
+					}
+				}
+			case nsODFText + " change":
+				if c, ok := changes[xmlAttr(t, "change-id")]; ok && c.kind == AnnotationDeletion {
+					text.annotate(c.kind, c.author, c.date, c.deleted.String(), text.offset())
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Space + " " + t.Name.Local {
+			case nsODFText + " changed-region":
+				change = nil
+			case nsODFOffice + " annotation":
+				if comment != nil {
+					text.annotate(AnnotationComment, comment.author, comment.date, comment.text.String(), comment.position)
+					comment = nil
+				}
+			case nsDC + " creator", nsDC + " date":
+				field = ""
+			case nsODFText + " p", nsODFText + " h":
+				paragraphs--
+				switch {
+				case change != nil || comment != nil:
+					writeODT(text, change, comment, "\n")
+				case notes > 0, len(cells) > 0:
+					text.separate(" ")
+				default:
+					text.endParagraph()
+				}
+			case nsODFText + " note-body":
+				notes--
+			case nsODFTable + " table-row":
+				if n := len(cells); n > 0 {
+					cells = cells[:n-1]
+				}
+				if change == nil && comment == nil {
+					text.separate("\n")
+				}
+			}
+
+		case xml.CharData:
+			if field != "" {
+				value := strings.TrimSpace(string(t))
+				switch {
+				case comment != nil && field == "creator":
+					comment.author = value
+				case comment != nil:
+					comment.date = parseDocumentDate(value)
+				case change != nil && field == "creator":
+					change.author = value
+				case change != nil:
+					change.date = parseDocumentDate(value)
+				}
+				continue
+			}
+			if paragraphs > 0 {
+				writeODT(text, change, comment, strings.NewReplacer("\n", " ", "\r", "", "\t", " ").Replace(string(t)))
+			}
+		}
+	}
+}
+
+// writeODT writes text to the comment or deleted text being read, or
+// otherwise to the document content
+func writeODT(text *textBuilder, change *odtChange, comment *odtComment, s string) {
+	switch {
+	case comment != nil:
+		comment.text.WriteString(s)
+	case change != nil:
+		change.deleted.WriteString(s)
+	default:
+		text.WriteString(s)
+	}
+}
+
+// readODTMetadata reads the properties in meta.xml
+func readODTMetadata(data []byte, metadata *Metadata) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var name xml.Name
+	var userField string
+	var value strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name = t.Name
+			value.Reset()
+			switch t.Name.Space + " " + t.Name.Local {
+			case nsODFMeta + " user-defined":
+				userField = xmlAttr(t, "name")
+			case nsODFMeta + " document-statistic":
+				for _, a := range t.Attr {
+					switch a.Name.Local {
+					case "page-count":
+						metadata.CustomFields["Pages"] = a.Value
+					case "word-count":
+						metadata.CustomFields["Words"] = a.Value
+					}
+				}
+			}
+		case xml.CharData:
+			value.Write(t)
+		case xml.EndElement:
+			if t.Name != name {
+				continue
+			}
+			v := strings.TrimSpace(value.String())
+			switch t.Name.Space + " " + t.Name.Local {
+			case nsODFMeta + " initial-creator":
+				metadata.Author = v
+			case nsDC + " creator":
+				metadata.LastModifiedBy = v
+			case nsODFMeta + " creation-date":
+				metadata.CreationDate = parseDocumentDate(v)
+			case nsDC + " date":
+				metadata.ModifiedDate = parseDocumentDate(v)
+			case nsODFMeta + " editing-cycles":
+				metadata.Revision = atoi(v)
+			case nsDC + " subject":
+				metadata.Subject = v
+			case nsDC + " title":
+				metadata.CustomFields["Title"] = v
+			case nsDC + " description":
+				metadata.CustomFields["Comments"] = v
+			case nsODFMeta + " keyword":
+				metadata.Keywords = append(metadata.Keywords, splitKeywords(v)...)
+			case nsODFMeta + " generator":
+				metadata.CustomFields["Application"] = v
+			case nsODFMeta + " editing-duration":
+				metadata.CustomFields["EditingDuration"] = v
+			case nsODFMeta + " print-date":
+				metadata.CustomFields["LastPrinted"] = v
+			case nsODFMeta + " printed-by":
+				metadata.CustomFields["PrintedBy"] = v
+			case nsODFMeta + " user-defined":
+				if userField != "" && v != "" {
+					metadata.CustomFields[userField] = v
+				}
+				userField = ""
+			}
+			name = xml.Name{}
+		}
+	}
+	if metadata.Subject == "" {
+		metadata.Subject = metadata.CustomFields["Title"]
+	}
+	return nil
+}
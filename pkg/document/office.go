@@ -0,0 +1,228 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+)
+
+// maxPartSize limits how much of any one part of a document is read, so
+// that a crafted file cannot exhaust memory
+const maxPartSize = 64 << 20
+
+// identifyAs checks that the content of a file is of the expected type,
+// whatever the file is called
+func identifyAs(filePath, name string) (*filetype.Result, os.FileInfo, error) {
+	fileType, err := filetype.IdentifyFile(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to identify file type: %w", err)
+	}
+	if fileType.Name != name {
+		return nil, nil, fmt.Errorf("not a %s file: %s (content is %s)", name, filePath, fileType.Description)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	return fileType, info, nil
+}
+
+// newProcessedDocument creates the document for a processed file
+func newProcessedDocument(filePath string, fileType *filetype.Result, info os.FileInfo, text *textBuilder, metadata Metadata) *Document {
+	if metadata.CustomFields == nil {
+		metadata.CustomFields = make(map[string]string)
+	}
+	doc := &Document{
+		Title:       filepath.Base(filePath),
+		Type:        TypeUnknown,
+		FilePath:    filePath,
+		ContentType: fileType.MIME,
+		FileSize:    info.Size(),
+		Content:     text.String(),
+		Metadata:    metadata,
+		Annotations: text.annotations,
+		CreatedAt:   time.Now(),
+		ModifiedAt:  time.Now(),
+	}
+	return doc
+}
+
+// readZipPart reads a part of a ZIP-based document. A missing part returns
+// nil without an error.
+func readZipPart(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name == name {
+			return readZipFile(f)
+		}
+	}
+	return nil, nil
+}
+
+// readZipFile reads a file from a ZIP archive up to maxPartSize
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	if len(data) > maxPartSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", f.Name, maxPartSize>>20)
+	}
+	return data, nil
+}
+
+// zipAttachments reads the files in a directory of a ZIP-based document,
+// such as word/media or Pictures
+func zipAttachments(zr *zip.Reader, dir string) ([]Attachment, error) {
+	var attachments []Attachment
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, dir) || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, newAttachment(path.Base(f.Name), data))
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Name < attachments[j].Name })
+	return attachments, nil
+}
+
+// newAttachment records an embedded file, identifying its type from its content
+func newAttachment(name string, data []byte) Attachment {
+	contentType := "application/octet-stream"
+	if ft, err := filetype.Identify(bytes.NewReader(data), int64(len(data))); err == nil {
+		contentType = ft.MIME
+	}
+	sum := sha256.Sum256(data)
+	return Attachment{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Data:        data,
+	}
+}
+
+// textBuilder accumulates document text and the annotations anchored in it.
+// Paragraph and table cell separators are held back until more text
+// follows, so that empty cells and trailing breaks leave no stray spaces.
+type textBuilder struct {
+	b           strings.Builder
+	pending     string
+	annotations []Annotation
+	counts      map[AnnotationKind]int
+}
+
+// WriteString appends text to the document content
+func (t *textBuilder) WriteString(s string) {
+	if s == "" {
+		return
+	}
+	if t.pending != "" {
+		t.b.WriteString(t.pending)
+		t.pending = ""
+	}
+	t.b.WriteString(s)
+}
+
+// offset returns the position at which the next text will start
+func (t *textBuilder) offset() int {
+	return t.b.Len() + len(t.pending)
+}
+
+// since returns the content written from a position on
+func (t *textBuilder) since(position int) string {
+	s := t.b.String()
+	if position >= len(s) {
+		return ""
+	}
+	return s[position:]
+}
+
+// endParagraph ends the current paragraph
+func (t *textBuilder) endParagraph() {
+	if t.b.Len() > 0 {
+		t.pending += "\n"
+	}
+}
+
+// separate places a separator, such as a table cell's tab, before the next
+// text in place of any pending one
+func (t *textBuilder) separate(s string) {
+	if t.b.Len() > 0 {
+		t.pending = s
+	}
+}
+
+// annotate records an annotation at a position in the content
+func (t *textBuilder) annotate(kind AnnotationKind, author string, date time.Time, text string, position int) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if t.counts == nil {
+		t.counts = make(map[AnnotationKind]int)
+	}
+	t.counts[kind]++
+	t.annotations = append(t.annotations, Annotation{
+		ID:        fmt.Sprintf("%s-%d", strings.ToLower(string(kind)), t.counts[kind]),
+		Kind:      kind,
+		Author:    author,
+		Text:      text,
+		CreatedAt: date,
+		Position:  position,
+	})
+}
+
+// String returns the content
+func (t *textBuilder) String() string {
+	return strings.TrimRight(t.b.String(), " \t\n")
+}
+
+// parseDocumentDate parses the W3C dates used in OOXML and OpenDocument
+// properties, with or without a zone
+func parseDocumentDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// splitKeywords splits a keyword property on commas or semicolons
+func splitKeywords(value string) []string {
+	var keywords []string
+	for _, k := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+// atoi parses a number property, returning 0 when it is not a number
+func atoi(value string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n
+}
@@ -0,0 +1,240 @@
+package document
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zipFile is a part of a ZIP-based test document
+type zipFile struct {
+	name, content string
+}
+
+// writeZip writes a ZIP-based document with its parts in order
+func writeZip(t *testing.T, name string, files ...zipFile) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(file.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pngData is the signature and header of a PNG image
+var pngData = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00"
+
+const docxBody = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+<w:p><w:pPr><w:tabs><w:tab w:val="left"/></w:tabs></w:pPr><w:r><w:t>Informe de</w:t></w:r><w:r><w:tab/><w:t>incidente</w:t></w:r></w:p>
+<w:p><w:commentRangeStart w:id="0"/><w:r><w:t xml:space="preserve">El sospechoso </w:t></w:r><w:ins w:id="1" w:author="Ana" w:date="2024-03-01T10:00:00Z"><w:r><w:t>no </w:t></w:r></w:ins><w:r><w:t>huyó</w:t></w:r><w:del w:id="2" w:author="Luis" w:date="2024-03-02T11:00:00Z"><w:r><w:delText> a pie</w:delText></w:r></w:del><w:r><w:t>.</w:t></w:r><w:commentRangeEnd w:id="0"/></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Nombre</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Edad</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Juan</w:t></w:r></w:p></w:tc><w:tc><w:p/></w:tc></w:tr></w:tbl>
+<w:sectPr><w:pgSz w:w="12240"/></w:sectPr>
+</w:body>
+</w:document>`
+
+const docxComments = `<w:comments xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:comment w:id="0" w:author="Marta" w:date="2024-03-03T09:00:00Z"><w:p><w:r><w:t>Confirmar con el testigo</w:t></w:r></w:p></w:comment>
+</w:comments>`
+
+const docxCore = `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+<dc:title>Informe 42</dc:title><dc:creator>Ana García</dc:creator><cp:lastModifiedBy>Luis</cp:lastModifiedBy>
+<cp:revision>7</cp:revision><cp:keywords>robo; vehículo</cp:keywords>
+<dcterms:created>2024-03-01T09:00:00Z</dcterms:created><dcterms:modified>2024-03-02T12:00:00Z</dcterms:modified>
+</cp:coreProperties>`
+
+const odtContent = `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<office:body><office:text>
+<text:tracked-changes>
+<text:changed-region text:id="c1"><text:insertion><office:change-info><dc:creator>Ana</dc:creator><dc:date>2024-03-01T10:00:00</dc:date></office:change-info></text:insertion></text:changed-region>
+<text:changed-region text:id="c2"><text:deletion><office:change-info><dc:creator>Luis</dc:creator><dc:date>2024-03-02T11:00:00</dc:date></office:change-info><text:p>a pie</text:p></text:deletion></text:changed-region>
+</text:tracked-changes>
+<text:h>Informe<text:s text:c="2"/>de incidente</text:h>
+<text:p><office:annotation><dc:creator>Marta</dc:creator><dc:date>2024-03-03T09:00:00</dc:date><text:p>Confirmar con el testigo</text:p></office:annotation>El sospechoso <text:change-start text:change-id="c1"/>no <text:change-end text:change-id="c1"/>huyó<text:change text:change-id="c2"/>.</text:p>
+<table:table><table:table-row><table:table-cell><text:p>Nombre</text:p></table:table-cell><table:table-cell><text:p>Edad</text:p></table:table-cell></table:table-row></table:table>
+</office:text></office:body></office:document-content>`
+
+const odtMeta = `<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<office:meta><meta:initial-creator>Ana García</meta:initial-creator><dc:creator>Luis</dc:creator><dc:title>Informe 42</dc:title>
+<meta:editing-cycles>7</meta:editing-cycles><meta:keyword>robo</meta:keyword><meta:keyword>vehículo</meta:keyword>
+<meta:creation-date>2024-03-01T09:00:00</meta:creation-date><meta:user-defined meta:name="Caso">2024-001</meta:user-defined>
+<meta:document-statistic meta:page-count="1" meta:word-count="12"/></office:meta></office:document-meta>`
+
+const rtfDocument = `{\rtf1\ansi\ansicpg1252\deff0{\fonttbl{\f0 Arial;}}{\colortbl;\red0\green0\blue0;}
+{\*\revtbl{Unknown;}{Ana;}{Luis;}}
+{\info{\title Informe 42}{\author Ana Garc\'eda}{\operator Luis}{\keywords robo, veh\'edculo}{\version7}{\creatim\yr2024\mo3\dy1\hr9\min0}}
+Informe de\tab incidente\par
+El sospechoso {\revised\revauth1\revdttm0 no }huy\'f3{\deleted\revauthdel2 a pie}.{\*\atnid M}{\*\atnauthor Marta}\chatn{\*\annotation Confirmar con el testigo}\par
+Nombre\cell Edad\cell\row
+{\pict\pngblip 89504e470d0a1a0a}
+\'80 y \u8364?5\par}`
+
+func TestOfficeProcessors(t *testing.T) {
+	tests := []struct {
+		name            string
+		processor       DocumentProcessor
+		path            func(t *testing.T) string
+		wantContent     []string // Lines of the content
+		wantAnnotations []string // Kind, author and text of each annotation
+		wantAuthor      string
+		wantModifiedBy  string
+		wantRevision    int
+		wantKeywords    string
+		wantAttachments int
+		wantField       [2]string // A custom field and its value
+	}{
+		{
+			name:      "docx",
+			processor: &DOCXProcessor{},
+			path: func(t *testing.T) string {
+				return writeZip(t, "report.bin",
+					zipFile{"[Content_Types].xml", `<Types/>`},
+					zipFile{"word/document.xml", docxBody},
+					zipFile{"word/comments.xml", docxComments},
+					zipFile{"word/media/image1.png", pngData},
+					zipFile{"docProps/core.xml", docxCore},
+					zipFile{"docProps/app.xml", `<Properties><Application>Microsoft Office Word</Application><Company>Policía</Company></Properties>`})
+			},
+			wantContent: []string{"Informe de\tincidente", "El sospechoso no huyó.", "Nombre\tEdad", "Juan"},
+			wantAnnotations: []string{
+				"COMMENT Marta Confirmar con el testigo",
+				"INSERTION Ana no",
+				"DELETION Luis a pie",
+			},
+			wantAuthor:      "Ana García",
+			wantModifiedBy:  "Luis",
+			wantRevision:    7,
+			wantKeywords:    "robo,vehículo",
+			wantAttachments: 1,
+			wantField:       [2]string{"Company", "Policía"},
+		},
+		{
+			name:      "odt",
+			processor: &ODTProcessor{},
+			path: func(t *testing.T) string {
+				return writeZip(t, "report.odt",
+					zipFile{"mimetype", "application/vnd.oasis.opendocument.text"},
+					zipFile{"content.xml", odtContent},
+					zipFile{"meta.xml", odtMeta},
+					zipFile{"Pictures/photo.png", pngData})
+			},
+			wantContent: []string{"Informe  de incidente", "El sospechoso no huyó.", "Nombre\tEdad"},
+			wantAnnotations: []string{
+				"COMMENT Marta Confirmar con el testigo",
+				"INSERTION Ana no",
+				"DELETION Luis a pie",
+			},
+			wantAuthor:      "Ana García",
+			wantModifiedBy:  "Luis",
+			wantRevision:    7,
+			wantKeywords:    "robo,vehículo",
+			wantAttachments: 1,
+			wantField:       [2]string{"Caso", "2024-001"},
+		},
+		{
+			name:      "rtf",
+			processor: &RTFProcessor{},
+			path: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "report.rtf")
+				if err := os.WriteFile(path, []byte(rtfDocument), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			wantContent: []string{"Informe de\tincidente", "El sospechoso no huyó.", "Nombre\tEdad", "€ y €5"},
+			wantAnnotations: []string{
+				"INSERTION Ana no",
+				"DELETION Luis a pie",
+				"COMMENT Marta Confirmar con el testigo",
+			},
+			wantAuthor:      "Ana García",
+			wantModifiedBy:  "Luis",
+			wantRevision:    7,
+			wantKeywords:    "robo,vehículo",
+			wantAttachments: 1,
+			wantField:       [2]string{"Title", "Informe 42"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := tt.processor.Process(tt.path(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Split(doc.Content, "\n"); strings.Join(got, "|") != strings.Join(tt.wantContent, "|") {
+				t.Errorf("content = %q", doc.Content)
+			}
+			var annotations []string
+			for _, a := range doc.Annotations {
+				annotations = append(annotations, string(a.Kind)+" "+a.Author+" "+a.Text)
+			}
+			if strings.Join(annotations, "|") != strings.Join(tt.wantAnnotations, "|") {
+				t.Errorf("annotations = %q", annotations)
+			}
+			m := doc.Metadata
+			if m.Author != tt.wantAuthor || m.LastModifiedBy != tt.wantModifiedBy || m.Revision != tt.wantRevision {
+				t.Errorf("metadata = %+v", m)
+			}
+			if strings.Join(m.Keywords, ",") != tt.wantKeywords {
+				t.Errorf("keywords = %q", m.Keywords)
+			}
+			if m.CreationDate.IsZero() {
+				t.Error("creation date not read")
+			}
+			if m.CustomFields[tt.wantField[0]] != tt.wantField[1] {
+				t.Errorf("custom fields = %v", m.CustomFields)
+			}
+			if len(doc.Attachments) != tt.wantAttachments {
+				t.Fatalf("attachments = %+v", doc.Attachments)
+			}
+			if a := doc.Attachments[0]; a.ContentType != "image/png" || a.SHA256 == "" {
+				t.Errorf("attachment = %s %s", a.Name, a.ContentType)
+			}
+		})
+	}
+}
+
+func TestOfficeProcessorsRejectOtherContent(t *testing.T) {
+	text := filepath.Join(t.TempDir(), "report.docx")
+	if err := os.WriteFile(text, []byte("just text, named like a document"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	odt := writeZip(t, "report.docx",
+		zipFile{"mimetype", "application/vnd.oasis.opendocument.text"},
+		zipFile{"content.xml", odtContent})
+
+	tests := []struct {
+		name      string
+		processor DocumentProcessor
+		path      string
+	}{
+		{"docx given text", &DOCXProcessor{}, text},
+		{"docx given odt", &DOCXProcessor{}, odt},
+		{"odt given text", &ODTProcessor{}, text},
+		{"rtf given text", &RTFProcessor{}, text},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.processor.Process(tt.path); err == nil || !strings.Contains(err.Error(), "not a") {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/pii"
+)
+
+// ProposalStatus is the review state of a proposed redaction
+type ProposalStatus string
+
+const (
+	ProposalPending  ProposalStatus = "PENDING"
+	ProposalAccepted ProposalStatus = "ACCEPTED"
+	ProposalRejected ProposalStatus = "REJECTED"
+)
+
+// RedactionProposal is personal information found in text, proposed for
+// redaction until a reviewer accepts or rejects it
+type RedactionProposal struct {
+	ID         string
+	Kind       pii.Kind
+	StartPos   int
+	EndPos     int
+	Reason     string
+	Confidence float64 // 0 to 1
+	Status     ProposalStatus
+	ReviewedBy string
+	ReviewedAt time.Time
+}
+
+// NewProposals turns PII findings into pending proposals, leaving out text
+// already redacted or already proposed
+func NewProposals(findings []pii.Finding, redactions []Redaction, proposals []RedactionProposal) []RedactionProposal {
+	var added []RedactionProposal
+	next := len(proposals) + 1
+	for _, f := range findings {
+		if covered(redactions, f.Start, f.End) {
+			continue
+		}
+		proposed := false
+		for _, p := range proposals {
+			if p.StartPos == f.Start && p.EndPos == f.End {
+				proposed = true
+				break
+			}
+		}
+		if proposed {
+			continue
+		}
+		added = append(added, RedactionProposal{
+			ID:         fmt.Sprintf("P%d", next),
+			Kind:       f.Kind,
+			StartPos:   f.Start,
+			EndPos:     f.End,
+			Reason:     f.Reason,
+			Confidence: f.Confidence,
+			Status:     ProposalPending,
+		})
+		next++
+	}
+	return added
+}
+
+// ReviewProposal records a reviewer's decision on a pending proposal and
+// returns it
+func ReviewProposal(proposals []RedactionProposal, id string, accept bool, reviewer string) (*RedactionProposal, error) {
+	if strings.TrimSpace(reviewer) == "" {
+		return nil, fmt.Errorf("the reviewer is required")
+	}
+	for i := range proposals {
+		p := &proposals[i]
+		if !strings.EqualFold(p.ID, id) {
+			continue
+		}
+		if p.Status != ProposalPending {
+			return nil, fmt.Errorf("proposal %s was already %s by %s", p.ID, strings.ToLower(string(p.Status)), p.ReviewedBy)
+		}
+		p.Status = ProposalRejected
+		if accept {
+			p.Status = ProposalAccepted
+		}
+		p.ReviewedBy = reviewer
+		p.ReviewedAt = time.Now()
+		return p, nil
+	}
+	return nil, fmt.Errorf("proposal not found: %s", id)
+}
+
+// ProposeRedactions scans a document for personal information and adds a
+// pending proposal for each new finding
+func (s *RedactionService) ProposeRedactions(documentID string, detector *pii.Detector) ([]RedactionProposal, error) {
+	doc, err := s.repo.Find(documentID)
+	if err != nil {
+		return nil, err
+	}
+	added := NewProposals(detector.Detect(doc.Content), doc.Redactions, doc.Proposals)
+	if len(added) == 0 {
+		return nil, nil
+	}
+	doc.Proposals = append(doc.Proposals, added...)
+	doc.ModifiedAt = time.Now()
+	if err := s.repo.Update(doc); err != nil {
+		return nil, fmt.Errorf("failed to save proposals: %w", err)
+	}
+	return added, nil
+}
+
+// ReviewProposal accepts or rejects a proposed redaction. Accepted
+// proposals become permanent redactions; both decisions are logged.
+func (s *RedactionService) ReviewProposal(documentID, proposalID string, accept bool, reviewer string) (*RedactionProposal, error) {
+	doc, err := s.repo.Find(documentID)
+	if err != nil {
+		return nil, err
+	}
+	// Check the proposal before redacting, then record the decision
+	pending := make([]RedactionProposal, len(doc.Proposals))
+	copy(pending, doc.Proposals)
+	p, err := ReviewProposal(pending, proposalID, accept, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	if accept {
+		if _, err := s.Redact(documentID, Redaction{
+			StartPos:   p.StartPos,
+			EndPos:     p.EndPos,
+			Reason:     p.Reason,
+			RedactedBy: reviewer,
+		}); err != nil {
+			return nil, err
+		}
+		if doc, err = s.repo.Find(documentID); err != nil {
+			return nil, err
+		}
+	}
+	if !accept {
+		if err := s.log(&RedactionLogEntry{
+			DocumentID: documentID,
+			Action:     RedactionRejected,
+			StartPos:   p.StartPos,
+			EndPos:     p.EndPos,
+			Page:       doc.PageAt(p.StartPos),
+			Reason:     p.Reason,
+			User:       reviewer,
+			Detail:     fmt.Sprintf("proposal %s (%s, %.0f%% confidence) rejected", p.ID, p.Kind, p.Confidence*100),
+			Timestamp:  p.ReviewedAt,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for i := range doc.Proposals {
+		if doc.Proposals[i].ID == p.ID {
+			doc.Proposals[i] = *p
+		}
+	}
+	doc.ModifiedAt = p.ReviewedAt
+	if err := s.repo.Update(doc); err != nil {
+		return nil, fmt.Errorf("failed to save review: %w", err)
+	}
+	return p, nil
+}
+
+// covered reports whether a span lies wholly within the redactions
+func covered(redactions []Redaction, start, end int) bool {
+	for i := start; i < end; i++ {
+		if !redacted(redactions, i) {
+			return false
+		}
+	}
+	return true
+}
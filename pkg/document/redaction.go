@@ -0,0 +1,482 @@
+package document
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/pdf"
+)
+
+// redactionMark replaces each redacted character in rendered text
+const redactionMark = '█'
+
+// RedactionAction identifies an entry in the redaction log
+type RedactionAction string
+
+const (
+	RedactionApplied  RedactionAction = "REDACTED" // A redaction was added
+	RedactionLifted   RedactionAction = "LIFTED"   // Temporary redactions were lifted for an authorized viewer
+	RedactionExported RedactionAction = "EXPORTED" // A redacted copy was written
+	RedactionRejected RedactionAction = "REJECTED" // A proposed redaction was rejected by a reviewer
+)
+
+// RedactionLogEntry records who redacted what and why, and every copy
+// rendered from the redactions
+type RedactionLogEntry struct {
+	ID            string
+	DocumentID    string
+	Action        RedactionAction
+	StartPos      int
+	EndPos        int
+	Page          int // Page of the redacted text, 0 for documents without pages
+	Reason        string
+	User          string
+	Authorization string // Court order under which temporary redactions were lifted
+	ReviewedBy    string // Person who checked the court order
+	Detail        string
+	Timestamp     time.Time
+}
+
+// Viewer is the person a redacted copy is prepared for
+type Viewer struct {
+	Name       string
+	CourtOrder string // Court authorization to see temporary redactions lifted
+	ReviewedBy string // Person who checked the court order, required to lift redactions
+}
+
+// RedactedExport describes the files written for a redacted copy
+type RedactedExport struct {
+	DocumentID string
+	TextPath   string
+	TextSHA256 string
+	PDFPath    string // Empty for documents that are not PDF files
+	PDFSHA256  string
+	Applied    int // Redactions applied
+	Lifted     int // Temporary redactions lifted for the viewer
+}
+
+// RedactionRepository stores documents and their redaction log
+type RedactionRepository interface {
+	Find(id string) (*Document, error)
+	Update(doc *Document) error
+	SaveRedactionLog(entry *RedactionLogEntry) error
+	FindRedactionLog(documentID string) ([]*RedactionLogEntry, error)
+}
+
+// RedactionService records redactions and renders redacted copies of
+// documents, leaving the original untouched
+type RedactionService struct {
+	repo RedactionRepository
+}
+
+// NewRedactionService creates a new redaction service
+func NewRedactionService(repo RedactionRepository) *RedactionService {
+	return &RedactionService{repo: repo}
+}
+
+// ValidateRedaction checks that a redaction covers text of the document
+// and says who made it and why
+func ValidateRedaction(doc *Document, r Redaction) error {
+	if r.StartPos < 0 || r.EndPos > len(doc.Content) || r.StartPos >= r.EndPos {
+		return fmt.Errorf("redaction %d-%d is outside the document text (%d bytes)", r.StartPos, r.EndPos, len(doc.Content))
+	}
+	if !utf8.RuneStart(doc.Content[r.StartPos]) || (r.EndPos < len(doc.Content) && !utf8.RuneStart(doc.Content[r.EndPos])) {
+		return fmt.Errorf("redaction %d-%d splits a character", r.StartPos, r.EndPos)
+	}
+	if strings.TrimSpace(r.Reason) == "" {
+		return fmt.Errorf("a reason for the redaction is required")
+	}
+	if strings.TrimSpace(r.RedactedBy) == "" {
+		return fmt.Errorf("the person redacting is required")
+	}
+	for _, existing := range doc.Redactions {
+		if existing.StartPos == r.StartPos && existing.EndPos == r.EndPos && existing.IsTemporary == r.IsTemporary {
+			return fmt.Errorf("text %d-%d is already redacted", r.StartPos, r.EndPos)
+		}
+	}
+	return nil
+}
+
+// Redact adds a redaction to a document and logs it
+func (s *RedactionService) Redact(documentID string, r Redaction) (*Redaction, error) {
+	doc, err := s.repo.Find(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateRedaction(doc, r); err != nil {
+		return nil, err
+	}
+	r.RedactedAt = time.Now()
+
+	// Log first so that no redaction is ever saved without its entry
+	kind := "permanent"
+	if r.IsTemporary {
+		kind = "temporary"
+	}
+	if err := s.log(&RedactionLogEntry{
+		DocumentID: documentID,
+		Action:     RedactionApplied,
+		StartPos:   r.StartPos,
+		EndPos:     r.EndPos,
+		Page:       doc.PageAt(r.StartPos),
+		Reason:     r.Reason,
+		User:       r.RedactedBy,
+		Detail:     fmt.Sprintf("%s redaction of %d characters", kind, utf8.RuneCountInString(doc.Content[r.StartPos:r.EndPos])),
+		Timestamp:  r.RedactedAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	count, modified := len(doc.Redactions), doc.ModifiedAt
+	doc.Redactions = append(doc.Redactions, r)
+	doc.ModifiedAt = r.RedactedAt
+	if err := s.repo.Update(doc); err != nil {
+		doc.Redactions, doc.ModifiedAt = doc.Redactions[:count], modified
+		return nil, fmt.Errorf("failed to save redaction: %w", err)
+	}
+	return &r, nil
+}
+
+// RedactText redacts every occurrence of a phrase in a document, ignoring
+// case
+func (s *RedactionService) RedactText(documentID, phrase string, r Redaction) ([]Redaction, error) {
+	doc, err := s.repo.Find(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(phrase) == "" {
+		return nil, fmt.Errorf("the text to redact is required")
+	}
+	var added []Redaction
+	for _, at := range FindText(doc.Content, phrase) {
+		r.StartPos, r.EndPos = at[0], at[1]
+		redaction, err := s.Redact(documentID, r)
+		if err != nil {
+			return added, err
+		}
+		added = append(added, *redaction)
+	}
+	if len(added) == 0 {
+		return nil, fmt.Errorf("text not found in document: %q", phrase)
+	}
+	return added, nil
+}
+
+// Render returns the text of a document as the viewer may see it.
+// Temporary redactions are lifted for viewers with a court order checked by
+// a reviewer, which is logged.
+func (s *RedactionService) Render(documentID string, viewer Viewer) (string, error) {
+	doc, err := s.repo.Find(documentID)
+	if err != nil {
+		return "", err
+	}
+	applied, lifted, err := forViewer(doc, viewer)
+	if err != nil {
+		return "", err
+	}
+	if err := s.logLifted(doc, viewer, lifted, "viewed"); err != nil {
+		return "", err
+	}
+	return ApplyRedactions(doc.Content, applied), nil
+}
+
+// Export writes a redacted copy of a document's text, and of the file
+// itself for PDF documents, to a directory. The copy is prepared for the
+// viewer, lifting temporary redactions for a court order checked by a
+// reviewer.
+func (s *RedactionService) Export(documentID string, viewer Viewer, dir string) (*RedactedExport, error) {
+	doc, err := s.repo.Find(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(viewer.Name) == "" {
+		return nil, fmt.Errorf("the person the copy is for is required")
+	}
+	applied, lifted, err := forViewer(doc, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	name := doc.ID + "-redacted"
+	if len(lifted) > 0 {
+		name = doc.ID + "-court-view"
+	}
+	export := &RedactedExport{DocumentID: doc.ID, Applied: len(applied), Lifted: len(lifted)}
+	export.TextPath = filepath.Join(dir, name+".txt")
+	text := []byte(ApplyRedactions(doc.Content, applied))
+	if export.TextSHA256, err = writeExport(export.TextPath, text, doc.FilePath); err != nil {
+		return nil, err
+	}
+
+	if doc.ContentType == "application/pdf" {
+		data, err := redactPDF(doc, applied)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact PDF: %w", err)
+		}
+		export.PDFPath = filepath.Join(dir, name+".pdf")
+		if export.PDFSHA256, err = writeExport(export.PDFPath, data, doc.FilePath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.logLifted(doc, viewer, lifted, "exported"); err != nil {
+		return nil, err
+	}
+	detail := "text " + export.TextPath + " (SHA-256 " + export.TextSHA256 + ")"
+	if export.PDFPath != "" {
+		detail += ", PDF " + export.PDFPath + " (SHA-256 " + export.PDFSHA256 + ")"
+	}
+	if err := s.log(&RedactionLogEntry{
+		DocumentID:    doc.ID,
+		Action:        RedactionExported,
+		User:          viewer.Name,
+		Authorization: viewer.CourtOrder,
+		ReviewedBy:    viewer.ReviewedBy,
+		Detail:        fmt.Sprintf("%d redactions applied, %d lifted: %s", len(applied), len(lifted), detail),
+		Timestamp:     time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Log returns the redaction log of a document, oldest first
+func (s *RedactionService) Log(documentID string) ([]*RedactionLogEntry, error) {
+	entries, err := s.repo.FindRedactionLog(documentID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+	return entries, nil
+}
+
+// forViewer splits the redactions of a document into those applied for a
+// viewer and the temporary ones lifted by their court order. A court order
+// must name the viewer and have been checked by someone else.
+func forViewer(doc *Document, viewer Viewer) (applied, lifted []Redaction, err error) {
+	authorized := strings.TrimSpace(viewer.CourtOrder) != ""
+	if authorized {
+		reviewer := strings.TrimSpace(viewer.ReviewedBy)
+		switch {
+		case strings.TrimSpace(viewer.Name) == "":
+			return nil, nil, fmt.Errorf("the person the court order is for is required")
+		case reviewer == "":
+			return nil, nil, fmt.Errorf("court order %s must be checked by a reviewer before redactions are lifted", viewer.CourtOrder)
+		case strings.EqualFold(reviewer, strings.TrimSpace(viewer.Name)):
+			return nil, nil, fmt.Errorf("the reviewer of court order %s cannot be its viewer", viewer.CourtOrder)
+		}
+	}
+	for _, r := range doc.Redactions {
+		if r.IsTemporary && authorized {
+			lifted = append(lifted, r)
+		} else {
+			applied = append(applied, r)
+		}
+	}
+	return applied, lifted, nil
+}
+
+// logLifted logs each temporary redaction lifted for a viewer
+func (s *RedactionService) logLifted(doc *Document, viewer Viewer, lifted []Redaction, how string) error {
+	for _, r := range lifted {
+		if err := s.log(&RedactionLogEntry{
+			DocumentID:    doc.ID,
+			Action:        RedactionLifted,
+			StartPos:      r.StartPos,
+			EndPos:        r.EndPos,
+			Page:          doc.PageAt(r.StartPos),
+			Reason:        r.Reason,
+			User:          viewer.Name,
+			Authorization: viewer.CourtOrder,
+			ReviewedBy:    viewer.ReviewedBy,
+			Detail:        fmt.Sprintf("temporary redaction lifted under court order %s, checked by %s, text %s", viewer.CourtOrder, viewer.ReviewedBy, how),
+			Timestamp:     time.Now(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// log saves a log entry
+func (s *RedactionService) log(entry *RedactionLogEntry) error {
+	entry.ID = newID("RDL")
+	if err := s.repo.SaveRedactionLog(entry); err != nil {
+		return fmt.Errorf("failed to log redaction: %w", err)
+	}
+	return nil
+}
+
+// ApplyRedactions returns text with each redacted character replaced by a
+// block. Line and page breaks are kept so that the layout is unchanged.
+func ApplyRedactions(content string, redactions []Redaction) string {
+	var b strings.Builder
+	for i, r := range content {
+		if r != '\n' && r != '\f' && redacted(redactions, i) {
+			b.WriteRune(redactionMark)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// redacted reports whether a position lies in any of the redactions
+func redacted(redactions []Redaction, pos int) bool {
+	for _, r := range redactions {
+		if pos >= r.StartPos && pos < r.EndPos {
+			return true
+		}
+	}
+	return false
+}
+
+// writeExport writes a redacted copy, refusing to overwrite the original,
+// and returns its SHA-256 hash
+func writeExport(path string, data []byte, original string) (string, error) {
+	if original != "" {
+		if a, err := filepath.Abs(path); err == nil {
+			if b, err := filepath.Abs(original); err == nil && a == b {
+				return "", fmt.Errorf("refusing to overwrite the original document %s", original)
+			}
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write redacted copy: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// redactPDF removes the redacted text from a copy of a PDF document and
+// covers it with black boxes. Scanned pages with redactions are replaced by
+// their redacted OCR text, since the text cannot be removed from the image.
+func redactPDF(doc *Document, redactions []Redaction) ([]byte, error) {
+	f, err := pdf.Open(doc.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	texts, err := f.PageText()
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Pages) != len(texts) {
+		return nil, fmt.Errorf("document has %d pages of text but the PDF has %d pages", len(doc.Pages), len(texts))
+	}
+
+	opts := pdf.RedactOptions{Ranges: make(map[int][]pdf.TextRange), Replace: make(map[int]string)}
+	for i, page := range doc.Pages {
+		var ranges []pdf.TextRange
+		for _, r := range redactions {
+			start := max(r.StartPos, page.Offset) - page.Offset
+			end := min(r.EndPos, page.Offset+len(page.Text)) - page.Offset
+			if start < end {
+				ranges = append(ranges, pdf.TextRange{Start: start, End: end})
+			}
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+		switch {
+		case page.MachineRead:
+			opts.Replace[page.Number] = page.Text
+		case page.Text != texts[i]:
+			// Extracted by pdftotext; find the same text in the text layer
+			if ranges, err = locateRanges(page.Text, texts[i], ranges); err != nil {
+				return nil, fmt.Errorf("page %d: %w", page.Number, err)
+			}
+		}
+		opts.Ranges[page.Number] = ranges
+	}
+
+	var b bytes.Buffer
+	if err := f.Redact(&b, opts); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// locateRanges finds ranges of one extraction of a page's text in another,
+// matching the text with runs of whitespace treated alike
+func locateRanges(from, to string, ranges []pdf.TextRange) ([]pdf.TextRange, error) {
+	fromText, fromIndex := collapseSpace(from)
+	toText, toIndex := collapseSpace(to)
+	var located []pdf.TextRange
+	for _, r := range ranges {
+		start := sort.SearchInts(fromIndex, r.Start)
+		end := sort.SearchInts(fromIndex, r.End)
+		target := strings.TrimSpace(fromText[start:end])
+		if target == "" {
+			continue
+		}
+		// Take the same occurrence of the text as in the original
+		occurrence := strings.Count(fromText[:start], target)
+		pos := -1
+		for i, offset := 0, 0; i <= occurrence; i++ {
+			at := strings.Index(toText[offset:], target)
+			if at < 0 {
+				return nil, fmt.Errorf("redacted text not found in the PDF text layer")
+			}
+			pos, offset = offset+at, offset+at+len(target)
+		}
+		located = append(located, pdf.TextRange{Start: toIndex[pos], End: toIndex[pos+len(target)-1] + 1})
+	}
+	return located, nil
+}
+
+// collapseSpace replaces runs of whitespace with a single space, returning
+// the position in s of each byte of the result
+func collapseSpace(s string) (string, []int) {
+	var b strings.Builder
+	var index []int
+	space := false
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+				index = append(index, i)
+			}
+			space = true
+		} else {
+			space = false
+			b.WriteString(s[i : i+size])
+			for j := 0; j < size; j++ {
+				index = append(index, i+j)
+			}
+		}
+		i += size
+	}
+	return b.String(), index
+}
+
+// FindText returns the byte ranges of each occurrence of a phrase in text,
+// ignoring case
+func FindText(text, phrase string) [][2]int {
+	var found [][2]int
+	n := utf8.RuneCountInString(phrase)
+	for i := range text {
+		end, count := i, 0
+		for end < len(text) && count < n {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+			count++
+		}
+		if count == n && strings.EqualFold(text[i:end], phrase) {
+			found = append(found, [2]int{i, end})
+		}
+	}
+	return found
+}
@@ -0,0 +1,162 @@
+package document
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// memRedactionRepo is an in-memory RedactionRepository that shares the
+// stored documents, as the CLI repository does
+type memRedactionRepo struct {
+	docs       map[string]*Document
+	log        []*RedactionLogEntry
+	failUpdate bool
+	failLog    bool
+}
+
+func newMemRedactionRepo(docs ...*Document) *memRedactionRepo {
+	r := &memRedactionRepo{docs: make(map[string]*Document)}
+	for _, d := range docs {
+		r.docs[d.ID] = d
+	}
+	return r
+}
+
+func (r *memRedactionRepo) Find(id string) (*Document, error) {
+	if d, ok := r.docs[id]; ok {
+		return d, nil
+	}
+	return nil, errors.New("document not found")
+}
+
+func (r *memRedactionRepo) Update(doc *Document) error {
+	if r.failUpdate {
+		return errors.New("disk full")
+	}
+	r.docs[doc.ID] = doc
+	return nil
+}
+
+func (r *memRedactionRepo) SaveRedactionLog(entry *RedactionLogEntry) error {
+	if r.failLog {
+		return errors.New("log unavailable")
+	}
+	r.log = append(r.log, entry)
+	return nil
+}
+
+func (r *memRedactionRepo) FindRedactionLog(documentID string) ([]*RedactionLogEntry, error) {
+	var entries []*RedactionLogEntry
+	for _, e := range r.log {
+		if e.DocumentID == documentID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// statementDoc is a document naming a witness twice
+func statementDoc() *Document {
+	return &Document{ID: "DOC-1", Content: "Witness Jane Doe saw the car. Jane Doe called 911."}
+}
+
+func TestRedactLogsBeforeSaving(t *testing.T) {
+	tests := []struct {
+		name           string
+		failUpdate     bool
+		failLog        bool
+		wantRedactions int
+		wantLog        int
+	}{
+		{"saved", false, false, 1, 1},
+		{"log fails", false, true, 0, 0},
+		{"update fails", true, false, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := statementDoc()
+			repo := newMemRedactionRepo(doc)
+			repo.failUpdate, repo.failLog = tt.failUpdate, tt.failLog
+			_, err := NewRedactionService(repo).Redact(doc.ID, Redaction{StartPos: 8, EndPos: 16, Reason: "Witness identity", RedactedBy: "DET-1"})
+			if (err != nil) != (tt.failUpdate || tt.failLog) {
+				t.Fatalf("err = %v", err)
+			}
+			if len(doc.Redactions) != tt.wantRedactions || len(repo.log) != tt.wantLog {
+				t.Errorf("%d redactions and %d log entries, want %d and %d", len(doc.Redactions), len(repo.log), tt.wantRedactions, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestRedactionLogIDsAreUnique(t *testing.T) {
+	doc := statementDoc()
+	repo := newMemRedactionRepo(doc)
+	s := NewRedactionService(repo)
+	if _, err := s.RedactText(doc.ID, "jane doe", Redaction{Reason: "Witness identity", RedactedBy: "DET-1"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if _, err := s.Render(doc.ID, Viewer{Name: "ADA Smith"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Render(doc.ID, Viewer{Name: "Judge Ruiz", CourtOrder: "Order 2024-117", ReviewedBy: "SGT-42"}); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, e := range repo.log {
+		if seen[e.ID] || !strings.HasPrefix(e.ID, "RDL-") {
+			t.Fatalf("log entry ID %q repeated or malformed", e.ID)
+		}
+		seen[e.ID] = true
+	}
+}
+
+func TestRenderLiftsTemporaryRedactions(t *testing.T) {
+	tests := []struct {
+		name       string
+		viewer     Viewer
+		wantText   string
+		wantLifted int
+		wantErr    bool
+	}{
+		{"no court order", Viewer{Name: "Defense counsel"}, "Witness ████████ saw", 0, false},
+		{"court order without reviewer", Viewer{Name: "Judge Ruiz", CourtOrder: "Order 2024-117"}, "", 0, true},
+		{"viewer reviews own order", Viewer{Name: "Judge Ruiz", CourtOrder: "Order 2024-117", ReviewedBy: "judge ruiz"}, "", 0, true},
+		{"court order without viewer", Viewer{CourtOrder: "Order 2024-117", ReviewedBy: "SGT-42"}, "", 0, true},
+		{"reviewed court order", Viewer{Name: "Judge Ruiz", CourtOrder: "Order 2024-117", ReviewedBy: "SGT-42"}, "Witness Jane Doe saw", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := statementDoc()
+			repo := newMemRedactionRepo(doc)
+			s := NewRedactionService(repo)
+			if _, err := s.RedactText(doc.ID, "Jane Doe", Redaction{Reason: "Witness identity", RedactedBy: "DET-1", IsTemporary: true}); err != nil {
+				t.Fatal(err)
+			}
+			text, err := s.Render(doc.ID, tt.viewer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !strings.Contains(text, tt.wantText) {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+
+			var lifted []*RedactionLogEntry
+			for _, e := range repo.log {
+				if e.Action == RedactionLifted {
+					lifted = append(lifted, e)
+				}
+			}
+			if len(lifted) != tt.wantLifted {
+				t.Fatalf("%d lifted entries, want %d", len(lifted), tt.wantLifted)
+			}
+			for _, e := range lifted {
+				if e.Authorization != tt.viewer.CourtOrder || e.ReviewedBy != tt.viewer.ReviewedBy || !strings.Contains(e.Detail, tt.viewer.CourtOrder) {
+					t.Errorf("entry %+v does not record the order and reviewer", e)
+				}
+			}
+		})
+	}
+}
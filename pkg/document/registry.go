@@ -0,0 +1,170 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+	"github.com/jth/claude/GoInspectorGadget/pkg/ocr"
+)
+
+// ProcessorInfo declares the content a processor handles
+type ProcessorInfo struct {
+	Name          string
+	Description   string
+	FileTypes     []string             // Content types identified by pkg/filetype, such as "PDF"
+	MIMETypes     []string             // MIME types of the identified content
+	Signatures    []filetype.Signature // Byte patterns for formats pkg/filetype does not identify
+	Tools         []string             // External programs the processor needs
+	OptionalTools []string             // External programs used when installed, with a fallback otherwise
+}
+
+// DeclaredProcessor is a DocumentProcessor that declares what it handles
+type DeclaredProcessor interface {
+	DocumentProcessor
+	Info() ProcessorInfo
+}
+
+// ProcessorStatus reports whether a registered processor can run on this machine
+type ProcessorStatus struct {
+	ProcessorInfo
+	Fallback        bool     // Used for content no other processor handles
+	Missing         []string // Tools that were not found
+	MissingOptional []string // Optional tools that were not found
+}
+
+// Available reports whether all the tools the processor needs were found
+func (s ProcessorStatus) Available() bool {
+	return len(s.Missing) == 0
+}
+
+// Registry chooses the processor for each document from its content.
+// Processors are tried in the order they were registered.
+type Registry struct {
+	processors []DeclaredProcessor
+	fallback   DeclaredProcessor
+	classifier *Classifier
+}
+
+// NewRegistry creates a registry that uses fallback for content no
+// registered processor handles
+func NewRegistry(fallback DeclaredProcessor) *Registry {
+	return &Registry{fallback: fallback}
+}
+
+// NewDefaultRegistry creates a registry with the processors built into the
+// system, falling back to plain text and binary strings. Scanned pages and
+// images are read with the OCR engine, if tesseract is installed.
+func NewDefaultRegistry(tempDir string, engine *ocr.Tesseract) *Registry {
+	pdftotext, _ := exec.LookPath("pdftotext")
+	pdftoppm, _ := exec.LookPath("pdftoppm")
+	r := NewRegistry(&TextProcessor{})
+	r.Register(&PDFProcessor{PdfToTextPath: pdftotext, PdfToPpmPath: pdftoppm, UseOCR: engine != nil, OCR: engine, TempDir: tempDir})
+	r.Register(&DOCXProcessor{})
+	r.Register(&ODTProcessor{})
+	r.Register(&RTFProcessor{})
+	r.Register(&ImageProcessor{OCR: engine})
+	return r
+}
+
+// Register adds a processor to the registry
+func (r *Registry) Register(processor DeclaredProcessor) {
+	r.processors = append(r.processors, processor)
+}
+
+// SetClassifier replaces the classifier that assigns types to processed
+// documents
+func (r *Registry) SetClassifier(c *Classifier) {
+	r.classifier = c
+}
+
+// Classifier returns the classifier that assigns types to processed
+// documents, the baseline classifier unless another was set
+func (r *Registry) Classifier() *Classifier {
+	if r.classifier == nil {
+		return BaselineClassifier()
+	}
+	return r.classifier
+}
+
+// ProcessorFor returns the first available processor that handles a file,
+// or the fallback processor
+func (r *Registry) ProcessorFor(filePath string, fileType *filetype.Result) (DeclaredProcessor, error) {
+	header, err := readHeader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range r.processors {
+		if handles(p.Info(), fileType, header) && len(missingTools(p.Info().Tools)) == 0 {
+			return p, nil
+		}
+	}
+	if r.fallback == nil {
+		return nil, fmt.Errorf("no processor for %s content", fileType.Description)
+	}
+	return r.fallback, nil
+}
+
+// Processors returns the status of each registered processor, followed by
+// the fallback
+func (r *Registry) Processors() []ProcessorStatus {
+	var statuses []ProcessorStatus
+	for _, p := range r.processors {
+		statuses = append(statuses, ProcessorStatus{
+			ProcessorInfo:   p.Info(),
+			Missing:         missingTools(p.Info().Tools),
+			MissingOptional: missingTools(p.Info().OptionalTools),
+		})
+	}
+	if r.fallback != nil {
+		statuses = append(statuses, ProcessorStatus{ProcessorInfo: r.fallback.Info(), Fallback: true, Missing: missingTools(r.fallback.Info().Tools)})
+	}
+	return statuses
+}
+
+// handles reports whether a processor declares the identified content
+func handles(info ProcessorInfo, fileType *filetype.Result, header []byte) bool {
+	for _, name := range info.FileTypes {
+		if name == fileType.Name {
+			return true
+		}
+	}
+	for _, mime := range info.MIMETypes {
+		if mime == fileType.MIME {
+			return true
+		}
+	}
+	for _, s := range info.Signatures {
+		end := s.Offset + len(s.Magic)
+		if end <= len(header) && bytes.Equal(header[s.Offset:end], s.Magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingTools returns the external programs that are not installed
+func missingTools(tools []string) []string {
+	var missing []string
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+	return missing
+}
+
+// readHeader reads the start of a file for signature matching
+func readHeader(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 4096)
+	n, _ := f.Read(header)
+	return header[:n], nil
+}
@@ -0,0 +1,152 @@
+package document
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+)
+
+// stubProcessor declares content without processing anything
+type stubProcessor struct {
+	info ProcessorInfo
+}
+
+func (p *stubProcessor) Info() ProcessorInfo { return p.info }
+
+func (p *stubProcessor) Process(filePath string) (*Document, error) {
+	return &Document{Title: p.info.Name}, nil
+}
+
+func TestProcessorFor(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"notes.txt":  []byte("Suspect arrived at 21:40.\n"),
+		"capture.db": append([]byte("CASEDB\x00\x01"), make([]byte, 64)...),
+		"blob.bin":   {0x00, 0x01, 0x02, 0x03, 0xFE},
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byType := &stubProcessor{ProcessorInfo{Name: "by-type", FileTypes: []string{"TEXT"}}}
+	byMIME := &stubProcessor{ProcessorInfo{Name: "by-mime", MIMETypes: []string{"text/plain"}}}
+	bySignature := &stubProcessor{ProcessorInfo{Name: "by-signature", Signatures: []filetype.Signature{{Offset: 0, Magic: []byte("CASEDB")}}}}
+	needsTool := &stubProcessor{ProcessorInfo{Name: "needs-tool", FileTypes: []string{"TEXT"}, Tools: []string{"no-such-tool-installed"}}}
+	fallback := &stubProcessor{ProcessorInfo{Name: "fallback"}}
+
+	tests := []struct {
+		name       string
+		processors []DeclaredProcessor
+		fallback   DeclaredProcessor
+		file       string
+		want       string
+		wantErr    bool
+	}{
+		{"file type", []DeclaredProcessor{byType, byMIME}, fallback, "notes.txt", "by-type", false},
+		{"registration order", []DeclaredProcessor{byMIME, byType}, fallback, "notes.txt", "by-mime", false},
+		{"missing tool skipped", []DeclaredProcessor{needsTool, byMIME}, fallback, "notes.txt", "by-mime", false},
+		{"signature", []DeclaredProcessor{byType, bySignature}, fallback, "capture.db", "by-signature", false},
+		{"fallback", []DeclaredProcessor{byType, bySignature}, fallback, "blob.bin", "fallback", false},
+		{"no fallback", []DeclaredProcessor{byType}, nil, "blob.bin", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(tt.fallback)
+			for _, p := range tt.processors {
+				r.Register(p)
+			}
+			path := filepath.Join(dir, tt.file)
+			fileType, err := filetype.IdentifyFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := r.ProcessorFor(path, fileType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && p.Info().Name != tt.want {
+				t.Errorf("processor = %s, want %s", p.Info().Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessors(t *testing.T) {
+	r := NewRegistry(&TextProcessor{})
+	r.Register(&stubProcessor{ProcessorInfo{Name: "needs-tool", Tools: []string{"no-such-tool-installed"}}})
+	r.Register(&stubProcessor{ProcessorInfo{Name: "optional-tool", OptionalTools: []string{"no-such-tool-installed"}}})
+
+	tests := []struct {
+		name          string
+		wantAvailable bool
+		wantFallback  bool
+		wantOptional  int
+	}{
+		{"needs-tool", false, false, 0},
+		{"optional-tool", true, false, 1},
+		{"text", true, true, 0},
+	}
+	statuses := r.Processors()
+	if len(statuses) != len(tests) {
+		t.Fatalf("statuses = %+v", statuses)
+	}
+	for i, tt := range tests {
+		s := statuses[i]
+		if s.Name != tt.name || s.Available() != tt.wantAvailable || s.Fallback != tt.wantFallback || len(s.MissingOptional) != tt.wantOptional {
+			t.Errorf("status %d = %+v", i, s)
+		}
+	}
+}
+
+func TestTextProcessor(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           []byte
+		wantContent    string
+		wantExtraction string
+		wantEncoding   string
+	}{
+		{"utf-8", []byte("\xEF\xBB\xBFLlegó a las 21:40.\r\nSalió.\r\n"), "Llegó a las 21:40.\nSalió.", "text", "UTF-8"},
+		{"utf-16le", []byte("\xFF\xFEh\x00o\x00l\x00a\x00"), "hola", "text", "UTF-16LE"},
+		{"utf-16be", []byte("\xFE\xFF\x00h\x00o\x00l\x00a"), "hola", "text", "UTF-16BE"},
+		{"windows-1252", []byte("Cami\xf3n \x80 20\n"), "Camión € 20", "text", "Windows-1252"},
+		{
+			name:           "binary strings",
+			data:           []byte("\x00\x01\x02\x03password\x00\x9f\x01ab\x00\x02C\x00:\x00\\\x00U\x00s\x00e\x00r\x00s\x00\x00\x00\xff"),
+			wantContent:    "password\nC:\\Users",
+			wantExtraction: "binary strings",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			doc, err := (&TextProcessor{}).Process(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", doc.Content, tt.wantContent)
+			}
+			fields := doc.Metadata.CustomFields
+			if fields["Extraction"] != tt.wantExtraction || fields["Encoding"] != tt.wantEncoding {
+				t.Errorf("fields = %v", fields)
+			}
+		})
+	}
+}
+
+func TestBinaryStrings(t *testing.T) {
+	data := []byte("abc\x00LONGER\x00\x00x\x00y\x00z\x00w\x00\x00tail")
+	got := strings.Join(binaryStrings(data), "|")
+	if got != "LONGER|xyzw|tail" {
+		t.Errorf("strings = %q", got)
+	}
+}
@@ -0,0 +1,592 @@
+package document
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRTFSize limits the RTF files read; embedded pictures make them large
+const maxRTFSize = 4 * maxPartSize
+
+// maxRTFDepth limits group nesting in RTF files
+const maxRTFDepth = 1024
+
+// RTFProcessor implements DocumentProcessor for Rich Text Format files
+type RTFProcessor struct{}
+
+// Info declares the content handled by the RTF processor
+func (p *RTFProcessor) Info() ProcessorInfo {
+	return ProcessorInfo{
+		Name:        "rtf",
+		Description: "Rich Text Format: text, document information, annotations, revisions and pictures",
+		FileTypes:   []string{"RTF"},
+	}
+}
+
+// Process extracts the text, document information, embedded pictures,
+// annotations and revision marks of an RTF file
+func (p *RTFProcessor) Process(filePath string) (*Document, error) {
+	fileType, info, err := identifyAs(filePath, "RTF")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxRTFSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	if len(data) > maxRTFSize {
+		return nil, fmt.Errorf("document is larger than %d MB", maxRTFSize>>20)
+	}
+
+	r := newRTFReader(data)
+	if err := r.parse(); err != nil {
+		return nil, err
+	}
+	doc := newProcessedDocument(filePath, fileType, info, r.text, r.metadata)
+	doc.Attachments = r.attachments
+	return doc, nil
+}
+
+// rtfDest identifies where the text of an RTF group goes
+type rtfDest int
+
+const (
+	rtfBody       rtfDest = iota // Document text
+	rtfSkip                      // Tables, headers and unknown destinations
+	rtfInfo                      // The \info group
+	rtfInfoText                  // A text field of \info, such as \author
+	rtfInfoTime                  // \creatim, \revtim or \printim
+	rtfRevTable                  // Revision author table
+	rtfPicture                   // Hex or binary picture data
+	rtfComment                   // Annotation text
+	rtfCommentRef                // Annotation fields: author, ID, date, range start
+)
+
+// rtfSkipDestinations are destinations whose text is not part of the document
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "listtable": true, "listoverridetable": true,
+	"rsidtbl": true, "generator": true, "xmlnstbl": true, "themedata": true, "colorschememapping": true,
+	"latentstyles": true, "datastore": true, "filetbl": true, "pgdsctbl": true, "nonshppict": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"objdata": true, "fldinst": true, "atnparent": true, "atnicn": true,
+}
+
+// rtfInfoFields maps the text fields of the \info group
+var rtfInfoFields = map[string]bool{
+	"title": true, "subject": true, "author": true, "operator": true, "keywords": true,
+	"doccomm": true, "comment": true, "company": true, "category": true, "manager": true, "hlinkbase": true,
+}
+
+// rtfSymbols are control words that stand for a character
+var rtfSymbols = map[string]string{
+	"par": "\n", "line": "\n", "page": "\n", "sect": "\n", "tab": "\t",
+	"emdash": "—", "endash": "–", "bullet": "•", "emspace": " ", "enspace": " ", "qmspace": " ",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+}
+
+// rtfGroup is the state of an open RTF group
+type rtfGroup struct {
+	dest  rtfDest
+	name  string // Destination control word, for groups that start one
+	owner bool   // The destination started in this group
+	buf   *strings.Builder
+	uc    int // Characters to skip after a \u character
+
+	inserted, deleted bool
+	insAuthor         int
+	insTime           int64
+	delAuthor         int
+	delTime           int64
+}
+
+// rtfRun is a stretch of text with the same revision mark
+type rtfRun struct {
+	kind     AnnotationKind
+	author   int
+	date     int64
+	position int
+	text     strings.Builder
+}
+
+// rtfReader parses an RTF document
+type rtfReader struct {
+	data        []byte
+	pos         int
+	stack       []*rtfGroup
+	ignorable   bool // The next control word starts an ignorable destination
+	skipChars   int  // Characters left to skip after \u
+	pendingCell bool // A table cell ended; the next text starts the next cell
+
+	text        *textBuilder
+	metadata    Metadata
+	attachments []Attachment
+
+	revAuthors    []string
+	run           *rtfRun
+	times         map[string]int // \yr, \mo, ... of the current \info time
+	pictExt       string
+	pictBin       []byte
+	commentAuthor string
+	commentID     string
+	commentDate   int64
+	commentStarts map[string]int
+	commentStart  int
+}
+
+func newRTFReader(data []byte) *rtfReader {
+	return &rtfReader{
+		data:          data,
+		text:          &textBuilder{},
+		metadata:      Metadata{CustomFields: make(map[string]string)},
+		commentStarts: make(map[string]int),
+	}
+}
+
+// group returns the innermost open group
+func (r *rtfReader) group() *rtfGroup {
+	return r.stack[len(r.stack)-1]
+}
+
+// parse reads the whole document
+func (r *rtfReader) parse() error {
+	r.stack = []*rtfGroup{{dest: rtfSkip, uc: 1}} // Outside the document group
+	for r.pos < len(r.data) {
+		c := r.data[r.pos]
+		r.pos++
+		switch c {
+		case '{':
+			if len(r.stack) >= maxRTFDepth {
+				return fmt.Errorf("RTF groups are nested more than %d deep", maxRTFDepth)
+			}
+			g := *r.group()
+			g.owner = false
+			if len(r.stack) == 1 {
+				g.dest = rtfBody
+			}
+			r.stack = append(r.stack, &g)
+			r.skipChars = 0
+		case '}':
+			if len(r.stack) == 1 {
+				continue
+			}
+			r.closeGroup()
+			r.skipChars = 0
+		case '\\':
+			r.control()
+		case '\r', '\n':
+		default:
+			r.char(cp1252Rune(c))
+		}
+	}
+	r.flushRun()
+
+	if r.metadata.Subject == "" {
+		r.metadata.Subject = r.metadata.CustomFields["Title"]
+	}
+	return nil
+}
+
+// control reads a control word or control symbol
+func (r *rtfReader) control() {
+	if r.pos >= len(r.data) {
+		return
+	}
+	c := r.data[r.pos]
+	if !isRTFLetter(c) {
+		r.pos++
+		switch c {
+		case '\'':
+			if r.pos+2 <= len(r.data) {
+				if b, err := hex.DecodeString(string(r.data[r.pos : r.pos+2])); err == nil {
+					r.char(cp1252Rune(b[0]))
+				}
+				r.pos += 2
+			}
+		case '*':
+			r.ignorable = true
+		case '~':
+			r.char(' ')
+		case '_':
+			r.char('-')
+		case '{', '}', '\\':
+			r.char(rune(c))
+		case '\r', '\n':
+			r.write("\n")
+		}
+		return
+	}
+
+	start := r.pos
+	for r.pos < len(r.data) && isRTFLetter(r.data[r.pos]) {
+		r.pos++
+	}
+	word := string(r.data[start:r.pos])
+	param, hasParam := 0, false
+	if r.pos < len(r.data) && (r.data[r.pos] == '-' || isRTFDigit(r.data[r.pos])) {
+		numStart := r.pos
+		r.pos++
+		for r.pos < len(r.data) && isRTFDigit(r.data[r.pos]) {
+			r.pos++
+		}
+		param, _ = strconv.Atoi(string(r.data[numStart:r.pos]))
+		hasParam = true
+	}
+	if r.pos < len(r.data) && r.data[r.pos] == ' ' {
+		r.pos++
+	}
+
+	ignorable := r.ignorable
+	r.ignorable = false
+	if word != "u" && word != "bin" && r.skipChars > 0 {
+		r.skipChars--
+		return
+	}
+	r.word(word, param, hasParam, ignorable)
+}
+
+// word acts on a control word
+func (r *rtfReader) word(word string, param int, hasParam, ignorable bool) {
+	g := r.group()
+	if g.dest == rtfSkip {
+		if word == "bin" && param > 0 {
+			r.pos = min(r.pos+param, len(r.data))
+		}
+		return
+	}
+
+	switch {
+	case word == "u":
+		if param < 0 {
+			param += 65536
+		}
+		r.char(rune(param))
+		r.skipChars = g.uc
+		return
+	case word == "uc":
+		g.uc = param
+		return
+	case word == "bin":
+		if param > 0 {
+			end := min(r.pos+param, len(r.data))
+			if g.dest == rtfPicture {
+				r.pictBin = append(r.pictBin, r.data[r.pos:end]...)
+			}
+			r.pos = end
+		}
+		return
+	}
+
+	if symbol, ok := rtfSymbols[word]; ok {
+		if word == "par" && g.dest == rtfComment && g.buf.Len() == 0 {
+			return
+		}
+		r.write(symbol)
+		return
+	}
+
+	switch word {
+	case "cell", "nestcell":
+		r.pendingCell = true
+		return
+	case "row", "nestrow":
+		r.pendingCell = false
+		r.write("\n")
+		return
+
+	// Revision marks
+	case "revised":
+		g.inserted = !hasParam || param != 0
+		return
+	case "deleted":
+		g.deleted = !hasParam || param != 0
+		return
+	case "revauth":
+		g.insAuthor = param
+		return
+	case "revauthdel":
+		g.delAuthor = param
+		return
+	case "revdttm":
+		g.insTime = int64(param)
+		return
+	case "revdttmdel":
+		g.delTime = int64(param)
+		return
+	case "plain":
+		g.inserted, g.deleted = false, false
+		return
+
+	// Numeric fields of \info and its times
+	case "version":
+		if g.dest == rtfInfo {
+			r.metadata.Revision = param
+		}
+		return
+	case "edmins", "nofpages", "nofwords", "vern":
+		if g.dest == rtfInfo {
+			name := map[string]string{"edmins": "TotalTime", "nofpages": "Pages", "nofwords": "Words", "vern": "InternalVersion"}[word]
+			r.metadata.CustomFields[name] = strconv.Itoa(param)
+		}
+		return
+	case "yr", "mo", "dy", "hr", "min", "sec":
+		if g.dest == rtfInfoTime {
+			r.times[word] = param
+		}
+		return
+
+	// Destinations that are read through
+	case "shppict", "fldrslt", "listtext", "pntext":
+		return
+	}
+
+	// Destinations
+	switch {
+	case word == "info":
+		r.startDestination(rtfInfo, word)
+	case g.dest == rtfInfo && rtfInfoFields[word]:
+		r.startDestination(rtfInfoText, word)
+	case g.dest == rtfInfo && (word == "creatim" || word == "revtim" || word == "printim" || word == "buptim"):
+		r.startDestination(rtfInfoTime, word)
+		r.times = make(map[string]int)
+	case word == "revtbl":
+		r.startDestination(rtfRevTable, word)
+	case word == "pict":
+		r.startDestination(rtfPicture, word)
+		r.pictExt, r.pictBin = ".bin", nil
+	case g.dest == rtfPicture:
+		if ext, ok := rtfPictureTypes[word]; ok {
+			r.pictExt = ext
+		} else if ignorable {
+			r.startDestination(rtfSkip, word)
+		}
+	case word == "annotation":
+		r.startDestination(rtfComment, word)
+		r.commentStart = r.text.offset()
+	case word == "atnid" || word == "atnauthor" || word == "atndate" || word == "atnref" || word == "atrfstart":
+		r.startDestination(rtfCommentRef, word)
+	case rtfSkipDestinations[word] || ignorable:
+		r.startDestination(rtfSkip, word)
+	}
+}
+
+// rtfPictureTypes maps picture format control words to file extensions
+var rtfPictureTypes = map[string]string{
+	"pngblip": ".png", "jpegblip": ".jpg", "emfblip": ".emf", "wmetafile": ".wmf",
+	"dibitmap": ".bmp", "wbitmap": ".bmp", "macpict": ".pict",
+}
+
+// startDestination sends the rest of the current group to a destination
+func (r *rtfReader) startDestination(dest rtfDest, name string) {
+	g := r.group()
+	g.dest, g.name, g.owner = dest, name, true
+	g.buf = &strings.Builder{}
+}
+
+// closeGroup ends the innermost group, finishing the destination it started
+func (r *rtfReader) closeGroup() {
+	g := r.group()
+	r.stack = r.stack[:len(r.stack)-1]
+	if !g.owner {
+		return
+	}
+
+	value := strings.TrimSpace(g.buf.String())
+	switch g.dest {
+	case rtfInfoText:
+		switch g.name {
+		case "author":
+			r.metadata.Author = value
+		case "operator":
+			r.metadata.LastModifiedBy = value
+		case "subject":
+			r.metadata.Subject = value
+		case "keywords":
+			r.metadata.Keywords = splitKeywords(value)
+		case "title":
+			r.metadata.CustomFields["Title"] = value
+		case "doccomm":
+			r.metadata.CustomFields["Comments"] = value
+		default:
+			r.metadata.CustomFields[strings.ToUpper(g.name[:1])+g.name[1:]] = value
+		}
+	case rtfInfoTime:
+		if r.times["yr"] == 0 {
+			break
+		}
+		t := time.Date(r.times["yr"], time.Month(max(r.times["mo"], 1)), max(r.times["dy"], 1),
+			r.times["hr"], r.times["min"], r.times["sec"], 0, time.UTC)
+		switch g.name {
+		case "creatim":
+			r.metadata.CreationDate = t
+		case "revtim":
+			r.metadata.ModifiedDate = t
+		case "printim":
+			r.metadata.CustomFields["LastPrinted"] = t.Format(time.RFC3339)
+		}
+	case rtfPicture:
+		data := r.pictBin
+		if len(data) == 0 {
+			data = decodeRTFHex(g.buf.String())
+		}
+		if len(data) > 0 {
+			name := fmt.Sprintf("image%d%s", len(r.attachments)+1, r.pictExt)
+			r.attachments = append(r.attachments, newAttachment(name, data))
+		}
+		r.pictBin = nil
+	case rtfCommentRef:
+		switch g.name {
+		case "atnauthor":
+			r.commentAuthor = value
+		case "atnref":
+			r.commentID = value
+		case "atndate":
+			r.commentDate, _ = strconv.ParseInt(value, 10, 64)
+		case "atrfstart":
+			r.commentStarts[value] = r.text.offset()
+		}
+	case rtfComment:
+		position, ok := r.commentStarts[r.commentID]
+		if !ok {
+			position = r.commentStart
+		}
+		r.text.annotate(AnnotationComment, r.commentAuthor, rtfDate(r.commentDate), g.buf.String(), position)
+		r.commentAuthor, r.commentID, r.commentDate = "", "", 0
+	}
+}
+
+// char writes a character, unless it stands in for the preceding \u
+// character in readers without Unicode support
+func (r *rtfReader) char(c rune) {
+	if r.skipChars > 0 {
+		r.skipChars--
+		return
+	}
+	r.write(string(c))
+}
+
+// write sends text to the current destination
+func (r *rtfReader) write(s string) {
+	g := r.group()
+	switch g.dest {
+	case rtfBody:
+		if r.pendingCell && s != "\n" {
+			r.pendingCell = false
+			r.write("\t")
+		}
+		r.writeBody(g, s)
+	case rtfRevTable:
+		// Entries are author names ending in a semicolon
+		for _, c := range s {
+			if c == ';' {
+				r.revAuthors = append(r.revAuthors, strings.TrimSpace(g.buf.String()))
+				g.buf.Reset()
+			} else {
+				g.buf.WriteRune(c)
+			}
+		}
+	case rtfInfoText, rtfPicture, rtfComment, rtfCommentRef:
+		g.buf.WriteString(s)
+	}
+}
+
+// writeBody writes document text, keeping deleted text out of the content
+// and recording revision marks as annotations
+func (r *rtfReader) writeBody(g *rtfGroup, s string) {
+	var kind AnnotationKind
+	author, date := 0, int64(0)
+	switch {
+	case g.deleted:
+		kind, author, date = AnnotationDeletion, g.delAuthor, g.delTime
+	case g.inserted:
+		kind, author, date = AnnotationInsertion, g.insAuthor, g.insTime
+	}
+
+	if r.run != nil && (r.run.kind != kind || r.run.author != author || r.run.date != date) {
+		r.flushRun()
+	}
+	if kind != "" {
+		if r.run == nil {
+			r.run = &rtfRun{kind: kind, author: author, date: date, position: r.text.offset()}
+		}
+		r.run.text.WriteString(s)
+	}
+	if kind != AnnotationDeletion {
+		r.text.WriteString(s)
+	}
+}
+
+// flushRun records the current revision run as an annotation
+func (r *rtfReader) flushRun() {
+	if r.run == nil {
+		return
+	}
+	author := ""
+	if r.run.author >= 0 && r.run.author < len(r.revAuthors) {
+		author = r.revAuthors[r.run.author]
+	}
+	r.text.annotate(r.run.kind, author, rtfDate(r.run.date), r.run.text.String(), r.run.position)
+	r.run = nil
+}
+
+// rtfDate decodes the packed DTTM dates of revision marks and annotations
+func rtfDate(v int64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	minute := int(v & 0x3f)
+	hour := int(v>>6) & 0x1f
+	day := int(v>>11) & 0x1f
+	month := int(v>>16) & 0xf
+	year := 1900 + int(v>>20)&0x1ff
+	return time.Date(year, time.Month(month), day, hour, minute, 0, 0, time.UTC)
+}
+
+// decodeRTFHex decodes picture data, ignoring line breaks and spaces
+func decodeRTFHex(s string) []byte {
+	digits := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isRTFDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' {
+			digits = append(digits, c)
+		}
+	}
+	data := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(data, digits[:len(digits)/2*2])
+	return data[:n]
+}
+
+func isRTFLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isRTFDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// cp1252High maps the bytes 0x80-0x9F of Windows-1252, the default RTF code
+// page; the other bytes match Latin-1
+var cp1252High = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+// cp1252Rune decodes a Windows-1252 byte
+func cp1252Rune(b byte) rune {
+	if b >= 0x80 && b < 0xa0 {
+		return cp1252High[b-0x80]
+	}
+	return rune(b)
+}
@@ -0,0 +1,167 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+)
+
+// minStringLength is the shortest run of printable characters kept when
+// extracting strings from binary content
+const minStringLength = 4
+
+// TextProcessor implements DocumentProcessor for plain text. Content that
+// is not text is reduced to its printable strings, so that any file can be
+// imported and searched.
+type TextProcessor struct{}
+
+// Info declares the content handled by the text processor
+func (p *TextProcessor) Info() ProcessorInfo {
+	return ProcessorInfo{
+		Name:        "text",
+		Description: "Plain text in UTF-8, UTF-16 or Windows-1252; printable strings of any other content",
+		FileTypes:   []string{"TEXT"},
+		MIMETypes:   []string{"text/plain"},
+	}
+}
+
+// Process reads a text file, or the printable strings of a binary file
+func (p *TextProcessor) Process(filePath string) (*Document, error) {
+	fileType, err := filetype.IdentifyFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify file type: %w", err)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxPartSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	metadata := Metadata{CustomFields: make(map[string]string)}
+	if info.Size() > maxPartSize {
+		metadata.CustomFields["Truncated"] = fmt.Sprintf("only the first %d MB were read", maxPartSize>>20)
+	}
+	text := &textBuilder{}
+	if fileType.Category == filetype.CategoryText {
+		content, encoding := decodeText(data)
+		text.WriteString(content)
+		metadata.CustomFields["Extraction"] = "text"
+		metadata.CustomFields["Encoding"] = encoding
+	} else {
+		text.WriteString(strings.Join(binaryStrings(data), "\n"))
+		metadata.CustomFields["Extraction"] = "binary strings"
+	}
+	return newProcessedDocument(filePath, fileType, info, text, metadata), nil
+}
+
+// decodeText decodes text in UTF-16 with a byte order mark, UTF-8 or
+// Windows-1252, returning the text and the encoding found
+func decodeText(data []byte) (string, string) {
+	var s, encoding string
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		s, encoding = decodeUTF16(data[2:], false), "UTF-16LE"
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		s, encoding = decodeUTF16(data[2:], true), "UTF-16BE"
+	case utf8.Valid(data):
+		s, encoding = string(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))), "UTF-8"
+	default:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = cp1252Rune(b)
+		}
+		s, encoding = string(runes), "Windows-1252"
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n"), encoding
+}
+
+// decodeUTF16 decodes UTF-16 text without its byte order mark
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// binaryStrings returns the runs of at least minStringLength printable ASCII
+// characters in data, single-byte or UTF-16LE as Windows stores them, in the
+// order they appear
+func binaryStrings(data []byte) []string {
+	type found struct {
+		offset int
+		text   string
+	}
+	var runs []found
+
+	// Single-byte strings
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && isPrintable(data[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minStringLength {
+			runs = append(runs, found{start, string(data[start:i])})
+		}
+		start = -1
+	}
+
+	// UTF-16LE strings, at either byte alignment
+	var b strings.Builder
+	start = -1
+	for i := 0; i < len(data); {
+		if i+1 < len(data) && isPrintable(data[i]) && data[i+1] == 0 {
+			if start < 0 {
+				start = i
+				b.Reset()
+			}
+			b.WriteByte(data[i])
+			i += 2
+			continue
+		}
+		if start >= 0 && b.Len() >= minStringLength {
+			runs = append(runs, found{start, b.String()})
+		}
+		if start < 0 {
+			i++
+		}
+		start = -1
+	}
+	if start >= 0 && b.Len() >= minStringLength {
+		runs = append(runs, found{start, b.String()})
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].offset < runs[j].offset })
+	strs := make([]string, len(runs))
+	for i, r := range runs {
+		strs[i] = r.text
+	}
+	return strs
+}
+
+// isPrintable reports whether a byte is printable ASCII or a tab
+func isPrintable(b byte) bool {
+	return b >= 0x20 && b < 0x7F || b == '\t'
+}
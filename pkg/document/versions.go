@@ -0,0 +1,247 @@
+package document
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fingerprintSize is the number of MinHash values in a fingerprint
+const fingerprintSize = 64
+
+// shingleWords is the number of consecutive words hashed together
+const shingleWords = 3
+
+// nearDuplicateSimilarity is the least estimated share of word sequences
+// two documents have in common to be near-duplicates
+const nearDuplicateSimilarity = 0.8
+
+// MatchKind tells how closely a document matches another
+type MatchKind string
+
+const (
+	MatchExact MatchKind = "EXACT" // Same file, by SHA-256 hash
+	MatchNear  MatchKind = "NEAR"  // Mostly the same text, such as a revision or a rescan
+)
+
+// Match is a document found to duplicate another
+type Match struct {
+	Document   *Document
+	Kind       MatchKind
+	Similarity float64 // Estimated share of word sequences in common, 0 to 1
+}
+
+// VersionRepository stores documents and finds the documents of a case
+type VersionRepository interface {
+	Find(id string) (*Document, error)
+	FindByCase(caseID string) ([]*Document, error)
+	Update(doc *Document) error
+}
+
+// VersionService finds duplicate documents and keeps the version chains of
+// documents revised by later ones
+type VersionService struct {
+	repo VersionRepository
+}
+
+// NewVersionService creates a new version service
+func NewVersionService(repo VersionRepository) *VersionService {
+	return &VersionService{repo: repo}
+}
+
+// ExactDuplicate returns the document of a case stored from a file with the
+// hash, or nil if there is none
+func (s *VersionService) ExactDuplicate(caseID, hash string) (*Document, error) {
+	docs, err := s.repo.FindByCase(caseID)
+	if err != nil {
+		return nil, err
+	}
+	var found *Document
+	for _, doc := range docs {
+		if doc.SHA256 == hash && (found == nil || doc.CreatedAt.Before(found.CreatedAt)) {
+			found = doc
+		}
+	}
+	return found, nil
+}
+
+// Duplicates returns the other documents of the document's case that are
+// exact or near-duplicates of it, closest first
+func (s *VersionService) Duplicates(id string) ([]Match, error) {
+	doc, err := s.repo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.repo.FindByCase(doc.CaseID)
+	if err != nil {
+		return nil, err
+	}
+	return FindDuplicates(doc, docs), nil
+}
+
+// Supersede records that a document is a revision of an earlier one, such
+// as an amended report, and numbers the versions of the chain
+func (s *VersionService) Supersede(previousID, nextID string) error {
+	if previousID == nextID {
+		return fmt.Errorf("a document cannot supersede itself")
+	}
+	previous, err := s.repo.Find(previousID)
+	if err != nil {
+		return err
+	}
+	next, err := s.repo.Find(nextID)
+	if err != nil {
+		return err
+	}
+	if previous.CaseID != next.CaseID {
+		return fmt.Errorf("documents %s and %s belong to different cases", previousID, nextID)
+	}
+	if previous.SupersededBy != "" {
+		return fmt.Errorf("document %s is already superseded by %s", previousID, previous.SupersededBy)
+	}
+	if next.Supersedes != "" {
+		return fmt.Errorf("document %s already supersedes %s", nextID, next.Supersedes)
+	}
+	later, err := s.Versions(nextID)
+	if err != nil {
+		return err
+	}
+	for _, doc := range later {
+		if doc.ID == previousID {
+			return fmt.Errorf("document %s is a later version of %s", previousID, nextID)
+		}
+	}
+
+	previous.SupersededBy = nextID
+	next.Supersedes = previousID
+	chain, err := s.Versions(previousID)
+	if err != nil {
+		return err
+	}
+	for i, doc := range chain {
+		doc.Version = i + 1
+		if err := s.repo.Update(doc); err != nil {
+			return fmt.Errorf("failed to update document %s: %w", doc.ID, err)
+		}
+	}
+	return nil
+}
+
+// Versions returns the version chain of a document, earliest first
+func (s *VersionService) Versions(id string) ([]*Document, error) {
+	doc, err := s.repo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Go back to the first version
+	seen := map[string]bool{doc.ID: true}
+	for doc.Supersedes != "" && !seen[doc.Supersedes] {
+		previous, err := s.repo.Find(doc.Supersedes)
+		if err != nil {
+			return nil, fmt.Errorf("earlier version of %s: %w", doc.ID, err)
+		}
+		seen[previous.ID] = true
+		doc = previous
+	}
+
+	chain := []*Document{doc}
+	seen = map[string]bool{doc.ID: true}
+	for doc.SupersededBy != "" && !seen[doc.SupersededBy] {
+		next, err := s.repo.Find(doc.SupersededBy)
+		if err != nil {
+			return nil, fmt.Errorf("later version of %s: %w", doc.ID, err)
+		}
+		seen[next.ID] = true
+		chain = append(chain, next)
+		doc = next
+	}
+	return chain, nil
+}
+
+// FindDuplicates returns the documents that are exact or near-duplicates of
+// a document, closest first
+func FindDuplicates(doc *Document, docs []*Document) []Match {
+	var matches []Match
+	for _, other := range docs {
+		if other.ID == doc.ID {
+			continue
+		}
+		if doc.SHA256 != "" && other.SHA256 == doc.SHA256 {
+			matches = append(matches, Match{Document: other, Kind: MatchExact, Similarity: 1})
+		} else if sim := Similarity(doc, other); sim >= nearDuplicateSimilarity {
+			matches = append(matches, Match{Document: other, Kind: MatchNear, Similarity: sim})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Kind != matches[j].Kind {
+			return matches[i].Kind == MatchExact
+		}
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].Document.CreatedAt.Before(matches[j].Document.CreatedAt)
+	})
+	return matches
+}
+
+// Similarity estimates the share of word sequences two documents have in
+// common from their fingerprints
+func Similarity(a, b *Document) float64 {
+	if a.SHA256 != "" && a.SHA256 == b.SHA256 {
+		return 1
+	}
+	if len(a.Fingerprint) != fingerprintSize || len(b.Fingerprint) != fingerprintSize {
+		return 0
+	}
+	same := 0
+	for i := range a.Fingerprint {
+		if a.Fingerprint[i] == b.Fingerprint[i] {
+			same++
+		}
+	}
+	return float64(same) / fingerprintSize
+}
+
+// Fingerprint returns the MinHash of the word sequences of a text, which is
+// unaffected by case, punctuation and layout. Text with no words has none.
+func Fingerprint(text string) []uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+
+	n := min(shingleWords, len(words))
+	fingerprint := make([]uint64, fingerprintSize)
+	for i := range fingerprint {
+		fingerprint[i] = ^uint64(0)
+	}
+	for i := 0; i+n <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+n], " ")))
+		shingle := h.Sum64()
+		seed := uint64(0)
+		for j := range fingerprint {
+			// Each value uses a differently seeded mix of the shingle hash
+			seed += 0x9e3779b97f4a7c15
+			if v := mix(shingle ^ seed); v < fingerprint[j] {
+				fingerprint[j] = v
+			}
+		}
+	}
+	return fingerprint
+}
+
+// mix scrambles the bits of a hash (the SplitMix64 finalizer)
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,278 @@
+package document
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memVersionRepo is an in-memory VersionRepository that shares the stored
+// documents, as the CLI repository does
+type memVersionRepo struct {
+	docs map[string]*Document
+}
+
+func newMemVersionRepo(docs ...*Document) *memVersionRepo {
+	r := &memVersionRepo{docs: make(map[string]*Document)}
+	for _, d := range docs {
+		r.docs[d.ID] = d
+	}
+	return r
+}
+
+func (r *memVersionRepo) Find(id string) (*Document, error) {
+	if d, ok := r.docs[id]; ok {
+		return d, nil
+	}
+	return nil, errors.New("document not found")
+}
+
+func (r *memVersionRepo) FindByCase(caseID string) ([]*Document, error) {
+	var docs []*Document
+	for _, d := range r.docs {
+		if d.CaseID == caseID {
+			docs = append(docs, d)
+		}
+	}
+	return docs, nil
+}
+
+func (r *memVersionRepo) Update(doc *Document) error {
+	r.docs[doc.ID] = doc
+	return nil
+}
+
+const reportText = `On 5 March 2024 at 21:30 officers responded to a burglary at 1234 Main Street.
+The rear door had been forced and a laptop, two phones and cash were taken. A neighbour
+saw a man in a red jacket leave on foot towards the park. The scene was photographed and
+fingerprints were lifted from the door frame. The owner was advised to list the serial numbers.`
+
+// textDoc creates a document of a case with a fingerprint of its content
+func textDoc(id, caseID, hash, content string) *Document {
+	return &Document{ID: id, CaseID: caseID, SHA256: hash, Content: content, Fingerprint: Fingerprint(content)}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    *Document
+		atLeast float64
+		below   float64
+	}{
+		{"same file", &Document{SHA256: "abc"}, &Document{SHA256: "abc"}, 1, 1.1},
+		{"layout and case ignored", textDoc("A", "", "1", reportText), textDoc("B", "", "2", strings.ToUpper(strings.ReplaceAll(reportText, "\n", "  "))), 1, 1.1},
+		{"one word changed", textDoc("A", "", "1", reportText), textDoc("B", "", "2", strings.Replace(reportText, "two phones", "three phones", 1)), 0.8, 1},
+		{"different text", textDoc("A", "", "1", reportText), textDoc("B", "", "2", "Witness statement of Mary Jones about the collision on Oak Avenue."), 0, 0.2},
+		{"no fingerprint", &Document{SHA256: "1"}, textDoc("B", "", "2", reportText), 0, 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sim := Similarity(tt.a, tt.b); sim < tt.atLeast || sim >= tt.below {
+				t.Errorf("similarity = %.2f, want in [%.2f, %.2f)", sim, tt.atLeast, tt.below)
+			}
+		})
+	}
+	if Fingerprint(" ... ") != nil {
+		t.Error("text with no words has a fingerprint")
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	original := textDoc("D1", "C1", "h1", reportText)
+	original.CreatedAt = time.Now().Add(-2 * time.Hour)
+	copied := textDoc("D2", "C1", "h1", reportText)
+	copied.CreatedAt = time.Now().Add(-time.Hour)
+	rescan := textDoc("D3", "C1", "h3", strings.Replace(reportText, "laptop", "lap top", 1))
+	other := textDoc("D4", "C1", "h4", "Autopsy report. Cause of death: blunt force trauma to the head.")
+	otherCase := textDoc("D5", "C2", "h1", reportText)
+	s := NewVersionService(newMemVersionRepo(original, copied, rescan, other, otherCase))
+
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{"D1", []string{"D2 EXACT", "D3 NEAR"}},
+		{"D3", []string{"D1 NEAR", "D2 NEAR"}},
+		{"D4", nil},
+	}
+	for _, tt := range tests {
+		matches, err := s.Duplicates(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range matches {
+			got = append(got, m.Document.ID+" "+string(m.Kind))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("duplicates of %s = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+
+	found, err := s.ExactDuplicate("C1", "h1")
+	if err != nil || found == nil || found.ID != "D1" {
+		t.Errorf("exact duplicate = %+v, %v; want the earliest, D1", found, err)
+	}
+	if found, _ := s.ExactDuplicate("C1", "h9"); found != nil {
+		t.Errorf("exact duplicate of an unknown hash = %s", found.ID)
+	}
+}
+
+func TestSupersede(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(r *memVersionRepo)
+		prev    string
+		next    string
+		wantErr string
+	}{
+		{name: "revision", prev: "D1", next: "D2"},
+		{name: "itself", prev: "D1", next: "D1", wantErr: "cannot supersede itself"},
+		{name: "other case", prev: "D1", next: "X1", wantErr: "different cases"},
+		{
+			name:    "already superseded",
+			setup:   func(r *memVersionRepo) { r.docs["D1"].SupersededBy, r.docs["D3"].Supersedes = "D3", "D1" },
+			prev:    "D1",
+			next:    "D2",
+			wantErr: "already superseded by D3",
+		},
+		{
+			name:    "already a revision",
+			setup:   func(r *memVersionRepo) { r.docs["D2"].Supersedes, r.docs["D3"].SupersededBy = "D3", "D2" },
+			prev:    "D1",
+			next:    "D2",
+			wantErr: "already supersedes D3",
+		},
+		{
+			name:    "cycle",
+			setup:   func(r *memVersionRepo) { r.docs["D1"].SupersededBy, r.docs["D2"].Supersedes = "D2", "D1" },
+			prev:    "D2",
+			next:    "D1",
+			wantErr: "D2 is a later version of D1",
+		},
+		{name: "unknown document", prev: "D1", next: "D9", wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMemVersionRepo(
+				&Document{ID: "D1", CaseID: "C1"},
+				&Document{ID: "D2", CaseID: "C1"},
+				&Document{ID: "D3", CaseID: "C1"},
+				&Document{ID: "X1", CaseID: "C2"},
+			)
+			if tt.setup != nil {
+				tt.setup(r)
+			}
+			err := NewVersionService(r).Supersede(tt.prev, tt.next)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.docs["D1"].SupersededBy != "D2" || r.docs["D2"].Supersedes != "D1" {
+				t.Errorf("links = %+v, %+v", r.docs["D1"], r.docs["D2"])
+			}
+		})
+	}
+}
+
+func TestVersionChain(t *testing.T) {
+	r := newMemVersionRepo(
+		&Document{ID: "D1", CaseID: "C1"},
+		&Document{ID: "D2", CaseID: "C1"},
+		&Document{ID: "D3", CaseID: "C1"},
+	)
+	s := NewVersionService(r)
+	// Link the later revisions first, then put the original in front
+	if err := s.Supersede("D2", "D3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Supersede("D1", "D2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Supersede("D3", "D1"); err == nil || !strings.Contains(err.Error(), "later version") {
+		t.Errorf("cycle allowed: %v", err)
+	}
+
+	for _, id := range []string{"D1", "D2", "D3"} {
+		chain, err := s.Versions(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, d := range chain {
+			got = append(got, d.ID)
+		}
+		if strings.Join(got, ",") != "D1,D2,D3" {
+			t.Errorf("versions of %s = %v", id, got)
+		}
+	}
+	for i, id := range []string{"D1", "D2", "D3"} {
+		if v := r.docs[id].Version; v != i+1 {
+			t.Errorf("%s is version %d, want %d", id, v, i+1)
+		}
+	}
+}
+
+func TestImportStoresByHash(t *testing.T) {
+	src := t.TempDir()
+	target := t.TempDir()
+	write := func(dir, content string) string {
+		path := filepath.Join(src, dir, "statement.txt")
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	first := write("a", "Statement of the first witness.\n")
+	second := write("b", "Statement of the second witness.\n")
+	copied := write("c", "Statement of the first witness.\n")
+
+	registry := NewRegistry(&TextProcessor{})
+	var docs []*Document
+	for _, path := range []string{first, second, copied} {
+		doc, err := ImportDocument(path, target, registry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+
+	tests := []struct {
+		name     string
+		a, b     *Document
+		wantSame bool
+	}{
+		{"same name, different content", docs[0], docs[1], false},
+		{"same content", docs[0], docs[2], true},
+	}
+	for _, tt := range tests {
+		if (tt.a.FilePath == tt.b.FilePath) != tt.wantSame || (tt.a.SHA256 == tt.b.SHA256) != tt.wantSame {
+			t.Errorf("%s: stored at %s and %s", tt.name, tt.a.FilePath, tt.b.FilePath)
+		}
+	}
+	for _, doc := range docs {
+		if !strings.HasPrefix(filepath.Base(doc.FilePath), doc.SHA256) || filepath.Base(filepath.Dir(doc.FilePath)) != doc.SHA256[:2] {
+			t.Errorf("%s stored at %s", doc.SourcePath, doc.FilePath)
+		}
+		data, err := os.ReadFile(doc.FilePath)
+		if err != nil || !strings.Contains(string(data), "witness") {
+			t.Errorf("stored copy of %s: %q, %v", doc.SourcePath, data, err)
+		}
+		if hash, _ := HashFile(doc.SourcePath); hash != doc.SHA256 {
+			t.Errorf("hash of %s = %s, want %s", doc.SourcePath, doc.SHA256, hash)
+		}
+	}
+	entries, _ := filepath.Glob(filepath.Join(target, storeDir, "*", "*"))
+	if len(entries) != 2 {
+		t.Errorf("store holds %v, want two files", entries)
+	}
+}
@@ -0,0 +1,449 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPartDepth limits the nesting of multipart bodies
+const maxPartDepth = 20
+
+// Address is a mailbox named in a message header
+type Address struct {
+	Name    string
+	Address string
+}
+
+// String formats the address as "Name <address>"
+func (a Address) String() string {
+	if a.Name == "" {
+		return a.Address
+	}
+	return fmt.Sprintf("%s <%s>", a.Name, a.Address)
+}
+
+// Header is a single header field, decoded
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Hop is one Received header: a server that handled the message
+type Hop struct {
+	From string // Sending host as reported, often with its IP address
+	By   string // Receiving host
+	With string // Protocol, e.g. ESMTPS
+	ID   string
+	For  string
+	Date time.Time
+	Raw  string
+}
+
+// Attachment is a file attached to or embedded in a message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool // Embedded in the HTML body rather than attached
+	Data        []byte
+}
+
+// Message is a parsed email message
+type Message struct {
+	Source      string // File of the message, or mailbox path and message number
+	MessageID   string
+	InReplyTo   string
+	Subject     string
+	Date        time.Time
+	From        []Address
+	To          []Address
+	Cc          []Address
+	Bcc         []Address
+	ReplyTo     []Address
+	Headers     []Header // In the order they appear
+	Received    []Hop    // Most recent hop first, as in the headers
+	Text        string
+	HTML        string
+	Attachments []Attachment
+	Warnings    []string // Parts that could not be decoded
+}
+
+// Participants returns every address in the From, To, Cc, Bcc and Reply-To headers
+func (m *Message) Participants() []Address {
+	var all []Address
+	for _, list := range [][]Address{m.From, m.To, m.Cc, m.Bcc, m.ReplyTo} {
+		all = append(all, list...)
+	}
+	return all
+}
+
+// Parse reads a single RFC 5322 message
+func Parse(r io.Reader) (*Message, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	headers, err := readHeaders(raw)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	m := &Message{Headers: headers}
+	h := msg.Header
+	m.MessageID = strings.Trim(h.Get("Message-Id"), "<> ")
+	m.InReplyTo = strings.Trim(h.Get("In-Reply-To"), "<> ")
+	m.Subject = decodeHeader(h.Get("Subject"))
+	if date := h.Get("Date"); date != "" {
+		if m.Date, err = parseDate(date); err != nil {
+			m.Warnings = append(m.Warnings, fmt.Sprintf("unreadable Date header %q", date))
+		}
+	}
+	m.From = addressList(h, "From", m)
+	m.To = addressList(h, "To", m)
+	m.Cc = addressList(h, "Cc", m)
+	m.Bcc = addressList(h, "Bcc", m)
+	m.ReplyTo = addressList(h, "Reply-To", m)
+	for _, value := range h["Received"] {
+		m.Received = append(m.Received, parseReceived(value))
+	}
+
+	p := &partWalker{msg: m}
+	p.walk(textproto.MIMEHeader(h), msg.Body, 0)
+	return m, nil
+}
+
+// readHeaders returns the header fields in order, which net/mail does not keep
+func readHeaders(raw []byte) ([]Header, error) {
+	var headers []Header
+	for len(raw) > 0 {
+		end := bytes.IndexByte(raw, '\n')
+		if end < 0 {
+			end = len(raw)
+		}
+		line := strings.TrimRight(string(raw[:end]), "\r")
+		raw = raw[min(end+1, len(raw)):]
+		if line == "" {
+			break
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			last := &headers[len(headers)-1]
+			last.Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			if len(headers) == 0 {
+				return nil, fmt.Errorf("not an email message: no header fields")
+			}
+			continue
+		}
+		headers = append(headers, Header{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("not an email message: no header fields")
+	}
+	for i := range headers {
+		headers[i].Value = decodeHeader(headers[i].Value)
+	}
+	return headers, nil
+}
+
+// addressList parses an address header, keeping what can be read when the
+// list is malformed
+func addressList(h mail.Header, name string, m *Message) []Address {
+	value := h.Get(name)
+	if value == "" {
+		return nil
+	}
+
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	list, err := parser.ParseList(value)
+	if err != nil {
+		// Fall back to each comma-separated part on its own
+		for _, part := range strings.Split(value, ",") {
+			if a, err := parser.Parse(part); err == nil {
+				list = append(list, a)
+			}
+		}
+		if len(list) == 0 {
+			m.Warnings = append(m.Warnings, fmt.Sprintf("unreadable %s header %q", name, value))
+		}
+	}
+
+	addresses := make([]Address, 0, len(list))
+	for _, a := range list {
+		addresses = append(addresses, Address{Name: a.Name, Address: strings.ToLower(a.Address)})
+	}
+	return addresses
+}
+
+// partWalker collects the bodies and attachments of a message
+type partWalker struct {
+	msg   *Message
+	parts int
+}
+
+// walk handles one MIME part, recursing into multipart bodies
+func (p *partWalker) walk(h textproto.MIMEHeader, body io.Reader, depth int) {
+	p.parts++
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxPartDepth {
+			p.warn("multipart nesting deeper than %d levels", maxPartDepth)
+			return
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				p.warn("malformed %s body: %v", mediaType, err)
+				return
+			}
+			p.walk(part.Header, part, depth+1)
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		p.warn("failed to decode %s part: %v", mediaType, err)
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	switch {
+	case disposition != "attachment" && filename == "" && mediaType == "text/plain":
+		p.msg.Text = joinBody(p.msg.Text, decodeCharset(params["charset"], data))
+	case disposition != "attachment" && filename == "" && mediaType == "text/html":
+		p.msg.HTML = joinBody(p.msg.HTML, decodeCharset(params["charset"], data))
+	default:
+		if filename == "" {
+			filename = p.defaultFilename(mediaType, data)
+		}
+		p.msg.Attachments = append(p.msg.Attachments, Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   strings.Trim(h.Get("Content-Id"), "<> "),
+			Inline:      disposition == "inline",
+			Data:        data,
+		})
+	}
+}
+
+// defaultFilename names an attachment that has no filename
+func (p *partWalker) defaultFilename(mediaType string, data []byte) string {
+	if mediaType == "message/rfc822" {
+		if inner, err := Parse(bytes.NewReader(data)); err == nil && inner.Subject != "" {
+			return inner.Subject + ".eml"
+		}
+		return fmt.Sprintf("message-%d.eml", p.parts)
+	}
+
+	ext := ".bin"
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		ext = exts[0]
+	}
+	return fmt.Sprintf("part-%d%s", p.parts, ext)
+}
+
+func (p *partWalker) warn(format string, args ...interface{}) {
+	p.msg.Warnings = append(p.msg.Warnings, fmt.Sprintf(format, args...))
+}
+
+// decodeTransfer undoes a Content-Transfer-Encoding
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Filter{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// base64Filter drops characters outside the base64 alphabet, which some mail
+// clients leave in encoded bodies
+type base64Filter struct {
+	r io.Reader
+}
+
+func (f *base64Filter) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		kept := 0
+		for _, c := range p[:n] {
+			if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' || c == '=' {
+				p[kept] = c
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+// joinBody appends a further body part of the same type
+func joinBody(body, part string) string {
+	if body == "" {
+		return part
+	}
+	return body + "\n" + part
+}
+
+// wordDecoder decodes RFC 2047 encoded words in the common charsets
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		if !knownCharset(charset) {
+			return nil, fmt.Errorf("unsupported charset %q", charset)
+		}
+		return strings.NewReader(decodeCharset(charset, data)), nil
+	},
+}
+
+// decodeHeader decodes encoded words, leaving the value as is if it cannot
+// be decoded
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// knownCharset reports whether decodeCharset handles a charset
+func knownCharset(charset string) bool {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii", "iso-8859-1", "latin1", "iso-8859-15", "windows-1252", "cp1252":
+		return true
+	}
+	return false
+}
+
+// decodeCharset converts text to UTF-8. Latin-1 and Windows-1252, common in
+// Spanish-language mail, are converted; other charsets are kept if they are
+// valid UTF-8 and otherwise read as Windows-1252.
+func decodeCharset(charset string, data []byte) string {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		if utf8.Valid(data) {
+			return string(data)
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range data {
+		if r, ok := cp1252[c]; ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// cp1252 maps the Windows-1252 bytes that differ from Latin-1
+var cp1252 = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž',
+	0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+// parseDate parses a Date or Received date, ignoring trailing comments
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	t, err := mail.ParseDate(value)
+	if err == nil {
+		return t, nil
+	}
+	if i := strings.Index(value, "("); i > 0 {
+		if t, err := mail.ParseDate(strings.TrimSpace(value[:i])); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// receivedClauses are the keywords of a Received header
+var receivedClauses = map[string]bool{"from": true, "by": true, "via": true, "with": true, "id": true, "for": true}
+
+// parseReceived splits a Received header into its clauses. Comments, which
+// usually hold the reverse DNS name and IP address, stay with their clause.
+func parseReceived(value string) Hop {
+	hop := Hop{Raw: value}
+	clauses := value
+	if i := strings.LastIndex(value, ";"); i >= 0 {
+		clauses = value[:i]
+		hop.Date, _ = parseDate(value[i+1:])
+	}
+
+	var keyword string
+	var words []string
+	flush := func() {
+		text := strings.Join(words, " ")
+		switch keyword {
+		case "from":
+			hop.From = text
+		case "by":
+			hop.By = text
+		case "with":
+			hop.With = text
+		case "id":
+			hop.ID = text
+		case "for":
+			hop.For = strings.Trim(text, "<>")
+		}
+		words = nil
+	}
+
+	depth := 0
+	for _, word := range strings.Fields(clauses) {
+		if depth == 0 && receivedClauses[strings.ToLower(word)] {
+			flush()
+			keyword = strings.ToLower(word)
+			continue
+		}
+		depth += strings.Count(word, "(") - strings.Count(word, ")")
+		if depth < 0 {
+			depth = 0
+		}
+		words = append(words, word)
+	}
+	flush()
+	return hop
+}
+
+// ErrNotEmail is returned for sources that are not EML, mbox or Maildir
+var ErrNotEmail = errors.New("not an email source")
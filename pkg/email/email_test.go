@@ -0,0 +1,118 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const multipartMessage = "Received: from mail.example.org (mail.example.org [192.0.2.10])\r\n" +
+	"\tby mx.example.com with ESMTPS id 4F2A for <ana@example.com>; Tue, 5 Mar 2024 10:15:30 +0100 (CET)\r\n" +
+	"From: =?ISO-8859-1?Q?Jos=E9_P=E9rez?= <Jose@Example.org>\r\n" +
+	"To: ana@example.com, broken@, \"Luis\" <luis@example.com>\r\n" +
+	"Subject: =?UTF-8?B?UmV1bmnDs24=?=\r\n" +
+	"Date: Tue, 5 Mar 2024 10:15:00 +0100 (CET)\r\n" +
+	"Message-ID: <abc@example.org>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Ma=F1ana a las 10.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Mañana</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"informe.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"informe.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBE\r\n" +
+	"Ri0x\r\n" +
+	"--outer--\r\n"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+		check   func(t *testing.T, m *Message)
+	}{
+		{
+			name: "multipart with attachment",
+			raw:  multipartMessage,
+			check: func(t *testing.T, m *Message) {
+				if m.Subject != "Reunión" || m.MessageID != "abc@example.org" {
+					t.Errorf("subject %q, id %q", m.Subject, m.MessageID)
+				}
+				if len(m.From) != 1 || m.From[0].String() != "José Pérez <jose@example.org>" {
+					t.Errorf("from %v", m.From)
+				}
+				if len(m.To) != 2 || m.To[1].Address != "luis@example.com" {
+					t.Errorf("to %v", m.To)
+				}
+				if strings.TrimSpace(m.Text) != "Mañana a las 10." || strings.TrimSpace(m.HTML) != "<p>Mañana</p>" {
+					t.Errorf("text %q, html %q", m.Text, m.HTML)
+				}
+				if len(m.Attachments) != 1 || m.Attachments[0].Filename != "informe.pdf" || string(m.Attachments[0].Data) != "%PDF-1" {
+					t.Errorf("attachments %+v", m.Attachments)
+				}
+				if !m.Date.Equal(time.Date(2024, 3, 5, 9, 15, 0, 0, time.UTC)) {
+					t.Errorf("date %v", m.Date)
+				}
+				if len(m.Received) != 1 {
+					t.Fatalf("received %+v", m.Received)
+				}
+				hop := m.Received[0]
+				if hop.By != "mx.example.com" || hop.With != "ESMTPS" || hop.For != "ana@example.com" || !strings.Contains(hop.From, "192.0.2.10") {
+					t.Errorf("hop %+v", hop)
+				}
+				if len(m.Headers) == 0 || m.Headers[0].Name != "Received" {
+					t.Errorf("headers out of order: %+v", m.Headers)
+				}
+			},
+		},
+		{
+			name: "unnamed parts and bad date",
+			raw: "From: a@example.com\nDate: someday\nContent-Type: multipart/mixed; boundary=b\n\n" +
+				"--b\nContent-Type: image/png\n\npng\n" +
+				"--b\nContent-Type: message/rfc822\n\nSubject: Forwarded\n\nbody\n--b--\n",
+			check: func(t *testing.T, m *Message) {
+				if len(m.Warnings) != 1 || !strings.Contains(m.Warnings[0], "Date") {
+					t.Errorf("warnings %v", m.Warnings)
+				}
+				if len(m.Attachments) != 2 || m.Attachments[0].Filename != "part-2.png" || m.Attachments[1].Filename != "Forwarded.eml" {
+					t.Errorf("attachments %+v", m.Attachments)
+				}
+			},
+		},
+		{
+			name: "windows-1252 body",
+			raw:  "From: a@example.com\nContent-Type: text/plain; charset=windows-1252\n\n\x93hola\x94 \x80\n",
+			check: func(t *testing.T, m *Message) {
+				if strings.TrimSpace(m.Text) != "“hola” €" {
+					t.Errorf("text %q", m.Text)
+				}
+			},
+		},
+		{name: "not a message", raw: "just some text\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(strings.NewReader(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, m)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Format identifies how messages are stored
+type Format string
+
+const (
+	FormatEML     Format = "EML"
+	FormatMbox    Format = "MBOX"
+	FormatMaildir Format = "MAILDIR"
+)
+
+// headerLine matches the first line of a message header
+var headerLine = regexp.MustCompile(`^[!-9;-~]+:`)
+
+// DetectFormat identifies an EML file, mbox file or Maildir directory
+func DetectFormat(path string) (Format, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get source info: %w", err)
+	}
+	if info.IsDir() {
+		if isMaildir(path) || len(maildirFolders(path)) > 0 {
+			return FormatMaildir, nil
+		}
+		return "", fmt.Errorf("%w: %s is not a Maildir", ErrNotEmail, path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open source: %w", err)
+	}
+	defer f.Close()
+
+	first, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read source: %w", err)
+	}
+	switch {
+	case strings.HasPrefix(first, "From "):
+		return FormatMbox, nil
+	case headerLine.MatchString(first):
+		return FormatEML, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotEmail, path)
+}
+
+// Walk parses every message in an EML file, mbox file or Maildir and calls fn
+// for each in order. Messages that cannot be parsed are passed with an error
+// and only their Source set; returning an error from fn stops the walk.
+func Walk(path string, fn func(m *Message, err error) error) (Format, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case FormatMbox:
+		err = walkMbox(path, fn)
+	case FormatMaildir:
+		err = walkMaildir(path, fn)
+	default:
+		err = walkFile(path, filepath.Base(path), fn)
+	}
+	return format, err
+}
+
+// walkFile parses a file holding a single message
+func walkFile(path, source string, fn func(*Message, error) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fn(&Message{Source: source}, fmt.Errorf("failed to read message: %w", err))
+	}
+	m, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return fn(&Message{Source: source}, err)
+	}
+	m.Source = source
+	return fn(m, nil)
+}
+
+// walkMbox splits an mbox file on its "From " separator lines. Quoted
+// ">From " lines in bodies are unescaped as in the mboxrd variant.
+func walkMbox(path string, fn func(*Message, error) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open mailbox: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 1<<16)
+	var buf bytes.Buffer
+	count := 0
+	inMessage := false
+	prevBlank := true
+
+	emit := func() error {
+		if !inMessage {
+			return nil
+		}
+		count++
+		source := fmt.Sprintf("%s#%d", filepath.Base(path), count)
+		m, err := Parse(bytes.NewReader(buf.Bytes()))
+		buf.Reset()
+		if err != nil {
+			return fn(&Message{Source: source}, err)
+		}
+		m.Source = source
+		return fn(m, nil)
+	}
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case prevBlank && bytes.HasPrefix(line, []byte("From ")):
+				if err := emit(); err != nil {
+					return err
+				}
+				inMessage = true
+			case inMessage:
+				if isQuotedFrom(line) {
+					line = line[1:]
+				}
+				buf.Write(line)
+			}
+			prevBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read mailbox: %w", err)
+		}
+	}
+	return emit()
+}
+
+// isQuotedFrom reports whether a line is a ">From " line escaped by the mailer
+func isQuotedFrom(line []byte) bool {
+	quoted := bytes.TrimLeft(line, ">")
+	return len(quoted) < len(line) && bytes.HasPrefix(quoted, []byte("From "))
+}
+
+// walkMaildir reads the cur and new directories of a Maildir and of its
+// Maildir++ sub-folders
+func walkMaildir(root string, fn func(*Message, error) error) error {
+	folders := []string{root}
+	folders = append(folders, maildirFolders(root)...)
+
+	for _, folder := range folders {
+		for _, sub := range []string{"cur", "new"} {
+			dir := filepath.Join(folder, sub)
+			entries, err := os.ReadDir(dir)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", dir, err)
+			}
+
+			sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+			for _, entry := range entries {
+				if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				path := filepath.Join(dir, entry.Name())
+				rel, _ := filepath.Rel(root, path)
+				if err := walkFile(path, filepath.ToSlash(rel), fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isMaildir reports whether a directory has the Maildir cur and new directories
+func isMaildir(dir string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// maildirFolders returns the Maildir++ sub-folders (".Sent", ".Archive.2023")
+func maildirFolders(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var folders []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") && isMaildir(path) {
+			folders = append(folders, path)
+		}
+	}
+	return folders
+}
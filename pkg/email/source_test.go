@@ -0,0 +1,117 @@
+package email
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files under a new directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestWalk(t *testing.T) {
+	mbox := "From ana@example.com Tue Mar  5 10:15:00 2024\n" +
+		"From: ana@example.com\nSubject: First\n\nhello\n>From the start\n\n" +
+		"From luis@example.com Tue Mar  5 11:00:00 2024\n" +
+		"not a header\n\n" +
+		"From luis@example.com Tue Mar  5 12:00:00 2024\n" +
+		"From: luis@example.com\nSubject: Third\n\nbye\n"
+	dir := writeFiles(t, map[string]string{
+		"single.eml":                   "From: ana@example.com\nSubject: Only\n\nbody\n",
+		"inbox.mbox":                   mbox,
+		"notes.txt":                    "shopping list\n",
+		"Maildir/cur/1.host:2,S":       "Subject: Read\n\nx\n",
+		"Maildir/new/2.host":           "Subject: New\n\nx\n",
+		"Maildir/tmp/3.host":           "Subject: Partial\n\nx\n",
+		"Maildir/.Sent/cur/4.host:2,S": "Subject: Sent\n\nx\n",
+		"Maildir/.Sent/new/.hidden":    "Subject: Hidden\n\nx\n",
+		"empty/placeholder":            "",
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		wantFormat  Format
+		wantSubject []string // "!" marks a message that failed to parse
+		wantSources []string
+		wantErr     error
+	}{
+		{"eml", "single.eml", FormatEML, []string{"Only"}, []string{"single.eml"}, nil},
+		{"mbox", "inbox.mbox", FormatMbox, []string{"First", "!", "Third"}, []string{"inbox.mbox#1", "inbox.mbox#2", "inbox.mbox#3"}, nil},
+		{"maildir", "Maildir", FormatMaildir, []string{"Read", "New", "Sent"}, []string{"cur/1.host:2,S", "new/2.host", ".Sent/cur/4.host:2,S"}, nil},
+		{"not email", "notes.txt", "", nil, nil, ErrNotEmail},
+		{"not a maildir", "empty", "", nil, nil, ErrNotEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subjects, sources []string
+			format, err := Walk(filepath.Join(dir, tt.path), func(m *Message, err error) error {
+				if err != nil {
+					subjects = append(subjects, "!")
+				} else {
+					subjects = append(subjects, m.Subject)
+				}
+				sources = append(sources, m.Source)
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if strings.Join(subjects, "|") != strings.Join(tt.wantSubject, "|") {
+				t.Errorf("subjects = %v, want %v", subjects, tt.wantSubject)
+			}
+			if strings.Join(sources, "|") != strings.Join(tt.wantSources, "|") {
+				t.Errorf("sources = %v, want %v", sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestWalkMboxUnquotesFrom(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"quoted.mbox": "From a Tue Mar  5 10:15:00 2024\nSubject: Q\n\n>From here\n>>From there\n",
+	})
+	var text string
+	if _, err := Walk(filepath.Join(dir, "quoted.mbox"), func(m *Message, err error) error {
+		text = m.Text
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if text != "From here\n>From there\n" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestWalkStops(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"inbox.mbox": "From a Tue Mar  5 10:15:00 2024\nSubject: 1\n\nx\n\nFrom b Tue Mar  5 10:15:00 2024\nSubject: 2\n\ny\n",
+	})
+	stop := errors.New("stop")
+	calls := 0
+	_, err := Walk(filepath.Join(dir, "inbox.mbox"), func(*Message, error) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("err = %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,426 @@
+// Package entity finds the people, phone numbers, email addresses, street
+// addresses, vehicles, license plates, weapons, dates and amounts of money
+// mentioned in English and Spanish text, with their positions, so that they
+// can be turned into case records.
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/pii"
+)
+
+// Kind identifies the type of an entity
+type Kind string
+
+const (
+	KindPerson  Kind = "PERSON"
+	KindPhone   Kind = "PHONE"
+	KindEmail   Kind = "EMAIL"
+	KindAddress Kind = "ADDRESS"
+	KindVehicle Kind = "VEHICLE"
+	KindPlate   Kind = "PLATE"
+	KindWeapon  Kind = "WEAPON"
+	KindDate    Kind = "DATE"
+	KindMoney   Kind = "MONEY"
+)
+
+// Kinds lists every kind of entity, in the order they are reported
+var Kinds = []Kind{KindPerson, KindPhone, KindEmail, KindAddress, KindVehicle, KindPlate, KindWeapon, KindDate, KindMoney}
+
+// Entity is a mention of an entity in text
+type Entity struct {
+	ID         string // E1, E2... in order of position
+	Kind       Kind
+	Text       string
+	Start      int // Byte offsets of the text
+	End        int
+	Value      string    // Normalized form, e.g. digits of a phone number or an ISO date
+	Time       time.Time // Date and time of DATE entities
+	Confidence float64   // 0 to 1
+}
+
+// nameWord is a capitalized word of a name, in English or Spanish
+const nameWord = `[A-ZÁÉÍÓÚÑÜ][a-záéíóúñü]*(?:['’-]?[A-ZÁÉÍÓÚÑÜ]?[a-záéíóúñü]+)+`
+
+// name is a sequence of capitalized words, allowing Spanish particles
+const name = nameWord + `(?:\s+(?:(?:de|del|de\s+la|de\s+los|de\s+las|y)\s+)?` + nameWord + `){0,4}`
+
+var (
+	honorificPattern = regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Miss|Dr|Det|Detective|Officer|Ofc|Sgt|Sergeant|Lt|Lieutenant|Capt|Captain|Agent|Sr|Sra|Srta|Señor|Señora|Señorita|Don|Doña|Lic|Licenciad[oa]|Ing|Comisario|Inspector|Fiscal)\.?\s+(` + name + `)`)
+	labelPattern     = regexp.MustCompile(`(?i:\b(?:name|full\s+name|nombres?|nombres\s+y\s+apellidos|victim|v[ií]ctima|witness|testigo|suspect|sospechoso|imputad[oa]|complainant|denunciante))\s*:\s*(` + name + `)`)
+	namePattern      = regexp.MustCompile(name)
+	emailPattern     = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)
+	vehiclePattern   = regexp.MustCompile(`\b(?:(?:19|20)\d{2}\s+)?(?:Toyota|Ford|Chevrolet|Chevy|Honda|Nissan|Hyundai|Kia|Jeep|Dodge|GMC|Mitsubishi|Volkswagen|VW|BMW|Mercedes(?:-Benz)?|Audi|Mazda|Subaru|Fiat|Renault|Peugeot|Chery|Tesla|Lexus|Buick|Cadillac|Chrysler|Suzuki|Yamaha|Harley-Davidson)\b(?:\s+(?:[A-Z][A-Za-z0-9-]*|\d{1,4}[A-Za-z]*))?`)
+	weaponPattern    = regexp.MustCompile(`(?i)(?:(?:\.\d{2,3}|\b\d{1,2}\s?mm)(?:\s?(?:caliber|calibre|cal\.?))?\s+)?(?:\b(?:Glock|Beretta|Colt|Ruger|Smith\s*&\s*Wesson|Sig\s*Sauer|Taurus|Remington|Mossberg|Winchester|Browning)(?:\s+\d{1,3}[A-Za-z]?)?\s+)?\b(?:handguns?|pistols?|revolvers?|rifles?|shotguns?|firearms?|guns?|knife|knives|machetes?|AK-?47|AR-?15|pistolas?|rev[oó]lver(?:es)?|escopetas?|fusil(?:es)?|cuchillos?|navajas?|machetes?|armas?\s+(?:de\s+fuego|blancas?))\b|\b(?:Glock|Beretta|Ruger|Sig\s*Sauer|Taurus)\s+\d{1,3}[A-Za-z]?\b`)
+	moneyPattern     = regexp.MustCompile(`(?i)(?:US\$|\$|USD|€|EUR|Bs\.?\s?S\.?|Bs\.?\s?D\.?|Bs\.?|VES|VEF)\s?\d(?:[\d.,]*\d)?(?:\s+(?:million|millones|mil))?|\b\d[\d.,]*(?:\s+(?:million|millones|mil))?\s+(?:dollars|d[oó]lares|bol[ií]vares|euros|USD|EUR|VES)\b`)
+)
+
+// months maps English and Spanish month names and abbreviations to months
+var months = map[string]time.Month{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	"enero": 1, "febrero": 2, "marzo": 3, "abril": 4, "mayo": 5, "junio": 6, "julio": 7,
+	"agosto": 8, "septiembre": 9, "setiembre": 9, "octubre": 10, "noviembre": 11, "diciembre": 12,
+}
+
+// clock is an optional time of day following a date
+const clock = `(,?\s+(?:at\s+|a\s+las?\s+)?(\d{1,2}):(\d{2})(?:\s*(am|pm|a\.\s?m\.|p\.\s?m\.)|\s*(?:hrs|horas|h)\b)?)?`
+
+var (
+	isoDatePattern     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})` + clock)
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})[/.-](\d{1,2})[/.-](\d{4}|\d{2})\b` + clock)
+	monthDayPattern    = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})` + clock)
+	dayMonthPattern    = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:de\s+)?(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec|enero|febrero|marzo|abril|mayo|junio|julio|agosto|septiembre|setiembre|octubre|noviembre|diciembre)[a-z]*\.?,?\s+(?:de(?:l)?\s+)?(\d{4})` + clock)
+)
+
+// leadingWords are capitalized words that start sentences rather than names
+var leadingWords = wordSet(`The A An This That These Those On In At Of To From By For With And But Or If When While After Before
+During Then Yesterday Today Tomorrow Later He She They We I You It His Her Their Our My Its There Here Also However Once
+Mr Mrs Ms Miss Dr Det Detective Officer Sgt Sergeant Lt Lieutenant Capt Captain Agent
+El La Los Las Un Una Unos Unas En Con Por Para Según Desde Hasta Cuando Luego Después Antes Ayer Hoy Mañana Él Ella Ellos Nosotros Yo Su Sus Mi Se Y O Pero
+Sr Sra Srta Señor Señora Señorita Don Doña Lic Licenciado Licenciada Ing Comisario Inspector Fiscal
+Monday Tuesday Wednesday Thursday Friday Saturday Sunday Lunes Martes Miércoles Jueves Viernes Sábado Domingo
+January February March April May June July August September October November December
+Enero Febrero Marzo Abril Mayo Junio Julio Agosto Septiembre Setiembre Octubre Noviembre Diciembre
+Victim Witness Suspect Name Víctima Testigo Sospechoso Nombre Statement Report Case Page`)
+
+// orgWords mark capitalized phrases that name places and organizations
+var orgWords = wordSet(`Street St Avenue Ave Road Rd Boulevard Blvd Lane Drive Court Ct Way Place Highway County City State
+Police Department Dept Office Bureau Agency Division Unit Bank Hospital Clinic University College School Church Hotel
+Inc LLC Ltd Corp Corporation Company Co Group Center Centre Store Market Airport Station Park Mall United States America
+Calle Avenida Av Carrera Urbanización Urb Edificio Edif Sector Barrio Policía Fiscalía Ministerio Tribunal Juzgado
+Banco Hospital Clínica Universidad Colegio Escuela Iglesia Empresa Compañía Centro Tienda Aeropuerto Estación Parque
+Venezuela Caracas Maracaibo Valencia Miranda Zulia Carabobo República Bolivariana Nacional Estado Municipio Parroquia
+Toyota Ford Chevrolet Honda Nissan Hyundai Kia Jeep Dodge Mitsubishi Volkswagen Mercedes Mazda Glock Beretta`)
+
+// wordSet splits a list of words into a set
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// Extractor finds entities in text
+type Extractor struct {
+	// DayFirst reads numeric dates as day/month/year where they are
+	// ambiguous; when false it is decided by the language of the text
+	DayFirst bool
+	pii      *pii.Detector
+}
+
+// NewExtractor creates an entity extractor
+func NewExtractor() *Extractor {
+	return &Extractor{pii: pii.NewDetector(nil)}
+}
+
+// Extract returns the entities mentioned in text, in order of position and
+// numbered E1, E2... Where mentions overlap only the most confident is kept.
+func (x *Extractor) Extract(text string) []Entity {
+	var found []Entity
+	add := func(kind Kind, start, end int, value string, confidence float64) {
+		found = append(found, Entity{Kind: kind, Text: text[start:end], Start: start, End: end, Value: value, Confidence: confidence})
+	}
+
+	for _, f := range x.pii.Detect(text) {
+		switch f.Kind {
+		case pii.KindPhone:
+			add(KindPhone, f.Start, f.End, phoneValue(f.Text), f.Confidence)
+		case pii.KindAddress:
+			add(KindAddress, f.Start, f.End, strings.Join(strings.Fields(f.Text), " "), f.Confidence)
+		case pii.KindLicensePlate:
+			add(KindPlate, f.Start, f.End, strings.NewReplacer(" ", "", "-", "").Replace(f.Text), f.Confidence)
+		}
+	}
+	for _, m := range emailPattern.FindAllStringIndex(text, -1) {
+		add(KindEmail, m[0], m[1], strings.ToLower(text[m[0]:m[1]]), 0.95)
+	}
+	for _, m := range vehiclePattern.FindAllStringIndex(text, -1) {
+		add(KindVehicle, m[0], m[1], text[m[0]:m[1]], 0.85)
+	}
+	for _, m := range weaponPattern.FindAllStringIndex(text, -1) {
+		add(KindWeapon, m[0], m[1], strings.ToLower(strings.Join(strings.Fields(text[m[0]:m[1]]), " ")), 0.8)
+	}
+	for _, m := range moneyPattern.FindAllStringIndex(text, -1) {
+		if value, ok := moneyValue(text[m[0]:m[1]]); ok {
+			add(KindMoney, m[0], m[1], value, 0.9)
+		}
+	}
+	found = append(found, x.dates(text)...)
+	found = append(found, persons(text)...)
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Confidence != found[j].Confidence {
+			return found[i].Confidence > found[j].Confidence
+		}
+		return found[i].End-found[i].Start > found[j].End-found[j].Start
+	})
+	var kept []Entity
+	for _, e := range found {
+		overlaps := false
+		for _, k := range kept {
+			if e.Start < k.End && k.Start < e.End {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, e)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Start < kept[j].Start })
+	for i := range kept {
+		kept[i].ID = fmt.Sprintf("E%d", i+1)
+	}
+	return kept
+}
+
+// Sentence returns the sentence of text holding a span, on one line
+func Sentence(text string, start, end int) string {
+	from := strings.LastIndexAny(text[:start], ".!?\n") + 1
+	to := len(text)
+	if i := strings.IndexAny(text[end:], ".!?\n"); i >= 0 {
+		to = end + i + 1
+	}
+	return strings.Join(strings.Fields(text[from:to]), " ")
+}
+
+// persons finds names introduced by a title or a label, and capitalized
+// phrases that look like names
+func persons(text string) []Entity {
+	var found []Entity
+	for _, p := range []struct {
+		re         *regexp.Regexp
+		group      int
+		confidence float64
+	}{
+		{honorificPattern, 1, 0.9},
+		{labelPattern, 1, 0.9},
+		{namePattern, 0, 0.6},
+	} {
+		for _, m := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end, ok := trimName(text, m[2*p.group], m[2*p.group+1])
+			if !ok {
+				continue
+			}
+			// A name starts a word
+			if r, _ := utf8.DecodeLastRuneInString(text[:m[0]]); m[0] > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				continue
+			}
+			// Names found without a title or label need a first and last name
+			if p.group == 0 && len(strings.Fields(text[start:end])) < 2 {
+				continue
+			}
+			value := strings.Join(strings.Fields(text[start:end]), " ")
+			found = append(found, Entity{Kind: KindPerson, Text: text[start:end], Start: start, End: end, Value: value, Confidence: p.confidence})
+		}
+	}
+	return found
+}
+
+// trimName drops leading and trailing words that are not part of a name,
+// rejecting names of places and organizations
+func trimName(text string, start, end int) (int, int, bool) {
+	type word struct{ start, end int }
+	var words []word
+	for i := start; i < end; {
+		for i < end && isSpace(text[i]) {
+			i++
+		}
+		j := i
+		for j < end && !isSpace(text[j]) {
+			j++
+		}
+		if i < j {
+			words = append(words, word{i, j})
+		}
+		i = j
+	}
+	for _, w := range words {
+		if orgWords[text[w.start:w.end]] {
+			return 0, 0, false
+		}
+	}
+	isName := func(w word) bool {
+		r, _ := utf8.DecodeRuneInString(text[w.start:])
+		return unicode.IsUpper(r) && !leadingWords[text[w.start:w.end]]
+	}
+	for len(words) > 0 && !isName(words[0]) {
+		words = words[1:]
+	}
+	for len(words) > 0 && !isName(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return 0, 0, false
+	}
+	return words[0].start, words[len(words)-1].end, true
+}
+
+// isSpace reports whether a byte is ASCII whitespace
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// dates finds dates, with their time of day where given
+func (x *Extractor) dates(text string) []Entity {
+	dayFirst := x.DayFirst || spanish(text)
+	var found []Entity
+	for _, p := range []*regexp.Regexp{isoDatePattern, numericDatePattern, monthDayPattern, dayMonthPattern} {
+		for _, m := range p.FindAllStringSubmatchIndex(text, -1) {
+			group := func(i int) string {
+				if m[2*i] < 0 {
+					return ""
+				}
+				return text[m[2*i]:m[2*i+1]]
+			}
+			var year, month, day int
+			switch p {
+			case isoDatePattern:
+				year, month, day = atoi(group(1)), atoi(group(2)), atoi(group(3))
+			case numericDatePattern:
+				a, b := atoi(group(1)), atoi(group(2))
+				year = atoi(group(3))
+				if len(group(3)) == 2 {
+					year += 1900
+					if year < 1950 {
+						year += 100
+					}
+				}
+				month, day = a, b
+				if a > 12 || (dayFirst && b <= 12) {
+					month, day = b, a
+				}
+			case monthDayPattern:
+				month, day, year = int(months[strings.ToLower(group(1))]), atoi(group(2)), atoi(group(3))
+			case dayMonthPattern:
+				day, month, year = atoi(group(1)), int(months[strings.ToLower(group(2))]), atoi(group(3))
+			}
+			t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+			if year < 1900 || year > 2100 || month < 1 || month > 12 || day < 1 || t.Day() != day {
+				continue
+			}
+
+			value := t.Format("2006-01-02")
+			end := m[1]
+			if hour := group(5); hour != "" {
+				h, min := atoi(hour), atoi(group(6))
+				switch meridiem := strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(group(7))); {
+				case meridiem == "pm" && h < 12:
+					h += 12
+				case meridiem == "am" && h == 12:
+					h = 0
+				}
+				if h < 24 && min < 60 {
+					t = t.Add(time.Duration(h)*time.Hour + time.Duration(min)*time.Minute)
+					value = t.Format("2006-01-02 15:04")
+				} else {
+					end = m[2*4] // Not a time of day; leave it out
+				}
+			}
+			end = m[0] + len(strings.TrimRight(text[m[0]:end], " ,"))
+			found = append(found, Entity{Kind: KindDate, Text: text[m[0]:end], Start: m[0], End: end, Value: value, Time: t, Confidence: 0.9})
+		}
+	}
+	return found
+}
+
+// spanish reports whether text reads as Spanish rather than English, by
+// its most common words
+func spanish(text string) bool {
+	score := 0
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		switch w {
+		case "el", "la", "los", "las", "de", "del", "que", "y", "en", "por", "con", "una":
+			score++
+		case "the", "of", "and", "to", "in", "was", "that", "with", "on", "a":
+			score--
+		}
+	}
+	return score > 0
+}
+
+// phoneValue returns the digits of a phone number, keeping a leading plus
+func phoneValue(s string) string {
+	var b strings.Builder
+	if strings.HasPrefix(s, "+") {
+		b.WriteByte('+')
+	}
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// currencies maps currency symbols and words to ISO 4217 codes
+var currencies = []struct{ prefix, code string }{
+	{"us$", "USD"}, {"$", "USD"}, {"usd", "USD"}, {"dollars", "USD"}, {"dólares", "USD"}, {"dolares", "USD"},
+	{"€", "EUR"}, {"eur", "EUR"}, {"euros", "EUR"},
+	{"bs", "VES"}, {"ves", "VES"}, {"vef", "VES"}, {"bolívares", "VES"}, {"bolivares", "VES"},
+}
+
+// moneyValue normalizes an amount to its currency code and value, e.g.
+// "USD 1500.00"
+func moneyValue(s string) (string, bool) {
+	lower := strings.ToLower(s)
+	code := ""
+	for _, c := range currencies {
+		if strings.HasPrefix(lower, c.prefix) || strings.HasSuffix(lower, c.prefix) {
+			code = c.code
+			break
+		}
+	}
+	i := strings.IndexFunc(s, func(r rune) bool { return r >= '0' && r <= '9' })
+	if code == "" || i < 0 {
+		return "", false
+	}
+	j := i
+	for j < len(s) && strings.ContainsRune("0123456789.,", rune(s[j])) {
+		j++
+	}
+	number := strings.TrimRight(s[i:j], ".,")
+
+	// The last separator is the decimal point when followed by one or two
+	// digits; any others group thousands
+	decimal := strings.LastIndexAny(number, ".,")
+	if decimal >= 0 && len(number)-decimal-1 > 2 {
+		decimal = -1
+	}
+	var b strings.Builder
+	for k, r := range number {
+		switch {
+		case k == decimal:
+			b.WriteByte('.')
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+	amount, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return "", false
+	}
+	switch rest := strings.ToLower(s[j:]); {
+	case strings.Contains(rest, "million") || strings.Contains(rest, "millones"):
+		amount *= 1e6
+	case strings.Contains(rest, "mil"):
+		amount *= 1e3
+	}
+	return fmt.Sprintf("%s %.2f", code, amount), true
+}
+
+// atoi converts digits to a number, 0 if there are none
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,127 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// summary lists entities as "KIND text=value"
+func summary(entities []Entity) []string {
+	var s []string
+	for _, e := range entities {
+		s = append(s, fmt.Sprintf("%s %s=%s", e.Kind, e.Text, e.Value))
+	}
+	return s
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		dayFirst bool
+		want     []string
+	}{
+		{
+			name: "English statement",
+			text: "On March 5, 2024 at 9:30 pm Detective Laura Gómez interviewed the witness John Smith at 1234 Main Street. " +
+				"He drove a 2019 Toyota Corolla with plate ABC-1234 and carried a Glock 19 pistol. " +
+				"Call (555) 123-4567 or write to JSmith@Example.com. He paid $1,500.00 in cash.",
+			want: []string{
+				"DATE March 5, 2024 at 9:30 pm=2024-03-05 21:30",
+				"PERSON Laura Gómez=Laura Gómez",
+				"PERSON John Smith=John Smith",
+				"ADDRESS 1234 Main Street=1234 Main Street",
+				"VEHICLE 2019 Toyota Corolla=2019 Toyota Corolla",
+				"PLATE ABC-1234=ABC1234",
+				"WEAPON Glock 19 pistol=glock 19 pistol",
+				"PHONE (555) 123-4567=5551234567",
+				"EMAIL JSmith@Example.com=jsmith@example.com",
+				"MONEY $1,500.00=USD 1500.00",
+			},
+		},
+		{
+			name: "Spanish statement",
+			text: "El 05/03/2024 a las 14:30 horas la víctima: María de los Ángeles Pérez denunció el robo de Bs. 2.500,50 y 300 dólares. " +
+				"El sospechoso portaba un arma de fuego.",
+			want: []string{
+				"DATE 05/03/2024 a las 14:30 horas=2024-03-05 14:30",
+				"PERSON María de los Ángeles Pérez=María de los Ángeles Pérez",
+				"MONEY Bs. 2.500,50=VES 2500.50",
+				"MONEY 300 dólares=USD 300.00",
+				"WEAPON arma de fuego=arma de fuego",
+			},
+		},
+		{
+			name: "organizations and places are not people",
+			text: "Officer Smith called Bank of America and the Main Street Police Department.",
+			want: []string{"PERSON Smith=Smith"},
+		},
+		{
+			name: "numeric dates",
+			text: "Seen 03/05/2024 and 13/05/24. Not dates: 2024-02-30, 31/04/2024. Meeting 2024-03-05 25:99.",
+			want: []string{
+				"DATE 03/05/2024=2024-03-05",
+				"DATE 13/05/24=2024-05-13",
+				"DATE 2024-03-05=2024-03-05",
+			},
+		},
+		{
+			name:     "day first",
+			text:     "Seen 03/05/2024.",
+			dayFirst: true,
+			want:     []string{"DATE 03/05/2024=2024-05-03"},
+		},
+		{
+			name: "day month year",
+			text: "Hechos del 5 de marzo de 2024 y del 1st Jan 2023, 12:15 am.",
+			want: []string{
+				"DATE 5 de marzo de 2024=2024-03-05",
+				"DATE 1st Jan 2023, 12:15 am=2023-01-01 00:15",
+			},
+		},
+		{
+			name: "amounts",
+			text: "Sums of EUR 1.250, US$ 20 and 2,5 millones bolívares.",
+			want: []string{
+				"MONEY EUR 1.250=EUR 1250.00",
+				"MONEY US$ 20=USD 20.00",
+				"MONEY 2,5 millones bolívares=VES 2500000.00",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewExtractor()
+			x.DayFirst = tt.dayFirst
+			entities := x.Extract(tt.text)
+			if got := summary(entities); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entities:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for i, e := range entities {
+				if e.ID != fmt.Sprintf("E%d", i+1) || tt.text[e.Start:e.End] != e.Text {
+					t.Errorf("entity %+v has the wrong ID or position", e)
+				}
+			}
+		})
+	}
+}
+
+func TestSentence(t *testing.T) {
+	text := "First sentence. The suspect fled\nnorth at 9:30! Last one"
+	tests := []struct {
+		span string
+		want string
+	}{
+		{"suspect", "The suspect fled"},
+		{"north", "north at 9:30!"},
+		{"First", "First sentence."},
+		{"Last", "Last one"},
+	}
+	for _, tt := range tests {
+		start := strings.Index(text, tt.span)
+		if got := Sentence(text, start, start+len(tt.span)); got != tt.want {
+			t.Errorf("Sentence(%q) = %q, want %q", tt.span, got, tt.want)
+		}
+	}
+}
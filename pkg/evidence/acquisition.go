@@ -0,0 +1,387 @@
+package evidence
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+)
+
+// AcquisitionType identifies the kind of source that was acquired
+type AcquisitionType string
+
+const (
+	AcquisitionDirectory AcquisitionType = "DIRECTORY"
+	AcquisitionRawImage  AcquisitionType = "RAW_IMAGE"
+)
+
+// rawImageExtensions lists file extensions treated as raw disk images
+var rawImageExtensions = []string{".dd", ".img", ".raw", ".001"}
+
+// imageBlockSize is the size of the byte runs hashed individually in a raw image
+const imageBlockSize = 64 * 1024 * 1024
+
+// AcquiredFile describes a single file captured during an acquisition
+type AcquiredFile struct {
+	EvidenceID   string // ID of the child evidence item created for the file
+	RelativePath string // Path relative to the acquisition root, slash separated
+	Size         int64
+	ModifiedTime time.Time
+	AccessTime   time.Time
+	ChangeTime   time.Time
+	Mode         fs.FileMode
+	FileType     *filetype.Result // Content-based type, only recorded for directory acquisitions
+	MD5          string
+	SHA1         string
+	SHA256       string
+	ByteRuns     []ByteRun // Per-block hashes, only recorded for raw images
+}
+
+// ByteRun describes a hashed region of a raw image
+type ByteRun struct {
+	Offset int64
+	Length int64
+	SHA256 string
+}
+
+// Acquisition is the result of acquiring a directory tree or raw image
+type Acquisition struct {
+	ID           string
+	EvidenceID   string // ID of the parent evidence item
+	Type         AcquisitionType
+	SourcePath   string
+	ManifestPath string
+	AcquiredBy   string
+	StartedAt    time.Time
+	CompletedAt  time.Time
+	Files        []AcquiredFile
+	Errors       []string // Files that could not be read
+}
+
+// IsRawImage reports whether a path looks like a raw disk image
+func IsRawImage(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range rawImageExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// AcquireSource acquires a directory tree or raw image as a single evidence
+// item and writes a DFXML manifest to manifestPath. For a directory a child
+// evidence item is created for every file. The file systems inside a raw
+// image are not read: the image becomes one child item, hashed in byte runs,
+// and its files are not itemized. The parent item's hash is the SHA-256 of
+// the manifest so that later tampering with the manifest is detectable.
+func (s *EvidenceService) AcquireSource(parent *DigitalEvidence, sourcePath, manifestPath string) (*Acquisition, error) {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source info: %w", err)
+	}
+
+	acq := &Acquisition{
+		ID:           generateID("ACQ"),
+		SourcePath:   sourcePath,
+		ManifestPath: manifestPath,
+		AcquiredBy:   parent.CollectedBy,
+		StartedAt:    time.Now(),
+	}
+
+	switch {
+	case info.IsDir():
+		acq.Type = AcquisitionDirectory
+		if err := acquireDirectory(acq, sourcePath); err != nil {
+			return nil, err
+		}
+	case IsRawImage(sourcePath):
+		acq.Type = AcquisitionRawImage
+		file, err := acquireFile(sourcePath, filepath.Base(sourcePath), true)
+		if err != nil {
+			return nil, err
+		}
+		acq.Files = append(acq.Files, *file)
+	default:
+		return nil, fmt.Errorf("unsupported acquisition source: %s (expected a directory or raw image)", sourcePath)
+	}
+	acq.CompletedAt = time.Now()
+
+	// Assign the parent's ID first so the children can reference it. The
+	// parent is saved last, once its hash (that of the manifest) is known;
+	// if anything fails before then the children are removed again.
+	if parent.ID == "" {
+		parent.ID = generateID("EV")
+	}
+	acq.EvidenceID = parent.ID
+	var children []string
+	fail := func(err error) (*Acquisition, error) {
+		s.discardEvidence(children)
+		return nil, err
+	}
+
+	for i := range acq.Files {
+		f := &acq.Files[i]
+		child := &Evidence{
+			CaseID:           parent.CaseID,
+			EvidenceNumber:   childEvidenceNumber(parent.EvidenceNumber, i+1),
+			Description:      fmt.Sprintf("%s (from %s)", f.RelativePath, acquisitionLabel(parent)),
+			Type:             TypeDigital,
+			CollectedBy:      parent.CollectedBy,
+			CollectionDate:   parent.CollectionDate,
+			CollectionMethod: fmt.Sprintf("Acquired as part of %s", acq.ID),
+			Location:         parent.Location,
+			StorageLocation:  parent.StorageLocation,
+			RelatedEvidence:  []string{parent.ID},
+			FileHash:         f.SHA256,
+			IsConfidential:   parent.IsConfidential,
+			DerivedFrom: &Derivation{
+				ParentID:   parent.ID,
+				Relation:   RelationExtractedFrom,
+				Tool:       fmt.Sprintf("%s acquisition %s", strings.ToLower(string(acq.Type)), acq.ID),
+				Operator:   parent.CollectedBy,
+				Timestamp:  acq.CompletedAt,
+				Hash:       f.SHA256,
+				SourcePath: f.RelativePath,
+			},
+		}
+		if f.FileType != nil && f.FileType.ExtensionMismatch {
+			markExtensionMismatch(child, f.FileType)
+		}
+		if err := s.classifyKnownFile(child, f.MD5, f.SHA1); err != nil {
+			return fail(err)
+		}
+		if err := s.CreateEvidence(child); err != nil {
+			return fail(fmt.Errorf("failed to create evidence for %s: %w", f.RelativePath, err))
+		}
+		children = append(children, child.ID)
+		f.EvidenceID = child.ID
+		parent.RelatedEvidence = append(parent.RelatedEvidence, child.ID)
+	}
+
+	if err := WriteManifestFile(manifestPath, acq); err != nil {
+		os.Remove(manifestPath)
+		return fail(err)
+	}
+
+	manifestHash, err := calculateFileHash(manifestPath)
+	if err != nil {
+		os.Remove(manifestPath)
+		return fail(fmt.Errorf("failed to hash manifest: %w", err))
+	}
+
+	parent.Type = TypeDigital
+	parent.FilePath = sourcePath
+	parent.FileSize = totalSize(acq.Files)
+	parent.FileType = string(acq.Type)
+	parent.FileHash = manifestHash
+	parent.OriginalHash = manifestHash
+	parent.ManifestPath = manifestPath
+	if parent.CollectionMethod == "" {
+		parent.CollectionMethod = fmt.Sprintf("%s acquisition", strings.ToLower(string(acq.Type)))
+	}
+
+	if err := s.CreateEvidence(&parent.Evidence); err != nil {
+		os.Remove(manifestPath)
+		return fail(err)
+	}
+	if err := s.saveDigital(parent); err != nil {
+		s.repo.Delete(parent.ID)
+		os.Remove(manifestPath)
+		return fail(err)
+	}
+
+	return acq, nil
+}
+
+// discardEvidence removes the items created by an acquisition that failed
+func (s *EvidenceService) discardEvidence(ids []string) {
+	for _, id := range ids {
+		s.repo.Delete(id)
+	}
+}
+
+// VerifyAcquisition checks a DFXML manifest against the SHA-256 recorded for
+// its evidence item, then re-hashes the acquired source against the manifest
+// and reports every file that no longer matches. manifestHash is the hash
+// printed at acquisition, used when the evidence item is not on record; the
+// recorded hash takes precedence.
+func (s *EvidenceService) VerifyAcquisition(manifestPath, sourcePath, manifestHash string) (*VerificationReport, error) {
+	acq, err := ReadManifestFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := strings.ToLower(strings.TrimSpace(manifestHash))
+	if parent, err := s.repo.Find(acq.EvidenceID); err == nil && parent.FileHash != "" {
+		expected = parent.FileHash
+	}
+	if expected == "" {
+		return nil, fmt.Errorf("no manifest hash on record for evidence %s; give the SHA-256 printed at acquisition", acq.EvidenceID)
+	}
+	actual, err := calculateFileHash(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash manifest: %w", err)
+	}
+
+	if sourcePath == "" {
+		sourcePath = acq.SourcePath
+	}
+
+	report, err := VerifyManifest(acq, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	report.ManifestSHA256 = actual
+	report.ExpectedManifestSHA256 = expected
+	return report, nil
+}
+
+// acquireDirectory walks a directory tree and records every regular file
+func acquireDirectory(acq *Acquisition, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			acq.Errors = append(acq.Errors, fmt.Sprintf("%s: %v", path, err))
+			return nil
+		}
+
+		// Symlinks and special files are not followed to keep the acquisition
+		// confined to the source tree
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve relative path: %w", err)
+		}
+
+		file, err := acquireFile(path, filepath.ToSlash(rel), false)
+		if err != nil {
+			acq.Errors = append(acq.Errors, fmt.Sprintf("%s: %v", rel, err))
+			return nil
+		}
+		if file.FileType, err = filetype.IdentifyFile(path); err != nil {
+			acq.Errors = append(acq.Errors, fmt.Sprintf("%s: %v", rel, err))
+		}
+		acq.Files = append(acq.Files, *file)
+		return nil
+	})
+}
+
+// acquireFile hashes a single file and records its metadata
+func acquireFile(path, relPath string, withByteRuns bool) (*AcquiredFile, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	file := &AcquiredFile{
+		RelativePath: relPath,
+		Size:         info.Size(),
+		ModifiedTime: info.ModTime(),
+		Mode:         info.Mode(),
+	}
+	file.AccessTime, file.ChangeTime = fileTimes(info)
+
+	hashes, runs, err := hashFile(path, withByteRuns)
+	if err != nil {
+		return nil, err
+	}
+	file.MD5 = hashes.md5
+	file.SHA1 = hashes.sha1
+	file.SHA256 = hashes.sha256
+	file.ByteRuns = runs
+
+	return file, nil
+}
+
+// fileHashes holds the digests computed for a file
+type fileHashes struct {
+	md5    string
+	sha1   string
+	sha256 string
+}
+
+// hashFile computes MD5, SHA-1 and SHA-256 in a single pass and optionally
+// hashes fixed-size byte runs as well
+func hashFile(path string, withByteRuns bool) (fileHashes, []ByteRun, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileHashes{}, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	md5Hash := md5.New()
+	sha1Hash := sha1.New()
+	sha256Hash := sha256.New()
+	w := io.MultiWriter(md5Hash, sha1Hash, sha256Hash)
+
+	var runs []ByteRun
+	if withByteRuns {
+		var offset int64
+		for {
+			runHash := sha256.New()
+			n, err := io.CopyN(io.MultiWriter(w, runHash), f, imageBlockSize)
+			if n > 0 {
+				runs = append(runs, ByteRun{
+					Offset: offset,
+					Length: n,
+					SHA256: hex.EncodeToString(runHash.Sum(nil)),
+				})
+				offset += n
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fileHashes{}, nil, fmt.Errorf("failed to read file: %w", err)
+			}
+		}
+	} else if _, err := io.Copy(w, f); err != nil {
+		return fileHashes{}, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return fileHashes{
+		md5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		sha1:   hex.EncodeToString(sha1Hash.Sum(nil)),
+		sha256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, runs, nil
+}
+
+// childEvidenceNumber derives an evidence number for an acquired file
+func childEvidenceNumber(parentNumber string, index int) string {
+	if parentNumber == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-%04d", parentNumber, index)
+}
+
+// acquisitionLabel returns a short label identifying the parent item
+func acquisitionLabel(parent *DigitalEvidence) string {
+	if parent.EvidenceNumber != "" {
+		return parent.EvidenceNumber
+	}
+	if parent.Description != "" {
+		return parent.Description
+	}
+	return parent.ID
+}
+
+// totalSize sums the size of all acquired files
+func totalSize(files []AcquiredFile) int64 {
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total
+}
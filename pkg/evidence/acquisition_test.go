@@ -0,0 +1,242 @@
+package evidence
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// acquisitionTree writes a small directory tree to acquire
+func acquisitionTree(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "usb")
+	files := map[string]string{
+		"notes.txt":        "meet at the dock at nine",
+		"photos/img1.jpg":  "\xff\xd8\xff\xe0 not really a jpeg",
+		"photos/img2.jpg":  "\xff\xd8\xff\xe0 another one",
+		"docs/ledger.csv":  "date,amount\n2024-01-02,500\n",
+		"docs/empty.bin":   "",
+		"docs/deep/x.txt":  "deep file",
+		"docs/deep/y.dat":  strings.Repeat("y", 4096),
+		"docs/deep/z.note": "z",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func acquisitionParent() *DigitalEvidence {
+	return &DigitalEvidence{Evidence: Evidence{
+		CaseID: "CASE-1", EvidenceNumber: "E-100", Description: "USB drive",
+		CollectedBy: "Officer A", StorageLocation: "Locker 1",
+	}}
+}
+
+func TestAcquireAndVerifyDirectory(t *testing.T) {
+	root := acquisitionTree(t)
+	manifest := filepath.Join(t.TempDir(), "manifest.xml")
+	repo := newMemRepo()
+	s := NewEvidenceService(repo)
+
+	parent := acquisitionParent()
+	acq, err := s.AcquireSource(parent, root, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acq.Files) != 8 {
+		t.Fatalf("acquired %d files, want 8", len(acq.Files))
+	}
+	if len(repo.items) != 9 {
+		t.Errorf("repository holds %d items, want 8 children and the parent", len(repo.items))
+	}
+	hash, err := calculateFileHash(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.FileHash != hash {
+		t.Errorf("parent hash %s is not the manifest hash %s", parent.FileHash, hash)
+	}
+	for _, f := range acq.Files {
+		child, err := repo.Find(f.EvidenceID)
+		if err != nil {
+			t.Fatalf("child of %s not saved: %v", f.RelativePath, err)
+		}
+		if child.DerivedFrom == nil || child.DerivedFrom.ParentID != parent.ID {
+			t.Errorf("%s is not derived from the parent", f.RelativePath)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T)
+		status map[string]VerificationStatus
+	}{
+		{"unchanged", func(t *testing.T) {}, nil},
+		{"modified", func(t *testing.T) {
+			os.WriteFile(filepath.Join(root, "notes.txt"), []byte("meet at the pier at ten"), 0644)
+		}, map[string]VerificationStatus{"notes.txt": VerifyModified}},
+		{"missing and added", func(t *testing.T) {
+			os.Remove(filepath.Join(root, "docs", "ledger.csv"))
+			os.WriteFile(filepath.Join(root, "docs", "new.txt"), []byte("new"), 0644)
+		}, map[string]VerificationStatus{
+			"notes.txt":       VerifyModified,
+			"docs/ledger.csv": VerifyMissing,
+			"docs/new.txt":    VerifyAdded,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change(t)
+			report, err := s.VerifyAcquisition(manifest, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.ManifestModified() {
+				t.Fatal("manifest reported modified")
+			}
+			got := make(map[string]VerificationStatus)
+			for _, m := range report.Mismatches() {
+				got[m.RelativePath] = m.Status
+			}
+			if len(got) != len(tt.status) {
+				t.Fatalf("mismatches = %v, want %v", got, tt.status)
+			}
+			for path, status := range tt.status {
+				if got[path] != status {
+					t.Errorf("%s: got %s, want %s", path, got[path], status)
+				}
+			}
+			if report.OK() != (len(tt.status) == 0) {
+				t.Errorf("OK() = %v", report.OK())
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsEditedManifest(t *testing.T) {
+	root := acquisitionTree(t)
+	manifest := filepath.Join(t.TempDir(), "manifest.xml")
+	s := NewEvidenceService(newMemRepo())
+	parent := acquisitionParent()
+	if _, err := s.AcquireSource(parent, root, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change a file and rewrite its hash in the manifest to match
+	path := filepath.Join(root, "notes.txt")
+	old, _ := calculateFileHash(path)
+	os.WriteFile(path, []byte("meet at the dock at ten"), 0644)
+	updated, _ := calculateFileHash(path)
+	data, _ := os.ReadFile(manifest)
+	os.WriteFile(manifest, []byte(strings.ReplaceAll(string(data), old, updated)), 0644)
+
+	report, err := s.VerifyAcquisition(manifest, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.ManifestModified() || report.OK() {
+		t.Errorf("edited manifest not detected: modified %v, OK %v", report.ManifestModified(), report.OK())
+	}
+
+	// Without the evidence item on record the hash printed at acquisition is used
+	other := NewEvidenceService(newMemRepo())
+	if _, err := other.VerifyAcquisition(manifest, "", ""); err == nil {
+		t.Error("verified a manifest with no recorded hash")
+	}
+	report, err = other.VerifyAcquisition(manifest, "", parent.FileHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.ManifestModified() {
+		t.Error("edited manifest not detected against the given hash")
+	}
+}
+
+func TestAcquireRemovesChildrenOnFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest func(t *testing.T) string
+		failSave bool
+	}{
+		{"manifest cannot be written", func(t *testing.T) string {
+			// A regular file where the manifest directory should be
+			blocker := filepath.Join(t.TempDir(), "file")
+			os.WriteFile(blocker, nil, 0644)
+			return filepath.Join(blocker, "manifest.xml")
+		}, false},
+		{"parent cannot be saved", func(t *testing.T) string {
+			return filepath.Join(t.TempDir(), "manifest.xml")
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemRepo()
+			parent := acquisitionParent()
+			parent.ID = "EV-PARENT"
+			if tt.failSave {
+				repo.failSave = func(e *Evidence) error {
+					if e.ID == parent.ID {
+						return errors.New("disk full")
+					}
+					return nil
+				}
+			}
+			manifest := tt.manifest(t)
+			if _, err := NewEvidenceService(repo).AcquireSource(parent, acquisitionTree(t), manifest); err == nil {
+				t.Fatal("acquisition succeeded")
+			}
+			if len(repo.items) != 0 {
+				t.Errorf("%d items left behind", len(repo.items))
+			}
+			if _, err := os.Stat(manifest); err == nil {
+				t.Error("manifest left behind")
+			}
+		})
+	}
+}
+
+func TestRawImageIsOneChildWithByteRuns(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "disk.dd")
+	if err := os.WriteFile(image, []byte(strings.Repeat("\x00", 8192)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo := newMemRepo()
+	acq, err := NewEvidenceService(repo).AcquireSource(acquisitionParent(), image, filepath.Join(t.TempDir(), "m.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acq.Type != AcquisitionRawImage || len(acq.Files) != 1 || len(acq.Files[0].ByteRuns) != 1 {
+		t.Errorf("got %s with %d files", acq.Type, len(acq.Files))
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	root := acquisitionTree(t)
+	manifest := filepath.Join(t.TempDir(), "manifest.xml")
+	acq, err := NewEvidenceService(newMemRepo()).AcquireSource(acquisitionParent(), root, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifestFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ID != acq.ID || read.EvidenceID != acq.EvidenceID || len(read.Files) != len(acq.Files) {
+		t.Fatalf("read %+v", read)
+	}
+	for i, f := range acq.Files {
+		r := read.Files[i]
+		if r.RelativePath != f.RelativePath || r.SHA256 != f.SHA256 || r.MD5 != f.MD5 || r.Size != f.Size || r.EvidenceID != f.EvidenceID {
+			t.Errorf("file %d: read %+v, wrote %+v", i, r, f)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/archive"
+)
+
+// ExpansionOptions control how archives are expanded into child evidence
+type ExpansionOptions struct {
+	DestDir  string // Directory the extracted files are written to
+	Operator string // Person performing the expansion; defaults to the container's collector
+	Limits   archive.Limits
+}
+
+// ExpandedFile describes a child item created from an archive entry
+type ExpandedFile struct {
+	EvidenceID  string
+	ContainerID string // Evidence ID of the archive the file came from
+	ArchivePath string // Path inside the archive
+	Depth       int    // 1 for entries of the top-level archive
+	Size        int64
+	MD5         string
+	SHA1        string
+	SHA256      string
+}
+
+// ExpansionReport is the result of expanding an archive
+type ExpansionReport struct {
+	ContainerID string
+	Format      archive.Format
+	Files       []ExpandedFile
+	Skipped     []string // Entries and nested archives that were not expanded
+	Errors      []string
+}
+
+// IsArchive reports whether a file is an archive or compressed container,
+// and whether it can be expanded
+func IsArchive(filePath string) (format archive.Format, supported bool) {
+	format, err := archive.DetectFormat(filePath)
+	if err != nil || format == archive.FormatNone {
+		return archive.FormatNone, false
+	}
+	return format, archive.Supported(format)
+}
+
+// ExpandArchive extracts a ZIP, TAR, GZIP or BZIP2 container, or a 7z, RAR
+// or XZ one when 7-Zip or xz is installed, and creates a child evidence item
+// for every file, with the container as its parent. Nested archives are
+// expanded in turn up to the depth limit. Extraction stops when a size or
+// file count limit is reached; the items created up to that point are kept
+// and the error is returned with the report.
+func (s *EvidenceService) ExpandArchive(container *DigitalEvidence, opts ExpansionOptions) (*ExpansionReport, error) {
+	if container.ID == "" {
+		return nil, fmt.Errorf("the container must be saved before it is expanded")
+	}
+	if container.IsDisposed() {
+		return nil, ErrEvidenceDisposed
+	}
+	if opts.DestDir == "" {
+		return nil, fmt.Errorf("an extraction directory is required")
+	}
+	if opts.Limits == (archive.Limits{}) {
+		opts.Limits = archive.DefaultLimits()
+	}
+	if opts.Operator == "" {
+		opts.Operator = container.CollectedBy
+	}
+
+	format, supported := IsArchive(container.FilePath)
+	if !supported {
+		if format != archive.FormatNone {
+			return nil, archive.CheckSupported(format)
+		}
+		return nil, fmt.Errorf("%s is not an archive", container.FilePath)
+	}
+
+	// Expand only the file that was recorded as evidence
+	if err := verifyRecordedHash(container); err != nil {
+		return nil, err
+	}
+
+	report := &ExpansionReport{ContainerID: container.ID, Format: format}
+	x := &expansion{
+		service: s,
+		opts:    opts,
+		budget:  archive.NewBudget(opts.Limits),
+		report:  report,
+	}
+	err := x.expand(container, opts.DestDir, 1)
+	if errors.Is(err, archive.ErrLimitExceeded) {
+		report.Errors = append(report.Errors, err.Error())
+		if markErr := s.markExpanded(container, err); markErr != nil {
+			return report, markErr
+		}
+		return report, err
+	}
+	if err != nil {
+		return report, err
+	}
+	return report, s.markExpanded(container, nil)
+}
+
+// markExpanded tags an expanded container, noting why expansion stopped
+// when a limit was reached. The stored record is reloaded so that the links
+// added for its children are kept.
+func (s *EvidenceService) markExpanded(container *DigitalEvidence, limitErr error) error {
+	current, err := s.repo.Find(container.ID)
+	if err != nil {
+		return fmt.Errorf("evidence not found: %w", err)
+	}
+
+	current.Tags = appendUnique(current.Tags, "archive-expanded")
+	if limitErr != nil {
+		current.Tags = appendUnique(current.Tags, "archive-limit-exceeded")
+		current.Notes = joinResults(current.Notes, "Archive expansion stopped: "+limitErr.Error())
+	}
+	if err := s.UpdateEvidence(current); err != nil {
+		return err
+	}
+
+	container.Evidence = *current
+	return nil
+}
+
+// expansion holds the state of one ExpandArchive call
+type expansion struct {
+	service *EvidenceService
+	opts    ExpansionOptions
+	budget  *archive.Budget
+	report  *ExpansionReport
+}
+
+// expand extracts one archive and recurses into nested archives
+func (x *expansion) expand(container *DigitalEvidence, destDir string, depth int) error {
+	listing, err := archive.Extract(container.FilePath, filepath.Join(destDir, container.ID), x.budget)
+	if listing != nil {
+		for _, skipped := range listing.Skipped {
+			x.report.Skipped = append(x.report.Skipped, fmt.Sprintf("%s: %s", container.ID, skipped))
+		}
+	}
+	if err != nil && (listing == nil || !errors.Is(err, archive.ErrLimitExceeded)) {
+		return fmt.Errorf("failed to expand %s: %w", container.ID, err)
+	}
+	limitErr := err
+
+	for i, entry := range listing.Entries {
+		child, err := x.createChild(container, listing.Format, entry, i+1, depth)
+		if err != nil {
+			x.report.Errors = append(x.report.Errors, fmt.Sprintf("%s: %v", entry.Path, err))
+			continue
+		}
+
+		format, supported := IsArchive(child.FilePath)
+		switch {
+		case format == archive.FormatNone:
+		case !supported:
+			x.report.Skipped = append(x.report.Skipped,
+				fmt.Sprintf("%s: %s cannot be expanded: %v", child.ID, entry.Path, archive.CheckSupported(format)))
+		case depth >= x.opts.Limits.MaxDepth:
+			x.report.Skipped = append(x.report.Skipped,
+				fmt.Sprintf("%s: nested archive %s not expanded (depth limit %d)", child.ID, entry.Path, x.opts.Limits.MaxDepth))
+		default:
+			err := x.expand(child, destDir, depth+1)
+			if errors.Is(err, archive.ErrLimitExceeded) {
+				if markErr := x.service.markExpanded(child, err); markErr != nil {
+					return markErr
+				}
+				return err
+			}
+			if err != nil {
+				x.report.Errors = append(x.report.Errors, err.Error())
+				continue
+			}
+			if err := x.service.markExpanded(child, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return limitErr
+}
+
+// createChild records an extracted file as evidence derived from its container
+func (x *expansion) createChild(container *DigitalEvidence, format archive.Format, entry archive.Entry, index, depth int) (*DigitalEvidence, error) {
+	child := &DigitalEvidence{
+		Evidence: Evidence{
+			CaseID:          container.CaseID,
+			EvidenceNumber:  childEvidenceNumber(container.EvidenceNumber, index),
+			Description:     fmt.Sprintf("%s (from %s)", entry.Path, archiveLabel(container)),
+			Location:        container.Location,
+			StorageLocation: container.StorageLocation,
+			IsConfidential:  container.IsConfidential,
+		},
+		FilePath:     entry.ExtractedPath,
+		ModifiedDate: entry.ModTime,
+		DeviceSource: container.DeviceSource,
+	}
+
+	req := DerivationRequest{
+		ParentID:   container.ID,
+		Relation:   RelationExtractedFrom,
+		Tool:       fmt.Sprintf("%s archive expansion", strings.ToLower(string(format))),
+		Operator:   x.opts.Operator,
+		SourcePath: entry.Path,
+	}
+	if err := x.service.DeriveDigitalEvidence(child, req); err != nil {
+		return nil, err
+	}
+	child.Metadata["ArchivePath"] = entry.Path
+	if err := x.service.saveDigital(child); err != nil {
+		return nil, err
+	}
+
+	x.report.Files = append(x.report.Files, ExpandedFile{
+		EvidenceID:  child.ID,
+		ContainerID: container.ID,
+		ArchivePath: entry.Path,
+		Depth:       depth,
+		Size:        entry.Size,
+		MD5:         child.Metadata["MD5"],
+		SHA1:        child.Metadata["SHA1"],
+		SHA256:      child.FileHash,
+	})
+	return child, nil
+}
+
+// archiveLabel returns a short label identifying a container
+func archiveLabel(container *DigitalEvidence) string {
+	if container.EvidenceNumber != "" {
+		return container.EvidenceNumber
+	}
+	if container.FilePath != "" {
+		return filepath.Base(container.FilePath)
+	}
+	return container.ID
+}
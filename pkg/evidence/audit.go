@@ -0,0 +1,239 @@
+package evidence
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// AuditFinding classifies an item encountered during an inventory audit
+type AuditFinding string
+
+const (
+	AuditPresent    AuditFinding = "PRESENT"    // Found where the records say it is
+	AuditMissing    AuditFinding = "MISSING"    // Recorded at the location but not found
+	AuditMisplaced  AuditFinding = "MISPLACED"  // Found, but recorded elsewhere
+	AuditUnexpected AuditFinding = "UNEXPECTED" // Found, but unknown or already disposed of
+)
+
+// AuditRequest describes a physical inventory of one storage location
+type AuditRequest struct {
+	Location         string   // Shelf, locker or bin being audited
+	ScannedCodes     []string // Barcodes, QR payloads or evidence numbers found there
+	AuditedBy        string
+	CorrectMisplaced bool // Update StorageLocation of misplaced items to the audited location
+	Notes            string
+}
+
+// AuditItem is a single line of an audit report
+type AuditItem struct {
+	Finding          AuditFinding
+	Code             string // Scanned code (empty for missing items)
+	EvidenceID       string
+	EvidenceNumber   string
+	Description      string
+	RecordedLocation string
+	Detail           string
+}
+
+// AuditReport is the result of reconciling an inventory against the records
+type AuditReport struct {
+	ID          string
+	Location    string
+	AuditedBy   string
+	PerformedAt time.Time
+	Items       []AuditItem
+	Notes       string
+}
+
+// ByFinding returns the report items with the given finding
+func (r *AuditReport) ByFinding(finding AuditFinding) []AuditItem {
+	var result []AuditItem
+	for _, item := range r.Items {
+		if item.Finding == finding {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Reconciled reports whether the location matched the records exactly
+func (r *AuditReport) Reconciled() bool {
+	return len(r.ByFinding(AuditPresent)) == len(r.Items)
+}
+
+// ReadAuditCodes reads scanned codes from a list, one per line. Blank lines
+// and lines starting with # are ignored.
+func ReadAuditCodes(r io.Reader) ([]string, error) {
+	var codes []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		codes = append(codes, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read scanned codes: %w", err)
+	}
+	return codes, nil
+}
+
+// AuditLocation reconciles the items physically found at a storage location
+// against the repository. Every item found is recorded in its chain of
+// custody as a verification event. The events are written once the whole
+// report is built; if any cannot be written, those already written are
+// undone and no item records the audit.
+func (s *EvidenceService) AuditLocation(req AuditRequest) (*AuditReport, error) {
+	if req.Location == "" {
+		return nil, fmt.Errorf("a storage location is required")
+	}
+	if req.AuditedBy == "" {
+		return nil, fmt.Errorf("the person performing the audit is required")
+	}
+
+	expected, err := s.repo.FindByStorageLocation(req.Location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find evidence at %s: %w", req.Location, err)
+	}
+
+	report := &AuditReport{
+		ID:          generateID("AUD"),
+		Location:    req.Location,
+		AuditedBy:   req.AuditedBy,
+		PerformedAt: time.Now(),
+		Notes:       req.Notes,
+	}
+
+	found := make(map[string]bool)
+	var verified []*Evidence
+	for _, code := range req.ScannedCodes {
+		e, err := s.LookupByLabelCode(code)
+		if err != nil {
+			report.Items = append(report.Items, AuditItem{
+				Finding: AuditUnexpected,
+				Code:    code,
+				Detail:  "no matching evidence record",
+			})
+			continue
+		}
+
+		if found[e.ID] {
+			continue // Scanned twice
+		}
+		found[e.ID] = true
+
+		item := AuditItem{
+			Code:             code,
+			EvidenceID:       e.ID,
+			EvidenceNumber:   e.EvidenceNumber,
+			Description:      e.Description,
+			RecordedLocation: e.StorageLocation,
+		}
+
+		switch {
+		case e.IsDisposed():
+			item.Finding = AuditUnexpected
+			item.Detail = fmt.Sprintf("item was %s on %s", strings.ToLower(string(e.Status)),
+				e.Disposition.Timestamp.Format("2006-01-02"))
+			report.Items = append(report.Items, item)
+			continue
+		case sameLocation(e.StorageLocation, req.Location):
+			item.Finding = AuditPresent
+		default:
+			item.Finding = AuditMisplaced
+			item.Detail = fmt.Sprintf("recorded at %s", e.StorageLocation)
+		}
+
+		verified = append(verified, e)
+		report.Items = append(report.Items, item)
+	}
+
+	for _, e := range expected {
+		if found[e.ID] || e.IsDisposed() {
+			continue
+		}
+		report.Items = append(report.Items, AuditItem{
+			Finding:          AuditMissing,
+			EvidenceID:       e.ID,
+			EvidenceNumber:   e.EvidenceNumber,
+			Description:      e.Description,
+			RecordedLocation: e.StorageLocation,
+			Detail:           "not found during audit",
+		})
+	}
+
+	if err := s.recordAuditVerifications(verified, report, req.CorrectMisplaced); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// recordAuditVerifications records the audit in the chain of custody of
+// each item found, restoring the items already updated if one fails
+func (s *EvidenceService) recordAuditVerifications(items []*Evidence, report *AuditReport, correct bool) error {
+	type saved struct {
+		e        *Evidence
+		custody  []CustodyEvent
+		location string
+		updated  time.Time
+	}
+	var done []saved
+	for _, e := range items {
+		before := saved{e, e.ChainOfCustody, e.StorageLocation, e.UpdatedAt}
+		if err := s.recordAuditVerification(e, report, correct); err != nil {
+			done = append(done, before) // Restore the failed item too
+			for _, d := range done {
+				d.e.ChainOfCustody, d.e.StorageLocation, d.e.UpdatedAt = d.custody, d.location, d.updated
+				// Best effort: the original error is the one reported
+				_ = s.repo.Update(d.e)
+			}
+			return err
+		}
+		done = append(done, before)
+	}
+	return nil
+}
+
+// recordAuditVerification adds a custody verification event for an item
+// found during an audit
+func (s *EvidenceService) recordAuditVerification(e *Evidence, report *AuditReport, correct bool) error {
+	event := CustodyEvent{
+		ID:                 generateID("CE"),
+		EvidenceID:         e.ID,
+		Timestamp:          report.PerformedAt,
+		Action:             "VERIFIED",
+		FromPerson:         report.AuditedBy,
+		ToPerson:           report.AuditedBy,
+		FromLocation:       e.StorageLocation,
+		ToLocation:         e.StorageLocation,
+		Reason:             "Inventory audit",
+		DocumentID:         report.ID,
+		VerificationMethod: fmt.Sprintf("Physical inventory of %s", report.Location),
+	}
+
+	if !sameLocation(e.StorageLocation, report.Location) {
+		event.Notes = fmt.Sprintf("Found at %s, recorded at %s", report.Location, e.StorageLocation)
+		if correct {
+			event.ToLocation = report.Location
+			event.Notes += "; storage location corrected"
+			e.StorageLocation = report.Location
+		}
+	}
+
+	e.ChainOfCustody = append(e.ChainOfCustody, event)
+	e.UpdatedAt = report.PerformedAt
+
+	if err := s.repo.Update(e); err != nil {
+		return fmt.Errorf("failed to record audit for %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// sameLocation compares storage locations ignoring case and surrounding space
+func sameLocation(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
@@ -0,0 +1,89 @@
+package evidence
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newAuditService creates a service holding items at the given locations,
+// numbered E-1, E-2 and so on
+func newAuditService(t *testing.T, locations ...string) (*EvidenceService, *memRepo, []*Evidence) {
+	t.Helper()
+	repo := newMemRepo()
+	s := NewEvidenceService(repo)
+	var items []*Evidence
+	for i, location := range locations {
+		e := &Evidence{CaseID: "CASE-1", EvidenceNumber: fmt.Sprintf("E-%d", i+1), Description: "Item",
+			CollectedBy: "Officer A", CollectionDate: time.Now(), StorageLocation: location}
+		if err := s.CreateEvidence(e); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, e)
+	}
+	return s, repo, items
+}
+
+func TestAuditLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanned []string
+		correct bool
+		want    map[AuditFinding]int
+		// Storage location of E-3 after the audit
+		wantE3Location string
+	}{
+		{"reconciled", []string{"E-1", "E-2"}, false,
+			map[AuditFinding]int{AuditPresent: 2}, "Shelf B"},
+		{"missing and unknown", []string{"E-1", "X-9"}, false,
+			map[AuditFinding]int{AuditPresent: 1, AuditMissing: 1, AuditUnexpected: 1}, "Shelf B"},
+		{"misplaced", []string{"E-1", "E-2", "E-3"}, false,
+			map[AuditFinding]int{AuditPresent: 2, AuditMisplaced: 1}, "Shelf B"},
+		{"misplaced corrected", []string{"E-1", "E-2", "E-3"}, true,
+			map[AuditFinding]int{AuditPresent: 2, AuditMisplaced: 1}, "Shelf A"},
+		{"scanned twice", []string{"E-1", "E-1", "E-2"}, false,
+			map[AuditFinding]int{AuditPresent: 2}, "Shelf B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, items := newAuditService(t, "Shelf A", "Shelf A", "Shelf B")
+			report, err := s.AuditLocation(AuditRequest{Location: "Shelf A", ScannedCodes: tt.scanned,
+				AuditedBy: "OFC-1", CorrectMisplaced: tt.correct})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, finding := range []AuditFinding{AuditPresent, AuditMissing, AuditMisplaced, AuditUnexpected} {
+				if got := len(report.ByFinding(finding)); got != tt.want[finding] {
+					t.Errorf("%s: %d items, want %d", finding, got, tt.want[finding])
+				}
+			}
+			if items[2].StorageLocation != tt.wantE3Location {
+				t.Errorf("E-3 is at %s, want %s", items[2].StorageLocation, tt.wantE3Location)
+			}
+		})
+	}
+}
+
+func TestAuditLocationIsAllOrNothing(t *testing.T) {
+	s, repo, items := newAuditService(t, "Shelf A", "Shelf A", "Shelf B")
+	repo.failUpdate = func(e *Evidence) error {
+		if e.ID == items[1].ID {
+			return fmt.Errorf("disk full")
+		}
+		return nil
+	}
+
+	_, err := s.AuditLocation(AuditRequest{Location: "Shelf A", ScannedCodes: []string{"E-1", "E-2", "E-3"},
+		AuditedBy: "OFC-1", CorrectMisplaced: true})
+	if err == nil {
+		t.Fatal("audit succeeded despite the failed update")
+	}
+	for _, e := range items {
+		if len(e.ChainOfCustody) != 1 {
+			t.Errorf("%s has %d custody events, want only the collection", e.EvidenceNumber, len(e.ChainOfCustody))
+		}
+	}
+	if items[2].StorageLocation != "Shelf B" {
+		t.Errorf("E-3 moved to %s", items[2].StorageLocation)
+	}
+}
@@ -0,0 +1,547 @@
+package evidence
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// StorageCondition is the environmental class required by, or provided for,
+// biological evidence
+type StorageCondition string
+
+const (
+	ConditionFrozen       StorageCondition = "FROZEN"
+	ConditionRefrigerated StorageCondition = "REFRIGERATED"
+	ConditionDry          StorageCondition = "DRY" // Room temperature, low humidity
+	ConditionUnknown      StorageCondition = ""
+)
+
+// conditionRanges are the default acceptable temperature ranges in °C
+var conditionRanges = map[StorageCondition][2]float64{
+	ConditionFrozen:       {-30, -10},
+	ConditionRefrigerated: {2, 8},
+	ConditionDry:          {15, 25},
+}
+
+// defaultConditions maps biological sample types to their usual storage class
+var defaultConditions = map[string]StorageCondition{
+	"BLOOD":  ConditionRefrigerated,
+	"URINE":  ConditionFrozen,
+	"TISSUE": ConditionFrozen,
+	"DNA":    ConditionFrozen,
+	"SALIVA": ConditionDry,
+	"SWAB":   ConditionDry,
+	"HAIR":   ConditionDry,
+	"SEMEN":  ConditionFrozen,
+}
+
+// storageKeywords are the words and phrases that name a storage condition.
+// Longer phrases come first so that "freeze dried" is not read as frozen;
+// negated phrases name no condition and are skipped.
+var storageKeywords = []struct {
+	words     []string
+	condition StorageCondition
+}{
+	{[]string{"DO", "NOT", "FREEZE"}, ConditionUnknown},
+	{[]string{"NOT", "FROZEN"}, ConditionUnknown},
+	{[]string{"FREEZE", "DRIED"}, ConditionDry},
+	{[]string{"ROOM", "TEMPERATURE"}, ConditionDry},
+	{[]string{"ROOM", "TEMP"}, ConditionDry},
+	{[]string{"FROZEN"}, ConditionFrozen},
+	{[]string{"FREEZER"}, ConditionFrozen},
+	{[]string{"FREEZE"}, ConditionFrozen},
+	{[]string{"REFRIGERATED"}, ConditionRefrigerated},
+	{[]string{"REFRIGERATOR"}, ConditionRefrigerated},
+	{[]string{"REFRIGERATE"}, ConditionRefrigerated},
+	{[]string{"FRIDGE"}, ConditionRefrigerated},
+	{[]string{"DRY"}, ConditionDry},
+	{[]string{"DRIED"}, ConditionDry},
+	{[]string{"AMBIENT"}, ConditionDry},
+}
+
+// ParseStorageCondition interprets free-text storage conditions or storage
+// location names. Only whole words from storageKeywords count, so "Room 12"
+// or "Cooler shelf" name no condition; text naming conflicting conditions
+// is unknown.
+func ParseStorageCondition(value string) StorageCondition {
+	words := strings.FieldsFunc(strings.ToUpper(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	found := ConditionUnknown
+	for i := 0; i < len(words); {
+		n := 1
+		for _, k := range storageKeywords {
+			if !hasWords(words[i:], k.words) {
+				continue
+			}
+			n = len(k.words)
+			if k.condition != ConditionUnknown {
+				if found != ConditionUnknown && found != k.condition {
+					return ConditionUnknown
+				}
+				found = k.condition
+			}
+			break
+		}
+		i += n
+	}
+	return found
+}
+
+// hasWords reports whether words starts with prefix
+func hasWords(words, prefix []string) bool {
+	if len(words) < len(prefix) {
+		return false
+	}
+	for i, w := range prefix {
+		if words[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// RequiredCondition returns the storage class an item requires, taken from
+// StorageConditions or, failing that, from its biological type
+func (b *BiologicalEvidence) RequiredCondition() StorageCondition {
+	if c := ParseStorageCondition(b.StorageConditions); c != ConditionUnknown {
+		return c
+	}
+	return defaultConditions[strings.ToUpper(b.BiologicalType)]
+}
+
+// StorageUnit describes a temperature-controlled storage location
+type StorageUnit struct {
+	Name      string // Matches Evidence.StorageLocation
+	Condition StorageCondition
+	MinTempC  float64
+	MaxTempC  float64
+}
+
+// BiologicalRepository stores the biological details of evidence items
+type BiologicalRepository interface {
+	SaveBiological(b *BiologicalEvidence) error
+	FindBiological(id string) (*BiologicalEvidence, error)
+	ListBiological() ([]*BiologicalEvidence, error)
+}
+
+// CaseNoteWriter adds notes to the case that owns an evidence item
+type CaseNoteWriter interface {
+	AddCaseNote(caseID, title, content string) error
+}
+
+// WarningKind classifies biological evidence warnings
+type WarningKind string
+
+const (
+	WarningExpiring          WarningKind = "EXPIRING"
+	WarningExpired           WarningKind = "EXPIRED"
+	WarningConditionMismatch WarningKind = "CONDITION_MISMATCH"
+	WarningExcursion         WarningKind = "TEMPERATURE_EXCURSION"
+)
+
+// BiologicalWarning is a single problem found by the monitor
+type BiologicalWarning struct {
+	Kind       WarningKind
+	EvidenceID string
+	CaseID     string
+	Message    string
+}
+
+// TemperatureReading is one line of a storage unit temperature log
+type TemperatureReading struct {
+	Unit      string
+	Timestamp time.Time
+	TempC     float64
+}
+
+// Excursion is a period during which a unit was outside its range
+type Excursion struct {
+	Unit     string
+	Start    time.Time
+	End      time.Time
+	MinTempC float64
+	MaxTempC float64
+	Readings int
+}
+
+// MonitorReport is the result of a monitoring run
+type MonitorReport struct {
+	GeneratedAt time.Time
+	Excursions  []Excursion
+	Warnings    []BiologicalWarning
+}
+
+// BiologicalMonitor watches biological evidence for expiration and storage
+// problems
+type BiologicalMonitor struct {
+	service *EvidenceService
+	bioRepo BiologicalRepository
+	notes   CaseNoteWriter
+	units   map[string]StorageUnit
+}
+
+// NewBiologicalMonitor creates a new biological evidence monitor
+func NewBiologicalMonitor(service *EvidenceService, bioRepo BiologicalRepository, notes CaseNoteWriter) *BiologicalMonitor {
+	return &BiologicalMonitor{
+		service: service,
+		bioRepo: bioRepo,
+		notes:   notes,
+		units:   make(map[string]StorageUnit),
+	}
+}
+
+// RegisterStorageUnit configures a storage unit. Units that are not
+// registered have their condition inferred from their name.
+func (m *BiologicalMonitor) RegisterStorageUnit(unit StorageUnit) {
+	if unit.MinTempC == 0 && unit.MaxTempC == 0 {
+		r := conditionRanges[unit.Condition]
+		unit.MinTempC, unit.MaxTempC = r[0], r[1]
+	}
+	m.units[strings.ToLower(strings.TrimSpace(unit.Name))] = unit
+}
+
+// storageUnit returns the configuration for a storage location
+func (m *BiologicalMonitor) storageUnit(location string) StorageUnit {
+	if unit, ok := m.units[strings.ToLower(strings.TrimSpace(location))]; ok {
+		return unit
+	}
+	condition := ParseStorageCondition(location)
+	r := conditionRanges[condition]
+	return StorageUnit{Name: location, Condition: condition, MinTempC: r[0], MaxTempC: r[1]}
+}
+
+// CreateBiologicalEvidence creates an evidence item and stores its
+// biological details
+func (m *BiologicalMonitor) CreateBiologicalEvidence(b *BiologicalEvidence) error {
+	b.Type = TypeBiological
+	if err := m.service.CreateEvidence(&b.Evidence); err != nil {
+		return err
+	}
+	if err := m.bioRepo.SaveBiological(b); err != nil {
+		return fmt.Errorf("failed to save biological details: %w", err)
+	}
+	return nil
+}
+
+// Check lists items expiring within the given window and items stored in a
+// location that does not provide their required condition
+func (m *BiologicalMonitor) Check(window time.Duration) (*MonitorReport, error) {
+	report := &MonitorReport{GeneratedAt: time.Now()}
+
+	items, err := m.activeItems()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		b, e := item.bio, item.current
+
+		if !b.ExpirationDate.IsZero() {
+			remaining := b.ExpirationDate.Sub(report.GeneratedAt)
+			switch {
+			case remaining <= 0:
+				report.Warnings = append(report.Warnings, BiologicalWarning{
+					Kind:       WarningExpired,
+					EvidenceID: e.ID,
+					CaseID:     e.CaseID,
+					Message: fmt.Sprintf("%s sample %s expired on %s",
+						b.BiologicalType, sampleLabel(b), b.ExpirationDate.Format("2006-01-02")),
+				})
+			case remaining <= window:
+				report.Warnings = append(report.Warnings, BiologicalWarning{
+					Kind:       WarningExpiring,
+					EvidenceID: e.ID,
+					CaseID:     e.CaseID,
+					Message: fmt.Sprintf("%s sample %s expires on %s (%d days)",
+						b.BiologicalType, sampleLabel(b), b.ExpirationDate.Format("2006-01-02"),
+						int(math.Ceil(remaining.Hours()/24))),
+				})
+			}
+		}
+
+		required := b.RequiredCondition()
+		if required == ConditionUnknown {
+			continue
+		}
+		// A location whose condition is not known, such as a general
+		// evidence locker, cannot be checked
+		unit := m.storageUnit(e.StorageLocation)
+		if unit.Condition != ConditionUnknown && unit.Condition != required {
+			provided := string(unit.Condition)
+			report.Warnings = append(report.Warnings, BiologicalWarning{
+				Kind:       WarningConditionMismatch,
+				EvidenceID: e.ID,
+				CaseID:     e.CaseID,
+				Message: fmt.Sprintf("%s sample %s requires %s storage but %s provides %s",
+					b.BiologicalType, sampleLabel(b), required, e.StorageLocation, provided),
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// IngestTemperatureLog reads a storage unit temperature log and flags
+// excursions. Every biological item held in an affected unit receives a
+// warning. defaultUnit is used when the log has no unit column.
+func (m *BiologicalMonitor) IngestTemperatureLog(r io.Reader, defaultUnit string) (*MonitorReport, error) {
+	readings, err := ParseTemperatureLog(r, defaultUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MonitorReport{GeneratedAt: time.Now()}
+	report.Excursions = m.findExcursions(readings)
+	if len(report.Excursions) == 0 {
+		return report, nil
+	}
+
+	items, err := m.activeItems()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exc := range report.Excursions {
+		for _, item := range items {
+			e := item.current
+			if !sameLocation(e.StorageLocation, exc.Unit) {
+				continue
+			}
+			report.Warnings = append(report.Warnings, BiologicalWarning{
+				Kind:       WarningExcursion,
+				EvidenceID: e.ID,
+				CaseID:     e.CaseID,
+				Message: fmt.Sprintf("%s sample %s: %s was outside its range from %s to %s (%.1f°C to %.1f°C over %d readings)",
+					item.bio.BiologicalType, sampleLabel(item.bio), exc.Unit,
+					exc.Start.Format("2006-01-02 15:04"), exc.End.Format("2006-01-02 15:04"),
+					exc.MinTempC, exc.MaxTempC, exc.Readings),
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// WriteWarningsToCases adds one note per case summarizing its warnings
+func (m *BiologicalMonitor) WriteWarningsToCases(report *MonitorReport) error {
+	if m.notes == nil {
+		return fmt.Errorf("no case note writer configured")
+	}
+
+	byCase := make(map[string][]string)
+	var caseIDs []string
+	for _, w := range report.Warnings {
+		if w.CaseID == "" {
+			continue
+		}
+		if _, ok := byCase[w.CaseID]; !ok {
+			caseIDs = append(caseIDs, w.CaseID)
+		}
+		byCase[w.CaseID] = append(byCase[w.CaseID], fmt.Sprintf("[%s] %s: %s", w.Kind, w.EvidenceID, w.Message))
+	}
+
+	for _, caseID := range caseIDs {
+		title := fmt.Sprintf("Biological evidence warnings (%s)", report.GeneratedAt.Format("2006-01-02"))
+		if err := m.notes.AddCaseNote(caseID, title, strings.Join(byCase[caseID], "\n")); err != nil {
+			return fmt.Errorf("failed to write warnings to case %s: %w", caseID, err)
+		}
+	}
+	return nil
+}
+
+// monitoredItem pairs biological details with the current evidence record
+type monitoredItem struct {
+	bio     *BiologicalEvidence
+	current *Evidence
+}
+
+// activeItems returns biological items that have not been disposed of. The
+// current evidence record is used for location and status since transfers
+// update it rather than the biological details.
+func (m *BiologicalMonitor) activeItems() ([]monitoredItem, error) {
+	bios, err := m.bioRepo.ListBiological()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list biological evidence: %w", err)
+	}
+
+	var items []monitoredItem
+	for _, b := range bios {
+		current, err := m.service.GetEvidence(b.ID)
+		if err != nil {
+			current = &b.Evidence
+		}
+		if current.IsDisposed() {
+			continue
+		}
+		items = append(items, monitoredItem{bio: b, current: current})
+	}
+	return items, nil
+}
+
+// findExcursions groups consecutive out-of-range readings per unit
+func (m *BiologicalMonitor) findExcursions(readings []TemperatureReading) []Excursion {
+	byUnit := make(map[string][]TemperatureReading)
+	var units []string
+	for _, r := range readings {
+		key := strings.ToLower(strings.TrimSpace(r.Unit))
+		if _, ok := byUnit[key]; !ok {
+			units = append(units, key)
+		}
+		byUnit[key] = append(byUnit[key], r)
+	}
+
+	var excursions []Excursion
+	for _, key := range units {
+		unitReadings := byUnit[key]
+		sort.Slice(unitReadings, func(i, j int) bool {
+			return unitReadings[i].Timestamp.Before(unitReadings[j].Timestamp)
+		})
+
+		unit := m.storageUnit(unitReadings[0].Unit)
+		if unit.Condition == ConditionUnknown {
+			continue
+		}
+
+		var current *Excursion
+		for _, r := range unitReadings {
+			if r.TempC >= unit.MinTempC && r.TempC <= unit.MaxTempC {
+				if current != nil {
+					excursions = append(excursions, *current)
+					current = nil
+				}
+				continue
+			}
+			if current == nil {
+				current = &Excursion{Unit: r.Unit, Start: r.Timestamp, MinTempC: r.TempC, MaxTempC: r.TempC}
+			}
+			current.End = r.Timestamp
+			current.Readings++
+			if r.TempC < current.MinTempC {
+				current.MinTempC = r.TempC
+			}
+			if r.TempC > current.MaxTempC {
+				current.MaxTempC = r.TempC
+			}
+		}
+		if current != nil {
+			excursions = append(excursions, *current)
+		}
+	}
+	return excursions
+}
+
+// temperatureTimeFormats are the timestamp layouts accepted in temperature logs
+var temperatureTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+}
+
+// ParseTemperatureLog parses a CSV temperature log. The header must contain
+// a timestamp column ("timestamp", "time" or "date") and a temperature
+// column ("temp..."); a "unit", "location" or "sensor" column is optional.
+// Temperature columns whose header ends in "F" or "(F)" are converted from
+// Fahrenheit.
+func ParseTemperatureLog(r io.Reader, defaultUnit string) ([]TemperatureReading, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read temperature log header: %w", err)
+	}
+
+	timeCol, tempCol, unitCol := -1, -1, -1
+	fahrenheit := false
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(h))
+		switch {
+		case timeCol < 0 && (strings.Contains(name, "timestamp") || name == "time" || name == "date" || name == "datetime"):
+			timeCol = i
+		case tempCol < 0 && strings.HasPrefix(name, "temp"):
+			tempCol = i
+			fahrenheit = strings.HasSuffix(name, "f") || strings.HasSuffix(name, "(f)") || strings.HasSuffix(name, "°f")
+		case unitCol < 0 && (name == "unit" || name == "location" || name == "sensor" || name == "storage_unit"):
+			unitCol = i
+		}
+	}
+	if timeCol < 0 || tempCol < 0 {
+		return nil, fmt.Errorf("temperature log must have timestamp and temperature columns")
+	}
+	if unitCol < 0 && defaultUnit == "" {
+		return nil, fmt.Errorf("temperature log has no unit column and no unit was given")
+	}
+
+	var readings []TemperatureReading
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(record) <= timeCol || len(record) <= tempCol {
+			continue
+		}
+
+		ts, err := parseLogTime(record[timeCol])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		temp, err := strconv.ParseFloat(strings.TrimSpace(record[tempCol]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid temperature %q", line, record[tempCol])
+		}
+		if fahrenheit {
+			temp = (temp - 32) * 5 / 9
+		}
+
+		unit := defaultUnit
+		if unitCol >= 0 && unitCol < len(record) && strings.TrimSpace(record[unitCol]) != "" {
+			unit = strings.TrimSpace(record[unitCol])
+		}
+
+		readings = append(readings, TemperatureReading{Unit: unit, Timestamp: ts, TempC: temp})
+	}
+
+	return readings, nil
+}
+
+// parseLogTime parses a timestamp in any supported layout. Timestamps
+// without a zone are in local time, as recorded by the unit's logger.
+func parseLogTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range temperatureTimeFormats {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", value)
+}
+
+// sampleLabel identifies a sample in warning messages
+func sampleLabel(b *BiologicalEvidence) string {
+	if b.SampleID != "" {
+		return b.SampleID
+	}
+	if b.EvidenceNumber != "" {
+		return b.EvidenceNumber
+	}
+	return b.ID
+}
@@ -0,0 +1,179 @@
+package evidence
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memBioRepo is an in-memory BiologicalRepository for tests
+type memBioRepo struct {
+	items []*BiologicalEvidence
+}
+
+func (r *memBioRepo) SaveBiological(b *BiologicalEvidence) error {
+	r.items = append(r.items, b)
+	return nil
+}
+
+func (r *memBioRepo) FindBiological(id string) (*BiologicalEvidence, error) {
+	for _, b := range r.items {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("biological evidence not found: %s", id)
+}
+
+func (r *memBioRepo) ListBiological() ([]*BiologicalEvidence, error) {
+	return r.items, nil
+}
+
+func newBiologicalMonitor(t *testing.T, samples ...*BiologicalEvidence) *BiologicalMonitor {
+	t.Helper()
+	m := NewBiologicalMonitor(NewEvidenceService(newMemRepo()), &memBioRepo{}, nil)
+	for _, b := range samples {
+		b.CaseID, b.CollectedBy, b.CollectionDate = "CASE-1", "Officer A", time.Now()
+		if err := m.CreateBiologicalEvidence(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestParseStorageCondition(t *testing.T) {
+	tests := []struct {
+		value string
+		want  StorageCondition
+	}{
+		{"Frozen", ConditionFrozen},
+		{"Freezer 1", ConditionFrozen},
+		{"ROOM 12 FREEZER", ConditionFrozen},
+		{"-20C freezer", ConditionFrozen},
+		{"Refrigerate, do not freeze", ConditionRefrigerated},
+		{"Fridge 2", ConditionRefrigerated},
+		{"Dry Room", ConditionDry},
+		{"Room temperature", ConditionDry},
+		{"Freeze-dried", ConditionDry},
+		{"ambient", ConditionDry},
+
+		// Words that only contain a keyword, or places that are not storage
+		// conditions
+		{"COOLER SHELF", ConditionUnknown},
+		{"ROOM 12", ConditionUnknown},
+		{"Evidence Room", ConditionUnknown},
+		{"Laundry shelf", ConditionUnknown},
+		{"Antifreeze bottles", ConditionUnknown},
+		{"Not frozen", ConditionUnknown},
+		{"Fridge or freezer", ConditionUnknown},
+		{"", ConditionUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ParseStorageCondition(tt.value); got != tt.want {
+				t.Errorf("ParseStorageCondition(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStorageConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		bioType  string
+		location string
+		want     bool // Whether a condition mismatch is reported
+	}{
+		{"refrigerated blood", "BLOOD", "Refrigerator 1", false},
+		{"frozen DNA in a fridge", "DNA", "Fridge 2", true},
+		{"frozen tissue in the dry room", "TISSUE", "Dry Room", true},
+		{"unknown location", "BLOOD", "Evidence Locker", false},
+		{"unknown sample type", "FIBER", "Freezer 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &BiologicalEvidence{BiologicalType: tt.bioType}
+			b.StorageLocation = tt.location
+			m := newBiologicalMonitor(t, b)
+			report, err := m.Check(30 * 24 * time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := false
+			for _, w := range report.Warnings {
+				got = got || w.Kind == WarningConditionMismatch
+			}
+			if got != tt.want {
+				t.Errorf("mismatch reported = %v, want %v (%+v)", got, tt.want, report.Warnings)
+			}
+		})
+	}
+}
+
+func TestCheckExpiration(t *testing.T) {
+	expired := &BiologicalEvidence{BiologicalType: "BLOOD", ExpirationDate: time.Now().Add(-time.Hour)}
+	expiring := &BiologicalEvidence{BiologicalType: "BLOOD", ExpirationDate: time.Now().Add(48 * time.Hour)}
+	later := &BiologicalEvidence{BiologicalType: "BLOOD", ExpirationDate: time.Now().Add(90 * 24 * time.Hour)}
+	for _, b := range []*BiologicalEvidence{expired, expiring, later} {
+		b.StorageLocation = "Refrigerator 1"
+	}
+	m := newBiologicalMonitor(t, expired, expiring, later)
+
+	report, err := m.Check(7 * 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]WarningKind)
+	for _, w := range report.Warnings {
+		kinds[w.EvidenceID] = w.Kind
+	}
+	if kinds[expired.ID] != WarningExpired || kinds[expiring.ID] != WarningExpiring || kinds[later.ID] != "" {
+		t.Errorf("warnings = %+v", report.Warnings)
+	}
+}
+
+func TestParseLogTimeIsLocal(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-03-01 14:30", time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local)},
+		{"03/01/2024 14:30:15", time.Date(2024, 3, 1, 14, 30, 15, 0, time.Local)},
+		{"2024-03-01T14:30:00Z", time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseLogTime(tt.value)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseLogTime(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := parseLogTime("yesterday"); err == nil {
+		t.Error("parseLogTime accepted an unrecognized timestamp")
+	}
+}
+
+func TestIngestTemperatureLog(t *testing.T) {
+	b := &BiologicalEvidence{BiologicalType: "DNA"}
+	b.StorageLocation = "Freezer 2"
+	m := newBiologicalMonitor(t, b)
+
+	log := "timestamp,unit,temp_f\n" +
+		"2024-03-01 10:00,Freezer 2,-4\n" +
+		"2024-03-01 11:00,Freezer 2,20\n" +
+		"2024-03-01 12:00,Freezer 2,25\n" +
+		"2024-03-01 13:00,Freezer 2,-4\n"
+	report, err := m.IngestTemperatureLog(strings.NewReader(log), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Excursions) != 1 || report.Excursions[0].Readings != 2 {
+		t.Fatalf("excursions = %+v", report.Excursions)
+	}
+	if start := report.Excursions[0].Start; !start.Equal(time.Date(2024, 3, 1, 11, 0, 0, 0, time.Local)) {
+		t.Errorf("excursion starts at %v", start)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].EvidenceID != b.ID {
+		t.Errorf("warnings = %+v", report.Warnings)
+	}
+}
@@ -0,0 +1,606 @@
+package evidence
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/chat"
+)
+
+// ChatMessage is a message read from a messaging app export
+type ChatMessage struct {
+	ID           string
+	EvidenceID   string // Evidence item holding the export
+	CaseID       string
+	Platform     chat.Format
+	Conversation string
+	Sender       string
+	Timestamp    time.Time // In the zone the export was read in
+	Text         string
+	Media        []ChatMedia
+	MediaOmitted bool // Media was sent but left out of the export
+	System       bool
+	Position     int // Line, record or message number in the export
+}
+
+// ChatMedia is a media file referenced by a message
+type ChatMedia struct {
+	Filename   string // As written in the export
+	EvidenceID string // Child evidence item, when the file was in the export
+	Missing    bool
+}
+
+// ChatImportOptions control how a chat export is read
+type ChatImportOptions struct {
+	Operator  string         // Defaults to the source's collector
+	Location  *time.Location // Zone of timestamps recorded without one; defaults to the local zone
+	DateOrder chat.DateOrder
+}
+
+// ChatConversationSummary describes a conversation found in an export
+type ChatConversationSummary struct {
+	Title        string
+	Participants []string
+	Messages     int
+	First, Last  time.Time
+	AssumedZone  string // Zone timestamps recorded without one were read in
+}
+
+// ChatImportReport summarizes the messages read from a chat export
+type ChatImportReport struct {
+	EvidenceID    string
+	Platform      chat.Format
+	Conversations []ChatConversationSummary
+	Messages      int
+	Media         int // Media files linked as child evidence
+	MissingMedia  int // References to files that were not in the export
+	Warnings      []string
+}
+
+// ChatRepository stores chat messages
+type ChatRepository interface {
+	SaveChatMessage(m *ChatMessage) error
+	FindChatMessagesByEvidence(evidenceID string) ([]*ChatMessage, error)
+	FindChatMessagesByCase(caseID string) ([]*ChatMessage, error)
+}
+
+// ChatService reads chat exports into messages and searches them
+type ChatService struct {
+	service *EvidenceService
+	repo    ChatRepository
+	indexes map[string]*chatIndex // Search index per case, built on first search
+}
+
+// NewChatService creates a new chat service
+func NewChatService(service *EvidenceService, repo ChatRepository) *ChatService {
+	return &ChatService{
+		service: service,
+		repo:    repo,
+		indexes: make(map[string]*chatIndex),
+	}
+}
+
+// ImportChat reads the WhatsApp, Telegram or CSV chat export of a digital
+// evidence item, which may be the export file or its unpacked directory.
+// Media files included in the export become child evidence items.
+func (s *ChatService) ImportChat(source *DigitalEvidence, opts ChatImportOptions) (*ChatImportReport, error) {
+	if source.ID == "" {
+		return nil, fmt.Errorf("the chat export must be saved before it is processed")
+	}
+	if source.IsDisposed() {
+		return nil, ErrEvidenceDisposed
+	}
+	if opts.Operator == "" {
+		opts.Operator = source.CollectedBy
+	}
+	if existing, err := s.repo.FindChatMessagesByEvidence(source.ID); err == nil && len(existing) > 0 {
+		return nil, fmt.Errorf("chat in %s has already been processed (%d messages)", source.ID, len(existing))
+	}
+	if err := verifyRecordedHash(source); err != nil {
+		return nil, err
+	}
+
+	conversations, err := chat.ParseFile(source.FilePath, chat.Options{Location: opts.Location, DateOrder: opts.DateOrder})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ChatImportReport{EvidenceID: source.ID, Platform: conversations[0].Format}
+	media := make(map[string]string) // Media path to child evidence ID
+	for _, c := range conversations {
+		summary := ChatConversationSummary{Title: c.Title, Participants: c.Participants, Messages: len(c.Messages), AssumedZone: c.AssumedZone}
+		if len(c.Messages) > 0 {
+			summary.First = c.Messages[0].Timestamp
+			summary.Last = c.Messages[len(c.Messages)-1].Timestamp
+		}
+		report.Conversations = append(report.Conversations, summary)
+		for _, w := range c.Warnings {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: %s", c.Title, w))
+		}
+
+		for _, m := range c.Messages {
+			record := &ChatMessage{
+				ID:           generateID("CM"),
+				EvidenceID:   source.ID,
+				CaseID:       source.CaseID,
+				Platform:     c.Format,
+				Conversation: c.Title,
+				Sender:       m.Sender,
+				Timestamp:    m.Timestamp,
+				Text:         m.Text,
+				MediaOmitted: m.MediaOmitted,
+				System:       m.System,
+				Position:     m.Position,
+			}
+			for _, name := range m.Media {
+				item, err := s.linkMedia(source, c, name, media, report, opts.Operator)
+				if err != nil {
+					report.Warnings = append(report.Warnings, fmt.Sprintf("%s: media %s: %v", c.Title, name, err))
+				}
+				record.Media = append(record.Media, item)
+			}
+
+			if err := s.repo.SaveChatMessage(record); err != nil {
+				return report, fmt.Errorf("failed to save message: %w", err)
+			}
+			report.Messages++
+		}
+	}
+	delete(s.indexes, source.CaseID)
+
+	if err := s.markProcessed(source, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// Messages returns the messages read from an evidence item in export order
+func (s *ChatService) Messages(evidenceID string) ([]*ChatMessage, error) {
+	return s.repo.FindChatMessagesByEvidence(evidenceID)
+}
+
+// SearchChats finds the messages of a case containing every word of the
+// query in their text or sender. Matching ignores case and accents, and a
+// word ending in * matches any word it starts.
+func (s *ChatService) SearchChats(caseID, query string) ([]*ChatMessage, error) {
+	terms := chatTokens(query, true)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("the search query has no words")
+	}
+	index, err := s.index(caseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []int
+	for i, term := range terms {
+		matches := index.lookup(term)
+		if i == 0 {
+			hits = matches
+		} else {
+			hits = intersect(hits, matches)
+		}
+		if len(hits) == 0 {
+			return nil, nil
+		}
+	}
+
+	results := make([]*ChatMessage, len(hits))
+	for i, n := range hits {
+		results[i] = index.messages[n]
+	}
+	return results, nil
+}
+
+// Transcript builds a conversation view of the chats of a case, or of a
+// single evidence item when evidenceID is set
+func (s *ChatService) Transcript(caseID, evidenceID string) (*ChatTranscript, error) {
+	var messages []*ChatMessage
+	var err error
+	if evidenceID != "" {
+		messages, err = s.repo.FindChatMessagesByEvidence(evidenceID)
+	} else {
+		messages, err = s.repo.FindChatMessagesByCase(caseID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no chat messages found")
+	}
+
+	if caseID == "" {
+		caseID = messages[0].CaseID
+	}
+	t := &ChatTranscript{CaseID: caseID, GeneratedAt: time.Now()}
+	byKey := make(map[string]*ChatThread)
+	for _, m := range messages {
+		key := m.EvidenceID + "\x00" + m.Conversation
+		thread, ok := byKey[key]
+		if !ok {
+			thread = &ChatThread{Title: m.Conversation, Platform: m.Platform, EvidenceID: m.EvidenceID}
+			byKey[key] = thread
+			t.Threads = append(t.Threads, thread)
+		}
+		thread.Messages = append(thread.Messages, m)
+	}
+	for _, thread := range t.Threads {
+		sort.SliceStable(thread.Messages, func(i, j int) bool {
+			return thread.Messages[i].Timestamp.Before(thread.Messages[j].Timestamp)
+		})
+	}
+	return t, nil
+}
+
+// linkMedia records a media file of the export as evidence extracted from
+// it. Files referenced by several messages are recorded once.
+func (s *ChatService) linkMedia(source *DigitalEvidence, c *chat.Conversation, name string, media map[string]string, report *ChatImportReport, operator string) (ChatMedia, error) {
+	item := ChatMedia{Filename: name}
+	path := filepath.Join(c.Dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(c.Dir, path); err != nil || strings.HasPrefix(rel, "..") {
+		item.Missing = true
+		report.MissingMedia++
+		return item, fmt.Errorf("path is outside the export")
+	}
+	if id, ok := media[path]; ok {
+		item.EvidenceID = id
+		return item, nil
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		item.Missing = true
+		report.MissingMedia++
+		return item, nil
+	}
+
+	child := &DigitalEvidence{
+		Evidence: Evidence{
+			CaseID:          source.CaseID,
+			EvidenceNumber:  childEvidenceNumber(source.EvidenceNumber, len(media)+1),
+			Description:     fmt.Sprintf("%s (media from %s chat %q)", filepath.Base(name), c.Format, c.Title),
+			Location:        source.Location,
+			StorageLocation: source.StorageLocation,
+			IsConfidential:  source.IsConfidential,
+		},
+		FilePath:     path,
+		DeviceSource: source.DeviceSource,
+	}
+	req := DerivationRequest{
+		ParentID:   source.ID,
+		Relation:   RelationExtractedFrom,
+		Tool:       strings.ToLower(string(c.Format)) + " chat export",
+		Operator:   operator,
+		SourcePath: name,
+	}
+	if err := s.service.DeriveDigitalEvidence(child, req); err != nil {
+		return item, err
+	}
+
+	media[path] = child.ID
+	item.EvidenceID = child.ID
+	report.Media++
+	return item, nil
+}
+
+// markProcessed tags the source and records what was found. The stored
+// record is reloaded so that the links added for media are kept.
+func (s *ChatService) markProcessed(source *DigitalEvidence, report *ChatImportReport) error {
+	current, err := s.service.repo.Find(source.ID)
+	if err != nil {
+		return fmt.Errorf("evidence not found: %w", err)
+	}
+
+	note := fmt.Sprintf("Chat processed: %d messages in %d conversations (%s)",
+		report.Messages, len(report.Conversations), report.Platform)
+	var zones []string
+	for _, c := range report.Conversations {
+		if c.AssumedZone != "" {
+			zones = appendUnique(zones, c.AssumedZone)
+		}
+	}
+	if len(zones) > 0 {
+		note += fmt.Sprintf("; times without a zone read as %s", strings.Join(zones, ", "))
+	}
+	current.Tags = appendUnique(current.Tags, "chat")
+	current.Notes = joinResults(current.Notes, note)
+	if err := s.service.UpdateEvidence(current); err != nil {
+		return err
+	}
+
+	source.Evidence = *current
+	return nil
+}
+
+// index returns the search index of a case, building it if needed
+func (s *ChatService) index(caseID string) (*chatIndex, error) {
+	if index, ok := s.indexes[caseID]; ok {
+		return index, nil
+	}
+	messages, err := s.repo.FindChatMessagesByCase(caseID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Timestamp.Before(messages[j].Timestamp) })
+
+	index := &chatIndex{messages: messages, postings: make(map[string][]int)}
+	for n, m := range messages {
+		for _, token := range chatTokens(m.Sender+" "+m.Text, false) {
+			list := index.postings[token]
+			if len(list) == 0 || list[len(list)-1] != n {
+				index.postings[token] = append(list, n)
+			}
+		}
+	}
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+	sort.Strings(index.terms)
+
+	s.indexes[caseID] = index
+	return index, nil
+}
+
+// chatIndex is an inverted index of the words in a case's chat messages
+type chatIndex struct {
+	messages []*ChatMessage   // Oldest first
+	postings map[string][]int // Word to ascending message numbers
+	terms    []string         // Sorted words, for prefix searches
+}
+
+// lookup returns the messages containing a word, or any word starting with
+// it when it ends in *
+func (x *chatIndex) lookup(term string) []int {
+	prefix, ok := strings.CutSuffix(term, "*")
+	if !ok {
+		return x.postings[term]
+	}
+
+	var result []int
+	for i := sort.SearchStrings(x.terms, prefix); i < len(x.terms) && strings.HasPrefix(x.terms[i], prefix); i++ {
+		result = union(result, x.postings[x.terms[i]])
+	}
+	return result
+}
+
+// accentFold maps accented Latin letters to their base letters
+var accentFold = map[rune]rune{
+	'á': 'a', 'à': 'a', 'ä': 'a', 'â': 'a', 'ã': 'a',
+	'é': 'e', 'è': 'e', 'ë': 'e', 'ê': 'e',
+	'í': 'i', 'ì': 'i', 'ï': 'i', 'î': 'i',
+	'ó': 'o', 'ò': 'o', 'ö': 'o', 'ô': 'o', 'õ': 'o',
+	'ú': 'u', 'ù': 'u', 'ü': 'u', 'û': 'u',
+	'ñ': 'n', 'ç': 'c',
+}
+
+// chatTokens splits text into lowercase words without accents. Query words
+// keep a trailing * for prefix matching.
+func chatTokens(text string, query bool) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !(query && r == '*')
+	})
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		w = strings.Map(func(r rune) rune {
+			if folded, ok := accentFold[r]; ok {
+				return folded
+			}
+			return r
+		}, w)
+		if query {
+			// Only a trailing * is a wildcard
+			star := strings.HasSuffix(w, "*")
+			w = strings.ReplaceAll(w, "*", "")
+			if w == "" {
+				continue
+			}
+			if star {
+				w += "*"
+			}
+		}
+		tokens = append(tokens, w)
+	}
+	return tokens
+}
+
+// intersect returns the numbers in both ascending lists
+func intersect(a, b []int) []int {
+	var result []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// union returns the numbers in either ascending list
+func union(a, b []int) []int {
+	result := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+// TranscriptFormat identifies an output format for chat transcripts
+type TranscriptFormat string
+
+const (
+	TranscriptText TranscriptFormat = "TEXT"
+	TranscriptHTML TranscriptFormat = "HTML"
+)
+
+// ChatTranscript is a readable view of chat conversations
+type ChatTranscript struct {
+	CaseID      string
+	GeneratedAt time.Time
+	Threads     []*ChatThread
+}
+
+// ChatThread is one conversation of a transcript, oldest message first
+type ChatThread struct {
+	Title      string
+	Platform   chat.Format
+	EvidenceID string
+	Messages   []*ChatMessage
+}
+
+// Render writes the transcript in the requested format
+func (t *ChatTranscript) Render(w io.Writer, format TranscriptFormat) error {
+	switch TranscriptFormat(strings.ToUpper(string(format))) {
+	case TranscriptText, "TXT", "":
+		return t.RenderText(w)
+	case TranscriptHTML:
+		return t.RenderHTML(w)
+	default:
+		return fmt.Errorf("unsupported transcript format: %s", format)
+	}
+}
+
+// RenderText writes the transcript as plain text with a line per message
+// and a separator at each new day
+func (t *ChatTranscript) RenderText(w io.Writer) error {
+	var b strings.Builder
+	for i, thread := range t.Threads {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		heading := fmt.Sprintf("%s - %s (%s)", thread.Platform, thread.Title, thread.EvidenceID)
+		fmt.Fprintf(&b, "%s\n%s\n", heading, strings.Repeat("=", len([]rune(heading))))
+
+		day := ""
+		for _, m := range thread.Messages {
+			if d := m.Timestamp.Format("2006-01-02 (Mon) MST"); d != day {
+				day = d
+				fmt.Fprintf(&b, "\n--- %s ---\n", day)
+			}
+			text := strings.ReplaceAll(chatMessageText(m), "\n", "\n           ")
+			if m.System {
+				fmt.Fprintf(&b, "[%s] * %s\n", m.Timestamp.Format("15:04:05"), text)
+			} else {
+				fmt.Fprintf(&b, "[%s] %s: %s\n", m.Timestamp.Format("15:04:05"), m.Sender, text)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RenderHTML writes the transcript as an HTML document in the style of a
+// messaging app
+func (t *ChatTranscript) RenderHTML(w io.Writer) error {
+	esc := html.EscapeString
+	var b strings.Builder
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>Chat transcript - Case %s</title>\n", esc(t.CaseID))
+	b.WriteString(`<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 10pt; margin: 2em; background: #f4f4f4; }
+h1 { font-size: 16pt; text-align: center; }
+h2 { font-size: 13pt; border-bottom: 2px solid #000; page-break-before: always; }
+h2.first { page-break-before: auto; }
+.meta { text-align: center; color: #555; }
+.day { text-align: center; margin: 1em 0; color: #555; font-weight: bold; }
+.msg { background: #fff; border: 1px solid #ccc; border-radius: 6px; padding: 4px 8px; margin: 4px 0; max-width: 40em; page-break-inside: avoid; }
+.system { text-align: center; color: #555; font-style: italic; margin: 4px 0; }
+.sender { font-weight: bold; }
+.time { color: #777; font-size: 8pt; float: right; margin-left: 1em; }
+.text { white-space: pre-wrap; }
+.media { color: #245; font-size: 9pt; }
+</style>
+</head>
+<body>
+`)
+	fmt.Fprintf(&b, "<h1>Chat transcript - Case %s</h1>\n", esc(t.CaseID))
+	fmt.Fprintf(&b, "<p class=\"meta\">Generated %s</p>\n", esc(t.GeneratedAt.Format(custodyTimeFormat)))
+
+	for i, thread := range t.Threads {
+		class := ""
+		if i == 0 {
+			class = ` class="first"`
+		}
+		fmt.Fprintf(&b, "<h2%s>%s - %s</h2>\n", class, esc(string(thread.Platform)), esc(thread.Title))
+		fmt.Fprintf(&b, "<p class=\"meta\">Evidence %s | %d messages</p>\n", esc(thread.EvidenceID), len(thread.Messages))
+
+		day := ""
+		for _, m := range thread.Messages {
+			if d := m.Timestamp.Format("Monday, 2006-01-02 (MST)"); d != day {
+				day = d
+				fmt.Fprintf(&b, "<div class=\"day\">%s</div>\n", esc(day))
+			}
+			if m.System {
+				fmt.Fprintf(&b, "<div class=\"system\">%s %s</div>\n", esc(m.Timestamp.Format("15:04:05")), esc(chatMessageText(m)))
+				continue
+			}
+			fmt.Fprintf(&b, "<div class=\"msg\"><span class=\"time\">%s</span><span class=\"sender\">%s</span>",
+				esc(m.Timestamp.Format("15:04:05")), esc(m.Sender))
+			if m.Text != "" {
+				fmt.Fprintf(&b, "<div class=\"text\">%s</div>", esc(m.Text))
+			}
+			for _, media := range m.Media {
+				fmt.Fprintf(&b, "<div class=\"media\">%s</div>", esc(chatMediaLabel(media)))
+			}
+			if m.MediaOmitted {
+				b.WriteString("<div class=\"media\">[media not included in export]</div>")
+			}
+			b.WriteString("</div>\n")
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// chatMessageText returns a message's text followed by its media
+func chatMessageText(m *ChatMessage) string {
+	parts := []string{}
+	if m.Text != "" {
+		parts = append(parts, m.Text)
+	}
+	for _, media := range m.Media {
+		parts = append(parts, chatMediaLabel(media))
+	}
+	if m.MediaOmitted {
+		parts = append(parts, "[media not included in export]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// chatMediaLabel describes a media reference and the evidence it became
+func chatMediaLabel(media ChatMedia) string {
+	switch {
+	case media.Missing:
+		return fmt.Sprintf("[media: %s - missing from export]", media.Filename)
+	case media.EvidenceID != "":
+		return fmt.Sprintf("[media: %s - evidence %s]", media.Filename, media.EvidenceID)
+	}
+	return fmt.Sprintf("[media: %s]", media.Filename)
+}
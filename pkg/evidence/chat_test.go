@@ -0,0 +1,263 @@
+package evidence
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/chat"
+)
+
+// memChatRepo is an in-memory ChatRepository for tests
+type memChatRepo struct {
+	messages []*ChatMessage
+}
+
+func (r *memChatRepo) SaveChatMessage(m *ChatMessage) error {
+	r.messages = append(r.messages, m)
+	return nil
+}
+
+func (r *memChatRepo) FindChatMessagesByEvidence(evidenceID string) ([]*ChatMessage, error) {
+	var result []*ChatMessage
+	for _, m := range r.messages {
+		if m.EvidenceID == evidenceID {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+func (r *memChatRepo) FindChatMessagesByCase(caseID string) ([]*ChatMessage, error) {
+	var result []*ChatMessage
+	for _, m := range r.messages {
+		if m.CaseID == caseID {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// chatZone is the zone the test exports are read in
+var chatZone = time.FixedZone("VET", -4*60*60)
+
+// chatExport is an unpacked Android export in Spanish. The photo is sent
+// twice, the video was left out of the unpacked folder and the audio was
+// not exported at all.
+const chatExport = "31/12/23, 9:15 p. m. - Los mensajes y las llamadas están cifrados de extremo a extremo.\n" +
+	"31/12/23, 9:16 p. m. - Pedro Pérez: ¿Llegó el envío?\n" +
+	"31/12/23, 9:17 p. m. - Ana: IMG-20231231-WA0001.jpg (archivo adjunto)\n" +
+	"Aquí está la transferencia\n" +
+	"01/01/24, 8:00 a. m. - Ana: IMG-20231231-WA0001.jpg (archivo adjunto)\n" +
+	"01/01/24, 8:01 a. m. - Ana: VID-20240101-WA0002.mp4 (archivo adjunto)\n" +
+	"01/01/24, 8:02 a. m. - Pedro Pérez: <Multimedia omitido>\n" +
+	"01/01/24, 8:03 a. m. - Ana cambió el asunto a \"Envíos: enero\"\n" +
+	"01/01/24, 8:04 a. m. - Pedro Pérez: Transferencia <recibida> & confirmada\n"
+
+// newChatImport imports chatExport as an unpacked export directory
+func newChatImport(t *testing.T) (*EvidenceService, *ChatService, *DigitalEvidence, *ChatImportReport) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "export")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"Chat de WhatsApp con Pedro Pérez.txt": chatExport,
+		"IMG-20231231-WA0001.jpg":              "\xff\xd8\xff\xe0 photo",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewEvidenceService(newMemRepo())
+	s.SetDigitalRepository(newMemDigitalRepo())
+	source := &DigitalEvidence{
+		Evidence: Evidence{CaseID: "CASE-1", EvidenceNumber: "E-7", Type: TypeDigital, Description: "WhatsApp export",
+			CollectedBy: "Officer A", CollectionDate: time.Now(), StorageLocation: "Locker 1"},
+		FilePath: dir,
+		FileType: "CHAT_EXPORT",
+	}
+	if err := s.CreateEvidence(&source.Evidence); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateDigitalEvidence(source); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewChatService(s, &memChatRepo{})
+	report, err := c.ImportChat(source, ChatImportOptions{Location: chatZone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, c, source, report
+}
+
+func TestImportChat(t *testing.T) {
+	s, c, source, report := newChatImport(t)
+
+	if report.Platform != chat.FormatWhatsApp || report.Messages != 8 || len(report.Conversations) != 1 {
+		t.Fatalf("report %+v", report)
+	}
+	summary := report.Conversations[0]
+	if summary.Title != "Pedro Pérez" || strings.Join(summary.Participants, ",") != "Pedro Pérez,Ana" || summary.AssumedZone != "VET" {
+		t.Errorf("conversation %+v", summary)
+	}
+	if report.Media != 1 || report.MissingMedia != 1 || len(report.Warnings) != 0 {
+		t.Errorf("%d media linked, %d missing, warnings %q", report.Media, report.MissingMedia, report.Warnings)
+	}
+
+	messages, err := c.Messages(source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	photo, again, video, omitted := messages[2].Media, messages[3].Media, messages[4].Media, messages[5]
+	if len(photo) != 1 || photo[0].EvidenceID == "" || photo[0].Missing {
+		t.Fatalf("photo %+v", photo)
+	}
+	if len(again) != 1 || again[0].EvidenceID != photo[0].EvidenceID {
+		t.Errorf("photo sent again linked as %+v, want %s", again, photo[0].EvidenceID)
+	}
+	if len(video) != 1 || !video[0].Missing || video[0].EvidenceID != "" {
+		t.Errorf("video %+v", video)
+	}
+	if !omitted.MediaOmitted || len(omitted.Media) != 0 {
+		t.Errorf("omitted media %+v", omitted)
+	}
+	if m := messages[6]; !m.System || m.Sender != "" || m.Text != `Ana cambió el asunto a "Envíos: enero"` {
+		t.Errorf("subject change read as %+v", m)
+	}
+
+	// The photo is child evidence extracted from the export
+	child, err := s.GetDigitalEvidence(photo[0].EvidenceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := child.DerivedFrom
+	if d == nil || d.ParentID != source.ID || d.Relation != RelationExtractedFrom || d.SourcePath != "IMG-20231231-WA0001.jpg" {
+		t.Errorf("derivation %+v", d)
+	}
+	if child.EvidenceNumber != "E-7-0001" || child.CaseID != "CASE-1" || child.FileHash == "" {
+		t.Errorf("child %s in %s, hash %q", child.EvidenceNumber, child.CaseID, child.FileHash)
+	}
+
+	stored, err := s.GetEvidence(source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsString(stored.Tags, "chat") || !containsString(stored.RelatedEvidence, child.ID) {
+		t.Errorf("source tags %v, related %v", stored.Tags, stored.RelatedEvidence)
+	}
+	if !strings.Contains(stored.Notes, "8 messages in 1 conversations (WHATSAPP); times without a zone read as VET") {
+		t.Errorf("notes %q", stored.Notes)
+	}
+
+	if _, err := c.ImportChat(source, ChatImportOptions{Location: chatZone}); err == nil {
+		t.Error("imported the same export twice")
+	}
+}
+
+func TestSearchChats(t *testing.T) {
+	_, c, _, _ := newChatImport(t)
+
+	tests := []struct {
+		query   string
+		want    []int // Positions of the matching messages
+		wantErr bool
+	}{
+		{"envio", []int{2}, false},
+		{"ENVÍOS", []int{8}, false},
+		{"transfer*", []int{3, 9}, false},
+		{"transferencia confirmada", []int{9}, false},
+		{"pedro llego", []int{2}, false},
+		{"ana", []int{3, 5, 6, 8}, false},
+		{"envio*", []int{2, 8}, false},
+		{"transferencia enero", nil, false},
+		{"*", nil, true},
+		{"  ¿? ", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := c.SearchChats("CASE-1", tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			var positions []string
+			for _, m := range got {
+				positions = append(positions, fmt.Sprint(m.Position))
+			}
+			if want := strings.Trim(fmt.Sprint(tt.want), "[]"); strings.Join(positions, " ") != want {
+				t.Errorf("positions %v, want %v", positions, tt.want)
+			}
+		})
+	}
+
+	if got, err := c.SearchChats("CASE-2", "envio"); err != nil || len(got) != 0 {
+		t.Errorf("other case: %d results, err %v", len(got), err)
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	_, c, source, _ := newChatImport(t)
+
+	transcript, err := c.Transcript("", source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcript.CaseID != "CASE-1" || len(transcript.Threads) != 1 || len(transcript.Threads[0].Messages) != 8 {
+		t.Fatalf("transcript %+v", transcript)
+	}
+	if _, err := c.Transcript("CASE-2", ""); err == nil {
+		t.Error("transcript of a case without chats")
+	}
+
+	var text bytes.Buffer
+	if err := transcript.Render(&text, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	photo := transcript.Threads[0].Messages[2].Media[0].EvidenceID
+	for _, want := range []string{
+		"WHATSAPP - Pedro Pérez (" + source.ID + ")\n",
+		"\n--- 2023-12-31 (Sun) VET ---\n",
+		"[21:15:00] * Los mensajes y las llamadas están cifrados de extremo a extremo.\n",
+		"[21:16:00] Pedro Pérez: ¿Llegó el envío?\n",
+		"[21:17:00] Ana: Aquí está la transferencia [media: IMG-20231231-WA0001.jpg - evidence " + photo + "]\n",
+		"\n--- 2024-01-01 (Mon) VET ---\n",
+		"[08:01:00] Ana: [media: VID-20240101-WA0002.mp4 - missing from export]\n",
+		"[08:02:00] Pedro Pérez: [media not included in export]\n",
+		"[08:03:00] * Ana cambió el asunto a \"Envíos: enero\"\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text transcript lacks %q:\n%s", want, text.String())
+		}
+	}
+
+	var page bytes.Buffer
+	if err := transcript.Render(&page, TranscriptHTML); err != nil {
+		t.Fatal(err)
+	}
+	out := page.String()
+	for _, want := range []string{
+		`<h2 class="first">WHATSAPP - Pedro Pérez</h2>`,
+		"<p class=\"meta\">Evidence " + source.ID + " | 8 messages</p>",
+		`<div class="day">Sunday, 2023-12-31 (VET)</div>`,
+		`<div class="system">08:03:00 Ana cambió el asunto a &#34;Envíos: enero&#34;</div>`,
+		`<div class="text">Transferencia &lt;recibida&gt; &amp; confirmada</div>`,
+		`<div class="media">[media: VID-20240101-WA0002.mp4 - missing from export]</div>`,
+		`<div class="media">[media not included in export]</div>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML transcript lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "<recibida>") {
+		t.Error("message text not escaped")
+	}
+	if err := transcript.Render(&page, "pdf"); err == nil {
+		t.Error("rendered an unsupported format")
+	}
+}
@@ -0,0 +1,444 @@
+package evidence
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/pdf"
+)
+
+// CustodyReportFormat identifies an output format for chain-of-custody reports
+type CustodyReportFormat string
+
+const (
+	CustodyText CustodyReportFormat = "TEXT"
+	CustodyHTML CustodyReportFormat = "HTML"
+	CustodyPDF  CustodyReportFormat = "PDF"
+)
+
+// Report layout
+const (
+	custodyReportWidth = 80
+	custodyLabelWidth  = 18
+	custodyTimeFormat  = "2006-01-02 15:04:05 MST"
+	signatureLine      = "______________________________"
+)
+
+// CustodyReport is a printable chain-of-custody record for one or more
+// evidence items, suitable for use as a courtroom exhibit
+type CustodyReport struct {
+	Title       string
+	CaseNumber  string
+	Agency      string
+	PreparedBy  string
+	GeneratedAt time.Time
+	Items       []*Evidence
+}
+
+// reportLine is a line of the text and PDF layouts
+type reportLine struct {
+	text    string
+	heading bool
+	block   int // Lines that follow and must stay on the same page
+}
+
+// NewCustodyReport creates a report for the given items. caseNumber is the
+// official number of the owning case.
+func NewCustodyReport(items []*Evidence, caseNumber, preparedBy string) *CustodyReport {
+	return &CustodyReport{
+		Title:       "CHAIN OF CUSTODY REPORT",
+		CaseNumber:  caseNumber,
+		PreparedBy:  preparedBy,
+		GeneratedAt: time.Now(),
+		Items:       items,
+	}
+}
+
+// CustodyReport builds a report for the given evidence items, or for every
+// item in the case when ids is empty
+func (s *EvidenceService) CustodyReport(caseID string, ids []string, caseNumber, preparedBy string) (*CustodyReport, error) {
+	var items []*Evidence
+	if len(ids) == 0 {
+		if caseID == "" {
+			return nil, fmt.Errorf("a case or at least one evidence ID is required")
+		}
+		found, err := s.repo.FindByCase(caseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find evidence for case: %w", err)
+		}
+		items = found
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].EvidenceNumber != items[j].EvidenceNumber {
+				return items[i].EvidenceNumber < items[j].EvidenceNumber
+			}
+			return items[i].ID < items[j].ID
+		})
+	} else {
+		for _, id := range ids {
+			e, err := s.repo.Find(id)
+			if err != nil {
+				return nil, fmt.Errorf("evidence %s not found: %w", id, err)
+			}
+			items = append(items, e)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no evidence found for case %s", caseID)
+	}
+	if caseNumber == "" {
+		caseNumber = items[0].CaseID
+	}
+	return NewCustodyReport(items, caseNumber, preparedBy), nil
+}
+
+// Render writes the report in the requested format
+func (r *CustodyReport) Render(w io.Writer, format CustodyReportFormat) error {
+	switch CustodyReportFormat(strings.ToUpper(string(format))) {
+	case CustodyText, "TXT":
+		return r.RenderText(w)
+	case CustodyHTML:
+		return r.RenderHTML(w)
+	case CustodyPDF:
+		return r.RenderPDF(w)
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+// RenderText writes the report as plain text
+func (r *CustodyReport) RenderText(w io.Writer) error {
+	var b strings.Builder
+	for i, item := range r.Items {
+		if i > 0 {
+			b.WriteString("\f\n") // Form feed between items when printed
+		}
+		for _, l := range r.itemLines(item, i+1) {
+			b.WriteString(l.text)
+			b.WriteString("\n")
+		}
+	}
+	for _, l := range r.certificationLines() {
+		b.WriteString(l.text)
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RenderPDF writes the report as a PDF document, starting each item on a
+// new page
+func (r *CustodyReport) RenderPDF(w io.Writer) error {
+	doc := pdf.NewDocument(r.Title)
+	doc.Author = r.PreparedBy
+	doc.Footer = fmt.Sprintf("Case %s - %s - generated %s", r.CaseNumber, r.Title, r.GeneratedAt.Format(custodyTimeFormat))
+
+	add := func(lines []reportLine) {
+		for _, l := range lines {
+			if l.block > 0 {
+				doc.KeepTogether(l.block + 1)
+			}
+			style := pdf.Regular
+			if l.heading {
+				style = pdf.Bold
+			}
+			doc.AddLine(l.text, style)
+		}
+	}
+	for i, item := range r.Items {
+		doc.PageBreak()
+		add(r.itemLines(item, i+1))
+	}
+	add(r.certificationLines())
+
+	if _, err := doc.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return nil
+}
+
+// RenderHTML writes the report as a printable HTML document
+func (r *CustodyReport) RenderHTML(w io.Writer) error {
+	esc := html.EscapeString
+	var b strings.Builder
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s - Case %s</title>\n", esc(r.Title), esc(r.CaseNumber))
+	b.WriteString(`<style>
+body { font-family: Georgia, serif; font-size: 11pt; margin: 2em; }
+h1 { font-size: 16pt; text-align: center; }
+h2 { font-size: 13pt; border-bottom: 2px solid #000; page-break-before: always; }
+h2.first { page-break-before: auto; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1em; }
+th, td { border: 1px solid #000; padding: 4px 6px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+table.details th { width: 12em; }
+tr.event { page-break-inside: avoid; }
+.signature { display: inline-block; width: 16em; border-bottom: 1px solid #000; height: 2em; }
+.meta { text-align: center; }
+</style>
+</head>
+<body>
+`)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", esc(r.Title))
+	fmt.Fprintf(&b, "<p class=\"meta\">%s</p>\n", esc(strings.Join(r.headerFields(), " | ")))
+
+	for i, item := range r.Items {
+		class := ""
+		if i == 0 {
+			class = ` class="first"`
+		}
+		fmt.Fprintf(&b, "<h2%s>Item %d of %d: %s</h2>\n", class, i+1, len(r.Items), esc(itemReference(item)))
+
+		b.WriteString("<table class=\"details\">\n")
+		for _, field := range itemFields(item) {
+			fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>\n", esc(field[0]), esc(field[1]))
+		}
+		b.WriteString("</table>\n")
+
+		b.WriteString("<table>\n<tr><th>#</th><th>Date/Time</th><th>Action</th><th>Released by</th>" +
+			"<th>Received by</th><th>Details</th><th>Signatures</th></tr>\n")
+		for n, event := range item.ChainOfCustody {
+			var details []string
+			for _, field := range eventFields(event) {
+				details = append(details, fmt.Sprintf("<b>%s:</b> %s", esc(field[0]), esc(field[1])))
+			}
+			fmt.Fprintf(&b, "<tr class=\"event\"><td>%d</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td>"+
+				"<td>Released:<br><span class=\"signature\"></span><br>Received:<br><span class=\"signature\"></span></td></tr>\n",
+				n+1, esc(eventTime(event)), esc(event.Action), esc(orDash(event.FromPerson)),
+				esc(orDash(event.ToPerson)), strings.Join(details, "<br>"))
+		}
+		b.WriteString("</table>\n")
+		fmt.Fprintf(&b, "<p>Total custody events: %d</p>\n", len(item.ChainOfCustody))
+	}
+
+	b.WriteString("<h2 class=\"first\">Certification</h2>\n")
+	fmt.Fprintf(&b, "<p>%s</p>\n", esc(r.certificationText()))
+	b.WriteString("<p>Signature: <span class=\"signature\"></span> Date: <span class=\"signature\"></span></p>\n")
+	fmt.Fprintf(&b, "<p>Printed name: %s</p>\n", esc(orBlank(r.PreparedBy)))
+	b.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// headerFields returns the case, preparer and generation time
+func (r *CustodyReport) headerFields() []string {
+	fields := []string{"Case: " + r.CaseNumber}
+	if r.Agency != "" {
+		fields = append(fields, "Agency: "+r.Agency)
+	}
+	if r.PreparedBy != "" {
+		fields = append(fields, "Prepared by: "+r.PreparedBy)
+	}
+	return append(fields, "Generated: "+r.GeneratedAt.Format(custodyTimeFormat))
+}
+
+// itemLines lays out one evidence item for the text and PDF formats
+func (r *CustodyReport) itemLines(e *Evidence, number int) []reportLine {
+	rule := strings.Repeat("=", custodyReportWidth)
+	thin := strings.Repeat("-", custodyReportWidth)
+
+	lines := []reportLine{
+		{text: centered(r.Title), heading: true},
+		{text: centered(strings.Join(r.headerFields(), "  "))},
+		{text: rule},
+		{text: fmt.Sprintf("ITEM %d OF %d: %s", number, len(r.Items), itemReference(e)), heading: true},
+		{text: rule},
+	}
+	for _, field := range itemFields(e) {
+		lines = append(lines, labelled(field[0], field[1], "")...)
+	}
+	lines = append(lines, reportLine{}, reportLine{text: "CHAIN OF CUSTODY", heading: true}, reportLine{text: thin})
+
+	for n, event := range e.ChainOfCustody {
+		var entry []reportLine
+		entry = append(entry, reportLine{
+			text:    fmt.Sprintf("%-4s %-24s %s", fmt.Sprintf("%d.", n+1), eventTime(event), event.Action),
+			heading: true,
+		})
+		entry = append(entry, labelled("Released by", orDash(event.FromPerson), "     ")...)
+		entry = append(entry, labelled("Received by", orDash(event.ToPerson), "     ")...)
+		for _, field := range eventFields(event) {
+			entry = append(entry, labelled(field[0], field[1], "     ")...)
+		}
+		entry = append(entry,
+			reportLine{},
+			reportLine{text: fmt.Sprintf("     Released by (signature): %s", signatureLine)},
+			reportLine{},
+			reportLine{text: fmt.Sprintf("     Received by (signature): %s", signatureLine)},
+			reportLine{text: thin},
+		)
+		entry[0].block = len(entry) - 1
+		lines = append(lines, entry...)
+	}
+	lines = append(lines, reportLine{text: fmt.Sprintf("Total custody events: %d", len(e.ChainOfCustody))}, reportLine{})
+	return lines
+}
+
+// certificationLines returns the attestation block signed by the preparer
+func (r *CustodyReport) certificationLines() []reportLine {
+	lines := []reportLine{
+		{text: strings.Repeat("=", custodyReportWidth), block: 9},
+		{text: "CERTIFICATION", heading: true},
+		{},
+	}
+	for _, text := range wrap(r.certificationText(), custodyReportWidth) {
+		lines = append(lines, reportLine{text: text})
+	}
+	return append(lines,
+		reportLine{},
+		reportLine{text: fmt.Sprintf("Signature: %s   Date: ______________", signatureLine)},
+		reportLine{},
+		reportLine{text: "Printed name: " + orBlank(r.PreparedBy)},
+	)
+}
+
+// certificationText is the statement signed by the person preparing the report
+func (r *CustodyReport) certificationText() string {
+	return fmt.Sprintf("I certify that this report is a true and complete copy of the chain-of-custody "+
+		"records maintained for the %d evidence item(s) listed above, as recorded in the evidence "+
+		"management system on %s.", len(r.Items), r.GeneratedAt.Format("January 2, 2006"))
+}
+
+// itemReference identifies an item by its evidence number and ID
+func itemReference(e *Evidence) string {
+	if e.EvidenceNumber != "" {
+		return fmt.Sprintf("%s (%s)", e.EvidenceNumber, e.ID)
+	}
+	return e.ID
+}
+
+// itemFields returns the descriptive fields printed for an item
+func itemFields(e *Evidence) [][2]string {
+	fields := [][2]string{
+		{"Description", e.Description},
+		{"Type", string(e.Type)},
+		{"Status", string(e.Status)},
+		{"Collected by", e.CollectedBy},
+	}
+	if !e.CollectionDate.IsZero() {
+		fields = append(fields, [2]string{"Collected on", e.CollectionDate.Format(custodyTimeFormat)})
+	}
+	if where := strings.Trim(strings.Join([]string{e.Location.Description, e.Location.Address}, ", "), ", "); where != "" {
+		fields = append(fields, [2]string{"Recovered at", where})
+	}
+	if e.CollectionMethod != "" {
+		fields = append(fields, [2]string{"Method", e.CollectionMethod})
+	}
+	fields = append(fields, [2]string{"Current location", orDash(e.StorageLocation)})
+	if e.FileHash != "" {
+		fields = append(fields, [2]string{"SHA-256", e.FileHash})
+	}
+	if e.DerivedFrom != nil {
+		fields = append(fields, [2]string{"Derived from", fmt.Sprintf("%s (%s)",
+			e.DerivedFrom.ParentID, relationLabels[e.DerivedFrom.Relation])})
+	}
+	if d := e.Disposition; d != nil {
+		fields = append(fields, [2]string{"Disposition", fmt.Sprintf("%s on %s, authorized by %s",
+			d.Action, d.Timestamp.Format(custodyTimeFormat), d.AuthorizedBy)})
+	}
+	return fields
+}
+
+// eventFields returns the optional details of a custody event
+func eventFields(event CustodyEvent) [][2]string {
+	var fields [][2]string
+	add := func(label, value string) {
+		if value != "" {
+			fields = append(fields, [2]string{label, value})
+		}
+	}
+	if event.FromLocation != "" || event.ToLocation != "" {
+		add("Location", fmt.Sprintf("%s -> %s", orDash(strings.Trim(event.FromLocation, ", ")), orDash(event.ToLocation)))
+	}
+	add("Reason", event.Reason)
+	add("Authorized by", event.AuthorizedBy)
+	add("Transport", event.TransportMethod)
+	add("Verification", event.VerificationMethod)
+	add("Document", event.DocumentID)
+	add("Notes", event.Notes)
+	add("Event ID", event.ID)
+	return fields
+}
+
+// eventTime formats the time of a custody event
+func eventTime(event CustodyEvent) string {
+	if event.Timestamp.IsZero() {
+		return "(time not recorded)"
+	}
+	return event.Timestamp.Format(custodyTimeFormat)
+}
+
+// labelled formats a "Label: value" field, wrapping long values under the value column
+func labelled(label, value, indent string) []reportLine {
+	prefix := fmt.Sprintf("%s%-*s", indent, custodyLabelWidth, label+":")
+	var lines []reportLine
+	for i, text := range wrap(value, custodyReportWidth-len(prefix)) {
+		if i == 0 {
+			lines = append(lines, reportLine{text: prefix + text})
+		} else {
+			lines = append(lines, reportLine{text: strings.Repeat(" ", len(prefix)) + text})
+		}
+	}
+	return lines
+}
+
+// wrap splits text into lines of at most width characters, breaking long words
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var current []rune
+		for _, word := range strings.Fields(paragraph) {
+			w := []rune(word)
+			for len(w) > width {
+				if len(current) > 0 {
+					lines = append(lines, string(current))
+					current = nil
+				}
+				lines = append(lines, string(w[:width]))
+				w = w[width:]
+			}
+			switch {
+			case len(current) == 0:
+				current = w
+			case len(current)+1+len(w) <= width:
+				current = append(append(current, ' '), w...)
+			default:
+				lines = append(lines, string(current))
+				current = w
+			}
+		}
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// centered centers text within the report width
+func centered(text string) string {
+	if pad := (custodyReportWidth - len([]rune(text))) / 2; pad > 0 {
+		return strings.Repeat(" ", pad) + text
+	}
+	return text
+}
+
+// orDash returns "-" for empty values
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// orBlank returns a blank line to fill in for empty values
+func orBlank(value string) string {
+	if value == "" {
+		return signatureLine
+	}
+	return value
+}
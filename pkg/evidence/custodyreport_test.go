@@ -0,0 +1,238 @@
+package evidence
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/pdf"
+)
+
+// custodyItem builds an item whose chain of custody has the given number of
+// transfers after collection
+func custodyItem(id, number, description string, transfers int) *Evidence {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	e := &Evidence{
+		ID:              id,
+		CaseID:          "CASE-1",
+		EvidenceNumber:  number,
+		Description:     description,
+		Type:            TypePhysical,
+		Status:          StatusInStorage,
+		CollectedBy:     "Officer A",
+		CollectionDate:  start,
+		StorageLocation: "Locker 1",
+		ChainOfCustody: []CustodyEvent{{
+			ID: id + "-CE0", EvidenceID: id, Timestamp: start, Action: "COLLECTED",
+			ToPerson: "Officer A", ToLocation: "Locker 1", Reason: "Initial collection",
+		}},
+	}
+	people := []string{"Officer A", "Tech B", "Analyst C", "Sgt. D"}
+	for i := 1; i <= transfers; i++ {
+		e.ChainOfCustody = append(e.ChainOfCustody, CustodyEvent{
+			ID:                 fmt.Sprintf("%s-CE%d", id, i),
+			EvidenceID:         id,
+			Timestamp:          start.Add(time.Duration(i) * time.Hour),
+			Action:             "TRANSFERRED",
+			FromPerson:         people[(i-1)%len(people)],
+			ToPerson:           people[i%len(people)],
+			FromLocation:       "Locker 1",
+			ToLocation:         "Lab",
+			Reason:             fmt.Sprintf("Transfer %d", i),
+			VerificationMethod: fmt.Sprintf("Seal check %d", i),
+		})
+	}
+	return e
+}
+
+// custodyReport builds a report over two items with more than two transfers each
+func custodyReport(transfers int) *CustodyReport {
+	r := NewCustodyReport([]*Evidence{
+		custodyItem("EV-1", "E-1", "Cuchillo (cocina) con mango \\ roto, señal <b>&</b>", transfers),
+		custodyItem("EV-2", "E-2", "Teléfono", transfers),
+	}, "2024-001", "Det. Ruiz")
+	r.GeneratedAt = time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC)
+	return r
+}
+
+func TestRenderCustodyText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := custodyReport(4).Render(&buf, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	items := strings.Split(out, "\f\n")
+	if len(items) != 2 {
+		t.Fatalf("%d form-feed separated items, want 2", len(items))
+	}
+	for i, item := range items {
+		id := fmt.Sprintf("EV-%d", i+1)
+		if !strings.Contains(item, fmt.Sprintf("ITEM %d OF 2: E-%d (%s)", i+1, i+1, id)) {
+			t.Errorf("item %d heading missing", i+1)
+		}
+		// The collection and all four transfers, each with two signature lines
+		for n := 1; n <= 5; n++ {
+			if !strings.Contains(item, fmt.Sprintf("\n%-4s", fmt.Sprintf("%d.", n))) {
+				t.Errorf("item %d: custody event %d missing", i+1, n)
+			}
+		}
+		for n := 1; n <= 4; n++ {
+			if !strings.Contains(item, fmt.Sprintf("Seal check %d", n)) || !strings.Contains(item, fmt.Sprintf("%s-CE%d", id, n)) {
+				t.Errorf("item %d: transfer %d details missing", i+1, n)
+			}
+		}
+		for _, signature := range []string{"Released by (signature):", "Received by (signature):"} {
+			if got := strings.Count(item, signature); got != 5 {
+				t.Errorf("item %d: %d %q lines, want 5", i+1, got, signature)
+			}
+		}
+		if !strings.Contains(item, "Total custody events: 5") {
+			t.Errorf("item %d: event total missing", i+1)
+		}
+	}
+	if !strings.Contains(out, "the 2 evidence item(s) listed above") || !strings.Contains(out, "Printed name: Det. Ruiz") {
+		t.Error("certification missing")
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if n := len([]rune(line)); n > custodyReportWidth {
+			t.Errorf("line of %d characters: %q", n, line)
+		}
+	}
+}
+
+func TestRenderCustodyHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := custodyReport(4).Render(&buf, CustodyHTML); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	tests := []struct {
+		name  string
+		count string
+		want  int
+	}{
+		{"item headings", "<h2", 3}, // Two items and the certification
+		{"custody rows", `<tr class="event">`, 10},
+		{"signature boxes", `<span class="signature"></span>`, 22}, // Two per event and two for the certification
+		{"verification methods", "<b>Verification:</b> Seal check", 8},
+		{"escaped description", "señal &lt;b&gt;&amp;&lt;/b&gt;", 1},
+		{"raw markup", "<b>&</b>", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Count(out, tt.count); got != tt.want {
+				t.Errorf("%d occurrences of %q, want %d", got, tt.count, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCustodyPDF(t *testing.T) {
+	tests := []struct {
+		name      string
+		transfers int
+		wantPages int
+	}{
+		// An item with three transfers is 63 lines, five more than a page
+		// holds, so its fourth event moves whole onto a second page
+		{"event moved to the next page", 3, 4},
+		{"items over several pages", 12, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := custodyReport(tt.transfers).Render(&buf, CustodyPDF); err != nil {
+				t.Fatal(err)
+			}
+			f, err := pdf.Parse(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages, err := f.PageText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != tt.wantPages {
+				t.Errorf("%d pages, want %d", len(pages), tt.wantPages)
+			}
+
+			var released, starts int
+			for i, page := range pages {
+				if strings.Contains(page, "ITEM 2 OF 2") && !strings.Contains(strings.SplitN(page, "\n", 2)[0], "CHAIN OF CUSTODY REPORT") {
+					t.Errorf("item 2 does not start page %d", i+1)
+				}
+				if strings.Contains(page, "ITEM ") {
+					starts++
+				}
+				// Each event's signature block stays on the page of the event
+				r, v := strings.Count(page, "Released by (signature)"), strings.Count(page, "Received by (signature)")
+				if r != v || r != strings.Count(page, "     Released by:") {
+					t.Errorf("page %d splits a custody event: %d released, %d received signatures", i+1, r, v)
+				}
+				released += r
+				if !strings.HasSuffix(page, fmt.Sprintf("Page %d of %d", i+1, len(pages))) {
+					t.Errorf("page %d footer missing", i+1)
+				}
+			}
+			if want := 2 * (tt.transfers + 1); released != want {
+				t.Errorf("%d custody events, want %d", released, want)
+			}
+			if starts != 2 {
+				t.Errorf("%d item headings, want 2", starts)
+			}
+			if !strings.Contains(pages[0], `Cuchillo (cocina) con mango \ roto, señal <b>&</b>`) {
+				t.Errorf("description not kept: %q", pages[0])
+			}
+		})
+	}
+}
+
+func TestCustodyReportSelection(t *testing.T) {
+	s := NewEvidenceService(newMemRepo())
+	for _, e := range []*Evidence{
+		custodyItem("EV-2", "E-2", "Phone", 3),
+		custodyItem("EV-1", "E-1", "Knife", 3),
+		custodyItem("EV-3", "E-3", "Other case", 3),
+	} {
+		s.repo.(*memRepo).items[e.ID] = e
+	}
+	s.repo.(*memRepo).items["EV-3"].CaseID = "CASE-2"
+
+	tests := []struct {
+		name    string
+		caseID  string
+		ids     []string
+		want    []string
+		wantErr bool
+	}{
+		{"whole case in evidence number order", "CASE-1", nil, []string{"EV-1", "EV-2"}, false},
+		{"chosen items in the order given", "", []string{"EV-3", "EV-1"}, []string{"EV-3", "EV-1"}, false},
+		{"unknown item", "", []string{"EV-9"}, nil, true},
+		{"case without evidence", "CASE-9", nil, nil, true},
+		{"nothing chosen", "", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := s.CustodyReport(tt.caseID, tt.ids, "", "Det. Ruiz")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, e := range r.Items {
+				got = append(got, e.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || r.CaseNumber != r.Items[0].CaseID {
+				t.Errorf("items %v, case %s, want %v", got, r.CaseNumber, tt.want)
+			}
+		})
+	}
+
+	if err := NewCustodyReport(nil, "", "").Render(&bytes.Buffer{}, "docx"); err == nil {
+		t.Error("rendered an unsupported format")
+	}
+}
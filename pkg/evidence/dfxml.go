@@ -0,0 +1,436 @@
+package evidence
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DFXML document constants
+const (
+	dfxmlNamespace = "http://www.forensicswiki.org/wiki/Category:Digital_Forensics_XML"
+	dfxmlProgram   = "GoInspectorGadget"
+)
+
+// dfxmlDocument is the root element of a DFXML manifest
+type dfxmlDocument struct {
+	XMLName     xml.Name          `xml:"dfxml"`
+	Xmlns       string            `xml:"xmlns,attr"`
+	Version     string            `xml:"version,attr"`
+	Metadata    dfxmlMetadata     `xml:"metadata"`
+	Creator     dfxmlCreator      `xml:"creator"`
+	Source      dfxmlSource       `xml:"source"`
+	FileObjects []dfxmlFileObject `xml:"fileobject"`
+}
+
+type dfxmlMetadata struct {
+	Type       string `xml:"http://purl.org/dc/elements/1.1/ type"`
+	Identifier string `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+}
+
+type dfxmlCreator struct {
+	Program     string         `xml:"program"`
+	Environment dfxmlExecution `xml:"execution_environment"`
+}
+
+type dfxmlExecution struct {
+	StartTime string `xml:"start_time"`
+	EndTime   string `xml:"end_time,omitempty"`
+	Operator  string `xml:"operator,omitempty"`
+}
+
+type dfxmlSource struct {
+	ImageFilename string   `xml:"image_filename"`
+	EvidenceID    string   `xml:"evidence_id,omitempty"`
+	Errors        []string `xml:"error,omitempty"`
+}
+
+type dfxmlFileObject struct {
+	Filename   string            `xml:"filename"`
+	EvidenceID string            `xml:"evidence_id,omitempty"`
+	Filesize   int64             `xml:"filesize"`
+	Mode       string            `xml:"mode"`
+	Mtime      string            `xml:"mtime,omitempty"`
+	Atime      string            `xml:"atime,omitempty"`
+	Ctime      string            `xml:"ctime,omitempty"`
+	ByteRuns   *dfxmlByteRuns    `xml:"byte_runs,omitempty"`
+	Hashes     []dfxmlHashdigest `xml:"hashdigest"`
+}
+
+type dfxmlByteRuns struct {
+	Runs []dfxmlByteRun `xml:"byte_run"`
+}
+
+type dfxmlByteRun struct {
+	ImgOffset int64             `xml:"img_offset,attr"`
+	Len       int64             `xml:"len,attr"`
+	Hashes    []dfxmlHashdigest `xml:"hashdigest"`
+}
+
+type dfxmlHashdigest struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteManifest writes an acquisition as a DFXML document
+func WriteManifest(w io.Writer, acq *Acquisition) error {
+	doc := dfxmlDocument{
+		Xmlns:   dfxmlNamespace,
+		Version: "1.0",
+		Metadata: dfxmlMetadata{
+			Type:       string(acq.Type),
+			Identifier: acq.ID,
+		},
+		Creator: dfxmlCreator{
+			Program: dfxmlProgram,
+			Environment: dfxmlExecution{
+				StartTime: formatDFXMLTime(acq.StartedAt),
+				EndTime:   formatDFXMLTime(acq.CompletedAt),
+				Operator:  acq.AcquiredBy,
+			},
+		},
+		Source: dfxmlSource{
+			ImageFilename: acq.SourcePath,
+			EvidenceID:    acq.EvidenceID,
+			Errors:        acq.Errors,
+		},
+	}
+
+	for _, f := range acq.Files {
+		obj := dfxmlFileObject{
+			Filename:   f.RelativePath,
+			EvidenceID: f.EvidenceID,
+			Filesize:   f.Size,
+			Mode:       strconv.FormatUint(uint64(f.Mode.Perm()), 8),
+			Mtime:      formatDFXMLTime(f.ModifiedTime),
+			Atime:      formatDFXMLTime(f.AccessTime),
+			Ctime:      formatDFXMLTime(f.ChangeTime),
+			Hashes: []dfxmlHashdigest{
+				{Type: "md5", Value: f.MD5},
+				{Type: "sha1", Value: f.SHA1},
+				{Type: "sha256", Value: f.SHA256},
+			},
+		}
+		if len(f.ByteRuns) > 0 {
+			obj.ByteRuns = &dfxmlByteRuns{}
+			for _, run := range f.ByteRuns {
+				obj.ByteRuns.Runs = append(obj.ByteRuns.Runs, dfxmlByteRun{
+					ImgOffset: run.Offset,
+					Len:       run.Length,
+					Hashes:    []dfxmlHashdigest{{Type: "sha256", Value: run.SHA256}},
+				})
+			}
+		}
+		doc.FileObjects = append(doc.FileObjects, obj)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteManifestFile writes an acquisition manifest to a file
+func WriteManifestFile(path string, acq *Acquisition) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	if err := WriteManifest(f, acq); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest parses a DFXML manifest back into an acquisition
+func ReadManifest(r io.Reader) (*Acquisition, error) {
+	var doc dfxmlDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	acq := &Acquisition{
+		ID:          doc.Metadata.Identifier,
+		EvidenceID:  doc.Source.EvidenceID,
+		Type:        AcquisitionType(doc.Metadata.Type),
+		SourcePath:  doc.Source.ImageFilename,
+		AcquiredBy:  doc.Creator.Environment.Operator,
+		StartedAt:   parseDFXMLTime(doc.Creator.Environment.StartTime),
+		CompletedAt: parseDFXMLTime(doc.Creator.Environment.EndTime),
+		Errors:      doc.Source.Errors,
+	}
+
+	for _, obj := range doc.FileObjects {
+		mode, _ := strconv.ParseUint(obj.Mode, 8, 32)
+		f := AcquiredFile{
+			EvidenceID:   obj.EvidenceID,
+			RelativePath: obj.Filename,
+			Size:         obj.Filesize,
+			Mode:         fs.FileMode(mode),
+			ModifiedTime: parseDFXMLTime(obj.Mtime),
+			AccessTime:   parseDFXMLTime(obj.Atime),
+			ChangeTime:   parseDFXMLTime(obj.Ctime),
+		}
+		for _, h := range obj.Hashes {
+			switch h.Type {
+			case "md5":
+				f.MD5 = h.Value
+			case "sha1":
+				f.SHA1 = h.Value
+			case "sha256":
+				f.SHA256 = h.Value
+			}
+		}
+		if obj.ByteRuns != nil {
+			for _, run := range obj.ByteRuns.Runs {
+				br := ByteRun{Offset: run.ImgOffset, Length: run.Len}
+				for _, h := range run.Hashes {
+					if h.Type == "sha256" {
+						br.SHA256 = h.Value
+					}
+				}
+				f.ByteRuns = append(f.ByteRuns, br)
+			}
+		}
+		acq.Files = append(acq.Files, f)
+	}
+
+	return acq, nil
+}
+
+// ReadManifestFile parses a DFXML manifest from a file
+func ReadManifestFile(path string) (*Acquisition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	return ReadManifest(f)
+}
+
+// VerificationStatus is the outcome of verifying a single file
+type VerificationStatus string
+
+const (
+	VerifyMatch    VerificationStatus = "MATCH"
+	VerifyModified VerificationStatus = "MODIFIED"
+	VerifyMissing  VerificationStatus = "MISSING"
+	VerifyAdded    VerificationStatus = "ADDED"
+	VerifyError    VerificationStatus = "ERROR"
+)
+
+// FileVerification records the verification result for one file
+type FileVerification struct {
+	RelativePath   string
+	EvidenceID     string
+	Status         VerificationStatus
+	ExpectedSize   int64
+	ActualSize     int64
+	ExpectedSHA256 string
+	ActualSHA256   string
+	Detail         string
+}
+
+// VerificationReport summarizes the re-verification of an acquisition
+type VerificationReport struct {
+	AcquisitionID          string
+	EvidenceID             string
+	SourcePath             string
+	ManifestSHA256         string // Hash of the manifest verified against
+	ExpectedManifestSHA256 string // Hash recorded for the manifest at acquisition
+	VerifiedAt             time.Time
+	Results                []FileVerification
+}
+
+// ManifestModified reports whether the manifest no longer has the hash
+// recorded at acquisition, so that its file hashes cannot be trusted
+func (r *VerificationReport) ManifestModified() bool {
+	return r.ExpectedManifestSHA256 != "" && r.ManifestSHA256 != r.ExpectedManifestSHA256
+}
+
+// OK reports whether the manifest is intact and every file in it still
+// matches
+func (r *VerificationReport) OK() bool {
+	return !r.ManifestModified() && len(r.Mismatches()) == 0
+}
+
+// Mismatches returns only the results that did not match
+func (r *VerificationReport) Mismatches() []FileVerification {
+	var result []FileVerification
+	for _, v := range r.Results {
+		if v.Status != VerifyMatch {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// VerifyManifest re-hashes the files under sourcePath and compares them with
+// the acquisition manifest
+func VerifyManifest(acq *Acquisition, sourcePath string) (*VerificationReport, error) {
+	report := &VerificationReport{
+		AcquisitionID: acq.ID,
+		EvidenceID:    acq.EvidenceID,
+		SourcePath:    sourcePath,
+		VerifiedAt:    time.Now(),
+	}
+
+	seen := make(map[string]bool)
+	for _, expected := range acq.Files {
+		seen[expected.RelativePath] = true
+
+		path := sourcePath
+		if acq.Type == AcquisitionDirectory {
+			path = filepath.Join(sourcePath, filepath.FromSlash(expected.RelativePath))
+		}
+		report.Results = append(report.Results, verifyFile(expected, path))
+	}
+
+	// Files that appeared in the tree after acquisition
+	if acq.Type == AcquisitionDirectory {
+		current, err := listRegularFiles(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range current {
+			if !seen[rel] {
+				report.Results = append(report.Results, FileVerification{
+					RelativePath: rel,
+					Status:       VerifyAdded,
+					Detail:       "file not present in manifest",
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].RelativePath < report.Results[j].RelativePath
+	})
+
+	return report, nil
+}
+
+// verifyFile re-hashes one file and compares it with its manifest entry
+func verifyFile(expected AcquiredFile, path string) FileVerification {
+	result := FileVerification{
+		RelativePath:   expected.RelativePath,
+		EvidenceID:     expected.EvidenceID,
+		ExpectedSize:   expected.Size,
+		ExpectedSHA256: expected.SHA256,
+	}
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		result.Status = VerifyMissing
+		result.Detail = "file no longer exists"
+		return result
+	}
+	if err != nil {
+		result.Status = VerifyError
+		result.Detail = err.Error()
+		return result
+	}
+	result.ActualSize = info.Size()
+
+	hashes, runs, err := hashFile(path, len(expected.ByteRuns) > 0)
+	if err != nil {
+		result.Status = VerifyError
+		result.Detail = err.Error()
+		return result
+	}
+	result.ActualSHA256 = hashes.sha256
+
+	if hashes.sha256 == expected.SHA256 {
+		result.Status = VerifyMatch
+		return result
+	}
+
+	result.Status = VerifyModified
+	switch {
+	case result.ActualSize != expected.Size:
+		result.Detail = fmt.Sprintf("size changed from %d to %d bytes", expected.Size, result.ActualSize)
+	case len(expected.ByteRuns) > 0:
+		result.Detail = describeChangedRuns(expected.ByteRuns, runs)
+	default:
+		result.Detail = "content hash differs"
+	}
+	return result
+}
+
+// describeChangedRuns lists the byte runs of a raw image whose hash changed
+func describeChangedRuns(expected, actual []ByteRun) string {
+	var changed []string
+	for i, run := range expected {
+		if i >= len(actual) || actual[i].SHA256 != run.SHA256 {
+			changed = append(changed, fmt.Sprintf("%d+%d", run.Offset, run.Length))
+		}
+	}
+	if len(changed) == 0 {
+		return "content hash differs"
+	}
+	return fmt.Sprintf("content differs in byte runs %v", changed)
+}
+
+// listRegularFiles returns the slash-separated relative paths of all regular
+// files under root
+func listRegularFiles(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve relative path: %w", err)
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	return paths, err
+}
+
+// formatDFXMLTime formats a timestamp as DFXML expects (ISO 8601, UTC)
+func formatDFXMLTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseDFXMLTime parses a DFXML timestamp, returning the zero time on failure
+func parseDFXMLTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,283 @@
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DispositionAction identifies how an evidence item left the property room
+type DispositionAction string
+
+const (
+	DispositionRelease  DispositionAction = "RELEASE"
+	DispositionDestroy  DispositionAction = "DESTROY"
+	DispositionReturn   DispositionAction = "RETURN_TO_OWNER"
+	DispositionTransfer DispositionAction = "TRANSFER" // Permanent transfer to another agency
+)
+
+// ErrEvidenceDisposed is returned when modifying evidence that has been disposed of
+var ErrEvidenceDisposed = errors.New("evidence has been disposed of and can no longer be modified")
+
+// DispositionRequest contains the details needed to dispose of an evidence item
+type DispositionRequest struct {
+	EvidenceID   string
+	PerformedBy  string // ID of the person carrying out the disposition
+	AuthorizedBy string // ID of the authorizing supervisor
+	WitnessedBy  string // ID of the witness (required for two-person integrity)
+	Recipient    string // Person or agency receiving released/returned items
+	Method       string // e.g., "INCINERATION", "SHREDDING" for destruction
+	CourtOrder   string // Reference to the authorizing court order (if any)
+	Reason       string
+	Notes        string
+}
+
+// DispositionRecord is the permanent record of an evidence disposition
+type DispositionRecord struct {
+	ID           string
+	EvidenceID   string
+	CaseID       string
+	Action       DispositionAction
+	PerformedBy  string
+	AuthorizedBy string
+	WitnessedBy  string
+	Recipient    string
+	Method       string
+	CourtOrder   string
+	Reason       string
+	Notes        string
+	EvidenceHash string // FileHash at the time of disposition (digital evidence)
+	Timestamp    time.Time
+}
+
+// CaseDispositionChecker reports whether the case owning an evidence item
+// permits that item to be disposed of
+type CaseDispositionChecker interface {
+	CanDisposeEvidence(caseID string) error
+}
+
+// SetCaseChecker configures the checker consulted before any disposition
+func (s *EvidenceService) SetCaseChecker(checker CaseDispositionChecker) {
+	s.caseChecker = checker
+}
+
+// ReleaseEvidence releases an evidence item to another person or agency
+func (s *EvidenceService) ReleaseEvidence(req DispositionRequest) (*DispositionRecord, error) {
+	if req.Recipient == "" {
+		return nil, fmt.Errorf("a recipient is required to release evidence")
+	}
+	return s.dispose(DispositionRelease, req)
+}
+
+// DestroyEvidence records the destruction of an evidence item. Destruction
+// always requires two-person integrity.
+func (s *EvidenceService) DestroyEvidence(req DispositionRequest) (*DispositionRecord, error) {
+	if req.Method == "" {
+		return nil, fmt.Errorf("a destruction method is required")
+	}
+	return s.dispose(DispositionDestroy, req)
+}
+
+// ReturnToOwner returns an evidence item to its lawful owner
+func (s *EvidenceService) ReturnToOwner(req DispositionRequest) (*DispositionRecord, error) {
+	if req.Recipient == "" {
+		return nil, fmt.Errorf("the owner receiving the evidence is required")
+	}
+	return s.dispose(DispositionReturn, req)
+}
+
+// TransferToAgency permanently transfers an evidence item to another agency,
+// such as a federal prosecutor taking over the case
+func (s *EvidenceService) TransferToAgency(req DispositionRequest) (*DispositionRecord, error) {
+	if req.Recipient == "" {
+		return nil, fmt.Errorf("the agency receiving the evidence is required")
+	}
+	return s.dispose(DispositionTransfer, req)
+}
+
+// IsDisposed reports whether an evidence item has been disposed of
+func (e *Evidence) IsDisposed() bool {
+	return e.Disposition != nil
+}
+
+// isDisposed reports whether an item has been disposed of, by the record
+// in the repository or by a disposition made through this service. The
+// service's own copy is checked because the repository may hand out the
+// same item the caller is modifying.
+func (s *EvidenceService) isDisposed(id string) bool {
+	if _, ok := s.disposed[id]; ok {
+		return true
+	}
+	existing, err := s.repo.Find(id)
+	return err == nil && existing.IsDisposed()
+}
+
+// RequiresTwoPersonIntegrity reports whether disposing of an item with the
+// given action needs an independent witness
+func RequiresTwoPersonIntegrity(e *Evidence, action DispositionAction) bool {
+	if action == DispositionDestroy {
+		return true
+	}
+	return e.Type == TypeWeapon || e.Type == TypeNarcotics
+}
+
+// dispose validates and applies a disposition
+func (s *EvidenceService) dispose(action DispositionAction, req DispositionRequest) (*DispositionRecord, error) {
+	evidence, err := s.repo.Find(req.EvidenceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find evidence: %w", err)
+	}
+
+	if s.isDisposed(evidence.ID) {
+		return nil, ErrEvidenceDisposed
+	}
+
+	if err := validateDisposition(evidence, action, req); err != nil {
+		return nil, err
+	}
+
+	if s.caseChecker == nil {
+		return nil, fmt.Errorf("no case checker configured; cannot verify that case %s permits disposition", evidence.CaseID)
+	}
+	if err := s.caseChecker.CanDisposeEvidence(evidence.CaseID); err != nil {
+		return nil, fmt.Errorf("case %s does not permit disposition: %w", evidence.CaseID, err)
+	}
+
+	now := time.Now()
+	record := &DispositionRecord{
+		ID:           generateID("DISP"),
+		EvidenceID:   evidence.ID,
+		CaseID:       evidence.CaseID,
+		Action:       action,
+		PerformedBy:  req.PerformedBy,
+		AuthorizedBy: req.AuthorizedBy,
+		WitnessedBy:  req.WitnessedBy,
+		Recipient:    req.Recipient,
+		Method:       req.Method,
+		CourtOrder:   req.CourtOrder,
+		Reason:       req.Reason,
+		Notes:        req.Notes,
+		EvidenceHash: evidence.FileHash,
+		Timestamp:    now,
+	}
+
+	event := CustodyEvent{
+		ID:                 generateID("CE"),
+		EvidenceID:         evidence.ID,
+		Timestamp:          now,
+		Action:             dispositionCustodyAction(action),
+		FromPerson:         req.PerformedBy,
+		ToPerson:           req.Recipient,
+		FromLocation:       evidence.StorageLocation,
+		Reason:             req.Reason,
+		Notes:              req.Notes,
+		DocumentID:         record.ID,
+		AuthorizedBy:       req.AuthorizedBy,
+		TransportMethod:    req.Method,
+		VerificationMethod: dispositionVerification(req),
+	}
+	if action == DispositionDestroy {
+		event.ToLocation = "DESTROYED"
+	} else {
+		event.ToLocation = req.Recipient
+	}
+
+	evidence.ChainOfCustody = append(evidence.ChainOfCustody, event)
+	evidence.Status = dispositionStatus(action)
+	evidence.StorageLocation = event.ToLocation
+	evidence.Disposition = record
+	evidence.UpdatedAt = now
+
+	if err := s.repo.Update(evidence); err != nil {
+		return nil, err
+	}
+	s.disposed[evidence.ID] = *record
+
+	return record, nil
+}
+
+// validateDisposition enforces the authorization rules for a disposition
+func validateDisposition(e *Evidence, action DispositionAction, req DispositionRequest) error {
+	if req.PerformedBy == "" {
+		return fmt.Errorf("the person performing the disposition is required")
+	}
+	if req.AuthorizedBy == "" {
+		return fmt.Errorf("an authorizing supervisor is required")
+	}
+	if req.AuthorizedBy == req.PerformedBy {
+		return fmt.Errorf("the authorizing supervisor cannot also perform the disposition")
+	}
+
+	if !RequiresTwoPersonIntegrity(e, action) {
+		return nil
+	}
+
+	if req.WitnessedBy == "" {
+		return fmt.Errorf("two-person integrity: a witness is required to %s %s evidence",
+			strings.ToLower(strings.ReplaceAll(string(action), "_", " ")), strings.ToLower(string(e.Type)))
+	}
+	if req.WitnessedBy == req.PerformedBy || req.WitnessedBy == req.AuthorizedBy {
+		return fmt.Errorf("two-person integrity: the witness must be independent of the performer and the supervisor")
+	}
+
+	return nil
+}
+
+// dispositionStatus maps a disposition action to the resulting status
+func dispositionStatus(action DispositionAction) EvidenceStatus {
+	switch action {
+	case DispositionDestroy:
+		return StatusDestroyed
+	case DispositionReturn:
+		return StatusReturned
+	case DispositionTransfer:
+		return StatusTransferred
+	default:
+		return StatusReleased
+	}
+}
+
+// dispositionCustodyAction maps a disposition action to a custody event action
+func dispositionCustodyAction(action DispositionAction) string {
+	switch action {
+	case DispositionDestroy:
+		return "DESTROYED"
+	case DispositionReturn:
+		return "RETURNED"
+	case DispositionTransfer:
+		return "TRANSFERRED_TO_AGENCY"
+	default:
+		return "RELEASED"
+	}
+}
+
+// dispositionVerification describes how a disposition was verified
+func dispositionVerification(req DispositionRequest) string {
+	if req.WitnessedBy == "" {
+		return fmt.Sprintf("Authorized by %s", req.AuthorizedBy)
+	}
+	return fmt.Sprintf("Two-person integrity: authorized by %s, witnessed by %s", req.AuthorizedBy, req.WitnessedBy)
+}
+
+// isDispositionStatus reports whether a status can only be reached through
+// a disposition operation
+func isDispositionStatus(status EvidenceStatus) bool {
+	switch status {
+	case StatusReleased, StatusReturned, StatusDestroyed, StatusTransferred:
+		return true
+	}
+	return false
+}
+
+// checkNoDisposition rejects an item that carries a disposition or
+// disposition status not recorded through a disposition operation
+func checkNoDisposition(e *Evidence) error {
+	if e.Disposition != nil {
+		return fmt.Errorf("a disposition can only be recorded through a disposition operation")
+	}
+	if isDispositionStatus(e.Status) {
+		return fmt.Errorf("status %s can only be set through a disposition operation", e.Status)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// caseChecker permits the disposition of evidence of closed cases
+type caseChecker map[string]bool
+
+func (c caseChecker) CanDisposeEvidence(caseID string) error {
+	if !c[caseID] {
+		return fmt.Errorf("case %s is still open", caseID)
+	}
+	return nil
+}
+
+func newDisposalService(t *testing.T, typ EvidenceType) (*EvidenceService, *Evidence) {
+	t.Helper()
+	s := NewEvidenceService(newMemRepo())
+	s.SetCaseChecker(caseChecker{"CASE-1": true})
+	e := &Evidence{CaseID: "CASE-1", Type: typ, Description: "Item", CollectedBy: "Officer A",
+		CollectionDate: time.Now(), StorageLocation: "Locker 1"}
+	if err := s.CreateEvidence(e); err != nil {
+		t.Fatal(err)
+	}
+	return s, e
+}
+
+func TestDispose(t *testing.T) {
+	tests := []struct {
+		name       string
+		typ        EvidenceType
+		dispose    func(s *EvidenceService, req DispositionRequest) (*DispositionRecord, error)
+		req        DispositionRequest
+		wantStatus EvidenceStatus
+		wantErr    bool
+	}{
+		{"release", TypeDocument, (*EvidenceService).ReleaseEvidence,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", Recipient: "Owner"}, StatusReleased, false},
+		{"return", TypeDocument, (*EvidenceService).ReturnToOwner,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", Recipient: "Owner"}, StatusReturned, false},
+		{"transfer", TypeDocument, (*EvidenceService).TransferToAgency,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", Recipient: "FBI"}, StatusTransferred, false},
+		{"transfer without agency", TypeDocument, (*EvidenceService).TransferToAgency,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1"}, "", true},
+		{"destroy with witness", TypeDocument, (*EvidenceService).DestroyEvidence,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", WitnessedBy: "OFC-2", Method: "Incineration"}, StatusDestroyed, false},
+		{"destroy without witness", TypeDocument, (*EvidenceService).DestroyEvidence,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1"}, "", true},
+		{"weapon release without witness", TypeWeapon, (*EvidenceService).ReleaseEvidence,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", Recipient: "Owner"}, "", true},
+		{"witness is the supervisor", TypeNarcotics, (*EvidenceService).TransferToAgency,
+			DispositionRequest{PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", WitnessedBy: "SGT-1", Recipient: "DEA"}, "", true},
+		{"supervisor performs", TypeDocument, (*EvidenceService).ReleaseEvidence,
+			DispositionRequest{PerformedBy: "SGT-1", AuthorizedBy: "SGT-1", Recipient: "Owner"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, e := newDisposalService(t, tt.typ)
+			tt.req.EvidenceID = e.ID
+			record, err := tt.dispose(s, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if e.IsDisposed() {
+					t.Error("evidence disposed of despite the error")
+				}
+				return
+			}
+			if e.Status != tt.wantStatus || e.Disposition == nil || e.Disposition.ID != record.ID {
+				t.Errorf("status %s, disposition %+v", e.Status, e.Disposition)
+			}
+		})
+	}
+}
+
+func TestDisposeRequiresClosedCase(t *testing.T) {
+	s, e := newDisposalService(t, TypeDocument)
+	s.SetCaseChecker(caseChecker{})
+	_, err := s.ReleaseEvidence(DispositionRequest{EvidenceID: e.ID, PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", Recipient: "Owner"})
+	if err == nil {
+		t.Fatal("disposition of evidence of an open case succeeded")
+	}
+}
+
+func TestDisposedEvidenceIsImmutable(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *EvidenceService, e *Evidence) error
+	}{
+		{"update", func(s *EvidenceService, e *Evidence) error {
+			e.Description = "Changed"
+			return s.UpdateEvidence(e)
+		}},
+		{"update with the disposition cleared", func(s *EvidenceService, e *Evidence) error {
+			e.Disposition = nil
+			e.Status = StatusInStorage
+			return s.UpdateEvidence(e)
+		}},
+		{"transfer custody", func(s *EvidenceService, e *Evidence) error {
+			return s.TransferCustody(e.ID, "OFC-1", "OFC-2", "Locker 1", "Lab", "Analysis", "")
+		}},
+		{"transfer custody with the disposition cleared", func(s *EvidenceService, e *Evidence) error {
+			e.Disposition = nil
+			return s.TransferCustody(e.ID, "OFC-1", "OFC-2", "Locker 1", "Lab", "Analysis", "")
+		}},
+		{"dispose again with the disposition cleared", func(s *EvidenceService, e *Evidence) error {
+			e.Disposition = nil
+			_, err := s.ReturnToOwner(DispositionRequest{EvidenceID: e.ID, PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", Recipient: "Owner"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, e := newDisposalService(t, TypeDocument)
+			_, err := s.ReleaseEvidence(DispositionRequest{EvidenceID: e.ID, PerformedBy: "OFC-1", AuthorizedBy: "SGT-1", Recipient: "Owner"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.modify(s, e); !errors.Is(err, ErrEvidenceDisposed) {
+				t.Errorf("err = %v, want ErrEvidenceDisposed", err)
+			}
+		})
+	}
+}
+
+func TestForgedDispositionIsRejected(t *testing.T) {
+	forged := func() *DispositionRecord {
+		return &DispositionRecord{ID: "DISP-FORGED", Action: DispositionDestroy, PerformedBy: "OFC-1"}
+	}
+	tests := []struct {
+		name   string
+		modify func(e *Evidence)
+	}{
+		{"destroyed with a disposition", func(e *Evidence) {
+			e.Status = StatusDestroyed
+			e.Disposition = forged()
+		}},
+		{"released with a disposition", func(e *Evidence) {
+			e.Status = StatusReleased
+			e.Disposition = forged()
+		}},
+		{"disposition status alone", func(e *Evidence) {
+			e.Status = StatusReturned
+		}},
+		{"disposition alone", func(e *Evidence) {
+			e.Disposition = forged()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, stored := newDisposalService(t, TypeWeapon)
+			e := *stored
+			tt.modify(&e)
+			if err := s.UpdateEvidence(&e); err == nil {
+				t.Fatal("update with a forged disposition succeeded")
+			}
+			if s.isDisposed(stored.ID) || stored.Status != StatusCollected {
+				t.Errorf("stored item disposed of: status %s, disposition %+v", stored.Status, stored.Disposition)
+			}
+
+			created := Evidence{CaseID: "CASE-1", Type: TypeWeapon, Description: "Item", CollectedBy: "Officer A"}
+			tt.modify(&created)
+			if err := s.CreateEvidence(&created); err == nil {
+				t.Error("created an item with a forged disposition")
+			}
+		})
+	}
+}
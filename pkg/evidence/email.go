@@ -0,0 +1,387 @@
+package evidence
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/email"
+)
+
+// EmailMessage is a structured record of a message found in email evidence
+type EmailMessage struct {
+	ID          string
+	EvidenceID  string // Evidence item holding the message
+	CaseID      string
+	Format      email.Format
+	Source      string // File of the message, or mailbox and message number
+	MessageID   string // Message-ID header
+	InReplyTo   string
+	Subject     string
+	Date        time.Time
+	From        []email.Address
+	To          []email.Address
+	Cc          []email.Address
+	Bcc         []email.Address
+	ReplyTo     []email.Address
+	Headers     []email.Header
+	Received    []email.Hop // Most recent hop first
+	TextBody    string
+	HTMLBody    string
+	Attachments []EmailAttachment
+	PersonIDs   []string // Case persons among the participants
+	Warnings    []string
+	CreatedAt   time.Time
+}
+
+// EmailAttachment is an attachment saved as child evidence
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Inline      bool
+	EvidenceID  string
+	SHA256      string
+}
+
+// EmailParticipant is an address seen in the messages of a source
+type EmailParticipant struct {
+	email.Address
+	Messages int
+	PersonID string // Matching case person, if any
+}
+
+// EmailImportOptions control how email evidence is processed
+type EmailImportOptions struct {
+	DestDir  string // Directory attachments are written to
+	Operator string // Defaults to the source's collector
+	Timeline bool   // Add each dated message to the case timeline
+}
+
+// EmailImportReport summarizes the messages read from an email source
+type EmailImportReport struct {
+	EvidenceID     string
+	Format         email.Format
+	Messages       []*EmailMessage
+	Attachments    int
+	Participants   []EmailParticipant // Most active first
+	TimelineEvents int
+	Errors         []string
+}
+
+// EmailRepository stores parsed email messages
+type EmailRepository interface {
+	SaveEmail(m *EmailMessage) error
+	FindEmail(id string) (*EmailMessage, error)
+	FindEmailsByEvidence(evidenceID string) ([]*EmailMessage, error)
+}
+
+// PersonMatcher finds the case person an email participant belongs to. It
+// returns an empty ID when no person matches.
+type PersonMatcher interface {
+	MatchPerson(caseID, address, name string) (string, error)
+}
+
+// EmailService parses email evidence into messages
+type EmailService struct {
+	service *EvidenceService
+	repo    EmailRepository
+	events  CaseEventWriter
+	persons PersonMatcher
+}
+
+// NewEmailService creates a new email service
+func NewEmailService(service *EvidenceService, repo EmailRepository, events CaseEventWriter, persons PersonMatcher) *EmailService {
+	return &EmailService{
+		service: service,
+		repo:    repo,
+		events:  events,
+		persons: persons,
+	}
+}
+
+// ImportEmail parses the EML file, mbox file or Maildir of a digital evidence
+// item. Each message is stored as a record, attachments become child
+// evidence items, and participants are matched to the persons of the case.
+// Messages that cannot be parsed are listed in the report's errors.
+func (s *EmailService) ImportEmail(source *DigitalEvidence, opts EmailImportOptions) (*EmailImportReport, error) {
+	if source.ID == "" {
+		return nil, fmt.Errorf("the email source must be saved before it is processed")
+	}
+	if source.IsDisposed() {
+		return nil, ErrEvidenceDisposed
+	}
+	if opts.DestDir == "" {
+		return nil, fmt.Errorf("an attachment directory is required")
+	}
+	if opts.Operator == "" {
+		opts.Operator = source.CollectedBy
+	}
+	if existing, err := s.repo.FindEmailsByEvidence(source.ID); err == nil && len(existing) > 0 {
+		return nil, fmt.Errorf("email in %s has already been processed (%d messages)", source.ID, len(existing))
+	}
+	if err := verifyRecordedHash(source); err != nil {
+		return nil, err
+	}
+
+	format, err := email.DetectFormat(source.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EmailImportReport{EvidenceID: source.ID, Format: format}
+	participants := make(map[string]*EmailParticipant)
+
+	_, err = email.Walk(source.FilePath, func(m *email.Message, err error) error {
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", m.Source, err))
+			return nil
+		}
+
+		record := &EmailMessage{
+			ID:         generateID("EM"),
+			EvidenceID: source.ID,
+			CaseID:     source.CaseID,
+			Format:     format,
+			Source:     m.Source,
+			MessageID:  m.MessageID,
+			InReplyTo:  m.InReplyTo,
+			Subject:    m.Subject,
+			Date:       m.Date,
+			From:       m.From,
+			To:         m.To,
+			Cc:         m.Cc,
+			Bcc:        m.Bcc,
+			ReplyTo:    m.ReplyTo,
+			Headers:    m.Headers,
+			Received:   m.Received,
+			TextBody:   m.Text,
+			HTMLBody:   m.HTML,
+			Warnings:   m.Warnings,
+			CreatedAt:  time.Now(),
+		}
+
+		dir := filepath.Join(opts.DestDir, source.ID, record.ID)
+		for _, a := range m.Attachments {
+			report.Attachments++
+			attachment, err := s.saveAttachment(source, record, a, dir, report.Attachments, opts.Operator)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: attachment %s: %v", m.Source, a.Filename, err))
+				continue
+			}
+			record.Attachments = append(record.Attachments, *attachment)
+		}
+
+		seen := make(map[string]bool)
+		for _, a := range m.Participants() {
+			if a.Address == "" || seen[a.Address] {
+				continue
+			}
+			seen[a.Address] = true
+			p, err := s.participant(participants, source.CaseID, a)
+			if err != nil {
+				return err
+			}
+			p.Messages++
+			if p.PersonID != "" {
+				record.PersonIDs = appendUnique(record.PersonIDs, p.PersonID)
+			}
+		}
+
+		if err := s.repo.SaveEmail(record); err != nil {
+			return fmt.Errorf("failed to save message %s: %w", m.Source, err)
+		}
+		report.Messages = append(report.Messages, record)
+
+		if opts.Timeline && !record.Date.IsZero() && s.events != nil && source.CaseID != "" {
+			ids := []string{source.ID}
+			for _, a := range record.Attachments {
+				ids = append(ids, a.EvidenceID)
+			}
+			if err := s.events.AddCaseEvent(source.CaseID, record.Date, emailEventDescription(record), "", ids); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: timeline: %v", m.Source, err))
+			} else {
+				report.TimelineEvents++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to read email: %w", err)
+	}
+
+	for _, p := range participants {
+		report.Participants = append(report.Participants, *p)
+	}
+	sort.Slice(report.Participants, func(i, j int) bool {
+		a, b := report.Participants[i], report.Participants[j]
+		if a.Messages != b.Messages {
+			return a.Messages > b.Messages
+		}
+		return a.Address.Address < b.Address.Address
+	})
+
+	if err := s.markProcessed(source, format, len(report.Messages)); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// GetEmail retrieves a parsed message
+func (s *EmailService) GetEmail(id string) (*EmailMessage, error) {
+	return s.repo.FindEmail(id)
+}
+
+// Messages returns the messages parsed from an evidence item, oldest first
+func (s *EmailService) Messages(evidenceID string) ([]*EmailMessage, error) {
+	messages, err := s.repo.FindEmailsByEvidence(evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Date.Before(messages[j].Date) })
+	return messages, nil
+}
+
+// saveAttachment writes an attachment to disk and records it as evidence
+// extracted from the email source
+func (s *EmailService) saveAttachment(source *DigitalEvidence, m *EmailMessage, a email.Attachment, dir string, index int, operator string) (*EmailAttachment, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+	name := attachmentName(a.Filename)
+	path := attachmentPath(dir, name)
+	if err := os.WriteFile(path, a.Data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write attachment: %w", err)
+	}
+
+	subject := m.Subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	child := &DigitalEvidence{
+		Evidence: Evidence{
+			CaseID:          source.CaseID,
+			EvidenceNumber:  childEvidenceNumber(source.EvidenceNumber, index),
+			Description:     fmt.Sprintf("%s (attachment to %q, %s)", name, subject, m.Source),
+			Location:        source.Location,
+			StorageLocation: source.StorageLocation,
+			IsConfidential:  source.IsConfidential,
+		},
+		FilePath:     path,
+		ModifiedDate: m.Date,
+		DeviceSource: source.DeviceSource,
+	}
+	req := DerivationRequest{
+		ParentID:   source.ID,
+		Relation:   RelationExtractedFrom,
+		Tool:       "email attachment extraction",
+		Operator:   operator,
+		SourcePath: m.Source + "/" + name,
+	}
+	if err := s.service.DeriveDigitalEvidence(child, req); err != nil {
+		return nil, err
+	}
+
+	return &EmailAttachment{
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        int64(len(a.Data)),
+		Inline:      a.Inline,
+		EvidenceID:  child.ID,
+		SHA256:      child.FileHash,
+	}, nil
+}
+
+// participant returns the tally for an address, matching it to a case person
+// the first time it is seen
+func (s *EmailService) participant(participants map[string]*EmailParticipant, caseID string, a email.Address) (*EmailParticipant, error) {
+	if p, ok := participants[a.Address]; ok {
+		if p.Name == "" {
+			p.Name = a.Name
+		}
+		return p, nil
+	}
+
+	p := &EmailParticipant{Address: a}
+	if s.persons != nil && caseID != "" {
+		id, err := s.persons.MatchPerson(caseID, a.Address, a.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match %s: %w", a.Address, err)
+		}
+		p.PersonID = id
+	}
+	participants[a.Address] = p
+	return p, nil
+}
+
+// markProcessed tags the source and records what was found. The stored
+// record is reloaded so that the links added for attachments are kept.
+func (s *EmailService) markProcessed(source *DigitalEvidence, format email.Format, messages int) error {
+	current, err := s.service.repo.Find(source.ID)
+	if err != nil {
+		return fmt.Errorf("evidence not found: %w", err)
+	}
+
+	current.Tags = appendUnique(current.Tags, "email")
+	current.Notes = joinResults(current.Notes, fmt.Sprintf("Email processed: %d messages (%s)", messages, format))
+	if err := s.service.UpdateEvidence(current); err != nil {
+		return err
+	}
+
+	source.Evidence = *current
+	return nil
+}
+
+// emailEventDescription describes a message on the case timeline
+func emailEventDescription(m *EmailMessage) string {
+	subject := m.Subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	description := fmt.Sprintf("Email %q", subject)
+	if len(m.From) > 0 {
+		description += " from " + m.From[0].Address
+	}
+	var to []string
+	for _, list := range [][]email.Address{m.To, m.Cc, m.Bcc} {
+		for _, a := range list {
+			to = append(to, a.Address)
+		}
+	}
+	if len(to) > 0 {
+		description += " to " + strings.Join(to, ", ")
+	}
+	return description
+}
+
+// attachmentName reduces an attachment filename to a name that cannot
+// escape the attachment directory
+func attachmentName(filename string) string {
+	name := filename[strings.LastIndexAny(filename, "/\\")+1:]
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+	return name
+}
+
+// attachmentPath returns a path in dir that does not overwrite an earlier
+// attachment of the same name
+func attachmentPath(dir, name string) string {
+	path := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s~%d%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"sync/atomic"
 	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/hashset"
 )
 
 // EvidenceType represents types of evidence
@@ -19,6 +21,7 @@ const (
 	TypeDocument   EvidenceType = "DOCUMENT"
 	TypeBiological EvidenceType = "BIOLOGICAL"
 	TypeWeapon     EvidenceType = "WEAPON"
+	TypeNarcotics  EvidenceType = "NARCOTICS"
 	TypeOther      EvidenceType = "OTHER"
 )
 
@@ -33,6 +36,7 @@ const (
 	StatusTransferred EvidenceStatus = "TRANSFERRED"
 	StatusReleased    EvidenceStatus = "RELEASED"
 	StatusDestroyed   EvidenceStatus = "DESTROYED"
+	StatusReturned    EvidenceStatus = "RETURNED"
 )
 
 // Evidence represents an item of evidence in a case
@@ -57,6 +61,10 @@ type Evidence struct {
 	FileHash          string   // For digital evidence, hash of the file
 	IsConfidential    bool
 	Notes             string
+	KnownFile         hashset.Status     // Hash set classification of a digital file; empty if not checked
+	KnownFileSets     []string           // Hash sets the file was found in
+	DerivedFrom       *Derivation        // How the item was derived from its parent; nil for original seizures
+	Disposition       *DispositionRecord // Set once the item has been released, returned or destroyed
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 }
@@ -104,7 +112,8 @@ type CustodyEvent struct {
 // DigitalEvidence contains additional fields for digital evidence
 type DigitalEvidence struct {
 	Evidence
-	FileType         string
+	FileType         string // Type identified from the file content, e.g. "JPEG"
+	MIMEType         string
 	FilePath         string
 	FileSize         int64
 	CreationDate     time.Time
@@ -115,8 +124,11 @@ type DigitalEvidence struct {
 	ExtractionMethod string
 	Encrypted        bool
 	Decrypted        bool
-	Password         string // This would be securely stored
+	SecretIDs        []string // Passwords and keys held in the secret vault
+	DecryptedWith    string   // ID of the secret that decrypted the item
 	Metadata         map[string]string
+	ManifestPath     string // DFXML manifest for directory and image acquisitions
+	TypeMismatch     bool   // File extension does not match the content
 }
 
 // BiologicalEvidence contains additional fields for biological evidence
@@ -136,20 +148,38 @@ type EvidenceRepository interface {
 	Save(e *Evidence) error
 	Find(id string) (*Evidence, error)
 	FindByCase(caseID string) ([]*Evidence, error)
+	FindByEvidenceNumber(evidenceNumber string) (*Evidence, error)
+	FindByStorageLocation(location string) ([]*Evidence, error)
 	Search(query string) ([]*Evidence, error)
 	Update(e *Evidence) error
 	Delete(id string) error
 }
 
+// DigitalRepository stores the digital details of evidence items, such as
+// file metadata and the secrets recorded for encrypted items
+type DigitalRepository interface {
+	SaveDigital(d *DigitalEvidence) error
+	FindDigital(id string) (*DigitalEvidence, error)
+}
+
 // EvidenceService provides business logic for evidence management
 type EvidenceService struct {
-	repo EvidenceRepository
+	repo            EvidenceRepository
+	caseChecker     CaseDispositionChecker
+	knownFiles      KnownFileIndex
+	knownBadAlerter KnownFileAlerter
+	digital         DigitalRepository
+
+	// Copies of the dispositions made, so that clearing the disposition of
+	// an item held by the repository cannot make it mutable again
+	disposed map[string]DispositionRecord
 }
 
 // NewEvidenceService creates a new evidence service
 func NewEvidenceService(repo EvidenceRepository) *EvidenceService {
 	return &EvidenceService{
-		repo: repo,
+		repo:     repo,
+		disposed: make(map[string]DispositionRecord),
 	}
 }
 
@@ -166,6 +196,9 @@ func (s *EvidenceService) CreateEvidence(e *Evidence) error {
 	if e.Status == "" {
 		e.Status = StatusCollected
 	}
+	if err := checkNoDisposition(e); err != nil {
+		return err
+	}
 
 	// If this is digital evidence, calculate file hash
 	if e.Type == TypeDigital && e.FileHash == "" {
@@ -197,7 +230,11 @@ func (s *EvidenceService) CreateEvidence(e *Evidence) error {
 		}
 	}
 
-	return s.repo.Save(e)
+	if err := s.repo.Save(e); err != nil {
+		return err
+	}
+	s.alertKnownBad(e)
+	return nil
 }
 
 // GetEvidence retrieves an evidence item by ID
@@ -205,8 +242,21 @@ func (s *EvidenceService) GetEvidence(id string) (*Evidence, error) {
 	return s.repo.Find(id)
 }
 
-// UpdateEvidence updates an existing evidence item
+// UpdateEvidence updates an existing evidence item. Disposed items are
+// immutable, and a disposition or disposition status can only be set
+// through ReleaseEvidence, DestroyEvidence, ReturnToOwner or
+// TransferToAgency.
 func (s *EvidenceService) UpdateEvidence(e *Evidence) error {
+	if s.isDisposed(e.ID) {
+		return ErrEvidenceDisposed
+	}
+
+	// The item is not disposed of on record, so any disposition it carries
+	// did not come from a disposition operation
+	if err := checkNoDisposition(e); err != nil {
+		return err
+	}
+
 	e.UpdatedAt = time.Now()
 	return s.repo.Update(e)
 }
@@ -220,6 +270,10 @@ func (s *EvidenceService) TransferCustody(
 		return fmt.Errorf("failed to find evidence: %w", err)
 	}
 
+	if s.isDisposed(evidenceID) {
+		return ErrEvidenceDisposed
+	}
+
 	// Create custody event
 	event := CustodyEvent{
 		ID:           generateID("CE"),
@@ -275,31 +329,130 @@ func (s *EvidenceService) VerifyIntegrity(evidenceID string) (bool, error) {
 	return false, fmt.Errorf("failed to convert to digital evidence type")
 }
 
+// verifyRecordedHash checks that the file of a digital evidence item still
+// matches the hash recorded at intake. Directories are not checked.
+func verifyRecordedHash(e *DigitalEvidence) error {
+	info, err := os.Stat(e.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+	if e.FileHash == "" || info.IsDir() {
+		return nil
+	}
+
+	hash, err := calculateFileHash(e.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", e.FilePath, err)
+	}
+	if hash != e.FileHash {
+		return fmt.Errorf("%s does not match the recorded hash of %s", e.FilePath, e.ID)
+	}
+	return nil
+}
+
 // CreateDigitalEvidence creates a new digital evidence item with file validation
 func (s *EvidenceService) CreateDigitalEvidence(e *DigitalEvidence) error {
+	if err := prepareDigitalEvidence(e); err != nil {
+		return err
+	}
+	if err := s.classifyKnownFile(&e.Evidence, e.Metadata["MD5"], e.Metadata["SHA1"]); err != nil {
+		return err
+	}
+
+	// Create evidence
+	if err := s.CreateEvidence(&e.Evidence); err != nil {
+		return err
+	}
+	if err := s.saveDigital(e); err != nil {
+		s.repo.Delete(e.ID)
+		return err
+	}
+	return nil
+}
+
+// SetDigitalRepository enables storage of the digital details of evidence
+// items. Without it only the common evidence record is kept.
+func (s *EvidenceService) SetDigitalRepository(repo DigitalRepository) {
+	s.digital = repo
+}
+
+// GetDigitalEvidence retrieves a digital evidence item with its digital
+// details. The common record is taken from the evidence repository, which
+// holds its latest state.
+func (s *EvidenceService) GetDigitalEvidence(id string) (*DigitalEvidence, error) {
+	e, err := s.repo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Type != TypeDigital {
+		return nil, fmt.Errorf("evidence %s is not digital evidence", id)
+	}
+
+	d := &DigitalEvidence{}
+	if s.digital != nil {
+		if stored, err := s.digital.FindDigital(id); err == nil {
+			copied := *stored
+			d = &copied
+		}
+	}
+	d.Evidence = *e
+	return d, nil
+}
+
+// UpdateDigitalEvidence updates a digital evidence item and its digital details
+func (s *EvidenceService) UpdateDigitalEvidence(d *DigitalEvidence) error {
+	if err := s.UpdateEvidence(&d.Evidence); err != nil {
+		return err
+	}
+	return s.saveDigital(d)
+}
+
+// saveDigital stores the digital details of an item, if enabled
+func (s *EvidenceService) saveDigital(d *DigitalEvidence) error {
+	if s.digital == nil {
+		return nil
+	}
+	if err := s.digital.SaveDigital(d); err != nil {
+		return fmt.Errorf("failed to save digital details: %w", err)
+	}
+	return nil
+}
+
+// prepareDigitalEvidence validates, identifies and hashes the file of a
+// digital evidence item
+func prepareDigitalEvidence(e *DigitalEvidence) error {
 	// Get file info
 	fileInfo, err := os.Stat(e.FilePath)
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	// Set file size and type
+	// Set file size and identify the type from the content
 	e.FileSize = fileInfo.Size()
-	e.FileType = filepath.Ext(e.FilePath)
+	if err := identifyFileType(e); err != nil {
+		return err
+	}
 
-	// Calculate hash
-	hash, err := calculateFileHash(e.FilePath)
+	// Calculate hashes; MD5 and SHA-1 are kept for comparison with hash sets
+	hashes, _, err := hashFile(e.FilePath, false)
 	if err != nil {
 		return fmt.Errorf("failed to calculate file hash: %w", err)
 	}
-	e.FileHash = hash
-	e.OriginalHash = hash
+	e.FileHash = hashes.sha256
+	e.OriginalHash = hashes.sha256
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]string)
+	}
+	e.Metadata["MD5"] = hashes.md5
+	e.Metadata["SHA1"] = hashes.sha1
+
+	// Read embedded camera, capture time and GPS metadata
+	applyMetadataOnIntake(e)
 
 	// Set as digital evidence type
 	e.Type = TypeDigital
 
-	// Create evidence
-	return s.CreateEvidence(&e.Evidence)
+	return nil
 }
 
 // calculateFileHash calculates the SHA-256 hash of a file
@@ -318,7 +471,20 @@ func calculateFileHash(filePath string) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// generateID generates a unique ID with a prefix
+// lastID holds the last timestamp handed out by generateID
+var lastID int64
+
+// generateID generates a unique ID with a prefix. IDs are strictly increasing
+// so that items created in a tight loop (e.g. during acquisition) never collide.
 func generateID(prefix string) string {
-	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	for {
+		last := atomic.LoadInt64(&lastID)
+		next := time.Now().UnixNano()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastID, last, next) {
+			return fmt.Sprintf("%s-%d", prefix, next)
+		}
+	}
 }
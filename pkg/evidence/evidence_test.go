@@ -0,0 +1,190 @@
+package evidence
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memRepo is an in-memory EvidenceRepository for tests
+type memRepo struct {
+	items      map[string]*Evidence
+	failSave   func(e *Evidence) error // Makes Save fail for chosen items
+	failUpdate func(e *Evidence) error // Makes Update fail for chosen items
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{items: make(map[string]*Evidence)}
+}
+
+func (r *memRepo) Save(e *Evidence) error {
+	if r.failSave != nil {
+		if err := r.failSave(e); err != nil {
+			return err
+		}
+	}
+	r.items[e.ID] = e
+	return nil
+}
+
+func (r *memRepo) Find(id string) (*Evidence, error) {
+	if e, ok := r.items[id]; ok {
+		return e, nil
+	}
+	return nil, fmt.Errorf("evidence not found: %s", id)
+}
+
+func (r *memRepo) FindByCase(caseID string) ([]*Evidence, error) {
+	var result []*Evidence
+	for _, e := range r.items {
+		if e.CaseID == caseID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (r *memRepo) FindByEvidenceNumber(number string) (*Evidence, error) {
+	for _, e := range r.items {
+		if e.EvidenceNumber == number {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("evidence not found with number: %s", number)
+}
+
+func (r *memRepo) FindByStorageLocation(location string) ([]*Evidence, error) {
+	var result []*Evidence
+	for _, e := range r.items {
+		if strings.EqualFold(e.StorageLocation, location) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (r *memRepo) Search(query string) ([]*Evidence, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r *memRepo) Update(e *Evidence) error {
+	if r.failUpdate != nil {
+		if err := r.failUpdate(e); err != nil {
+			return err
+		}
+	}
+	if _, ok := r.items[e.ID]; !ok {
+		return fmt.Errorf("evidence not found: %s", e.ID)
+	}
+	r.items[e.ID] = e
+	return nil
+}
+
+func (r *memRepo) Delete(id string) error {
+	delete(r.items, id)
+	return nil
+}
+
+func TestCreateEvidenceStartsChainOfCustody(t *testing.T) {
+	repo := newMemRepo()
+	s := NewEvidenceService(repo)
+	e := &Evidence{CaseID: "CASE-1", Description: "Knife", CollectedBy: "Officer A",
+		CollectionDate: time.Now(), StorageLocation: "Locker 1"}
+	if err := s.CreateEvidence(e); err != nil {
+		t.Fatal(err)
+	}
+	if e.ID == "" || e.Status != StatusCollected {
+		t.Errorf("got ID %q status %s", e.ID, e.Status)
+	}
+	if len(e.ChainOfCustody) != 1 || e.ChainOfCustody[0].Action != "COLLECTED" {
+		t.Errorf("chain of custody = %+v", e.ChainOfCustody)
+	}
+}
+
+func TestGenerateIDIsUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		id := generateID("EV")
+		if seen[id] {
+			t.Fatalf("duplicate ID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+// memDigitalRepo is an in-memory DigitalRepository for tests
+type memDigitalRepo struct {
+	items map[string]*DigitalEvidence
+}
+
+func newMemDigitalRepo() *memDigitalRepo {
+	return &memDigitalRepo{items: make(map[string]*DigitalEvidence)}
+}
+
+func (r *memDigitalRepo) SaveDigital(d *DigitalEvidence) error {
+	r.items[d.ID] = d
+	return nil
+}
+
+func (r *memDigitalRepo) FindDigital(id string) (*DigitalEvidence, error) {
+	if d, ok := r.items[id]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("digital evidence not found: %s", id)
+}
+
+func TestDigitalDetailsArePersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("meet at the docks at nine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewEvidenceService(newMemRepo())
+	s.SetDigitalRepository(newMemDigitalRepo())
+
+	d := &DigitalEvidence{Evidence: Evidence{CaseID: "CASE-1", Type: TypeDigital, Description: "Notes",
+		CollectedBy: "Officer A", CollectionDate: time.Now()}, FilePath: path}
+	if err := s.CreateDigitalEvidence(d); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes to the common record are seen with the digital details
+	e, err := s.GetEvidence(d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Tags = append(e.Tags, "reviewed")
+	if err := s.UpdateEvidence(e); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetDigitalEvidence(d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FilePath != path || got.FileType == "" || got.Metadata["SHA1"] == "" || len(got.Tags) != 1 {
+		t.Errorf("digital evidence = %+v", got)
+	}
+
+	got.SecretIDs = []string{"SEC-1"}
+	if err := s.UpdateDigitalEvidence(got); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := s.GetDigitalEvidence(d.ID); len(again.SecretIDs) != 1 {
+		t.Errorf("secret IDs were not kept: %+v", again)
+	}
+}
+
+func TestGetDigitalEvidenceRejectsOtherTypes(t *testing.T) {
+	s := NewEvidenceService(newMemRepo())
+	e := &Evidence{CaseID: "CASE-1", Type: TypePhysical, Description: "Knife", CollectedBy: "Officer A",
+		CollectionDate: time.Now()}
+	if err := s.CreateEvidence(e); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetDigitalEvidence(e.ID); err == nil {
+		t.Error("physical evidence returned as digital evidence")
+	}
+}
@@ -0,0 +1,16 @@
+package evidence
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// fileTimes returns the access and inode change times of a file
+func fileTimes(info fs.FileInfo) (atime, ctime time.Time) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}
+	}
+	return time.Unix(st.Atimespec.Unix()), time.Unix(st.Ctimespec.Unix())
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package evidence
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fileTimes is not supported on this platform; only the modification time
+// recorded by fs.FileInfo is available
+func fileTimes(info fs.FileInfo) (atime, ctime time.Time) {
+	return time.Time{}, time.Time{}
+}
@@ -0,0 +1,39 @@
+package evidence
+
+import (
+	"fmt"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/filetype"
+)
+
+// identifyFileType records the content-based type and MIME of a digital
+// evidence file and flags an extension that does not match the content
+func identifyFileType(e *DigitalEvidence) error {
+	result, err := filetype.IdentifyFile(e.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to identify file type: %w", err)
+	}
+
+	e.FileType = result.Name
+	e.MIMEType = result.MIME
+	e.TypeMismatch = result.ExtensionMismatch
+	if result.ExtensionMismatch {
+		markExtensionMismatch(&e.Evidence, result)
+	}
+	return nil
+}
+
+// markExtensionMismatch tags an item whose file extension disguises its
+// content, so it can be reviewed as a possible concealment attempt
+func markExtensionMismatch(e *Evidence, result *filetype.Result) {
+	if !containsString(e.Tags, filetype.MismatchTag) {
+		e.Tags = append(e.Tags, filetype.MismatchTag)
+	}
+
+	note := fmt.Sprintf("Extension .%s does not match content: %s (%s). Possible concealment.",
+		result.Extension, result.Description, result.MIME)
+	if e.Notes != "" {
+		e.Notes += "\n"
+	}
+	e.Notes += note
+}
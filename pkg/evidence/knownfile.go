@@ -0,0 +1,73 @@
+package evidence
+
+import (
+	"fmt"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/hashset"
+)
+
+// KnownFileIndex looks file hashes up in known-good and known-bad hash sets
+type KnownFileIndex interface {
+	Lookup(md5, sha1, sha256 string) (hashset.Match, error)
+}
+
+// KnownFileAlerter is notified when an evidence file matches a known-bad hash set
+type KnownFileAlerter interface {
+	KnownBadFile(e *Evidence)
+}
+
+// SetKnownFileIndex enables classification of digital evidence files against
+// hash sets as they are hashed. The alerter may be nil.
+func (s *EvidenceService) SetKnownFileIndex(index KnownFileIndex, alerter KnownFileAlerter) {
+	s.knownFiles = index
+	s.knownBadAlerter = alerter
+}
+
+// CheckKnownFile hashes a file and looks it up in the hash sets
+func (s *EvidenceService) CheckKnownFile(filePath string) (hashset.Match, error) {
+	if s.knownFiles == nil {
+		return hashset.Match{}, fmt.Errorf("no hash sets configured")
+	}
+	hashes, _, err := hashFile(filePath, false)
+	if err != nil {
+		return hashset.Match{}, err
+	}
+	return s.knownFiles.Lookup(hashes.md5, hashes.sha1, hashes.sha256)
+}
+
+// IsKnownGood reports whether the item's file is in a known-good hash set,
+// such as an operating system or application file
+func (e *Evidence) IsKnownGood() bool {
+	return e.KnownFile == hashset.StatusKnownGood
+}
+
+// IsKnownBad reports whether the item's file is in a known-bad hash set
+func (e *Evidence) IsKnownBad() bool {
+	return e.KnownFile == hashset.StatusKnownBad
+}
+
+// classifyKnownFile looks the file of an evidence item up in the hash sets.
+// Known-bad items are tagged so they stand out in searches.
+func (s *EvidenceService) classifyKnownFile(e *Evidence, md5, sha1 string) error {
+	if s.knownFiles == nil || e.FileHash == "" {
+		return nil
+	}
+
+	match, err := s.knownFiles.Lookup(md5, sha1, e.FileHash)
+	if err != nil {
+		return fmt.Errorf("failed to check hash sets: %w", err)
+	}
+	e.KnownFile = match.Status
+	e.KnownFileSets = match.Sets
+	if match.Status == hashset.StatusKnownBad {
+		e.Tags = appendUnique(e.Tags, "known-bad")
+	}
+	return nil
+}
+
+// alertKnownBad raises an alert for a saved known-bad item
+func (s *EvidenceService) alertKnownBad(e *Evidence) {
+	if e.IsKnownBad() && s.knownBadAlerter != nil {
+		s.knownBadAlerter.KnownBadFile(e)
+	}
+}
@@ -0,0 +1,125 @@
+package evidence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/hashset"
+)
+
+// recordingAlerter counts known-bad alerts
+type recordingAlerter struct {
+	alerted []string
+}
+
+func (a *recordingAlerter) KnownBadFile(e *Evidence) {
+	a.alerted = append(a.alerted, e.EvidenceNumber)
+}
+
+// failingIndex is a hash set index that cannot be read
+type failingIndex struct{}
+
+func (failingIndex) Lookup(md5, sha1, sha256 string) (hashset.Match, error) {
+	return hashset.Match{}, errors.New("index unavailable")
+}
+
+// hashListFile writes a hash set holding the SHA-256 of content
+func hashListFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(content))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(sum[:])+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKnownFileClassification(t *testing.T) {
+	dir := t.TempDir()
+	index, err := hashset.Open(filepath.Join(dir, "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	if _, err := index.Import(hashListFile(t, dir, "good.txt", "system library"), "OS files", hashset.StatusKnownGood); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.Import(hashListFile(t, dir, "bad.txt", "contraband image"), "Agency list", hashset.StatusKnownBad); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		wantStatus hashset.Status
+		wantTagged bool
+		wantAlert  bool
+	}{
+		{"known good", "system library", hashset.StatusKnownGood, false, false},
+		{"known bad", "contraband image", hashset.StatusKnownBad, true, true},
+		{"unknown", "holiday photo", hashset.StatusUnknown, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.bin")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			alerter := &recordingAlerter{}
+			s := NewEvidenceService(newMemRepo())
+			s.SetKnownFileIndex(index, alerter)
+
+			e := acquisitionParent()
+			e.FilePath = path
+			if err := s.CreateDigitalEvidence(e); err != nil {
+				t.Fatal(err)
+			}
+			if e.KnownFile != tt.wantStatus {
+				t.Errorf("status = %q, want %q", e.KnownFile, tt.wantStatus)
+			}
+			if containsTag(e.Tags, "known-bad") != tt.wantTagged {
+				t.Errorf("tags = %v", e.Tags)
+			}
+			if (len(alerter.alerted) == 1) != tt.wantAlert {
+				t.Errorf("alerts = %v", alerter.alerted)
+			}
+			match, err := s.CheckKnownFile(path)
+			if err != nil || match.Status != tt.wantStatus {
+				t.Errorf("CheckKnownFile = %+v, %v", match, err)
+			}
+		})
+	}
+}
+
+func TestKnownFileIndexFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo := newMemRepo()
+	s := NewEvidenceService(repo)
+	s.SetKnownFileIndex(failingIndex{}, nil)
+
+	e := acquisitionParent()
+	e.FilePath = path
+	if err := s.CreateDigitalEvidence(e); err == nil {
+		t.Error("created evidence without checking the hash sets")
+	}
+	if len(repo.items) != 0 {
+		t.Errorf("repository holds %d items after the failure", len(repo.items))
+	}
+}
+
+// containsTag reports whether a tag is in the list
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
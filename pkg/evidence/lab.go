@@ -0,0 +1,518 @@
+package evidence
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LabStatus tracks a lab request from submission to the return of the evidence
+type LabStatus string
+
+const (
+	LabRequested  LabStatus = "REQUESTED"
+	LabShipped    LabStatus = "SHIPPED"
+	LabProcessing LabStatus = "PROCESSING"
+	LabAnalyzed   LabStatus = "ANALYZED"
+	LabReturned   LabStatus = "RETURNED"
+	LabCancelled  LabStatus = "CANCELLED"
+)
+
+// LabPriority is the urgency of a lab request
+type LabPriority string
+
+const (
+	LabPriorityRoutine LabPriority = "ROUTINE"
+	LabPriorityHigh    LabPriority = "HIGH"
+	LabPriorityRush    LabPriority = "RUSH"
+)
+
+// labTransitions lists the statuses a request may move to from each status
+var labTransitions = map[LabStatus][]LabStatus{
+	LabRequested:  {LabShipped, LabCancelled},
+	LabShipped:    {LabProcessing, LabAnalyzed, LabReturned},
+	LabProcessing: {LabAnalyzed, LabReturned},
+	LabAnalyzed:   {LabReturned},
+}
+
+// LabRequest is a request for forensic examination of an evidence item
+type LabRequest struct {
+	ID             string
+	EvidenceID     string
+	CaseID         string
+	Lab            string
+	Exams          []string // Requested examinations, e.g. "DNA profiling", "Latent prints"
+	SubmittedBy    string   // Submitting officer
+	Priority       LabPriority
+	DueDate        time.Time
+	Status         LabStatus
+	LabCaseNumber  string // Reference assigned by the lab
+	Carrier        string
+	TrackingNumber string
+	ResultReports  []string // Document IDs of imported result reports
+	ResultsSummary string
+	Notes          string
+	History        []LabStatusChange
+	RequestedAt    time.Time
+	ShippedAt      time.Time
+	CompletedAt    time.Time
+	ReturnedAt     time.Time
+}
+
+// LabStatusChange is an entry in the history of a lab request
+type LabStatusChange struct {
+	Status    LabStatus
+	ChangedBy string
+	Timestamp time.Time
+	Note      string
+}
+
+// LabSubmission describes a new lab request
+type LabSubmission struct {
+	Lab         string
+	Exams       []string
+	SubmittedBy string
+	Priority    LabPriority
+	DueDate     time.Time
+	Notes       string
+}
+
+// LabReport is a lab result report imported into the document store
+type LabReport struct {
+	DocumentID string
+	Content    string // Extracted text, used to summarize the results
+}
+
+// LabRepository stores lab requests
+type LabRepository interface {
+	SaveLabRequest(r *LabRequest) error
+	FindLabRequest(id string) (*LabRequest, error)
+	FindLabRequestsByEvidence(evidenceID string) ([]*LabRequest, error)
+	ListLabRequests() ([]*LabRequest, error)
+	UpdateLabRequest(r *LabRequest) error
+}
+
+// LabReportImporter imports lab result reports as case documents
+type LabReportImporter interface {
+	ImportLabReport(caseID, evidenceID, filePath string) (*LabReport, error)
+}
+
+// LabService manages evidence submissions to forensic labs
+type LabService struct {
+	service *EvidenceService
+	repo    LabRepository
+	bioRepo BiologicalRepository
+	reports LabReportImporter
+}
+
+// NewLabService creates a new lab service
+func NewLabService(service *EvidenceService, repo LabRepository, bioRepo BiologicalRepository, reports LabReportImporter) *LabService {
+	return &LabService{
+		service: service,
+		repo:    repo,
+		bioRepo: bioRepo,
+		reports: reports,
+	}
+}
+
+// ParseLabPriority converts user input to a lab priority
+func ParseLabPriority(value string) (LabPriority, error) {
+	switch p := LabPriority(strings.ToUpper(strings.TrimSpace(value))); p {
+	case "":
+		return LabPriorityRoutine, nil
+	case LabPriorityRoutine, LabPriorityHigh, LabPriorityRush:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown priority %q (expected ROUTINE, HIGH or RUSH)", value)
+}
+
+// SubmitToLab creates a lab request for an evidence item
+func (s *LabService) SubmitToLab(evidenceID string, sub LabSubmission) (*LabRequest, error) {
+	if sub.Lab == "" {
+		return nil, fmt.Errorf("lab is required")
+	}
+	if len(sub.Exams) == 0 {
+		return nil, fmt.Errorf("at least one examination must be requested")
+	}
+	if sub.SubmittedBy == "" {
+		return nil, fmt.Errorf("submitting officer is required")
+	}
+	if sub.Priority == "" {
+		sub.Priority = LabPriorityRoutine
+	}
+
+	e, err := s.service.GetEvidence(evidenceID)
+	if err != nil {
+		return nil, fmt.Errorf("evidence not found: %w", err)
+	}
+	if e.IsDisposed() {
+		return nil, ErrEvidenceDisposed
+	}
+
+	now := time.Now()
+	if !sub.DueDate.IsZero() && sub.DueDate.Before(now) {
+		return nil, fmt.Errorf("due date %s is in the past", sub.DueDate.Format("2006-01-02"))
+	}
+
+	request := &LabRequest{
+		ID:          generateID("LAB"),
+		EvidenceID:  e.ID,
+		CaseID:      e.CaseID,
+		Lab:         sub.Lab,
+		Exams:       sub.Exams,
+		SubmittedBy: sub.SubmittedBy,
+		Priority:    sub.Priority,
+		DueDate:     sub.DueDate,
+		Status:      LabRequested,
+		Notes:       sub.Notes,
+		RequestedAt: now,
+	}
+	request.History = append(request.History, LabStatusChange{
+		Status:    LabRequested,
+		ChangedBy: sub.SubmittedBy,
+		Timestamp: now,
+		Note:      strings.Join(sub.Exams, ", "),
+	})
+
+	if err := s.repo.SaveLabRequest(request); err != nil {
+		return nil, fmt.Errorf("failed to save lab request: %w", err)
+	}
+	return request, nil
+}
+
+// ShipToLab records the shipment of the evidence to the lab and adds it to
+// the chain of custody
+func (s *LabService) ShipToLab(requestID, shippedBy, carrier, trackingNumber string) error {
+	request, e, err := s.load(requestID)
+	if err != nil {
+		return err
+	}
+	if shippedBy == "" {
+		return fmt.Errorf("the person shipping the evidence is required")
+	}
+	if err := checkLabTransition(request, LabShipped); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request.Carrier = carrier
+	request.TrackingNumber = trackingNumber
+	request.ShippedAt = now
+
+	event := CustodyEvent{
+		ID:              generateID("CE"),
+		EvidenceID:      e.ID,
+		Timestamp:       now,
+		Action:          "SHIPPED_TO_LAB",
+		FromPerson:      shippedBy,
+		ToPerson:        request.Lab,
+		FromLocation:    e.StorageLocation,
+		ToLocation:      request.Lab,
+		Reason:          fmt.Sprintf("Lab request %s: %s", request.ID, strings.Join(request.Exams, ", ")),
+		TransportMethod: strings.TrimSpace(carrier + " " + trackingNumber),
+	}
+	e.ChainOfCustody = append(e.ChainOfCustody, event)
+	e.StorageLocation = request.Lab
+
+	if err := s.service.UpdateEvidence(e); err != nil {
+		return fmt.Errorf("failed to record shipment: %w", err)
+	}
+	return s.setStatus(request, LabShipped, shippedBy, strings.TrimSpace("Shipped "+event.TransportMethod))
+}
+
+// UpdateLabStatus records progress reported by the lab. PROCESSING and
+// ANALYZED are mirrored on the evidence status.
+func (s *LabService) UpdateLabStatus(requestID string, status LabStatus, user, note string) error {
+	request, e, err := s.load(requestID)
+	if err != nil {
+		return err
+	}
+	if status == LabShipped || status == LabReturned {
+		return fmt.Errorf("use the shipment and return operations to record %s", status)
+	}
+	if err := checkLabTransition(request, status); err != nil {
+		return err
+	}
+
+	if evidenceStatus, ok := labEvidenceStatus(status); ok {
+		e.Status = evidenceStatus
+		if err := s.service.UpdateEvidence(e); err != nil {
+			return fmt.Errorf("failed to update evidence status: %w", err)
+		}
+	}
+	if status == LabAnalyzed && request.CompletedAt.IsZero() {
+		request.CompletedAt = time.Now()
+	}
+	return s.setStatus(request, status, user, note)
+}
+
+// ImportResults imports a lab result report as a document linked to the
+// case and request, and records the results on the evidence. When summary
+// is empty, the findings are summarized from the report text. Reports may
+// also be imported after the evidence has been returned, since labs often
+// send the written report later.
+func (s *LabService) ImportResults(requestID, filePath, summary, user string) (*LabReport, error) {
+	request, e, err := s.load(requestID)
+	if err != nil {
+		return nil, err
+	}
+	keepStatus := request.Status == LabAnalyzed || request.Status == LabReturned
+	if !keepStatus {
+		if err := checkLabTransition(request, LabAnalyzed); err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := s.reports.ImportLabReport(request.CaseID, request.EvidenceID, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import lab report: %w", err)
+	}
+	if summary == "" {
+		summary = SummarizeLabReport(report.Content)
+	}
+
+	request.ResultReports = append(request.ResultReports, report.DocumentID)
+	if summary != "" {
+		request.ResultsSummary = joinResults(request.ResultsSummary, summary)
+	}
+
+	// Record the results on the evidence
+	results := fmt.Sprintf("[%s %s, report %s] %s", request.Lab, request.ID, report.DocumentID, summary)
+	if e.Type == TypeBiological {
+		if err := s.recordBiologicalResults(e.ID, results); err != nil {
+			return nil, err
+		}
+	} else {
+		e.Notes = joinResults(e.Notes, "Lab results "+results)
+	}
+	e.Status = StatusAnalyzed
+	if err := s.service.UpdateEvidence(e); err != nil {
+		return nil, fmt.Errorf("failed to record results: %w", err)
+	}
+
+	if request.CompletedAt.IsZero() {
+		request.CompletedAt = time.Now()
+	}
+	// Analyzed and returned requests keep their status
+	status := LabAnalyzed
+	if keepStatus {
+		status = request.Status
+	}
+	if err := s.setStatus(request, status, user, "Results imported: "+report.DocumentID); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ReturnFromLab records the return of the evidence from the lab and adds
+// it to the chain of custody
+func (s *LabService) ReturnFromLab(requestID, receivedBy, location, notes string) error {
+	request, e, err := s.load(requestID)
+	if err != nil {
+		return err
+	}
+	if receivedBy == "" || location == "" {
+		return fmt.Errorf("receiving person and storage location are required")
+	}
+	if err := checkLabTransition(request, LabReturned); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request.ReturnedAt = now
+
+	e.ChainOfCustody = append(e.ChainOfCustody, CustodyEvent{
+		ID:              generateID("CE"),
+		EvidenceID:      e.ID,
+		Timestamp:       now,
+		Action:          "RETURNED_FROM_LAB",
+		FromPerson:      request.Lab,
+		ToPerson:        receivedBy,
+		FromLocation:    request.Lab,
+		ToLocation:      location,
+		Reason:          fmt.Sprintf("Lab request %s completed", request.ID),
+		Notes:           notes,
+		TransportMethod: request.Carrier,
+	})
+	e.StorageLocation = location
+	if e.Status != StatusAnalyzed {
+		e.Status = StatusInStorage
+	}
+
+	if err := s.service.UpdateEvidence(e); err != nil {
+		return fmt.Errorf("failed to record return: %w", err)
+	}
+	return s.setStatus(request, LabReturned, receivedBy, notes)
+}
+
+// CancelLabRequest cancels a request that has not yet been shipped
+func (s *LabService) CancelLabRequest(requestID, user, reason string) error {
+	request, err := s.repo.FindLabRequest(requestID)
+	if err != nil {
+		return fmt.Errorf("lab request not found: %w", err)
+	}
+	if err := checkLabTransition(request, LabCancelled); err != nil {
+		return err
+	}
+	return s.setStatus(request, LabCancelled, user, reason)
+}
+
+// GetLabRequest retrieves a lab request by ID
+func (s *LabService) GetLabRequest(id string) (*LabRequest, error) {
+	return s.repo.FindLabRequest(id)
+}
+
+// RequestsFor returns the lab requests for an evidence item, oldest first
+func (s *LabService) RequestsFor(evidenceID string) ([]*LabRequest, error) {
+	requests, err := s.repo.FindLabRequestsByEvidence(evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].RequestedAt.Before(requests[j].RequestedAt) })
+	return requests, nil
+}
+
+// Overdue returns open requests whose due date has passed, most overdue first
+func (s *LabService) Overdue(now time.Time) ([]*LabRequest, error) {
+	requests, err := s.repo.ListLabRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	var overdue []*LabRequest
+	for _, r := range requests {
+		if r.IsOpen() && !r.DueDate.IsZero() && r.DueDate.Before(now) {
+			overdue = append(overdue, r)
+		}
+	}
+	sort.Slice(overdue, func(i, j int) bool { return overdue[i].DueDate.Before(overdue[j].DueDate) })
+	return overdue, nil
+}
+
+// IsOpen reports whether the lab has not yet completed the request
+func (r *LabRequest) IsOpen() bool {
+	switch r.Status {
+	case LabRequested, LabShipped, LabProcessing:
+		return true
+	}
+	return false
+}
+
+// SummarizeLabReport picks the conclusion of a lab report: the text after a
+// "Results", "Conclusion(s)", "Findings" or "Opinion" heading, or failing
+// that the first lines of the report
+func SummarizeLabReport(content string) string {
+	const maxLines = 5
+
+	lines := strings.Split(content, "\n")
+	start := -1
+	for i, line := range lines {
+		heading := strings.ToLower(strings.TrimRight(strings.TrimSpace(line), ":"))
+		for _, h := range []string{"results", "result", "conclusion", "conclusions", "findings", "opinion", "summary"} {
+			if heading == h || strings.HasPrefix(heading, h+":") {
+				start = i
+				break
+			}
+		}
+		if start >= 0 {
+			break
+		}
+	}
+
+	var summary []string
+	if start >= 0 {
+		// Keep text that follows the heading on the same line
+		if _, rest, ok := strings.Cut(lines[start], ":"); ok && strings.TrimSpace(rest) != "" {
+			summary = append(summary, strings.TrimSpace(rest))
+		}
+		lines = lines[start+1:]
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if start >= 0 && len(summary) > 0 {
+				break // End of the results paragraph
+			}
+			continue
+		}
+		summary = append(summary, line)
+		if len(summary) == maxLines {
+			break
+		}
+	}
+	return strings.Join(summary, " ")
+}
+
+// load retrieves a request and its evidence item
+func (s *LabService) load(requestID string) (*LabRequest, *Evidence, error) {
+	request, err := s.repo.FindLabRequest(requestID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lab request not found: %w", err)
+	}
+	e, err := s.service.GetEvidence(request.EvidenceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("evidence not found: %w", err)
+	}
+	if e.IsDisposed() {
+		return nil, nil, ErrEvidenceDisposed
+	}
+	return request, e, nil
+}
+
+// setStatus records a status change on a request and saves it
+func (s *LabService) setStatus(request *LabRequest, status LabStatus, user, note string) error {
+	request.Status = status
+	request.History = append(request.History, LabStatusChange{
+		Status:    status,
+		ChangedBy: user,
+		Timestamp: time.Now(),
+		Note:      note,
+	})
+	if err := s.repo.UpdateLabRequest(request); err != nil {
+		return fmt.Errorf("failed to update lab request: %w", err)
+	}
+	return nil
+}
+
+// recordBiologicalResults appends lab results to a biological sample
+func (s *LabService) recordBiologicalResults(evidenceID, results string) error {
+	b, err := s.bioRepo.FindBiological(evidenceID)
+	if err != nil {
+		return fmt.Errorf("biological details not found: %w", err)
+	}
+	b.AnalysisResults = joinResults(b.AnalysisResults, results)
+	if err := s.bioRepo.SaveBiological(b); err != nil {
+		return fmt.Errorf("failed to save analysis results: %w", err)
+	}
+	return nil
+}
+
+// checkLabTransition rejects status changes the workflow does not allow
+func checkLabTransition(request *LabRequest, to LabStatus) error {
+	for _, allowed := range labTransitions[request.Status] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("lab request %s cannot move from %s to %s", request.ID, request.Status, to)
+}
+
+// labEvidenceStatus maps lab progress to the evidence status
+func labEvidenceStatus(status LabStatus) (EvidenceStatus, bool) {
+	switch status {
+	case LabProcessing:
+		return StatusProcessing, true
+	case LabAnalyzed:
+		return StatusAnalyzed, true
+	}
+	return "", false
+}
+
+// joinResults appends a paragraph to existing text
+func joinResults(existing, addition string) string {
+	if existing == "" {
+		return addition
+	}
+	return existing + "\n" + addition
+}
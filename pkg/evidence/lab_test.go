@@ -0,0 +1,139 @@
+package evidence
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// memLabRepo is an in-memory LabRepository for tests
+type memLabRepo map[string]*LabRequest
+
+func (r memLabRepo) SaveLabRequest(req *LabRequest) error {
+	r[req.ID] = req
+	return nil
+}
+
+func (r memLabRepo) FindLabRequest(id string) (*LabRequest, error) {
+	if req, ok := r[id]; ok {
+		return req, nil
+	}
+	return nil, fmt.Errorf("lab request not found: %s", id)
+}
+
+func (r memLabRepo) FindLabRequestsByEvidence(evidenceID string) ([]*LabRequest, error) {
+	var result []*LabRequest
+	for _, req := range r {
+		if req.EvidenceID == evidenceID {
+			result = append(result, req)
+		}
+	}
+	return result, nil
+}
+
+func (r memLabRepo) ListLabRequests() ([]*LabRequest, error) {
+	var result []*LabRequest
+	for _, req := range r {
+		result = append(result, req)
+	}
+	return result, nil
+}
+
+func (r memLabRepo) UpdateLabRequest(req *LabRequest) error {
+	r[req.ID] = req
+	return nil
+}
+
+// fakeReports imports lab reports without a document store
+type fakeReports struct{}
+
+func (fakeReports) ImportLabReport(caseID, evidenceID, filePath string) (*LabReport, error) {
+	return &LabReport{DocumentID: "DOC-" + filePath, Content: "Results\nThe profile matches the suspect.\n"}, nil
+}
+
+// newLabRequest submits a stored item to a lab and moves the request
+// through the given steps
+func newLabRequest(t *testing.T, steps ...func(s *LabService, id string) error) (*LabService, *LabRequest) {
+	t.Helper()
+	service := NewEvidenceService(newMemRepo())
+	e := &Evidence{CaseID: "CASE-1", Description: "Swab", CollectedBy: "Officer A",
+		CollectionDate: time.Now(), StorageLocation: "Locker 1"}
+	if err := service.CreateEvidence(e); err != nil {
+		t.Fatal(err)
+	}
+	s := NewLabService(service, memLabRepo{}, &memBioRepo{}, fakeReports{})
+	req, err := s.SubmitToLab(e.ID, LabSubmission{Lab: "State Lab", Exams: []string{"DNA"}, SubmittedBy: "DET-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if err := step(s, req.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, req
+}
+
+func ship(s *LabService, id string) error { return s.ShipToLab(id, "DET-1", "Courier", "1Z") }
+
+func analyze(s *LabService, id string) error { return s.UpdateLabStatus(id, LabAnalyzed, "LAB", "") }
+
+func giveBack(s *LabService, id string) error { return s.ReturnFromLab(id, "DET-1", "Locker 2", "") }
+
+func TestImportResults(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []func(s *LabService, id string) error
+		wantErr    bool
+		wantStatus LabStatus
+	}{
+		{"not shipped", nil, true, LabRequested},
+		{"at the lab", []func(*LabService, string) error{ship}, false, LabAnalyzed},
+		{"analyzed", []func(*LabService, string) error{ship, analyze}, false, LabAnalyzed},
+		{"returned after analysis", []func(*LabService, string) error{ship, analyze, giveBack}, false, LabReturned},
+		{"returned before the report", []func(*LabService, string) error{ship, giveBack}, false, LabReturned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, req := newLabRequest(t, tt.steps...)
+			_, err := s.ImportResults(req.ID, "report.pdf", "", "DET-1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			got, _ := s.GetLabRequest(req.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.ResultReports) != 1 || got.ResultsSummary == "" || got.CompletedAt.IsZero() {
+				t.Errorf("request = %+v", got)
+			}
+			e, _ := s.service.GetEvidence(req.EvidenceID)
+			if e.Status != StatusAnalyzed {
+				t.Errorf("evidence status = %s", e.Status)
+			}
+		})
+	}
+}
+
+func TestLabTransitions(t *testing.T) {
+	s, req := newLabRequest(t)
+	if err := giveBack(s, req.ID); err == nil {
+		t.Error("request returned before it was shipped")
+	}
+	if err := ship(s, req.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CancelLabRequest(req.ID, "DET-1", "No longer needed"); err == nil {
+		t.Error("shipped request cancelled")
+	}
+	if err := giveBack(s, req.ID); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := s.service.GetEvidence(req.EvidenceID)
+	if e.StorageLocation != "Locker 2" || e.ChainOfCustody[len(e.ChainOfCustody)-1].Action != "RETURNED_FROM_LAB" {
+		t.Errorf("evidence after return = %+v", e)
+	}
+}
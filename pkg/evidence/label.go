@@ -0,0 +1,362 @@
+package evidence
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/barcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// LabelFormat identifies an output format for evidence labels
+type LabelFormat string
+
+const (
+	LabelPNG LabelFormat = "PNG"
+	LabelSVG LabelFormat = "SVG"
+	LabelZPL LabelFormat = "ZPL" // Zebra Programming Language for thermal printers
+)
+
+// Label layout, in dots at 203 dpi (a 4" x 2" thermal label)
+const (
+	labelWidth      = 812
+	labelHeight     = 406
+	labelMargin     = 20
+	labelTextScale  = 2
+	labelLineHeight = 30
+	labelBarHeight  = 90
+	labelBarModule  = 2
+	labelQRModule   = 5
+	labelMaxDescLen = 32
+)
+
+// labelPayloadPrefix marks QR payloads generated by this package
+const labelPayloadPrefix = "EVD1"
+
+// Label holds the information printed on an evidence label
+type Label struct {
+	EvidenceID     string
+	CaseNumber     string
+	EvidenceNumber string
+	Description    string
+	CollectedBy    string
+	CollectionDate time.Time
+}
+
+// NewLabel builds a label for an evidence item. caseNumber is the official
+// number of the owning case; the case ID is used when it is empty.
+func NewLabel(e *Evidence, caseNumber string) *Label {
+	if caseNumber == "" {
+		caseNumber = e.CaseID
+	}
+	return &Label{
+		EvidenceID:     e.ID,
+		CaseNumber:     caseNumber,
+		EvidenceNumber: e.EvidenceNumber,
+		Description:    e.Description,
+		CollectedBy:    e.CollectedBy,
+		CollectionDate: e.CollectionDate,
+	}
+}
+
+// BarcodeValue returns the value encoded in the Code 128 barcode: the
+// evidence number, or the evidence ID when no number has been assigned
+func (l *Label) BarcodeValue() string {
+	if l.EvidenceNumber != "" {
+		return l.EvidenceNumber
+	}
+	return l.EvidenceID
+}
+
+// QRPayload returns the value encoded in the QR code. Fields are separated
+// by "|"; a "|" or backslash within a field is escaped with a backslash.
+func (l *Label) QRPayload() string {
+	fields := []string{labelPayloadPrefix, l.CaseNumber, l.EvidenceNumber, l.EvidenceID}
+	for i, f := range fields {
+		fields[i] = labelFieldEscaper.Replace(f)
+	}
+	return strings.Join(fields, "|")
+}
+
+// labelFieldEscaper escapes the separator in QR payload fields
+var labelFieldEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`)
+
+// splitLabelPayload splits a QR payload into its unescaped fields
+func splitLabelPayload(payload string) []string {
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(payload); i++ {
+		switch c := payload[i]; {
+		case c == '\\' && i+1 < len(payload):
+			i++
+			field.WriteByte(payload[i])
+		case c == '|':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// lines returns the text lines printed on the label
+func (l *Label) lines() []string {
+	description := l.Description
+	if runes := []rune(description); len(runes) > labelMaxDescLen {
+		description = string(runes[:labelMaxDescLen-3]) + "..."
+	}
+	date := ""
+	if !l.CollectionDate.IsZero() {
+		date = l.CollectionDate.Format("2006-01-02 15:04")
+	}
+	return []string{
+		"CASE: " + l.CaseNumber,
+		"EVIDENCE: " + l.BarcodeValue(),
+		"DESC: " + description,
+		"COLLECTED BY: " + l.CollectedBy,
+		"DATE: " + date,
+	}
+}
+
+// ParseLabelFormat returns the label format with the given name, in any case
+func ParseLabelFormat(name string) (LabelFormat, error) {
+	switch format := LabelFormat(strings.ToUpper(name)); format {
+	case LabelPNG, LabelSVG, LabelZPL:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported label format: %s", name)
+	}
+}
+
+// Render writes the label in the requested format
+func (l *Label) Render(w io.Writer, format LabelFormat) error {
+	format, err := ParseLabelFormat(string(format))
+	if err != nil {
+		return err
+	}
+	switch format {
+	case LabelPNG:
+		return l.RenderPNG(w)
+	case LabelSVG:
+		return l.RenderSVG(w)
+	default:
+		return l.RenderZPL(w)
+	}
+}
+
+// RenderPNG writes the label as a PNG image
+func (l *Label) RenderPNG(w io.Writer) error {
+	bars, qr, err := l.encode()
+	if err != nil {
+		return err
+	}
+
+	img := image.NewGray(image.Rect(0, 0, labelWidth, labelHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for i, line := range l.lines() {
+		drawText(img, line, labelMargin, labelMargin+i*labelLineHeight)
+	}
+
+	barY := labelHeight - labelMargin - labelBarHeight
+	for i, dark := range bars {
+		if dark {
+			x := labelMargin + i*labelBarModule
+			draw.Draw(img, image.Rect(x, barY, x+labelBarModule, barY+labelBarHeight), image.Black, image.Point{}, draw.Src)
+		}
+	}
+
+	qrX, qrY := qrOrigin(qr)
+	for y, row := range qr.Modules {
+		for x, dark := range row {
+			if dark {
+				px, py := qrX+x*labelQRModule, qrY+y*labelQRModule
+				draw.Draw(img, image.Rect(px, py, px+labelQRModule, py+labelQRModule), image.Black, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode label: %w", err)
+	}
+	return nil
+}
+
+// RenderSVG writes the label as an SVG document
+func (l *Label) RenderSVG(w io.Writer) error {
+	bars, qr, err := l.encode()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		labelWidth, labelHeight, labelWidth, labelHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", labelWidth, labelHeight)
+
+	b.WriteString(`<g font-family="monospace" font-size="22" fill="#000">` + "\n")
+	for i, line := range l.lines() {
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n",
+			labelMargin, labelMargin+i*labelLineHeight+22, html.EscapeString(line))
+	}
+	b.WriteString("</g>\n")
+
+	b.WriteString(`<g fill="#000">` + "\n")
+	barY := labelHeight - labelMargin - labelBarHeight
+	for i := 0; i < len(bars); {
+		if !bars[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(bars) && bars[i] {
+			i++
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d"/>`+"\n",
+			labelMargin+start*labelBarModule, barY, (i-start)*labelBarModule, labelBarHeight)
+	}
+
+	qrX, qrY := qrOrigin(qr)
+	for y, row := range qr.Modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d"/>`+"\n",
+					qrX+x*labelQRModule, qrY+y*labelQRModule, labelQRModule, labelQRModule)
+			}
+		}
+	}
+	b.WriteString("</g>\n</svg>\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// RenderZPL writes the label as ZPL II. Barcodes are generated by the
+// printer from the ^BC and ^BQ commands.
+func (l *Label) RenderZPL(w io.Writer) error {
+	// The printer would draw an over-long barcode past the label edge, so
+	// the symbols are checked here as for the image formats
+	if _, _, err := l.encode(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("^XA\n")
+	fmt.Fprintf(&b, "^PW%d\n^LL%d\n^CF0,26\n", labelWidth, labelHeight)
+
+	for i, line := range l.lines() {
+		fmt.Fprintf(&b, "^FO%d,%d^FH^FD%s^FS\n", labelMargin, labelMargin+i*labelLineHeight, zplEscape(line))
+	}
+
+	fmt.Fprintf(&b, "^FO%d,%d^BY%d^BCN,%d,N,N,N^FH^FD%s^FS\n",
+		labelMargin, labelHeight-labelMargin-labelBarHeight, labelBarModule, labelBarHeight, zplEscape(l.BarcodeValue()))
+	fmt.Fprintf(&b, "^FO%d,%d^BQN,2,%d^FH^FDMA,%s^FS\n",
+		labelWidth-labelMargin-200, labelMargin, labelQRModule, zplEscape(l.QRPayload()))
+
+	b.WriteString("^XZ\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// encode generates the Code 128 and QR symbols for the label
+func (l *Label) encode() ([]bool, *barcode.QRCode, error) {
+	bars, err := barcode.Code128(l.BarcodeValue())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode barcode: %w", err)
+	}
+	if maxModules := (labelWidth - 2*labelMargin) / labelBarModule; len(bars) > maxModules {
+		return nil, nil, fmt.Errorf("barcode value %q is too long for the label: %d modules, at most %d fit",
+			l.BarcodeValue(), len(bars), maxModules)
+	}
+
+	qr, err := barcode.EncodeQR([]byte(l.QRPayload()), barcode.ECLevelM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	return bars, qr, nil
+}
+
+// qrOrigin returns the top-left corner of the QR code on the label
+func qrOrigin(qr *barcode.QRCode) (int, int) {
+	return labelWidth - labelMargin - qr.Size*labelQRModule, labelMargin
+}
+
+// drawText draws scaled text with its top-left corner at (x, y)
+func drawText(dst *image.Gray, text string, x, y int) {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, text).Ceil()
+	height := face.Metrics().Height.Ceil()
+
+	src := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	d := font.Drawer{
+		Dst:  src,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(0, face.Metrics().Ascent.Ceil()),
+	}
+	d.DrawString(text)
+
+	for sy := 0; sy < height; sy++ {
+		for sx := 0; sx < width; sx++ {
+			if src.GrayAt(sx, sy).Y >= 128 {
+				continue
+			}
+			for dy := 0; dy < labelTextScale; dy++ {
+				for dx := 0; dx < labelTextScale; dx++ {
+					dst.SetGray(x+sx*labelTextScale+dx, y+sy*labelTextScale+dy, color.Gray{})
+				}
+			}
+		}
+	}
+}
+
+// zplEscape hex-escapes characters that have special meaning in ZPL field
+// data (used together with ^FH)
+func zplEscape(s string) string {
+	r := strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E")
+	return r.Replace(s)
+}
+
+// ParseLabelCode extracts the evidence number and ID from a scanned label.
+// QR payloads carry both; a Code 128 scan yields only the barcode value,
+// which is returned as the evidence number.
+func ParseLabelCode(code string) (evidenceNumber, evidenceID string) {
+	code = strings.TrimSpace(code)
+	parts := splitLabelPayload(code)
+	if len(parts) == 4 && parts[0] == labelPayloadPrefix {
+		return parts[2], parts[3]
+	}
+	return code, ""
+}
+
+// LookupByLabelCode finds the evidence item identified by a scanned label
+func (s *EvidenceService) LookupByLabelCode(code string) (*Evidence, error) {
+	number, id := ParseLabelCode(code)
+	if id != "" {
+		return s.repo.Find(id)
+	}
+
+	if e, err := s.repo.FindByEvidenceNumber(number); err == nil {
+		return e, nil
+	}
+
+	// Items without an evidence number carry their ID in the barcode
+	e, err := s.repo.Find(number)
+	if err != nil {
+		return nil, fmt.Errorf("no evidence found for scanned code %q", code)
+	}
+	return e, nil
+}
@@ -0,0 +1,105 @@
+package evidence
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLabelCodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		label  Label
+		number string
+		id     string
+	}{
+		{"plain", Label{CaseNumber: "2024-001", EvidenceNumber: "E-7", EvidenceID: "EV-1"}, "E-7", "EV-1"},
+		{"separator in numbers", Label{CaseNumber: "A|B", EvidenceNumber: "E|7", EvidenceID: "EV-1"}, "E|7", "EV-1"},
+		{"backslash in numbers", Label{CaseNumber: `C\`, EvidenceNumber: `E\|7\`, EvidenceID: "EV-1"}, `E\|7\`, "EV-1"},
+		{"no evidence number", Label{CaseNumber: "2024-001", EvidenceID: "EV-1"}, "", "EV-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, id := ParseLabelCode(tt.label.QRPayload())
+			if number != tt.number || id != tt.id {
+				t.Errorf("ParseLabelCode = %q, %q, want %q, %q", number, id, tt.number, tt.id)
+			}
+		})
+	}
+}
+
+func TestParseLabelCodeBarcode(t *testing.T) {
+	number, id := ParseLabelCode(" E-7 \n")
+	if number != "E-7" || id != "" {
+		t.Errorf("ParseLabelCode = %q, %q", number, id)
+	}
+}
+
+func TestLabelTruncatesDescriptionOnRunes(t *testing.T) {
+	l := Label{Description: strings.Repeat("é", 40)}
+	desc := strings.TrimPrefix(l.lines()[2], "DESC: ")
+	if !utf8.ValidString(desc) {
+		t.Errorf("description %q is not valid UTF-8", desc)
+	}
+	if n := utf8.RuneCountInString(desc); n != labelMaxDescLen {
+		t.Errorf("description has %d characters, want %d", n, labelMaxDescLen)
+	}
+}
+
+func TestParseLabelFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    LabelFormat
+		wantErr bool
+	}{
+		{"png", LabelPNG, false},
+		{"SVG", LabelSVG, false},
+		{"Zpl", LabelZPL, false},
+		{"pdf", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLabelFormat(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseLabelFormat(%q) = %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestRenderLabel(t *testing.T) {
+	l := Label{EvidenceID: "EV-1", CaseNumber: "2024-001", EvidenceNumber: "E-7", Description: "Knife"}
+	for _, format := range []LabelFormat{LabelPNG, LabelSVG, LabelZPL} {
+		var buf bytes.Buffer
+		if err := l.Render(&buf, format); err != nil {
+			t.Errorf("%s: %v", format, err)
+		} else if buf.Len() == 0 {
+			t.Errorf("%s: empty label", format)
+		}
+	}
+}
+
+func TestRenderLabelBarcodeWidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		wantErr bool
+	}{
+		// Code 128 set B takes 11 modules a character plus 35 for the start,
+		// check and stop symbols; 386 two-dot modules fit between the margins
+		{"longest text that fits", strings.Repeat("E", 31), false},
+		{"one character too many", strings.Repeat("E", 32), true},
+		{"digits packed in pairs", strings.Repeat("7", 60), false},
+	}
+	for _, tt := range tests {
+		for _, format := range []LabelFormat{LabelPNG, LabelSVG, LabelZPL} {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				l := Label{EvidenceID: "EV-1", CaseNumber: "2024-001", EvidenceNumber: tt.number}
+				var buf bytes.Buffer
+				if err := l.Render(&buf, format); (err != nil) != tt.wantErr {
+					t.Errorf("err = %v, want error %v", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package evidence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RelationType describes how a derived item was produced from its parent
+type RelationType string
+
+const (
+	RelationExtractedFrom RelationType = "EXTRACTED_FROM" // Files extracted or carved from a device or image
+	RelationCopyOf        RelationType = "COPY_OF"        // Bit-for-bit or working copy
+	RelationAnalyzedInto  RelationType = "ANALYZED_INTO"  // Report or result produced by analysing the parent
+	RelationSubsampleOf   RelationType = "SUBSAMPLE_OF"   // Portion of a physical or biological sample
+)
+
+// relationLabels are the phrases used when printing lineage
+var relationLabels = map[RelationType]string{
+	RelationExtractedFrom: "extracted from",
+	RelationCopyOf:        "copy of",
+	RelationAnalyzedInto:  "analysis of",
+	RelationSubsampleOf:   "subsample of",
+}
+
+// maxLineageDepth guards against cycles in corrupt records
+const maxLineageDepth = 64
+
+// Derivation records how an evidence item was derived from its parent
+type Derivation struct {
+	ParentID    string
+	Relation    RelationType
+	Tool        string // e.g. "Cellebrite UFED", "PhotoRec"
+	ToolVersion string
+	Operator    string // Person who performed the derivation
+	Timestamp   time.Time
+	ParentHash  string // Parent's hash at the time of derivation
+	Hash        string // Hash of the derived item
+	SourcePath  string // Path of the item within its parent, e.g. inside an archive
+	Notes       string
+}
+
+// DerivationRequest describes a derivation to record
+type DerivationRequest struct {
+	ParentID    string
+	Relation    RelationType
+	Tool        string
+	ToolVersion string
+	Operator    string
+	SourcePath  string
+	Notes       string
+}
+
+// LineageNode is an item in an evidence lineage tree
+type LineageNode struct {
+	Evidence *Evidence
+	Children []*LineageNode
+}
+
+// ParseRelationType converts user input such as "extracted-from" to a relation type
+func ParseRelationType(value string) (RelationType, error) {
+	relation := RelationType(strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(value), "-", "_")))
+	if _, ok := relationLabels[relation]; !ok {
+		return "", fmt.Errorf("unknown relation %q (expected EXTRACTED_FROM, COPY_OF, ANALYZED_INTO or SUBSAMPLE_OF)", value)
+	}
+	return relation, nil
+}
+
+// DeriveEvidence creates a new evidence item derived from an existing one,
+// recording the relation, tool and operator and carrying hashes forward
+func (s *EvidenceService) DeriveEvidence(child *Evidence, req DerivationRequest) error {
+	parent, derivation, err := s.prepareDerivation(child, req)
+	if err != nil {
+		return err
+	}
+
+	if child.CaseID == "" {
+		child.CaseID = parent.CaseID
+	}
+	if child.CollectedBy == "" {
+		child.CollectedBy = req.Operator
+	}
+	if child.CollectionDate.IsZero() {
+		child.CollectionDate = derivation.Timestamp
+	}
+	if child.CollectionMethod == "" {
+		child.CollectionMethod = derivationMethod(derivation)
+	}
+	child.DerivedFrom = derivation
+	child.RelatedEvidence = appendUnique(child.RelatedEvidence, parent.ID)
+
+	if err := s.CreateEvidence(child); err != nil {
+		return err
+	}
+	return s.linkToParent(parent, child, derivation)
+}
+
+// DeriveDigitalEvidence creates a derived digital evidence item from a file,
+// such as a file extracted from a phone image
+func (s *EvidenceService) DeriveDigitalEvidence(child *DigitalEvidence, req DerivationRequest) error {
+	if err := prepareDigitalEvidence(child); err != nil {
+		return err
+	}
+	if err := s.classifyKnownFile(&child.Evidence, child.Metadata["MD5"], child.Metadata["SHA1"]); err != nil {
+		return err
+	}
+	if err := s.DeriveEvidence(&child.Evidence, req); err != nil {
+		return err
+	}
+	return s.saveDigital(child)
+}
+
+// RecordDerivation links an existing evidence item to the item it was derived from
+func (s *EvidenceService) RecordDerivation(childID string, req DerivationRequest) error {
+	child, err := s.repo.Find(childID)
+	if err != nil {
+		return fmt.Errorf("evidence not found: %w", err)
+	}
+	if child.DerivedFrom != nil {
+		return fmt.Errorf("evidence %s is already derived from %s", child.ID, child.DerivedFrom.ParentID)
+	}
+
+	parent, derivation, err := s.prepareDerivation(child, req)
+	if err != nil {
+		return err
+	}
+
+	// The child must not be an ancestor of its new parent
+	ancestors, err := s.ancestors(parent)
+	if err != nil {
+		return err
+	}
+	for _, a := range append(ancestors, parent) {
+		if a.ID == child.ID {
+			return fmt.Errorf("recording %s as derived from %s would create a cycle", child.ID, parent.ID)
+		}
+	}
+
+	child.DerivedFrom = derivation
+	child.RelatedEvidence = appendUnique(child.RelatedEvidence, parent.ID)
+	if err := s.UpdateEvidence(child); err != nil {
+		return err
+	}
+	return s.linkToParent(parent, child, derivation)
+}
+
+// Lineage returns the full derivation tree containing an item, rooted at
+// the original seizure
+func (s *EvidenceService) Lineage(id string) (*LineageNode, error) {
+	e, err := s.repo.Find(id)
+	if err != nil {
+		return nil, fmt.Errorf("evidence not found: %w", err)
+	}
+
+	ancestors, err := s.ancestors(e)
+	if err != nil {
+		return nil, err
+	}
+	root := e
+	if len(ancestors) > 0 {
+		root = ancestors[len(ancestors)-1]
+	}
+
+	return s.lineageTree(root, 0)
+}
+
+// Ancestors returns the chain of items an evidence item was derived from,
+// nearest first
+func (s *EvidenceService) Ancestors(id string) ([]*Evidence, error) {
+	e, err := s.repo.Find(id)
+	if err != nil {
+		return nil, fmt.Errorf("evidence not found: %w", err)
+	}
+	return s.ancestors(e)
+}
+
+// FormatLineage renders a lineage tree as indented text, marking the item
+// with focusID
+func FormatLineage(root *LineageNode, focusID string) string {
+	var b strings.Builder
+	writeLineageNode(&b, root, focusID, "", "", true)
+	return b.String()
+}
+
+// prepareDerivation validates a derivation request and builds the record
+func (s *EvidenceService) prepareDerivation(child *Evidence, req DerivationRequest) (*Evidence, *Derivation, error) {
+	if _, ok := relationLabels[req.Relation]; !ok {
+		return nil, nil, fmt.Errorf("unknown relation type: %s", req.Relation)
+	}
+	if req.Operator == "" {
+		return nil, nil, fmt.Errorf("the operator performing the derivation is required")
+	}
+
+	parent, err := s.repo.Find(req.ParentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parent evidence not found: %w", err)
+	}
+	if parent.IsDisposed() {
+		return nil, nil, ErrEvidenceDisposed
+	}
+	if child.CaseID != "" && child.CaseID != parent.CaseID {
+		return nil, nil, fmt.Errorf("derived evidence must belong to the parent's case %s", parent.CaseID)
+	}
+
+	derivation := &Derivation{
+		ParentID:    parent.ID,
+		Relation:    req.Relation,
+		Tool:        req.Tool,
+		ToolVersion: req.ToolVersion,
+		Operator:    req.Operator,
+		Timestamp:   time.Now(),
+		ParentHash:  parent.FileHash,
+		SourcePath:  req.SourcePath,
+		Notes:       req.Notes,
+	}
+
+	// A copy carries its parent's hash; if it was hashed separately the
+	// hashes must agree
+	if req.Relation == RelationCopyOf && parent.FileHash != "" {
+		switch child.FileHash {
+		case "":
+			child.FileHash = parent.FileHash
+		case parent.FileHash:
+		default:
+			return nil, nil, fmt.Errorf("copy hash %s does not match parent hash %s", child.FileHash, parent.FileHash)
+		}
+	}
+	derivation.Hash = child.FileHash
+
+	return parent, derivation, nil
+}
+
+// linkToParent records the derived item on its parent
+func (s *EvidenceService) linkToParent(parent, child *Evidence, derivation *Derivation) error {
+	parent.RelatedEvidence = appendUnique(parent.RelatedEvidence, child.ID)
+	parent.ChainOfCustody = append(parent.ChainOfCustody, CustodyEvent{
+		ID:           generateID("CE"),
+		EvidenceID:   parent.ID,
+		Timestamp:    derivation.Timestamp,
+		Action:       "DERIVED",
+		FromPerson:   derivation.Operator,
+		ToPerson:     derivation.Operator,
+		FromLocation: parent.StorageLocation,
+		ToLocation:   parent.StorageLocation,
+		Reason:       fmt.Sprintf("%s created (%s)", child.ID, relationLabels[derivation.Relation]),
+		Notes:        derivation.Notes,
+	})
+	if derivation.Tool != "" {
+		parent.ChainOfCustody[len(parent.ChainOfCustody)-1].VerificationMethod = derivationTool(derivation)
+	}
+
+	if err := s.UpdateEvidence(parent); err != nil {
+		return fmt.Errorf("failed to link %s to parent %s: %w", child.ID, parent.ID, err)
+	}
+	return nil
+}
+
+// ancestors follows DerivedFrom links, nearest first
+func (s *EvidenceService) ancestors(e *Evidence) ([]*Evidence, error) {
+	var chain []*Evidence
+	for current := e; current.DerivedFrom != nil; {
+		if len(chain) >= maxLineageDepth {
+			return nil, fmt.Errorf("lineage of %s exceeds %d levels", e.ID, maxLineageDepth)
+		}
+		parent, err := s.repo.Find(current.DerivedFrom.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("parent %s of %s not found: %w", current.DerivedFrom.ParentID, current.ID, err)
+		}
+		chain = append(chain, parent)
+		current = parent
+	}
+	return chain, nil
+}
+
+// lineageTree builds the tree of items derived from e
+func (s *EvidenceService) lineageTree(e *Evidence, depth int) (*LineageNode, error) {
+	if depth > maxLineageDepth {
+		return nil, fmt.Errorf("lineage of %s exceeds %d levels", e.ID, maxLineageDepth)
+	}
+
+	node := &LineageNode{Evidence: e}
+	for _, id := range e.RelatedEvidence {
+		related, err := s.repo.Find(id)
+		if err != nil || related.DerivedFrom == nil || related.DerivedFrom.ParentID != e.ID {
+			continue // Untyped or reverse link
+		}
+		child, err := s.lineageTree(related, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node, nil
+}
+
+// writeLineageNode writes one node and its children
+func writeLineageNode(b *strings.Builder, node *LineageNode, focusID, prefix, childPrefix string, root bool) {
+	e := node.Evidence
+	b.WriteString(prefix)
+	fmt.Fprintf(b, "%s [%s] %s", e.ID, e.Type, e.Description)
+	if root && e.DerivedFrom == nil {
+		b.WriteString(" (original seizure)")
+	}
+	if e.ID == focusID {
+		b.WriteString("  <==")
+	}
+	b.WriteString("\n")
+
+	if d := e.DerivedFrom; d != nil {
+		details := []string{relationLabels[d.Relation] + " " + d.ParentID}
+		if tool := derivationTool(d); tool != "" {
+			details = append(details, tool)
+		}
+		details = append(details, "by "+d.Operator, d.Timestamp.Format("2006-01-02 15:04"))
+		fmt.Fprintf(b, "%s    %s\n", childPrefix, strings.Join(details, ", "))
+		if d.SourcePath != "" {
+			fmt.Fprintf(b, "%s    path %s\n", childPrefix, d.SourcePath)
+		}
+		if d.Hash != "" {
+			fmt.Fprintf(b, "%s    hash %s", childPrefix, shortHash(d.Hash))
+			if d.ParentHash != "" {
+				fmt.Fprintf(b, " (parent %s)", shortHash(d.ParentHash))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	for i, child := range node.Children {
+		last := i == len(node.Children)-1
+		branch, indent := "├── ", "│   "
+		if last {
+			branch, indent = "└── ", "    "
+		}
+		writeLineageNode(b, child, focusID, childPrefix+branch, childPrefix+indent, false)
+	}
+}
+
+// derivationTool formats the tool and version of a derivation
+func derivationTool(d *Derivation) string {
+	return strings.TrimSpace(d.Tool + " " + d.ToolVersion)
+}
+
+// derivationMethod describes a derivation as a collection method
+func derivationMethod(d *Derivation) string {
+	method := fmt.Sprintf("%s %s", strings.ToUpper(relationLabels[d.Relation][:1])+relationLabels[d.Relation][1:], d.ParentID)
+	if tool := derivationTool(d); tool != "" {
+		method += " using " + tool
+	}
+	return method
+}
+
+// shortHash abbreviates a hash for display
+func shortHash(hash string) string {
+	if len(hash) > 16 {
+		return hash[:16] + "…"
+	}
+	return hash
+}
+
+// appendUnique appends value to s unless it is already present
+func appendUnique(s []string, value string) []string {
+	if containsString(s, value) {
+		return s
+	}
+	return append(s, value)
+}
@@ -0,0 +1,154 @@
+package evidence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSeizure returns a service holding a seized phone
+func newSeizure(t *testing.T) (*EvidenceService, *Evidence) {
+	t.Helper()
+	s := NewEvidenceService(newMemRepo())
+	phone := &Evidence{CaseID: "CASE-1", Type: TypeDigital, Description: "Seized phone", CollectedBy: "OFC-1",
+		CollectionDate: time.Now(), StorageLocation: "Locker 1", FileHash: "aaaa"}
+	if err := s.CreateEvidence(phone); err != nil {
+		t.Fatal(err)
+	}
+	return s, phone
+}
+
+func TestDeriveEvidence(t *testing.T) {
+	tests := []struct {
+		name     string
+		child    Evidence
+		req      DerivationRequest
+		wantHash string
+		wantErr  bool
+	}{
+		{"copy carries the parent hash", Evidence{Description: "Working copy"},
+			DerivationRequest{Relation: RelationCopyOf, Tool: "dd", Operator: "EXAM-1"}, "aaaa", false},
+		{"copy with a matching hash", Evidence{Description: "Working copy", FileHash: "aaaa"},
+			DerivationRequest{Relation: RelationCopyOf, Operator: "EXAM-1"}, "aaaa", false},
+		{"copy with another hash", Evidence{Description: "Working copy", FileHash: "bbbb"},
+			DerivationRequest{Relation: RelationCopyOf, Operator: "EXAM-1"}, "", true},
+		{"extraction keeps its own hash", Evidence{Description: "sms.db", FileHash: "cccc"},
+			DerivationRequest{Relation: RelationExtractedFrom, Tool: "UFED", Operator: "EXAM-1", SourcePath: "/data/sms.db"}, "cccc", false},
+		{"unknown relation", Evidence{Description: "x"}, DerivationRequest{Relation: "BORROWED", Operator: "EXAM-1"}, "", true},
+		{"no operator", Evidence{Description: "x"}, DerivationRequest{Relation: RelationCopyOf}, "", true},
+		{"other case", Evidence{Description: "x", CaseID: "CASE-2"}, DerivationRequest{Relation: RelationCopyOf, Operator: "EXAM-1"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, phone := newSeizure(t)
+			child := tt.child
+			tt.req.ParentID = phone.ID
+			err := s.DeriveEvidence(&child, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(phone.RelatedEvidence) != 0 {
+					t.Errorf("parent linked to %v despite the error", phone.RelatedEvidence)
+				}
+				return
+			}
+			d := child.DerivedFrom
+			if d == nil || d.ParentID != phone.ID || d.ParentHash != "aaaa" || d.Hash != tt.wantHash || child.FileHash != tt.wantHash {
+				t.Errorf("derivation %+v, hash %s", d, child.FileHash)
+			}
+			if child.CaseID != "CASE-1" || child.CollectedBy != "EXAM-1" {
+				t.Errorf("child case %s collected by %s", child.CaseID, child.CollectedBy)
+			}
+			last := phone.ChainOfCustody[len(phone.ChainOfCustody)-1]
+			if last.Action != "DERIVED" || !strings.Contains(last.Reason, child.ID) {
+				t.Errorf("parent custody event %+v", last)
+			}
+		})
+	}
+}
+
+func TestLineage(t *testing.T) {
+	s, phone := newSeizure(t)
+	image := &Evidence{Description: "Phone image"}
+	if err := s.DeriveEvidence(image, DerivationRequest{ParentID: phone.ID, Relation: RelationCopyOf, Tool: "UFED", Operator: "EXAM-1"}); err != nil {
+		t.Fatal(err)
+	}
+	db := &Evidence{Description: "sms.db"}
+	if err := s.DeriveEvidence(db, DerivationRequest{ParentID: image.ID, Relation: RelationExtractedFrom, Operator: "EXAM-1"}); err != nil {
+		t.Fatal(err)
+	}
+	report := &Evidence{CaseID: "CASE-1", Description: "Message report", CollectedBy: "EXAM-2", CollectionDate: time.Now()}
+	if err := s.CreateEvidence(report); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordDerivation(report.ID, DerivationRequest{ParentID: db.ID, Relation: RelationAnalyzedInto, Operator: "EXAM-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		id            string
+		wantAncestors []string
+	}{
+		{"seizure", phone.ID, nil},
+		{"extracted file", db.ID, []string{image.ID, phone.ID}},
+		{"report", report.ID, []string{db.ID, image.ID, phone.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ancestors, err := s.Ancestors(tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, a := range ancestors {
+				got = append(got, a.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantAncestors, ",") {
+				t.Errorf("ancestors = %v, want %v", got, tt.wantAncestors)
+			}
+			root, err := s.Lineage(tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root.Evidence.ID != phone.ID {
+				t.Errorf("lineage rooted at %s", root.Evidence.ID)
+			}
+			text := FormatLineage(root, tt.id)
+			for _, e := range []*Evidence{phone, image, db, report} {
+				if !strings.Contains(text, e.ID) {
+					t.Errorf("lineage does not show %s:\n%s", e.ID, text)
+				}
+			}
+		})
+	}
+
+	// The seizure cannot become derived from its own descendant
+	if err := s.RecordDerivation(phone.ID, DerivationRequest{ParentID: report.ID, Relation: RelationCopyOf, Operator: "EXAM-1"}); err == nil {
+		t.Error("derivation cycle recorded")
+	}
+	if err := s.RecordDerivation(db.ID, DerivationRequest{ParentID: phone.ID, Relation: RelationCopyOf, Operator: "EXAM-1"}); err == nil {
+		t.Error("second parent recorded")
+	}
+}
+
+func TestParseRelationType(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RelationType
+		wantErr bool
+	}{
+		{"extracted-from", RelationExtractedFrom, false},
+		{" COPY_OF ", RelationCopyOf, false},
+		{"analyzed-into", RelationAnalyzedInto, false},
+		{"Subsample-Of", RelationSubsampleOf, false},
+		{"parent-of", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRelationType(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRelationType(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+}
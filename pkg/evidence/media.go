@@ -0,0 +1,130 @@
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/metadata"
+)
+
+// CaseEventWriter adds events to the timeline of the case that owns an evidence item
+type CaseEventWriter interface {
+	AddCaseEvent(caseID string, timestamp time.Time, description, location string, evidenceIDs []string) error
+}
+
+// ApplyEmbeddedMetadata reads EXIF, PNG text and HEIC/MP4 metadata from the
+// evidence file into Metadata. Embedded GPS fills Location.GPS when no
+// location was recorded at collection, and still images are added to
+// ImagePaths. Files without a supported container are left unchanged.
+func ApplyEmbeddedMetadata(e *DigitalEvidence) (*metadata.Metadata, error) {
+	m, err := metadata.ExtractFile(e.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]string)
+	}
+	for name, value := range m.Fields {
+		e.Metadata[name] = value
+	}
+
+	e.Metadata["MetadataFormat"] = string(m.Format)
+	if m.Make != "" {
+		e.Metadata["Make"] = m.Make
+	}
+	if m.Model != "" {
+		e.Metadata["Model"] = m.Model
+	}
+	if m.Software != "" {
+		e.Metadata["Software"] = m.Software
+	}
+	if m.Orientation != 0 {
+		e.Metadata["Orientation"] = strconv.Itoa(m.Orientation)
+	}
+	if !m.CaptureTime.IsZero() {
+		e.Metadata["CaptureTime"] = m.CaptureTime.Format(time.RFC3339)
+		if m.CaptureTimeZoneless {
+			e.Metadata["CaptureTimeZone"] = fmt.Sprintf("not recorded, assumed local (%s)", m.CaptureTime.Location())
+		} else {
+			delete(e.Metadata, "CaptureTimeZone")
+		}
+	}
+	if m.GPS != nil {
+		e.Metadata["GPS"] = m.GPS.String()
+		if m.GPS.HasAltitude {
+			e.Metadata["GPSAltitude"] = strconv.FormatFloat(m.GPS.Altitude, 'f', 1, 64)
+		}
+		if e.Location.GPS == "" {
+			e.Location.GPS = m.GPS.String()
+		}
+	}
+
+	if m.IsImage() && !containsString(e.ImagePaths, e.FilePath) {
+		e.ImagePaths = append(e.ImagePaths, e.FilePath)
+	}
+
+	return m, nil
+}
+
+// CaptureTime returns the capture time recorded in the embedded metadata
+func (e *DigitalEvidence) CaptureTime() (time.Time, bool) {
+	value, ok := e.Metadata["CaptureTime"]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// PlaceOnTimeline adds the moment a photo or video was captured to the case
+// timeline, located at its embedded GPS position when known. When the
+// camera recorded no time zone the event says which zone was assumed.
+func (s *EvidenceService) PlaceOnTimeline(e *DigitalEvidence, events CaseEventWriter) error {
+	captured, ok := e.CaptureTime()
+	if !ok {
+		return fmt.Errorf("evidence %s has no embedded capture time", e.ID)
+	}
+	if e.CaseID == "" {
+		return fmt.Errorf("evidence %s is not associated with a case", e.ID)
+	}
+
+	description := fmt.Sprintf("Captured: %s", e.Description)
+	if device := e.Metadata["Model"]; device != "" {
+		description += fmt.Sprintf(" (%s)", device)
+	}
+	if zone := e.Metadata["CaptureTimeZone"]; zone != "" {
+		description += fmt.Sprintf("; camera time zone %s", zone)
+	}
+
+	if err := events.AddCaseEvent(e.CaseID, captured, description, e.Metadata["GPS"], []string{e.ID}); err != nil {
+		return fmt.Errorf("failed to add timeline event for %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// applyMetadataOnIntake extracts embedded metadata without failing intake on
+// unsupported or damaged files; parse errors are recorded in Metadata
+func applyMetadataOnIntake(e *DigitalEvidence) {
+	if _, err := ApplyEmbeddedMetadata(e); err != nil && !errors.Is(err, metadata.ErrUnsupportedFormat) {
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata["MetadataError"] = err.Error()
+	}
+}
+
+// containsString reports whether s contains value
+func containsString(s []string, value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package evidence
+
+import (
+	"testing"
+	"time"
+)
+
+// recordingEvents records the events added to case timelines
+type recordingEvents struct {
+	times        []time.Time
+	descriptions []string
+}
+
+func (r *recordingEvents) AddCaseEvent(caseID string, timestamp time.Time, description, location string, evidenceIDs []string) error {
+	r.times = append(r.times, timestamp)
+	r.descriptions = append(r.descriptions, description)
+	return nil
+}
+
+func TestPlaceOnTimeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     time.Time
+		wantDesc string
+		wantErr  bool
+	}{
+		{"recorded offset",
+			map[string]string{"CaptureTime": "2024-03-01T14:30:00-04:00", "Model": "iPhone 13"},
+			time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC), "Captured: Photo (iPhone 13)", false},
+		{"assumed zone",
+			map[string]string{"CaptureTime": "2024-03-01T14:30:00Z", "CaptureTimeZone": "not recorded, assumed local (UTC)"},
+			time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC), "Captured: Photo; camera time zone not recorded, assumed local (UTC)", false},
+		{"no capture time", map[string]string{"Model": "iPhone 13"}, time.Time{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &DigitalEvidence{Evidence: Evidence{ID: "EV-1", CaseID: "CASE-1", Description: "Photo"}, Metadata: tt.metadata}
+			events := &recordingEvents{}
+			err := NewEvidenceService(newMemRepo()).PlaceOnTimeline(e, events)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(events.times) != 1 || !events.times[0].Equal(tt.want) || events.descriptions[0] != tt.wantDesc {
+				t.Errorf("events = %v %q, want %v %q", events.times, events.descriptions, tt.want, tt.wantDesc)
+			}
+		})
+	}
+}
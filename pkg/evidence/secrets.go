@@ -0,0 +1,296 @@
+package evidence
+
+import (
+	"fmt"
+	"time"
+)
+
+// SecretKind classifies the secrets held for protected evidence
+type SecretKind string
+
+const (
+	SecretPassword      SecretKind = "PASSWORD"       // Passcode or passphrase
+	SecretDecryptionKey SecretKind = "DECRYPTION_KEY" // Raw key or key file contents
+	SecretRecoveryKey   SecretKind = "RECOVERY_KEY"   // e.g. BitLocker or FileVault recovery key
+)
+
+// SecretAction is an operation recorded in the secret access log
+type SecretAction string
+
+const (
+	SecretStored      SecretAction = "STORED"
+	SecretRevealed    SecretAction = "REVEALED"
+	SecretDecryptOK   SecretAction = "DECRYPTION_SUCCEEDED"
+	SecretDecryptFail SecretAction = "DECRYPTION_FAILED"
+	SecretDenied      SecretAction = "DENIED"
+)
+
+// SecretVault stores secret values encrypted at rest
+type SecretVault interface {
+	StoreSecret(id, value string) error
+	RetrieveSecret(id string) (string, error)
+	DeleteSecret(id string) error
+}
+
+// EvidenceSecret references a secret held in the vault. The value itself is
+// never stored with the evidence record.
+type EvidenceSecret struct {
+	ID         string
+	EvidenceID string
+	Kind       SecretKind
+	Label      string // e.g. "Seized sticky note", "Suspect interview"
+	Source     string // Where the secret was obtained
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+// SecretAccess is an entry in the secret access log
+type SecretAccess struct {
+	ID         string
+	SecretID   string
+	EvidenceID string
+	User       string
+	Action     SecretAction
+	Reason     string
+	Timestamp  time.Time
+}
+
+// DecryptionRecord records which secret unlocked which evidence item
+type DecryptionRecord struct {
+	EvidenceID  string
+	SecretID    string
+	DecryptedBy string
+	Method      string // Tool or procedure used
+	Timestamp   time.Time
+}
+
+// SecretRepository stores secret references, the access log and decryption records
+type SecretRepository interface {
+	SaveSecret(secret *EvidenceSecret) error
+	FindSecret(id string) (*EvidenceSecret, error)
+	FindSecretsByEvidence(evidenceID string) ([]*EvidenceSecret, error)
+	LogSecretAccess(access SecretAccess) error
+	SecretAccessLog(evidenceID string) ([]SecretAccess, error)
+	SaveDecryption(record DecryptionRecord) error
+	FindDecryption(evidenceID string) (*DecryptionRecord, error)
+}
+
+// SecretManager keeps passwords and keys for protected digital evidence in
+// an encrypted vault and logs every access per user
+type SecretManager struct {
+	service *EvidenceService
+	vault   SecretVault
+	repo    SecretRepository
+}
+
+// NewSecretManager creates a new secret manager
+func NewSecretManager(service *EvidenceService, vault SecretVault, repo SecretRepository) *SecretManager {
+	return &SecretManager{
+		service: service,
+		vault:   vault,
+		repo:    repo,
+	}
+}
+
+// AddSecret stores a password or key for an evidence item and marks the
+// item as encrypted. The item is updated before the secret reference is
+// saved, and restored if the reference cannot be saved; the value is removed
+// from the vault again when either step fails.
+func (m *SecretManager) AddSecret(e *DigitalEvidence, kind SecretKind, value, label, source, user string) (*EvidenceSecret, error) {
+	if value == "" {
+		return nil, fmt.Errorf("secret value is required")
+	}
+	if user == "" {
+		return nil, fmt.Errorf("user is required")
+	}
+	if e.IsDisposed() || m.service.isDisposed(e.ID) {
+		return nil, ErrEvidenceDisposed
+	}
+	if kind == "" {
+		kind = SecretPassword
+	}
+
+	secret := &EvidenceSecret{
+		ID:         generateID("SEC"),
+		EvidenceID: e.ID,
+		Kind:       kind,
+		Label:      label,
+		Source:     source,
+		CreatedBy:  user,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := m.vault.StoreSecret(secret.ID, value); err != nil {
+		return nil, fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	// discard removes the stored value after a failure, reporting the
+	// original error and any failure to clean up
+	discard := func(err error) (*EvidenceSecret, error) {
+		if derr := m.vault.DeleteSecret(secret.ID); derr != nil {
+			return nil, fmt.Errorf("%w; removing secret %s from the vault also failed: %v", err, secret.ID, derr)
+		}
+		return nil, err
+	}
+
+	before := *e
+	e.SecretIDs = append(e.SecretIDs, secret.ID)
+	e.Encrypted = true
+	if err := m.service.UpdateDigitalEvidence(e); err != nil {
+		*e = before
+		return discard(fmt.Errorf("failed to update evidence: %w", err))
+	}
+	if err := m.repo.SaveSecret(secret); err != nil {
+		return discard(m.restore(e, before, fmt.Errorf("failed to save secret reference: %w", err)))
+	}
+
+	if err := m.logAccess(secret, user, SecretStored, source); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// RevealSecret returns a secret value to a user. A reason is required and
+// every request, granted or not, is written to the access log.
+func (m *SecretManager) RevealSecret(secretID, user, reason string) (string, error) {
+	secret, err := m.repo.FindSecret(secretID)
+	if err != nil {
+		return "", fmt.Errorf("secret not found: %w", err)
+	}
+
+	if user == "" || reason == "" {
+		if err := m.logAccess(secret, user, SecretDenied, "no user or reason given"); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("user and reason are required to reveal a secret")
+	}
+
+	if m.service.isDisposed(secret.EvidenceID) {
+		if err := m.logAccess(secret, user, SecretDenied, "evidence disposed"); err != nil {
+			return "", err
+		}
+		return "", ErrEvidenceDisposed
+	}
+
+	value, err := m.vault.RetrieveSecret(secretID)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve secret: %w", err)
+	}
+
+	if err := m.logAccess(secret, user, SecretRevealed, reason); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// RecordDecryptionAttempt logs an attempt to open an evidence item with a
+// secret. A successful attempt marks the item decrypted, records which secret
+// unlocked it and adds an entry to its chain of custody; the decryption
+// record is saved only once the item is updated. Secrets held for other
+// items may be tried, since passwords are often reused.
+func (m *SecretManager) RecordDecryptionAttempt(e *DigitalEvidence, secretID, user, method string, succeeded bool) error {
+	if user == "" {
+		return fmt.Errorf("user is required")
+	}
+	secret, err := m.repo.FindSecret(secretID)
+	if err != nil {
+		return fmt.Errorf("secret not found: %w", err)
+	}
+
+	action := SecretDecryptFail
+	if succeeded {
+		action = SecretDecryptOK
+	}
+	access := SecretAccess{
+		ID:         generateID("SA"),
+		SecretID:   secret.ID,
+		EvidenceID: e.ID,
+		User:       user,
+		Action:     action,
+		Reason:     method,
+		Timestamp:  time.Now(),
+	}
+	if err := m.repo.LogSecretAccess(access); err != nil {
+		return fmt.Errorf("failed to log secret access: %w", err)
+	}
+	if !succeeded {
+		return nil
+	}
+
+	record := DecryptionRecord{
+		EvidenceID:  e.ID,
+		SecretID:    secret.ID,
+		DecryptedBy: user,
+		Method:      method,
+		Timestamp:   access.Timestamp,
+	}
+
+	before := *e
+	e.Encrypted = true
+	e.Decrypted = true
+	e.DecryptedWith = secret.ID
+	e.ChainOfCustody = append(e.ChainOfCustody, CustodyEvent{
+		ID:                 generateID("CE"),
+		EvidenceID:         e.ID,
+		Timestamp:          record.Timestamp,
+		Action:             "DECRYPTED",
+		FromPerson:         user,
+		ToPerson:           user,
+		FromLocation:       e.StorageLocation,
+		ToLocation:         e.StorageLocation,
+		Reason:             "Decryption of protected content",
+		Notes:              fmt.Sprintf("Decrypted with secret %s (%s)", secret.ID, secret.Kind),
+		VerificationMethod: method,
+	})
+	if err := m.service.UpdateDigitalEvidence(e); err != nil {
+		*e = before
+		return fmt.Errorf("failed to update evidence: %w", err)
+	}
+
+	if err := m.repo.SaveDecryption(record); err != nil {
+		return m.restore(e, before, fmt.Errorf("failed to save decryption record: %w", err))
+	}
+	return nil
+}
+
+// restore puts back the state of an item whose update could not be
+// completed. It returns err, noting when the item could not be restored.
+func (m *SecretManager) restore(e *DigitalEvidence, before DigitalEvidence, err error) error {
+	*e = before
+	if rerr := m.service.UpdateDigitalEvidence(e); rerr != nil {
+		return fmt.Errorf("%w; restoring evidence %s also failed: %v", err, e.ID, rerr)
+	}
+	return err
+}
+
+// SecretsFor returns the secret references held for an evidence item
+func (m *SecretManager) SecretsFor(evidenceID string) ([]*EvidenceSecret, error) {
+	return m.repo.FindSecretsByEvidence(evidenceID)
+}
+
+// AccessLog returns the secret access log for an evidence item
+func (m *SecretManager) AccessLog(evidenceID string) ([]SecretAccess, error) {
+	return m.repo.SecretAccessLog(evidenceID)
+}
+
+// Decryption returns the record of which secret decrypted an evidence item
+func (m *SecretManager) Decryption(evidenceID string) (*DecryptionRecord, error) {
+	return m.repo.FindDecryption(evidenceID)
+}
+
+// logAccess writes an entry to the access log for a secret
+func (m *SecretManager) logAccess(secret *EvidenceSecret, user string, action SecretAction, reason string) error {
+	access := SecretAccess{
+		ID:         generateID("SA"),
+		SecretID:   secret.ID,
+		EvidenceID: secret.EvidenceID,
+		User:       user,
+		Action:     action,
+		Reason:     reason,
+		Timestamp:  time.Now(),
+	}
+	if err := m.repo.LogSecretAccess(access); err != nil {
+		return fmt.Errorf("failed to log secret access: %w", err)
+	}
+	return nil
+}
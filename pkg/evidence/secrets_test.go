@@ -0,0 +1,255 @@
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memVault is an in-memory SecretVault for tests
+type memVault map[string]string
+
+func (v memVault) StoreSecret(id, value string) error {
+	v[id] = value
+	return nil
+}
+
+func (v memVault) RetrieveSecret(id string) (string, error) {
+	if value, ok := v[id]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret not found: %s", id)
+}
+
+func (v memVault) DeleteSecret(id string) error {
+	if _, ok := v[id]; !ok {
+		return fmt.Errorf("secret not found: %s", id)
+	}
+	delete(v, id)
+	return nil
+}
+
+// memSecretRepo is an in-memory SecretRepository for tests
+type memSecretRepo struct {
+	secrets        map[string]*EvidenceSecret
+	access         []SecretAccess
+	decryptions    map[string]DecryptionRecord
+	failSave       bool
+	failDecryption bool
+}
+
+func newMemSecretRepo() *memSecretRepo {
+	return &memSecretRepo{secrets: make(map[string]*EvidenceSecret), decryptions: make(map[string]DecryptionRecord)}
+}
+
+func (r *memSecretRepo) SaveSecret(secret *EvidenceSecret) error {
+	if r.failSave {
+		return fmt.Errorf("disk full")
+	}
+	r.secrets[secret.ID] = secret
+	return nil
+}
+
+func (r *memSecretRepo) FindSecret(id string) (*EvidenceSecret, error) {
+	if s, ok := r.secrets[id]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("secret not found: %s", id)
+}
+
+func (r *memSecretRepo) FindSecretsByEvidence(evidenceID string) ([]*EvidenceSecret, error) {
+	var result []*EvidenceSecret
+	for _, s := range r.secrets {
+		if s.EvidenceID == evidenceID {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (r *memSecretRepo) LogSecretAccess(access SecretAccess) error {
+	r.access = append(r.access, access)
+	return nil
+}
+
+func (r *memSecretRepo) SecretAccessLog(evidenceID string) ([]SecretAccess, error) {
+	var result []SecretAccess
+	for _, a := range r.access {
+		if a.EvidenceID == evidenceID {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (r *memSecretRepo) SaveDecryption(record DecryptionRecord) error {
+	if r.failDecryption {
+		return fmt.Errorf("disk full")
+	}
+	r.decryptions[record.EvidenceID] = record
+	return nil
+}
+
+func (r *memSecretRepo) FindDecryption(evidenceID string) (*DecryptionRecord, error) {
+	if d, ok := r.decryptions[evidenceID]; ok {
+		return &d, nil
+	}
+	return nil, fmt.Errorf("no decryption recorded for %s", evidenceID)
+}
+
+// newSecretManager creates a manager and a stored digital evidence item
+func newSecretManager(t *testing.T) (*SecretManager, *memSecretRepo, *EvidenceService, string) {
+	t.Helper()
+	s := NewEvidenceService(newMemRepo())
+	s.SetDigitalRepository(newMemDigitalRepo())
+	e := &Evidence{CaseID: "CASE-1", Type: TypeDigital, Description: "Laptop image", CollectedBy: "Officer A",
+		CollectionDate: time.Now()}
+	if err := s.CreateEvidence(e); err != nil {
+		t.Fatal(err)
+	}
+	repo := newMemSecretRepo()
+	return NewSecretManager(s, memVault{}, repo), repo, s, e.ID
+}
+
+func TestAddSecretIsKeptWithTheEvidence(t *testing.T) {
+	m, _, s, id := newSecretManager(t)
+	d, err := s.GetDigitalEvidence(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := m.AddSecret(d, SecretPassword, "hunter2", "Sticky note", "Desk", "DET-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetDigitalEvidence(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Encrypted || len(got.SecretIDs) != 1 || got.SecretIDs[0] != secret.ID {
+		t.Errorf("evidence after AddSecret = %+v", got)
+	}
+	if value, err := m.RevealSecret(secret.ID, "DET-1", "Unlock image"); err != nil || value != "hunter2" {
+		t.Errorf("RevealSecret = %q, %v", value, err)
+	}
+}
+
+func TestAddSecretRollsBackOnFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		failSave   bool
+		failUpdate func(call int) bool // Whether the n-th evidence update fails
+		wantErr    string
+	}{
+		{"reference cannot be saved", true, nil, "failed to save secret reference"},
+		{"evidence cannot be updated", false, func(int) bool { return true }, "failed to update evidence"},
+		{"evidence cannot be restored", true, func(call int) bool { return call > 1 }, "restoring evidence"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, repo, s, id := newSecretManager(t)
+			repo.failSave = tt.failSave
+			calls := 0
+			s.repo.(*memRepo).failUpdate = func(e *Evidence) error {
+				calls++
+				if tt.failUpdate != nil && tt.failUpdate(calls) {
+					return fmt.Errorf("disk full")
+				}
+				return nil
+			}
+			d, _ := s.GetDigitalEvidence(id)
+
+			_, err := m.AddSecret(d, SecretPassword, "hunter2", "", "", "DET-1")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if vault := m.vault.(memVault); len(vault) != 0 {
+				t.Errorf("secret left in the vault: %v", vault)
+			}
+			if d.Encrypted || len(d.SecretIDs) != 0 {
+				t.Errorf("caller's item not restored: %+v", d)
+			}
+			// The stored item can only be checked when the restore succeeded
+			if !strings.Contains(err.Error(), "restoring") {
+				got, _ := s.GetDigitalEvidence(id)
+				if got.Encrypted || len(got.SecretIDs) != 0 {
+					t.Errorf("stored item not restored: %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordDecryptionAttempt(t *testing.T) {
+	tests := []struct {
+		name           string
+		succeeded      bool
+		failDecryption bool
+		dispose        bool
+		wantErr        bool
+		wantDecrypted  bool
+		wantRecord     bool
+	}{
+		{"succeeded", true, false, false, false, true, true},
+		{"failed attempt", false, false, false, false, false, false},
+		{"record cannot be saved", true, true, false, true, false, false},
+		{"evidence cannot be updated", true, false, true, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, repo, s, id := newSecretManager(t)
+			d, _ := s.GetDigitalEvidence(id)
+			secret, err := m.AddSecret(d, SecretPassword, "hunter2", "", "", "DET-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.dispose {
+				s.disposed[id] = DispositionRecord{}
+			}
+			repo.failDecryption = tt.failDecryption
+			custody := len(d.ChainOfCustody)
+
+			err = m.RecordDecryptionAttempt(d, secret.ID, "DET-1", "VeraCrypt", tt.succeeded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			got, _ := s.GetDigitalEvidence(id)
+			if got.Decrypted != tt.wantDecrypted || d.Decrypted != tt.wantDecrypted {
+				t.Errorf("decrypted: stored %v, caller %v, want %v", got.Decrypted, d.Decrypted, tt.wantDecrypted)
+			}
+			if wantCustody := custody + map[bool]int{true: 1}[tt.wantDecrypted]; len(got.ChainOfCustody) != wantCustody {
+				t.Errorf("%d custody events, want %d", len(got.ChainOfCustody), wantCustody)
+			}
+			if _, err := repo.FindDecryption(id); (err == nil) != tt.wantRecord {
+				t.Errorf("decryption record saved = %v, want %v", err == nil, tt.wantRecord)
+			}
+			// Every attempt is logged
+			last := repo.access[len(repo.access)-1]
+			if last.Action != SecretDecryptOK && last.Action != SecretDecryptFail {
+				t.Errorf("last access = %+v", last)
+			}
+		})
+	}
+}
+
+func TestRevealSecretRequiresReason(t *testing.T) {
+	m, repo, s, id := newSecretManager(t)
+	d, _ := s.GetDigitalEvidence(id)
+	secret, err := m.AddSecret(d, SecretPassword, "hunter2", "", "", "DET-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.RevealSecret(secret.ID, "DET-1", ""); err == nil {
+		t.Fatal("secret revealed without a reason")
+	}
+	if last := repo.access[len(repo.access)-1]; last.Action != SecretDenied {
+		t.Errorf("refusal not logged: %+v", last)
+	}
+
+	s.disposed[id] = DispositionRecord{}
+	if _, err := m.RevealSecret(secret.ID, "DET-1", "Review"); !errors.Is(err, ErrEvidenceDisposed) {
+		t.Errorf("err = %v, want ErrEvidenceDisposed", err)
+	}
+}
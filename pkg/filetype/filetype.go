@@ -0,0 +1,320 @@
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MismatchTag is the tag applied to evidence and documents whose extension
+// does not match their content
+const MismatchTag = "extension-mismatch"
+
+// headerSize is the amount of the file read for signature matching; it
+// covers the ISO 9660 volume descriptor at 32 KiB
+const headerSize = 36 * 1024
+
+// textSampleSize is the amount of data examined when checking for text
+const textSampleSize = 8 * 1024
+
+// Result is the outcome of identifying a file
+type Result struct {
+	FileType
+	Extension         string // Extension of the file name, lower case without the dot
+	ExtensionMismatch bool   // The extension does not match the content: a possible concealment indicator
+}
+
+// String summarises the result for display
+func (r *Result) String() string {
+	s := fmt.Sprintf("%s (%s, %s)", r.Name, r.Description, r.MIME)
+	if r.ExtensionMismatch {
+		s += fmt.Sprintf(" - extension .%s does not match content", r.Extension)
+	}
+	return s
+}
+
+// IdentifyFile identifies a file from its content and checks its extension
+func IdentifyFile(path string) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	ft, err := Identify(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{FileType: *ft, Extension: extension(path)}
+	result.ExtensionMismatch = !MatchesExtension(ft, path)
+	return result, nil
+}
+
+// Identify determines the type of the content in r from its signature,
+// falling back to a plain-text check
+func Identify(r io.ReaderAt, size int64) (*FileType, error) {
+	header := make([]byte, headerSize)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+	header = header[:n]
+	text := isText(header)
+
+	for _, ft := range signatureTypes {
+		if !matchesAny(header, ft.Signatures, text) {
+			continue
+		}
+		switch ft {
+		case typeZIP:
+			return refineZIP(r, size), nil
+		case typeOLE:
+			return refineOLE(r, size), nil
+		case typeISOBMFF:
+			return refineBMFF(header), nil
+		case typeRIFF:
+			return refineRIFF(header), nil
+		}
+		return ft, nil
+	}
+
+	if text {
+		return typeText, nil
+	}
+	return typeUnknown, nil
+}
+
+// MatchesExtension reports whether the extension of name is plausible for
+// the identified type. Files without an extension only match types that
+// are conventionally extensionless.
+func MatchesExtension(ft *FileType, name string) bool {
+	ext := extension(name)
+	if ft == typeUnknown {
+		return true
+	}
+	if ft == typeText {
+		// Text may carry almost any extension, but not one claiming a binary format
+		return ext == "" || !isBinaryExtension(ext)
+	}
+	if ext == "" {
+		return ft.Category == CategoryExecutable || ft.Category == CategorySystem
+	}
+	for _, e := range ft.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// ByName returns the file type with the given name
+func ByName(name string) (*FileType, bool) {
+	name = strings.ToUpper(name)
+	if ft, ok := containerTypes[name]; ok {
+		return ft, true
+	}
+	for _, ft := range signatureTypes {
+		if ft.Name == name {
+			return ft, true
+		}
+	}
+	switch name {
+	case typeText.Name:
+		return typeText, true
+	case typeUnknown.Name:
+		return typeUnknown, true
+	}
+	return nil, false
+}
+
+// extension returns the lower-case extension of name without the dot
+func extension(name string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+}
+
+// matchesAny reports whether header matches one of the signatures; text
+// tells whether the header is plain text
+func matchesAny(header []byte, signatures []Signature, text bool) bool {
+	for _, s := range signatures {
+		// Short printable signatures such as "BM" or "MZ" also begin
+		// ordinary words, so they are not trusted for text
+		if text && s.weak() {
+			continue
+		}
+		end := s.Offset + len(s.Magic)
+		if end <= len(header) && bytes.Equal(header[s.Offset:end], s.Magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinaryExtension reports whether ext is claimed by a binary format
+func isBinaryExtension(ext string) bool {
+	for _, ft := range signatureTypes {
+		if ft.Category == CategoryExecutable || ft.Category == CategorySystem {
+			continue // Their extensions are too generic to count
+		}
+		for _, e := range ft.Extensions {
+			if e == ext {
+				return true
+			}
+		}
+	}
+	for _, ft := range containerTypes {
+		for _, e := range ft.Extensions {
+			if e == ext {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isText reports whether data looks like text: UTF-16 with a byte order
+// mark, or UTF-8 or a single-byte encoding such as Latin-1 with no NUL bytes
+// and few control characters
+func isText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return true
+	}
+	if len(data) > textSampleSize {
+		data = data[:textSampleSize]
+	}
+
+	control := 0
+	for _, b := range data {
+		switch {
+		case b == 0:
+			return false
+		case b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != 0x1B:
+			control++
+		case b == 0x7F:
+			control++
+		}
+	}
+	return control*100 <= len(data)
+}
+
+// refineZIP distinguishes office documents, e-books and packages stored as ZIP
+func refineZIP(r io.ReaderAt, size int64) *FileType {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return typeZIP
+	}
+
+	names := make(map[string]bool)
+	prefixes := make(map[string]bool)
+	var mimetype *zip.File
+	for _, f := range zr.File {
+		names[f.Name] = true
+		if i := strings.Index(f.Name, "/"); i > 0 {
+			prefixes[f.Name[:i+1]] = true
+		}
+		if f.Name == "mimetype" {
+			mimetype = f
+		}
+	}
+
+	if mimetype != nil {
+		if rc, err := mimetype.Open(); err == nil {
+			data, _ := io.ReadAll(io.LimitReader(rc, 128))
+			rc.Close()
+			switch strings.TrimSpace(string(data)) {
+			case "application/vnd.oasis.opendocument.text":
+				return containerTypes["ODT"]
+			case "application/vnd.oasis.opendocument.spreadsheet":
+				return containerTypes["ODS"]
+			case "application/vnd.oasis.opendocument.presentation":
+				return containerTypes["ODP"]
+			case "application/epub+zip":
+				return containerTypes["EPUB"]
+			}
+		}
+	}
+
+	if names["[Content_Types].xml"] {
+		switch {
+		case prefixes["word/"]:
+			return containerTypes["DOCX"]
+		case prefixes["xl/"]:
+			return containerTypes["XLSX"]
+		case prefixes["ppt/"]:
+			return containerTypes["PPTX"]
+		}
+	}
+
+	if names["AndroidManifest.xml"] && names["classes.dex"] {
+		return containerTypes["APK"]
+	}
+	if names["META-INF/MANIFEST.MF"] {
+		return containerTypes["JAR"]
+	}
+
+	return typeZIP
+}
+
+// refineBMFF classifies ISO base media files by their ftyp brands
+func refineBMFF(header []byte) *FileType {
+	if len(header) < 12 {
+		return typeISOBMFF
+	}
+	boxSize := int(header[0])<<24 | int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+	if boxSize > len(header) || boxSize < 16 {
+		boxSize = 16
+	}
+	brands := []string{string(header[8:12])}
+	for i := 16; i+4 <= boxSize; i += 4 {
+		brands = append(brands, string(header[i:i+4]))
+	}
+
+	for _, brand := range brands {
+		switch brand {
+		case "heic", "heix", "heim", "heis", "hevc", "mif1", "msf1":
+			return containerTypes["HEIC"]
+		case "avif", "avis":
+			return containerTypes["AVIF"]
+		}
+	}
+	switch major := brands[0]; {
+	case major == "qt  ":
+		return containerTypes["MOV"]
+	case major == "M4A " || major == "M4B ":
+		return containerTypes["M4A"]
+	case strings.HasPrefix(major, "3gp") || strings.HasPrefix(major, "3g2"):
+		return containerTypes["3GP"]
+	}
+	return typeISOBMFF
+}
+
+// refineRIFF classifies RIFF containers by their form type
+func refineRIFF(header []byte) *FileType {
+	if len(header) < 12 {
+		return typeRIFF
+	}
+	switch string(header[8:12]) {
+	case "WAVE":
+		return containerTypes["WAV"]
+	case "AVI ":
+		return containerTypes["AVI"]
+	case "WEBP":
+		return containerTypes["WEBP"]
+	}
+	return typeRIFF
+}
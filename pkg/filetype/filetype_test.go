@@ -0,0 +1,75 @@
+package filetype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// bmpHeader returns the start of a 1x1 24-bit bitmap
+func bmpHeader() []byte {
+	data := make([]byte, 58)
+	copy(data, "BM")
+	binary.LittleEndian.PutUint32(data[2:], 58)
+	binary.LittleEndian.PutUint32(data[10:], 54)
+	binary.LittleEndian.PutUint32(data[14:], 40)
+	return data
+}
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"bitmap", bmpHeader(), "BMP"},
+		{"text starting with BM", []byte("BMW service record for the vehicle\nMileage: 42000\n"), "TEXT"},
+		{"text starting with MZ", []byte("MZ Holdings invoice 2024-113\n"), "TEXT"},
+		{"text starting with ID3", []byte("ID3 tags were removed from the files\n"), "TEXT"},
+		{"text starting with BZh", []byte("BZh is not a word\n"), "TEXT"},
+		{"Windows executable", append([]byte("MZ\x90\x00\x03\x00\x00\x00"), make([]byte, 64)...), "PE"},
+		{"MP3 with ID3 tag", []byte("ID3\x03\x00\x00\x00\x00\x00\x0a"), "MP3"},
+		{"PNG", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "PNG"},
+		{"JPEG", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10}, "JPEG"},
+		{"PDF", []byte("%PDF-1.7\n"), "PDF"},
+		{"plain text", []byte("Suspect arrived at 21:40.\n"), "TEXT"},
+		{"binary", []byte{0x01, 0x02, 0x00, 0x7F, 0x80}, "UNKNOWN"},
+		{"empty", nil, "UNKNOWN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft, err := Identify(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ft.Name != tt.want {
+				t.Errorf("Identify = %s, want %s", ft.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesExtension(t *testing.T) {
+	bmp, _ := ByName("BMP")
+	pe, _ := ByName("PE")
+	text, _ := ByName("TEXT")
+	tests := []struct {
+		ft   *FileType
+		name string
+		want bool
+	}{
+		{bmp, "photo.bmp", true},
+		{bmp, "photo.BMP", true},
+		{bmp, "photo.jpg", false},
+		{pe, "setup", true},
+		{pe, "holiday.jpg", false},
+		{text, "notes.txt", true},
+		{text, "notes", true},
+		{text, "notes.jpg", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesExtension(tt.ft, tt.name); got != tt.want {
+			t.Errorf("MatchesExtension(%s, %q) = %v, want %v", tt.ft.Name, tt.name, got, tt.want)
+		}
+	}
+}
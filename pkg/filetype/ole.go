@@ -0,0 +1,160 @@
+package filetype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Compound File Binary limits and markers
+const (
+	oleHeaderDIFATEntries = 109
+	oleEndOfChain         = 0xFFFFFFFE
+	oleMaxSector          = 0xFFFFFFFA
+	oleDirEntrySize       = 128
+	oleMaxDirSectors      = 256
+	oleMaxDIFATSectors    = 1024
+)
+
+// msiCLSID is the root storage class ID of Windows installer packages
+var msiCLSID = []byte{0x84, 0x10, 0x0C, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+// oleFile reads sectors of a Compound File Binary container
+type oleFile struct {
+	r          io.ReaderAt
+	size       int64
+	sectorSize int64
+	difat      []uint32 // Locations of the FAT sectors
+	fatCache   map[uint32][]byte
+}
+
+// refineOLE distinguishes Word, Excel, PowerPoint, Outlook and installer
+// files by the stream names in the compound file directory
+func refineOLE(r io.ReaderAt, size int64) *FileType {
+	names, rootCLSID := readOLEDirectory(r, size)
+
+	if bytes.Equal(rootCLSID, msiCLSID) {
+		return containerTypes["MSI"]
+	}
+	for _, name := range names {
+		switch {
+		case name == "WordDocument":
+			return containerTypes["DOC"]
+		case name == "Workbook" || name == "Book":
+			return containerTypes["XLS"]
+		case name == "PowerPoint Document":
+			return containerTypes["PPT"]
+		case strings.HasPrefix(name, "__substg1.0_") || name == "__properties_version1.0":
+			return containerTypes["MSG"]
+		}
+	}
+	return typeOLE
+}
+
+// readOLEDirectory returns the directory entry names and the root CLSID.
+// Damaged containers yield whatever entries could be read.
+func readOLEDirectory(r io.ReaderAt, size int64) ([]string, []byte) {
+	header := make([]byte, 512)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, nil
+	}
+	shift := binary.LittleEndian.Uint16(header[0x1E:])
+	if shift != 9 && shift != 12 {
+		return nil, nil
+	}
+
+	f := &oleFile{r: r, size: size, sectorSize: 1 << shift, fatCache: make(map[uint32][]byte)}
+	for i := 0; i < oleHeaderDIFATEntries; i++ {
+		sector := binary.LittleEndian.Uint32(header[0x4C+i*4:])
+		if sector <= oleMaxSector {
+			f.difat = append(f.difat, sector)
+		}
+	}
+	f.readDIFATChain(binary.LittleEndian.Uint32(header[0x44:]))
+
+	var names []string
+	var rootCLSID []byte
+	sector := binary.LittleEndian.Uint32(header[0x30:])
+	for count := 0; sector <= oleMaxSector && count < oleMaxDirSectors; count++ {
+		data := f.readSector(sector)
+		if data == nil {
+			break
+		}
+		for off := 0; off+oleDirEntrySize <= len(data); off += oleDirEntrySize {
+			entry := data[off : off+oleDirEntrySize]
+			name := decodeOLEName(entry)
+			if name == "" {
+				continue
+			}
+			if entry[0x42] == 5 { // Root storage
+				rootCLSID = append([]byte(nil), entry[0x50:0x60]...)
+			}
+			names = append(names, name)
+		}
+		sector = f.next(sector)
+	}
+	return names, rootCLSID
+}
+
+// readDIFATChain appends FAT sector locations held in DIFAT sectors
+func (f *oleFile) readDIFATChain(sector uint32) {
+	perSector := int(f.sectorSize/4) - 1
+	for count := 0; sector <= oleMaxSector && count < oleMaxDIFATSectors; count++ {
+		data := f.readSector(sector)
+		if data == nil {
+			return
+		}
+		for i := 0; i < perSector; i++ {
+			if s := binary.LittleEndian.Uint32(data[i*4:]); s <= oleMaxSector {
+				f.difat = append(f.difat, s)
+			}
+		}
+		sector = binary.LittleEndian.Uint32(data[perSector*4:])
+	}
+}
+
+// readSector reads a sector, returning nil if it lies outside the file
+func (f *oleFile) readSector(sector uint32) []byte {
+	offset := (int64(sector) + 1) * f.sectorSize
+	if offset+f.sectorSize > f.size {
+		return nil
+	}
+	data := make([]byte, f.sectorSize)
+	if _, err := f.r.ReadAt(data, offset); err != nil {
+		return nil
+	}
+	return data
+}
+
+// next follows the FAT chain from sector
+func (f *oleFile) next(sector uint32) uint32 {
+	perSector := uint32(f.sectorSize / 4)
+	index := sector / perSector
+	if int(index) >= len(f.difat) {
+		return oleEndOfChain
+	}
+	fat, ok := f.fatCache[index]
+	if !ok {
+		fat = f.readSector(f.difat[index])
+		f.fatCache[index] = fat
+	}
+	if fat == nil {
+		return oleEndOfChain
+	}
+	return binary.LittleEndian.Uint32(fat[(sector%perSector)*4:])
+}
+
+// decodeOLEName decodes the UTF-16 name of a directory entry
+func decodeOLEName(entry []byte) string {
+	length := int(binary.LittleEndian.Uint16(entry[0x40:]))
+	if length < 2 || length > 64 {
+		return ""
+	}
+	units := make([]uint16, 0, length/2-1)
+	for i := 0; i+1 < length-2; i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(entry[i:]))
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,243 @@
+package filetype
+
+// Category groups file types for triage
+type Category string
+
+const (
+	CategoryDocument   Category = "DOCUMENT"
+	CategoryImage      Category = "IMAGE"
+	CategoryAudio      Category = "AUDIO"
+	CategoryVideo      Category = "VIDEO"
+	CategoryArchive    Category = "ARCHIVE"
+	CategoryDatabase   Category = "DATABASE"
+	CategoryExecutable Category = "EXECUTABLE"
+	CategoryEmail      Category = "EMAIL"
+	CategorySystem     Category = "SYSTEM" // Logs, registry hives, captures and similar artefacts
+	CategoryText       Category = "TEXT"
+	CategoryUnknown    Category = "UNKNOWN"
+)
+
+// Signature is a byte pattern found at a fixed offset
+type Signature struct {
+	Offset int
+	Magic  []byte
+}
+
+// FileType describes a file format that can be identified
+type FileType struct {
+	Name        string // Short identifier, e.g. "JPEG"
+	Description string
+	MIME        string
+	Category    Category
+	Extensions  []string // Expected extensions without the dot, lower case
+	Signatures  []Signature
+}
+
+// weak reports whether a signature is short printable text, which plain
+// text files can begin with by chance
+func (s Signature) weak() bool {
+	if len(s.Magic) >= 4 {
+		return false
+	}
+	for _, b := range s.Magic {
+		if b < 0x20 || b > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+// sig is shorthand for a signature at the start of the file
+func sig(magic string) Signature {
+	return Signature{Magic: []byte(magic)}
+}
+
+// sigAt is shorthand for a signature at an offset
+func sigAt(offset int, magic string) Signature {
+	return Signature{Offset: offset, Magic: []byte(magic)}
+}
+
+// Types identified by inspecting container contents rather than a signature
+var (
+	typeZIP = &FileType{Name: "ZIP", Description: "ZIP archive", MIME: "application/zip",
+		Category: CategoryArchive, Extensions: []string{"zip"},
+		Signatures: []Signature{sig("PK\x03\x04"), sig("PK\x05\x06"), sig("PK\x07\x08")}}
+	typeOLE = &FileType{Name: "OLE2", Description: "Microsoft compound document", MIME: "application/x-ole-storage",
+		Category: CategoryDocument, Extensions: []string{"doc", "xls", "ppt", "msg", "msi", "db"},
+		Signatures: []Signature{sig("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")}}
+	typeISOBMFF = &FileType{Name: "MP4", Description: "MPEG-4 video", MIME: "video/mp4",
+		Category: CategoryVideo, Extensions: []string{"mp4", "m4v"},
+		Signatures: []Signature{sigAt(4, "ftyp")}}
+	typeRIFF = &FileType{Name: "RIFF", Description: "RIFF container", MIME: "application/octet-stream",
+		Category: CategoryUnknown, Extensions: []string{"riff"},
+		Signatures: []Signature{sig("RIFF")}}
+	typeText = &FileType{Name: "TEXT", Description: "Plain text", MIME: "text/plain",
+		Category: CategoryText, Extensions: []string{"txt", "text", "log", "csv", "tsv", "md", "json",
+			"xml", "html", "htm", "ini", "cfg", "conf", "yaml", "yml", "eml", "mbox", "vcf", "ics",
+			"sh", "bat", "ps1", "py", "js", "go", "c", "h", "java", "sql", "srt", "rtf"}}
+	typeUnknown = &FileType{Name: "UNKNOWN", Description: "Unidentified binary data",
+		MIME: "application/octet-stream", Category: CategoryUnknown}
+)
+
+// containerTypes are the refinements of ZIP, OLE2, ISO BMFF and RIFF containers
+var containerTypes = map[string]*FileType{
+	"DOCX": {Name: "DOCX", Description: "Microsoft Word document", Category: CategoryDocument,
+		MIME:       "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		Extensions: []string{"docx", "docm", "dotx"}},
+	"XLSX": {Name: "XLSX", Description: "Microsoft Excel workbook", Category: CategoryDocument,
+		MIME:       "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extensions: []string{"xlsx", "xlsm", "xltx"}},
+	"PPTX": {Name: "PPTX", Description: "Microsoft PowerPoint presentation", Category: CategoryDocument,
+		MIME:       "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		Extensions: []string{"pptx", "pptm", "potx"}},
+	"ODT": {Name: "ODT", Description: "OpenDocument text", Category: CategoryDocument,
+		MIME: "application/vnd.oasis.opendocument.text", Extensions: []string{"odt"}},
+	"ODS": {Name: "ODS", Description: "OpenDocument spreadsheet", Category: CategoryDocument,
+		MIME: "application/vnd.oasis.opendocument.spreadsheet", Extensions: []string{"ods"}},
+	"ODP": {Name: "ODP", Description: "OpenDocument presentation", Category: CategoryDocument,
+		MIME: "application/vnd.oasis.opendocument.presentation", Extensions: []string{"odp"}},
+	"EPUB": {Name: "EPUB", Description: "EPUB e-book", Category: CategoryDocument,
+		MIME: "application/epub+zip", Extensions: []string{"epub"}},
+	"JAR": {Name: "JAR", Description: "Java archive", Category: CategoryExecutable,
+		MIME: "application/java-archive", Extensions: []string{"jar", "war", "ear"}},
+	"APK": {Name: "APK", Description: "Android application package", Category: CategoryExecutable,
+		MIME: "application/vnd.android.package-archive", Extensions: []string{"apk"}},
+	"DOC": {Name: "DOC", Description: "Microsoft Word 97-2003 document", Category: CategoryDocument,
+		MIME: "application/msword", Extensions: []string{"doc", "dot"}},
+	"XLS": {Name: "XLS", Description: "Microsoft Excel 97-2003 workbook", Category: CategoryDocument,
+		MIME: "application/vnd.ms-excel", Extensions: []string{"xls", "xlt"}},
+	"PPT": {Name: "PPT", Description: "Microsoft PowerPoint 97-2003 presentation", Category: CategoryDocument,
+		MIME: "application/vnd.ms-powerpoint", Extensions: []string{"ppt", "pps", "pot"}},
+	"MSG": {Name: "MSG", Description: "Outlook message", Category: CategoryEmail,
+		MIME: "application/vnd.ms-outlook", Extensions: []string{"msg"}},
+	"MSI": {Name: "MSI", Description: "Windows installer package", Category: CategoryExecutable,
+		MIME: "application/x-msi", Extensions: []string{"msi"}},
+	"HEIC": {Name: "HEIC", Description: "HEIF image", Category: CategoryImage,
+		MIME: "image/heic", Extensions: []string{"heic", "heif"}},
+	"AVIF": {Name: "AVIF", Description: "AVIF image", Category: CategoryImage,
+		MIME: "image/avif", Extensions: []string{"avif"}},
+	"MOV": {Name: "MOV", Description: "QuickTime movie", Category: CategoryVideo,
+		MIME: "video/quicktime", Extensions: []string{"mov", "qt"}},
+	"M4A": {Name: "M4A", Description: "MPEG-4 audio", Category: CategoryAudio,
+		MIME: "audio/mp4", Extensions: []string{"m4a", "m4b", "m4r"}},
+	"3GP": {Name: "3GP", Description: "3GPP multimedia", Category: CategoryVideo,
+		MIME: "video/3gpp", Extensions: []string{"3gp", "3g2"}},
+	"WAV": {Name: "WAV", Description: "WAVE audio", Category: CategoryAudio,
+		MIME: "audio/wav", Extensions: []string{"wav"}},
+	"AVI": {Name: "AVI", Description: "AVI video", Category: CategoryVideo,
+		MIME: "video/x-msvideo", Extensions: []string{"avi"}},
+	"WEBP": {Name: "WEBP", Description: "WebP image", Category: CategoryImage,
+		MIME: "image/webp", Extensions: []string{"webp"}},
+}
+
+// signatureTypes is the magic number database, most specific signatures first
+var signatureTypes = []*FileType{
+	// Documents
+	{Name: "PDF", Description: "PDF document", MIME: "application/pdf", Category: CategoryDocument,
+		Extensions: []string{"pdf"}, Signatures: []Signature{sig("%PDF-")}},
+	{Name: "RTF", Description: "Rich Text Format document", MIME: "application/rtf", Category: CategoryDocument,
+		Extensions: []string{"rtf", "doc"}, Signatures: []Signature{sig("{\\rtf")}},
+	{Name: "PS", Description: "PostScript document", MIME: "application/postscript", Category: CategoryDocument,
+		Extensions: []string{"ps", "eps", "ai"}, Signatures: []Signature{sig("%!PS")}},
+	typeOLE,
+
+	// Images
+	{Name: "JPEG", Description: "JPEG image", MIME: "image/jpeg", Category: CategoryImage,
+		Extensions: []string{"jpg", "jpeg", "jpe", "jfif"}, Signatures: []Signature{sig("\xFF\xD8\xFF")}},
+	{Name: "PNG", Description: "PNG image", MIME: "image/png", Category: CategoryImage,
+		Extensions: []string{"png"}, Signatures: []Signature{sig("\x89PNG\r\n\x1A\n")}},
+	{Name: "GIF", Description: "GIF image", MIME: "image/gif", Category: CategoryImage,
+		Extensions: []string{"gif"}, Signatures: []Signature{sig("GIF87a"), sig("GIF89a")}},
+	{Name: "TIFF", Description: "TIFF image", MIME: "image/tiff", Category: CategoryImage,
+		Extensions: []string{"tif", "tiff", "dng", "nef", "cr2", "arw"},
+		Signatures: []Signature{sig("II*\x00"), sig("MM\x00*")}},
+	{Name: "PSD", Description: "Photoshop image", MIME: "image/vnd.adobe.photoshop", Category: CategoryImage,
+		Extensions: []string{"psd"}, Signatures: []Signature{sig("8BPS")}},
+	{Name: "ICO", Description: "Windows icon", MIME: "image/vnd.microsoft.icon", Category: CategoryImage,
+		Extensions: []string{"ico"}, Signatures: []Signature{sig("\x00\x00\x01\x00")}},
+	{Name: "BMP", Description: "Bitmap image", MIME: "image/bmp", Category: CategoryImage,
+		Extensions: []string{"bmp", "dib"}, Signatures: []Signature{sig("BM")}},
+
+	// Audio and video
+	{Name: "MP3", Description: "MP3 audio", MIME: "audio/mpeg", Category: CategoryAudio,
+		Extensions: []string{"mp3"}, Signatures: []Signature{sig("ID3"), sig("\xFF\xFB"), sig("\xFF\xF3"), sig("\xFF\xF2")}},
+	{Name: "FLAC", Description: "FLAC audio", MIME: "audio/flac", Category: CategoryAudio,
+		Extensions: []string{"flac"}, Signatures: []Signature{sig("fLaC")}},
+	{Name: "OGG", Description: "Ogg media", MIME: "audio/ogg", Category: CategoryAudio,
+		Extensions: []string{"ogg", "oga", "ogv", "opus"}, Signatures: []Signature{sig("OggS")}},
+	{Name: "AMR", Description: "AMR audio", MIME: "audio/amr", Category: CategoryAudio,
+		Extensions: []string{"amr"}, Signatures: []Signature{sig("#!AMR")}},
+	{Name: "MIDI", Description: "MIDI audio", MIME: "audio/midi", Category: CategoryAudio,
+		Extensions: []string{"mid", "midi"}, Signatures: []Signature{sig("MThd")}},
+	{Name: "MKV", Description: "Matroska/WebM video", MIME: "video/x-matroska", Category: CategoryVideo,
+		Extensions: []string{"mkv", "webm", "mka"}, Signatures: []Signature{sig("\x1A\x45\xDF\xA3")}},
+	{Name: "FLV", Description: "Flash video", MIME: "video/x-flv", Category: CategoryVideo,
+		Extensions: []string{"flv"}, Signatures: []Signature{sig("FLV\x01")}},
+	{Name: "ASF", Description: "Windows Media file", MIME: "video/x-ms-asf", Category: CategoryVideo,
+		Extensions: []string{"wmv", "wma", "asf"},
+		Signatures: []Signature{sig("\x30\x26\xB2\x75\x8E\x66\xCF\x11")}},
+	{Name: "MPEG", Description: "MPEG program stream", MIME: "video/mpeg", Category: CategoryVideo,
+		Extensions: []string{"mpg", "mpeg", "vob"}, Signatures: []Signature{sig("\x00\x00\x01\xBA")}},
+	typeISOBMFF,
+	typeRIFF,
+
+	// Archives
+	typeZIP,
+	{Name: "RAR", Description: "RAR archive", MIME: "application/vnd.rar", Category: CategoryArchive,
+		Extensions: []string{"rar"}, Signatures: []Signature{sig("Rar!\x1A\x07")}},
+	{Name: "7Z", Description: "7-Zip archive", MIME: "application/x-7z-compressed", Category: CategoryArchive,
+		Extensions: []string{"7z"}, Signatures: []Signature{sig("7z\xBC\xAF\x27\x1C")}},
+	{Name: "GZIP", Description: "gzip compressed data", MIME: "application/gzip", Category: CategoryArchive,
+		Extensions: []string{"gz", "tgz"}, Signatures: []Signature{sig("\x1F\x8B")}},
+	{Name: "BZIP2", Description: "bzip2 compressed data", MIME: "application/x-bzip2", Category: CategoryArchive,
+		Extensions: []string{"bz2", "tbz2"}, Signatures: []Signature{sig("BZh")}},
+	{Name: "XZ", Description: "xz compressed data", MIME: "application/x-xz", Category: CategoryArchive,
+		Extensions: []string{"xz", "txz"}, Signatures: []Signature{sig("\xFD7zXZ\x00")}},
+	{Name: "ZSTD", Description: "Zstandard compressed data", MIME: "application/zstd", Category: CategoryArchive,
+		Extensions: []string{"zst"}, Signatures: []Signature{sig("\x28\xB5\x2F\xFD")}},
+	{Name: "CAB", Description: "Microsoft cabinet", MIME: "application/vnd.ms-cab-compressed", Category: CategoryArchive,
+		Extensions: []string{"cab"}, Signatures: []Signature{sig("MSCF")}},
+	{Name: "TAR", Description: "tar archive", MIME: "application/x-tar", Category: CategoryArchive,
+		Extensions: []string{"tar"}, Signatures: []Signature{sigAt(257, "ustar")}},
+	{Name: "ISO", Description: "ISO 9660 disc image", MIME: "application/x-iso9660-image", Category: CategoryArchive,
+		Extensions: []string{"iso"}, Signatures: []Signature{sigAt(32769, "CD001")}},
+
+	// Databases
+	{Name: "SQLITE", Description: "SQLite database", MIME: "application/vnd.sqlite3", Category: CategoryDatabase,
+		Extensions: []string{"sqlite", "sqlite3", "db", "db3", "sqlitedb"},
+		Signatures: []Signature{sig("SQLite format 3\x00")}},
+	{Name: "ACCESS", Description: "Microsoft Access database", MIME: "application/x-msaccess", Category: CategoryDatabase,
+		Extensions: []string{"mdb", "accdb"},
+		Signatures: []Signature{sigAt(4, "Standard Jet DB"), sigAt(4, "Standard ACE DB")}},
+
+	// Email
+	{Name: "PST", Description: "Outlook data file", MIME: "application/vnd.ms-outlook-pst", Category: CategoryEmail,
+		Extensions: []string{"pst", "ost"}, Signatures: []Signature{sig("!BDN")}},
+
+	// Executables
+	{Name: "ELF", Description: "ELF executable", MIME: "application/x-elf", Category: CategoryExecutable,
+		Extensions: []string{"", "so", "o", "elf", "bin"}, Signatures: []Signature{sig("\x7FELF")}},
+	{Name: "MACHO", Description: "Mach-O executable", MIME: "application/x-mach-binary", Category: CategoryExecutable,
+		Extensions: []string{"", "dylib", "bundle"},
+		Signatures: []Signature{sig("\xFE\xED\xFA\xCE"), sig("\xFE\xED\xFA\xCF"), sig("\xCE\xFA\xED\xFE"), sig("\xCF\xFA\xED\xFE")}},
+	{Name: "CLASS", Description: "Java class file", MIME: "application/java-vm", Category: CategoryExecutable,
+		Extensions: []string{"class"}, Signatures: []Signature{sig("\xCA\xFE\xBA\xBE")}},
+	{Name: "DEX", Description: "Android Dalvik executable", MIME: "application/vnd.android.dex", Category: CategoryExecutable,
+		Extensions: []string{"dex"}, Signatures: []Signature{sig("dex\n")}},
+	{Name: "PE", Description: "Windows executable", MIME: "application/vnd.microsoft.portable-executable",
+		Category: CategoryExecutable, Extensions: []string{"exe", "dll", "sys", "scr", "cpl", "ocx", "drv", "efi", "com"},
+		Signatures: []Signature{sig("MZ")}},
+
+	// System artefacts
+	{Name: "EVTX", Description: "Windows event log", MIME: "application/x-ms-evtx", Category: CategorySystem,
+		Extensions: []string{"evtx"}, Signatures: []Signature{sig("ElfFile\x00")}},
+	{Name: "REGF", Description: "Windows registry hive", MIME: "application/x-ms-registry", Category: CategorySystem,
+		Extensions: []string{"", "dat", "hve", "sav"}, Signatures: []Signature{sig("regf")}},
+	{Name: "LNK", Description: "Windows shortcut", MIME: "application/x-ms-shortcut", Category: CategorySystem,
+		Extensions: []string{"lnk"}, Signatures: []Signature{sig("\x4C\x00\x00\x00\x01\x14\x02\x00")}},
+	{Name: "PCAP", Description: "Packet capture", MIME: "application/vnd.tcpdump.pcap", Category: CategorySystem,
+		Extensions: []string{"pcap", "cap", "dmp"},
+		Signatures: []Signature{sig("\xD4\xC3\xB2\xA1"), sig("\xA1\xB2\xC3\xD4"), sig("\x4D\x3C\xB2\xA1"), sig("\xA1\xB2\x3C\x4D")}},
+	{Name: "PCAPNG", Description: "Packet capture (next generation)", MIME: "application/x-pcapng", Category: CategorySystem,
+		Extensions: []string{"pcapng", "ntar"}, Signatures: []Signature{sig("\x0A\x0D\x0D\x0A")}},
+}
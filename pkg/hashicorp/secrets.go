@@ -0,0 +1,126 @@
+package hashicorp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MasterKeyEnv is the environment variable holding the base64-encoded master key
+const MasterKeyEnv = "EVIDENCE_MASTER_KEY"
+
+// masterKeySize is the AES-256 key size in bytes
+const masterKeySize = 32
+
+// secretFormatPrefix identifies the encryption scheme of a stored secret
+const secretFormatPrefix = "aes256gcm:"
+
+// SecretStore encrypts secrets with AES-256-GCM before they are written to a
+// CredentialManager, so the credential file never holds plaintext
+type SecretStore struct {
+	credentials *CredentialManager
+	engine      string
+	aead        cipher.AEAD
+}
+
+// NewSecretStore creates a secret store in the given credential engine
+func NewSecretStore(credentials *CredentialManager, engine string, masterKey []byte) (*SecretStore, error) {
+	if credentials == nil {
+		return nil, fmt.Errorf("credential manager is required")
+	}
+	if len(masterKey) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(masterKey))
+	}
+
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &SecretStore{
+		credentials: credentials,
+		engine:      engine,
+		aead:        aead,
+	}, nil
+}
+
+// StoreSecret encrypts and stores a secret under id. The id is bound to the
+// ciphertext, so a stored value cannot be moved to another id.
+func (s *SecretStore) StoreSecret(id, value string) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(value), []byte(id))
+	encoded := secretFormatPrefix + base64.StdEncoding.EncodeToString(sealed)
+
+	return s.credentials.StoreCredential(s.engine, id, encoded)
+}
+
+// RetrieveSecret decrypts the secret stored under id
+func (s *SecretStore) RetrieveSecret(id string) (string, error) {
+	encoded, err := s.credentials.GetCredential(s.engine, id)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(encoded, secretFormatPrefix) {
+		return "", fmt.Errorf("secret '%s' is not encrypted with a supported scheme", id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encoded, secretFormatPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret '%s': %w", id, err)
+	}
+	if len(sealed) < s.aead.NonceSize() {
+		return "", fmt.Errorf("secret '%s' is truncated", id)
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret '%s': wrong master key or tampered value", id)
+	}
+
+	return string(plaintext), nil
+}
+
+// DeleteSecret removes the secret stored under id
+func (s *SecretStore) DeleteSecret(id string) error {
+	return s.credentials.DeleteCredential(s.engine, id)
+}
+
+// GenerateMasterKey returns a new random master key, base64 encoded
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate master key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// MasterKeyFromEnvironment reads the master key from EVIDENCE_MASTER_KEY
+func MasterKeyFromEnvironment() ([]byte, error) {
+	encoded := os.Getenv(MasterKeyEnv)
+	if encoded == "" {
+		return nil, fmt.Errorf("%s is not set", MasterKeyEnv)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", MasterKeyEnv, err)
+	}
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("%s must decode to %d bytes, got %d", MasterKeyEnv, masterKeySize, len(key))
+	}
+
+	return key, nil
+}
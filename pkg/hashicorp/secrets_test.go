@@ -0,0 +1,173 @@
+package hashicorp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestStore creates a secret store over a credential file in a temporary directory
+func newTestStore(t *testing.T, path string, key []byte) (*SecretStore, *CredentialManager) {
+	t.Helper()
+	credentials, err := NewCredentialManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewSecretStore(credentials, "evidence-secrets", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, credentials
+}
+
+func TestSecretStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	key := bytes.Repeat([]byte{7}, masterKeySize)
+	store, _ := newTestStore(t, path, key)
+
+	for _, value := range []string{"hunter2", "contraseña ñandú", ""} {
+		if err := store.StoreSecret("SEC-1", value); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != "" && bytes.Contains(data, []byte(value)) {
+			t.Errorf("credential file holds %q in plaintext", value)
+		}
+
+		// A new store over the same file reads the value back
+		reopened, _ := newTestStore(t, path, key)
+		if got, err := reopened.RetrieveSecret("SEC-1"); err != nil || got != value {
+			t.Errorf("RetrieveSecret = %q, %v, want %q", got, err, value)
+		}
+	}
+
+	if err := store.DeleteSecret("SEC-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RetrieveSecret("SEC-1"); err == nil {
+		t.Error("deleted secret still retrievable")
+	}
+	if err := store.DeleteSecret("SEC-1"); err == nil {
+		t.Error("deleting a missing secret succeeded")
+	}
+}
+
+func TestSecretStoreRejects(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, masterKeySize)
+	tests := []struct {
+		name    string
+		tamper  func(credentials *CredentialManager)
+		readKey []byte // Key the value is read back with, if not the one it was stored with
+		id      string
+		wantErr string
+	}{
+		{"wrong master key", func(*CredentialManager) {}, bytes.Repeat([]byte{8}, masterKeySize), "SEC-1", "wrong master key"},
+		{"tampered ciphertext", func(credentials *CredentialManager) {
+			encoded, _ := credentials.GetCredential("evidence-secrets", "SEC-1")
+			sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(encoded, secretFormatPrefix))
+			sealed[len(sealed)-1] ^= 1
+			credentials.StoreCredential("evidence-secrets", "SEC-1", secretFormatPrefix+base64.StdEncoding.EncodeToString(sealed))
+		}, nil, "SEC-1", "tampered"},
+		{"value moved to another id", func(credentials *CredentialManager) {
+			encoded, _ := credentials.GetCredential("evidence-secrets", "SEC-1")
+			credentials.StoreCredential("evidence-secrets", "SEC-2", encoded)
+		}, nil, "SEC-2", "tampered"},
+		{"plaintext value", func(credentials *CredentialManager) {
+			credentials.StoreCredential("evidence-secrets", "SEC-1", "hunter2")
+		}, nil, "SEC-1", "supported scheme"},
+		{"truncated value", func(credentials *CredentialManager) {
+			credentials.StoreCredential("evidence-secrets", "SEC-1", secretFormatPrefix+"AAAA")
+		}, nil, "SEC-1", "truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "credentials.json")
+			store, credentials := newTestStore(t, path, key)
+			if err := store.StoreSecret("SEC-1", "hunter2"); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(credentials)
+
+			readKey := key
+			if tt.readKey != nil {
+				readKey = tt.readKey
+			}
+			reader, _ := newTestStore(t, path, readKey)
+			_, err := reader.RetrieveSecret(tt.id)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSecretStoreKeyLength(t *testing.T) {
+	credentials, err := NewCredentialManager(filepath.Join(t.TempDir(), "credentials.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		key     []byte
+		wantErr bool
+	}{
+		{"AES-256", make([]byte, 32), false},
+		{"AES-128", make([]byte, 16), true},
+		{"too long", make([]byte, 33), true},
+		{"empty", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSecretStore(credentials, "evidence-secrets", tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := NewSecretStore(nil, "evidence-secrets", make([]byte, 32)); err == nil {
+		t.Error("created a secret store without a credential manager")
+	}
+}
+
+func TestMasterKeys(t *testing.T) {
+	first, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("two generated master keys are equal")
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"generated key", first, false},
+		{"surrounding whitespace", " " + second + "\n", false},
+		{"unset", "", true},
+		{"not base64", "not a key!", true},
+		{"short key", base64.StdEncoding.EncodeToString(make([]byte, 16)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(MasterKeyEnv, tt.value)
+			key, err := MasterKeyFromEnvironment()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(key) != masterKeySize {
+				t.Errorf("key is %d bytes, want %d", len(key), masterKeySize)
+			}
+		})
+	}
+}
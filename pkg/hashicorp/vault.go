@@ -85,6 +85,24 @@ func (c *CredentialManager) StoreCredential(engineName, key, value string) error
 	return c.saveCredentials()
 }
 
+// DeleteCredential removes a credential
+func (c *CredentialManager) DeleteCredential(engineName, key string) error {
+	if !c.initialized {
+		return fmt.Errorf("credential manager not initialized")
+	}
+
+	engine, ok := c.credentials[engineName]
+	if !ok {
+		return fmt.Errorf("engine '%s' not found", engineName)
+	}
+	if _, ok := engine[key]; !ok {
+		return fmt.Errorf("key '%s' not found in engine '%s'", key, engineName)
+	}
+
+	delete(engine, key)
+	return c.saveCredentials()
+}
+
 // GetAPIKey is a convenience method to retrieve API keys for speech services
 func (c *CredentialManager) GetAPIKey(service string) (string, error) {
 	return c.GetCredential("speech-services", service+"-api-key")
@@ -0,0 +1,562 @@
+package hashset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Status is the classification of a file against the loaded hash sets
+type Status string
+
+const (
+	StatusUnknown   Status = "UNKNOWN"
+	StatusKnownGood Status = "KNOWN_GOOD"
+	StatusKnownBad  Status = "KNOWN_BAD"
+)
+
+// ParseStatus parses a hash set kind such as "good", "bad" or "KNOWN_BAD"
+func ParseStatus(value string) (Status, error) {
+	switch strings.TrimPrefix(strings.ReplaceAll(strings.ToUpper(value), "-", "_"), "KNOWN_") {
+	case "GOOD":
+		return StatusKnownGood, nil
+	case "BAD":
+		return StatusKnownBad, nil
+	}
+	return "", fmt.Errorf("unknown hash set kind %q (expected good or bad)", value)
+}
+
+// Algorithm identifies a hash algorithm held in the index
+type Algorithm string
+
+const (
+	MD5    Algorithm = "MD5"
+	SHA1   Algorithm = "SHA-1"
+	SHA256 Algorithm = "SHA-256"
+)
+
+// algorithms lists the indexed algorithms with their digest size and index file
+var algorithms = []struct {
+	alg  Algorithm
+	size int
+	file string
+}{
+	{MD5, 16, "md5.idx"},
+	{SHA1, 20, "sha1.idx"},
+	{SHA256, 32, "sha256.idx"},
+}
+
+const (
+	setsFile = "sets.json"
+	idSize   = 2       // Each record is a digest followed by a big-endian set ID
+	maxBatch = 1 << 26 // Bytes of records buffered per algorithm before merging
+)
+
+// Set describes an imported hash set
+type Set struct {
+	ID         uint16
+	Name       string
+	Kind       Status
+	Source     string // File the set was imported from
+	Hashes     map[Algorithm]int
+	Rejected   int // Lines without a recognisable hash
+	ImportedAt time.Time
+	Complete   bool // False if the import was interrupted; such sets are ignored
+}
+
+// Match is the result of looking a file up in the index
+type Match struct {
+	Status    Status
+	Algorithm Algorithm // Algorithm that matched
+	Hash      string
+	Sets      []string // Names of the matching sets
+}
+
+// Index is a collection of hash sets stored as sorted record files, one per
+// algorithm, which are searched on disk rather than loaded into memory
+type Index struct {
+	dir   string
+	sets  []Set
+	files map[Algorithm]*os.File
+}
+
+// Open opens the index in a directory, creating it if needed
+func Open(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create hash set directory: %w", err)
+	}
+
+	x := &Index{dir: dir, files: make(map[Algorithm]*os.File)}
+	data, err := os.ReadFile(filepath.Join(dir, setsFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read hash sets: %w", err)
+	default:
+		if err := json.Unmarshal(data, &x.sets); err != nil {
+			return nil, fmt.Errorf("failed to parse hash sets: %w", err)
+		}
+	}
+
+	for _, a := range algorithms {
+		if err := x.reopen(a.alg, a.file); err != nil {
+			x.Close()
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// Close closes the index files
+func (x *Index) Close() error {
+	var firstErr error
+	for alg, f := range x.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(x.files, alg)
+	}
+	return firstErr
+}
+
+// Sets returns the imported hash sets
+func (x *Index) Sets() []Set {
+	return append([]Set(nil), x.sets...)
+}
+
+// Import adds the hashes in a file to the index as a new set. NSRL RDS-style
+// CSV files are read by their SHA-1, MD5 and SHA-256 columns; other files may
+// be CSV with such a header, or lists with one hash per line in the first
+// column (as written by md5sum and similar tools).
+func (x *Index) Import(path, name string, kind Status) (*Set, error) {
+	if kind != StatusKnownGood && kind != StatusKnownBad {
+		return nil, fmt.Errorf("a hash set must be known-good or known-bad")
+	}
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if len(x.sets) >= 1<<16 {
+		return nil, fmt.Errorf("the index already holds the maximum number of hash sets")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hash set: %w", err)
+	}
+	defer f.Close()
+
+	// Record the set before merging so its ID is never reused, even if the
+	// import is interrupted
+	set := Set{
+		ID:         uint16(len(x.sets)),
+		Name:       name,
+		Kind:       kind,
+		Source:     path,
+		Hashes:     make(map[Algorithm]int),
+		ImportedAt: time.Now(),
+	}
+	x.sets = append(x.sets, set)
+	if err := x.saveSets(); err != nil {
+		return nil, err
+	}
+
+	batches := make(map[Algorithm][]byte)
+	flush := func(alg Algorithm) error {
+		err := x.merge(alg, batches[alg])
+		batches[alg] = batches[alg][:0]
+		return err
+	}
+
+	var id [idSize]byte
+	binary.BigEndian.PutUint16(id[:], set.ID)
+	err = readHashes(f, func(alg Algorithm, digest []byte) {
+		batches[alg] = append(append(batches[alg], digest...), id[:]...)
+		set.Hashes[alg]++
+	}, func() {
+		set.Rejected++
+	}, func(alg Algorithm) error {
+		if len(batches[alg]) < maxBatch {
+			return nil
+		}
+		return flush(alg)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	for _, a := range algorithms {
+		if len(batches[a.alg]) > 0 {
+			if err := flush(a.alg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	set.Complete = true
+	x.sets[set.ID] = set
+	if err := x.saveSets(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Lookup classifies a file by its hashes; empty hashes are not looked up. A
+// file in any known-bad set is known-bad even if a known-good set lists it.
+func (x *Index) Lookup(md5, sha1, sha256 string) (Match, error) {
+	var good *Match
+	for _, q := range []struct {
+		alg  Algorithm
+		hash string
+	}{{SHA256, sha256}, {SHA1, sha1}, {MD5, md5}} {
+		if q.hash == "" {
+			continue
+		}
+		ids, err := x.find(q.alg, q.hash)
+		if err != nil {
+			return Match{}, err
+		}
+
+		var bad, known []string
+		for _, id := range ids {
+			if int(id) >= len(x.sets) || !x.sets[id].Complete {
+				continue
+			}
+			set := x.sets[id]
+			if set.Kind == StatusKnownBad {
+				bad = appendName(bad, set.Name)
+			} else {
+				known = appendName(known, set.Name)
+			}
+		}
+
+		hash := strings.ToLower(q.hash)
+		if len(bad) > 0 {
+			return Match{Status: StatusKnownBad, Algorithm: q.alg, Hash: hash, Sets: bad}, nil
+		}
+		if len(known) > 0 && good == nil {
+			good = &Match{Status: StatusKnownGood, Algorithm: q.alg, Hash: hash, Sets: known}
+		}
+	}
+
+	if good != nil {
+		return *good, nil
+	}
+	return Match{Status: StatusUnknown}, nil
+}
+
+// find returns the IDs of the sets holding a digest
+func (x *Index) find(alg Algorithm, hash string) ([]uint16, error) {
+	digest, err := hex.DecodeString(strings.TrimSpace(hash))
+	if err != nil || len(digest) != digestSize(alg) {
+		return nil, fmt.Errorf("invalid %s hash %q", alg, hash)
+	}
+
+	f := x.files[alg]
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s index: %w", alg, err)
+	}
+	size := len(digest) + idSize
+	count := int(info.Size() / int64(size))
+
+	record := make([]byte, size)
+	var readErr error
+	read := func(i int) []byte {
+		if _, err := f.ReadAt(record, int64(i)*int64(size)); err != nil && readErr == nil {
+			readErr = err
+		}
+		return record
+	}
+
+	var ids []uint16
+	first := sort.Search(count, func(i int) bool {
+		return bytes.Compare(read(i)[:len(digest)], digest) >= 0
+	})
+	for i := first; i < count && readErr == nil; i++ {
+		r := read(i)
+		if !bytes.Equal(r[:len(digest)], digest) {
+			break
+		}
+		ids = append(ids, binary.BigEndian.Uint16(r[len(digest):]))
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read %s index: %w", alg, readErr)
+	}
+	return ids, nil
+}
+
+// merge sorts a batch of records and merges it into the index file for an
+// algorithm, dropping duplicates
+func (x *Index) merge(alg Algorithm, batch []byte) error {
+	size := digestSize(alg) + idSize
+	sort.Sort(records{data: batch, size: size, tmp: make([]byte, size)})
+
+	name := indexFile(alg)
+	tmp, err := os.CreateTemp(x.dir, name+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s index: %w", alg, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	existing := bufio.NewReader(io.NewSectionReader(x.files[alg], 0, 1<<62))
+	out := bufio.NewWriter(tmp)
+
+	var last []byte
+	write := func(r []byte) error {
+		if last != nil && bytes.Equal(last, r) {
+			return nil
+		}
+		last = append(last[:0], r...)
+		_, err := out.Write(r)
+		return err
+	}
+
+	current := make([]byte, size)
+	haveCurrent := false
+	next := func() error {
+		_, err := io.ReadFull(existing, current)
+		if err == io.EOF {
+			haveCurrent = false
+			return nil
+		}
+		haveCurrent = err == nil
+		return err
+	}
+	if err := next(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to read %s index: %w", alg, err)
+	}
+
+	for off := 0; off < len(batch); off += size {
+		r := batch[off : off+size]
+		for haveCurrent && bytes.Compare(current, r) < 0 {
+			if err := write(current); err != nil {
+				tmp.Close()
+				return fmt.Errorf("failed to write %s index: %w", alg, err)
+			}
+			if err := next(); err != nil {
+				tmp.Close()
+				return fmt.Errorf("failed to read %s index: %w", alg, err)
+			}
+		}
+		if err := write(r); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write %s index: %w", alg, err)
+		}
+	}
+	for haveCurrent {
+		if err := write(current); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write %s index: %w", alg, err)
+		}
+		if err := next(); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to read %s index: %w", alg, err)
+		}
+	}
+
+	if err := out.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s index: %w", alg, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s index: %w", alg, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s index: %w", alg, err)
+	}
+
+	x.files[alg].Close()
+	if err := os.Rename(tmp.Name(), filepath.Join(x.dir, name)); err != nil {
+		return fmt.Errorf("failed to replace %s index: %w", alg, err)
+	}
+	return x.reopen(alg, name)
+}
+
+// reopen opens the index file of an algorithm, creating it if needed
+func (x *Index) reopen(alg Algorithm, name string) error {
+	f, err := os.OpenFile(filepath.Join(x.dir, name), os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s index: %w", alg, err)
+	}
+	x.files[alg] = f
+	return nil
+}
+
+// saveSets writes the set descriptions
+func (x *Index) saveSets() error {
+	data, err := json.MarshalIndent(x.sets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode hash sets: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(x.dir, setsFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write hash sets: %w", err)
+	}
+	return nil
+}
+
+// readHashes reads the digests in a hash set file. Each digest is passed to
+// add, followed by a call to check that may merge the buffered records.
+func readHashes(r io.Reader, add func(Algorithm, []byte), reject func(), check func(Algorithm) error) error {
+	cr := csv.NewReader(bufio.NewReaderSize(r, 1<<20))
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	var columns map[int]Algorithm // Set when the file has a header row
+	first := true
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if first {
+			first = false
+			if columns = headerColumns(record); columns != nil {
+				continue
+			}
+		}
+
+		found := false
+		for i, field := range record {
+			var alg Algorithm
+			if columns != nil {
+				var ok bool
+				if alg, ok = columns[i]; !ok {
+					continue
+				}
+			}
+			if fields := strings.Fields(field); len(fields) > 0 {
+				field = fields[0]
+			}
+			digest, ok := parseDigest(field, alg)
+			if !ok {
+				continue
+			}
+			if alg == "" {
+				alg = algorithmForSize(len(digest))
+			}
+			add(alg, digest)
+			found = true
+			if err := check(alg); err != nil {
+				return err
+			}
+		}
+		if !found {
+			reject()
+		}
+	}
+}
+
+// headerColumns maps the hash columns of a header row, or returns nil if the
+// row is not a header
+func headerColumns(record []string) map[int]Algorithm {
+	columns := make(map[int]Algorithm)
+	for i, field := range record {
+		name := strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "", "\"", "").Replace(field))
+		switch name {
+		case "md5":
+			columns[i] = MD5
+		case "sha1":
+			columns[i] = SHA1
+		case "sha256":
+			columns[i] = SHA256
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	return columns
+}
+
+// parseDigest decodes a hex digest. Without an algorithm any MD5, SHA-1 or
+// SHA-256 sized digest is accepted.
+func parseDigest(field string, alg Algorithm) ([]byte, bool) {
+	field = strings.TrimSpace(strings.Trim(field, "\""))
+	digest, err := hex.DecodeString(field)
+	if err != nil {
+		return nil, false
+	}
+	if alg != "" {
+		return digest, len(digest) == digestSize(alg)
+	}
+	return digest, algorithmForSize(len(digest)) != ""
+}
+
+// digestSize returns the size in bytes of a digest
+func digestSize(alg Algorithm) int {
+	for _, a := range algorithms {
+		if a.alg == alg {
+			return a.size
+		}
+	}
+	return 0
+}
+
+// algorithmForSize returns the algorithm producing digests of a size
+func algorithmForSize(size int) Algorithm {
+	for _, a := range algorithms {
+		if a.size == size {
+			return a.alg
+		}
+	}
+	return ""
+}
+
+// indexFile returns the name of the index file of an algorithm
+func indexFile(alg Algorithm) string {
+	for _, a := range algorithms {
+		if a.alg == alg {
+			return a.file
+		}
+	}
+	return ""
+}
+
+// appendName appends a set name once
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// records sorts fixed-size records held in a byte slice
+type records struct {
+	data []byte
+	size int
+	tmp  []byte
+}
+
+func (r records) Len() int { return len(r.data) / r.size }
+
+func (r records) Less(i, j int) bool {
+	return bytes.Compare(r.data[i*r.size:(i+1)*r.size], r.data[j*r.size:(j+1)*r.size]) < 0
+}
+
+func (r records) Swap(i, j int) {
+	a, b := r.data[i*r.size:(i+1)*r.size], r.data[j*r.size:(j+1)*r.size]
+	copy(r.tmp, a)
+	copy(a, b)
+	copy(b, r.tmp)
+}
@@ -0,0 +1,136 @@
+package hashset
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// digests returns the MD5, SHA-1 and SHA-256 of content as hex
+func digests(content string) (string, string, string) {
+	m := md5.Sum([]byte(content))
+	s1 := sha1.Sum([]byte(content))
+	s2 := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(m[:]), hex.EncodeToString(s1[:]), hex.EncodeToString(s2[:])
+}
+
+// writeSet writes a hash set file and returns its path
+func writeSet(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportAndLookup(t *testing.T) {
+	kernelMD5, kernelSHA1, kernelSHA256 := digests("kernel32.dll")
+	toolMD5, toolSHA1, _ := digests("psexec.exe")
+	malwareMD5, _, malwareSHA256 := digests("dropper.exe")
+	_, _, unknownSHA256 := digests("holiday.jpg")
+
+	nsrl := "\"SHA-1\",\"MD5\",\"CRC32\",\"FileName\",\"FileSize\",\"ProductCode\",\"OpSystemCode\",\"SpecialCode\"\n" +
+		fmt.Sprintf("%q,%q,\"0\",\"kernel32.dll\",1,1,\"WIN\",\"\"\n", strings.ToUpper(kernelSHA1), strings.ToUpper(kernelMD5)) +
+		fmt.Sprintf("%q,%q,\"0\",\"psexec.exe\",1,1,\"WIN\",\"\"\n", toolSHA1, toolMD5) +
+		"\"not a hash\",\"\",\"0\",\"x\",1,1,\"WIN\",\"\"\n"
+	bad := "# agency list\n" + malwareMD5 + "  dropper.exe\n" + toolMD5 + "  psexec.exe\n" + malwareSHA256 + "\n"
+
+	x, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+	good, err := x.Import(writeSet(t, "NSRLFile.txt", nsrl), "NSRL", StatusKnownGood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if good.Hashes[SHA1] != 2 || good.Hashes[MD5] != 2 || good.Rejected != 1 || !good.Complete {
+		t.Errorf("NSRL set %+v", good)
+	}
+	badSet, err := x.Import(writeSet(t, "bad.txt", bad), "", StatusKnownBad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if badSet.Name != "bad.txt" || badSet.Hashes[MD5] != 2 || badSet.Hashes[SHA256] != 1 {
+		t.Errorf("bad set %+v", badSet)
+	}
+
+	tests := []struct {
+		name             string
+		md5, sha1, sh256 string
+		want             Status
+		wantSets         string
+	}{
+		{"known good by SHA-1", "", kernelSHA1, kernelSHA256, StatusKnownGood, "NSRL"},
+		{"known good by upper-case MD5", strings.ToUpper(kernelMD5), "", "", StatusKnownGood, "NSRL"},
+		{"bad wins over good", toolMD5, toolSHA1, "", StatusKnownBad, "bad.txt"},
+		{"known bad by SHA-256", "", "", malwareSHA256, StatusKnownBad, "bad.txt"},
+		{"unknown", "", "", unknownSHA256, StatusUnknown, ""},
+		{"nothing to look up", "", "", "", StatusUnknown, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := x.Lookup(tt.md5, tt.sha1, tt.sh256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Status != tt.want || strings.Join(m.Sets, ",") != tt.wantSets {
+				t.Errorf("match %+v, want %s in %q", m, tt.want, tt.wantSets)
+			}
+		})
+	}
+
+	if _, err := x.Lookup("abc", "", ""); err == nil {
+		t.Error("malformed hash looked up")
+	}
+}
+
+func TestIndexPersists(t *testing.T) {
+	dir := t.TempDir()
+	md5sum, _, _ := digests("dropper.exe")
+	x, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x.Import(writeSet(t, "bad.txt", md5sum+"\n"), "Agency", StatusKnownBad); err != nil {
+		t.Fatal(err)
+	}
+	x.Close()
+
+	x, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+	if sets := x.Sets(); len(sets) != 1 || sets[0].Name != "Agency" {
+		t.Errorf("sets after reopening: %+v", sets)
+	}
+	if m, err := x.Lookup(md5sum, "", ""); err != nil || m.Status != StatusKnownBad {
+		t.Errorf("match after reopening: %+v, %v", m, err)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Status
+		wantErr bool
+	}{
+		{"good", StatusKnownGood, false},
+		{"known-bad", StatusKnownBad, false},
+		{"KNOWN_GOOD", StatusKnownGood, false},
+		{"unknown", "", true},
+	}
+	for _, tt := range tests {
+		if got, err := ParseStatus(tt.value); got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseStatus(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+}
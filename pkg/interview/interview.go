@@ -2,7 +2,11 @@ package interview
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/document"
+	"github.com/jth/claude/GoInspectorGadget/pkg/pii"
 )
 
 // InterviewType categorizes the interview context
@@ -47,6 +51,9 @@ type Transcript struct {
 	Language    string
 	IsAutomated bool
 	Segments    []Segment
+	Redactions  []document.Redaction         // Redacted portions of the content
+	Proposals   []document.RedactionProposal // Redactions proposed by PII detection
+	Log         []document.RedactionLogEntry // Accepted and rejected proposals
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
@@ -164,7 +171,150 @@ func (s *InterviewService) TranscribeInterview(interviewID string, options Speec
 	return transcript, nil
 }
 
+// ProposeTranscriptRedactions scans the transcript of an interview for
+// personal information and adds a pending proposal for each new finding
+func (s *InterviewService) ProposeTranscriptRedactions(interviewID string, detector *pii.Detector) ([]document.RedactionProposal, error) {
+	t, err := s.transcript(interviewID)
+	if err != nil {
+		return nil, err
+	}
+	added := document.NewProposals(detector.Detect(t.Content), t.Redactions, t.Proposals)
+	if len(added) == 0 {
+		return nil, nil
+	}
+	t.Proposals = append(t.Proposals, added...)
+	t.UpdatedAt = time.Now()
+	if err := s.transcriptRepo.Update(t); err != nil {
+		return nil, fmt.Errorf("failed to save proposals: %w", err)
+	}
+	return added, nil
+}
+
+// ReviewTranscriptProposal accepts or rejects a redaction proposed for the
+// transcript of an interview; accepted proposals are redacted and both
+// decisions are logged with the transcript
+func (s *InterviewService) ReviewTranscriptProposal(interviewID, proposalID string, accept bool, reviewer string) (*document.RedactionProposal, error) {
+	t, err := s.transcript(interviewID)
+	if err != nil {
+		return nil, err
+	}
+	// Review a copy so that a failed save leaves the transcript unchanged
+	proposals := make([]document.RedactionProposal, len(t.Proposals))
+	copy(proposals, t.Proposals)
+	p, err := document.ReviewProposal(proposals, proposalID, accept, reviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := document.RedactionLogEntry{
+		ID:         newID("TRL"),
+		DocumentID: t.ID,
+		Action:     document.RedactionRejected,
+		StartPos:   p.StartPos,
+		EndPos:     p.EndPos,
+		Reason:     p.Reason,
+		User:       reviewer,
+		Detail:     fmt.Sprintf("proposal %s (%s, %.0f%% confidence) rejected", p.ID, p.Kind, p.Confidence*100),
+		Timestamp:  p.ReviewedAt,
+	}
+	redactions := t.Redactions
+	if accept {
+		entry.Action = document.RedactionApplied
+		entry.Detail = fmt.Sprintf("proposal %s (%s, %.0f%% confidence) accepted", p.ID, p.Kind, p.Confidence*100)
+		redactions = append(redactions[:len(redactions):len(redactions)], document.Redaction{
+			StartPos:   p.StartPos,
+			EndPos:     p.EndPos,
+			Reason:     p.Reason,
+			RedactedBy: reviewer,
+			RedactedAt: p.ReviewedAt,
+		})
+	}
+
+	saved := *t
+	t.Proposals = proposals
+	t.Redactions = redactions
+	t.Log = append(t.Log[:len(t.Log):len(t.Log)], entry)
+	t.UpdatedAt = p.ReviewedAt
+	if err := s.transcriptRepo.Update(t); err != nil {
+		*t = saved
+		return nil, fmt.Errorf("failed to save review: %w", err)
+	}
+	return p, nil
+}
+
+// RedactedTranscript returns the text of an interview's transcript with its
+// redactions applied
+func (s *InterviewService) RedactedTranscript(interviewID string) (string, error) {
+	t, err := s.transcript(interviewID)
+	if err != nil {
+		return "", err
+	}
+	return document.ApplyRedactions(t.Content, t.Redactions), nil
+}
+
+// RedactedSegments returns copies of the segments of an interview's
+// transcript in which the text redacted from the transcript is redacted
+// wherever it appears
+func (s *InterviewService) RedactedSegments(interviewID string) ([]Segment, error) {
+	t, err := s.transcript(interviewID)
+	if err != nil {
+		return nil, err
+	}
+	return RedactSegments(t), nil
+}
+
+// RedactSegments applies the redactions of a transcript, which refer to its
+// content, to the text of its segments
+func RedactSegments(t *Transcript) []Segment {
+	segments := make([]Segment, len(t.Segments))
+	copy(segments, t.Segments)
+	for i := range segments {
+		var redactions []document.Redaction
+		for _, r := range t.Redactions {
+			for _, at := range document.FindText(segments[i].Text, t.Content[r.StartPos:r.EndPos]) {
+				redactions = append(redactions, document.Redaction{StartPos: at[0], EndPos: at[1]})
+			}
+		}
+		segments[i].Text = document.ApplyRedactions(segments[i].Text, redactions)
+	}
+	return segments
+}
+
+// transcript finds the transcript of an interview
+func (s *InterviewService) transcript(interviewID string) (*Transcript, error) {
+	interview, err := s.interviewRepo.Find(interviewID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find interview: %w", err)
+	}
+	if interview.TranscriptID == "" {
+		return nil, fmt.Errorf("interview %s has not been transcribed", interviewID)
+	}
+	t, err := s.transcriptRepo.Find(interview.TranscriptID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transcript: %w", err)
+	}
+	return t, nil
+}
+
+// lastID holds the last timestamp handed out by newID
+var lastID int64
+
 // generateID generates a unique ID
 func generateID() string {
-	return fmt.Sprintf("INT-%d", time.Now().UnixNano())
+	return newID("INT")
+}
+
+// newID generates a unique ID with a prefix. IDs are strictly increasing so
+// that records created at the same moment never collide.
+func newID(prefix string) string {
+	for {
+		last := atomic.LoadInt64(&lastID)
+		next := time.Now().UnixNano()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastID, last, next) {
+			return fmt.Sprintf("%s-%d", prefix, next)
+		}
+	}
 }
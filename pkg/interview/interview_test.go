@@ -0,0 +1,138 @@
+package interview
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jth/claude/GoInspectorGadget/pkg/document"
+	"github.com/jth/claude/GoInspectorGadget/pkg/pii"
+)
+
+// memInterviewRepo is an in-memory InterviewRepository for tests
+type memInterviewRepo struct {
+	InterviewRepository
+	interviews map[string]*Interview
+}
+
+func (r *memInterviewRepo) Save(i *Interview) error {
+	r.interviews[i.ID] = i
+	return nil
+}
+
+func (r *memInterviewRepo) Find(id string) (*Interview, error) {
+	if i, ok := r.interviews[id]; ok {
+		return i, nil
+	}
+	return nil, errors.New("interview not found")
+}
+
+// memTranscriptRepo is an in-memory TranscriptRepository that shares the
+// stored transcripts, as the CLI repository does
+type memTranscriptRepo struct {
+	TranscriptRepository
+	transcripts map[string]*Transcript
+	failUpdate  bool
+}
+
+func (r *memTranscriptRepo) Find(id string) (*Transcript, error) {
+	if t, ok := r.transcripts[id]; ok {
+		return t, nil
+	}
+	return nil, errors.New("transcript not found")
+}
+
+func (r *memTranscriptRepo) Update(t *Transcript) error {
+	if r.failUpdate {
+		return errors.New("disk full")
+	}
+	r.transcripts[t.ID] = t
+	return nil
+}
+
+// newTranscribedInterview returns a service holding an interview whose
+// transcript names a protected witness, with redactions proposed
+func newTranscribedInterview(t *testing.T) (*InterviewService, *memTranscriptRepo, *Transcript) {
+	t.Helper()
+	tr := &Transcript{
+		ID:      "TR-1",
+		Content: "Interviewer: Did Maria Lopez see the car?\nWitness: Yes, Maria Lopez was with me.",
+		Segments: []Segment{
+			{SpeakerRole: "Interviewer", Text: "Did Maria Lopez see the car?"},
+			{SpeakerRole: "Witness", Text: "Yes, MARIA LOPEZ was with me."},
+			{SpeakerRole: "Witness", Text: "Nobody else was there."},
+		},
+	}
+	transcripts := &memTranscriptRepo{transcripts: map[string]*Transcript{tr.ID: tr}}
+	s := NewInterviewService(&memInterviewRepo{interviews: make(map[string]*Interview)}, transcripts, nil)
+	if err := s.CreateInterview(&Interview{ID: "INT-1", TranscriptID: tr.ID}); err != nil {
+		t.Fatal(err)
+	}
+	added, err := s.ProposeTranscriptRedactions("INT-1", pii.NewDetector([]string{"Maria Lopez"}))
+	if err != nil || len(added) != 2 {
+		t.Fatalf("proposals %+v, err %v", added, err)
+	}
+	return s, transcripts, tr
+}
+
+func TestReviewTranscriptProposal(t *testing.T) {
+	tests := []struct {
+		name           string
+		accept         bool
+		failUpdate     bool
+		wantAction     document.RedactionAction
+		wantRedactions int
+		wantLog        int
+		wantSegment    string
+	}{
+		{"accepted", true, false, document.RedactionApplied, 1, 1, "Did ███████████ see the car?"},
+		{"rejected", false, false, document.RedactionRejected, 0, 1, "Did Maria Lopez see the car?"},
+		{"save fails", true, true, "", 0, 0, "Did Maria Lopez see the car?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, tr := newTranscribedInterview(t)
+			repo.failUpdate = tt.failUpdate
+			_, err := s.ReviewTranscriptProposal("INT-1", "P1", tt.accept, "SGT-42")
+			if (err != nil) != tt.failUpdate {
+				t.Fatalf("err = %v", err)
+			}
+			if len(tr.Redactions) != tt.wantRedactions || len(tr.Log) != tt.wantLog {
+				t.Fatalf("%d redactions and %d log entries, want %d and %d", len(tr.Redactions), len(tr.Log), tt.wantRedactions, tt.wantLog)
+			}
+			if tt.failUpdate && tr.Proposals[0].Status != document.ProposalPending {
+				t.Errorf("proposal %s after a failed save", tr.Proposals[0].Status)
+			}
+			if tt.wantLog > 0 {
+				if e := tr.Log[0]; e.Action != tt.wantAction || e.User != "SGT-42" || e.ID == "" || !strings.Contains(e.Detail, "P1") {
+					t.Errorf("log entry %+v", e)
+				}
+			}
+			if segments := RedactSegments(tr); segments[0].Text != tt.wantSegment {
+				t.Errorf("segment = %q, want %q", segments[0].Text, tt.wantSegment)
+			}
+		})
+	}
+}
+
+func TestRedactSegments(t *testing.T) {
+	s, _, tr := newTranscribedInterview(t)
+	for _, id := range []string{"P1", "P2"} {
+		if _, err := s.ReviewTranscriptProposal("INT-1", id, true, "SGT-42"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	segments, err := s.RedactedSegments("INT-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Did ███████████ see the car?", "Yes, ███████████ was with me.", "Nobody else was there."}
+	for i, seg := range segments {
+		if seg.Text != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, seg.Text, want[i])
+		}
+	}
+	if !strings.Contains(tr.Segments[1].Text, "MARIA LOPEZ") {
+		t.Error("the stored segments were changed")
+	}
+}
@@ -0,0 +1,505 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBoxDepth bounds recursion into nested ISO BMFF boxes
+const maxBoxDepth = 8
+
+// mp4Epoch is the reference time for MP4/QuickTime timestamps
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// iso6709Pattern matches locations such as "+40.4462-079.9489+267.000/"
+var iso6709Pattern = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)?`)
+
+// quickTimeUserData maps QuickTime udta atoms to field names
+var quickTimeUserData = map[string]string{
+	"\xa9mak": "Make",
+	"\xa9mod": "Model",
+	"\xa9swr": "Software",
+	"\xa9day": "CreationDate",
+	"\xa9xyz": "Location",
+}
+
+// quickTimeKeys maps QuickTime metadata keys to field names
+var quickTimeKeys = map[string]string{
+	"com.apple.quicktime.make":             "Make",
+	"com.apple.quicktime.model":            "Model",
+	"com.apple.quicktime.software":         "Software",
+	"com.apple.quicktime.creationdate":     "CreationDate",
+	"com.apple.quicktime.location.ISO6709": "Location",
+}
+
+// box is an ISO BMFF box located within the file
+type box struct {
+	typ   string
+	start int64 // Offset of the payload
+	end   int64 // Offset just past the box
+}
+
+// walkBoxes calls fn for each box in [start, end)
+func walkBoxes(r io.ReaderAt, start, end int64, fn func(b box) error) error {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return fmt.Errorf("failed to read box header: %w", err)
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		b := box{typ: string(header[4:8]), start: offset + 8}
+
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return fmt.Errorf("failed to read box size: %w", err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			b.start += 8
+		}
+		if size < b.start-offset || offset+size > end {
+			return fmt.Errorf("invalid size for %q box at offset %d", b.typ, offset)
+		}
+		b.end = offset + size
+
+		if err := fn(b); err != nil {
+			return err
+		}
+		offset = b.end
+	}
+	return nil
+}
+
+// extractBMFF reads metadata from HEIC, MP4 and QuickTime files
+func extractBMFF(r io.ReaderAt, size int64, m *Metadata) error {
+	return walkBoxes(r, 0, size, func(b box) error {
+		switch b.typ {
+		case "ftyp":
+			data, err := readSection(r, b.start, b.end-b.start)
+			if err != nil {
+				return err
+			}
+			m.Format = bmffFormat(data)
+		case "meta":
+			if m.Format == FormatHEIC {
+				return extractHEIFExif(r, b, m)
+			}
+		case "moov":
+			return extractMovie(r, b, m)
+		}
+		return nil
+	})
+}
+
+// bmffFormat classifies a file from the brands in its ftyp box
+func bmffFormat(ftyp []byte) Format {
+	if len(ftyp) < 4 {
+		return FormatMP4
+	}
+	brands := []string{string(ftyp[:4])}
+	for i := 8; i+4 <= len(ftyp); i += 4 {
+		brands = append(brands, string(ftyp[i:i+4]))
+	}
+	for _, brand := range brands {
+		switch brand {
+		case "heic", "heix", "heim", "heis", "mif1", "msf1", "avif":
+			return FormatHEIC
+		}
+	}
+	if brands[0] == "qt  " {
+		return FormatMOV
+	}
+	return FormatMP4
+}
+
+// extractMovie reads the movie header and user data of a moov box
+func extractMovie(r io.ReaderAt, moov box, m *Metadata) error {
+	err := walkBoxes(r, moov.start, moov.end, func(b box) error {
+		switch b.typ {
+		case "mvhd":
+			data, err := readSection(r, b.start, b.end-b.start)
+			if err != nil {
+				return err
+			}
+			if created := movieCreationTime(data); !created.IsZero() {
+				m.setField("CreationTime", created.Format(time.RFC3339))
+				if m.CaptureTime.IsZero() {
+					m.CaptureTime = created
+				}
+			}
+		case "udta":
+			return extractUserData(r, b, m, 0)
+		case "meta":
+			return extractQuickTimeKeys(r, b, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.Make = m.Fields["Make"]
+	m.Model = m.Fields["Model"]
+	m.Software = m.Fields["Software"]
+	if value := m.Fields["CreationDate"]; value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			m.CaptureTime = t
+		} else if t, err := time.Parse("2006-01-02T15:04:05-0700", value); err == nil {
+			m.CaptureTime = t
+		}
+	}
+	if value := m.Fields["Location"]; value != "" {
+		m.GPS = parseISO6709(value)
+	}
+	return nil
+}
+
+// movieCreationTime decodes the creation time from an mvhd payload
+func movieCreationTime(mvhd []byte) time.Time {
+	if len(mvhd) < 4 {
+		return time.Time{}
+	}
+	var seconds uint64
+	if mvhd[0] == 1 {
+		if len(mvhd) < 12 {
+			return time.Time{}
+		}
+		seconds = binary.BigEndian.Uint64(mvhd[4:])
+	} else {
+		if len(mvhd) < 8 {
+			return time.Time{}
+		}
+		seconds = uint64(binary.BigEndian.Uint32(mvhd[4:]))
+	}
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return mp4Epoch.Add(time.Duration(seconds) * time.Second)
+}
+
+// extractUserData reads QuickTime text atoms from a udta box
+func extractUserData(r io.ReaderAt, udta box, m *Metadata, depth int) error {
+	if depth > maxBoxDepth {
+		return nil
+	}
+	return walkBoxes(r, udta.start, udta.end, func(b box) error {
+		if b.typ == "meta" {
+			return extractQuickTimeKeys(r, b, m)
+		}
+		name, ok := quickTimeUserData[b.typ]
+		if !ok {
+			return nil
+		}
+		data, err := readSection(r, b.start, b.end-b.start)
+		if err != nil {
+			return err
+		}
+		// Text atoms carry a 16-bit length and a 16-bit language code
+		if len(data) < 4 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data))
+		if 4+length > len(data) {
+			length = len(data) - 4
+		}
+		m.setField(name, strings.TrimRight(string(data[4:4+length]), "\x00"))
+		return nil
+	})
+}
+
+// extractQuickTimeKeys reads the keys/ilst metadata of a meta box
+func extractQuickTimeKeys(r io.ReaderAt, meta box, m *Metadata) error {
+	start := meta.start
+	// The ISO meta box is a full box; the QuickTime variant is not
+	peek := make([]byte, 8)
+	if _, err := r.ReadAt(peek, start); err == nil && string(peek[4:8]) != "hdlr" {
+		start += 4
+	}
+
+	var keys []string
+	var items []box
+	err := walkBoxes(r, start, meta.end, func(b box) error {
+		switch b.typ {
+		case "keys":
+			data, err := readSection(r, b.start, b.end-b.start)
+			if err != nil {
+				return err
+			}
+			keys = parseKeys(data)
+		case "ilst":
+			return walkBoxes(r, b.start, b.end, func(item box) error {
+				items = append(items, item)
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		index := int(binary.BigEndian.Uint32([]byte(item.typ)))
+		if index < 1 || index > len(keys) {
+			continue
+		}
+		name, ok := quickTimeKeys[keys[index-1]]
+		if !ok {
+			continue
+		}
+		err := walkBoxes(r, item.start, item.end, func(b box) error {
+			if b.typ != "data" {
+				return nil
+			}
+			data, err := readSection(r, b.start, b.end-b.start)
+			if err != nil {
+				return err
+			}
+			// Type indicator and locale precede the value
+			if len(data) > 8 {
+				m.setField(name, string(data[8:]))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseKeys decodes the key names of a keys box
+func parseKeys(data []byte) []string {
+	if len(data) < 8 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(data[4:]))
+	var keys []string
+	offset := 8
+	for i := 0; i < count && offset+8 <= len(data); i++ {
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		if size < 8 || offset+size > len(data) {
+			break
+		}
+		keys = append(keys, string(data[offset+8:offset+size]))
+		offset += size
+	}
+	return keys
+}
+
+// parseISO6709 decodes an ISO 6709 location string
+func parseISO6709(value string) *GPSCoordinate {
+	match := iso6709Pattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return nil
+	}
+	lat, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return nil
+	}
+	lon, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return nil
+	}
+	gps := &GPSCoordinate{Latitude: lat, Longitude: lon}
+	if match[3] != "" {
+		if alt, err := strconv.ParseFloat(match[3], 64); err == nil {
+			gps.Altitude = alt
+			gps.HasAltitude = true
+		}
+	}
+	return gps
+}
+
+// heifItemLocation is the location of an item's data within the file
+type heifItemLocation struct {
+	offset int64
+	length int64
+}
+
+// extractHEIFExif locates the Exif item of a HEIF meta box and parses it
+func extractHEIFExif(r io.ReaderAt, meta box, m *Metadata) error {
+	var exifID uint32
+	var locations map[uint32]heifItemLocation
+
+	// Skip the full box version and flags
+	err := walkBoxes(r, meta.start+4, meta.end, func(b box) error {
+		switch b.typ {
+		case "iinf":
+			data, err := readSection(r, b.start, b.end-b.start)
+			if err != nil {
+				return err
+			}
+			exifID = findExifItem(data)
+		case "iloc":
+			data, err := readSection(r, b.start, b.end-b.start)
+			if err != nil {
+				return err
+			}
+			locations, err = parseItemLocations(data)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	loc, ok := locations[exifID]
+	if exifID == 0 || !ok {
+		return nil
+	}
+
+	data, err := readSection(r, loc.offset, loc.length)
+	if err != nil {
+		return err
+	}
+	// The payload starts with the offset of the TIFF header
+	if len(data) < 4 {
+		return nil
+	}
+	tiffOffset := int64(binary.BigEndian.Uint32(data)) + 4
+	if tiffOffset >= int64(len(data)) {
+		return fmt.Errorf("invalid Exif item header")
+	}
+	return parseTIFF(data[tiffOffset:], m)
+}
+
+// findExifItem returns the ID of the Exif item described in an iinf payload
+func findExifItem(iinf []byte) uint32 {
+	if len(iinf) < 6 {
+		return 0
+	}
+	offset := 6
+	if iinf[0] != 0 {
+		offset = 8
+	}
+
+	r := bytes.NewReader(iinf)
+	var exifID uint32
+	walkBoxes(r, int64(offset), int64(len(iinf)), func(b box) error {
+		if b.typ != "infe" || exifID != 0 {
+			return nil
+		}
+		infe := iinf[b.start:b.end]
+		if len(infe) < 4 || infe[0] < 2 {
+			return nil
+		}
+		var id uint32
+		var rest []byte
+		if infe[0] == 2 {
+			if len(infe) < 12 {
+				return nil
+			}
+			id = uint32(binary.BigEndian.Uint16(infe[4:]))
+			rest = infe[8:]
+		} else {
+			if len(infe) < 14 {
+				return nil
+			}
+			id = binary.BigEndian.Uint32(infe[4:])
+			rest = infe[10:]
+		}
+		if string(rest[:4]) == "Exif" {
+			exifID = id
+		}
+		return nil
+	})
+	return exifID
+}
+
+// parseItemLocations decodes an iloc payload into file locations. Only
+// items stored in the file itself (construction method 0) are returned.
+func parseItemLocations(iloc []byte) (map[uint32]heifItemLocation, error) {
+	errTruncated := fmt.Errorf("truncated iloc box")
+	if len(iloc) < 8 {
+		return nil, errTruncated
+	}
+	version := iloc[0]
+	offsetSize := int(iloc[4] >> 4)
+	lengthSize := int(iloc[4] & 0x0F)
+	baseOffsetSize := int(iloc[5] >> 4)
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(iloc[5] & 0x0F)
+	}
+
+	pos := 6
+	readUint := func(n int) (uint64, bool) {
+		if pos+n > len(iloc) {
+			return 0, false
+		}
+		var v uint64
+		for i := 0; i < n; i++ {
+			v = v<<8 | uint64(iloc[pos+i])
+		}
+		pos += n
+		return v, true
+	}
+
+	countSize := 2
+	if version == 2 {
+		countSize = 4
+	}
+	count, ok := readUint(countSize)
+	if !ok {
+		return nil, errTruncated
+	}
+
+	locations := make(map[uint32]heifItemLocation)
+	for i := uint64(0); i < count; i++ {
+		id, ok := readUint(countSize)
+		if !ok {
+			return nil, errTruncated
+		}
+		method := uint64(0)
+		if version == 1 || version == 2 {
+			if method, ok = readUint(2); !ok {
+				return nil, errTruncated
+			}
+			method &= 0x0F
+		}
+		if _, ok = readUint(2); !ok { // Data reference index
+			return nil, errTruncated
+		}
+		base, ok := readUint(baseOffsetSize)
+		if !ok {
+			return nil, errTruncated
+		}
+		extents, ok := readUint(2)
+		if !ok {
+			return nil, errTruncated
+		}
+
+		var loc heifItemLocation
+		for j := uint64(0); j < extents; j++ {
+			if _, ok = readUint(indexSize); !ok {
+				return nil, errTruncated
+			}
+			offset, ok := readUint(offsetSize)
+			if !ok {
+				return nil, errTruncated
+			}
+			length, ok := readUint(lengthSize)
+			if !ok {
+				return nil, errTruncated
+			}
+			// Exif items are small and stored in a single extent
+			if j == 0 {
+				loc = heifItemLocation{offset: int64(base + offset), length: int64(length)}
+			}
+		}
+		if method == 0 && extents > 0 {
+			locations[uint32(id)] = loc
+		}
+	}
+	return locations, nil
+}
@@ -0,0 +1,405 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TIFF field types
+const (
+	tiffByte      = 1
+	tiffASCII     = 2
+	tiffShort     = 3
+	tiffLong      = 4
+	tiffRational  = 5
+	tiffUndefined = 7
+	tiffSLong     = 9
+	tiffSRational = 10
+)
+
+// tiffTypeSizes gives the size in bytes of one value of each field type
+var tiffTypeSizes = map[uint16]int{
+	tiffByte: 1, tiffASCII: 1, tiffShort: 2, tiffLong: 4, tiffRational: 8,
+	tiffUndefined: 1, tiffSLong: 4, tiffSRational: 8,
+}
+
+// Tag identifiers used when reading IFDs
+const (
+	tagMake              = 0x010F
+	tagModel             = 0x0110
+	tagOrientation       = 0x0112
+	tagSoftware          = 0x0131
+	tagDateTime          = 0x0132
+	tagArtist            = 0x013B
+	tagExifIFD           = 0x8769
+	tagGPSIFD            = 0x8825
+	tagDateTimeOriginal  = 0x9003
+	tagDateTimeDigitized = 0x9004
+	tagOffsetTime        = 0x9010
+	tagOffsetTimeOrig    = 0x9011
+	tagLensModel         = 0xA434
+	tagSerialNumber      = 0xA431
+	tagImageWidth        = 0xA002
+	tagImageHeight       = 0xA003
+)
+
+// tagNames maps IFD0 and Exif IFD tags to field names
+var tagNames = map[uint16]string{
+	tagMake:              "Make",
+	tagModel:             "Model",
+	tagOrientation:       "Orientation",
+	tagSoftware:          "Software",
+	tagDateTime:          "DateTime",
+	tagArtist:            "Artist",
+	tagDateTimeOriginal:  "DateTimeOriginal",
+	tagDateTimeDigitized: "DateTimeDigitized",
+	tagOffsetTime:        "OffsetTime",
+	tagOffsetTimeOrig:    "OffsetTimeOriginal",
+	tagLensModel:         "LensModel",
+	tagSerialNumber:      "BodySerialNumber",
+	tagImageWidth:        "PixelXDimension",
+	tagImageHeight:       "PixelYDimension",
+	0x829A:               "ExposureTime",
+	0x829D:               "FNumber",
+	0x8827:               "ISOSpeedRatings",
+	0x920A:               "FocalLength",
+}
+
+// gpsTagNames maps GPS IFD tags to field names
+var gpsTagNames = map[uint16]string{
+	0x01: "GPSLatitudeRef",
+	0x02: "GPSLatitude",
+	0x03: "GPSLongitudeRef",
+	0x04: "GPSLongitude",
+	0x05: "GPSAltitudeRef",
+	0x06: "GPSAltitude",
+	0x07: "GPSTimeStamp",
+	0x1D: "GPSDateStamp",
+}
+
+// maxIFDEntries bounds the number of entries read from one IFD
+const maxIFDEntries = 1024
+
+// tiffReader decodes values from a TIFF structure. IFDs and values are read
+// at their offsets, so large TIFF and raw files are never read whole.
+type tiffReader struct {
+	r     io.ReaderAt
+	size  int64
+	order binary.ByteOrder
+}
+
+// ifdEntry is one decoded IFD entry
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// extractJPEG scans JPEG segments for an APP1 Exif block
+func extractJPEG(r io.ReaderAt, size int64, m *Metadata) error {
+	offset := int64(2)
+	header := make([]byte, 4)
+	for offset+4 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return fmt.Errorf("failed to read JPEG segment: %w", err)
+		}
+		if header[0] != 0xFF {
+			return fmt.Errorf("invalid JPEG segment marker at offset %d", offset)
+		}
+		marker := header[1]
+		// Start of scan or end of image: no more metadata segments
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		length := int64(binary.BigEndian.Uint16(header[2:]))
+		if length < 2 {
+			return fmt.Errorf("invalid JPEG segment length at offset %d", offset)
+		}
+
+		if marker == 0xE1 {
+			segment, err := readSection(r, offset+4, length-2)
+			if err != nil {
+				return err
+			}
+			if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				return parseTIFF(segment[6:], m)
+			}
+		}
+		offset += 2 + length
+	}
+	return nil
+}
+
+// parseTIFF reads IFD0, the Exif IFD and the GPS IFD from TIFF data
+func parseTIFF(data []byte, m *Metadata) error {
+	return parseTIFFAt(bytes.NewReader(data), int64(len(data)), m)
+}
+
+// parseTIFFAt reads IFD0, the Exif IFD and the GPS IFD from a TIFF
+// structure of the given size
+func parseTIFFAt(r io.ReaderAt, size int64, m *Metadata) error {
+	t := &tiffReader{r: r, size: size}
+	data, ok := t.read(0, 8)
+	if !ok {
+		return fmt.Errorf("TIFF header too short")
+	}
+
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return fmt.Errorf("invalid TIFF byte order")
+	}
+	if t.order.Uint16(data[2:]) != 42 {
+		return fmt.Errorf("invalid TIFF magic number")
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:]))
+	if err != nil {
+		return err
+	}
+
+	var exifOffset, gpsOffset uint32
+	for _, e := range ifd0 {
+		switch e.tag {
+		case tagExifIFD:
+			exifOffset = t.uint(e)
+		case tagGPSIFD:
+			gpsOffset = t.uint(e)
+		default:
+			t.record(e, tagNames, m)
+		}
+	}
+
+	if exifOffset != 0 {
+		exif, err := t.readIFD(exifOffset)
+		if err != nil {
+			return err
+		}
+		for _, e := range exif {
+			t.record(e, tagNames, m)
+		}
+	}
+
+	if gpsOffset != 0 {
+		gps, err := t.readIFD(gpsOffset)
+		if err != nil {
+			return err
+		}
+		t.readGPS(gps, m)
+	}
+
+	m.Make = m.Fields["Make"]
+	m.Model = m.Fields["Model"]
+	m.Software = m.Fields["Software"]
+	if o, err := strconv.Atoi(m.Fields["Orientation"]); err == nil {
+		m.Orientation = o
+	}
+	m.CaptureTime, m.CaptureTimeZoneless = exifCaptureTime(m.Fields)
+
+	return nil
+}
+
+// read returns length bytes at offset, or false if they are not all
+// within the structure or exceed maxSectionSize
+func (t *tiffReader) read(offset, length uint64) ([]byte, bool) {
+	if length > maxSectionSize || offset+length > uint64(t.size) {
+		return nil, false
+	}
+	buf := make([]byte, length)
+	if _, err := t.r.ReadAt(buf, int64(offset)); err != nil && err != io.EOF {
+		return nil, false
+	}
+	return buf, true
+}
+
+// readIFD reads the entries of the IFD at offset
+func (t *tiffReader) readIFD(offset uint32) ([]ifdEntry, error) {
+	header, ok := t.read(uint64(offset), 2)
+	if !ok {
+		return nil, fmt.Errorf("IFD offset %d out of range", offset)
+	}
+	count := int(t.order.Uint16(header))
+	if count > maxIFDEntries {
+		return nil, fmt.Errorf("IFD has too many entries: %d", count)
+	}
+
+	// A table cut short by the end of the data yields the entries present
+	available := (t.size - int64(offset) - 2) / 12
+	count = min(count, int(max(available, 0)))
+	table, ok := t.read(uint64(offset)+2, uint64(count)*12)
+	if !ok {
+		return nil, fmt.Errorf("failed to read IFD at offset %d", offset)
+	}
+
+	var entries []ifdEntry
+	for i := 0; i < count; i++ {
+		raw := table[i*12 : i*12+12]
+		e := ifdEntry{
+			tag:   t.order.Uint16(raw[0:]),
+			typ:   t.order.Uint16(raw[2:]),
+			count: t.order.Uint32(raw[4:]),
+		}
+
+		size, ok := tiffTypeSizes[e.typ]
+		if !ok {
+			continue
+		}
+		total := uint64(size) * uint64(e.count)
+		if total <= 4 {
+			e.value = raw[8 : 8+total]
+		} else {
+			value, ok := t.read(uint64(t.order.Uint32(raw[8:])), total)
+			if !ok {
+				continue
+			}
+			e.value = value
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// uint returns the first value of an integer entry
+func (t *tiffReader) uint(e ifdEntry) uint32 {
+	switch e.typ {
+	case tiffByte, tiffUndefined:
+		if len(e.value) >= 1 {
+			return uint32(e.value[0])
+		}
+	case tiffShort:
+		if len(e.value) >= 2 {
+			return uint32(t.order.Uint16(e.value))
+		}
+	case tiffLong, tiffSLong:
+		if len(e.value) >= 4 {
+			return t.order.Uint32(e.value)
+		}
+	}
+	return 0
+}
+
+// rationals returns the values of a (signed) rational entry
+func (t *tiffReader) rationals(e ifdEntry) []float64 {
+	var result []float64
+	for i := 0; i+8 <= len(e.value); i += 8 {
+		var num, den float64
+		if e.typ == tiffSRational {
+			num = float64(int32(t.order.Uint32(e.value[i:])))
+			den = float64(int32(t.order.Uint32(e.value[i+4:])))
+		} else {
+			num = float64(t.order.Uint32(e.value[i:]))
+			den = float64(t.order.Uint32(e.value[i+4:]))
+		}
+		if den == 0 {
+			result = append(result, 0)
+			continue
+		}
+		result = append(result, num/den)
+	}
+	return result
+}
+
+// format renders an entry's value as a string
+func (t *tiffReader) format(e ifdEntry) string {
+	switch e.typ {
+	case tiffASCII:
+		return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+	case tiffByte, tiffShort, tiffLong, tiffSLong:
+		return strconv.FormatUint(uint64(t.uint(e)), 10)
+	case tiffRational, tiffSRational:
+		var parts []string
+		for _, v := range t.rationals(e) {
+			parts = append(parts, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		return strings.Join(parts, " ")
+	}
+	return ""
+}
+
+// record stores a named entry in the metadata fields
+func (t *tiffReader) record(e ifdEntry, names map[uint16]string, m *Metadata) {
+	if name, ok := names[e.tag]; ok {
+		m.setField(name, t.format(e))
+	}
+}
+
+// readGPS converts the GPS IFD into a coordinate
+func (t *tiffReader) readGPS(entries []ifdEntry, m *Metadata) {
+	var lat, lon, alt []float64
+	var latRef, lonRef string
+	var altRef uint32
+	for _, e := range entries {
+		t.record(e, gpsTagNames, m)
+		switch e.tag {
+		case 0x01:
+			latRef = t.format(e)
+		case 0x02:
+			lat = t.rationals(e)
+		case 0x03:
+			lonRef = t.format(e)
+		case 0x04:
+			lon = t.rationals(e)
+		case 0x05:
+			altRef = t.uint(e)
+		case 0x06:
+			alt = t.rationals(e)
+		}
+	}
+
+	if len(lat) != 3 || len(lon) != 3 {
+		return
+	}
+
+	gps := &GPSCoordinate{
+		Latitude:  lat[0] + lat[1]/60 + lat[2]/3600,
+		Longitude: lon[0] + lon[1]/60 + lon[2]/3600,
+	}
+	if strings.EqualFold(latRef, "S") {
+		gps.Latitude = -gps.Latitude
+	}
+	if strings.EqualFold(lonRef, "W") {
+		gps.Longitude = -gps.Longitude
+	}
+	if len(alt) == 1 {
+		gps.Altitude = alt[0]
+		if altRef == 1 {
+			gps.Altitude = -gps.Altitude
+		}
+		gps.HasAltitude = true
+	}
+	m.GPS = gps
+}
+
+// exifCaptureTime derives the capture time from the EXIF date fields. EXIF
+// dates are the camera's wall-clock time; without an offset tag they are
+// read as local time and reported as zone-less.
+func exifCaptureTime(fields map[string]string) (t time.Time, zoneless bool) {
+	candidates := []struct{ date, offset string }{
+		{fields["DateTimeOriginal"], fields["OffsetTimeOriginal"]},
+		{fields["DateTimeDigitized"], fields["OffsetTime"]},
+		{fields["DateTime"], fields["OffsetTime"]},
+	}
+	for _, c := range candidates {
+		if c.date == "" {
+			continue
+		}
+		if c.offset != "" {
+			if t, err := time.Parse("2006:01:02 15:04:05-07:00", c.date+c.offset); err == nil {
+				return t, false
+			}
+		}
+		if t, err := time.ParseInLocation("2006:01:02 15:04:05", c.date, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,131 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Format identifies the container format metadata was read from
+type Format string
+
+const (
+	FormatJPEG Format = "JPEG"
+	FormatTIFF Format = "TIFF"
+	FormatPNG  Format = "PNG"
+	FormatHEIC Format = "HEIC"
+	FormatMP4  Format = "MP4"
+	FormatMOV  Format = "MOV"
+)
+
+// ErrUnsupportedFormat is returned for files that carry no supported metadata container
+var ErrUnsupportedFormat = errors.New("unsupported file format for metadata extraction")
+
+// maxSectionSize bounds how much of any single metadata structure is read
+// into memory, protecting against corrupt or hostile length fields
+const maxSectionSize = 16 * 1024 * 1024
+
+// Metadata holds the embedded metadata extracted from an image or video
+type Metadata struct {
+	Format              Format
+	Make                string
+	Model               string
+	Software            string
+	CaptureTime         time.Time // Zero if unknown
+	CaptureTimeZoneless bool      // No zone or offset was recorded; CaptureTime was read as local time
+	Orientation         int       // EXIF orientation (1-8), zero if unknown
+	GPS                 *GPSCoordinate
+	Fields              map[string]string // Every tag read, keyed by tag name
+}
+
+// GPSCoordinate is a WGS-84 position
+type GPSCoordinate struct {
+	Latitude    float64
+	Longitude   float64
+	Altitude    float64 // Metres above sea level
+	HasAltitude bool
+}
+
+// String formats the coordinate as "lat,lon" with six decimal places
+func (g GPSCoordinate) String() string {
+	return fmt.Sprintf("%.6f,%.6f", g.Latitude, g.Longitude)
+}
+
+// ExtractFile reads embedded metadata from a file
+func ExtractFile(path string) (*Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	return Extract(f, info.Size())
+}
+
+// Extract reads embedded metadata from JPEG, TIFF, PNG, HEIC, MP4 or MOV data
+func Extract(r io.ReaderAt, size int64) (*Metadata, error) {
+	head := make([]byte, 12)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+
+	m := &Metadata{Fields: make(map[string]string)}
+
+	var err error
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		m.Format = FormatJPEG
+		err = extractJPEG(r, size, m)
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		m.Format = FormatTIFF
+		err = parseTIFFAt(r, size, m)
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		m.Format = FormatPNG
+		err = extractPNG(r, size, m)
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		err = extractBMFF(r, size, m)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// IsImage reports whether the format is a still image
+func (m *Metadata) IsImage() bool {
+	switch m.Format {
+	case FormatJPEG, FormatTIFF, FormatPNG, FormatHEIC:
+		return true
+	}
+	return false
+}
+
+// setField records a raw field, ignoring empty values
+func (m *Metadata) setField(name, value string) {
+	if value != "" {
+		m.Fields[name] = value
+	}
+}
+
+// readSection reads [offset, offset+length) from r, bounded by maxSectionSize
+func readSection(r io.ReaderAt, offset, length int64) ([]byte, error) {
+	if length < 0 || length > maxSectionSize {
+		return nil, fmt.Errorf("metadata section of %d bytes exceeds limit", length)
+	}
+	buf := make([]byte, length)
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return buf[:n], nil
+}
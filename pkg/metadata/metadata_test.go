@@ -0,0 +1,172 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tiffEntry is an IFD entry for building test files
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// buildIFD encodes a little-endian IFD placed at offset, with values that
+// do not fit in an entry stored after it
+func buildIFD(offset uint32, entries []tiffEntry) []byte {
+	var table, values bytes.Buffer
+	valueOffset := offset + 2 + uint32(len(entries))*12 + 4
+	binary.Write(&table, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&table, binary.LittleEndian, e.tag)
+		binary.Write(&table, binary.LittleEndian, e.typ)
+		binary.Write(&table, binary.LittleEndian, e.count)
+		if len(e.value) <= 4 {
+			field := make([]byte, 4)
+			copy(field, e.value)
+			table.Write(field)
+			continue
+		}
+		binary.Write(&table, binary.LittleEndian, valueOffset+uint32(values.Len()))
+		values.Write(e.value)
+	}
+	table.Write(make([]byte, 4)) // No next IFD
+	return append(table.Bytes(), values.Bytes()...)
+}
+
+func ascii(s string) tiffEntry {
+	return tiffEntry{typ: tiffASCII, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func withTag(tag uint16, e tiffEntry) tiffEntry {
+	e.tag = tag
+	return e
+}
+
+// buildTIFF returns a TIFF whose IFD0 is at ifdOffset, padded with zeros
+func buildTIFF(ifdOffset uint32) []byte {
+	data := make([]byte, ifdOffset)
+	copy(data, "II*\x00")
+	binary.LittleEndian.PutUint32(data[4:], ifdOffset)
+	return append(data, buildIFD(ifdOffset, []tiffEntry{
+		withTag(tagMake, ascii("Canon")),
+		withTag(tagModel, ascii("EOS R5")),
+		withTag(tagDateTime, ascii("2024:03:01 14:30:00")),
+		{tag: tagOrientation, typ: tiffShort, count: 1, value: []byte{6, 0}},
+	})...)
+}
+
+func TestExtractTIFF(t *testing.T) {
+	tests := []struct {
+		name      string
+		ifdOffset uint32
+	}{
+		{"small", 8},
+		// Larger than maxSectionSize, as raw camera files often are
+		{"over 16 MiB", 20 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "image.tif")
+			if err := os.WriteFile(path, buildTIFF(tt.ifdOffset), 0644); err != nil {
+				t.Fatal(err)
+			}
+			m, err := ExtractFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// No offset tag, so the camera's clock is read as local time
+			want := time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local)
+			if m.Format != FormatTIFF || m.Make != "Canon" || m.Model != "EOS R5" ||
+				m.Orientation != 6 || !m.CaptureTime.Equal(want) || !m.CaptureTimeZoneless {
+				t.Errorf("metadata = %+v", m)
+			}
+		})
+	}
+}
+
+func TestExtractJPEGExif(t *testing.T) {
+	tiff := buildTIFF(8)
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&jpeg, binary.BigEndian, uint16(2+6+len(tiff)))
+	jpeg.WriteString("Exif\x00\x00")
+	jpeg.Write(tiff)
+	jpeg.Write([]byte{0xFF, 0xD9})
+
+	m, err := Extract(bytes.NewReader(jpeg.Bytes()), int64(jpeg.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Format != FormatJPEG || m.Make != "Canon" {
+		t.Errorf("metadata = %+v", m)
+	}
+}
+
+func TestExtractRejectsBadData(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated TIFF header", []byte("II*\x00\x08")},
+		{"IFD beyond the end", func() []byte {
+			data := []byte("II*\x00\x00\x00\x00\x00")
+			binary.LittleEndian.PutUint32(data[4:], 1<<30)
+			return data
+		}()},
+		{"unsupported", []byte("plain text file")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err == nil {
+				t.Error("Extract succeeded")
+			}
+			if tt.name == "unsupported" && !errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("err = %v, want ErrUnsupportedFormat", err)
+			}
+		})
+	}
+}
+
+func TestExifCaptureTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		fields       map[string]string
+		want         time.Time
+		wantZoneless bool
+	}{
+		{"original with offset",
+			map[string]string{"DateTimeOriginal": "2024:03:01 14:30:00", "OffsetTimeOriginal": "-04:00"},
+			time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC), false},
+		{"original without offset",
+			map[string]string{"DateTimeOriginal": "2024:03:01 14:30:00", "OffsetTime": "+02:00"},
+			time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local), true},
+		{"digitized with offset",
+			map[string]string{"DateTimeDigitized": "2024:03:01 14:30:00", "OffsetTime": "+02:00"},
+			time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), false},
+		{"modification time only",
+			map[string]string{"DateTime": "2024:03:01 14:30:00"},
+			time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local), true},
+		{"unreadable date", map[string]string{"DateTimeOriginal": "2024:03:01"}, time.Time{}, false},
+		{"no date", map[string]string{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, zoneless := exifCaptureTime(tt.fields)
+			if !got.Equal(tt.want) || zoneless != tt.wantZoneless {
+				t.Errorf("exifCaptureTime = %v, zone-less %v, want %v, %v", got, zoneless, tt.want, tt.wantZoneless)
+			}
+			if zoneless && got.Location() != time.Local {
+				t.Errorf("zone-less time in %v, want local time", got.Location())
+			}
+		})
+	}
+}
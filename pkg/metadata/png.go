@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxTextChunkSize bounds decompressed PNG text chunks
+const maxTextChunkSize = 1024 * 1024
+
+// pngTimeFormats are the layouts commonly used for the "Creation Time" keyword
+// and whether they carry a zone
+var pngTimeFormats = []struct {
+	layout string
+	zoned  bool
+}{
+	{time.RFC1123Z, true},
+	{time.RFC1123, true},
+	{time.RFC3339, true},
+	{"2006:01:02 15:04:05", false},
+	{"2006-01-02 15:04:05", false},
+}
+
+// extractPNG reads text, compressed text, international text and eXIf chunks
+func extractPNG(r io.ReaderAt, size int64, m *Metadata) error {
+	offset := int64(8)
+	header := make([]byte, 8)
+	for offset+8 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return fmt.Errorf("failed to read PNG chunk: %w", err)
+		}
+		length := int64(binary.BigEndian.Uint32(header))
+		chunkType := string(header[4:])
+		dataOffset := offset + 8
+		offset = dataOffset + length + 4 // Skip data and CRC
+
+		switch chunkType {
+		case "IEND":
+			return finishPNG(m)
+		case "tEXt", "zTXt", "iTXt", "eXIf":
+		default:
+			continue
+		}
+
+		data, err := readSection(r, dataOffset, length)
+		if err != nil {
+			return err
+		}
+
+		if chunkType == "eXIf" {
+			if err := parseTIFF(data, m); err != nil {
+				return fmt.Errorf("failed to parse eXIf chunk: %w", err)
+			}
+			continue
+		}
+
+		keyword, text, err := decodePNGText(chunkType, data)
+		if err != nil {
+			continue // A damaged text chunk should not hide the rest
+		}
+		m.setField(keyword, text)
+	}
+	return finishPNG(m)
+}
+
+// decodePNGText decodes a tEXt, zTXt or iTXt chunk
+func decodePNGText(chunkType string, data []byte) (string, string, error) {
+	sep := bytes.IndexByte(data, 0)
+	if sep < 0 {
+		return "", "", fmt.Errorf("missing keyword separator")
+	}
+	keyword := string(data[:sep])
+	rest := data[sep+1:]
+
+	switch chunkType {
+	case "tEXt":
+		return keyword, latin1(rest), nil
+
+	case "zTXt":
+		if len(rest) < 1 {
+			return "", "", fmt.Errorf("truncated zTXt chunk")
+		}
+		text, err := inflate(rest[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return keyword, latin1(text), nil
+
+	case "iTXt":
+		if len(rest) < 2 {
+			return "", "", fmt.Errorf("truncated iTXt chunk")
+		}
+		compressed := rest[0] == 1
+		rest = rest[2:]
+		// Skip language tag and translated keyword
+		for i := 0; i < 2; i++ {
+			sep := bytes.IndexByte(rest, 0)
+			if sep < 0 {
+				return "", "", fmt.Errorf("truncated iTXt chunk")
+			}
+			rest = rest[sep+1:]
+		}
+		if compressed {
+			text, err := inflate(rest)
+			if err != nil {
+				return "", "", err
+			}
+			return keyword, string(text), nil
+		}
+		return keyword, string(rest), nil
+	}
+
+	return "", "", fmt.Errorf("unsupported chunk type %s", chunkType)
+}
+
+// finishPNG maps well-known PNG keywords onto the common fields. EXIF
+// values from an eXIf chunk take precedence.
+func finishPNG(m *Metadata) error {
+	if m.Software == "" {
+		m.Software = m.Fields["Software"]
+	}
+	if m.Make == "" {
+		m.Make = m.Fields["Source"]
+	}
+	if m.CaptureTime.IsZero() {
+		if value := strings.TrimSpace(m.Fields["Creation Time"]); value != "" {
+			for _, f := range pngTimeFormats {
+				if t, err := time.ParseInLocation(f.layout, value, time.Local); err == nil {
+					m.CaptureTime = t
+					m.CaptureTimeZoneless = !f.zoned
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// inflate decompresses zlib data with a size limit
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, maxTextChunkSize))
+}
+
+// latin1 converts ISO 8859-1 text to UTF-8
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,239 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguages are recognized when no languages are chosen, as far as
+// their language data is installed
+var DefaultLanguages = []string{"eng", "spa"}
+
+// Word is a word read from an image
+type Word struct {
+	Text       string
+	Confidence float64 // 0 to 100, as reported by the engine
+	Offset     int     // Position of the word in its page's text
+	Left       int     // Bounding box in pixels
+	Top        int
+	Width      int
+	Height     int
+}
+
+// Page is the text read from one page of an image
+type Page struct {
+	Text  string
+	Words []Word
+}
+
+// Confidence returns the mean confidence of the words on a page
+func (p Page) Confidence() float64 {
+	if len(p.Words) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, w := range p.Words {
+		total += w.Confidence
+	}
+	return total / float64(len(p.Words))
+}
+
+// Tesseract runs a locally installed tesseract OCR engine
+type Tesseract struct {
+	Path      string   // Path to the tesseract executable
+	Languages []string // Language data to use, such as "eng" and "spa"
+	TempDir   string   // Directory for temporary files
+
+	mu        sync.Mutex // Guards installed
+	installed []string
+}
+
+// NewTesseract finds tesseract on the path
+func NewTesseract(tempDir string) (*Tesseract, error) {
+	path, err := exec.LookPath("tesseract")
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not found, please install tesseract-ocr")
+	}
+	return &Tesseract{Path: path, TempDir: tempDir}, nil
+}
+
+// InstalledLanguages lists the language data installed for tesseract
+func (t *Tesseract) InstalledLanguages() ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.installed != nil {
+		return t.installed, nil
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(t.Path, "--list-langs")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract --list-langs failed: %w: %s", err, stderr.String())
+	}
+	// Older versions print the list on standard error
+	output := stdout.String() + stderr.String()
+	var languages []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, " ") {
+			continue // The "List of available languages" heading
+		}
+		languages = append(languages, line)
+	}
+	t.installed = languages
+	return languages, nil
+}
+
+// LanguageList returns the languages that will be recognized, joined with
+// "+" as tesseract takes them
+func (t *Tesseract) LanguageList() (string, error) {
+	installed, err := t.InstalledLanguages()
+	if err != nil {
+		return "", err
+	}
+	has := make(map[string]bool)
+	for _, l := range installed {
+		has[l] = true
+	}
+
+	var languages []string
+	if len(t.Languages) > 0 {
+		for _, l := range t.Languages {
+			if !has[l] {
+				return "", fmt.Errorf("tesseract language data not installed: %s", l)
+			}
+			languages = append(languages, l)
+		}
+	} else {
+		for _, l := range DefaultLanguages {
+			if has[l] {
+				languages = append(languages, l)
+			}
+		}
+		if len(languages) == 0 {
+			return "", fmt.Errorf("tesseract language data not installed: %s", strings.Join(DefaultLanguages, ", "))
+		}
+	}
+	return strings.Join(languages, "+"), nil
+}
+
+// Recognize reads the text of an image, one page per image in multi-page
+// TIFF files. The resolution is passed to tesseract when it is known, as
+// images taken from PDF files do not record it.
+func (t *Tesseract) Recognize(imagePath string, dpi int) ([]Page, error) {
+	languages, err := t.LanguageList()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(t.TempDir, "ocr-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	outBase := filepath.Join(dir, "out")
+	args := []string{imagePath, outBase, "-l", languages}
+	if dpi > 0 {
+		args = append(args, "--dpi", strconv.Itoa(dpi))
+	}
+	args = append(args, "tsv")
+	var stderr bytes.Buffer
+	cmd := exec.Command(t.Path, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	f, err := os.Open(outBase + ".tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	defer f.Close()
+	return ParseTSV(f)
+}
+
+// ParseTSV reads tesseract's TSV output. Words on a line are separated by
+// spaces, lines by a line break and paragraphs and blocks by a blank line.
+func ParseTSV(r io.Reader) ([]Page, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var pages []Page
+	var text strings.Builder
+	var words []Word
+	page, block, par, line := -1, -1, -1, -1
+	flush := func() {
+		if page >= 0 {
+			pages = append(pages, Page{Text: text.String(), Words: words})
+		}
+		text.Reset()
+		words = nil
+	}
+
+	header := true
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if header {
+			header = false
+			if len(fields) > 0 && fields[0] == "level" {
+				continue
+			}
+		}
+		if len(fields) < 12 {
+			continue
+		}
+		n := make([]int, 10)
+		for i := range n {
+			n[i], _ = strconv.Atoi(fields[i])
+		}
+		level, pageNum, blockNum, parNum, lineNum := n[0], n[1], n[2], n[3], n[4]
+		for pageNum > page+1 || page < 0 && pageNum > 0 {
+			// Pages are numbered from 1; keep empty pages in place
+			flush()
+			page++
+			block, par, line = -1, -1, -1
+		}
+		if level != 5 {
+			continue
+		}
+		word := strings.TrimSpace(fields[11])
+		if word == "" {
+			continue
+		}
+		confidence, _ := strconv.ParseFloat(fields[10], 64)
+
+		switch {
+		case text.Len() == 0:
+		case blockNum != block || parNum != par:
+			text.WriteString("\n\n")
+		case lineNum != line:
+			text.WriteString("\n")
+		default:
+			text.WriteString(" ")
+		}
+		block, par, line = blockNum, parNum, lineNum
+		words = append(words, Word{
+			Text:       word,
+			Confidence: confidence,
+			Offset:     text.Len(),
+			Left:       n[6],
+			Top:        n[7],
+			Width:      n[8],
+			Height:     n[9],
+		})
+		text.WriteString(word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	flush()
+	return pages, nil
+}
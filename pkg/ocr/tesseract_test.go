@@ -0,0 +1,182 @@
+package ocr
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTesseract writes a script that answers --list-langs, counting its runs
+func fakeTesseract(t *testing.T, languages string) (*Tesseract, string) {
+	t.Helper()
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	script := "#!/bin/sh\necho run >> " + runs + "\necho 'List of available languages (2):'\nprintf '" + languages + "'\n"
+	path := filepath.Join(dir, "tesseract")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &Tesseract{Path: path, TempDir: dir}, runs
+}
+
+func TestLanguageList(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed string
+		languages []string
+		want      string
+		wantErr   bool
+	}{
+		{"defaults", "eng\\nspa\\nosd\\n", nil, "eng+spa", false},
+		{"default subset", "eng\\nosd\\n", nil, "eng", false},
+		{"chosen", "eng\\nspa\\n", []string{"spa"}, "spa", false},
+		{"chosen not installed", "eng\\n", []string{"por"}, "", true},
+		{"no defaults installed", "osd\\n", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tess, _ := fakeTesseract(t, tt.installed)
+			tess.Languages = tt.languages
+			got, err := tess.LanguageList()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("got %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstalledLanguagesConcurrent(t *testing.T) {
+	tess, runs := fakeTesseract(t, "eng\\nspa\\n")
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := tess.InstalledLanguages(); err != nil || strings.Join(got, ",") != "eng,spa" {
+				t.Errorf("got %v, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	data, _ := os.ReadFile(runs)
+	if n := strings.Count(string(data), "run"); n != 1 {
+		t.Errorf("tesseract run %d times, want once", n)
+	}
+}
+
+// tsvHeader is the first line of tesseract's TSV output
+const tsvHeader = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n"
+
+// tsvWord is a word row of tesseract's TSV output
+func tsvWord(page, block, par, line, word int, conf, text string) string {
+	n := func(i int) string { return strconv.Itoa(i) }
+	return strings.Join([]string{"5", n(page), n(block), n(par), n(line), n(word), "10", "20", "30", "40", conf, text}, "\t") + "\n"
+}
+
+func TestParseTSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		tsv       string
+		wantPages []string
+		wantConf  []float64
+	}{
+		{
+			name: "lines and paragraphs",
+			tsv: tsvHeader +
+				"1\t1\t0\t0\t0\t0\t0\t0\t2550\t3300\t-1\t\n" +
+				tsvWord(1, 1, 1, 1, 1, "96.5", "Informe") +
+				tsvWord(1, 1, 1, 1, 2, "91.5", "policial") +
+				tsvWord(1, 1, 1, 2, 1, "80", "Madrid") +
+				tsvWord(1, 1, 2, 1, 1, "70", "Firma") +
+				tsvWord(1, 2, 1, 1, 1, "60", " ") +
+				tsvWord(1, 3, 1, 1, 1, "50", "Fin"),
+			wantPages: []string{"Informe policial\nMadrid\n\nFirma\n\nFin"},
+			wantConf:  []float64{77.6},
+		},
+		{
+			name: "blank page kept in place",
+			tsv: tsvHeader +
+				tsvWord(1, 1, 1, 1, 1, "90", "uno") +
+				"1\t2\t0\t0\t0\t0\t0\t0\t2550\t3300\t-1\t\n" +
+				tsvWord(3, 1, 1, 1, 1, "80", "tres"),
+			wantPages: []string{"uno", "", "tres"},
+			wantConf:  []float64{90, 0, 80},
+		},
+		{name: "no output", tsv: "", wantPages: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := ParseTSV(strings.NewReader(tt.tsv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != len(tt.wantPages) {
+				t.Fatalf("pages = %+v", pages)
+			}
+			for i, p := range pages {
+				if p.Text != tt.wantPages[i] {
+					t.Errorf("page %d text = %q, want %q", i+1, p.Text, tt.wantPages[i])
+				}
+				if got := math.Round(p.Confidence()*10) / 10; got != tt.wantConf[i] {
+					t.Errorf("page %d confidence = %v, want %v", i+1, got, tt.wantConf[i])
+				}
+				for _, w := range p.Words {
+					if !strings.HasPrefix(p.Text[w.Offset:], w.Text) {
+						t.Errorf("word %q not at offset %d of %q", w.Text, w.Offset, p.Text)
+					}
+				}
+			}
+		})
+	}
+}
+
+// fakeRecognizer writes a script that lists eng and spa and answers every
+// other run with tsv, recording its arguments
+func fakeRecognizer(t *testing.T, tsv string) (*Tesseract, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "out.tsv"), []byte(tsv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = --list-langs ]; then printf 'eng\\nspa\\n'; exit 0; fi\n" +
+		"echo \"$@\" > " + args + "\n" +
+		"cat " + filepath.Join(dir, "out.tsv") + " > \"$2.tsv\"\n"
+	path := filepath.Join(dir, "tesseract")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &Tesseract{Path: path, TempDir: dir}, args
+}
+
+func TestRecognize(t *testing.T) {
+	tests := []struct {
+		name     string
+		dpi      int
+		wantArgs string
+	}{
+		{"resolution unknown", 0, "-l eng+spa tsv"},
+		{"resolution given", 300, "-l eng+spa --dpi 300 tsv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tess, args := fakeRecognizer(t, tsvHeader+tsvWord(1, 1, 1, 1, 1, "88", "hola"))
+			pages, err := tess.Recognize("scan.png", tt.dpi)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != 1 || pages[0].Text != "hola" {
+				t.Errorf("pages = %+v", pages)
+			}
+			data, _ := os.ReadFile(args)
+			if !strings.HasSuffix(strings.TrimSpace(string(data)), tt.wantArgs) {
+				t.Errorf("args = %q, want suffix %q", data, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+)
+
+// passwordPadding pads passwords in the standard security handler
+var passwordPadding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// cryptMethod is how strings or streams are encrypted
+type cryptMethod int
+
+const (
+	cryptNone cryptMethod = iota
+	cryptRC4
+	cryptAESV2 // AES-128
+	cryptAESV3 // AES-256
+)
+
+// decrypter decrypts the strings and streams of a file encrypted with the
+// standard security handler. Only files that open without a user password,
+// such as those restricted with an owner password, can be read.
+type decrypter struct {
+	key             []byte
+	revision        int
+	strings         cryptMethod
+	streams         cryptMethod
+	encryptMetadata bool
+	encryptRef      Ref // The encryption dictionary is not itself encrypted
+}
+
+// newDecrypter prepares decryption with the empty user password
+func newDecrypter(f *File, obj Object) (*decrypter, error) {
+	enc, ok := f.Resolve(obj).(Dict)
+	if !ok {
+		return nil, fmt.Errorf("invalid encryption dictionary")
+	}
+	if filter, _ := enc["Filter"].(Name); filter != "Standard" {
+		return nil, fmt.Errorf("unsupported PDF security handler %q", filter)
+	}
+	v, _ := enc["V"].(int64)
+	r, _ := enc["R"].(int64)
+	d := &decrypter{revision: int(r), encryptMetadata: true}
+	if ref, ok := obj.(Ref); ok {
+		d.encryptRef = ref
+	}
+	if em, ok := enc["EncryptMetadata"].(bool); ok {
+		d.encryptMetadata = em
+	}
+
+	switch v {
+	case 1, 2, 3:
+		d.strings, d.streams = cryptRC4, cryptRC4
+	case 4, 5:
+		cf, _ := f.Resolve(enc["CF"]).(Dict)
+		method := func(name Object) cryptMethod {
+			n, _ := name.(Name)
+			if n == "" || n == "Identity" {
+				return cryptNone
+			}
+			filter, _ := f.Resolve(cf[n]).(Dict)
+			switch filter["CFM"] {
+			case Name("AESV2"):
+				return cryptAESV2
+			case Name("AESV3"):
+				return cryptAESV3
+			case Name("V2"):
+				return cryptRC4
+			}
+			return cryptNone
+		}
+		d.strings, d.streams = method(enc["StrF"]), method(enc["StmF"])
+	default:
+		return nil, fmt.Errorf("unsupported PDF encryption version %d", v)
+	}
+
+	o, _ := enc["O"].(String)
+	u, _ := enc["U"].(String)
+	var err error
+	if r >= 5 {
+		ue, _ := enc["UE"].(String)
+		d.key, err = d.aesV3Key([]byte(u), []byte(ue))
+	} else {
+		length, _ := enc["Length"].(int64)
+		if length == 0 || v == 1 {
+			length = 40
+		}
+		p, _ := enc["P"].(int64)
+		var id []byte
+		if ids, ok := f.Resolve(f.trailer["ID"]).(Array); ok && len(ids) > 0 {
+			first, _ := f.Resolve(ids[0]).(String)
+			id = []byte(first)
+		}
+		d.key, err = d.rc4Key([]byte(o), []byte(u), uint32(int32(p)), id, int(length/8))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// rc4Key computes and checks the file key of revisions 2 to 4 for the empty
+// user password
+func (d *decrypter) rc4Key(o, u []byte, p uint32, id []byte, n int) ([]byte, error) {
+	if n < 5 || n > 16 {
+		n = 5
+	}
+	h := md5.New()
+	h.Write(passwordPadding)
+	h.Write(o)
+	binary.Write(h, binary.LittleEndian, p)
+	h.Write(id)
+	if d.revision >= 4 && !d.encryptMetadata {
+		h.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+	}
+	key := h.Sum(nil)
+	if d.revision >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key[:n])
+			key = sum[:]
+		}
+	}
+	key = key[:n]
+
+	// Check the key against the user password entry
+	var check, want []byte
+	if d.revision == 2 {
+		check, want = rc4Crypt(key, passwordPadding), u
+	} else {
+		sum := md5.Sum(append(append([]byte{}, passwordPadding...), id...))
+		check = sum[:]
+		for i := 0; i < 20; i++ {
+			k := make([]byte, len(key))
+			for j := range key {
+				k[j] = key[j] ^ byte(i)
+			}
+			check = rc4Crypt(k, check)
+		}
+		if len(u) >= 16 {
+			want = u[:16]
+		}
+	}
+	if !bytes.Equal(check, want) {
+		return nil, ErrPasswordProtected
+	}
+	return key, nil
+}
+
+// aesV3Key computes and checks the file key of revisions 5 and 6 for the
+// empty user password
+func (d *decrypter) aesV3Key(u, ue []byte) ([]byte, error) {
+	if len(u) < 48 || len(ue) < 32 {
+		return nil, fmt.Errorf("invalid AES-256 encryption dictionary")
+	}
+	if !bytes.Equal(d.hashV3(u[32:40]), u[:32]) {
+		return nil, ErrPasswordProtected
+	}
+	block, err := aes.NewCipher(d.hashV3(u[40:48]))
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	cipher.NewCBCDecrypter(block, make([]byte, 16)).CryptBlocks(key, ue[:32])
+	return key, nil
+}
+
+// hashV3 hashes the empty password with a salt: SHA-256 in revision 5 and
+// the iterated hash of revision 6
+func (d *decrypter) hashV3(salt []byte) []byte {
+	sum := sha256.Sum256(salt)
+	k := sum[:]
+	if d.revision < 6 {
+		return k
+	}
+	for round := 0; ; {
+		k1 := bytes.Repeat(k, 64)
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+		total := 0
+		for _, b := range e[:16] {
+			total += int(b)
+		}
+		switch total % 3 {
+		case 0:
+			s := sha256.Sum256(e)
+			k = s[:]
+		case 1:
+			s := sha512.Sum384(e)
+			k = s[:]
+		case 2:
+			s := sha512.Sum512(e)
+			k = s[:]
+		}
+		round++
+		if round >= 64 && int(e[len(e)-1]) <= round-32 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+// exempt reports whether an object is the encryption dictionary
+func (d *decrypter) exempt(num int) bool {
+	return num == d.encryptRef.Num && d.encryptRef.Num != 0
+}
+
+// exemptStream reports whether a stream is stored unencrypted
+func (d *decrypter) exemptStream(s *Stream) bool {
+	switch s.Dict["Type"] {
+	case Name("XRef"):
+		return true
+	case Name("Metadata"):
+		return !d.encryptMetadata
+	}
+	return false
+}
+
+// decryptStrings decrypts the strings in an object read from the file
+func (d *decrypter) decryptStrings(obj Object, ref Ref) Object {
+	switch v := obj.(type) {
+	case String:
+		if out, err := d.decrypt(v, ref, false); err == nil {
+			return String(out)
+		}
+	case Array:
+		for i := range v {
+			v[i] = d.decryptStrings(v[i], ref)
+		}
+	case Dict:
+		for k := range v {
+			v[k] = d.decryptStrings(v[k], ref)
+		}
+	case *Stream:
+		d.decryptStrings(v.Dict, ref)
+	}
+	return obj
+}
+
+// decrypt decrypts the data of a string or stream of an object
+func (d *decrypter) decrypt(data []byte, ref Ref, stream bool) ([]byte, error) {
+	method := d.strings
+	if stream {
+		method = d.streams
+	}
+	if method == cryptNone {
+		return data, nil
+	}
+
+	key := d.key
+	if method != cryptAESV3 {
+		h := md5.New()
+		h.Write(key)
+		h.Write([]byte{byte(ref.Num), byte(ref.Num >> 8), byte(ref.Num >> 16), byte(ref.Gen), byte(ref.Gen >> 8)})
+		if method == cryptAESV2 {
+			h.Write([]byte("sAlT"))
+		}
+		key = h.Sum(nil)[:min(len(d.key)+5, 16)]
+	}
+	if method == cryptRC4 {
+		return rc4Crypt(key, data), nil
+	}
+
+	if len(data) < 16 {
+		return nil, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	iv, body := data[:16], data[16:]
+	body = body[:len(body)/aes.BlockSize*aes.BlockSize]
+	out := make([]byte, len(body))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, body)
+	if n := len(out); n > 0 {
+		if pad := int(out[n-1]); pad >= 1 && pad <= aes.BlockSize && pad <= n {
+			out = out[:n-pad]
+		}
+	}
+	return out, nil
+}
+
+// rc4Crypt encrypts or decrypts data with RC4
+func rc4Crypt(key, data []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return data
+	}
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
@@ -0,0 +1,211 @@
+package pdf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// winAnsiNames are the glyph names of WinAnsiEncoding from code 0x20
+var winAnsiNames = [224]string{
+	"space", "exclam", "quotedbl", "numbersign", "dollar", "percent", "ampersand", "quotesingle",
+	"parenleft", "parenright", "asterisk", "plus", "comma", "hyphen", "period", "slash",
+	"zero", "one", "two", "three", "four", "five", "six", "seven",
+	"eight", "nine", "colon", "semicolon", "less", "equal", "greater", "question",
+	"at", "A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O",
+	"P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z",
+	"bracketleft", "backslash", "bracketright", "asciicircum", "underscore",
+	"grave", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o",
+	"p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z",
+	"braceleft", "bar", "braceright", "asciitilde", "",
+	"Euro", "", "quotesinglbase", "florin", "quotedblbase", "ellipsis", "dagger", "daggerdbl",
+	"circumflex", "perthousand", "Scaron", "guilsinglleft", "OE", "", "Zcaron", "",
+	"", "quoteleft", "quoteright", "quotedblleft", "quotedblright", "bullet", "endash", "emdash",
+	"tilde", "trademark", "scaron", "guilsinglright", "oe", "", "zcaron", "Ydieresis",
+	"nbspace", "exclamdown", "cent", "sterling", "currency", "yen", "brokenbar", "section",
+	"dieresis", "copyright", "ordfeminine", "guillemotleft", "logicalnot", "sfthyphen", "registered", "macron",
+	"degree", "plusminus", "twosuperior", "threesuperior", "acute", "mu", "paragraph", "periodcentered",
+	"cedilla", "onesuperior", "ordmasculine", "guillemotright", "onequarter", "onehalf", "threequarters", "questiondown",
+	"Agrave", "Aacute", "Acircumflex", "Atilde", "Adieresis", "Aring", "AE", "Ccedilla",
+	"Egrave", "Eacute", "Ecircumflex", "Edieresis", "Igrave", "Iacute", "Icircumflex", "Idieresis",
+	"Eth", "Ntilde", "Ograve", "Oacute", "Ocircumflex", "Otilde", "Odieresis", "multiply",
+	"Oslash", "Ugrave", "Uacute", "Ucircumflex", "Udieresis", "Yacute", "Thorn", "germandbls",
+	"agrave", "aacute", "acircumflex", "atilde", "adieresis", "aring", "ae", "ccedilla",
+	"egrave", "eacute", "ecircumflex", "edieresis", "igrave", "iacute", "icircumflex", "idieresis",
+	"eth", "ntilde", "ograve", "oacute", "ocircumflex", "otilde", "odieresis", "divide",
+	"oslash", "ugrave", "uacute", "ucircumflex", "udieresis", "yacute", "thorn", "ydieresis",
+}
+
+// extraGlyphs are glyph names outside WinAnsiEncoding that are common in
+// font encodings
+var extraGlyphs = map[string]string{
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+	"dotlessi": "ı", "Lslash": "Ł", "lslash": "ł", "minus": "−", "fraction": "⁄",
+	"breve": "˘", "dotaccent": "˙", "ring": "˚", "hungarumlaut": "˝", "ogonek": "˛", "caron": "ˇ",
+	"quotesingle": "'", "middot": "·", "Delta": "∆", "Omega": "Ω", "pi": "π", "mu1": "µ",
+	"notequal": "≠", "lessequal": "≤", "greaterequal": "≥", "infinity": "∞", "summation": "∑",
+	"product": "∏", "integral": "∫", "partialdiff": "∂", "radical": "√", "approxequal": "≈",
+	"lozenge": "◊", "apple": "", "arrowright": "→", "arrowleft": "←", "checkmark": "✓",
+	"Gbreve": "Ğ", "gbreve": "ğ", "Scedilla": "Ş", "scedilla": "ş", "Idotaccent": "İ",
+	"Cacute": "Ć", "cacute": "ć", "Nacute": "Ń", "nacute": "ń", "Sacute": "Ś", "sacute": "ś",
+	"Zacute": "Ź", "zacute": "ź", "Zdotaccent": "Ż", "zdotaccent": "ż", "Aogonek": "Ą", "aogonek": "ą",
+	"Eogonek": "Ę", "eogonek": "ę", "Ccaron": "Č", "ccaron": "č", "Rcaron": "Ř", "rcaron": "ř",
+	"Ecaron": "Ě", "ecaron": "ě", "Uring": "Ů", "uring": "ů", "Ohungarumlaut": "Ő", "ohungarumlaut": "ő",
+	"Uhungarumlaut": "Ű", "uhungarumlaut": "ű", "nbspace": " ", "sfthyphen": "-", "hyphen": "-",
+}
+
+// glyphNames maps glyph names to their text
+var glyphNames = func() map[string]string {
+	m := make(map[string]string, len(winAnsiNames)+len(extraGlyphs))
+	for i, name := range winAnsiNames {
+		if name != "" {
+			m[name] = winAnsiText(byte(0x20 + i))
+		}
+	}
+	for name, text := range extraGlyphs {
+		m[name] = text
+	}
+	return m
+}()
+
+// winAnsiHigh is the text of WinAnsiEncoding codes 0x80 to 0x9F
+var winAnsiHigh = func() [32]rune {
+	var t [32]rune
+	for r, c := range winAnsiExtras {
+		t[c-0x80] = r
+	}
+	return t
+}()
+
+// winAnsiText returns the text of a WinAnsiEncoding code
+func winAnsiText(c byte) string {
+	switch {
+	case c >= 0x80 && c < 0xA0:
+		if r := winAnsiHigh[c-0x80]; r != 0 {
+			return string(r)
+		}
+		return ""
+	case c < 0x20 || c == 0x7F:
+		return ""
+	}
+	return string(rune(c))
+}
+
+// macRomanHigh is the text of MacRomanEncoding codes 0x80 to 0xFF
+var macRomanHigh = []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü" +
+	"†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+	"¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ" +
+	"‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+
+// standardHigh is the text of the StandardEncoding codes that differ from
+// ASCII and Latin-1
+var standardHigh = map[byte]string{
+	0x27: "’", 0x60: "‘", 0xA1: "¡", 0xA2: "¢", 0xA3: "£", 0xA4: "⁄", 0xA5: "¥", 0xA6: "ƒ",
+	0xA7: "§", 0xA8: "¤", 0xA9: "'", 0xAA: "“", 0xAB: "«", 0xAC: "‹", 0xAD: "›", 0xAE: "fi",
+	0xAF: "fl", 0xB1: "–", 0xB2: "†", 0xB3: "‡", 0xB4: "·", 0xB6: "¶", 0xB7: "•", 0xB8: "‚",
+	0xB9: "„", 0xBA: "”", 0xBB: "»", 0xBC: "…", 0xBD: "‰", 0xBF: "¿", 0xC1: "`", 0xC2: "´",
+	0xC3: "ˆ", 0xC4: "˜", 0xC5: "¯", 0xC6: "˘", 0xC7: "˙", 0xC8: "¨", 0xCA: "˚", 0xCB: "¸",
+	0xCD: "˝", 0xCE: "˛", 0xCF: "ˇ", 0xD0: "—", 0xE1: "Æ", 0xE3: "ª", 0xE8: "Ł", 0xE9: "Ø",
+	0xEA: "Œ", 0xEB: "º", 0xF1: "æ", 0xF5: "ı", 0xF8: "ł", 0xF9: "ø", 0xFA: "œ", 0xFB: "ß",
+}
+
+// pdfDocHigh is the text of PDFDocEncoding codes 0x80 to 0xA0
+var pdfDocHigh = []rune("•†‡…—–ƒ⁄‹›−‰„“”‘’‚™ﬁﬂŁŒŠŸŽıłœšž�€")
+
+// pdfDocLow is the text of PDFDocEncoding codes 0x18 to 0x1F
+var pdfDocLow = []rune("˘ˇˆ˙˝˛˚˜")
+
+// baseEncoding returns the text of each code in a named encoding
+func baseEncoding(name Name) [256]string {
+	var enc [256]string
+	for c := 0; c < 256; c++ {
+		switch name {
+		case "MacRomanEncoding":
+			if c >= 0x80 {
+				enc[c] = string(macRomanHigh[c-0x80])
+			} else {
+				enc[c] = winAnsiText(byte(c))
+			}
+		case "StandardEncoding":
+			switch {
+			case standardHigh[byte(c)] != "":
+				enc[c] = standardHigh[byte(c)]
+			case c < 0x7F:
+				enc[c] = winAnsiText(byte(c))
+			}
+		default: // WinAnsiEncoding, also a sensible guess for fonts without an encoding
+			enc[c] = winAnsiText(byte(c))
+		}
+	}
+	return enc
+}
+
+// glyphText returns the text of a glyph name, following the Adobe glyph
+// naming conventions for uniXXXX, uXXXX, ligatures and suffixes
+func glyphText(name string) string {
+	if text, ok := glyphNames[name]; ok {
+		return text
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i] // Variants such as a.sc or one.oldstyle
+	}
+	if strings.Contains(name, "_") {
+		var b strings.Builder
+		for _, part := range strings.Split(name, "_") {
+			b.WriteString(glyphText(part))
+		}
+		return b.String()
+	}
+	if text, ok := glyphNames[name]; ok {
+		return text
+	}
+	if strings.HasPrefix(name, "uni") && len(name) >= 7 && (len(name)-3)%4 == 0 {
+		var units []uint16
+		for i := 3; i < len(name); i += 4 {
+			v, err := strconv.ParseUint(name[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			units = append(units, uint16(v))
+		}
+		return string(utf16.Decode(units))
+	}
+	if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+// decodeTextString decodes a text string such as a document title, which is
+// UTF-16BE or UTF-8 with a byte order mark, or PDFDocEncoding
+func decodeTextString(s String) string {
+	switch {
+	case len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF:
+		return decodeUTF16BE(s[2:])
+	case len(s) >= 3 && s[0] == 0xEF && s[1] == 0xBB && s[2] == 0xBF:
+		return string(s[3:])
+	}
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= 0x18 && c < 0x20:
+			b.WriteRune(pdfDocLow[c-0x18])
+		case c >= 0x80 && c <= 0xA0:
+			b.WriteRune(pdfDocHigh[c-0x80])
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// decodeUTF16BE decodes big-endian UTF-16
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
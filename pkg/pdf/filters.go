@@ -0,0 +1,322 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// maxDecodedSize limits the decoded size of a stream, so that a crafted
+// file cannot exhaust memory
+const maxDecodedSize = 256 << 20
+
+// errImageFilter is returned for streams compressed with image codecs,
+// which hold no text
+var errImageFilter = fmt.Errorf("image data")
+
+// StreamData returns the decoded data of a stream
+func (f *File) StreamData(s *Stream) ([]byte, error) {
+	return f.decodeStream(s, true)
+}
+
+// decodeStream decrypts a stream, unless it is exempt, and applies its filters
+func (f *File) decodeStream(s *Stream, decrypt bool) ([]byte, error) {
+	data := s.Raw
+	if decrypt && f.crypt != nil && !f.crypt.exemptStream(s) {
+		var err error
+		if data, err = f.crypt.decrypt(data, s.ref, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var filters, params Array
+	switch v := f.Resolve(s.Dict["Filter"]).(type) {
+	case Name:
+		filters = Array{v}
+	case Array:
+		filters = v
+	}
+	switch v := f.Resolve(s.Dict["DecodeParms"]).(type) {
+	case Dict:
+		params = Array{v}
+	case Array:
+		params = v
+	}
+
+	for i, filter := range filters {
+		name, _ := f.Resolve(filter).(Name)
+		var param Dict
+		if i < len(params) {
+			param, _ = f.Resolve(params[i]).(Dict)
+		}
+		var err error
+		if data, err = f.applyFilter(name, param, data); err != nil {
+			return data, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return data, nil
+}
+
+// applyFilter decodes data with one filter
+func (f *File) applyFilter(name Name, param Dict, data []byte) ([]byte, error) {
+	switch name {
+	case "FlateDecode", "Fl":
+		out, err := inflate(data)
+		if err != nil {
+			return nil, err
+		}
+		return f.unpredict(out, param)
+	case "LZWDecode", "LZW":
+		early := true
+		if v, ok := f.Resolve(param["EarlyChange"]).(int64); ok && v == 0 {
+			early = false
+		}
+		return f.unpredict(lzwDecode(data, early), param)
+	case "ASCIIHexDecode", "AHx":
+		l := &lexer{data: append(append([]byte{'<'}, data...), '>')}
+		return []byte(l.hexString()), nil
+	case "ASCII85Decode", "A85":
+		return ascii85Decode(data), nil
+	case "RunLengthDecode", "RL":
+		return runLengthDecode(data), nil
+	case "Crypt":
+		return data, nil // Identity crypt filter; encryption is handled separately
+	case "DCTDecode", "DCT", "JPXDecode", "CCITTFaxDecode", "CCF", "JBIG2Decode":
+		return data, errImageFilter
+	}
+	return data, fmt.Errorf("unsupported filter")
+}
+
+// inflate decompresses zlib data, keeping what can be read of damaged or
+// truncated streams and tolerating a missing zlib header
+func inflate(data []byte) ([]byte, error) {
+	var r io.Reader
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err == nil {
+		r = zr
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if len(out) > maxDecodedSize {
+		return nil, fmt.Errorf("stream is larger than %d MB", maxDecodedSize>>20)
+	}
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// unpredict reverses the PNG and TIFF predictors of Flate and LZW data
+func (f *File) unpredict(data []byte, param Dict) ([]byte, error) {
+	predictor, _ := f.Resolve(param["Predictor"]).(int64)
+	if predictor < 2 {
+		return data, nil
+	}
+	intParam := func(key Name, def int64) int {
+		if v, ok := f.Resolve(param[key]).(int64); ok && v > 0 {
+			return int(v)
+		}
+		return int(def)
+	}
+	colors, bits, columns := intParam("Colors", 1), intParam("BitsPerComponent", 8), intParam("Columns", 1)
+	bpp := max(1, (colors*bits+7)/8)
+	rowLen := (columns*colors*bits + 7) / 8
+	if rowLen <= 0 {
+		return nil, fmt.Errorf("invalid predictor parameters")
+	}
+
+	if predictor == 2 {
+		if bits != 8 {
+			return data, nil
+		}
+		for row := 0; row+rowLen <= len(data); row += rowLen {
+			for i := row + bpp; i < row+rowLen; i++ {
+				data[i] += data[i-bpp]
+			}
+		}
+		return data, nil
+	}
+
+	// PNG predictors: each row starts with its filter type
+	var out []byte
+	prev := make([]byte, rowLen)
+	for pos := 0; pos < len(data); pos += rowLen + 1 {
+		end := min(pos+1+rowLen, len(data))
+		kind := data[pos]
+		row := make([]byte, rowLen)
+		copy(row, data[pos+1:end])
+		for i := 0; i < rowLen; i++ {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row[:end-pos-1]...)
+		prev = row
+	}
+	return out, nil
+}
+
+// paeth is the PNG Paeth predictor
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// lzwDecode decodes PDF LZW data. With early change, as PDF uses by
+// default, the code width grows one code sooner than in GIF.
+func lzwDecode(data []byte, early bool) []byte {
+	const clear, eod = 256, 257
+	var out []byte
+	table := make([][]byte, 258, 4096)
+	reset := func() {
+		table = table[:258]
+		for i := 0; i < 256; i++ {
+			table[i] = []byte{byte(i)}
+		}
+	}
+	reset()
+
+	width := 9
+	var bitBuf uint32
+	bits := 0
+	var prev []byte
+	for _, b := range data {
+		bitBuf = bitBuf<<8 | uint32(b)
+		bits += 8
+		for bits >= width {
+			code := int(bitBuf>>(bits-width)) & (1<<width - 1)
+			bits -= width
+			switch {
+			case code == clear:
+				reset()
+				width, prev = 9, nil
+				continue
+			case code == eod:
+				return out
+			}
+
+			var entry []byte
+			switch {
+			case code < len(table):
+				entry = table[code]
+			case code == len(table) && prev != nil:
+				entry = append(append([]byte{}, prev...), prev[0])
+			default:
+				return out // Corrupt data
+			}
+			out = append(out, entry...)
+			if len(out) > maxDecodedSize {
+				return out
+			}
+			if prev != nil && len(table) < 4096 {
+				table = append(table, append(append([]byte{}, prev...), entry[0]))
+			}
+			prev = entry
+
+			next := len(table)
+			if early {
+				next++
+			}
+			switch {
+			case next >= 2048:
+				width = 12
+			case next >= 1024:
+				width = 11
+			case next >= 512:
+				width = 10
+			}
+		}
+	}
+	return out
+}
+
+// ascii85Decode decodes ASCII base-85 data up to the ~> end marker
+func ascii85Decode(data []byte) []byte {
+	var out []byte
+	var group [5]byte
+	n := 0
+	flush := func(count int) {
+		var v uint32
+		for i := 0; i < 5; i++ {
+			c := byte('u')
+			if i < count {
+				c = group[i]
+			}
+			v = v*85 + uint32(c-'!')
+		}
+		buf := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, buf[:count-1]...)
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '~':
+			if n > 1 {
+				flush(n)
+			}
+			return out
+		case c == 'z' && n == 0:
+			out = append(out, 0, 0, 0, 0)
+		case c >= '!' && c <= 'u':
+			group[n] = c
+			if n++; n == 5 {
+				flush(5)
+				n = 0
+			}
+		}
+	}
+	if n > 1 {
+		flush(n)
+	}
+	return out
+}
+
+// runLengthDecode decodes PackBits run-length data
+func runLengthDecode(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		i++
+		switch {
+		case n == 128:
+			return out
+		case n < 128:
+			end := min(i+n+1, len(data))
+			out = append(out, data[i:end]...)
+			i = end
+		case i < len(data):
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		}
+	}
+	return out
+}
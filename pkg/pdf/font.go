@@ -0,0 +1,315 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// codespace is a range of character codes of one length in a CMap
+type codespace struct {
+	n      int // Code length in bytes
+	lo, hi uint32
+}
+
+// cmapRange maps a range of codes to consecutive text
+type cmapRange struct {
+	n      int
+	lo, hi uint32
+	base   []uint16 // UTF-16 text of the first code
+	list   []string // Text of each code, when given as an array
+}
+
+// cmap is a ToUnicode character map
+type cmap struct {
+	codespaces []codespace
+	chars      map[[2]uint32]string // Keyed by code length and code
+	ranges     []cmapRange
+}
+
+// parseCMap reads the codespace ranges and bfchar and bfrange mappings of
+// a ToUnicode CMap
+func parseCMap(data []byte) *cmap {
+	c := &cmap{chars: make(map[[2]uint32]string)}
+	l := &lexer{data: data}
+	var operands []Object
+	for {
+		obj, err := l.object()
+		if err != nil {
+			break
+		}
+		k, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch k {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(String)
+				hi, ok2 := operands[i+1].(String)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 && len(lo) <= 4 {
+					c.codespaces = append(c.codespaces, codespace{len(lo), uint32(beUint(lo)), uint32(beUint(hi))})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(String)
+				if !ok1 || len(src) == 0 || len(src) > 4 {
+					continue
+				}
+				c.chars[[2]uint32{uint32(len(src)), uint32(beUint(src))}] = cmapText(operands[i+1])
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(String)
+				hi, ok2 := operands[i+1].(String)
+				if !ok1 || !ok2 || len(lo) == 0 || len(lo) > 4 || len(lo) != len(hi) {
+					continue
+				}
+				r := cmapRange{n: len(lo), lo: uint32(beUint(lo)), hi: uint32(beUint(hi))}
+				switch dst := operands[i+2].(type) {
+				case String:
+					for j := 0; j+1 < len(dst); j += 2 {
+						r.base = append(r.base, uint16(dst[j])<<8|uint16(dst[j+1]))
+					}
+				case Array:
+					for _, d := range dst {
+						r.list = append(r.list, cmapText(d))
+					}
+				}
+				if r.hi >= r.lo {
+					c.ranges = append(c.ranges, r)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return c
+}
+
+// cmapText decodes the UTF-16BE destination of a CMap mapping
+func cmapText(obj Object) string {
+	switch v := obj.(type) {
+	case String:
+		return decodeUTF16BE(v)
+	case Name:
+		return glyphText(string(v))
+	}
+	return ""
+}
+
+// codeLength returns the length of the code at the start of s, from the
+// codespace ranges, or def when there are none
+func (c *cmap) codeLength(s []byte, def int) int {
+	if c == nil || len(c.codespaces) == 0 {
+		return def
+	}
+	for n := 1; n <= 4 && n <= len(s); n++ {
+		code := uint32(beUint(s[:n]))
+		for _, cs := range c.codespaces {
+			if cs.n == n && code >= cs.lo && code <= cs.hi {
+				return n
+			}
+		}
+	}
+	return def
+}
+
+// lookup returns the text of a code
+func (c *cmap) lookup(code uint32, n int) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if text, ok := c.chars[[2]uint32{uint32(n), code}]; ok {
+		return text, true
+	}
+	for _, r := range c.ranges {
+		if r.n != n || code < r.lo || code > r.hi {
+			continue
+		}
+		offset := code - r.lo
+		if r.list != nil {
+			if int(offset) < len(r.list) {
+				return r.list[offset], true
+			}
+			return "", false
+		}
+		if len(r.base) == 0 {
+			return "", false
+		}
+		units := append([]uint16{}, r.base...)
+		units[len(units)-1] += uint16(offset)
+		return decodeUTF16BE(unitsToBytes(units)), true
+	}
+	return "", false
+}
+
+// unitsToBytes encodes UTF-16 code units big-endian
+func unitsToBytes(units []uint16) []byte {
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		b[2*i], b[2*i+1] = byte(u>>8), byte(u)
+	}
+	return b
+}
+
+// font decodes the strings shown with a font and measures their glyphs
+type font struct {
+	composite    bool // Type0 font with multi-byte codes
+	utf16        bool // Composite font whose codes are UTF-16, by a predefined CMap
+	encoding     [256]string
+	toUnicode    *cmap
+	widths       map[uint32]float64 // Glyph widths in thousandths of text space
+	defaultWidth float64
+	widthScale   float64 // Converts Type 3 glyph space widths to thousandths
+}
+
+// glyph is one character code shown with a font
+type glyph struct {
+	text  string
+	width float64 // In thousandths of the font size
+	space bool    // Single-byte code 32, to which word spacing applies
+	n     int     // Length of the code in bytes
+}
+
+// loadFont reads a font dictionary
+func (f *File) loadFont(dict Dict) *font {
+	ft := &font{widths: make(map[uint32]float64), defaultWidth: 500, widthScale: 1}
+	subtype, _ := f.Resolve(dict["Subtype"]).(Name)
+	baseFont, _ := f.Resolve(dict["BaseFont"]).(Name)
+	if strings.Contains(string(baseFont), "Courier") {
+		ft.defaultWidth = 600
+	}
+
+	if s, ok := f.Resolve(dict["ToUnicode"]).(*Stream); ok {
+		if data, err := f.StreamData(s); err == nil {
+			ft.toUnicode = parseCMap(data)
+		}
+	}
+
+	if subtype == "Type0" {
+		ft.composite = true
+		ft.defaultWidth = 1000
+		if enc, ok := f.Resolve(dict["Encoding"]).(Name); ok {
+			ft.utf16 = strings.Contains(string(enc), "UCS2") || strings.Contains(string(enc), "UTF16")
+		}
+		if descendants, ok := f.Resolve(dict["DescendantFonts"]).(Array); ok && len(descendants) > 0 {
+			f.loadCIDWidths(ft, f.dict(descendants[0]))
+		}
+		return ft
+	}
+
+	// Simple fonts: a base encoding with differences
+	base := Name("WinAnsiEncoding")
+	if subtype == "Type1" || subtype == "Type3" {
+		base = "StandardEncoding"
+	}
+	var differences Array
+	switch enc := f.Resolve(dict["Encoding"]).(type) {
+	case Name:
+		base = enc
+	case Dict:
+		if b, ok := f.Resolve(enc["BaseEncoding"]).(Name); ok {
+			base = b
+		}
+		differences, _ = f.Resolve(enc["Differences"]).(Array)
+	}
+	ft.encoding = baseEncoding(base)
+	code := 0
+	for _, d := range differences {
+		switch v := f.Resolve(d).(type) {
+		case int64:
+			code = int(v)
+		case Name:
+			if code >= 0 && code < 256 {
+				ft.encoding[code] = glyphText(string(v))
+			}
+			code++
+		}
+	}
+
+	if subtype == "Type3" {
+		if m, ok := f.Resolve(dict["FontMatrix"]).(Array); ok && len(m) > 0 {
+			if scale, ok := f.number(m[0]); ok {
+				ft.widthScale = scale * 1000
+			}
+		}
+	}
+	first, _ := f.number(dict["FirstChar"])
+	if widths, ok := f.Resolve(dict["Widths"]).(Array); ok {
+		for i, w := range widths {
+			if v, ok := f.number(w); ok {
+				ft.widths[uint32(int(first)+i)] = v * ft.widthScale
+			}
+		}
+	}
+	if desc := f.dict(dict["FontDescriptor"]); desc != nil {
+		if mw, ok := f.number(desc["MissingWidth"]); ok && mw > 0 {
+			ft.defaultWidth = mw * ft.widthScale
+		}
+	}
+	return ft
+}
+
+// loadCIDWidths reads the /W and /DW widths of a CID font
+func (f *File) loadCIDWidths(ft *font, cidFont Dict) {
+	if cidFont == nil {
+		return
+	}
+	if dw, ok := f.number(cidFont["DW"]); ok {
+		ft.defaultWidth = dw
+	}
+	w, _ := f.Resolve(cidFont["W"]).(Array)
+	for i := 0; i < len(w); {
+		first, ok := f.number(w[i])
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if list, ok := f.Resolve(w[i+1]).(Array); ok {
+			for j, v := range list {
+				if width, ok := f.number(v); ok {
+					ft.widths[uint32(int(first)+j)] = width
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last, _ := f.number(w[i+1])
+		width, _ := f.number(w[i+2])
+		for c := first; c <= last && c-first < 65536; c++ {
+			ft.widths[uint32(c)] = width
+		}
+		i += 3
+	}
+}
+
+// decode splits a shown string into glyphs
+func (ft *font) decode(s []byte) []glyph {
+	var glyphs []glyph
+	for len(s) > 0 {
+		n := 1
+		if ft.composite {
+			n = ft.toUnicode.codeLength(s, 2)
+		}
+		n = min(n, len(s))
+		code := uint32(beUint(s[:n]))
+		s = s[n:]
+
+		text, ok := ft.toUnicode.lookup(code, n)
+		switch {
+		case ok:
+		case ft.utf16:
+			text = decodeUTF16BE(unitsToBytes([]uint16{uint16(code)}))
+		case !ft.composite && code < 256:
+			text = ft.encoding[code]
+		}
+		width, ok := ft.widths[code]
+		if !ok {
+			width = ft.defaultWidth
+		}
+		glyphs = append(glyphs, glyph{text: text, width: width, space: n == 1 && code == 32, n: n})
+	}
+	return glyphs
+}
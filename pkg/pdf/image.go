@@ -0,0 +1,338 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// maxImagePixels limits the size of images decoded to bitmaps
+const maxImagePixels = 100 << 20
+
+// Image is an image drawn on a page, in a file format image readers accept
+type Image struct {
+	Format string // "jpeg", "jp2", "tiff" or "png"
+	Data   []byte
+	Width  int
+	Height int
+	DPI    int // Resolution at which the image is drawn, 0 if unknown
+}
+
+// PageImages returns the images drawn on a page, numbered from 1, including
+// those drawn by form XObjects. Images in formats that cannot be converted,
+// such as JBIG2, are left out.
+func (f *File) PageImages(number int) ([]Image, error) {
+	pages := f.pages()
+	if number < 1 || number > len(pages) {
+		return nil, fmt.Errorf("page %d out of range, the document has %d pages", number, len(pages))
+	}
+	p := pages[number-1]
+	var images []Image
+	e := &textExtractor{file: f, fonts: make(map[Ref]*font), gs: graphicsState{ctm: identity, scale: 1}, images: &images}
+	e.run(f.pageContent(p.dict), p.resources, 0)
+	return images, nil
+}
+
+// image converts an image XObject drawn with the current transformation
+func (e *textExtractor) image(s *Stream) {
+	img, err := e.file.convertImage(s)
+	if err != nil {
+		return
+	}
+	// The image fills the unit square, so its drawn width in points is the
+	// length of the transformed x axis
+	if width := math.Hypot(e.gs.ctm[0], e.gs.ctm[1]); width > 1 {
+		img.DPI = int(math.Round(float64(img.Width) * 72 / width))
+	}
+	*e.images = append(*e.images, img)
+}
+
+// convertImage decodes an image XObject into an image file
+func (f *File) convertImage(s *Stream) (Image, error) {
+	width, _ := f.number(s.Dict["Width"])
+	height, _ := f.number(s.Dict["Height"])
+	img := Image{Width: int(width), Height: int(height)}
+	if img.Width <= 0 || img.Height <= 0 || img.Width*img.Height > maxImagePixels {
+		return img, fmt.Errorf("invalid image size %dx%d", img.Width, img.Height)
+	}
+
+	data, err := f.StreamData(s)
+	if err == nil {
+		img.Format = "png"
+		img.Data, err = f.encodePNG(s, data, img.Width, img.Height)
+		return img, err
+	}
+	if !errors.Is(err, errImageFilter) {
+		return img, err
+	}
+	filter, param := f.lastFilter(s)
+	switch filter {
+	case "DCTDecode", "DCT":
+		img.Format, img.Data = "jpeg", data
+	case "JPXDecode":
+		img.Format, img.Data = "jp2", data
+	case "CCITTFaxDecode", "CCF":
+		img.Format, img.Data = "tiff", f.ccittTIFF(data, param, img.Width, img.Height)
+	default:
+		return img, fmt.Errorf("unsupported image filter %s", filter)
+	}
+	return img, nil
+}
+
+// lastFilter returns the final filter of a stream and its parameters
+func (f *File) lastFilter(s *Stream) (Name, Dict) {
+	var name Name
+	var param Dict
+	switch v := f.Resolve(s.Dict["Filter"]).(type) {
+	case Name:
+		name = v
+		param, _ = f.Resolve(s.Dict["DecodeParms"]).(Dict)
+	case Array:
+		if len(v) > 0 {
+			name, _ = f.Resolve(v[len(v)-1]).(Name)
+			if params, ok := f.Resolve(s.Dict["DecodeParms"]).(Array); ok && len(params) == len(v) {
+				param, _ = f.Resolve(params[len(params)-1]).(Dict)
+			}
+		}
+	}
+	return name, param
+}
+
+// ccittTIFF wraps CCITT fax data in a single-strip TIFF file
+func (f *File) ccittTIFF(data []byte, param Dict, width, height int) []byte {
+	k, _ := f.number(param["K"])
+	if columns, ok := f.number(param["Columns"]); ok && columns > 0 {
+		width = int(columns)
+	}
+	if rows, ok := f.number(param["Rows"]); ok && rows > 0 {
+		height = int(rows)
+	}
+	compression, t4Options := 4, -1 // Group 4
+	if k >= 0 {
+		compression, t4Options = 3, 0 // Group 3, one-dimensional
+		if k > 0 {
+			t4Options = 1
+		}
+	}
+	// Unless BlackIs1 is set, 0 bits are black, as with WhiteIsZero
+	photometric := 0
+	if b, _ := f.Resolve(param["BlackIs1"]).(bool); b {
+		photometric = 1
+	}
+
+	type entry struct {
+		tag, typ uint16
+		value    uint32
+	}
+	entries := []entry{
+		{256, 4, uint32(width)},
+		{257, 4, uint32(height)},
+		{258, 3, 1},
+		{259, 3, uint32(compression)},
+		{262, 3, uint32(photometric)},
+		{273, 4, 0}, // Strip offset, set below
+		{277, 3, 1},
+		{278, 4, uint32(height)},
+		{279, 4, uint32(len(data))},
+	}
+	if t4Options >= 0 {
+		entries = append(entries, entry{292, 4, uint32(t4Options)})
+	}
+	ifdSize := 2 + 12*len(entries) + 4
+	entries[5].value = uint32(8 + ifdSize)
+
+	var b bytes.Buffer
+	b.WriteString("II*\x00")
+	binary.Write(&b, binary.LittleEndian, uint32(8))
+	binary.Write(&b, binary.LittleEndian, uint16(len(entries)))
+	for _, en := range entries {
+		binary.Write(&b, binary.LittleEndian, en.tag)
+		binary.Write(&b, binary.LittleEndian, en.typ)
+		binary.Write(&b, binary.LittleEndian, uint32(1))
+		if en.typ == 3 {
+			binary.Write(&b, binary.LittleEndian, uint16(en.value))
+			binary.Write(&b, binary.LittleEndian, uint16(0))
+		} else {
+			binary.Write(&b, binary.LittleEndian, en.value)
+		}
+	}
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	b.Write(data)
+	return b.Bytes()
+}
+
+// colorSpace is an image color space reduced to what bitmaps need
+type colorSpace struct {
+	components int
+	indexed    bool
+	base       int    // Components of the base space of an indexed space
+	lookup     []byte // Palette of an indexed space
+	cmyk       bool
+}
+
+// colorSpace resolves the color space of an image
+func (f *File) colorSpace(obj Object) (colorSpace, error) {
+	switch v := f.Resolve(obj).(type) {
+	case Name:
+		switch v {
+		case "DeviceGray", "G", "CalGray":
+			return colorSpace{components: 1}, nil
+		case "DeviceRGB", "RGB", "CalRGB":
+			return colorSpace{components: 3}, nil
+		case "DeviceCMYK", "CMYK":
+			return colorSpace{components: 4, cmyk: true}, nil
+		}
+		return colorSpace{}, fmt.Errorf("unsupported color space %s", v)
+	case Array:
+		if len(v) == 0 {
+			break
+		}
+		family, _ := f.Resolve(v[0]).(Name)
+		switch family {
+		case "CalGray":
+			return colorSpace{components: 1}, nil
+		case "CalRGB", "Lab":
+			return colorSpace{components: 3}, nil
+		case "ICCBased":
+			if len(v) > 1 {
+				if s, ok := f.Resolve(v[1]).(*Stream); ok {
+					n, _ := f.number(s.Dict["N"])
+					switch int(n) {
+					case 1, 3:
+						return colorSpace{components: int(n)}, nil
+					case 4:
+						return colorSpace{components: 4, cmyk: true}, nil
+					}
+				}
+			}
+		case "Indexed", "I":
+			if len(v) < 4 {
+				break
+			}
+			base, err := f.colorSpace(v[1])
+			if err != nil || base.indexed {
+				return colorSpace{}, fmt.Errorf("unsupported indexed base color space")
+			}
+			var lookup []byte
+			switch l := f.Resolve(v[3]).(type) {
+			case String:
+				lookup = l
+			case *Stream:
+				lookup, _ = f.StreamData(l)
+			}
+			return colorSpace{components: 1, indexed: true, base: base.components, lookup: lookup, cmyk: base.cmyk}, nil
+		}
+		return colorSpace{}, fmt.Errorf("unsupported color space %s", family)
+	}
+	return colorSpace{}, fmt.Errorf("missing color space")
+}
+
+// encodePNG converts decoded image samples to a PNG file. Image masks and
+// gray images become gray PNGs; color images become RGB.
+func (f *File) encodePNG(s *Stream, data []byte, width, height int) ([]byte, error) {
+	bpc := 1
+	cs := colorSpace{components: 1}
+	mask, _ := f.Resolve(s.Dict["ImageMask"]).(bool)
+	if !mask {
+		v, _ := f.number(s.Dict["BitsPerComponent"])
+		bpc = int(v)
+		var err error
+		if cs, err = f.colorSpace(s.Dict["ColorSpace"]); err != nil {
+			return nil, err
+		}
+	}
+	if bpc != 1 && bpc != 2 && bpc != 4 && bpc != 8 {
+		return nil, fmt.Errorf("unsupported bits per component %d", bpc)
+	}
+	rowBytes := (width*cs.components*bpc + 7) / 8
+	if len(data) < rowBytes*height {
+		return nil, fmt.Errorf("image data truncated")
+	}
+
+	// A Decode array of [1 0] inverts the samples; image masks paint their
+	// 0 samples black by default
+	inverted := mask
+	if d, ok := f.Resolve(s.Dict["Decode"]).(Array); ok && len(d) >= 2 && !cs.indexed {
+		lo, _ := f.number(d[0])
+		hi, _ := f.number(d[1])
+		if lo > hi {
+			inverted = !mask
+		}
+	}
+
+	maxSample := (1 << bpc) - 1
+	sample := func(row []byte, i int) int {
+		switch bpc {
+		case 8:
+			return int(row[i])
+		default:
+			bit := i * bpc
+			return int(row[bit/8]>>(8-bpc-bit%8)) & maxSample
+		}
+	}
+	scale := func(v int) uint8 {
+		if inverted {
+			v = maxSample - v
+		}
+		return uint8(v * 255 / maxSample)
+	}
+
+	var img image.Image
+	if cs.components == 1 && !cs.indexed {
+		gray := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			row := data[y*rowBytes:]
+			for x := 0; x < width; x++ {
+				gray.Pix[y*gray.Stride+x] = scale(sample(row, x))
+			}
+		}
+		img = gray
+	} else {
+		rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			row := data[y*rowBytes:]
+			for x := 0; x < width; x++ {
+				var c []uint8
+				if cs.indexed {
+					i := sample(row, x) * cs.base
+					c = make([]uint8, cs.base)
+					if i+cs.base <= len(cs.lookup) {
+						copy(c, cs.lookup[i:i+cs.base])
+					}
+				} else {
+					c = make([]uint8, cs.components)
+					for j := range c {
+						c[j] = scale(sample(row, x*cs.components+j))
+					}
+				}
+				rgba.Set(x, y, toRGBA(c, cs.cmyk))
+			}
+		}
+		img = rgba
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// toRGBA converts gray, RGB or CMYK components to a color
+func toRGBA(c []uint8, cmyk bool) color.RGBA {
+	switch {
+	case cmyk && len(c) == 4:
+		r, g, b := color.CMYKToRGB(c[0], c[1], c[2], c[3])
+		return color.RGBA{r, g, b, 255}
+	case len(c) >= 3:
+		return color.RGBA{c[0], c[1], c[2], 255}
+	case len(c) == 1:
+		return color.RGBA{c[0], c[0], c[0], 255}
+	}
+	return color.RGBA{A: 255}
+}
@@ -0,0 +1,186 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Info is the document information of a PDF file, from its Info dictionary
+// or, where that is empty, its XMP metadata
+type Info struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string // Application that created the original document
+	Producer     string // Application that produced the PDF
+	CreationDate time.Time
+	ModDate      time.Time
+	XMP          map[string]string // XMP properties by prefixed name, e.g. "xmpMM:DocumentID"
+}
+
+// xmpPrefixes are the usual prefixes of XMP namespaces
+var xmpPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":                 "dc",
+	"http://ns.adobe.com/xap/1.0/":                     "xmp",
+	"http://ns.adobe.com/pdf/1.3/":                     "pdf",
+	"http://ns.adobe.com/xap/1.0/mm/":                  "xmpMM",
+	"http://ns.adobe.com/xap/1.0/rights/":              "xmpRights",
+	"http://ns.adobe.com/photoshop/1.0/":               "photoshop",
+	"http://ns.adobe.com/pdfx/1.3/":                    "pdfx",
+	"http://www.aiim.org/pdfa/ns/id/":                  "pdfaid",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceEvent#": "stEvt",
+}
+
+const nsRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// Info returns the document information
+func (f *File) Info() Info {
+	info := Info{XMP: make(map[string]string)}
+	if d := f.dict(f.trailer["Info"]); d != nil {
+		text := func(key Name) string {
+			s, _ := f.Resolve(d[key]).(String)
+			return strings.TrimSpace(decodeTextString(s))
+		}
+		info.Title, info.Author, info.Subject = text("Title"), text("Author"), text("Subject")
+		info.Keywords, info.Creator, info.Producer = text("Keywords"), text("Creator"), text("Producer")
+		info.CreationDate, info.ModDate = ParseDate(text("CreationDate")), ParseDate(text("ModDate"))
+	}
+
+	if s, ok := f.Resolve(f.catalog()["Metadata"]).(*Stream); ok {
+		if data, err := f.StreamData(s); err == nil {
+			info.XMP = parseXMP(data)
+		}
+	}
+	fill := func(field *string, keys ...string) {
+		for _, k := range keys {
+			if *field == "" {
+				*field = info.XMP[k]
+			}
+		}
+	}
+	fill(&info.Title, "dc:title")
+	fill(&info.Author, "dc:creator")
+	fill(&info.Subject, "dc:description")
+	fill(&info.Keywords, "pdf:Keywords", "dc:subject")
+	fill(&info.Creator, "xmp:CreatorTool")
+	fill(&info.Producer, "pdf:Producer")
+	if info.CreationDate.IsZero() {
+		info.CreationDate = parseXMPDate(info.XMP["xmp:CreateDate"])
+	}
+	if info.ModDate.IsZero() {
+		info.ModDate = parseXMPDate(info.XMP["xmp:ModifyDate"])
+	}
+	return info
+}
+
+// pdfDate matches a PDF date: D:YYYYMMDDHHmmSSOHH'mm', all but the year
+// optional
+var pdfDate = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?\s*([Zz+-])?(\d{2})?'?(\d{2})?'?`)
+
+// ParseDate parses a PDF date, returning the zero time if it is invalid
+func ParseDate(s string) time.Time {
+	m := pdfDate.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}
+	}
+	part := func(i, def int) int {
+		if m[i] == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+	loc := time.UTC
+	if sign := m[7]; sign == "+" || sign == "-" {
+		offset := part(8, 0)*3600 + part(9, 0)*60
+		if sign == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	t := time.Date(part(1, 0), time.Month(part(2, 1)), part(3, 1), part(4, 0), part(5, 0), part(6, 0), 0, loc)
+	if t.Year() < 1900 {
+		return time.Time{}
+	}
+	return t
+}
+
+// parseXMPDate parses an XMP date, which may omit the time or zone
+func parseXMPDate(s string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04Z07:00", "2006-01-02T15:04", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseXMP reads the simple properties of an XMP packet. Values of
+// alternatives, sequences and bags are joined with "; ".
+func parseXMP(data []byte) map[string]string {
+	props := make(map[string]string)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	key := func(n xml.Name) string {
+		if p, ok := xmpPrefixes[n.Space]; ok {
+			return p + ":" + n.Local
+		}
+		return n.Local
+	}
+	add := func(k, v string) {
+		v = strings.TrimSpace(v)
+		if v == "" || strings.Contains(props[k], v) {
+			return
+		}
+		if props[k] != "" {
+			v = props[k] + "; " + v
+		}
+		props[k] = v
+	}
+
+	depth, descDepth := 0, -1
+	var property string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return props
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case t.Name.Space == nsRDF && t.Name.Local == "Description":
+				descDepth = depth
+				for _, a := range t.Attr {
+					if a.Name.Space != nsRDF && a.Name.Space != "xmlns" && a.Name.Local != "about" && a.Name.Space != "" {
+						add(key(a.Name), a.Value)
+					}
+				}
+			case descDepth >= 0 && depth == descDepth+1:
+				property = key(t.Name)
+				for _, a := range t.Attr {
+					if a.Name.Space == nsRDF && a.Name.Local == "resource" {
+						add(property, a.Value)
+					}
+				}
+			}
+		case xml.EndElement:
+			if depth == descDepth+1 {
+				property = ""
+			}
+			if depth == descDepth {
+				descDepth = -1
+			}
+			depth--
+		case xml.CharData:
+			if property != "" {
+				add(property, string(t))
+			}
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Object is a PDF object: nil, bool, int64, float64, String, Name, Array,
+// Dict, *Stream or Ref
+type Object interface{}
+
+// Name is a PDF name object, without the leading slash
+type Name string
+
+// String is a PDF string object, as raw bytes
+type String []byte
+
+// Array is a PDF array object
+type Array []Object
+
+// Dict is a PDF dictionary object
+type Dict map[Name]Object
+
+// Ref is a reference to an indirect object
+type Ref struct {
+	Num, Gen int
+}
+
+// Stream is a PDF stream object with its encoded data
+type Stream struct {
+	Dict Dict
+	Raw  []byte
+	ref  Ref // Object the stream belongs to, for decryption
+}
+
+// keyword is a bare word such as obj, R or a content stream operator, or a
+// delimiter such as [ or <<
+type keyword string
+
+// lexer reads PDF tokens and objects from data
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+// isWhite reports whether c is PDF whitespace
+func isWhite(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+// isDelim reports whether c is a PDF delimiter
+func isDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace skips whitespace and comments
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isWhite(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// errEOF is returned when the data ends before a token
+var errEOF = fmt.Errorf("unexpected end of data")
+
+// token reads the next token: a number, name, string, bool, nil or keyword
+func (l *lexer) token() (Object, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return keyword("<<"), nil
+		}
+		return l.hexString(), nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return keyword(">>"), nil
+		}
+		l.pos++
+		return keyword(">"), nil
+	case c == '[', c == ']', c == '{', c == '}', c == ')':
+		l.pos++
+		return keyword(string(c)), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isWhite(l.data[l.pos]) && !isDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if n, ok := parseNumber(word); ok {
+		return n, nil
+	}
+	return keyword(word), nil
+}
+
+// parseNumber parses an integer or real, leniently as PDF readers do
+func parseNumber(word string) (Object, bool) {
+	if word == "" || !(word[0] >= '0' && word[0] <= '9' || word[0] == '-' || word[0] == '+' || word[0] == '.') {
+		return nil, false
+	}
+	if i, err := strconv.ParseInt(word, 10, 64); err == nil {
+		return i, true
+	}
+	// Tolerate doubled signs such as "--5" written by some producers
+	for len(word) > 1 && (word[0] == '-' || word[0] == '+') && (word[1] == '-' || word[1] == '+') {
+		word = word[1:]
+	}
+	if f, err := strconv.ParseFloat(word, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// name reads a name, decoding #xx escapes
+func (l *lexer) name() Name {
+	l.pos++ // Skip the slash
+	var b []byte
+	for l.pos < len(l.data) && !isWhite(l.data[l.pos]) && !isDelim(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return Name(b)
+}
+
+// literalString reads a string in parentheses
+func (l *lexer) literalString() String {
+	l.pos++ // Skip the opening parenthesis
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return String(b)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return String(b)
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue // Line continuation
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		case '\r':
+			// End of line markers in strings are read as a line feed
+			if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			c = '\n'
+		}
+		b = append(b, c)
+	}
+	return String(b)
+}
+
+// hexString reads a string of hexadecimal digits in angle brackets
+func (l *lexer) hexString() String {
+	l.pos++ // Skip the opening bracket
+	var b []byte
+	var digit byte
+	half := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		v, ok := hexValue(c)
+		if !ok {
+			continue
+		}
+		if half {
+			b = append(b, digit<<4|v)
+		} else {
+			digit = v
+		}
+		half = !half
+	}
+	if half {
+		b = append(b, digit<<4)
+	}
+	return String(b)
+}
+
+// hexValue returns the value of a hexadecimal digit
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// object reads a complete object, including arrays, dictionaries and
+// references. Keywords other than delimiters are returned as they are.
+func (l *lexer) object() (Object, error) {
+	return l.objectDepth(0)
+}
+
+// maxNesting limits how deeply arrays and dictionaries may nest
+const maxNesting = 256
+
+func (l *lexer) objectDepth(depth int) (Object, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("objects nested too deeply")
+	}
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case keyword:
+		switch t {
+		case "[":
+			var a Array
+			for {
+				l.skipSpace()
+				if l.pos < len(l.data) && l.data[l.pos] == ']' {
+					l.pos++
+					return a, nil
+				}
+				v, err := l.objectDepth(depth + 1)
+				if err != nil {
+					return a, err
+				}
+				if k, ok := v.(keyword); ok && (k == ">>" || k == "endobj") {
+					return a, nil // Unterminated array
+				}
+				a = append(a, v)
+			}
+		case "<<":
+			d := make(Dict)
+			for {
+				k, err := l.objectDepth(depth + 1)
+				if err != nil {
+					return d, err
+				}
+				switch key := k.(type) {
+				case keyword:
+					if key == ">>" || key == "endobj" || key == "stream" {
+						if key != ">>" {
+							l.pos -= len(key) // Unterminated dictionary
+						}
+						return d, nil
+					}
+					continue
+				case Name:
+					v, err := l.objectDepth(depth + 1)
+					if err != nil {
+						return d, err
+					}
+					if kw, ok := v.(keyword); ok && kw == ">>" {
+						return d, nil
+					}
+					d[key] = v
+				}
+			}
+		}
+		return t, nil
+	case int64:
+		// An integer may start a reference: num gen R
+		save := l.pos
+		gen, err := l.token()
+		if g, ok := gen.(int64); ok && err == nil {
+			if r, err := l.token(); err == nil && r == keyword("R") {
+				return Ref{Num: int(t), Gen: int(g)}, nil
+			}
+		}
+		l.pos = save
+		return t, nil
+	}
+	return tok, nil
+}
+
+// hasKeywordAt reports whether data holds word at pos, followed by a
+// delimiter or whitespace
+func hasKeywordAt(data []byte, pos int, word string) bool {
+	if pos < 0 || pos+len(word) > len(data) || !bytes.Equal(data[pos:pos+len(word)], []byte(word)) {
+		return false
+	}
+	end := pos + len(word)
+	return end == len(data) || isWhite(data[end]) || isDelim(data[end])
+}
@@ -0,0 +1,591 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// maxFileSize limits the PDF files that are read into memory
+const maxFileSize = 1 << 30
+
+// ErrPasswordProtected is returned for encrypted files that cannot be
+// opened without a password
+var ErrPasswordProtected = errors.New("PDF is password protected")
+
+// File is a PDF file opened for reading
+type File struct {
+	Version string // Version in the file header, e.g. "1.7"
+
+	data      []byte
+	xref      map[int]xrefEntry
+	trailer   Dict
+	objects   map[int]Object
+	loading   map[int]bool
+	recovered map[int]xrefEntry // Objects found by scanning, when the xref is damaged
+	crypt     *decrypter
+}
+
+// xrefEntry locates an object in the file or in an object stream
+type xrefEntry struct {
+	offset   int
+	gen      int
+	inStream bool
+	stream   int // Object stream holding the object
+	index    int // Index of the object in its stream
+}
+
+// Open reads a PDF file
+func Open(path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("PDF is larger than %d MB", maxFileSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads a PDF file held in memory. Files with a damaged cross-reference
+// table are recovered by scanning for objects.
+func Parse(data []byte) (*File, error) {
+	header := bytes.Index(data[:min(len(data), 1024)], []byte("%PDF-"))
+	if header < 0 {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+	f := &File{
+		data:    data,
+		xref:    make(map[int]xrefEntry),
+		objects: make(map[int]Object),
+		loading: make(map[int]bool),
+	}
+	if m := regexp.MustCompile(`^%PDF-(\d\.\d)`).FindSubmatch(data[header:]); m != nil {
+		f.Version = string(m[1])
+	}
+
+	if err := f.readXrefChain(); err != nil || f.trailer["Root"] == nil {
+		f.recover()
+	}
+	if f.trailer == nil || f.trailer["Root"] == nil {
+		return nil, fmt.Errorf("PDF has no document catalog")
+	}
+
+	// A catalog version overrides the header
+	if v, ok := f.catalog()["Version"].(Name); ok && v > Name(f.Version) {
+		f.Version = string(v)
+	}
+
+	if enc := f.trailer["Encrypt"]; enc != nil {
+		crypt, err := newDecrypter(f, enc)
+		if err != nil {
+			return nil, err
+		}
+		f.crypt = crypt
+		f.objects = make(map[int]Object) // Objects read so far were not decrypted
+	}
+	return f, nil
+}
+
+// Encrypted reports whether the file is encrypted
+func (f *File) Encrypted() bool {
+	return f.trailer["Encrypt"] != nil
+}
+
+// Trailer returns the trailer dictionary
+func (f *File) Trailer() Dict {
+	return f.trailer
+}
+
+// readXrefChain reads the cross-reference sections from startxref, following
+// /Prev links to older sections
+func (f *File) readXrefChain() error {
+	at := bytes.LastIndex(f.data, []byte("startxref"))
+	if at < 0 {
+		return fmt.Errorf("no startxref")
+	}
+	l := &lexer{data: f.data, pos: at + len("startxref")}
+	tok, err := l.token()
+	offset, ok := tok.(int64)
+	if err != nil || !ok {
+		return fmt.Errorf("invalid startxref")
+	}
+
+	seen := make(map[int64]bool)
+	for offset > 0 && !seen[offset] {
+		seen[offset] = true
+		trailer, err := f.readXref(int(offset))
+		if err != nil {
+			return err
+		}
+		if f.trailer == nil {
+			f.trailer = trailer
+		}
+		// Hybrid files list objects in compressed streams separately
+		if stm, ok := trailer["XRefStm"].(int64); ok && !seen[stm] {
+			seen[stm] = true
+			if _, err := f.readXref(int(stm)); err != nil {
+				return err
+			}
+		}
+		prev, _ := trailer["Prev"].(int64)
+		offset = prev
+	}
+	return nil
+}
+
+// readXref reads one cross-reference section, a table or a stream, and
+// returns its trailer. Entries already read from newer sections are kept.
+func (f *File) readXref(offset int) (Dict, error) {
+	if offset < 0 || offset >= len(f.data) {
+		return nil, fmt.Errorf("xref offset %d out of range", offset)
+	}
+	l := &lexer{data: f.data, pos: offset}
+	l.skipSpace()
+	if hasKeywordAt(f.data, l.pos, "xref") {
+		l.pos += len("xref")
+		return f.readXrefTable(l)
+	}
+
+	_, obj, err := f.parseIndirect(l.pos)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xref section: %w", err)
+	}
+	s, ok := obj.(*Stream)
+	if !ok || s.Dict["Type"] != Name("XRef") {
+		return nil, fmt.Errorf("invalid xref section at %d", offset)
+	}
+	return s.Dict, f.readXrefStream(s)
+}
+
+// readXrefTable reads a classic cross-reference table and its trailer
+func (f *File) readXrefTable(l *lexer) (Dict, error) {
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok == keyword("trailer") {
+			obj, err := l.object()
+			if err != nil {
+				return nil, fmt.Errorf("invalid trailer: %w", err)
+			}
+			trailer, ok := obj.(Dict)
+			if !ok {
+				return nil, fmt.Errorf("invalid trailer")
+			}
+			return trailer, nil
+		}
+		start, ok := tok.(int64)
+		if !ok {
+			return nil, fmt.Errorf("invalid xref subsection")
+		}
+		countTok, err := l.token()
+		count, ok := countTok.(int64)
+		if err != nil || !ok {
+			return nil, fmt.Errorf("invalid xref subsection")
+		}
+		for i := 0; i < int(count); i++ {
+			offTok, _ := l.token()
+			genTok, _ := l.token()
+			kind, _ := l.token()
+			off, ok1 := offTok.(int64)
+			gen, ok2 := genTok.(int64)
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("invalid xref entry")
+			}
+			num := int(start) + i
+			if _, exists := f.xref[num]; exists || kind != keyword("n") {
+				continue
+			}
+			f.xref[num] = xrefEntry{offset: int(off), gen: int(gen)}
+		}
+	}
+}
+
+// readXrefStream reads the entries of a cross-reference stream
+func (f *File) readXrefStream(s *Stream) error {
+	data, err := f.decodeStream(s, false)
+	if err != nil {
+		return fmt.Errorf("failed to decode xref stream: %w", err)
+	}
+	w, _ := s.Dict["W"].(Array)
+	if len(w) != 3 {
+		return fmt.Errorf("invalid xref stream widths")
+	}
+	var widths [3]int
+	for i := range widths {
+		n, _ := w[i].(int64)
+		if n < 0 || n > 8 {
+			return fmt.Errorf("invalid xref stream widths")
+		}
+		widths[i] = int(n)
+	}
+	rowSize := widths[0] + widths[1] + widths[2]
+	if rowSize == 0 {
+		return fmt.Errorf("invalid xref stream widths")
+	}
+
+	index, _ := s.Dict["Index"].(Array)
+	if index == nil {
+		size, _ := s.Dict["Size"].(int64)
+		index = Array{int64(0), size}
+	}
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int64)
+		count, _ := index[i+1].(int64)
+		for j := 0; j < int(count) && pos+rowSize <= len(data); j++ {
+			row := data[pos : pos+rowSize]
+			pos += rowSize
+			fields := [3]int{1, 0, 0} // The type defaults to 1 when its width is 0
+			for k, o := 0, 0; k < 3; k++ {
+				if widths[k] > 0 {
+					fields[k] = int(beUint(row[o : o+widths[k]]))
+				}
+				o += widths[k]
+			}
+			num := int(start) + j
+			if _, exists := f.xref[num]; exists {
+				continue
+			}
+			switch fields[0] {
+			case 1:
+				f.xref[num] = xrefEntry{offset: fields[1], gen: fields[2]}
+			case 2:
+				f.xref[num] = xrefEntry{inStream: true, stream: fields[1], index: fields[2]}
+			}
+		}
+	}
+	return nil
+}
+
+// beUint decodes a big-endian unsigned integer
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// objectHeader matches the start of an indirect object
+var objectHeader = regexp.MustCompile(`(\d+)[ \t\r\n\f\x00]+(\d+)[ \t\r\n\f\x00]+obj\b`)
+
+// recover rebuilds the cross-reference table by scanning the file for
+// objects, later definitions replacing earlier ones as incremental updates do
+func (f *File) recover() {
+	f.recovered = make(map[int]xrefEntry)
+	for _, m := range objectHeader.FindAllSubmatchIndex(f.data, -1) {
+		num, _ := strconv.Atoi(string(f.data[m[2]:m[3]]))
+		gen, _ := strconv.Atoi(string(f.data[m[4]:m[5]]))
+		f.recovered[num] = xrefEntry{offset: m[0], gen: gen}
+	}
+	f.xref = f.recovered
+	f.objects = make(map[int]Object)
+
+	// Merge the trailers found, newest last
+	trailer := make(Dict)
+	for _, at := range allIndexes(f.data, []byte("trailer")) {
+		l := &lexer{data: f.data, pos: at + len("trailer")}
+		if obj, err := l.object(); err == nil {
+			if d, ok := obj.(Dict); ok {
+				for k, v := range d {
+					trailer[k] = v
+				}
+			}
+		}
+	}
+
+	// Include objects in object streams and the trailers of xref streams
+	var streams []int
+	for num := range f.recovered {
+		streams = append(streams, num)
+	}
+	for _, num := range streams {
+		s, ok := f.object(num).(*Stream)
+		if !ok {
+			continue
+		}
+		switch s.Dict["Type"] {
+		case Name("ObjStm"):
+			data, err := f.decodeStream(s, true)
+			if err != nil {
+				continue
+			}
+			for i, o := range objectStreamIndex(s, data) {
+				if _, exists := f.recovered[o[0]]; !exists {
+					f.recovered[o[0]] = xrefEntry{inStream: true, stream: num, index: i}
+				}
+			}
+		case Name("XRef"):
+			for _, k := range []Name{"Root", "Info", "Encrypt", "ID"} {
+				if v, ok := s.Dict[k]; ok && trailer[k] == nil {
+					trailer[k] = v
+				}
+			}
+		}
+	}
+
+	if trailer["Root"] == nil {
+		for num := range f.recovered {
+			if d, ok := f.object(num).(Dict); ok && d["Type"] == Name("Catalog") {
+				trailer["Root"] = Ref{Num: num, Gen: f.recovered[num].gen}
+				break
+			}
+		}
+	}
+	f.trailer = trailer
+}
+
+// allIndexes returns the positions of every occurrence of sep in data
+func allIndexes(data, sep []byte) []int {
+	var positions []int
+	for i := 0; ; {
+		j := bytes.Index(data[i:], sep)
+		if j < 0 {
+			return positions
+		}
+		positions = append(positions, i+j)
+		i += j + len(sep)
+	}
+}
+
+// Resolve follows a reference to the object it refers to
+func (f *File) Resolve(obj Object) Object {
+	for depth := 0; depth < 32; depth++ {
+		ref, ok := obj.(Ref)
+		if !ok {
+			return obj
+		}
+		obj = f.object(ref.Num)
+	}
+	return nil
+}
+
+// object returns an indirect object, or nil if it cannot be read
+func (f *File) object(num int) Object {
+	if obj, ok := f.objects[num]; ok {
+		return obj
+	}
+	if f.loading[num] {
+		return nil // Reference cycle
+	}
+	f.loading[num] = true
+	defer delete(f.loading, num)
+
+	obj, err := f.load(num)
+	if err != nil && f.recovered == nil {
+		// The xref may point to the wrong place; look for the object itself
+		f.recoverObject(num)
+		obj, _ = f.load(num)
+	}
+	f.objects[num] = obj
+	return obj
+}
+
+// recoverObject finds an object by scanning the file when the
+// cross-reference table does not locate it correctly
+func (f *File) recoverObject(num int) {
+	pattern := regexp.MustCompile(`(?:^|[^0-9])` + strconv.Itoa(num) + `[ \t\r\n\f\x00]+(\d+)[ \t\r\n\f\x00]+obj\b`)
+	matches := pattern.FindAllSubmatchIndex(f.data, -1)
+	if len(matches) == 0 {
+		return
+	}
+	m := matches[len(matches)-1]
+	start := m[0]
+	if f.data[start] < '0' || f.data[start] > '9' {
+		start++
+	}
+	gen, _ := strconv.Atoi(string(f.data[m[2]:m[3]]))
+	f.xref[num] = xrefEntry{offset: start, gen: gen}
+}
+
+// load reads an object from the file
+func (f *File) load(num int) (Object, error) {
+	entry, ok := f.xref[num]
+	if !ok {
+		return nil, fmt.Errorf("object %d not found", num)
+	}
+	if entry.inStream {
+		return f.loadFromStream(num, entry)
+	}
+	ref, obj, err := f.parseIndirect(entry.offset)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Num != num {
+		return nil, fmt.Errorf("object %d not at offset %d", num, entry.offset)
+	}
+	if f.crypt != nil && !f.crypt.exempt(num) {
+		obj = f.crypt.decryptStrings(obj, ref)
+	}
+	return obj, nil
+}
+
+// parseIndirect parses "num gen obj ... endobj" at offset, reading the
+// stream data of stream objects
+func (f *File) parseIndirect(offset int) (Ref, Object, error) {
+	if offset < 0 || offset >= len(f.data) {
+		return Ref{}, nil, fmt.Errorf("offset %d out of range", offset)
+	}
+	l := &lexer{data: f.data, pos: offset}
+	numTok, _ := l.token()
+	genTok, _ := l.token()
+	objTok, _ := l.token()
+	num, ok1 := numTok.(int64)
+	gen, ok2 := genTok.(int64)
+	if !ok1 || !ok2 || objTok != keyword("obj") {
+		return Ref{}, nil, fmt.Errorf("no object at offset %d", offset)
+	}
+	ref := Ref{Num: int(num), Gen: int(gen)}
+
+	obj, err := l.object()
+	if err != nil && err != errEOF {
+		return ref, nil, err
+	}
+	dict, ok := obj.(Dict)
+	if !ok {
+		return ref, obj, nil
+	}
+	save := l.pos
+	if tok, _ := l.token(); tok != keyword("stream") {
+		l.pos = save
+		return ref, dict, nil
+	}
+	return ref, &Stream{Dict: dict, Raw: f.streamData(l.pos, dict), ref: ref}, nil
+}
+
+// streamData returns the encoded data of a stream starting after the
+// stream keyword, using /Length when it is correct and otherwise the
+// endstream keyword
+func (f *File) streamData(pos int, dict Dict) []byte {
+	if pos < len(f.data) && f.data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(f.data) && f.data[pos] == '\n' {
+		pos++
+	}
+
+	// Avoid resolving a length that is itself being loaded
+	var length int64 = -1
+	switch v := dict["Length"].(type) {
+	case int64:
+		length = v
+	case Ref:
+		if !f.loading[v.Num] {
+			length, _ = f.Resolve(v).(int64)
+		}
+	}
+	if length >= 0 && pos+int(length) <= len(f.data) {
+		end := pos + int(length)
+		l := &lexer{data: f.data, pos: end}
+		l.skipSpace()
+		if hasKeywordAt(f.data, l.pos, "endstream") {
+			return f.data[pos:end]
+		}
+	}
+
+	end := bytes.Index(f.data[pos:], []byte("endstream"))
+	if end < 0 {
+		return f.data[pos:]
+	}
+	data := f.data[pos : pos+end]
+	// The end of line before endstream is not part of the data
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return data
+}
+
+// loadFromStream reads an object stored in an object stream
+func (f *File) loadFromStream(num int, entry xrefEntry) (Object, error) {
+	s, ok := f.object(entry.stream).(*Stream)
+	if !ok {
+		return nil, fmt.Errorf("object stream %d not found", entry.stream)
+	}
+	data, err := f.decodeStream(s, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode object stream %d: %w", entry.stream, err)
+	}
+	index := objectStreamIndex(s, data)
+	offset := -1
+	if entry.index < len(index) && index[entry.index][0] == num {
+		offset = index[entry.index][1]
+	} else {
+		// The index in the xref stream is wrong; search by number
+		for _, o := range index {
+			if o[0] == num {
+				offset = o[1]
+			}
+		}
+	}
+	first, _ := s.Dict["First"].(int64)
+	if offset >= 0 && int(first)+offset <= len(data) {
+		l := &lexer{data: data, pos: int(first) + offset}
+		obj, err := l.object()
+		if err != nil && err != errEOF {
+			return nil, err
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("object %d not in stream %d", num, entry.stream)
+}
+
+// objectStreamIndex returns the object numbers and offsets listed at the
+// start of an object stream
+func objectStreamIndex(s *Stream, data []byte) [][2]int {
+	n, _ := s.Dict["N"].(int64)
+	first, _ := s.Dict["First"].(int64)
+	if first <= 0 || int(first) > len(data) {
+		return nil
+	}
+	l := &lexer{data: data[:first]}
+	var index [][2]int
+	for i := 0; i < int(n); i++ {
+		numTok, err1 := l.token()
+		offTok, err2 := l.token()
+		num, ok1 := numTok.(int64)
+		off, ok2 := offTok.(int64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			break
+		}
+		index = append(index, [2]int{int(num), int(off)})
+	}
+	return index
+}
+
+// catalog returns the document catalog
+func (f *File) catalog() Dict {
+	d, _ := f.Resolve(f.trailer["Root"]).(Dict)
+	return d
+}
+
+// dict resolves an object that should be a dictionary, returning the
+// dictionary of a stream
+func (f *File) dict(obj Object) Dict {
+	switch v := f.Resolve(obj).(type) {
+	case Dict:
+		return v
+	case *Stream:
+		return v.Dict
+	}
+	return nil
+}
+
+// number resolves an object that should be a number
+func (f *File) number(obj Object) (float64, bool) {
+	switch v := f.Resolve(obj).(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,704 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Glyph extent above and below the baseline, in units of the font size,
+// covered by redaction boxes
+const (
+	boxAscent  = 0.9
+	boxDescent = 0.25
+)
+
+// replacementFontSize is the size of the text written on replaced pages
+const replacementFontSize = 9
+
+// TextRange is a range of bytes in the text of a page, as returned by
+// PageText
+type TextRange struct {
+	Start, End int
+}
+
+// RedactOptions selects the text removed by Redact
+type RedactOptions struct {
+	Ranges map[int][]TextRange // Text to remove, by page number from 1
+
+	// Replace gives pages whose content is replaced by text, such as
+	// scanned pages whose images cannot be redacted. Ranges for these pages
+	// refer to the replacement text.
+	Replace map[int]string
+}
+
+// shownGlyph records a glyph shown by a content stream, where it came from
+// and where its text went
+type shownGlyph struct {
+	stream     Ref // Form XObject, zero for the page content
+	op, item   int // Operation and element of a TJ array
+	start, end int // Bytes of the code in the shown string
+	outStart   int // Text written for the glyph in the extractor output
+	outEnd     int
+	advance    float64    // Displacement in thousandths of the font size, as TJ adjusts
+	box        [4]float64 // x0, y0, x1, y1 in default user space
+}
+
+// record notes a glyph as it is shown; tx is its displacement before
+// horizontal scaling
+func (e *textExtractor) record(g glyph, pos, outStart int, tx float64) {
+	sg := shownGlyph{
+		stream:   e.stream,
+		op:       e.opIndex,
+		item:     e.item,
+		start:    pos,
+		end:      pos + g.n,
+		outStart: outStart,
+		outEnd:   e.out.Len(),
+	}
+	if fs := e.gs.fontSize; fs != 0 {
+		sg.advance = tx * 1000 / fs
+		m := e.tm.multiply(e.gs.ctm)
+		width := tx * e.gs.scale
+		low, high := e.gs.rise-boxDescent*fs, e.gs.rise+boxAscent*fs
+		sg.box = [4]float64{1e9, 1e9, -1e9, -1e9}
+		for _, c := range [][2]float64{{0, low}, {width, low}, {0, high}, {width, high}} {
+			x := c[0]*m[0] + c[1]*m[2] + m[4]
+			y := c[0]*m[1] + c[1]*m[3] + m[5]
+			sg.box = [4]float64{min(sg.box[0], x), min(sg.box[1], y), max(sg.box[2], x), max(sg.box[3], y)}
+		}
+	}
+	*e.glyphs = append(*e.glyphs, sg)
+}
+
+// trimmedIndex returns the text of the extractor output as text returns
+// it, with the position in the output of each of its bytes
+func trimmedIndex(raw string) (string, []int) {
+	var text strings.Builder
+	var index []int
+	offset := 0
+	for i, line := range strings.Split(raw, "\n") {
+		if i > 0 {
+			text.WriteByte('\n')
+			index = append(index, offset-1)
+		}
+		kept := strings.TrimRight(line, " ")
+		text.WriteString(kept)
+		for j := range kept {
+			index = append(index, offset+j)
+		}
+		offset += len(line) + 1
+	}
+	s := text.String()
+	lead := len(s) - len(strings.TrimLeftFunc(s, unicode.IsSpace))
+	trail := len(s) - len(strings.TrimRightFunc(s, unicode.IsSpace))
+	if lead == len(s) {
+		return "", nil
+	}
+	return s[lead : len(s)-trail], index[lead : len(s)-trail]
+}
+
+// Redact writes a copy of the file without the text in the given ranges,
+// covering where it was drawn with black boxes. The copy is written out
+// in full and decrypted, so no earlier revision of a page survives in it,
+// and the annotations, thumbnails, document information, XMP metadata,
+// outlines, attachments and structure tree, which may repeat the text,
+// are left out. A form XObject with removed text is copied for the page, so
+// that the other pages drawing it are unchanged, and each drawing of a
+// removed glyph on the page is covered.
+func (f *File) Redact(w io.Writer, opts RedactOptions) error {
+	pages := f.pages()
+	size := 0
+	for num := range f.xref {
+		size = max(size, num+1)
+	}
+	if n, ok := f.number(f.trailer["Size"]); ok {
+		size = max(size, int(n))
+	}
+	next := func() Ref {
+		size++
+		return Ref{Num: size - 1}
+	}
+
+	overrides := make(map[int]Object)
+	fonts := make(map[Ref]*font)
+	for i, p := range pages {
+		number := i + 1
+		if p.ref.Num == 0 {
+			return fmt.Errorf("page %d is not an indirect object", number)
+		}
+		page := copyDict(p.dict)
+		for _, key := range []Name{"Annots", "Thumb", "PieceInfo", "Metadata", "StructParents", "B"} {
+			delete(page, key)
+		}
+
+		ranges := opts.Ranges[number]
+		if text, ok := opts.Replace[number]; ok {
+			content, err := replacementContent(text, ranges, f.pageBox(p.dict))
+			if err != nil {
+				return fmt.Errorf("page %d: %w", number, err)
+			}
+			ref := next()
+			overrides[ref.Num] = newStream(content)
+			page["Contents"] = ref
+			page["Resources"] = Dict{"Font": Dict{"R1": Dict{
+				"Type": Name("Font"), "Subtype": Name("Type1"), "BaseFont": Name("Courier"), "Encoding": Name("WinAnsiEncoding"),
+			}}}
+		} else if len(ranges) > 0 {
+			var glyphs []shownGlyph
+			e := &textExtractor{file: f, fonts: fonts, gs: graphicsState{ctm: identity, scale: 1}, glyphs: &glyphs}
+			content := f.pageContent(p.dict)
+			e.run(content, p.resources, 0)
+			removed, err := selectGlyphs(e.out.String(), glyphs, ranges)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", number, err)
+			}
+
+			// A glyph of a form is removed once from its copy, and covered
+			// wherever the form is drawn on the page
+			var pageRemovals []shownGlyph
+			formRemovals := make(map[Ref][]shownGlyph)
+			seen := make(map[glyphKey]bool)
+			for _, g := range removed {
+				switch {
+				case g.stream.Num == 0:
+					pageRemovals = append(pageRemovals, g)
+				case !seen[g.key()]:
+					seen[g.key()] = true
+					formRemovals[g.stream] = append(formRemovals[g.stream], g)
+				}
+			}
+			var covered []shownGlyph
+			for _, g := range glyphs {
+				if seen[g.key()] {
+					covered = append(covered, g)
+				}
+			}
+			for _, g := range removed {
+				if g.stream.Num == 0 {
+					covered = append(covered, g)
+				}
+			}
+
+			if len(formRemovals) > 0 {
+				resources, _, err := f.copyForms(p.resources, formRemovals, make(map[Ref]Ref), next, overrides, 0)
+				if err != nil {
+					return fmt.Errorf("page %d: %w", number, err)
+				}
+				page["Resources"] = resources
+			}
+			var b bytes.Buffer
+			b.WriteString("q\n")
+			b.Write(rewriteContent(content, pageRemovals))
+			b.WriteString("Q\n")
+			b.WriteString(boxContent(covered))
+			ref := next()
+			overrides[ref.Num] = newStream(b.Bytes())
+			page["Contents"] = ref
+		}
+		overrides[p.ref.Num] = page
+	}
+
+	// Keep only what the catalog needs to display the pages
+	root, ok := f.trailer["Root"].(Ref)
+	if !ok {
+		return fmt.Errorf("PDF has no catalog")
+	}
+	catalog := Dict{}
+	for _, key := range []Name{"Type", "Pages", "PageLabels", "PageLayout", "ViewerPreferences", "Lang"} {
+		if v, ok := f.catalog()[key]; ok {
+			catalog[key] = v
+		}
+	}
+	overrides[root.Num] = catalog
+	info := next()
+	overrides[info.Num] = Dict{
+		"Producer": String("GoInspectorGadget redaction"),
+		"ModDate":  String(time.Now().Format("D:20060102150405")),
+	}
+	return f.write(w, root, info, size, overrides)
+}
+
+// glyphKey identifies a glyph of a content stream, which a form drawn more
+// than once shows each time
+type glyphKey struct {
+	stream          Ref
+	op, item, start int
+}
+
+func (g shownGlyph) key() glyphKey {
+	return glyphKey{g.stream, g.op, g.item, g.start}
+}
+
+// copyForms returns the resources of a page with each form that has glyphs
+// removed, and each form drawing one, replaced by a redacted copy. The
+// copies are added to overrides and recorded in copies. The boolean
+// reports whether anything was replaced.
+func (f *File) copyForms(resources Dict, removals map[Ref][]shownGlyph, copies map[Ref]Ref, next func() Ref, overrides map[int]Object, depth int) (Dict, bool, error) {
+	xobjects := f.dict(resources["XObject"])
+	if xobjects == nil || depth >= maxFormDepth {
+		return resources, false, nil
+	}
+	replaced := make(Dict)
+	for name, obj := range xobjects {
+		ref, ok := obj.(Ref)
+		if !ok {
+			continue
+		}
+		if c, ok := copies[ref]; ok {
+			replaced[name] = c
+			continue
+		}
+		s, ok := f.Resolve(ref).(*Stream)
+		if !ok || s.Dict["Subtype"] != Name("Form") {
+			continue
+		}
+		formResources, nested, err := f.copyForms(f.dict(s.Dict["Resources"]), removals, copies, next, overrides, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		if !nested && len(removals[ref]) == 0 {
+			continue
+		}
+
+		data, err := f.StreamData(s)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read form %d: %w", ref.Num, err)
+		}
+		if len(removals[ref]) > 0 {
+			data = rewriteContent(data, removals[ref])
+		}
+		form := newStream(data)
+		for k, v := range s.Dict {
+			if k != "Filter" && k != "DecodeParms" && k != "Length" {
+				form.Dict[k] = v
+			}
+		}
+		if nested {
+			form.Dict["Resources"] = formResources
+		}
+		c := next()
+		overrides[c.Num] = form
+		copies[ref] = c
+		replaced[name] = c
+	}
+	if len(replaced) == 0 {
+		return resources, false, nil
+	}
+
+	res := copyDict(resources)
+	x := copyDict(xobjects)
+	for name, c := range replaced {
+		x[name] = c
+	}
+	res["XObject"] = x
+	return res, true, nil
+}
+
+// selectGlyphs returns the glyphs whose text lies in the ranges of the
+// page text. Glyphs without text are removed when they fall inside a range.
+func selectGlyphs(raw string, glyphs []shownGlyph, ranges []TextRange) ([]shownGlyph, error) {
+	text, index := trimmedIndex(raw)
+	var removed []shownGlyph
+	taken := make(map[int]bool)
+	for _, r := range ranges {
+		if r.Start < 0 || r.End > len(text) || r.Start >= r.End {
+			return nil, fmt.Errorf("range %d-%d is outside the page text (%d bytes)", r.Start, r.End, len(text))
+		}
+		start, end := index[r.Start], index[r.End-1]+1
+		for i, g := range glyphs {
+			inside := g.outEnd > start && g.outStart < end
+			if g.outStart == g.outEnd {
+				inside = g.outStart > start && g.outStart < end
+			}
+			if inside && !taken[i] {
+				taken[i] = true
+				removed = append(removed, g)
+			}
+		}
+	}
+	return removed, nil
+}
+
+// rewriteContent rewrites a content stream without the removed glyphs.
+// Strings shown with removed glyphs become TJ arrays in which each removed
+// glyph is replaced by an adjustment of its width, so that the glyphs
+// kept stay where they were.
+func rewriteContent(content []byte, removed []shownGlyph) []byte {
+	byOp := make(map[int][]shownGlyph)
+	for _, g := range removed {
+		byOp[g.op] = append(byOp[g.op], g)
+	}
+	var b bytes.Buffer
+	for i, o := range contentOps(content) {
+		glyphs, ok := byOp[i]
+		if !ok {
+			b.Write(bytes.TrimSpace(content[o.start:o.end]))
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteString(redactShow(o, glyphs))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// redactShow rewrites a text showing operation without some of its glyphs
+func redactShow(o operation, removed []shownGlyph) string {
+	var items Array
+	switch o.op {
+	case "TJ":
+		if len(o.operands) > 0 {
+			items, _ = o.operands[len(o.operands)-1].(Array)
+		}
+	default:
+		if len(o.operands) > 0 {
+			items = Array{o.operands[len(o.operands)-1]}
+		}
+	}
+
+	var out Array
+	adjust := func(n float64) {
+		if len(out) > 0 {
+			if v, ok := out[len(out)-1].(float64); ok {
+				out[len(out)-1] = v + n
+				return
+			}
+		}
+		out = append(out, n)
+	}
+	for i, item := range items {
+		s, ok := item.(String)
+		if !ok {
+			if n, ok := numbers([]Object{item}, 1); ok {
+				adjust(n[0])
+			}
+			continue
+		}
+		var glyphs []shownGlyph
+		for _, g := range removed {
+			if g.item == i {
+				glyphs = append(glyphs, g)
+			}
+		}
+		sort.Slice(glyphs, func(a, b int) bool { return glyphs[a].start < glyphs[b].start })
+		pos := 0
+		for _, g := range glyphs {
+			if g.start > pos {
+				out = append(out, String(s[pos:g.start]))
+			}
+			adjust(-g.advance)
+			pos = max(pos, g.end)
+		}
+		if pos < len(s) {
+			out = append(out, String(s[pos:]))
+		}
+	}
+
+	var b bytes.Buffer
+	switch o.op {
+	case "'":
+		b.WriteString("T* ")
+	case "\"":
+		if len(o.operands) >= 3 {
+			writeObject(&b, o.operands[0])
+			b.WriteString(" Tw ")
+			writeObject(&b, o.operands[1])
+			b.WriteString(" Tc T* ")
+		}
+	}
+	writeObject(&b, out)
+	b.WriteString(" TJ")
+	return b.String()
+}
+
+// boxContent draws black boxes over removed glyphs, joining the boxes of
+// glyphs shown together
+func boxContent(removed []shownGlyph) string {
+	var boxes [][4]float64
+	for i, g := range removed {
+		if g.box[2] <= g.box[0] && g.box[3] <= g.box[1] {
+			continue
+		}
+		if i > 0 && len(boxes) > 0 {
+			prev := removed[i-1]
+			if prev.stream == g.stream && prev.op == g.op && prev.item == g.item && g.start >= prev.end {
+				last := &boxes[len(boxes)-1]
+				*last = [4]float64{min(last[0], g.box[0]), min(last[1], g.box[1]), max(last[2], g.box[2]), max(last[3], g.box[3])}
+				continue
+			}
+		}
+		boxes = append(boxes, g.box)
+	}
+	if len(boxes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("q 0 0 0 rg\n")
+	for _, box := range boxes {
+		fmt.Fprintf(&b, "%.2f %.2f %.2f %.2f re\n", box[0], box[1], box[2]-box[0], box[3]-box[1])
+	}
+	b.WriteString("f Q\n")
+	return b.String()
+}
+
+// replacementContent writes text in Courier on a page, covering the
+// ranges with black boxes in place of their characters
+func replacementContent(text string, ranges []TextRange, box [4]float64) ([]byte, error) {
+	redacted := make([]bool, len(text))
+	for _, r := range ranges {
+		if r.Start < 0 || r.End > len(text) || r.Start >= r.End {
+			return nil, fmt.Errorf("range %d-%d is outside the page text (%d bytes)", r.Start, r.End, len(text))
+		}
+		for i := r.Start; i < r.End; i++ {
+			redacted[i] = true
+		}
+	}
+
+	// Lay the text out in lines of fixed-width characters
+	width, height := box[2]-box[0], box[3]-box[1]
+	margin := min(36, width/10, height/10)
+	columns := max(20, int((width-2*margin)/(replacementFontSize*0.6)))
+	type char struct {
+		r        rune
+		redacted bool
+	}
+	var lines [][]char
+	var line []char
+	for i, r := range text {
+		switch {
+		case r == '\n':
+			lines, line = append(lines, line), nil
+			continue
+		case r == '\t', r == '\f', r == '\r':
+			r = ' '
+		}
+		if len(line) >= columns {
+			lines, line = append(lines, line), nil
+		}
+		line = append(line, char{r, redacted[i]})
+	}
+	lines = append(lines, line)
+
+	size := float64(replacementFontSize)
+	if need := float64(len(lines)) * size * 1.25; need > height-2*margin {
+		size = max(2, size*(height-2*margin)/need)
+	}
+	advance, leading := size*0.6, size*1.25
+
+	var b, boxes strings.Builder
+	y := box[3] - margin - size
+	for _, l := range lines {
+		var s strings.Builder
+		run := -1 // Start of the run of redacted characters
+		for j := 0; j <= len(l); j++ {
+			if j < len(l) && l[j].redacted {
+				if run < 0 {
+					run = j
+				}
+				s.WriteRune(' ')
+				continue
+			}
+			if run >= 0 {
+				x := box[0] + margin + float64(run)*advance
+				fmt.Fprintf(&boxes, "%.2f %.2f %.2f %.2f re\n", x, y-boxDescent*size, float64(j-run)*advance, (boxAscent+boxDescent)*size)
+				run = -1
+			}
+			if j < len(l) {
+				s.WriteRune(l[j].r)
+			}
+		}
+		if strings.TrimSpace(s.String()) != "" {
+			fmt.Fprintf(&b, "BT /R1 %.2f Tf %.2f %.2f Td %s Tj ET\n", size, box[0]+margin, y, literal(s.String()))
+		}
+		y -= leading
+	}
+	if boxes.Len() > 0 {
+		b.WriteString("q 0 0 0 rg\n" + boxes.String() + "f Q\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// pageBox returns the media box of a page, which may be inherited
+func (f *File) pageBox(page Dict) [4]float64 {
+	node := page
+	for depth := 0; node != nil && depth < 64; depth++ {
+		if a, ok := f.Resolve(node["MediaBox"]).(Array); ok {
+			if v, ok := numbers(a, 4); ok && len(a) == 4 {
+				return [4]float64{min(v[0], v[2]), min(v[1], v[3]), max(v[0], v[2]), max(v[1], v[3])}
+			}
+		}
+		node = f.dict(node["Parent"])
+	}
+	return [4]float64{0, 0, PageWidth, PageHeight}
+}
+
+// copyDict returns a shallow copy of a dictionary
+func copyDict(d Dict) Dict {
+	c := make(Dict, len(d))
+	for k, v := range d {
+		c[k] = v
+	}
+	return c
+}
+
+// newStream creates a stream compressed with Flate
+func newStream(data []byte) *Stream {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return &Stream{Dict: Dict{"Filter": Name("FlateDecode")}, Raw: b.Bytes()}
+}
+
+// write writes the objects reachable from the catalog and document
+// information as a new file, replacing those in overrides
+func (f *File) write(w io.Writer, root, info Ref, size int, overrides map[int]Object) error {
+	get := func(num int) Object {
+		if obj, ok := overrides[num]; ok {
+			return obj
+		}
+		return f.object(num)
+	}
+
+	// Find the objects in use
+	used := make(map[int]bool)
+	queue := []int{root.Num, info.Num}
+	var walk func(obj Object)
+	walk = func(obj Object) {
+		switch v := obj.(type) {
+		case Ref:
+			if !used[v.Num] {
+				used[v.Num] = true
+				queue = append(queue, v.Num)
+			}
+		case Array:
+			for _, o := range v {
+				walk(o)
+			}
+		case Dict:
+			for _, o := range v {
+				walk(o)
+			}
+		case *Stream:
+			walk(v.Dict)
+		}
+	}
+	used[root.Num], used[info.Num] = true, true
+	for len(queue) > 0 {
+		num := queue[0]
+		queue = queue[1:]
+		walk(get(num))
+	}
+
+	version := f.Version
+	if version == "" {
+		version = "1.7"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%%PDF-%s\n%%\xE2\xE3\xCF\xD3\n", version)
+	offsets := make([]int, size)
+	for num := 1; num < size; num++ {
+		if !used[num] {
+			continue
+		}
+		obj := get(num)
+		offsets[num] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", num)
+		if s, ok := obj.(*Stream); ok {
+			data := s.Raw
+			if _, isNew := overrides[num]; !isNew && f.crypt != nil && !f.crypt.exemptStream(s) {
+				var err error
+				if data, err = f.crypt.decrypt(data, s.ref, true); err != nil {
+					return fmt.Errorf("failed to decrypt object %d: %w", num, err)
+				}
+			}
+			dict := copyDict(s.Dict)
+			dict["Length"] = int64(len(data))
+			writeObject(&b, dict)
+			b.WriteString("\nstream\n")
+			b.Write(data)
+			b.WriteString("\nendstream")
+		} else {
+			writeObject(&b, obj)
+		}
+		b.WriteString("\nendobj\n")
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := 1; num < size; num++ {
+		if used[num] {
+			fmt.Fprintf(&b, "%010d 00000 n \n", offsets[num])
+		} else {
+			b.WriteString("0000000000 00000 f \n")
+		}
+	}
+	trailer := Dict{"Size": int64(size), "Root": Ref{Num: root.Num}, "Info": Ref{Num: info.Num}}
+	if id, ok := f.Resolve(f.trailer["ID"]).(Array); ok {
+		trailer["ID"] = id
+	}
+	b.WriteString("trailer\n")
+	writeObject(&b, trailer)
+	fmt.Fprintf(&b, "\nstartxref\n%d\n%%%%EOF\n", xref)
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// writeObject serializes a direct object
+func writeObject(b *bytes.Buffer, obj Object) {
+	switch v := obj.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case Name:
+		b.WriteByte('/')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < '!' || c > '~' || c == '#' || isDelim(c) {
+				fmt.Fprintf(b, "#%02X", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	case String:
+		fmt.Fprintf(b, "<%X>", []byte(v))
+	case Array:
+		b.WriteByte('[')
+		for i, o := range v {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeObject(b, o)
+		}
+		b.WriteByte(']')
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+		b.WriteString("<<")
+		for _, k := range keys {
+			writeObject(b, Name(k))
+			b.WriteByte(' ')
+			writeObject(b, v[Name(k)])
+		}
+		b.WriteString(">>")
+	case Ref:
+		fmt.Fprintf(b, "%d 0 R", v.Num)
+	default:
+		b.WriteString("null")
+	}
+}
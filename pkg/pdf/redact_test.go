@@ -0,0 +1,74 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// sharedFormPDF has two pages that draw the same form, the first of them
+// twice
+func sharedFormPDF() []byte {
+	form := "BT /F1 12 Tf 0 0 Td (Name: SECRET) Tj ET"
+	return buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [4 0 R 6 0 R] /Count 2 /Resources << /Font << /F1 3 0 R >> /XObject << /Fm1 8 0 R >> >> >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 5 0 R >>",
+		streamObject("", "q 1 0 0 1 72 700 cm /Fm1 Do Q q 1 0 0 1 72 600 cm /Fm1 Do Q"),
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 7 0 R >>",
+		streamObject("", "q 1 0 0 1 72 700 cm /Fm1 Do Q"),
+		streamObject("/Type /XObject /Subtype /Form /BBox [0 0 300 50]", form),
+	)
+}
+
+func TestRedactSharedForm(t *testing.T) {
+	f, err := Parse(sharedFormPDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, err := f.PageText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := strings.Index(texts[0], "SECRET")
+	if at < 0 {
+		t.Fatalf("page 1 text %q does not contain the form text", texts[0])
+	}
+
+	var out bytes.Buffer
+	if err := f.Redact(&out, RedactOptions{Ranges: map[int][]TextRange{1: {{Start: at, End: at + len("SECRET")}}}}); err != nil {
+		t.Fatal(err)
+	}
+	redacted, err := Parse(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, err = redacted.PageText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		page       int
+		wantSecret bool
+		wantBoxes  int
+	}{
+		{1, false, 2},
+		{2, true, 0},
+	}
+	pages := redacted.pages()
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("page %d", tt.page), func(t *testing.T) {
+			text := texts[tt.page-1]
+			if strings.Contains(text, "SECRET") != tt.wantSecret || !strings.Contains(text, "Name:") {
+				t.Errorf("text = %q", text)
+			}
+			content := string(redacted.pageContent(pages[tt.page-1].dict))
+			if boxes := strings.Count(content, " re\n"); boxes != tt.wantBoxes {
+				t.Errorf("%d boxes drawn, want %d:\n%s", boxes, tt.wantBoxes, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxPages limits the pages read from the page tree
+const maxPages = 100000
+
+// maxFormDepth limits the nesting of form XObjects
+const maxFormDepth = 8
+
+// page is a leaf of the page tree with its inherited resources
+type page struct {
+	ref       Ref
+	dict      Dict
+	resources Dict
+}
+
+// NumPages returns the number of pages
+func (f *File) NumPages() int {
+	return len(f.pages())
+}
+
+// pages walks the page tree
+func (f *File) pages() []page {
+	var pages []page
+	visited := make(map[Ref]bool)
+	var walk func(obj Object, resources Dict, depth int)
+	walk = func(obj Object, resources Dict, depth int) {
+		ref, isRef := obj.(Ref)
+		if isRef {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		node := f.dict(obj)
+		if node == nil || depth > 64 || len(pages) >= maxPages {
+			return
+		}
+		if r := f.dict(node["Resources"]); r != nil {
+			resources = r
+		}
+		kids, hasKids := f.Resolve(node["Kids"]).(Array)
+		if node["Type"] == Name("Page") || !hasKids {
+			if !isRef {
+				ref = Ref{}
+			}
+			pages = append(pages, page{ref: ref, dict: node, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, resources, depth+1)
+		}
+	}
+	walk(f.catalog()["Pages"], nil, 0)
+	return pages
+}
+
+// PageText returns the text of each page, in the order it is drawn, with
+// lines broken where the text moves to a new baseline
+func (f *File) PageText() ([]string, error) {
+	pages := f.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("PDF has no pages")
+	}
+	texts := make([]string, len(pages))
+	fonts := make(map[Ref]*font)
+	for i, p := range pages {
+		e := &textExtractor{file: f, fonts: fonts, gs: graphicsState{ctm: identity, scale: 1}}
+		e.run(f.pageContent(p.dict), p.resources, 0)
+		texts[i] = e.text()
+	}
+	return texts, nil
+}
+
+// pageContent returns the content streams of a page, concatenated
+func (f *File) pageContent(p Dict) []byte {
+	var streams []Object
+	switch v := f.Resolve(p["Contents"]).(type) {
+	case *Stream:
+		streams = []Object{v}
+	case Array:
+		streams = v
+	}
+	var b bytes.Buffer
+	for _, s := range streams {
+		if stream, ok := f.Resolve(s).(*Stream); ok {
+			if data, err := f.StreamData(stream); err == nil || len(data) > 0 {
+				b.Write(data)
+				b.WriteByte('\n')
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+// matrix is an affine transformation [a b c d e f]
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// graphicsState is the part of the graphics state that affects text
+type graphicsState struct {
+	ctm       matrix
+	font      *font
+	fontSize  float64
+	charSpace float64
+	wordSpace float64
+	scale     float64 // Horizontal scaling, 1 for 100%
+	leading   float64
+	rise      float64
+}
+
+// textExtractor interprets content streams, writing the text they show
+type textExtractor struct {
+	file  *File
+	fonts map[Ref]*font
+	gs    graphicsState
+	stack []graphicsState
+	tm    matrix // Text matrix
+	tlm   matrix // Text line matrix
+	out   strings.Builder
+
+	hasLast      bool
+	lastX, lastY float64 // End of the last glyph shown
+	lastSize     float64
+
+	images *[]Image // Collects the images drawn, when set
+
+	glyphs  *[]shownGlyph // Records where each glyph came from, when set
+	stream  Ref           // Form XObject being drawn, zero for the page content
+	opIndex int           // Operation being applied
+	item    int           // Element of the TJ array being shown
+}
+
+// run interprets a content stream with its resources
+func (e *textExtractor) run(content []byte, resources Dict, depth int) {
+	for i, o := range contentOps(content) {
+		if o.op == "BI" {
+			continue
+		}
+		e.opIndex = i
+		e.operator(o.op, o.operands, resources, depth)
+	}
+}
+
+// operation is one operator of a content stream with its operands, and
+// the bytes of the stream it was read from
+type operation struct {
+	op         string
+	operands   []Object
+	start, end int
+}
+
+// contentOps splits a content stream into operations. An inline image,
+// from BI to EI, is a single operation.
+func contentOps(content []byte) []operation {
+	l := &lexer{data: content}
+	var ops []operation
+	var operands []Object
+	start := 0
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return ops
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		if op == "BI" {
+			skipInlineImage(l)
+		}
+		ops = append(ops, operation{op: string(op), operands: operands, start: start, end: l.pos})
+		operands = nil
+		start = l.pos
+	}
+}
+
+// skipInlineImage skips the data of an inline image up to EI
+func skipInlineImage(l *lexer) {
+	at := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if at < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += at + 2
+	for {
+		end := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if end < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		l.pos += end + 2
+		if isWhite(l.data[l.pos-3]) && (l.pos == len(l.data) || isWhite(l.data[l.pos]) || isDelim(l.data[l.pos])) {
+			return
+		}
+	}
+}
+
+// numbers converts operands to numbers
+func numbers(operands []Object, n int) ([]float64, bool) {
+	if len(operands) < n {
+		return nil, false
+	}
+	values := make([]float64, n)
+	for i, o := range operands[len(operands)-n:] {
+		switch v := o.(type) {
+		case int64:
+			values[i] = float64(v)
+		case float64:
+			values[i] = v
+		default:
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// operator applies one content stream operator
+func (e *textExtractor) operator(op string, operands []Object, resources Dict, depth int) {
+	switch op {
+	case "q":
+		e.stack = append(e.stack, e.gs)
+	case "Q":
+		if n := len(e.stack); n > 0 {
+			e.gs = e.stack[n-1]
+			e.stack = e.stack[:n-1]
+		}
+	case "cm":
+		if v, ok := numbers(operands, 6); ok {
+			e.gs.ctm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}.multiply(e.gs.ctm)
+		}
+	case "BT":
+		e.tm, e.tlm = identity, identity
+	case "Tf":
+		if len(operands) >= 2 {
+			name, _ := operands[len(operands)-2].(Name)
+			e.gs.font = e.font(resources, name)
+			if v, ok := numbers(operands, 1); ok {
+				e.gs.fontSize = v[0]
+			}
+		}
+	case "Tc":
+		if v, ok := numbers(operands, 1); ok {
+			e.gs.charSpace = v[0]
+		}
+	case "Tw":
+		if v, ok := numbers(operands, 1); ok {
+			e.gs.wordSpace = v[0]
+		}
+	case "Tz":
+		if v, ok := numbers(operands, 1); ok {
+			e.gs.scale = v[0] / 100
+		}
+	case "TL":
+		if v, ok := numbers(operands, 1); ok {
+			e.gs.leading = v[0]
+		}
+	case "Ts":
+		if v, ok := numbers(operands, 1); ok {
+			e.gs.rise = v[0]
+		}
+	case "Td":
+		if v, ok := numbers(operands, 2); ok {
+			e.moveLine(v[0], v[1])
+		}
+	case "TD":
+		if v, ok := numbers(operands, 2); ok {
+			e.gs.leading = -v[1]
+			e.moveLine(v[0], v[1])
+		}
+	case "Tm":
+		if v, ok := numbers(operands, 6); ok {
+			e.tm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}
+			e.tlm = e.tm
+		}
+	case "T*":
+		e.moveLine(0, -e.gs.leading)
+	case "Tj":
+		e.item = 0
+		if len(operands) > 0 {
+			s, _ := operands[len(operands)-1].(String)
+			e.show(s)
+		}
+	case "'":
+		e.item = 0
+		e.moveLine(0, -e.gs.leading)
+		if len(operands) > 0 {
+			s, _ := operands[len(operands)-1].(String)
+			e.show(s)
+		}
+	case "\"":
+		if v, ok := numbers(operands[:max(0, len(operands)-1)], 2); ok {
+			e.gs.wordSpace, e.gs.charSpace = v[0], v[1]
+		}
+		e.item = 0
+		e.moveLine(0, -e.gs.leading)
+		if len(operands) > 0 {
+			s, _ := operands[len(operands)-1].(String)
+			e.show(s)
+		}
+	case "TJ":
+		if len(operands) == 0 {
+			return
+		}
+		items, _ := operands[len(operands)-1].(Array)
+		for i, item := range items {
+			e.item = i
+			switch v := item.(type) {
+			case String:
+				e.show(v)
+			case int64, float64:
+				n, _ := numbers([]Object{v}, 1)
+				e.advance(-n[0] / 1000 * e.gs.fontSize * e.gs.scale)
+			}
+		}
+	case "Do":
+		if len(operands) > 0 {
+			name, _ := operands[len(operands)-1].(Name)
+			e.form(resources, name, depth)
+		}
+	}
+}
+
+// moveLine starts a new line offset from the start of the current one
+func (e *textExtractor) moveLine(tx, ty float64) {
+	e.tlm = matrix{1, 0, 0, 1, tx, ty}.multiply(e.tlm)
+	e.tm = e.tlm
+}
+
+// advance moves the text position along the line
+func (e *textExtractor) advance(tx float64) {
+	e.tm = matrix{1, 0, 0, 1, tx, 0}.multiply(e.tm)
+}
+
+// font returns a font from the resources, loading it once
+func (e *textExtractor) font(resources Dict, name Name) *font {
+	fonts := e.file.dict(resources["Font"])
+	obj := fonts[name]
+	ref, isRef := obj.(Ref)
+	if isRef {
+		if ft, ok := e.fonts[ref]; ok {
+			return ft
+		}
+	}
+	dict := e.file.dict(obj)
+	if dict == nil {
+		return nil
+	}
+	ft := e.file.loadFont(dict)
+	if isRef {
+		e.fonts[ref] = ft
+	}
+	return ft
+}
+
+// show writes the text of a string, separating it from the previous text
+// by a space or line break according to where it is drawn
+func (e *textExtractor) show(s String) {
+	ft := e.gs.font
+	if ft == nil {
+		ft = &font{encoding: baseEncoding("WinAnsiEncoding"), defaultWidth: 500}
+	}
+	pos := 0
+	for _, g := range ft.decode(s) {
+		trm := matrix{e.gs.fontSize * e.gs.scale, 0, 0, e.gs.fontSize, 0, e.gs.rise}.multiply(e.tm).multiply(e.gs.ctm)
+		x, y := trm[4], trm[5]
+		size := math.Hypot(trm[2], trm[3])
+		if size == 0 {
+			size = 1
+		}
+
+		outStart := e.out.Len()
+		if g.text != "" {
+			if e.hasLast {
+				ref := math.Max(size, e.lastSize)
+				switch dx := x - e.lastX; {
+				case math.Abs(y-e.lastY) > ref*0.5:
+					e.newLine()
+				case dx > ref*0.15, dx < -ref*3:
+					e.space()
+				}
+			}
+			outStart = e.out.Len()
+			e.out.WriteString(g.text)
+		}
+
+		tx := g.width/1000*e.gs.fontSize + e.gs.charSpace
+		if g.space {
+			tx += e.gs.wordSpace
+		}
+		if e.glyphs != nil {
+			e.record(g, pos, outStart, tx)
+		}
+		pos += g.n
+		e.advance(tx * e.gs.scale)
+		if g.text != "" {
+			end := matrix{1, 0, 0, 1, 0, e.gs.rise}.multiply(e.tm).multiply(e.gs.ctm)
+			e.lastX, e.lastY, e.lastSize, e.hasLast = end[4], end[5], size, true
+		}
+	}
+}
+
+// newLine ends the current line of output
+func (e *textExtractor) newLine() {
+	if e.out.Len() == 0 {
+		return
+	}
+	s := e.out.String()
+	if !strings.HasSuffix(s, "\n") {
+		e.out.WriteByte('\n')
+	}
+}
+
+// space separates words in the output
+func (e *textExtractor) space() {
+	s := e.out.String()
+	if s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		e.out.WriteByte(' ')
+	}
+}
+
+// form draws a form XObject, or records an image XObject when collecting
+// images
+func (e *textExtractor) form(resources Dict, name Name, depth int) {
+	if depth >= maxFormDepth {
+		return
+	}
+	s, ok := e.file.Resolve(e.file.dict(resources["XObject"])[name]).(*Stream)
+	if ok && e.images != nil && s.Dict["Subtype"] == Name("Image") {
+		e.image(s)
+		return
+	}
+	if !ok || s.Dict["Subtype"] != Name("Form") {
+		return
+	}
+	data, err := e.file.StreamData(s)
+	if err != nil && len(data) == 0 {
+		return
+	}
+	formResources := e.file.dict(s.Dict["Resources"])
+	if formResources == nil {
+		formResources = resources
+	}
+
+	saved, tm, tlm := e.gs, e.tm, e.tlm
+	stream, opIndex, item := e.stream, e.opIndex, e.item
+	e.stream, _ = e.file.dict(resources["XObject"])[name].(Ref)
+	if m, ok := e.file.Resolve(s.Dict["Matrix"]).(Array); ok {
+		if v, ok := numbers(m, 6); ok {
+			e.gs.ctm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}.multiply(e.gs.ctm)
+		}
+	}
+	e.run(data, formResources, depth+1)
+	e.gs, e.tm, e.tlm = saved, tm, tlm
+	e.stream, e.opIndex, e.item = stream, opIndex, item
+}
+
+// text returns the text written, without trailing spaces on each line
+func (e *textExtractor) text() string {
+	lines := strings.Split(e.out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,238 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a file from the bodies of objects 1, 2, ...; object 1 is
+// the catalog
+func buildPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// streamObject returns the body of an uncompressed stream object
+func streamObject(dict, content string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(content), content)
+}
+
+// deflate compresses a stream for /FlateDecode
+func deflate(s string) string {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.String()
+}
+
+// onePage builds a file with a single page drawing content with font F1,
+// which is the given font dictionary
+func onePage(fontDict, contentDict, content string, extra ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		fontDict,
+		streamObject(contentDict, content),
+	}
+	return buildPDF(append(objects, extra...)...)
+}
+
+const courier = "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>"
+
+func TestPageText(t *testing.T) {
+	toUnicode := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0001> <0048> <0002> <00F3> endbfchar\n" +
+		"1 beginbfrange <0003> <0004> <0061> endbfrange\n" +
+		"endcmap end end"
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "lines and words",
+			data: onePage(courier, "", "BT /F1 12 Tf 72 700 Td (Informe de) Tj 0 -14 Td (incidente) Tj ET"),
+			want: "Informe de\nincidente",
+		},
+		{
+			name: "kerned array",
+			data: onePage(courier, "", "BT /F1 12 Tf 72 700 Td [(Sosp) 20 (echoso) -900 (huy\\363)] TJ ET"),
+			want: "Sospechoso huyó",
+		},
+		{
+			name: "T* and quote operators",
+			data: onePage(courier, "", "BT /F1 12 Tf 14 TL 72 700 Td (uno) Tj T* (dos) Tj (tres) ' ET"),
+			want: "uno\ndos\ntres",
+		},
+		{
+			name: "compressed content",
+			data: onePage(courier, "/Filter /FlateDecode", deflate("BT /F1 12 Tf 72 700 Td <43616d69> Tj ET")),
+			want: "Cami",
+		},
+		{
+			name: "ToUnicode map",
+			data: onePage(
+				"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 6 0 R /DescendantFonts [<< /Type /Font /Subtype /CIDFontType2 /DW 600 >>] >>",
+				"", "BT /F1 12 Tf 72 700 Td <0001000200030004> Tj ET",
+				streamObject("", toUnicode)),
+			want: "Hóab",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.NumPages() != 1 {
+				t.Errorf("pages = %d", f.NumPages())
+			}
+			texts, err := f.PageText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if texts[0] != tt.want {
+				t.Errorf("text = %q, want %q", texts[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDamagedFiles(t *testing.T) {
+	good := onePage(courier, "", "BT /F1 12 Tf 72 700 Td (recovered) Tj ET")
+	badOffset := bytes.Replace(good, []byte("startxref\n"), []byte("startxref\n9"), 1)
+	noXref := good[:bytes.Index(good, []byte("xref\n"))]
+	noXref = append(noXref, []byte("trailer\n<< /Root 1 0 R >>\n%%EOF\n")...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"wrong startxref", badOffset, "recovered", false},
+		{"missing xref table", noXref, "recovered", false},
+		{"not a PDF", []byte("plain text"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			texts, err := f.PageText()
+			if err != nil || len(texts) != 1 || texts[0] != tt.want {
+				t.Errorf("text = %q, %v", texts, err)
+			}
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		data   string
+		want   string
+	}{
+		{"flate", "/FlateDecode", deflate("hola mundo"), "hola mundo"},
+		{"ascii hex", "/ASCIIHexDecode", "686f6c61 206d756e646f>", "hola mundo"},
+		{"ascii85", "/ASCII85Decode", "BQ%]q+Dl7=A8_~>", "hola mundo"},
+		{"run length", "/RunLengthDecode", "\x03hola\xFE \x04mundo\x80", "hola   mundo"},
+		{"chained", "[/ASCIIHexDecode /RunLengthDecode]", "03686f6c61fe2004", "hola   "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(onePage(courier, "/Filter "+tt.filter, tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, ok := f.Resolve(Ref{Num: 5}).(*Stream)
+			if !ok {
+				t.Fatal("content stream not found")
+			}
+			data, err := f.StreamData(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(data), tt.want) {
+				t.Errorf("data = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfo(t *testing.T) {
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/" xmpMM:DocumentID="uuid:1234">
+<dc:creator xmlns:dc="http://purl.org/dc/elements/1.1/"><rdf:Seq><rdf:li>Ana García</rdf:li><rdf:li>Luis</rdf:li></rdf:Seq></dc:creator>
+</rdf:Description></rdf:RDF></x:xmpmeta>`
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R /Metadata 4 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+		streamObject("/Type /Metadata /Subtype /XML", xmp),
+		"<< /Title <FEFF0049006E0066006F0072006D00650020004E00BA00200034> /Producer (Scanner 2.0) /CreationDate (D:20240301091500+01'00') >>",
+	)
+	data = bytes.Replace(data, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Info 5 0 R"), 1)
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := f.Info()
+
+	tests := []struct {
+		field, got, want string
+	}{
+		{"Title", info.Title, "Informe Nº 4"},
+		{"Producer", info.Producer, "Scanner 2.0"},
+		{"CreationDate", info.CreationDate.UTC().Format("2006-01-02 15:04"), "2024-03-01 08:15"},
+		{"Author from XMP", info.Author, "Ana García; Luis"},
+		{"DocumentID", info.XMP["xmpMM:DocumentID"], "uuid:1234"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"D:20240301091500Z", "2024-03-01T09:15:00Z"},
+		{"D:20240301091500-05'00'", "2024-03-01T14:15:00Z"},
+		{"D:2024", "2024-01-01T00:00:00Z"},
+		{"20240301", "2024-03-01T00:00:00Z"},
+		{"yesterday", "0001-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		if got := ParseDate(tt.value).UTC().Format("2006-01-02T15:04:05Z07:00"); got != tt.want {
+			t.Errorf("ParseDate(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
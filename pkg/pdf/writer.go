@@ -0,0 +1,208 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Page layout in points (US Letter)
+const (
+	PageWidth  = 612
+	PageHeight = 792
+	margin     = 54
+	footerGap  = 24
+)
+
+// Style selects the font of a line
+type Style int
+
+const (
+	Regular Style = iota
+	Bold
+)
+
+// fontNames are the standard Type 1 fonts used for each style. Monospaced
+// fonts keep column layouts produced for plain text aligned.
+var fontNames = map[Style]string{
+	Regular: "Courier",
+	Bold:    "Courier-Bold",
+}
+
+// line is a line of text on a page
+type line struct {
+	text  string
+	style Style
+}
+
+// Document is a simple text document laid out on fixed pages. Lines that do
+// not fit on the current page flow onto a new one.
+type Document struct {
+	Title    string
+	Author   string
+	Footer   string // Printed on every page before the page number
+	FontSize float64
+	pages    [][]line
+}
+
+// NewDocument creates an empty document
+func NewDocument(title string) *Document {
+	return &Document{Title: title, FontSize: 9}
+}
+
+// LinesPerPage returns the number of lines that fit on a page
+func (d *Document) LinesPerPage() int {
+	return int((PageHeight - 2*margin - footerGap) / d.lineHeight())
+}
+
+// Columns returns the number of characters that fit on a line
+func (d *Document) Columns() int {
+	return int((PageWidth - 2*margin) / (d.FontSize * 0.6)) // Courier advance is 600/1000 em
+}
+
+// AddLine adds a line of text
+func (d *Document) AddLine(text string, style Style) {
+	if len(d.pages) == 0 || len(d.pages[len(d.pages)-1]) >= d.LinesPerPage() {
+		d.pages = append(d.pages, nil)
+	}
+	last := len(d.pages) - 1
+	d.pages[last] = append(d.pages[last], line{text: text, style: style})
+}
+
+// PageBreak starts a new page unless the current page is empty
+func (d *Document) PageBreak() {
+	if len(d.pages) > 0 && len(d.pages[len(d.pages)-1]) > 0 {
+		d.pages = append(d.pages, nil)
+	}
+}
+
+// KeepTogether starts a new page if the next n lines would not fit on the
+// current one
+func (d *Document) KeepTogether(n int) {
+	if len(d.pages) > 0 && len(d.pages[len(d.pages)-1])+n > d.LinesPerPage() {
+		d.pages = append(d.pages, nil)
+	}
+}
+
+// WriteTo writes the document as PDF 1.4
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = [][]line{nil}
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// Objects 1-4: catalog, page tree, fonts and info; pages follow
+	const firstPage = 5
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[Regular]))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[Bold]))
+
+	for i, page := range pages {
+		content := d.pageContent(page, i+1, len(pages))
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	infoID := len(offsets) + 1
+	object(fmt.Sprintf("<< /Title %s /Author %s /Producer (GoInspectorGadget) /CreationDate %s >>",
+		literal(d.Title), literal(d.Author), literal(time.Now().Format("D:20060102150405"))))
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, infoID, xref)
+
+	return buf.WriteTo(w)
+}
+
+// lineHeight returns the distance between baselines
+func (d *Document) lineHeight() float64 {
+	return d.FontSize * 1.25
+}
+
+// pageContent builds the content stream of one page
+func (d *Document) pageContent(page []line, number, total int) string {
+	var b strings.Builder
+	y := PageHeight - margin - d.FontSize
+	for _, l := range page {
+		font := "/F1"
+		if l.style == Bold {
+			font = "/F2"
+		}
+		fmt.Fprintf(&b, "BT %s %.1f Tf %d %.2f Td %s Tj ET\n", font, d.FontSize, margin, y, literal(l.text))
+		y -= d.lineHeight()
+	}
+
+	footer := fmt.Sprintf("Page %d of %d", number, total)
+	if d.Footer != "" {
+		footer = d.Footer + " - " + footer
+	}
+	fmt.Fprintf(&b, "BT /F1 %.1f Tf %d %d Td %s Tj ET", d.FontSize-1, margin, margin-footerGap/2, literal(footer))
+	return b.String()
+}
+
+// literal encodes text as a PDF string literal in WinAnsiEncoding
+func literal(text string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range text {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 0x20 || c > 0x7E {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// winAnsiExtras maps characters outside Latin-1 to their WinAnsiEncoding codes
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsi converts a rune to WinAnsiEncoding
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r == '\t':
+		return ' ', true
+	case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	c, ok := winAnsiExtras[r]
+	return c, ok
+}
@@ -0,0 +1,129 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Item 1", "(Item 1)"},
+		{"Knife (kitchen)", `(Knife \(kitchen\))`},
+		{`C:\Evidence\`, `(C:\\Evidence\\)`},
+		{"Cédula Ñáñez", `(C\351dula \321\341\361ez)`},
+		{"€20 – “sealed”", `(\20020 \226 \223sealed\224)`},
+		{"tab\there", "(tab here)"},
+		{"日本 line\nbreak", "(?? line?break)"},
+		{"", "()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := literal(tt.text); got != tt.want {
+				t.Errorf("literal(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentLayout(t *testing.T) {
+	perPage := NewDocument("").LinesPerPage()
+	tests := []struct {
+		name      string
+		build     func(d *Document)
+		wantPages []int // Lines on each page, not counting the footer
+	}{
+		{"empty", func(d *Document) {}, []int{0}},
+		{"overflow onto a new page", func(d *Document) {
+			for i := 0; i < perPage+3; i++ {
+				d.AddLine(fmt.Sprintf("line %d", i), Regular)
+			}
+		}, []int{perPage, 3}},
+		{"page breaks", func(d *Document) {
+			d.PageBreak() // Nothing on the page yet, so no empty page
+			d.AddLine("first", Bold)
+			d.PageBreak()
+			d.PageBreak()
+			d.AddLine("second", Regular)
+		}, []int{1, 1}},
+		{"block kept together", func(d *Document) {
+			for i := 0; i < perPage-2; i++ {
+				d.AddLine("filler", Regular)
+			}
+			d.KeepTogether(3)
+			for i := 0; i < 3; i++ {
+				d.AddLine("block", Regular)
+			}
+		}, []int{perPage - 2, 3}},
+		{"block that fits", func(d *Document) {
+			d.AddLine("filler", Regular)
+			d.KeepTogether(3)
+			d.AddLine("block", Regular)
+		}, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocument("Report")
+			d.Footer = "Case 2024-001"
+			tt.build(d)
+
+			var buf bytes.Buffer
+			if _, err := d.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			f, err := Parse(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages, err := f.PageText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != len(tt.wantPages) || f.NumPages() != len(tt.wantPages) {
+				t.Fatalf("%d pages, want %d", len(pages), len(tt.wantPages))
+			}
+			for i, text := range pages {
+				lines := strings.Split(text, "\n")
+				footer := fmt.Sprintf("Case 2024-001 - Page %d of %d", i+1, len(pages))
+				if lines[len(lines)-1] != footer {
+					t.Errorf("page %d ends %q, want %q", i+1, lines[len(lines)-1], footer)
+				}
+				if got := len(lines) - 1; got != tt.wantPages[i] {
+					t.Errorf("page %d has %d lines, want %d", i+1, got, tt.wantPages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentText(t *testing.T) {
+	d := NewDocument("Cadena de custodia (copia)")
+	d.Author = `Sgto. Pérez \ Lab`
+	d.AddLine("Descripción: cuchillo (cocina)", Bold)
+	d.AddLine(`Ruta: C:\Evidencia\01`, Regular)
+	d.AddLine("Importe: €20", Regular)
+
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.PageText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Descripción: cuchillo (cocina)\nRuta: C:\\Evidencia\\01\nImporte: €20\nPage 1 of 1"
+	if len(pages) != 1 || pages[0] != want {
+		t.Errorf("text = %q, want %q", pages, want)
+	}
+	if info := f.Info(); info.Title != d.Title || info.Author != d.Author || info.Producer != "GoInspectorGadget" {
+		t.Errorf("info = %+v", info)
+	}
+}
@@ -0,0 +1,323 @@
+// Package pii finds personal information in text, such as Social Security
+// and cédula numbers, dates of birth, phone numbers, addresses, license
+// plates, account numbers and the names of protected persons, so that it can
+// be redacted from released documents. Patterns cover English and Spanish
+// text, including Venezuelan formats.
+package pii
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind identifies the type of personal information found
+type Kind string
+
+const (
+	KindSSN           Kind = "SSN"
+	KindCedula        Kind = "CEDULA"
+	KindDateOfBirth   Kind = "DATE_OF_BIRTH"
+	KindPhone         Kind = "PHONE"
+	KindAddress       Kind = "ADDRESS"
+	KindLicensePlate  Kind = "LICENSE_PLATE"
+	KindAccount       Kind = "ACCOUNT_NUMBER"
+	KindProtectedName Kind = "PROTECTED_NAME"
+)
+
+// Finding is a span of text holding personal information
+type Finding struct {
+	Kind       Kind
+	Start      int // Byte offsets of the text
+	End        int
+	Text       string
+	Reason     string
+	Confidence float64 // 0 to 1
+}
+
+// pattern finds one form of personal information
+type pattern struct {
+	kind       Kind
+	re         *regexp.Regexp
+	group      int // Submatch holding the information, 0 for the whole match
+	confidence float64
+	reason     string
+	valid      func(string) bool // Checks a match, nil to accept all
+}
+
+// date matches written dates in numeric, English and Spanish forms
+const date = `(?:\d{1,2}[/.-]\d{1,2}[/.-](?:\d{4}|\d{2})` +
+	`|\d{4}-\d{2}-\d{2}` +
+	`|(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{1,2},?\s+\d{4}` +
+	`|\d{1,2}\s+(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{4}` +
+	`|\d{1,2}\s+de\s+(?:enero|febrero|marzo|abril|mayo|junio|julio|agosto|septiembre|setiembre|octubre|noviembre|diciembre)\s+(?:de(?:l)?\s+)?\d{4})`
+
+// patterns are checked in order; where findings overlap the most confident
+// is kept
+var patterns = []pattern{
+	{KindSSN, regexp.MustCompile(`(?i)\b(?:SSN|SS#|soc\.? sec\.?|social security)(?:\s+(?:number|no\.?|#))?\s*[:#]?\s*(\d{3}[- ]?\d{2}[- ]?\d{4})\b`), 1, 0.98,
+		"Social Security number", validSSN},
+	{KindSSN, regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), 0, 0.9,
+		"Social Security number", validSSN},
+
+	{KindCedula, regexp.MustCompile(`(?i)(?:\bC\.\s?I\.|\bCI\b|\bc[ée]dula(?:\s+de\s+identidad)?)\s*(?:(?:N[°º.]|No\.?|Nro\.?|#|n[uú]mero)\s*)?:?\s*((?:[VE]\s?[-.]?\s?)?\d{1,2}\.?\d{3}\.?\d{3})\b`), 1, 0.97,
+		"Venezuelan cédula (identity card) number", nil},
+	{KindCedula, regexp.MustCompile(`\b[VE]\s?[-.]\s?(?:\d{1,2}\.\d{3}\.\d{3}|\d{6,8})\b`), 0, 0.9,
+		"Venezuelan cédula (identity card) number", nil},
+
+	{KindDateOfBirth, regexp.MustCompile(`(?i)\b(?:DOB|D\.O\.B\.?|date\s+of\s+birth|birth\s?date|born(?:\s+on)?|fecha\s+de\s+nacimiento|f\.\s?(?:de\s+)?nac\.?|nacid[oa]\s+(?:el\s+)?)\s*[:-]?\s*(` + date + `)`), 1, 0.95,
+		"Date of birth", nil},
+
+	{KindPhone, regexp.MustCompile(`(?:\+58[\s.-]?\(?0?|\(0|\b0)(?:4(?:12|14|16|22|24|26)|2\d{2})\)?[\s.-]?\d{3}[\s.-]?\d{2}[\s.-]?\d{2}\b`), 0, 0.9,
+		"Venezuelan phone number", nil},
+	{KindPhone, regexp.MustCompile(`(?:\+1[\s.-]?)?(?:\([2-9]\d{2}\)\s?|\b[2-9]\d{2}[\s.-])\d{3}[\s.-]\d{4}\b`), 0, 0.85,
+		"Phone number", nil},
+
+	{KindAddress, regexp.MustCompile(`\b\d{1,6}(?:\s+[A-Z][A-Za-z.'-]*){1,4}\s+(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Lane|Ln|Drive|Dr|Court|Ct|Way|Place|Pl|Terrace|Ter|Circle|Cir|Highway|Hwy|Parkway|Pkwy)\b\.?(?:,?\s*(?:Apt|Apartment|Suite|Ste|Unit|#)\.?\s*[A-Za-z0-9-]+)?(?:,\s*[A-Z][A-Za-z .]+,\s*[A-Z]{2}\s+\d{5}(?:-\d{4})?)?`), 0, 0.85,
+		"Street address", nil},
+	{KindAddress, regexp.MustCompile(`\b(?:Calle|Avenida|Av\.|Avda\.|Carrera|Transversal|Urbanizaci[oó]n|Urb\.|Edificio|Edif\.|Residencias|Res\.|Quinta|Qta\.|Sector|Barrio|Callej[oó]n)\s+[^\n,;.()]{2,40}(?:,\s*(?:Calle|Av\.|Avenida|Edificio|Edif\.|Residencias|Torre|Piso|Apto\.?|Apartamento|Casa|Quinta|Qta\.|Local|Nro\.?|N[°º]|Urbanizaci[oó]n|Urb\.|Sector|Parroquia|Municipio)\s*[^\n,;.()]{1,40})*`), 0, 0.75,
+		"Street address", nil},
+	{KindAddress, regexp.MustCompile(`(?i)\b(?:address|residing\s+at|resides\s+at|lives\s+at|domicilio|direcci[oó]n|reside\s+en|residenciad[oa]\s+en)\s*:?\s*([^\n]{5,100})`), 1, 0.8,
+		"Street address", hasDigit},
+
+	{KindLicensePlate, regexp.MustCompile(`(?i)\b(?:licen[cs]e\s+plate|plate|tag|placas?|matr[ií]cula)(?:\s+(?:number|no\.?|#|n[°º]\.?|nro\.?))?\s*[:#]?\s*(?:was\s+|is\s+|es\s+)?((?-i:[A-Z0-9]{1,4}[\s-]?[A-Z0-9]{1,4}(?:[\s-]?[A-Z0-9]{1,3})?))\b`), 1, 0.9,
+		"Vehicle license plate", validPlate},
+	{KindLicensePlate, regexp.MustCompile(`\b[A-Z]{2}\d{3}[A-Z]{2}\b`), 0, 0.7,
+		"Vehicle license plate", nil},
+	{KindLicensePlate, regexp.MustCompile(`\b[A-Z]{3}-\d{3,4}\b`), 0, 0.6,
+		"Vehicle license plate", nil},
+
+	{KindAccount, regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), 0, 0.95,
+		"Payment card number", validCard},
+	{KindAccount, regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`), 0, 0.95,
+		"Bank account number (IBAN)", validIBAN},
+	{KindAccount, regexp.MustCompile(`\b01\d{2}[\s-]?\d{4}[\s-]?\d{2}[\s-]?\d{10}\b`), 0, 0.9,
+		"Venezuelan bank account number", nil},
+	{KindAccount, regexp.MustCompile(`(?i)\b(?:account|acct\.?|a/c|routing|cuenta)(?:\s+(?:number|no\.?|#|n[°º]\.?|nro\.?|n[uú]mero))?\s*[:#]?\s*(\d[\d -]{4,30}\d)\b`), 1, 0.85,
+		"Account number", validAccount},
+}
+
+// Detector finds personal information in text, including the names of
+// persons under protection
+type Detector struct {
+	names []pattern
+}
+
+// NewDetector creates a detector that also finds the given names of
+// protected persons, in full or in part, ignoring case and accents
+func NewDetector(protectedNames []string) *Detector {
+	d := &Detector{}
+	seen := make(map[string]bool)
+	add := func(words []string, confidence float64, reason string) {
+		key := strings.ToLower(strings.Join(words, " "))
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		d.names = append(d.names, pattern{
+			kind:       KindProtectedName,
+			re:         nameRegexp(words),
+			confidence: confidence,
+			reason:     reason,
+		})
+	}
+	for _, name := range protectedNames {
+		words := strings.Fields(name)
+		if len(words) == 0 {
+			continue
+		}
+		add(words, 0.95, "Name of a protected person")
+		if len(words) < 2 {
+			continue
+		}
+		// First name with any surname, surname first, and surnames alone
+		for _, surname := range words[1:] {
+			add([]string{words[0], surname}, 0.9, "Name of a protected person")
+			add([]string{surname + ",", words[0]}, 0.9, "Name of a protected person")
+			if utf8.RuneCountInString(surname) >= 4 {
+				add([]string{surname}, 0.6, "Surname of a protected person")
+			}
+		}
+	}
+	return d
+}
+
+// Detect returns the personal information found in text, in order. Where
+// findings overlap only the most confident is kept.
+func (d *Detector) Detect(text string) []Finding {
+	var found []Finding
+	for _, p := range append(append([]pattern{}, patterns...), d.names...) {
+		for _, m := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[2*p.group], m[2*p.group+1]
+			if start < 0 {
+				continue
+			}
+			value := strings.TrimRight(text[start:end], " \t.;")
+			end = start + len(value)
+			if p.kind == KindProtectedName && !wordBounded(text, start, end) {
+				continue
+			}
+			if value == "" || (p.valid != nil && !p.valid(value)) {
+				continue
+			}
+			found = append(found, Finding{
+				Kind:       p.kind,
+				Start:      start,
+				End:        end,
+				Text:       value,
+				Reason:     p.reason,
+				Confidence: p.confidence,
+			})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Confidence != found[j].Confidence {
+			return found[i].Confidence > found[j].Confidence
+		}
+		return found[i].End-found[i].Start > found[j].End-found[j].Start
+	})
+	var kept []Finding
+	for _, f := range found {
+		overlaps := false
+		for _, k := range kept {
+			if f.Start < k.End && k.Start < f.End {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, f)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Start < kept[j].Start })
+	return kept
+}
+
+// accents maps letters to a class matching them with or without accents
+var accents = map[rune]string{
+	'a': "aáàâäã", 'e': "eéèêë", 'i': "iíìîï", 'o': "oóòôöõ",
+	'u': "uúùûü", 'n': "nñ", 'c': "cç", 'y': "yý",
+}
+
+// nameRegexp matches a sequence of name words separated by any whitespace,
+// ignoring case and accents
+func nameRegexp(words []string) *regexp.Regexp {
+	parts := make([]string, len(words))
+	for i, word := range words {
+		var b strings.Builder
+		for _, r := range strings.ToLower(word) {
+			base := r
+			for letter, class := range accents {
+				if strings.ContainsRune(class, r) {
+					base = letter
+					break
+				}
+			}
+			if class, ok := accents[base]; ok {
+				b.WriteString("[" + class + "]")
+			} else {
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		parts[i] = b.String()
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(parts, `\s+`))
+}
+
+// wordBounded reports whether a span starts and ends at word boundaries
+func wordBounded(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+// isWordRune reports whether a rune is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// digits returns the digits of a string
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// hasDigit reports whether a string holds a digit, as street addresses do
+func hasDigit(s string) bool {
+	return digits(s) != ""
+}
+
+// validSSN rejects numbers never issued as Social Security numbers
+func validSSN(s string) bool {
+	d := digits(s)
+	if len(d) != 9 {
+		return false
+	}
+	area, group, serial := d[:3], d[3:5], d[5:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// validCard checks the length and Luhn check digit of a card number
+func validCard(s string) bool {
+	d := digits(s)
+	if len(d) < 13 || len(d) > 19 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(d); i++ {
+		n := int(d[len(d)-1-i] - '0')
+		if i%2 == 1 {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks the length and mod-97 check digits of an IBAN
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	s = s[4:] + s[:4]
+	remainder := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// validAccount accepts account numbers of 6 to 20 digits
+func validAccount(s string) bool {
+	n := len(digits(s))
+	return n >= 6 && n <= 20
+}
+
+// validPlate accepts plates of 4 to 8 characters mixing letters and digits,
+// so that years and counts after words such as "tag" are not taken for plates
+func validPlate(s string) bool {
+	plate := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	return len(plate) >= 4 && len(plate) <= 8 && hasDigit(plate) && strings.ContainsFunc(plate, unicode.IsLetter)
+}
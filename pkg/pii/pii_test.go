@@ -0,0 +1,153 @@
+package pii
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// findings summarizes findings as KIND "text" for comparison
+func findings(found []Finding) string {
+	parts := make([]string, len(found))
+	for i, f := range found {
+		parts[i] = fmt.Sprintf("%s %q", f.Kind, f.Text)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func TestDetect(t *testing.T) {
+	d := NewDetector([]string{"María José Peña", "Luis"})
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		// Social Security numbers
+		{"labelled SSN", "SSN: 123-45-6789", `SSN "123-45-6789"`},
+		{"labelled SSN with spaces", "ssn 123 45 6789", `SSN "123 45 6789"`},
+		{"SSN without a label", "call 123-45-6789", `SSN "123-45-6789"`},
+		{"SSN area 000", "SSN 000-12-3456", ""},
+		{"SSN area 666", "SSN 666-12-3456", ""},
+		{"SSN area 9xx", "900-12-3456", ""},
+		{"SSN group 00", "123-00-4567", ""},
+		{"SSN serial 0000", "123-45-0000", ""},
+
+		// Venezuelan cédulas
+		{"cédula with prefix and dots", "C.I. V-12.345.678", `CEDULA "V-12.345.678"`},
+		{"cédula without prefix", "cédula de identidad Nro. 12345678", `CEDULA "12345678"`},
+		{"cédula with seven digits", "CI: 9.876.543", `CEDULA "9.876.543"`},
+		{"foreigner's cédula without a label", "titular E-81.234.567", `CEDULA "E-81.234.567"`},
+		{"prefixed cédula without a label", "titular V-12345678", `CEDULA "V-12345678"`},
+
+		// Phones
+		{"Venezuelan mobile", "llamar al 0414-123.45.67", `PHONE "0414-123.45.67"`},
+		{"Venezuelan mobile with country code", "+58 412 1234567", `PHONE "+58 412 1234567"`},
+		{"Venezuelan landline", "(0212) 555-12-34", `PHONE "(0212) 555-12-34"`},
+		{"NANP with parentheses", "call (555) 867-5309", `PHONE "(555) 867-5309"`},
+		{"NANP with country code", "+1 212-555-0147", `PHONE "+1 212-555-0147"`},
+		{"NANP with dots", "212.555.0147", `PHONE "212.555.0147"`},
+
+		// Dates of birth
+		{"DOB numeric", "DOB: 01/02/1980", `DATE_OF_BIRTH "01/02/1980"`},
+		{"born in English", "born on March 3, 1975", `DATE_OF_BIRTH "March 3, 1975"`},
+		{"fecha de nacimiento", "fecha de nacimiento: 3 de marzo de 1975", `DATE_OF_BIRTH "3 de marzo de 1975"`},
+		{"nacida el", "nacida el 03-03-1975", `DATE_OF_BIRTH "03-03-1975"`},
+		{"F. Nac. ISO", "F. Nac. 1975-03-03", `DATE_OF_BIRTH "1975-03-03"`},
+		{"date without a birth label", "seized on 01/02/1980", ""},
+
+		// Accounts
+		{"card passing Luhn", "card 4111 1111 1111 1111", `ACCOUNT_NUMBER "4111 1111 1111 1111"`},
+		{"card failing Luhn", "card 4111 1111 1111 1112", ""},
+		{"valid IBAN", "IBAN ES91 2100 0418 4502 0005 1332", `ACCOUNT_NUMBER "ES91 2100 0418 4502 0005 1332"`},
+		{"IBAN with wrong check digits", "IBAN ES92 2100 0418 4502 0005 1332", ""},
+		{"Venezuelan bank account", "cuenta 0102-0123-45-0000012345", `ACCOUNT_NUMBER "0102-0123-45-0000012345"`},
+		{"labelled account", "account no. 123456789", `ACCOUNT_NUMBER "123456789"`},
+		{"labelled account too short", "account no. 12345", ""},
+
+		// Plates
+		{"US plate", "plate ABC-1234", `LICENSE_PLATE "ABC-1234"`},
+		{"Venezuelan plate", "placa AB123CD", `LICENSE_PLATE "AB123CD"`},
+		{"tag", "tag: 7XYZ123", `LICENSE_PLATE "7XYZ123"`},
+		{"matrícula with dashes", "matrícula AA-123-BB", `LICENSE_PLATE "AA-123-BB"`},
+		{"Venezuelan plate without a label", "vehículo AB123CD", `LICENSE_PLATE "AB123CD"`},
+
+		// Protected names
+		{"name without accents", "maria jose pena was there", `PROTECTED_NAME "maria jose pena"`},
+		{"name in capitals", "MARÍA JOSÉ PEÑA", `PROTECTED_NAME "MARÍA JOSÉ PEÑA"`},
+		{"name across a line break", "María\nPeña", `PROTECTED_NAME "María\nPeña"`},
+		{"surname first", "Peña, María", `PROTECTED_NAME "Peña, María"`},
+		{"surname alone", "la Sra. Peña dijo", `PROTECTED_NAME "Peña"`},
+		{"single name", "Luis llegó", `PROTECTED_NAME "Luis"`},
+		{"name inside a word", "Penalty kick", ""},
+		{"longer name", "Luisa llegó", ""},
+
+		// False positives
+		{"case number", "Case 2024-001234 opened", ""},
+		{"expediente", "Expediente 2024-05-1234", ""},
+		{"case ID", "case CASE-2024-0042", ""},
+		{"docket number", "Case No. 24-CR-00123", ""},
+		{"time and date", "at 10:30 on 12/03/2024", ""},
+		{"times", "between 14:30:00 and 15:45, then 9:15 p.m.", ""},
+		{"tag as a verb", "tag the evidence bag", ""},
+		{"price tag and year", "price tag 2024 model", ""},
+		{"tag and a count", "we will tag 1200 items", ""},
+
+		// Overlaps keep the most confident finding
+		{"labelled SSN over the bare pattern", "SSN 123-45-6789 call 0414-123.45.67", `SSN "123-45-6789", PHONE "0414-123.45.67"`},
+		{"full name over its surname", "Sra. María José Peña", `PROTECTED_NAME "María José Peña"`},
+		{"labelled cédula over the bare pattern", "C.I. V-12345678", `CEDULA "V-12345678"`},
+		{"card over a labelled account", "account 4111-1111-1111-1111", `ACCOUNT_NUMBER "4111-1111-1111-1111"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findings(d.Detect(tt.text)); got != tt.want {
+				t.Errorf("Detect(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectOffsets(t *testing.T) {
+	text := "Víctima: María José Peña, C.I. V-12.345.678, teléfono 0414-123.45.67."
+	found := NewDetector([]string{"María José Peña"}).Detect(text)
+	if len(found) != 3 {
+		t.Fatalf("findings: %s", findings(found))
+	}
+	for i, f := range found {
+		if text[f.Start:f.End] != f.Text {
+			t.Errorf("finding %d spans %q, text %q", i, text[f.Start:f.End], f.Text)
+		}
+		if i > 0 && f.Start < found[i-1].End {
+			t.Errorf("finding %d overlaps or precedes finding %d", i, i-1)
+		}
+		if f.Reason == "" || f.Confidence <= 0 || f.Confidence > 1 {
+			t.Errorf("finding %d: reason %q, confidence %v", i, f.Reason, f.Confidence)
+		}
+	}
+	// The phone's trailing period is not part of the finding
+	if last := found[2]; last.Text != "0414-123.45.67" || last.End != len(text)-1 {
+		t.Errorf("phone %q ends at %d", last.Text, last.End)
+	}
+}
+
+func TestNewDetectorNames(t *testing.T) {
+	tests := []struct {
+		names []string
+		text  string
+		want  string
+	}{
+		{nil, "María José Peña", ""},
+		{[]string{"", "  "}, "María José Peña", ""},
+		// Short surnames are only found with the first name
+		{[]string{"Ana Gil"}, "Ana Gil y el Sr. Gil", `PROTECTED_NAME "Ana Gil"`},
+		{[]string{"José Peña", "jose pena"}, "José Peña", `PROTECTED_NAME "José Peña"`},
+		{[]string{"John O'Brien"}, "Mr. O'Brien", `PROTECTED_NAME "O'Brien"`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.names, ","), func(t *testing.T) {
+			if got := findings(NewDetector(tt.names).Detect(tt.text)); got != tt.want {
+				t.Errorf("Detect(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}